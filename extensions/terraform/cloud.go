@@ -0,0 +1,503 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package terraform
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	log "github.com/rs/zerolog/log"
+)
+
+const (
+	// TerraformCloudDefaultHostname is used when no terraform enterprise
+	// hostname is configured
+	TerraformCloudDefaultHostname = "app.terraform.io"
+	// TerraformCloudTokenEnv holds the terraform cloud api token, it's never
+	// stored on the cluster record
+	TerraformCloudTokenEnv = "TFE_TOKEN"
+
+	terraformCloudDefaultWorkspacePrefix = "kubefirst"
+	terraformCloudContentType            = "application/vnd.api+json"
+	terraformCloudPollInterval           = 10 * time.Second
+	terraformCloudRunTimeout             = 2 * time.Hour
+)
+
+var (
+	terraformCloudSucceededStatuses = []string{"applied", "planned_and_finished"}
+	terraformCloudFailedStatuses    = []string{"errored", "discarded", "canceled", "force_canceled", "policy_soft_failed"}
+)
+
+// TerraformCloudEnabled reports whether terraform runs execute in terraform
+// cloud instead of locally
+func TerraformCloudEnabled(cloud pkgtypes.TerraformCloud) bool {
+	return cloud.Organization != ""
+}
+
+// TerraformCloudWorkspaceName returns the workspace an entrypoint runs in,
+// e.g. kubefirst-<cluster name>-aws
+func TerraformCloudWorkspaceName(cloud pkgtypes.TerraformCloud, clusterName string, tfEntrypoint string) string {
+	prefix := cloud.WorkspacePrefix
+	if prefix == "" {
+		prefix = terraformCloudDefaultWorkspacePrefix
+	}
+
+	return fmt.Sprintf("%s-%s-%s", prefix, clusterName, filepath.Base(tfEntrypoint))
+}
+
+// CloudApplyAutoApprove uploads the entrypoint to its terraform cloud
+// workspace and waits for an auto-applied run to finish, runCreated is called
+// as soon as the run is queued so its url can be surfaced while it executes
+func CloudApplyAutoApprove(cloud pkgtypes.TerraformCloud, clusterName string, tfEntrypoint string, tfEnvs map[string]string, runCreated func(pkgtypes.TerraformCloudRun)) (pkgtypes.TerraformCloudRun, error) {
+	return cloudActionAutoApprove(cloud, clusterName, tfEntrypoint, tfEnvs, false, runCreated)
+}
+
+// DestroyAutoApprove destroys the entrypoint locally or, when the cluster
+// executes terraform in terraform cloud, as a terraform cloud destroy run
+func DestroyAutoApprove(terraformClientPath string, cl *pkgtypes.Cluster, tfEntrypoint string, tfEnvs map[string]string) error {
+	if !TerraformCloudEnabled(cl.TerraformCloud) {
+		return InitDestroyAutoApprove(terraformClientPath, tfEntrypoint, tfEnvs)
+	}
+
+	_, err := cloudActionAutoApprove(cl.TerraformCloud, cl.ClusterName, tfEntrypoint, tfEnvs, true, nil)
+	return err
+}
+
+func cloudActionAutoApprove(cloud pkgtypes.TerraformCloud, clusterName string, tfEntrypoint string, tfEnvs map[string]string, destroy bool, runCreated func(pkgtypes.TerraformCloudRun)) (pkgtypes.TerraformCloudRun, error) {
+	client, err := newTerraformCloudClient(cloud)
+	if err != nil {
+		return pkgtypes.TerraformCloudRun{}, err
+	}
+
+	workspace := TerraformCloudWorkspaceName(cloud, clusterName, tfEntrypoint)
+
+	// a workspace that was never created has nothing to destroy
+	if destroy {
+		_, found, err := client.getWorkspace(workspace)
+		if err != nil {
+			return pkgtypes.TerraformCloudRun{}, err
+		}
+		if !found {
+			log.Info().Msgf("terraform cloud workspace %s/%s does not exist, nothing to destroy", cloud.Organization, workspace)
+			return pkgtypes.TerraformCloudRun{}, nil
+		}
+	}
+
+	log.Info().Msgf("executing %s in terraform cloud workspace %s/%s", tfEntrypoint, cloud.Organization, workspace)
+
+	workspaceID, err := client.ensureWorkspace(workspace)
+	if err != nil {
+		return pkgtypes.TerraformCloudRun{}, err
+	}
+
+	err = client.setEnvironmentVariables(workspaceID, tfEnvs)
+	if err != nil {
+		return pkgtypes.TerraformCloudRun{}, err
+	}
+
+	configurationVersionID, err := client.uploadConfiguration(workspaceID, tfEntrypoint)
+	if err != nil {
+		return pkgtypes.TerraformCloudRun{}, err
+	}
+
+	runID, err := client.createRun(workspaceID, configurationVersionID, destroy)
+	if err != nil {
+		return pkgtypes.TerraformCloudRun{}, err
+	}
+
+	run := pkgtypes.TerraformCloudRun{
+		Workspace: workspace,
+		RunID:     runID,
+		URL:       fmt.Sprintf("https://%s/app/%s/workspaces/%s/runs/%s", client.hostname, cloud.Organization, workspace, runID),
+		Status:    "pending",
+	}
+	log.Info().Msgf("terraform cloud run queued: %s", run.URL)
+	if runCreated != nil {
+		runCreated(run)
+	}
+
+	run.Status, err = client.waitForRun(runID)
+	if err != nil {
+		return run, fmt.Errorf("terraform cloud run %s failed: %s", run.URL, err)
+	}
+
+	log.Info().Msgf("terraform cloud run %s finished with status %s", run.URL, run.Status)
+
+	return run, nil
+}
+
+// terraformCloudClient is a minimal client of the terraform cloud/enterprise
+// api covering the calls needed to execute a run
+type terraformCloudClient struct {
+	hostname     string
+	organization string
+	token        string
+	httpClient   *http.Client
+}
+
+// terraformCloudHTTPClient sends the terraform cloud api requests,
+// overridden in tests
+var terraformCloudHTTPClient = &http.Client{Timeout: 60 * time.Second}
+
+func newTerraformCloudClient(cloud pkgtypes.TerraformCloud) (*terraformCloudClient, error) {
+	token := os.Getenv(TerraformCloudTokenEnv)
+	if token == "" {
+		return nil, fmt.Errorf("%s must be set to execute terraform in terraform cloud", TerraformCloudTokenEnv)
+	}
+
+	hostname := cloud.Hostname
+	if hostname == "" {
+		hostname = TerraformCloudDefaultHostname
+	}
+
+	return &terraformCloudClient{
+		hostname:     hostname,
+		organization: cloud.Organization,
+		token:        token,
+		httpClient:   terraformCloudHTTPClient,
+	}, nil
+}
+
+// terraformCloudDocument is the json:api envelope of every request and response
+type terraformCloudDocument struct {
+	Data terraformCloudResource `json:"data"`
+}
+
+type terraformCloudResource struct {
+	ID            string                 `json:"id,omitempty"`
+	Type          string                 `json:"type"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+	Relationships map[string]interface{} `json:"relationships,omitempty"`
+}
+
+func (c *terraformCloudClient) do(method string, path string, body interface{}, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("https://%s/api/v2%s", c.hostname, path), reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.token))
+	req.Header.Add("Content-Type", terraformCloudContentType)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return res.StatusCode, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("terraform cloud %s %s returned %d: %q", method, path, res.StatusCode, string(resBody))
+	}
+
+	if out != nil && len(resBody) != 0 {
+		err = json.Unmarshal(resBody, out)
+		if err != nil {
+			return res.StatusCode, fmt.Errorf("error decoding terraform cloud response for %s: %s", path, err)
+		}
+	}
+
+	return res.StatusCode, nil
+}
+
+// getWorkspace returns the id of the named workspace and whether it exists
+func (c *terraformCloudClient) getWorkspace(name string) (string, bool, error) {
+	var workspace terraformCloudDocument
+	status, err := c.do(http.MethodGet, fmt.Sprintf("/organizations/%s/workspaces/%s", c.organization, name), nil, &workspace)
+	if status == http.StatusNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	return workspace.Data.ID, true, nil
+}
+
+// ensureWorkspace returns the id of the named workspace, creating it with
+// auto-apply enabled when it doesn't exist
+func (c *terraformCloudClient) ensureWorkspace(name string) (string, error) {
+	workspaceID, found, err := c.getWorkspace(name)
+	if err != nil {
+		return "", err
+	}
+	if found {
+		return workspaceID, nil
+	}
+
+	var workspace terraformCloudDocument
+	_, err = c.do(http.MethodPost, fmt.Sprintf("/organizations/%s/workspaces", c.organization), terraformCloudDocument{
+		Data: terraformCloudResource{
+			Type: "workspaces",
+			Attributes: map[string]interface{}{
+				"name":           name,
+				"execution-mode": "remote",
+				"auto-apply":     true,
+			},
+		},
+	}, &workspace)
+	if err != nil {
+		return "", fmt.Errorf("error creating terraform cloud workspace %s: %s", name, err)
+	}
+	log.Info().Msgf("created terraform cloud workspace %s", name)
+
+	return workspace.Data.ID, nil
+}
+
+// setEnvironmentVariables creates or updates the workspace's sensitive
+// environment variables from the entrypoint's terraform envs
+func (c *terraformCloudClient) setEnvironmentVariables(workspaceID string, tfEnvs map[string]string) error {
+	var vars struct {
+		Data []terraformCloudResource `json:"data"`
+	}
+	_, err := c.do(http.MethodGet, fmt.Sprintf("/workspaces/%s/vars", workspaceID), nil, &vars)
+	if err != nil {
+		return err
+	}
+
+	existing := map[string]string{}
+	for _, v := range vars.Data {
+		key, _ := v.Attributes["key"].(string)
+		category, _ := v.Attributes["category"].(string)
+		if category == "env" {
+			existing[key] = v.ID
+		}
+	}
+
+	for key, value := range tfEnvs {
+		attributes := map[string]interface{}{
+			"key":       key,
+			"value":     value,
+			"category":  "env",
+			"sensitive": true,
+		}
+
+		if id, ok := existing[key]; ok {
+			_, err = c.do(http.MethodPatch, fmt.Sprintf("/workspaces/%s/vars/%s", workspaceID, id), terraformCloudDocument{
+				Data: terraformCloudResource{ID: id, Type: "vars", Attributes: attributes},
+			}, nil)
+		} else {
+			_, err = c.do(http.MethodPost, fmt.Sprintf("/workspaces/%s/vars", workspaceID), terraformCloudDocument{
+				Data: terraformCloudResource{Type: "vars", Attributes: attributes},
+			}, nil)
+		}
+		if err != nil {
+			return fmt.Errorf("error setting terraform cloud variable %s: %s", key, err)
+		}
+	}
+
+	return nil
+}
+
+// uploadConfiguration uploads the entrypoint directory as a new
+// configuration version and waits until it's been processed
+func (c *terraformCloudClient) uploadConfiguration(workspaceID string, tfEntrypoint string) (string, error) {
+	var configurationVersion terraformCloudDocument
+	_, err := c.do(http.MethodPost, fmt.Sprintf("/workspaces/%s/configuration-versions", workspaceID), terraformCloudDocument{
+		Data: terraformCloudResource{
+			Type:       "configuration-versions",
+			Attributes: map[string]interface{}{"auto-queue-runs": false},
+		},
+	}, &configurationVersion)
+	if err != nil {
+		return "", err
+	}
+
+	uploadURL, _ := configurationVersion.Data.Attributes["upload-url"].(string)
+	if uploadURL == "" {
+		return "", fmt.Errorf("terraform cloud returned no upload url for configuration version %s", configurationVersion.Data.ID)
+	}
+
+	archive, err := archiveTerraformEntrypoint(tfEntrypoint)
+	if err != nil {
+		return "", fmt.Errorf("error archiving %s: %s", tfEntrypoint, err)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, uploadURL, bytes.NewReader(archive))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Add("Content-Type", "application/octet-stream")
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error uploading %s to terraform cloud: %s", tfEntrypoint, err)
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return "", fmt.Errorf("error uploading %s to terraform cloud, http status code is: %d", tfEntrypoint, res.StatusCode)
+	}
+
+	for i := 0; i < 30; i++ {
+		_, err = c.do(http.MethodGet, fmt.Sprintf("/configuration-versions/%s", configurationVersion.Data.ID), nil, &configurationVersion)
+		if err != nil {
+			return "", err
+		}
+
+		status, _ := configurationVersion.Data.Attributes["status"].(string)
+		switch status {
+		case "uploaded":
+			return configurationVersion.Data.ID, nil
+		case "errored":
+			return "", fmt.Errorf("terraform cloud could not process the configuration of %s", tfEntrypoint)
+		}
+		time.Sleep(2 * time.Second)
+	}
+
+	return "", fmt.Errorf("timed out waiting for terraform cloud to process the configuration of %s", tfEntrypoint)
+}
+
+// createRun queues an auto-applied run of the configuration version
+func (c *terraformCloudClient) createRun(workspaceID string, configurationVersionID string, destroy bool) (string, error) {
+	var run terraformCloudDocument
+	_, err := c.do(http.MethodPost, "/runs", terraformCloudDocument{
+		Data: terraformCloudResource{
+			Type: "runs",
+			Attributes: map[string]interface{}{
+				"is-destroy": destroy,
+				"auto-apply": true,
+				"message":    "Queued by kubefirst",
+			},
+			Relationships: map[string]interface{}{
+				"workspace": map[string]interface{}{
+					"data": map[string]string{"type": "workspaces", "id": workspaceID},
+				},
+				"configuration-version": map[string]interface{}{
+					"data": map[string]string{"type": "configuration-versions", "id": configurationVersionID},
+				},
+			},
+		},
+	}, &run)
+	if err != nil {
+		return "", fmt.Errorf("error creating terraform cloud run: %s", err)
+	}
+
+	return run.Data.ID, nil
+}
+
+// waitForRun polls the run until it reaches a final status, runs waiting on a
+// policy override keep being polled until someone acts on them or it times out
+func (c *terraformCloudClient) waitForRun(runID string) (string, error) {
+	deadline := time.Now().Add(terraformCloudRunTimeout)
+	lastStatus := ""
+
+	for time.Now().Before(deadline) {
+		var run terraformCloudDocument
+		_, err := c.do(http.MethodGet, fmt.Sprintf("/runs/%s", runID), nil, &run)
+		if err != nil {
+			return lastStatus, err
+		}
+
+		status, _ := run.Data.Attributes["status"].(string)
+		if status != lastStatus {
+			log.Info().Msgf("terraform cloud run %s status: %s", runID, status)
+			lastStatus = status
+		}
+
+		for _, s := range terraformCloudSucceededStatuses {
+			if status == s {
+				return status, nil
+			}
+		}
+		for _, s := range terraformCloudFailedStatuses {
+			if status == s {
+				return status, fmt.Errorf("run finished with status %s", status)
+			}
+		}
+
+		time.Sleep(terraformCloudPollInterval)
+	}
+
+	return lastStatus, fmt.Errorf("timed out after %s waiting for the run, last status %s", terraformCloudRunTimeout, lastStatus)
+}
+
+// archiveTerraformEntrypoint packs the entrypoint into the tar.gz terraform
+// cloud expects, leaving out local terraform working files
+func archiveTerraformEntrypoint(tfEntrypoint string) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.Walk(tfEntrypoint, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relative, err := filepath.Rel(tfEntrypoint, path)
+		if err != nil {
+			return err
+		}
+		if relative == "." {
+			return nil
+		}
+		if info.IsDir() && info.Name() == ".terraform" {
+			return filepath.SkipDir
+		}
+		if strings.HasSuffix(info.Name(), ".tfstate") || strings.HasSuffix(info.Name(), ".tfstate.backup") {
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(relative)
+
+		err = tw.WriteHeader(header)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return nil, err
+	}
+	err = gz.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
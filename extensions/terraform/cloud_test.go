@@ -0,0 +1,79 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package terraform
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// testTerraformCloud starts a terraform cloud api answering with handler and
+// returns the cluster's terraform cloud settings pointing at it
+func testTerraformCloud(t *testing.T, handler http.HandlerFunc) pkgtypes.TerraformCloud {
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+
+	original := terraformCloudHTTPClient
+	terraformCloudHTTPClient = server.Client()
+	t.Cleanup(func() { terraformCloudHTTPClient = original })
+	t.Setenv(TerraformCloudTokenEnv, "tfe-token")
+
+	return pkgtypes.TerraformCloud{
+		Organization: "acme",
+		Hostname:     strings.TrimPrefix(server.URL, "https://"),
+	}
+}
+
+func TestTerraformCloudDestroyMissingWorkspace(t *testing.T) {
+	cloud := testTerraformCloud(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/v2/organizations/acme/workspaces/kubefirst-kf-dev-github" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	cl := &pkgtypes.Cluster{ClusterName: "kf-dev", TerraformCloud: cloud}
+	err := DestroyAutoApprove("", cl, "/tmp/gitops/terraform/github", map[string]string{})
+	if err != nil {
+		t.Errorf("DestroyAutoApprove() = %s, want a missing workspace to have nothing to destroy", err)
+	}
+}
+
+func TestTerraformCloudApplyNotFound(t *testing.T) {
+	var requests []string
+	cloud := testTerraformCloud(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v2/organizations/acme/workspaces/kubefirst-kf-dev-aws":
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v2/organizations/acme/workspaces":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"data": {"id": "ws-123", "type": "workspaces"}}`))
+		default:
+			// e.g. the variables of a workspace deleted in the meantime
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	_, err := CloudApplyAutoApprove(cloud, "kf-dev", "/tmp/gitops/terraform/aws", map[string]string{"TF_VAR_region": "us-east-1"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "returned 404") {
+		t.Errorf("CloudApplyAutoApprove() = %v, want the 404 of the variables to fail the apply", err)
+	}
+
+	want := []string{
+		"GET /api/v2/organizations/acme/workspaces/kubefirst-kf-dev-aws",
+		"POST /api/v2/organizations/acme/workspaces",
+		"GET /api/v2/workspaces/ws-123/vars",
+	}
+	if strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+}
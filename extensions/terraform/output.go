@@ -0,0 +1,57 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package terraform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+
+	log "github.com/rs/zerolog/log"
+)
+
+// Output is one value of terraform output -json
+type Output struct {
+	Sensitive bool            `json:"sensitive"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// InitOutput returns the outputs of the entrypoint's state - stdout is
+// captured instead of logged since outputs can hold credentials
+func InitOutput(terraformClientPath string, tfEntrypoint string, tfEnvs map[string]string) (map[string]Output, error) {
+	err := os.Chdir(tfEntrypoint)
+	if err != nil {
+		log.Printf("error: could not change to directory %s", tfEntrypoint)
+		return nil, err
+	}
+	err = ExecShellWithVars(tfEnvs, terraformClientPath, "init", "-force-copy")
+	if err != nil {
+		log.Printf("error: terraform init for %s failed: %s", tfEntrypoint, err)
+		return nil, err
+	}
+	defer os.RemoveAll(fmt.Sprintf("%s/.terraform/", tfEntrypoint))
+	defer os.Remove(fmt.Sprintf("%s/.terraform.lock.hcl", tfEntrypoint))
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(terraformClientPath, "output", "-json")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("terraform output for %s failed: %s: %s", tfEntrypoint, err, stderr.String())
+	}
+
+	outputs := map[string]Output{}
+	err = json.Unmarshal(stdout.Bytes(), &outputs)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing terraform output for %s: %s", tfEntrypoint, err)
+	}
+
+	return outputs, nil
+}
@@ -0,0 +1,146 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package terraform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+
+	log "github.com/rs/zerolog/log"
+)
+
+// planFile is where InitPlan saves the plan it reads the changes from
+const planFile = "kubefirst.tfplan"
+
+// Plan is a saved plan of an entrypoint, Apply applies exactly the planned
+// changes and Close removes the plan and terraform's init state
+type Plan struct {
+	Changes []PlannedChange
+
+	terraformClientPath string
+	tfEntrypoint        string
+	tfEnvs              map[string]string
+}
+
+// PlannedChange is a resource terraform plans to change and the actions it
+// plans for it, a replacement lists both delete and create
+type PlannedChange struct {
+	Address string   `json:"address"`
+	Actions []string `json:"actions"`
+}
+
+// Destroys reports whether the change deletes the resource, including when
+// it's replaced
+func (change PlannedChange) Destroys() bool {
+	for _, action := range change.Actions {
+		if action == "delete" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InitPlan plans the entrypoint without applying it and returns the saved
+// plan with the resources it changes, resources left as they are aren't
+// listed. The plan has to be closed once it's applied or discarded
+func InitPlan(terraformClientPath string, tfEntrypoint string, tfEnvs map[string]string) (*Plan, error) {
+	err := os.Chdir(tfEntrypoint)
+	if err != nil {
+		log.Printf("error: could not change to directory %s", tfEntrypoint)
+		return nil, err
+	}
+	plan := &Plan{terraformClientPath: terraformClientPath, tfEntrypoint: tfEntrypoint, tfEnvs: tfEnvs}
+
+	err = ExecShellWithVars(tfEnvs, terraformClientPath, "init", "-force-copy")
+	if err != nil {
+		log.Printf("error: terraform init for %s failed: %s", tfEntrypoint, err)
+		plan.Close()
+		return nil, err
+	}
+
+	err = ExecShellWithVars(tfEnvs, terraformClientPath, "plan", "-input=false", fmt.Sprintf("-out=%s", planFile))
+	if err != nil {
+		log.Printf("error: terraform plan for %s failed: %s", tfEntrypoint, err)
+		plan.Close()
+		return nil, err
+	}
+
+	// the plan json holds variable values, it's captured instead of logged
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(terraformClientPath, "show", "-json", planFile)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		plan.Close()
+		return nil, fmt.Errorf("terraform show for %s failed: %s: %s", tfEntrypoint, err, stderr.String())
+	}
+
+	plan.Changes, err = parsePlannedChanges(stdout.Bytes())
+	if err != nil {
+		plan.Close()
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// Apply applies the saved plan, terraform refuses it when the state changed
+// since it was planned
+func (plan *Plan) Apply() error {
+	err := os.Chdir(plan.tfEntrypoint)
+	if err != nil {
+		log.Printf("error: could not change to directory %s", plan.tfEntrypoint)
+		return err
+	}
+
+	err = ExecShellWithVars(plan.tfEnvs, plan.terraformClientPath, "apply", "-input=false", planFile)
+	if err != nil {
+		log.Printf("error: terraform apply of the plan for %s failed: %s", plan.tfEntrypoint, err)
+		return err
+	}
+
+	return nil
+}
+
+// Close removes the saved plan and terraform's init state
+func (plan *Plan) Close() {
+	os.Remove(fmt.Sprintf("%s/%s", plan.tfEntrypoint, planFile))
+	os.RemoveAll(fmt.Sprintf("%s/.terraform/", plan.tfEntrypoint))
+	os.Remove(fmt.Sprintf("%s/.terraform.lock.hcl", plan.tfEntrypoint))
+}
+
+// parsePlannedChanges reads the resource changes of terraform show -json
+func parsePlannedChanges(planJSON []byte) ([]PlannedChange, error) {
+	plan := struct {
+		ResourceChanges []struct {
+			Address string `json:"address"`
+			Change  struct {
+				Actions []string `json:"actions"`
+			} `json:"change"`
+		} `json:"resource_changes"`
+	}{}
+	err := json.Unmarshal(planJSON, &plan)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing terraform plan: %s", err)
+	}
+
+	changes := []PlannedChange{}
+	for _, resourceChange := range plan.ResourceChanges {
+		actions := resourceChange.Change.Actions
+		if len(actions) == 1 && (actions[0] == "no-op" || actions[0] == "read") {
+			continue
+		}
+		changes = append(changes, PlannedChange{Address: resourceChange.Address, Actions: actions})
+	}
+
+	return changes, nil
+}
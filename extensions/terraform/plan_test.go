@@ -0,0 +1,64 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package terraform
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParsePlannedChanges(t *testing.T) {
+	for name, test := range map[string]struct {
+		actions      []string
+		wantChanged  bool
+		wantDestroys bool
+	}{
+		"no-op":                  {actions: []string{"no-op"}},
+		"read":                   {actions: []string{"read"}},
+		"create":                 {actions: []string{"create"}, wantChanged: true},
+		"update":                 {actions: []string{"update"}, wantChanged: true},
+		"delete":                 {actions: []string{"delete"}, wantChanged: true, wantDestroys: true},
+		"replace":                {actions: []string{"delete", "create"}, wantChanged: true, wantDestroys: true},
+		"replace creating first": {actions: []string{"create", "delete"}, wantChanged: true, wantDestroys: true},
+	} {
+		actions := ""
+		for i, action := range test.actions {
+			if i > 0 {
+				actions += ","
+			}
+			actions += fmt.Sprintf("%q", action)
+		}
+		planJSON := fmt.Sprintf(`{"format_version": "1.2", "resource_changes": [{"address": "vault_policy.admin", "change": {"actions": [%s]}}]}`, actions)
+
+		changes, err := parsePlannedChanges([]byte(planJSON))
+		if err != nil {
+			t.Errorf("%s: parsePlannedChanges() = %s", name, err)
+			continue
+		}
+		if !test.wantChanged {
+			if len(changes) != 0 {
+				t.Errorf("%s: parsePlannedChanges() = %+v, want no changes", name, changes)
+			}
+			continue
+		}
+
+		want := []PlannedChange{{Address: "vault_policy.admin", Actions: test.actions}}
+		if !reflect.DeepEqual(changes, want) {
+			t.Errorf("%s: parsePlannedChanges() = %+v, want %+v", name, changes, want)
+			continue
+		}
+		if changes[0].Destroys() != test.wantDestroys {
+			t.Errorf("%s: Destroys() = %v, want %v", name, changes[0].Destroys(), test.wantDestroys)
+		}
+	}
+
+	_, err := parsePlannedChanges([]byte("not json"))
+	if err == nil {
+		t.Error("expected invalid plan json to be rejected")
+	}
+}
@@ -54,3 +54,59 @@ func InitDestroyAutoApprove(terraformClientPath string, tfEntrypoint string, tfE
 	}
 	return nil
 }
+
+// InitImport imports an existing resource into the entrypoint's state, it's
+// a no-op when the address is already managed
+func InitImport(terraformClientPath string, tfEntrypoint string, tfEnvs map[string]string, address string, id string) error {
+	err := os.Chdir(tfEntrypoint)
+	if err != nil {
+		log.Printf("error: could not change to directory %s", tfEntrypoint)
+		return err
+	}
+	err = ExecShellWithVars(tfEnvs, terraformClientPath, "init", "-force-copy")
+	if err != nil {
+		log.Printf("error: terraform init for %s failed: %s", tfEntrypoint, err)
+		return err
+	}
+
+	// state show fails for addresses that aren't in the state yet
+	err = ExecShellWithVars(tfEnvs, terraformClientPath, "state", "show", address)
+	if err == nil {
+		log.Printf("%s is already managed by %s, skipping import", address, tfEntrypoint)
+		return nil
+	}
+
+	err = ExecShellWithVars(tfEnvs, terraformClientPath, "import", address, id)
+	if err != nil {
+		log.Printf("error: terraform import of %s for %s failed: %s", address, tfEntrypoint, err)
+		return err
+	}
+
+	return nil
+}
+
+// InitReconfigure re-initializes the entrypoint's backend with the current
+// environment and lists its state, confirming the state can still be read
+func InitReconfigure(terraformClientPath string, tfEntrypoint string, tfEnvs map[string]string) error {
+	err := os.Chdir(tfEntrypoint)
+	if err != nil {
+		log.Printf("error: could not change to directory %s", tfEntrypoint)
+		return err
+	}
+	defer os.RemoveAll(fmt.Sprintf("%s/.terraform/", tfEntrypoint))
+	defer os.Remove(fmt.Sprintf("%s/.terraform.lock.hcl", tfEntrypoint))
+
+	err = ExecShellWithVars(tfEnvs, terraformClientPath, "init", "-reconfigure")
+	if err != nil {
+		log.Printf("error: terraform init for %s failed: %s", tfEntrypoint, err)
+		return err
+	}
+
+	err = ExecShellWithVars(tfEnvs, terraformClientPath, "state", "list")
+	if err != nil {
+		log.Printf("error: terraform state list for %s failed: %s", tfEntrypoint, err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// variableDeclaration matches the variable blocks of a terraform file
+var variableDeclaration = regexp.MustCompile(`(?m)^\s*variable\s+"([^"]+)"`)
+
+// DeclaredVariables returns the variables the terraform configuration in
+// tfEntrypoint declares, TF_VAR_ values of other variables are ignored by
+// terraform
+func DeclaredVariables(tfEntrypoint string) (map[string]bool, error) {
+	files, err := filepath.Glob(filepath.Join(tfEntrypoint, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	variables := map[string]bool{}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range variableDeclaration.FindAllStringSubmatch(string(content), -1) {
+			variables[match[1]] = true
+		}
+	}
+
+	return variables, nil
+}
@@ -0,0 +1,173 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package appCatalog
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	ParameterTypeString  = "string"
+	ParameterTypeInteger = "integer"
+	ParameterTypeBoolean = "boolean"
+)
+
+// RenderContext holds the cluster values available to catalog app templates
+// alongside the user supplied .Values
+type RenderContext struct {
+	ClusterName string
+	DomainName  string
+	Destination string
+	// IngressClass of the cluster's ingress controller
+	IngressClass string
+	Project      string
+	Values       map[string]interface{}
+}
+
+// Apps returns the installable catalog apps sorted by name
+func Apps() []pkgtypes.CatalogAppTemplate {
+	apps := make([]pkgtypes.CatalogAppTemplate, 0, len(catalogApps))
+	for _, app := range catalogApps {
+		apps = append(apps, app)
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Name < apps[j].Name })
+
+	return apps
+}
+
+// Get returns the catalog app called name
+func Get(name string) (pkgtypes.CatalogAppTemplate, error) {
+	app, ok := catalogApps[name]
+	if !ok {
+		names := make([]string, 0, len(catalogApps))
+		for n := range catalogApps {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return pkgtypes.CatalogAppTemplate{}, fmt.Errorf("unknown catalog app %s: must be one of %v", name, names)
+	}
+
+	return app, nil
+}
+
+// ValidateValues checks user supplied values against the app's parameters and
+// returns them merged with the parameter defaults
+func ValidateValues(app pkgtypes.CatalogAppTemplate, values map[string]interface{}) (map[string]interface{}, error) {
+	known := map[string]bool{}
+	merged := map[string]interface{}{}
+
+	for _, param := range app.Parameters {
+		known[param.Name] = true
+
+		value, ok := values[param.Name]
+		if !ok || value == nil {
+			if param.Required && param.Default == nil {
+				return nil, fmt.Errorf("catalog app %s: value %s is required", app.Name, param.Name)
+			}
+			merged[param.Name] = param.Default
+			continue
+		}
+
+		value, err := validateValue(param, value)
+		if err != nil {
+			return nil, fmt.Errorf("catalog app %s: invalid value for %s: %s", app.Name, param.Name, err)
+		}
+		merged[param.Name] = value
+	}
+
+	for name := range values {
+		if !known[name] {
+			return nil, fmt.Errorf("catalog app %s: unknown value %s", app.Name, name)
+		}
+	}
+
+	return merged, nil
+}
+
+// validateValue checks a single value against its parameter and normalizes
+// json numbers to integers
+func validateValue(param pkgtypes.CatalogAppParameter, value interface{}) (interface{}, error) {
+	switch param.Type {
+	case ParameterTypeString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %v", value)
+		}
+		if strings.ContainsAny(s, "\n\r") {
+			return nil, fmt.Errorf("must be a single line")
+		}
+		if len(param.Enum) != 0 && !pkg.FindStringInSlice(param.Enum, s) {
+			return nil, fmt.Errorf("%q must be one of %v", s, param.Enum)
+		}
+		if param.Pattern != "" && !regexp.MustCompile(param.Pattern).MatchString(s) {
+			return nil, fmt.Errorf("%q does not match %s", s, param.Pattern)
+		}
+		return s, nil
+	case ParameterTypeInteger:
+		switch n := value.(type) {
+		case int:
+			return n, nil
+		case int64:
+			return int(n), nil
+		case float64:
+			if n != math.Trunc(n) {
+				return nil, fmt.Errorf("expected an integer, got %v", n)
+			}
+			return int(n), nil
+		}
+		return nil, fmt.Errorf("expected an integer, got %v", value)
+	case ParameterTypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected a boolean, got %v", value)
+		}
+		return b, nil
+	}
+
+	return nil, fmt.Errorf("unsupported parameter type %s", param.Type)
+}
+
+// Render executes the app's template with ctx and makes sure the result is an
+// argocd Application manifest
+func Render(app pkgtypes.CatalogAppTemplate, ctx RenderContext) ([]byte, error) {
+	tmpl, err := template.New(app.Name).Option("missingkey=error").Parse(app.Template)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing catalog app %s template: %s", app.Name, err)
+	}
+
+	var out bytes.Buffer
+	err = tmpl.Execute(&out, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering catalog app %s template: %s", app.Name, err)
+	}
+
+	var manifest struct {
+		Kind     string `yaml:"kind"`
+		Metadata struct {
+			Name string `yaml:"name"`
+		} `yaml:"metadata"`
+	}
+	err = yaml.Unmarshal(out.Bytes(), &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("catalog app %s rendered invalid yaml: %s", app.Name, err)
+	}
+	if manifest.Kind != "Application" || manifest.Metadata.Name == "" {
+		return nil, fmt.Errorf("catalog app %s did not render an argocd Application", app.Name)
+	}
+
+	return out.Bytes(), nil
+}
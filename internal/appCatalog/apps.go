@@ -0,0 +1,110 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package appCatalog
+
+import pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+
+// namespacePattern matches a valid kubernetes namespace name
+const namespacePattern = `^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+
+// catalogApps are the installable catalog apps keyed by name
+var catalogApps = map[string]pkgtypes.CatalogAppTemplate{
+	"podinfo": {
+		Name:        "podinfo",
+		DisplayName: "Podinfo",
+		Description: "A tiny web application to demonstrate and test deployments on Kubernetes.",
+		Category:    "Example",
+		Parameters: []pkgtypes.CatalogAppParameter{
+			{Name: "version", Description: "Helm chart version", Type: ParameterTypeString, Default: "6.5.4", Pattern: `^[0-9]+\.[0-9]+\.[0-9]+$`},
+			{Name: "namespace", Description: "Namespace to install into", Type: ParameterTypeString, Default: "podinfo", Pattern: namespacePattern},
+			{Name: "replicas", Description: "Number of replicas", Type: ParameterTypeInteger, Default: 1},
+			{Name: "ingress", Description: "Expose podinfo on podinfo.<domain>", Type: ParameterTypeBoolean, Default: false},
+		},
+		Template: `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: podinfo
+  namespace: argocd
+  annotations:
+    argocd.argoproj.io/sync-wave: '100'
+  finalizers:
+    - resources-finalizer.argocd.argoproj.io
+spec:
+  project: {{ .Project }}
+  source:
+    repoURL: https://stefanprodan.github.io/podinfo
+    chart: podinfo
+    targetRevision: {{ .Values.version }}
+    helm:
+      values: |
+        replicaCount: {{ .Values.replicas }}
+        ingress:
+          enabled: {{ .Values.ingress }}
+          className: {{ .IngressClass }}
+          hosts:
+            - host: podinfo.{{ .DomainName }}
+              paths:
+                - path: /
+                  pathType: ImplementationSpecific
+  destination:
+    name: {{ .Destination }}
+    namespace: {{ .Values.namespace }}
+  syncPolicy:
+    automated:
+      prune: true
+      selfHeal: true
+    syncOptions:
+      - CreateNamespace=true
+`,
+	},
+	"redis": {
+		Name:        "redis",
+		DisplayName: "Redis",
+		Description: "An in-memory data store used as a database, cache and message broker.",
+		Category:    "Database",
+		Parameters: []pkgtypes.CatalogAppParameter{
+			{Name: "version", Description: "Helm chart version", Type: ParameterTypeString, Default: "18.6.1", Pattern: `^[0-9]+\.[0-9]+\.[0-9]+$`},
+			{Name: "namespace", Description: "Namespace to install into", Type: ParameterTypeString, Default: "redis", Pattern: namespacePattern},
+			{Name: "architecture", Description: "Redis architecture", Type: ParameterTypeString, Default: "standalone", Enum: []string{"standalone", "replication"}},
+			{Name: "persistence_size", Description: "Size of the data volume", Type: ParameterTypeString, Default: "8Gi", Pattern: `^[0-9]+(Mi|Gi|Ti)$`},
+			{Name: "auth", Description: "Require a password, stored in the redis secret", Type: ParameterTypeBoolean, Default: true},
+		},
+		Template: `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: redis
+  namespace: argocd
+  annotations:
+    argocd.argoproj.io/sync-wave: '100'
+  finalizers:
+    - resources-finalizer.argocd.argoproj.io
+spec:
+  project: {{ .Project }}
+  source:
+    repoURL: https://charts.bitnami.com/bitnami
+    chart: redis
+    targetRevision: {{ .Values.version }}
+    helm:
+      values: |
+        architecture: {{ .Values.architecture }}
+        auth:
+          enabled: {{ .Values.auth }}
+        master:
+          persistence:
+            size: {{ .Values.persistence_size }}
+  destination:
+    name: {{ .Destination }}
+    namespace: {{ .Values.namespace }}
+  syncPolicy:
+    automated:
+      prune: true
+      selfHeal: true
+    syncOptions:
+      - CreateNamespace=true
+`,
+	},
+}
@@ -144,8 +144,8 @@ func RefreshApplication(host string, token string, appName string) error {
 	return nil
 }
 
-// returnArgoCDApplicationStatus returns the status details of a given ArgoCD application
-func returnArgoCDApplicationStatus(clientset kubernetes.Interface, applicationName string) (health.HealthStatusCode, error) {
+// GetApplication returns the ArgoCD application object for applicationName
+func GetApplication(clientset kubernetes.Interface, applicationName string) (*v1alpha1.Application, error) {
 	// Call the API to return an ArgoCD application object
 	data, err := clientset.CoreV1().RESTClient().Get().
 		AbsPath(fmt.Sprintf("/apis/%s", ArgoCDAPIVersion)).
@@ -154,14 +154,22 @@ func returnArgoCDApplicationStatus(clientset kubernetes.Interface, applicationNa
 		Name(applicationName).
 		DoRaw(context.Background())
 	if err != nil {
-		log.Error().Msgf("error retrieving argocd applications: %s", err)
-		return health.HealthStatusUnknown, err
+		return nil, err
 	}
 
-	// Unmarshal JSON API response to map[string]interface{}
 	var resp *v1alpha1.Application
 	if err := json.Unmarshal(data, &resp); err != nil {
-		log.Error().Msgf("error converting argocd application data: %s", err)
+		return nil, fmt.Errorf("error converting argocd application data: %s", err)
+	}
+
+	return resp, nil
+}
+
+// returnArgoCDApplicationStatus returns the status details of a given ArgoCD application
+func returnArgoCDApplicationStatus(clientset kubernetes.Interface, applicationName string) (health.HealthStatusCode, error) {
+	resp, err := GetApplication(clientset, applicationName)
+	if err != nil {
+		log.Error().Msgf("error retrieving argocd applications: %s", err)
 		return health.HealthStatusUnknown, err
 	}
 	status := resp.Status.Health.Status
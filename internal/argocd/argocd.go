@@ -18,6 +18,7 @@ import (
 	v1alpha1ArgocdApplication "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
 	pkg "github.com/kubefirst/kubefirst-api/internal"
 	"github.com/kubefirst/kubefirst-api/internal/argocdModel"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -202,3 +203,21 @@ func GetArgoCDApplicationObject(gitopsRepoURL, registryPath string) *v1alpha1Arg
 		},
 	}
 }
+
+// SetSyncPolicy replaces the automated sync of an application with the
+// cluster's sync policy, manual applications only sync when promoted
+func SetSyncPolicy(app *v1alpha1ArgocdApplication.Application, policy pkgtypes.ArgoCDSyncPolicy) {
+	if app.Spec.SyncPolicy == nil {
+		app.Spec.SyncPolicy = &v1alpha1ArgocdApplication.SyncPolicy{}
+	}
+
+	if policy.Mode == pkgtypes.SyncModeManual {
+		app.Spec.SyncPolicy.Automated = nil
+		return
+	}
+
+	app.Spec.SyncPolicy.Automated = &v1alpha1ArgocdApplication.SyncPolicyAutomated{
+		Prune:    !policy.DisablePrune,
+		SelfHeal: !policy.DisableSelfHeal,
+	}
+}
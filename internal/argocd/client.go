@@ -0,0 +1,93 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+
+	v1alpha1ArgocdApplication "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	argocdapi "github.com/argoproj/argo-cd/v2/pkg/client/clientset/versioned"
+	"github.com/kubefirst/kubefirst-api/internal/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Client is the part of argocd the controller drives while bootstrapping a
+// cluster, NewClient talks to the cluster and tests substitute a fake
+type Client interface {
+	// InitialAdminPassword reads the password argocd generated for admin
+	InitialAdminPassword() (string, error)
+	// AuthToken creates an api session token for the user
+	AuthToken(username string, password string) (string, error)
+	// RestartApplicationSetController makes the applicationset controller
+	// pick up the repository credentials created since it started
+	RestartApplicationSetController() error
+	// CreateApplication creates an Application in the argocd namespace
+	CreateApplication(app *v1alpha1ArgocdApplication.Application) (*v1alpha1ArgocdApplication.Application, error)
+}
+
+// kubernetesClient drives argocd through the kubernetes api of its cluster
+type kubernetesClient struct {
+	kcfg           *k8s.KubernetesClient
+	kubeconfigPath string
+	argocdClient   argocdapi.Interface
+}
+
+// NewClient returns a Client for the argocd installed in the cluster of kcfg,
+// kubeconfigPath is used by the kubectl calls
+func NewClient(kcfg *k8s.KubernetesClient, kubeconfigPath string) (Client, error) {
+	argocdClient, err := argocdapi.NewForConfig(kcfg.RestConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kubernetesClient{
+		kcfg:           kcfg,
+		kubeconfigPath: kubeconfigPath,
+		argocdClient:   argocdClient,
+	}, nil
+}
+
+func (c *kubernetesClient) InitialAdminPassword() (string, error) {
+	ArgocdSecretClient = c.kcfg.Clientset.CoreV1().Secrets("argocd")
+
+	password := k8s.GetSecretValue(ArgocdSecretClient, "argocd-initial-admin-secret", "password")
+	if password == "" {
+		return "", fmt.Errorf("argocd password not found in secret")
+	}
+
+	return password, nil
+}
+
+func (c *kubernetesClient) AuthToken(username string, password string) (string, error) {
+	argoCDStopChannel := make(chan struct{}, 1)
+	defer func() {
+		close(argoCDStopChannel)
+	}()
+	k8s.OpenPortForwardPodWrapper(
+		c.kcfg.Clientset,
+		c.kcfg.RestConfig,
+		"argocd-server",
+		"argocd",
+		8080,
+		8080,
+		argoCDStopChannel,
+	)
+
+	return GetArgoCDToken(username, password)
+}
+
+func (c *kubernetesClient) RestartApplicationSetController() error {
+	cmdStr := fmt.Sprintf("kubectl --kubeconfig=%s rollout restart -n argocd deploy/argocd-applicationset-controller", c.kubeconfigPath)
+
+	return exec.Command("/bin/sh", "-c", cmdStr).Run()
+}
+
+func (c *kubernetesClient) CreateApplication(app *v1alpha1ArgocdApplication.Application) (*v1alpha1ArgocdApplication.Application, error) {
+	return c.argocdClient.ArgoprojV1alpha1().Applications("argocd").Create(context.Background(), app, metav1.CreateOptions{})
+}
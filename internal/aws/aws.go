@@ -13,6 +13,7 @@ import (
 	"net"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -199,7 +200,7 @@ func (conf *AWSConfiguration) TestHostedZoneLivenessWithTxtRecords(hostedZoneNam
 										Value: aws.String(strconv.Quote(validationRecordValue)),
 									},
 								},
-								TTL:           aws.Int64(10),
+								TTL:           aws.Int64(conf.recordTTL()),
 								Weight:        aws.Int64(100),
 								SetIdentifier: aws.String("CREATE sanity check for kubefirst installation"),
 							},
@@ -273,16 +274,28 @@ func NewAwsV2(region string) aws.Config {
 	return awsClient
 }
 
-func NewAwsV3(region string, accessKeyID string, secretAccessKey string, sessionToken string) aws.Config {
-	awsClient, err := config.LoadDefaultConfig(
-		context.Background(),
+// EndpointURLVar is the environment variable overriding the aws endpoint url
+// of definitions that don't set aws_auth.api_url
+const EndpointURLVar = "AWS_ENDPOINT_URL"
+
+func NewAwsV3(region string, accessKeyID string, secretAccessKey string, sessionToken string, endpointURL string) aws.Config {
+	opts := []func(*config.LoadOptions) error{
 		config.WithRegion(region),
 		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
 			accessKeyID,
 			secretAccessKey,
 			sessionToken,
 		)),
-	)
+	}
+
+	if endpointURL == "" {
+		endpointURL = os.Getenv(EndpointURLVar)
+	}
+	if endpointURL != "" {
+		opts = append(opts, config.WithEndpointResolverWithOptions(endpointResolver(endpointURL)))
+	}
+
+	awsClient, err := config.LoadDefaultConfig(context.Background(), opts...)
 	if err != nil {
 		log.Error().Msg("unable to create aws client")
 	}
@@ -290,6 +303,22 @@ func NewAwsV3(region string, accessKeyID string, secretAccessKey string, session
 	return awsClient
 }
 
+// endpointResolver sends every service to endpointURL, a {service} in it is
+// replaced by the lowercased service id without spaces (ec2, route53, s3) so
+// per-service endpoints such as https://{service}.us-iso-east-1.c2s.ic.gov
+// can be given as one url
+func endpointResolver(endpointURL string) aws.EndpointResolverWithOptionsFunc {
+	return func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+		serviceName := strings.ToLower(strings.ReplaceAll(service, " ", ""))
+
+		return aws.Endpoint{
+			URL:           strings.ReplaceAll(endpointURL, "{service}", serviceName),
+			SigningRegion: region,
+			Source:        aws.EndpointSourceCustom,
+		}, nil
+	}
+}
+
 // GetRegions lists all available regions
 func (conf *AWSConfiguration) GetRegions(region string) ([]string, error) {
 	var regionList []string
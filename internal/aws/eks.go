@@ -0,0 +1,47 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+)
+
+// ListKubernetesVersions returns the kubernetes versions new eks clusters can
+// be created with - eks has no call listing them, the cluster versions the
+// vpc-cni addon, installed on every cluster, is published for are used instead
+func (conf *AWSConfiguration) ListKubernetesVersions() ([]string, error) {
+	eksClient := eks.NewFromConfig(conf.Config)
+
+	versions := []string{}
+	paginator := eks.NewDescribeAddonVersionsPaginator(eksClient, &eks.DescribeAddonVersionsInput{
+		AddonName: aws.String("vpc-cni"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("error listing eks addon versions: %s", err)
+		}
+
+		for _, addon := range page.Addons {
+			for _, addonVersion := range addon.AddonVersions {
+				for _, compatibility := range addonVersion.Compatibilities {
+					clusterVersion := aws.ToString(compatibility.ClusterVersion)
+					if clusterVersion != "" && !pkg.FindStringInSlice(versions, clusterVersion) {
+						versions = append(versions, clusterVersion)
+					}
+				}
+			}
+		}
+	}
+
+	return versions, nil
+}
@@ -0,0 +1,171 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package aws
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/rs/zerolog/log"
+)
+
+// iamPolicyDocument is the subset of an iam policy document needed to edit
+// role trust policies
+type iamPolicyDocument struct {
+	Version   string                   `json:"Version"`
+	Statement []map[string]interface{} `json:"Statement"`
+}
+
+// GetEKSOIDCIssuer returns the oidc issuer url of an eks cluster
+func (conf *AWSConfiguration) GetEKSOIDCIssuer(clusterName string) (string, error) {
+	eksClient := eks.NewFromConfig(conf.Config)
+
+	cluster, err := eksClient.DescribeCluster(context.Background(), &eks.DescribeClusterInput{
+		Name: aws.String(clusterName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error describing eks cluster %s: %s", clusterName, err)
+	}
+
+	if cluster.Cluster.Identity == nil || cluster.Cluster.Identity.Oidc == nil || cluster.Cluster.Identity.Oidc.Issuer == nil {
+		return "", fmt.Errorf("eks cluster %s does not have an oidc issuer", clusterName)
+	}
+
+	return *cluster.Cluster.Identity.Oidc.Issuer, nil
+}
+
+// EnsureOIDCProvider creates an iam oidc provider for issuerURL if one does
+// not already exist and returns its arn
+func (conf *AWSConfiguration) EnsureOIDCProvider(issuerURL string) (string, error) {
+	iamClient := iam.NewFromConfig(conf.Config)
+	issuerHost := strings.TrimPrefix(issuerURL, "https://")
+
+	providers, err := iamClient.ListOpenIDConnectProviders(context.Background(), &iam.ListOpenIDConnectProvidersInput{})
+	if err != nil {
+		return "", fmt.Errorf("error listing iam oidc providers: %s", err)
+	}
+	for _, provider := range providers.OpenIDConnectProviderList {
+		if strings.HasSuffix(aws.ToString(provider.Arn), fmt.Sprintf("oidc-provider/%s", issuerHost)) {
+			log.Info().Msgf("iam oidc provider for %s already exists", issuerHost)
+			return aws.ToString(provider.Arn), nil
+		}
+	}
+
+	thumbprint, err := issuerThumbprint(issuerURL)
+	if err != nil {
+		return "", err
+	}
+
+	provider, err := iamClient.CreateOpenIDConnectProvider(context.Background(), &iam.CreateOpenIDConnectProviderInput{
+		Url:            aws.String(issuerURL),
+		ClientIDList:   []string{"sts.amazonaws.com"},
+		ThumbprintList: []string{thumbprint},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error creating iam oidc provider for %s: %s", issuerHost, err)
+	}
+	log.Info().Msgf("created iam oidc provider for %s", issuerHost)
+
+	return aws.ToString(provider.OpenIDConnectProviderArn), nil
+}
+
+// AddWebIdentityTrust allows the kubernetes service account namespace/name to
+// assume roleArn through the oidc provider, existing trust is kept
+func (conf *AWSConfiguration) AddWebIdentityTrust(roleArn string, providerArn string, issuerURL string, namespace string, serviceAccount string) error {
+	iamClient := iam.NewFromConfig(conf.Config)
+	roleName := roleArn[strings.LastIndex(roleArn, "/")+1:]
+
+	role, err := conf.GetIamRole(roleName)
+	if err != nil {
+		return fmt.Errorf("error getting iam role %s: %s", roleName, err)
+	}
+
+	rawPolicy, err := url.QueryUnescape(aws.ToString(role.Role.AssumeRolePolicyDocument))
+	if err != nil {
+		return fmt.Errorf("error decoding trust policy for iam role %s: %s", roleName, err)
+	}
+
+	var policy iamPolicyDocument
+	err = json.Unmarshal([]byte(rawPolicy), &policy)
+	if err != nil {
+		return fmt.Errorf("error parsing trust policy for iam role %s: %s", roleName, err)
+	}
+
+	issuerHost := strings.TrimPrefix(issuerURL, "https://")
+	subject := fmt.Sprintf("system:serviceaccount:%s:%s", namespace, serviceAccount)
+	sid := strings.NewReplacer("-", "", "_", "", ".", "").Replace(fmt.Sprintf("kubefirst%s%s", namespace, serviceAccount))
+
+	for _, statement := range policy.Statement {
+		if statement["Sid"] == sid {
+			log.Info().Msgf("iam role %s already trusts %s", roleName, subject)
+			return nil
+		}
+	}
+
+	policy.Statement = append(policy.Statement, map[string]interface{}{
+		"Sid":    sid,
+		"Effect": "Allow",
+		"Principal": map[string]string{
+			"Federated": providerArn,
+		},
+		"Action": "sts:AssumeRoleWithWebIdentity",
+		"Condition": map[string]interface{}{
+			"StringEquals": map[string]string{
+				fmt.Sprintf("%s:sub", issuerHost): subject,
+				fmt.Sprintf("%s:aud", issuerHost): "sts.amazonaws.com",
+			},
+		},
+	})
+
+	policyBytes, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+
+	_, err = iamClient.UpdateAssumeRolePolicy(context.Background(), &iam.UpdateAssumeRolePolicyInput{
+		RoleName:       aws.String(roleName),
+		PolicyDocument: aws.String(string(policyBytes)),
+	})
+	if err != nil {
+		return fmt.Errorf("error updating trust policy for iam role %s: %s", roleName, err)
+	}
+	log.Info().Msgf("iam role %s now trusts %s", roleName, subject)
+
+	return nil
+}
+
+// issuerThumbprint returns the sha1 fingerprint of the root certificate
+// presented by the oidc issuer, as required by iam
+func issuerThumbprint(issuerURL string) (string, error) {
+	u, err := url.Parse(issuerURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid oidc issuer url %s: %s", issuerURL, err)
+	}
+
+	conn, err := tls.Dial("tcp", fmt.Sprintf("%s:443", u.Hostname()), &tls.Config{})
+	if err != nil {
+		return "", fmt.Errorf("error connecting to oidc issuer %s: %s", u.Hostname(), err)
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return "", fmt.Errorf("oidc issuer %s presented no certificates", u.Hostname())
+	}
+	fingerprint := sha1.Sum(certs[len(certs)-1].Raw)
+
+	return hex.EncodeToString(fingerprint[:]), nil
+}
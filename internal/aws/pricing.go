@@ -0,0 +1,67 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package aws
+
+import (
+	"fmt"
+	"strconv"
+
+	awsv1 "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/pricing"
+)
+
+// pricingRegion is the region the price list api is served from
+const pricingRegion = "us-east-1"
+
+// GetInstanceHourlyPrice returns the on demand linux price per hour in usd
+// of an ec2 instance type in region
+func (conf *AWSConfiguration) GetInstanceHourlyPrice(instanceType string, region string) (float64, error) {
+	sess, err := conf.sessionV1(pricingRegion)
+	if err != nil {
+		return 0, err
+	}
+
+	filter := func(field string, value string) *pricing.Filter {
+		return &pricing.Filter{Type: awsv1.String(pricing.FilterTypeTermMatch), Field: awsv1.String(field), Value: awsv1.String(value)}
+	}
+
+	output, err := pricing.New(sess).GetProducts(&pricing.GetProductsInput{
+		ServiceCode: awsv1.String("AmazonEC2"),
+		Filters: []*pricing.Filter{
+			filter("instanceType", instanceType),
+			filter("regionCode", region),
+			filter("operatingSystem", "Linux"),
+			filter("tenancy", "Shared"),
+			filter("preInstalledSw", "NA"),
+			filter("capacitystatus", "Used"),
+		},
+		MaxResults: awsv1.Int64(1),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error getting the price of %s in %s: %s", instanceType, region, err)
+	}
+
+	for _, product := range output.PriceList {
+		terms, _ := product["terms"].(map[string]interface{})
+		onDemand, _ := terms["OnDemand"].(map[string]interface{})
+		for _, term := range onDemand {
+			termFields, _ := term.(map[string]interface{})
+			dimensions, _ := termFields["priceDimensions"].(map[string]interface{})
+			for _, dimension := range dimensions {
+				dimensionFields, _ := dimension.(map[string]interface{})
+				pricePerUnit, _ := dimensionFields["pricePerUnit"].(map[string]interface{})
+				usd, ok := pricePerUnit["USD"].(string)
+				if !ok {
+					continue
+				}
+				return strconv.ParseFloat(usd, 64)
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("no on demand price found for %s in %s", instanceType, region)
+}
@@ -0,0 +1,154 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/rs/zerolog/log"
+)
+
+// TaggedResource is a resource carrying a tag, Value is the tag's value
+type TaggedResource struct {
+	ID    string
+	Name  string
+	Value string
+}
+
+// ListTaggedClusters returns the eks clusters of the region carrying the
+// tag key
+func (conf *AWSConfiguration) ListTaggedClusters(tagKey string) ([]TaggedResource, error) {
+	eksClient := eks.NewFromConfig(conf.Config)
+
+	clusters := []TaggedResource{}
+	paginator := eks.NewListClustersPaginator(eksClient, &eks.ListClustersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("error listing eks clusters: %s", err)
+		}
+		for _, name := range page.Clusters {
+			cluster, err := eksClient.DescribeCluster(context.Background(), &eks.DescribeClusterInput{
+				Name: aws.String(name),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("error describing eks cluster %s: %s", name, err)
+			}
+			if value, ok := cluster.Cluster.Tags[tagKey]; ok {
+				clusters = append(clusters, TaggedResource{ID: aws.ToString(cluster.Cluster.Arn), Name: name, Value: value})
+			}
+		}
+	}
+
+	return clusters, nil
+}
+
+// ListTaggedBuckets returns the s3 buckets of the region carrying the tag
+// key. Buckets are listed for the whole account, so each bucket's location
+// is checked before its tags, buckets whose location or tags can't be read
+// are skipped with a warning
+func (conf *AWSConfiguration) ListTaggedBuckets(tagKey string) ([]TaggedResource, error) {
+	s3Client := s3.NewFromConfig(conf.Config)
+
+	output, err := s3Client.ListBuckets(context.Background(), &s3.ListBucketsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing s3 buckets: %s", err)
+	}
+
+	buckets := []TaggedResource{}
+	for _, bucket := range output.Buckets {
+		name := aws.ToString(bucket.Name)
+		location, err := s3Client.GetBucketLocation(context.Background(), &s3.GetBucketLocationInput{
+			Bucket: aws.String(name),
+		})
+		if err != nil {
+			log.Warn().Msgf("skipping s3 bucket %s, its location can't be read: %s", name, err)
+			continue
+		}
+		if bucketRegion(string(location.LocationConstraint)) != conf.Config.Region {
+			continue
+		}
+
+		tagging, err := s3Client.GetBucketTagging(context.Background(), &s3.GetBucketTaggingInput{
+			Bucket: aws.String(name),
+		})
+		if err != nil {
+			var apiErr smithy.APIError
+			if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchTagSet" {
+				continue
+			}
+			log.Warn().Msgf("skipping s3 bucket %s, its tags can't be read: %s", name, err)
+			continue
+		}
+		for _, tag := range tagging.TagSet {
+			if aws.ToString(tag.Key) == tagKey {
+				buckets = append(buckets, TaggedResource{ID: name, Name: name, Value: aws.ToString(tag.Value)})
+			}
+		}
+	}
+
+	return buckets, nil
+}
+
+// bucketRegion returns the region of an s3 location constraint, buckets in
+// us-east-1 have none and old eu-west-1 buckets report EU
+func bucketRegion(locationConstraint string) string {
+	switch locationConstraint {
+	case "":
+		return "us-east-1"
+	case "EU":
+		return "eu-west-1"
+	}
+
+	return locationConstraint
+}
+
+// ListTaggedAddresses returns the elastic ips of the region carrying the tag
+// key
+func (conf *AWSConfiguration) ListTaggedAddresses(tagKey string) ([]TaggedResource, error) {
+	ec2Client := ec2.NewFromConfig(conf.Config)
+
+	output, err := ec2Client.DescribeAddresses(context.Background(), &ec2.DescribeAddressesInput{
+		Filters: []ec2Types.Filter{{Name: aws.String("tag-key"), Values: []string{tagKey}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing elastic ips: %s", err)
+	}
+
+	addresses := []TaggedResource{}
+	for _, address := range output.Addresses {
+		for _, tag := range address.Tags {
+			if aws.ToString(tag.Key) == tagKey {
+				addresses = append(addresses, TaggedResource{ID: aws.ToString(address.AllocationId), Name: aws.ToString(address.PublicIp), Value: aws.ToString(tag.Value)})
+			}
+		}
+	}
+
+	return addresses, nil
+}
+
+// ReleaseAddress releases an elastic ip that is no longer associated
+func (conf *AWSConfiguration) ReleaseAddress(allocationID string) error {
+	ec2Client := ec2.NewFromConfig(conf.Config)
+
+	_, err := ec2Client.ReleaseAddress(context.Background(), &ec2.ReleaseAddressInput{
+		AllocationId: aws.String(allocationID),
+	})
+	if err != nil {
+		return fmt.Errorf("error releasing elastic ip %s: %s", allocationID, err)
+	}
+
+	return nil
+}
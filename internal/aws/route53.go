@@ -69,7 +69,7 @@ func (conf *AWSConfiguration) TestHostedZoneLiveness(hostedZoneName string) bool
 									Value: aws.String(strconv.Quote(route53RecordValue)),
 								},
 							},
-							TTL:           aws.Int64(10),
+							TTL:           aws.Int64(conf.recordTTL()),
 							Weight:        aws.Int64(100),
 							SetIdentifier: aws.String("CREATE liveness check for kubefirst installation"),
 						},
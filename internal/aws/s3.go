@@ -7,8 +7,10 @@ See the LICENSE file for more details.
 package aws
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -66,17 +68,137 @@ func (conf *AWSConfiguration) CreateBucket(bucketName string) (*s3.CreateBucketO
 	return bucket, nil
 }
 
-// DeleteBucket
+// SetBucketEncryption enables SSE-KMS default encryption on a bucket using the
+// provided customer managed key
+func (conf *AWSConfiguration) SetBucketEncryption(bucketName string, kmsKeyID string) error {
+	s3Client := s3.NewFromConfig(conf.Config)
+
+	log.Info().Msgf("enabling sse-kms encryption on s3 bucket %s", bucketName)
+	_, err := s3Client.PutBucketEncryption(context.Background(), &s3.PutBucketEncryptionInput{
+		Bucket: aws.String(bucketName),
+		ServerSideEncryptionConfiguration: &s3Types.ServerSideEncryptionConfiguration{
+			Rules: []s3Types.ServerSideEncryptionRule{
+				{
+					ApplyServerSideEncryptionByDefault: &s3Types.ServerSideEncryptionByDefault{
+						SSEAlgorithm:   s3Types.ServerSideEncryptionAwsKms,
+						KMSMasterKeyID: aws.String(kmsKeyID),
+					},
+					BucketKeyEnabled: true,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error enabling encryption on s3 bucket %s: %s", bucketName, err)
+	}
+
+	return nil
+}
+
+// TagBucket sets the tags of an s3 bucket
+func (conf *AWSConfiguration) TagBucket(bucketName string, tags map[string]string) error {
+	s3Client := s3.NewFromConfig(conf.Config)
+
+	tagSet := []s3Types.Tag{}
+	for key, value := range tags {
+		tagSet = append(tagSet, s3Types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+
+	log.Info().Msgf("tagging s3 bucket %s", bucketName)
+	_, err := s3Client.PutBucketTagging(context.Background(), &s3.PutBucketTaggingInput{
+		Bucket:  aws.String(bucketName),
+		Tagging: &s3Types.Tagging{TagSet: tagSet},
+	})
+	if err != nil {
+		return fmt.Errorf("error tagging s3 bucket %s: %s", bucketName, err)
+	}
+
+	return nil
+}
+
+// VerifyBucketAccess confirms an existing bucket can be reached with the
+// configured credentials
+func (conf *AWSConfiguration) VerifyBucketAccess(bucketName string) error {
+	s3Client := s3.NewFromConfig(conf.Config)
+
+	log.Info().Msgf("verifying access to existing s3 bucket %s", bucketName)
+	_, err := s3Client.HeadBucket(context.Background(), &s3.HeadBucketInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		return fmt.Errorf("s3 bucket %s is missing or inaccessible: %s", bucketName, err)
+	}
+
+	return nil
+}
+
+// ProbeBucket writes, reads back and deletes an object in a bucket to
+// confirm the configured credentials can store state in it
+func (conf *AWSConfiguration) ProbeBucket(bucketName string, key string) error {
+	s3Client := s3.NewFromConfig(conf.Config)
+	body := []byte(key)
+
+	log.Info().Msgf("probing s3 bucket %s", bucketName)
+	_, err := s3Client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(body),
+	})
+	if err != nil {
+		return fmt.Errorf("error writing to s3 bucket %s: %s", bucketName, err)
+	}
+
+	readErr := func() error {
+		obj, err := s3Client.GetObject(context.Background(), &s3.GetObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return err
+		}
+		defer obj.Body.Close()
+
+		got, err := io.ReadAll(obj.Body)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(got, body) {
+			return fmt.Errorf("the object read back differs from the one written")
+		}
+		return nil
+	}()
+
+	// the probe object is removed even when it couldn't be read back
+	_, err = s3Client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if readErr != nil {
+		return fmt.Errorf("error reading from s3 bucket %s: %s", bucketName, readErr)
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting from s3 bucket %s: %s", bucketName, err)
+	}
+
+	return nil
+}
+
+// DeleteBucket deletes the bucket with every object version and delete
+// marker it holds, s3 only deletes empty buckets
 func (conf *AWSConfiguration) DeleteBucket(bucketName string) error {
 	s3Client := s3.NewFromConfig(conf.Config)
 
-	// Create bucket
+	err := emptyBucket(s3Client, bucketName)
+	if err != nil {
+		return err
+	}
+
 	log.Info().Msgf("deleting s3 bucket %s", bucketName)
 	s3DeleteBucketInput := &s3.DeleteBucketInput{
 		Bucket: aws.String(bucketName),
 	}
 
-	_, err := s3Client.DeleteBucket(context.Background(), s3DeleteBucketInput)
+	_, err = s3Client.DeleteBucket(context.Background(), s3DeleteBucketInput)
 	if err != nil {
 		return fmt.Errorf("error deleting s3 bucket %s: %s", bucketName, err)
 	}
@@ -84,6 +206,45 @@ func (conf *AWSConfiguration) DeleteBucket(bucketName string) error {
 	return nil
 }
 
+// emptyBucket deletes every object version and delete marker of the bucket,
+// objects of unversioned buckets are listed with the null version
+func emptyBucket(s3Client *s3.Client, bucketName string) error {
+	input := &s3.ListObjectVersionsInput{Bucket: aws.String(bucketName)}
+	for {
+		output, err := s3Client.ListObjectVersions(context.Background(), input)
+		if err != nil {
+			return fmt.Errorf("error listing the objects of s3 bucket %s: %s", bucketName, err)
+		}
+
+		objects := []s3Types.ObjectIdentifier{}
+		for _, version := range output.Versions {
+			objects = append(objects, s3Types.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+		}
+		for _, marker := range output.DeleteMarkers {
+			objects = append(objects, s3Types.ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+		}
+		// a listed page holds at most the 1000 objects DeleteObjects takes
+		if len(objects) > 0 {
+			deleted, err := s3Client.DeleteObjects(context.Background(), &s3.DeleteObjectsInput{
+				Bucket: aws.String(bucketName),
+				Delete: &s3Types.Delete{Objects: objects, Quiet: true},
+			})
+			if err != nil {
+				return fmt.Errorf("error deleting the objects of s3 bucket %s: %s", bucketName, err)
+			}
+			if len(deleted.Errors) > 0 {
+				return fmt.Errorf("error deleting object %s of s3 bucket %s: %s", aws.ToString(deleted.Errors[0].Key), bucketName, aws.ToString(deleted.Errors[0].Message))
+			}
+		}
+
+		if !output.IsTruncated {
+			return nil
+		}
+		input.KeyMarker = output.NextKeyMarker
+		input.VersionIdMarker = output.NextVersionIdMarker
+	}
+}
+
 func (conf *AWSConfiguration) ListBuckets() (*s3.ListBucketsOutput, error) {
 	fmt.Println("listing buckets")
 	s3Client := s3.NewFromConfig(conf.Config)
@@ -0,0 +1,65 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	awsv1 "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+)
+
+// PutSecretString creates or updates a secrets manager secret, the secrets
+// manager client uses the credentials of the configuration
+func (conf *AWSConfiguration) PutSecretString(name string, value string) error {
+	sess, err := conf.sessionV1(conf.Config.Region)
+	if err != nil {
+		return err
+	}
+	client := secretsmanager.New(sess)
+
+	_, err = client.PutSecretValue(&secretsmanager.PutSecretValueInput{
+		SecretId:     awsv1.String(name),
+		SecretString: awsv1.String(value),
+	})
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+		_, err = client.CreateSecret(&secretsmanager.CreateSecretInput{
+			Name:         awsv1.String(name),
+			SecretString: awsv1.String(value),
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("error writing secret %s: %s", name, err)
+	}
+
+	return nil
+}
+
+// sessionV1 returns an aws-sdk-go session using the credentials of the
+// configuration, for services the v2 sdk isn't vendored for
+func (conf *AWSConfiguration) sessionV1(region string) (*session.Session, error) {
+	creds, err := conf.Config.Credentials.Retrieve(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving aws credentials: %s", err)
+	}
+
+	sess, err := session.NewSession(&awsv1.Config{
+		Region:      awsv1.String(region),
+		Credentials: credentials.NewStaticCredentials(creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating aws session: %s", err)
+	}
+
+	return sess, nil
+}
@@ -0,0 +1,35 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package aws
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// SpotInstanceTypeOffered reports whether instanceType has a recent linux
+// spot price in the configuration's region, which it only does when it can
+// be launched as a spot instance there
+func (conf *AWSConfiguration) SpotInstanceTypeOffered(instanceType string) (bool, error) {
+	ec2Client := ec2.NewFromConfig(conf.Config)
+
+	history, err := ec2Client.DescribeSpotPriceHistory(context.Background(), &ec2.DescribeSpotPriceHistoryInput{
+		InstanceTypes:       []ec2Types.InstanceType{ec2Types.InstanceType(instanceType)},
+		ProductDescriptions: []string{"Linux/UNIX"},
+		StartTime:           aws.Time(time.Now()),
+		MaxResults:          aws.Int32(5),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return len(history.SpotPriceHistory) != 0, nil
+}
@@ -15,6 +15,8 @@ import (
 // AWSConfiguration stores session data to organize all AWS functions into a single struct
 type AWSConfiguration struct {
 	Config aws.Config
+	// RecordTTL is the ttl of the route53 records created, 0 keeps 10 seconds
+	RecordTTL int
 }
 
 type AWSRoute53AlterResourceRecord struct {
@@ -40,3 +42,11 @@ type AWSTXTRecord struct {
 	Weight        *int64
 	TTL           int64
 }
+
+func (conf *AWSConfiguration) recordTTL() int64 {
+	if conf.RecordTTL > 0 {
+		return int64(conf.RecordTTL)
+	}
+
+	return 10
+}
@@ -102,3 +102,53 @@ func (conf *AWSConfiguration) ListCompatibleRegions() ([]string, error) {
 
 	return compatibleRegions, nil
 }
+
+// GetVPCAvailableAddresses confirms vpcID exists and returns the number of
+// free ip addresses across subnetIDs, or across every subnet of the vpc when
+// no subnets are given
+func (conf *AWSConfiguration) GetVPCAvailableAddresses(vpcID string, subnetIDs []string) (int, error) {
+	ec2Client := ec2.NewFromConfig(conf.Config)
+
+	vpcs, err := ec2Client.DescribeVpcs(context.Background(), &ec2.DescribeVpcsInput{
+		VpcIds: []string{vpcID},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error describing vpc %s: %s", vpcID, err)
+	}
+	if len(vpcs.Vpcs) == 0 {
+		return 0, fmt.Errorf("vpc %s not found", vpcID)
+	}
+
+	input := &ec2.DescribeSubnetsInput{}
+	if len(subnetIDs) != 0 {
+		input.SubnetIds = subnetIDs
+	} else {
+		filterName := "vpc-id"
+		input.Filters = []ec2Types.Filter{
+			{
+				Name:   &filterName,
+				Values: []string{vpcID},
+			},
+		}
+	}
+
+	subnets, err := ec2Client.DescribeSubnets(context.Background(), input)
+	if err != nil {
+		return 0, fmt.Errorf("error describing subnets of vpc %s: %s", vpcID, err)
+	}
+	if len(subnets.Subnets) == 0 {
+		return 0, fmt.Errorf("vpc %s has no subnets", vpcID)
+	}
+
+	available := 0
+	for _, subnet := range subnets.Subnets {
+		if subnet.VpcId == nil || *subnet.VpcId != vpcID {
+			return 0, fmt.Errorf("subnet %s does not belong to vpc %s", *subnet.SubnetId, vpcID)
+		}
+		if subnet.AvailableIpAddressCount != nil {
+			available += int(*subnet.AvailableIpAddressCount)
+		}
+	}
+
+	return available, nil
+}
@@ -31,7 +31,7 @@ func (c *CivoConfiguration) TestDomainLiveness(domainName string, domainId strin
 		Name:     civoRecordName,
 		Value:    civoRecordValue,
 		Priority: 100,
-		TTL:      600,
+		TTL:      c.recordTTL(),
 	}
 
 	log.Info().Msgf("checking to see if record %s exists", domainName)
@@ -187,3 +187,29 @@ func (c *CivoConfiguration) GetKubeconfig(clusterName string) (string, error) {
 
 	return cluster.KubeConfig, nil
 }
+
+// CheckInstanceQuota returns an error when the account cannot launch
+// instanceCount more instances
+func (c *CivoConfiguration) CheckInstanceQuota(instanceCount int) error {
+	quota, err := c.Client.GetQuota()
+	if err != nil {
+		return err
+	}
+
+	available := quota.InstanceCountLimit - quota.InstanceCountUsage
+	if available < instanceCount {
+		return fmt.Errorf("civo instance quota allows %d more instances but %d are required", available, instanceCount)
+	}
+
+	return nil
+}
+
+// GetNetworkCIDR returns the ipv4 cidr of an existing private network
+func (c *CivoConfiguration) GetNetworkCIDR(networkID string) (string, error) {
+	network, err := c.Client.GetNetwork(networkID)
+	if err != nil {
+		return "", fmt.Errorf("error getting civo network %s: %s", networkID, err)
+	}
+
+	return network.CIDR, nil
+}
@@ -7,10 +7,25 @@ See the LICENSE file for more details.
 package civo
 
 import (
+	"os"
+
 	"github.com/civo/civogo"
 )
 
-func NewCivo(civoToken string, region string) *civogo.Client {
+// APIURLVar is the environment variable overriding the civo api url of
+// definitions that don't set civo_auth.api_url, the civo terraform provider
+// reads it too
+const APIURLVar = "CIVO_API_URL"
+
+func NewCivo(civoToken string, region string, apiURL string) *civogo.Client {
+	if apiURL == "" {
+		apiURL = os.Getenv(APIURLVar)
+	}
+	if apiURL != "" {
+		civoClient, _ := civogo.NewClientWithURL(civoToken, apiURL, region)
+		return civoClient
+	}
+
 	civoClient, _ := civogo.NewClient(civoToken, region)
 
 	return civoClient
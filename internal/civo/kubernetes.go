@@ -0,0 +1,49 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package civo
+
+import (
+	"fmt"
+
+	"github.com/civo/civogo"
+)
+
+// ListKubernetesClusters returns the kubernetes clusters of the region
+func (c *CivoConfiguration) ListKubernetesClusters() ([]civogo.KubernetesCluster, error) {
+	clusters, err := c.Client.ListKubernetesClusters()
+	if err != nil {
+		return nil, fmt.Errorf("error listing kubernetes clusters: %s", err)
+	}
+
+	return clusters.Items, nil
+}
+
+// DeleteKubernetesCluster deletes a kubernetes cluster by id
+func (c *CivoConfiguration) DeleteKubernetesCluster(clusterID string) error {
+	_, err := c.Client.DeleteKubernetesCluster(clusterID)
+	if err != nil {
+		return fmt.Errorf("error deleting kubernetes cluster %s: %s", clusterID, err)
+	}
+
+	return nil
+}
+
+// ListKubernetesVersions returns the kubernetes versions new clusters can be
+// created with
+func (c *CivoConfiguration) ListKubernetesVersions() ([]string, error) {
+	available, err := c.Client.ListAvailableKubernetesVersions()
+	if err != nil {
+		return nil, fmt.Errorf("error listing kubernetes versions: %s", err)
+	}
+
+	versions := []string{}
+	for _, version := range available {
+		versions = append(versions, version.Version)
+	}
+
+	return versions, nil
+}
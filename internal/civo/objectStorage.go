@@ -7,7 +7,9 @@ See the LICENSE file for more details.
 package civo
 
 import (
+	"crypto/rand"
 	"fmt"
+	"math/big"
 	"os"
 	"time"
 
@@ -151,3 +153,55 @@ func (c *CivoConfiguration) getAccessCredentials(id string, region string) (civo
 	}
 	return *creds, nil
 }
+
+// RotateAccessCredentials replaces the keys of object store access credentials
+// in place, buckets owned by the credentials keep their owner and the old keys
+// stop working
+func (c *CivoConfiguration) RotateAccessCredentials(id string, region string) (civogo.ObjectStoreCredential, error) {
+	accessKeyID, err := randomKey(20, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+	if err != nil {
+		return civogo.ObjectStoreCredential{}, err
+	}
+	secretAccessKey, err := randomKey(40, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789")
+	if err != nil {
+		return civogo.ObjectStoreCredential{}, err
+	}
+
+	log.Info().Msgf("rotating object store credential %s", id)
+	_, err = c.Client.UpdateObjectStoreCredential(id, &civogo.UpdateObjectStoreCredentialRequest{
+		AccessKeyID:       &accessKeyID,
+		SecretAccessKeyID: &secretAccessKey,
+		Region:            region,
+	})
+	if err != nil {
+		return civogo.ObjectStoreCredential{}, fmt.Errorf("error rotating object store credential %s: %s", id, err)
+	}
+
+	for i := 0; i < 12; i++ {
+		creds, err := c.getAccessCredentials(id, region)
+		if err != nil {
+			return civogo.ObjectStoreCredential{}, err
+		}
+		if creds.AccessKeyID == accessKeyID && creds.SecretAccessKeyID == secretAccessKey {
+			return creds, nil
+		}
+		log.Warn().Msg("waiting for civo credentials rotation")
+		time.Sleep(time.Second * 10)
+	}
+
+	return civogo.ObjectStoreCredential{}, fmt.Errorf("object store credential %s did not take the rotated keys", id)
+}
+
+// randomKey returns a random string of length drawn from charset
+func randomKey(length int, charset string) (string, error) {
+	key := make([]byte, length)
+	for i := range key {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+		if err != nil {
+			return "", fmt.Errorf("error generating object store key: %s", err)
+		}
+		key[i] = charset[n.Int64()]
+	}
+
+	return string(key), nil
+}
@@ -15,4 +15,14 @@ import (
 type CivoConfiguration struct {
 	Client  *civogo.Client
 	Context context.Context
+	// RecordTTL is the ttl of the dns records created, 0 keeps 600 seconds
+	RecordTTL int
+}
+
+func (c *CivoConfiguration) recordTTL() int {
+	if c.RecordTTL > 0 {
+		return c.RecordTTL
+	}
+
+	return 600
 }
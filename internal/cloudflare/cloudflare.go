@@ -88,7 +88,7 @@ func (c *CloudflareConfiguration) TestDomainLiveness(domainName string) bool {
 
 	// create record if it does not exist
 	createParams := cloudflare.CreateDNSRecordParams{
-		TTL:     60,
+		TTL:     c.recordTTL(),
 		Type:    "TXT",
 		Name:    RecordName,
 		Content: RecordValue,
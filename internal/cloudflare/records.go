@@ -0,0 +1,170 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cloudflare
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/kubefirst/kubefirst-api/internal/dns"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhpk/randstr"
+)
+
+const (
+	// acmeChallengePrefix is the record name cert-manager uses for dns-01
+	acmeChallengePrefix = "_acme-challenge"
+	// txtRecordTTL is the default ttl of the records created
+	txtRecordTTL = 60
+)
+
+var (
+	// lookupTXT resolves TXT records, overridden in tests
+	lookupTXT = net.LookupTXT
+
+	dns01PollAttempts = 30
+	dns01PollInterval = 10 * time.Second
+)
+
+// CreateZone creates a zone for domainName in the cloudflare account and
+// returns its id - an existing zone for the domain is reused
+func (c *CloudflareConfiguration) CreateZone(domainName string, accountID string) (string, error) {
+	zoneId, err := c.Client.ZoneIDByName(domainName)
+	if err == nil {
+		log.Info().Msgf("cloudflare zone %s already exists for domain %s", zoneId, domainName)
+		return zoneId, nil
+	}
+
+	zone, err := c.Client.CreateZone(c.Context, domainName, false, cloudflare.Account{ID: accountID}, "full")
+	if err != nil {
+		return "", fmt.Errorf("error creating cloudflare zone for domain %s: %s", domainName, err)
+	}
+	log.Info().Msgf("created cloudflare zone %s for domain %s", zone.ID, domainName)
+
+	return zone.ID, nil
+}
+
+// UpsertTXTRecord creates or updates the TXT record name in the zone for
+// domainName and returns the record id
+func (c *CloudflareConfiguration) UpsertTXTRecord(domainName string, name string, value string) (string, error) {
+	zoneId, err := c.Client.ZoneIDByName(domainName)
+	if err != nil {
+		return "", fmt.Errorf("error finding cloudflare zoneid for domain %s: %s", domainName, err)
+	}
+	rc := cloudflare.ZoneIdentifier(zoneId)
+
+	existingRecords, _, err := c.Client.ListDNSRecords(c.Context, rc, cloudflare.ListDNSRecordsParams{
+		Type: "TXT",
+		Name: name,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error listing cloudflare dns records for domain %s: %s", domainName, err)
+	}
+
+	for _, existingRecord := range existingRecords {
+		if existingRecord.Name != name {
+			continue
+		}
+		if existingRecord.Content == value {
+			return existingRecord.ID, nil
+		}
+		record, err := c.Client.UpdateDNSRecord(c.Context, rc, cloudflare.UpdateDNSRecordParams{
+			ID:      existingRecord.ID,
+			Type:    "TXT",
+			Name:    name,
+			Content: value,
+			TTL:     c.recordTTL(),
+		})
+		if err != nil {
+			return "", fmt.Errorf("error updating cloudflare TXT record %s: %s", name, err)
+		}
+		return record.ID, nil
+	}
+
+	record, err := c.Client.CreateDNSRecord(c.Context, rc, cloudflare.CreateDNSRecordParams{
+		Type:    "TXT",
+		Name:    name,
+		Content: value,
+		TTL:     c.recordTTL(),
+		ZoneID:  zoneId,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error creating cloudflare TXT record %s: %s", name, err)
+	}
+
+	return record.ID, nil
+}
+
+// DeleteTXTRecord removes every TXT record called name from the zone for
+// domainName
+func (c *CloudflareConfiguration) DeleteTXTRecord(domainName string, name string) error {
+	zoneId, err := c.Client.ZoneIDByName(domainName)
+	if err != nil {
+		return fmt.Errorf("error finding cloudflare zoneid for domain %s: %s", domainName, err)
+	}
+	rc := cloudflare.ZoneIdentifier(zoneId)
+
+	existingRecords, _, err := c.Client.ListDNSRecords(c.Context, rc, cloudflare.ListDNSRecordsParams{
+		Type: "TXT",
+		Name: name,
+	})
+	if err != nil {
+		return fmt.Errorf("error listing cloudflare dns records for domain %s: %s", domainName, err)
+	}
+
+	for _, existingRecord := range existingRecords {
+		if existingRecord.Name != name {
+			continue
+		}
+		err = c.Client.DeleteDNSRecord(c.Context, rc, existingRecord.ID)
+		if err != nil {
+			return fmt.Errorf("error deleting cloudflare TXT record %s: %s", name, err)
+		}
+	}
+
+	return nil
+}
+
+// DNS01RoundTrip publishes a challenge TXT record the way cert-manager does
+// for a dns-01 solve, waits for it to resolve and removes it again
+func (c *CloudflareConfiguration) DNS01RoundTrip(domainName string) error {
+	recordName := fmt.Sprintf("%s.%s", acmeChallengePrefix, domainName)
+	recordValue := randstr.String(32)
+
+	_, err := c.UpsertTXTRecord(domainName, recordName, recordValue)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := c.DeleteTXTRecord(domainName, recordName)
+		if err != nil {
+			log.Warn().Msgf("error cleaning up dns-01 challenge record %s: %s", recordName, err)
+		}
+	}()
+
+	for i := 0; i < dns01PollAttempts; i++ {
+		values, err := lookupTXT(recordName)
+		if err != nil {
+			values, err = dns.BackupResolver.LookupTXT(c.Context, recordName)
+		}
+		if err == nil {
+			for _, value := range values {
+				if value == recordValue {
+					log.Info().Msgf("dns-01 challenge record %s resolved", recordName)
+					return nil
+				}
+			}
+		}
+
+		log.Info().Msgf("waiting for dns-01 challenge record %s to resolve", recordName)
+		time.Sleep(dns01PollInterval)
+	}
+
+	return fmt.Errorf("dns-01 challenge record %s did not resolve, check the ns records for %s point to cloudflare", recordName, domainName)
+}
@@ -0,0 +1,221 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+)
+
+// mockCloudflare is an in-memory stand in for the parts of the cloudflare
+// api used by the dns provider
+type mockCloudflare struct {
+	mu      sync.Mutex
+	zones   map[string]string // name -> id
+	records map[string]cloudflare.DNSRecord
+	nextID  int
+}
+
+func newMockCloudflare(zones ...string) *mockCloudflare {
+	m := &mockCloudflare{
+		zones:   map[string]string{},
+		records: map[string]cloudflare.DNSRecord{},
+	}
+	for _, zone := range zones {
+		m.zones[zone] = m.id()
+	}
+	return m
+}
+
+func (m *mockCloudflare) id() string {
+	m.nextID++
+	return fmt.Sprintf("id%d", m.nextID)
+}
+
+func (m *mockCloudflare) respond(w http.ResponseWriter, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"errors":      []interface{}{},
+		"messages":    []interface{}{},
+		"result":      result,
+		"result_info": map[string]int{"page": 1, "per_page": 100, "count": 1, "total_count": 1, "total_pages": 1},
+	})
+}
+
+func (m *mockCloudflare) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		zones := []cloudflare.Zone{}
+		for name, id := range m.zones {
+			if filter := r.URL.Query().Get("name"); filter == "" || filter == name {
+				zones = append(zones, cloudflare.Zone{ID: id, Name: name})
+			}
+		}
+		m.respond(w, zones)
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		var body struct {
+			Name string `json:"name"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		m.zones[body.Name] = m.id()
+		m.respond(w, cloudflare.Zone{ID: m.zones[body.Name], Name: body.Name})
+	case len(parts) == 3 && r.Method == http.MethodGet:
+		records := []cloudflare.DNSRecord{}
+		for _, record := range m.records {
+			if record.ZoneID == parts[1] && record.Name == r.URL.Query().Get("name") {
+				records = append(records, record)
+			}
+		}
+		m.respond(w, records)
+	case len(parts) == 3 && r.Method == http.MethodPost:
+		var record cloudflare.DNSRecord
+		json.NewDecoder(r.Body).Decode(&record)
+		record.ID = m.id()
+		record.ZoneID = parts[1]
+		m.records[record.ID] = record
+		m.respond(w, record)
+	case len(parts) == 4 && r.Method == http.MethodPatch:
+		record := m.records[parts[3]]
+		json.NewDecoder(r.Body).Decode(&record)
+		m.records[parts[3]] = record
+		m.respond(w, record)
+	case len(parts) == 4 && r.Method == http.MethodDelete:
+		delete(m.records, parts[3])
+		m.respond(w, map[string]string{"id": parts[3]})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestConfiguration(t *testing.T, mock *mockCloudflare) *CloudflareConfiguration {
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+
+	client, err := cloudflare.NewWithAPIToken("test-token", cloudflare.BaseURL(server.URL), cloudflare.UsingRateLimit(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &CloudflareConfiguration{Client: client, Context: context.Background()}
+}
+
+func TestCreateZone(t *testing.T) {
+	mock := newMockCloudflare("existing.com")
+	conf := newTestConfiguration(t, mock)
+
+	existingID, err := conf.CreateZone("existing.com", "account")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existingID != mock.zones["existing.com"] {
+		t.Errorf("expected existing zone id %s, got %s", mock.zones["existing.com"], existingID)
+	}
+
+	newID, err := conf.CreateZone("new.com", "account")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newID == "" || newID != mock.zones["new.com"] {
+		t.Errorf("expected zone new.com to be created, got id %q", newID)
+	}
+}
+
+func TestUpsertAndDeleteTXTRecord(t *testing.T) {
+	mock := newMockCloudflare("example.com")
+	conf := newTestConfiguration(t, mock)
+
+	firstID, err := conf.UpsertTXTRecord("example.com", "test.example.com", "one")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secondID, err := conf.UpsertTXTRecord("example.com", "test.example.com", "two")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if firstID != secondID {
+		t.Errorf("expected upsert to update record %s, created %s", firstID, secondID)
+	}
+	if len(mock.records) != 1 || mock.records[firstID].Content != "two" {
+		t.Errorf("expected a single record with content two, got %v", mock.records)
+	}
+
+	err = conf.DeleteTXTRecord("example.com", "test.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mock.records) != 0 {
+		t.Errorf("expected records to be deleted, got %v", mock.records)
+	}
+}
+
+func TestUpsertTXTRecordMissingZone(t *testing.T) {
+	conf := newTestConfiguration(t, newMockCloudflare())
+
+	_, err := conf.UpsertTXTRecord("missing.com", "test.missing.com", "value")
+	if err == nil {
+		t.Error("expected an error for a domain without a zone")
+	}
+}
+
+func TestDNS01RoundTrip(t *testing.T) {
+	mock := newMockCloudflare("example.com")
+	conf := newTestConfiguration(t, mock)
+
+	defer func(lookup func(string) ([]string, error), interval time.Duration, attempts int) {
+		lookupTXT, dns01PollInterval, dns01PollAttempts = lookup, interval, attempts
+	}(lookupTXT, dns01PollInterval, dns01PollAttempts)
+	dns01PollInterval = time.Millisecond
+	dns01PollAttempts = 3
+
+	tests := []struct {
+		name      string
+		propagate bool
+		wantErr   bool
+	}{
+		{name: "record resolves", propagate: true},
+		{name: "record never resolves", propagate: false, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookupTXT = func(name string) ([]string, error) {
+				mock.mu.Lock()
+				defer mock.mu.Unlock()
+				var values []string
+				for _, record := range mock.records {
+					if tt.propagate && record.Name == name {
+						values = append(values, record.Content)
+					}
+				}
+				return values, nil
+			}
+
+			err := conf.DNS01RoundTrip("example.com")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DNS01RoundTrip() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(mock.records) != 0 {
+				t.Errorf("expected challenge record to be cleaned up, got %v", mock.records)
+			}
+		})
+	}
+}
@@ -15,4 +15,14 @@ import (
 type CloudflareConfiguration struct {
 	Client  *cloudflare.API
 	Context context.Context
+	// RecordTTL is the ttl of the dns records created, 0 keeps 60 seconds
+	RecordTTL int
+}
+
+func (c *CloudflareConfiguration) recordTTL() int {
+	if c.RecordTTL > 0 {
+		return c.RecordTTL
+	}
+
+	return txtRecordTTL
 }
@@ -14,11 +14,22 @@ const (
 	KubefirstAuthSecretName = "kubefirst-secret"
 
 	// Cluster statuses
+	ClusterStatusDegraded     = "degraded"
 	ClusterStatusDeleted      = "deleted"
 	ClusterStatusDeleting     = "deleting"
 	ClusterStatusError        = "error"
+	ClusterStatusInterrupted  = "interrupted"
+	ClusterStatusPaused       = "paused"
 	ClusterStatusProvisioned  = "provisioned"
 	ClusterStatusProvisioning = "provisioning"
+	ClusterStatusQueued       = "queued"
+	ClusterStatusUnknown      = "unknown"
+
+	// Operation statuses
+	OperationStatusFailed    = "failed"
+	OperationStatusPending   = "pending"
+	OperationStatusRunning   = "running"
+	OperationStatusSucceeded = "succeeded"
 
 	SilenceGetEnv = true
 )
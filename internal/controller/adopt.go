@@ -0,0 +1,72 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+
+	terraformext "github.com/kubefirst/kubefirst-api/extensions/terraform"
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+)
+
+// adoptedRepositoryAddresses are the gitops template's terraform addresses of
+// a repository created by the git terraform, keyed by git provider
+var adoptedRepositoryAddresses = map[string]string{
+	"github": "module.%s.github_repository.repo",
+	"gitlab": "module.%s.gitlab_project.project",
+}
+
+// importAdoptedRepositories imports the repositories adopted during git
+// initialization into the git terraform's state so applying it manages them
+// instead of failing to create them
+func (clctrl *ClusterController) importAdoptedRepositories(adopted []string, tfEntrypoint string, tfEnvs map[string]string) error {
+	for _, repositoryName := range adopted {
+		address := fmt.Sprintf(adoptedRepositoryAddresses[clctrl.GitProvider], repositoryName)
+
+		id := repositoryName
+		if clctrl.GitProvider == "gitlab" {
+			id = fmt.Sprintf("%s/%s", clctrl.GitAuth.Owner, repositoryName)
+		}
+
+		clctrl.logger().Infof("importing adopted repository %s as %s", id, address)
+		err := terraformext.InitImport(clctrl.ProviderConfig.TerraformClient, tfEntrypoint, tfEnvs, address, id)
+		if err != nil {
+			return fmt.Errorf("error importing adopted repository %s: %s", repositoryName, err)
+		}
+	}
+
+	return nil
+}
+
+// adoptedTeamAddresses are the gitops template's terraform addresses of a
+// team created by the git terraform, keyed by git provider
+var adoptedTeamAddresses = map[string]string{
+	"github": "github_team.%s",
+}
+
+// importAdoptedTeams imports the teams adopted during git initialization into
+// the git terraform's state so applying it manages them instead of failing to
+// create them
+func (clctrl *ClusterController) importAdoptedTeams(adopted []string, tfEntrypoint string, tfEnvs map[string]string) error {
+	for _, teamName := range adopted {
+		address := fmt.Sprintf(adoptedTeamAddresses[clctrl.GitProvider], teamName)
+
+		clctrl.logger().Infof("importing adopted team %s as %s", teamName, address)
+		err := terraformext.InitImport(clctrl.ProviderConfig.TerraformClient, tfEntrypoint, tfEnvs, address, teamName)
+		if err != nil {
+			return fmt.Errorf("error importing adopted team %s: %s", teamName, err)
+		}
+	}
+
+	return nil
+}
+
+// forcePushRepository reports whether repositoryName was adopted and should be
+// force pushed, which is only the case when force adoption was requested
+func (clctrl *ClusterController) forcePushRepository(adopted []string, repositoryName string) bool {
+	return clctrl.ForceAdoptRepositories && pkg.FindStringInSlice(adopted, repositoryName)
+}
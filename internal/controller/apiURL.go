@@ -0,0 +1,142 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	awsinternal "github.com/kubefirst/kubefirst-api/internal/aws"
+	"github.com/kubefirst/kubefirst-api/internal/civo"
+	"github.com/kubefirst/kubefirst-api/internal/digitalocean"
+	"github.com/kubefirst/kubefirst-api/internal/vultr"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"github.com/linode/linodego"
+)
+
+// apiURLProbeTimeout bounds the preflight request to a custom cloud api url
+const apiURLProbeTimeout = 10 * time.Second
+
+// apiURLVars are the environment variables each cloud provider's clients and
+// terraform provider read a custom api url from
+var apiURLVars = map[string]string{
+	"akamai":       linodego.APIHostVar,
+	"aws":          awsinternal.EndpointURLVar,
+	"civo":         civo.APIURLVar,
+	"digitalocean": digitalocean.APIURLVar,
+	"vultr":        vultr.APIURLVar,
+}
+
+// definitionAPIURL returns the api url the definition sets for its cloud
+// provider, empty when it uses the provider's public api
+func definitionAPIURL(def *pkgtypes.ClusterDefinition) string {
+	switch def.CloudProvider {
+	case "akamai":
+		return def.AkamaiAuth.APIURL
+	case "aws":
+		return def.AWSAuth.APIURL
+	case "civo":
+		return def.CivoAuth.APIURL
+	case "digitalocean":
+		return def.DigitaloceanAuth.APIURL
+	case "vultr":
+		return def.VultrAuth.APIURL
+	}
+
+	return ""
+}
+
+// clusterAPIURL returns the api url the cluster was created with for its
+// cloud provider, empty when it uses the provider's public api
+func clusterAPIURL(cl *pkgtypes.Cluster) string {
+	return definitionAPIURL(&pkgtypes.ClusterDefinition{
+		CloudProvider:    cl.CloudProvider,
+		AkamaiAuth:       cl.AkamaiAuth,
+		AWSAuth:          cl.AWSAuth,
+		CivoAuth:         cl.CivoAuth,
+		DigitaloceanAuth: cl.DigitaloceanAuth,
+		VultrAuth:        cl.VultrAuth,
+	})
+}
+
+// validateAPIURL makes sure a custom cloud api url is an absolute http(s) url
+// and set only for the definition's own cloud provider
+func validateAPIURL(def *pkgtypes.ClusterDefinition) error {
+	for provider, apiURL := range map[string]string{
+		"akamai":       def.AkamaiAuth.APIURL,
+		"aws":          def.AWSAuth.APIURL,
+		"civo":         def.CivoAuth.APIURL,
+		"digitalocean": def.DigitaloceanAuth.APIURL,
+		"vultr":        def.VultrAuth.APIURL,
+	} {
+		if apiURL == "" {
+			continue
+		}
+		field := fmt.Sprintf("%s_auth.api_url", provider)
+		if provider == "digitalocean" {
+			field = "do_auth.api_url"
+		}
+		if provider != def.CloudProvider {
+			return fmt.Errorf("%s is set but the cloud provider is %s", field, def.CloudProvider)
+		}
+
+		parsed, err := url.Parse(strings.ReplaceAll(apiURL, "{service}", "sts"))
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return fmt.Errorf("invalid %s %s: must be an absolute http or https url", field, apiURL)
+		}
+	}
+
+	return nil
+}
+
+// getAPIURLTerraformEnvs points the cloud terraform provider at the cluster's
+// custom api url through the environment variable the provider reads, and
+// passes it as TF_VAR_cloud_api_url to templates whose provider has none. A
+// url set through the environment instead of the definition is inherited by
+// terraform as is
+func getAPIURLTerraformEnvs(envs map[string]string, cloudProvider string, apiURL string) map[string]string {
+	if apiURL == "" {
+		return envs
+	}
+
+	envs["TF_VAR_cloud_api_url"] = apiURL
+	if envVar, ok := apiURLVars[cloudProvider]; ok {
+		envs[envVar] = apiURL
+	}
+
+	return envs
+}
+
+// preflightAPIURL confirms a custom cloud api url, from the definition or the
+// environment, answers http requests - any response will do, the credentials
+// check exercises the api itself
+func preflightAPIURL(def *pkgtypes.ClusterDefinition) error {
+	apiURL := definitionAPIURL(def)
+	if apiURL == "" {
+		apiURL = os.Getenv(apiURLVars[def.CloudProvider])
+	}
+	if apiURL == "" {
+		return nil
+	}
+
+	// per-service aws endpoints are checked through sts, which every
+	// install calls
+	probeURL := strings.ReplaceAll(apiURL, "{service}", "sts")
+
+	client := http.Client{Timeout: apiURLProbeTimeout}
+	res, err := client.Get(probeURL)
+	if err != nil {
+		return fmt.Errorf("%s api url %s is unreachable: %s", def.CloudProvider, probeURL, err)
+	}
+	res.Body.Close()
+
+	return nil
+}
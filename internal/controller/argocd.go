@@ -8,19 +8,22 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"os/exec"
+	"strings"
 	"time"	
 
-	argocdapi "github.com/argoproj/argo-cd/v2/pkg/client/clientset/versioned"
 	awsext "github.com/kubefirst/kubefirst-api/extensions/aws"
 	pkg "github.com/kubefirst/kubefirst-api/internal"
 	"github.com/kubefirst/kubefirst-api/internal/argocd"
 	"github.com/kubefirst/kubefirst-api/internal/k8s"
 	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	"github.com/kubefirst/kubefirst-api/internal/ssl"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
 	"github.com/kubefirst/metrics-client/pkg/telemetry"
-	log "github.com/rs/zerolog/log"
+	"golang.org/x/crypto/bcrypt"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // InstallArgoCD
@@ -47,21 +50,21 @@ func (clctrl *ClusterController) InstallArgoCD() error {
 		}
 
 		argoCDInstallPath := fmt.Sprintf("github.com:kubefirst/manifests/argocd/cloud?ref=%s", pkg.KubefirstManifestRepoRef)
-		log.Info().Msg("installing argocd")
+		clctrl.logger().Info("installing argocd")
 
-		telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.ArgoCDInstallStarted, "")
+		clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.ArgoCDInstallStarted, "")
 		err = argocd.ApplyArgoCDKustomize(kcfg.Clientset, argoCDInstallPath)
 		if err != nil {
-			telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.ArgoCDInstallFailed, err.Error())
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.ArgoCDInstallFailed, err.Error())
 			return err
 		}
 
-		telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.ArgoCDInstallCompleted, "")
+		clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.ArgoCDInstallCompleted, "")
 
 		// Wait for ArgoCD to be ready
 		_, err = k8s.VerifyArgoCDReadiness(kcfg.Clientset, true, 300)
 		if err != nil {
-			log.Error().Msgf("error waiting for ArgoCD to become ready: %s", err)
+			clctrl.logger().Errorf("error waiting for ArgoCD to become ready: %s", err)
 			return err
 		}
 
@@ -98,45 +101,16 @@ func (clctrl *ClusterController) InitializeArgoCD() error {
 			}
 		}
 
-		log.Info().Msg("Setting argocd username and password credentials")
-
-		argocd.ArgocdSecretClient = kcfg.Clientset.CoreV1().Secrets("argocd")
-
-		argocdPassword := k8s.GetSecretValue(argocd.ArgocdSecretClient, "argocd-initial-admin-secret", "password")
-		if argocdPassword == "" {
-			return fmt.Errorf("argocd password not found in secret")
+		argocdClient, err := clctrl.argoClient(kcfg)
+		if err != nil {
+			return err
 		}
 
-		log.Info().Msg("argocd username and password credentials set successfully")
-		log.Info().Msg("getting an argocd auth token")
-
-		var argoCDToken string
-
-		switch clctrl.CloudProvider {
-		case "aws", "civo", "google", "digitalocean", "vultr", "k3s":
-
-			// kcfg.Clientset.RbacV1().
-			argoCDStopChannel := make(chan struct{}, 1)
-			defer func() {
-				close(argoCDStopChannel)
-			}()
-			k8s.OpenPortForwardPodWrapper(
-				kcfg.Clientset,
-				kcfg.RestConfig,
-				"argocd-server",
-				"argocd",
-				8080,
-				8080,
-				argoCDStopChannel,
-			)
-			argoCDToken, err = argocd.GetArgoCDToken("admin", argocdPassword)
-			if err != nil {
-				return err
-			}
+		argocdPassword, argoCDToken, err := clctrl.initializeArgoCD(argocdClient)
+		if err != nil {
+			return err
 		}
 
-		log.Info().Msg("argocd admin auth token set")
-
 		clctrl.Cluster.ArgoCDPassword = argocdPassword
 		clctrl.Cluster.ArgoCDAuthToken = argoCDToken
 		clctrl.Cluster.ArgoCDInitializeCheck = true
@@ -173,70 +147,337 @@ func (clctrl *ClusterController) DeployRegistryApplication() error {
 			}
 		}
 
-		telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.CreateRegistryStarted, "")
-		argocdClient, err := argocdapi.NewForConfig(kcfg.RestConfig)
+		clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.CreateRegistryStarted, "")
+		argocdClient, err := clctrl.argoClient(kcfg)
 		if err != nil {
 			return err
 		}
-		
-		log.Info().Msg("applying the registry application to argocd")
 
-		registryURL, err := clctrl.GetRepoURL()
+		err = clctrl.applyRegistryApplication(argocdClient)
 		if err != nil {
 			return err
 		}
 
-		var registryPath string
-		if clctrl.CloudProvider == "k3d" {
-			registryPath = fmt.Sprintf("registry/%s", clctrl.ClusterName)
-		} else {
-			registryPath = fmt.Sprintf("registry/clusters/%s", clctrl.ClusterName)
+		clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.CreateRegistryCompleted, "")
+
+		clctrl.Cluster.ArgoCDCreateRegistryCheck = true
+		err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
+		if err != nil {
+			return err
 		}
+	}
 
-		registryApplicationObject := argocd.GetArgoCDApplicationObject(
-			registryURL,
-			registryPath,
-		)
+	return nil
+}
 
+// argoClient returns a client for the argocd in the cluster of kcfg
+func (clctrl *ClusterController) argoClient(kcfg *k8s.KubernetesClient) (argocd.Client, error) {
+	return argocd.NewClient(kcfg, clctrl.ProviderConfig.Kubeconfig)
+}
 
-		cmdStr := fmt.Sprintf("kubectl --kubeconfig=%s rollout restart -n argocd deploy/argocd-applicationset-controller", clctrl.ProviderConfig.Kubeconfig)
+// initializeArgoCD reads the argocd admin password and creates an api token
+// with it
+func (clctrl *ClusterController) initializeArgoCD(argocdClient argocd.Client) (string, string, error) {
+	clctrl.logger().Info("Setting argocd username and password credentials")
 
-		cmd := exec.Command("/bin/sh", "-c", cmdStr)
+	argocdPassword, err := argocdClient.InitialAdminPassword()
+	if err != nil {
+		return "", "", err
+	}
+
+	clctrl.logger().Info("argocd username and password credentials set successfully")
+	clctrl.logger().Info("getting an argocd auth token")
 
-		err = cmd.Run()
+	var argoCDToken string
+
+	switch clctrl.CloudProvider {
+	case "aws", "civo", "google", "digitalocean", "vultr", "k3s":
+		argoCDToken, err = argocdClient.AuthToken("admin", argocdPassword)
 		if err != nil {
-			log.Info().Msgf("Error executing kubectl command: %v\n", err)
-			return err
+			return "", "", err
 		}
+	}
+
+	clctrl.logger().Info("argocd admin auth token set")
 
+	return argocdPassword, argoCDToken, nil
+}
 
-		retryAttempts := 2
-		for attempt := 1; attempt <= retryAttempts; attempt++ {
-			log.Info().Msgf("Attempt #%d to create Argo CD application...\n", attempt)
+// applyRegistryApplication creates the registry Application that syncs the
+// cluster's registry directory of the gitops repository
+func (clctrl *ClusterController) applyRegistryApplication(argocdClient argocd.Client) error {
+	clctrl.logger().Info("applying the registry application to argocd")
 
-			app, err := argocdClient.ArgoprojV1alpha1().Applications("argocd").Create(context.Background(), registryApplicationObject, metav1.CreateOptions{})
-			if err != nil {
-				if attempt == retryAttempts {
-					return err
-				}
-				log.Info().Msgf("Error creating Argo CD application on attempt number #%d: %v\n", attempt, err)
-				time.Sleep(5 * time.Second)
-				continue 
+	registryURL, err := clctrl.GetRepoURL()
+	if err != nil {
+		return err
+	}
+
+	var registryPath string
+	if clctrl.CloudProvider == "k3d" {
+		registryPath = fmt.Sprintf("registry/%s", clctrl.ClusterName)
+	} else {
+		registryPath = fmt.Sprintf("registry/clusters/%s", clctrl.ClusterName)
+	}
+
+	registryApplicationObject := argocd.GetArgoCDApplicationObject(
+		registryURL,
+		registryPath,
+	)
+	argocd.SetSyncPolicy(registryApplicationObject, clctrl.ArgoCDSyncPolicy)
+
+	err = argocdClient.RestartApplicationSetController()
+	if err != nil {
+		clctrl.logger().Infof("Error executing kubectl command: %v\n", err)
+		return err
+	}
+
+	retryAttempts := 2
+	for attempt := 1; attempt <= retryAttempts; attempt++ {
+		clctrl.logger().Infof("Attempt #%d to create Argo CD application...\n", attempt)
+
+		app, err := argocdClient.CreateApplication(registryApplicationObject)
+		if err != nil {
+			if attempt == retryAttempts {
+				return err
 			}
+			clctrl.logger().Infof("Error creating Argo CD application on attempt number #%d: %v\n", attempt, err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		clctrl.logger().Infof("Argo CD application created successfully on attempt #%d: %s\n", attempt, app.Name)
+		break
+	}
+
+	return nil
+}
+
+// argoCDHost returns the hostname argocd is exposed on
+func (clctrl *ClusterController) argoCDHost() string {
+	if clctrl.ArgoCDHost != "" {
+		return clctrl.ArgoCDHost
+	}
 
-			log.Info().Msgf("Argo CD application created successfully on attempt #%d: %s\n", attempt, app.Name)
-			break 
+	if clctrl.SubdomainName != "" {
+		return fmt.Sprintf("argocd.%s.%s", clctrl.SubdomainName, clctrl.DomainName)
+	}
+
+	return fmt.Sprintf("argocd.%s", clctrl.DomainName)
+}
+
+// GetArgoCDCredentials returns the argocd url and admin credentials for the cluster
+//
+// The password is read from the argocd-initial-admin-secret when it still
+// exists and falls back to the password stored on the cluster record
+func (clctrl *ClusterController) GetArgoCDCredentials() (pkgtypes.ArgoCDCredentials, error) {
+	credentials := pkgtypes.ArgoCDCredentials{
+		URL:      fmt.Sprintf("https://%s", clctrl.argoCDHost()),
+		Username: "admin",
+	}
+
+	kcfg, err := clctrl.GetClusterKubernetesClient()
+	if err != nil {
+		return credentials, err
+	}
+
+	secretClient := kcfg.Clientset.CoreV1().Secrets("argocd")
+	credentials.Password = k8s.GetSecretValue(secretClient, "argocd-initial-admin-secret", "password")
+	if credentials.Password == "" {
+		cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+		if err != nil {
+			return credentials, err
 		}
+		credentials.Password = cl.ArgoCDPassword
+	}
 
+	if credentials.Password == "" {
+		return credentials, fmt.Errorf("argocd password not found for cluster %s", clctrl.ClusterName)
+	}
 
-		telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.CreateRegistryCompleted, "")
+	return credentials, nil
+}
 
-		clctrl.Cluster.ArgoCDCreateRegistryCheck = true
-		err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
+// RotateArgoCDPassword sets a new random argocd admin password and returns the
+// updated credentials. The cluster record and its copy exported to the
+// cluster get the new password and an auth token issued with it
+func (clctrl *ClusterController) RotateArgoCDPassword() (pkgtypes.ArgoCDCredentials, error) {
+	err := CheckWritable()
+	if err != nil {
+		return pkgtypes.ArgoCDCredentials{}, err
+	}
+
+	err = clctrl.VerifyConnectivity()
+	if err != nil {
+		return pkgtypes.ArgoCDCredentials{}, err
+	}
+
+	kcfg, err := clctrl.GetClusterKubernetesClient()
+	if err != nil {
+		return pkgtypes.ArgoCDCredentials{}, err
+	}
+
+	password := pkg.Random(20)
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return pkgtypes.ArgoCDCredentials{}, fmt.Errorf("error hashing argocd password: %s", err)
+	}
+
+	secretClient := kcfg.Clientset.CoreV1().Secrets("argocd")
+
+	// argocd-secret holds the password argocd authenticates against
+	argocdSecret, err := secretClient.Get(context.Background(), "argocd-secret", metav1.GetOptions{})
+	if err != nil {
+		return pkgtypes.ArgoCDCredentials{}, fmt.Errorf("error reading argocd-secret: %s", err)
+	}
+	if argocdSecret.Data == nil {
+		argocdSecret.Data = map[string][]byte{}
+	}
+	argocdSecret.Data["admin.password"] = passwordHash
+	argocdSecret.Data["admin.passwordMtime"] = []byte(time.Now().UTC().Format(time.RFC3339))
+	_, err = secretClient.Update(context.Background(), argocdSecret, metav1.UpdateOptions{})
+	if err != nil {
+		return pkgtypes.ArgoCDCredentials{}, fmt.Errorf("error updating argocd-secret: %s", err)
+	}
+
+	// keep the initial admin secret in step so it can still be used for lookups
+	initialSecret, err := secretClient.Get(context.Background(), "argocd-initial-admin-secret", metav1.GetOptions{})
+	if err == nil {
+		initialSecret.Data["password"] = []byte(password)
+		_, err = secretClient.Update(context.Background(), initialSecret, metav1.UpdateOptions{})
+		if err != nil {
+			clctrl.logger().Warnf("error updating argocd-initial-admin-secret: %s", err)
+		}
+	}
+
+	// argocd rejects tokens issued before the password changed
+	argoCDToken := ""
+	argocdClient, err := clctrl.argoClient(kcfg)
+	if err == nil {
+		argoCDToken, err = argocdClient.AuthToken("admin", password)
+	}
+	if err != nil {
+		clctrl.logger().Warnf("error getting an argocd auth token with the new password: %s", err)
+	}
+
+	// the record is read again so changes made since the controller was
+	// initialized aren't overwritten
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return pkgtypes.ArgoCDCredentials{}, err
+	}
+	cl.ArgoCDPassword = password
+	if argoCDToken != "" {
+		cl.ArgoCDAuthToken = argoCDToken
+	}
+	err = secrets.UpdateCluster(clctrl.KubernetesClient, cl)
+	if err != nil {
+		return pkgtypes.ArgoCDCredentials{}, err
+	}
+	clctrl.Cluster = cl
+
+	err = clctrl.updateExportedArgoCDCredentials(kcfg, cl)
+	if err != nil {
+		return pkgtypes.ArgoCDCredentials{}, err
+	}
+
+	clctrl.logger().Infof("rotated argocd admin password for cluster %s", clctrl.ClusterName)
+
+	return pkgtypes.ArgoCDCredentials{
+		URL:      fmt.Sprintf("https://%s", clctrl.argoCDHost()),
+		Username: "admin",
+		Password: password,
+	}, nil
+}
+
+// updateExportedArgoCDCredentials writes the argocd credentials of the cluster
+// record to the copy of the record exported to the cluster, which imports
+// read instead of the database
+func (clctrl *ClusterController) updateExportedArgoCDCredentials(kcfg *k8s.KubernetesClient, cl pkgtypes.Cluster) error {
+	current, err := k8s.ReadSecretV2(kcfg.Clientset, "kubefirst", "kubefirst-initial-state")
+	if err != nil {
+		clctrl.logger().Warnf("skipping secret kubefirst/kubefirst-initial-state: %s", err)
+		return nil
+	}
+
+	values := map[string][]byte{}
+	for key, value := range current {
+		values[key] = []byte(value)
+	}
+	// the exported record holds json encoded values
+	for key, value := range map[string]string{"argocd_password": cl.ArgoCDPassword, "argocd_auth_token": cl.ArgoCDAuthToken} {
+		encoded, err := json.Marshal(value)
 		if err != nil {
 			return err
 		}
+		values[key] = encoded
+	}
+
+	err = k8s.UpdateSecretV2(kcfg.Clientset, "kubefirst", "kubefirst-initial-state", values)
+	if err != nil {
+		return fmt.Errorf("error updating secret kubefirst/kubefirst-initial-state: %s", err)
+	}
+
+	return nil
+}
+
+// validateArgoCDHost makes sure an argocd host override is a valid hostname
+// within the cluster domain so dns records and certificates can be issued for it
+func validateArgoCDHost(host string, domainName string) error {
+	if errs := validation.IsDNS1123Subdomain(host); len(errs) > 0 {
+		return fmt.Errorf("invalid argocd host %s: %s", host, strings.Join(errs, ", "))
+	}
+
+	if !strings.HasSuffix(host, fmt.Sprintf(".%s", domainName)) {
+		return fmt.Errorf("invalid argocd host %s: must be a subdomain of %s", host, domainName)
+	}
+
+	return nil
+}
+
+// validateArgoCDSyncPolicy makes sure the sync mode is known and prune and
+// self heal are only turned off for automated sync
+func validateArgoCDSyncPolicy(policy pkgtypes.ArgoCDSyncPolicy) error {
+	switch policy.Mode {
+	case "", pkgtypes.SyncModeAutomated:
+	case pkgtypes.SyncModeManual:
+		if policy.DisablePrune || policy.DisableSelfHeal {
+			return fmt.Errorf("argocd_sync_policy.disable_prune and disable_self_heal only apply to automated sync")
+		}
+	default:
+		return fmt.Errorf("unsupported argocd sync mode %s: must be one of %s, %s", policy.Mode, pkgtypes.SyncModeAutomated, pkgtypes.SyncModeManual)
+	}
+
+	return nil
+}
+
+// argoCDCertificateAttempts and argoCDCertificateInterval bound how long
+// cert-manager is given to issue the argocd certificate, verifyHostCertificate
+// is replaced in tests
+var (
+	argoCDCertificateAttempts = 60
+	argoCDCertificateInterval = 30 * time.Second
+	verifyHostCertificate     = ssl.VerifyHostCertificate
+)
+
+// VerifyArgoCDCertificate checks that argocd serves a valid certificate for its
+// host, waiting for the certificate to be issued
+func (clctrl *ClusterController) VerifyArgoCDCertificate() error {
+	host := clctrl.argoCDHost()
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = verifyHostCertificate(host)
+		if err == nil || attempt == argoCDCertificateAttempts {
+			break
+		}
+		clctrl.logger().Warnf("waiting for the argocd certificate to be issued: %s", err)
+		time.Sleep(argoCDCertificateInterval)
+	}
+	if err != nil {
+		return fmt.Errorf("argocd certificate was not issued: %s", err)
 	}
+	clctrl.logger().Infof("argocd serves a valid certificate for %s", host)
 
 	return nil
 }
@@ -0,0 +1,121 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	v1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	argocdapi "github.com/argoproj/argo-cd/v2/pkg/client/clientset/versioned"
+	health "github.com/argoproj/gitops-engine/pkg/health"
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+	"github.com/kubefirst/kubefirst-api/internal/argocd"
+	"github.com/kubefirst/kubefirst-api/internal/k8s"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// argoCDSelfApplication is the argocd Application through which argocd
+// manages its own installation from the gitops registry
+const argoCDSelfApplication = "argocd"
+
+// RepairArgoCD checks that argocd is running and reconciling its own
+// Application, and re-applies the bootstrap manifests and registry
+// Application when it is missing, out of sync or unhealthy - a healthy argocd
+// is left untouched so it is safe to run repeatedly
+func (clctrl *ClusterController) RepairArgoCD() (pkgtypes.ArgoCDRepairResult, error) {
+	result := pkgtypes.ArgoCDRepairResult{}
+
+	err := CheckWritable()
+	if err != nil {
+		return result, err
+	}
+
+	err = clctrl.VerifyConnectivity()
+	if err != nil {
+		return result, err
+	}
+
+	kcfg, err := clctrl.GetClusterKubernetesClient()
+	if err != nil {
+		return result, err
+	}
+
+	needsRepair := false
+	_, err = k8s.VerifyArgoCDReadiness(kcfg.Clientset, true, 60)
+	if err != nil {
+		clctrl.logger().Warnf("argocd is not ready on cluster %s: %s", clctrl.ClusterName, err)
+		needsRepair = true
+	}
+
+	app, err := argocd.GetApplication(kcfg.Clientset, argoCDSelfApplication)
+	switch {
+	case err != nil:
+		clctrl.logger().Warnf("argocd application %s not found on cluster %s: %s", argoCDSelfApplication, clctrl.ClusterName, err)
+		needsRepair = true
+	default:
+		result.SyncStatus = string(app.Status.Sync.Status)
+		result.HealthStatus = string(app.Status.Health.Status)
+		if app.Status.Sync.Status != v1alpha1.SyncStatusCodeSynced || app.Status.Health.Status != health.HealthStatusHealthy {
+			clctrl.logger().Warnf("argocd application %s is %s/%s on cluster %s", argoCDSelfApplication, result.SyncStatus, result.HealthStatus, clctrl.ClusterName)
+			needsRepair = true
+		}
+	}
+
+	if !needsRepair {
+		clctrl.logger().Infof("argocd is healthy on cluster %s, nothing to repair", clctrl.ClusterName)
+		return result, nil
+	}
+
+	argoCDInstallPath := fmt.Sprintf("github.com:kubefirst/manifests/argocd/cloud?ref=%s", pkg.KubefirstManifestRepoRef)
+	clctrl.logger().Infof("re-applying argocd bootstrap manifests on cluster %s", clctrl.ClusterName)
+	err = argocd.ApplyArgoCDKustomize(kcfg.Clientset, argoCDInstallPath)
+	if err != nil {
+		return result, fmt.Errorf("error re-applying argocd bootstrap manifests: %s", err)
+	}
+	result.Repaired = true
+	result.Actions = append(result.Actions, "re-applied argocd bootstrap manifests")
+
+	_, err = k8s.VerifyArgoCDReadiness(kcfg.Clientset, true, 300)
+	if err != nil {
+		return result, fmt.Errorf("error waiting for argocd to become ready: %s", err)
+	}
+
+	argocdClient, err := argocdapi.NewForConfig(kcfg.RestConfig)
+	if err != nil {
+		return result, err
+	}
+
+	_, err = argocdClient.ArgoprojV1alpha1().Applications("argocd").Get(context.Background(), "registry", metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		registryURL, err := clctrl.GetRepoURL()
+		if err != nil {
+			return result, err
+		}
+
+		registryPath := fmt.Sprintf("registry/clusters/%s", clctrl.ClusterName)
+		if clctrl.CloudProvider == "k3d" {
+			registryPath = fmt.Sprintf("registry/%s", clctrl.ClusterName)
+		}
+
+		registryApplication := argocd.GetArgoCDApplicationObject(registryURL, registryPath)
+		argocd.SetSyncPolicy(registryApplication, clctrl.ArgoCDSyncPolicy)
+		_, err = argocdClient.ArgoprojV1alpha1().Applications("argocd").Create(context.Background(), registryApplication, metav1.CreateOptions{})
+		if err != nil && !k8serrors.IsAlreadyExists(err) {
+			return result, fmt.Errorf("error recreating registry application: %s", err)
+		}
+		result.Actions = append(result.Actions, "recreated registry application")
+	} else if err != nil {
+		return result, fmt.Errorf("error getting registry application: %s", err)
+	}
+
+	clctrl.logger().Infof("repaired argocd on cluster %s: %v", clctrl.ClusterName, result.Actions)
+
+	return result, nil
+}
@@ -0,0 +1,197 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	v1alpha1ArgocdApplication "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+
+	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
+)
+
+func TestApplyRegistryApplication(t *testing.T) {
+	tests := []struct {
+		name          string
+		cloudProvider string
+		gitProtocol   string
+		wantRepoURL   string
+		wantPath      string
+	}{
+		{"https", "civo", "https", "https://github.com/kubefirst-test/gitops.git", "registry/clusters/kf-test"},
+		{"ssh", "aws", "ssh", "git@github.com:kubefirst-test/gitops.git", "registry/clusters/kf-test"},
+		{"k3d", "k3d", "https", "https://github.com/kubefirst-test/gitops.git", "registry/kf-test"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeArgocdClient{}
+			clctrl := &ClusterController{
+				ClusterName:   "kf-test",
+				CloudProvider: tt.cloudProvider,
+				GitProvider:   "github",
+				GitProtocol:   tt.gitProtocol,
+				ProviderConfig: providerConfigs.ProviderConfig{
+					DestinationGitopsRepoURL:    "https://github.com/kubefirst-test/gitops.git",
+					DestinationGitopsRepoGitURL: "git@github.com:kubefirst-test/gitops.git",
+				},
+			}
+
+			err := clctrl.applyRegistryApplication(fake)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if fake.Restarts != 1 {
+				t.Errorf("expected the applicationset controller to be restarted once, got %d", fake.Restarts)
+			}
+			if len(fake.Applications) != 1 {
+				t.Fatalf("expected 1 application, got %d", len(fake.Applications))
+			}
+			app := fake.Applications[0]
+			if app.Name != "registry" || app.Namespace != "argocd" {
+				t.Errorf("expected argocd/registry, got %s/%s", app.Namespace, app.Name)
+			}
+			if app.Spec.Source.RepoURL != tt.wantRepoURL {
+				t.Errorf("expected repo url %s, got %s", tt.wantRepoURL, app.Spec.Source.RepoURL)
+			}
+			if app.Spec.Source.Path != tt.wantPath {
+				t.Errorf("expected path %s, got %s", tt.wantPath, app.Spec.Source.Path)
+			}
+			if app.Spec.SyncPolicy == nil || app.Spec.SyncPolicy.Automated == nil || !app.Spec.SyncPolicy.Automated.Prune {
+				t.Errorf("expected an automated sync policy with prune")
+			}
+		})
+	}
+}
+
+func TestInitializeArgoCD(t *testing.T) {
+	tests := []struct {
+		name          string
+		cloudProvider string
+		wantToken     string
+	}{
+		{"port-forwarded provider", "civo", "argocd-token"},
+		{"akamai skips the token", "akamai", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeArgocdClient{Password: "admin-password", Token: "argocd-token"}
+			clctrl := &ClusterController{ClusterName: "kf-test", CloudProvider: tt.cloudProvider}
+
+			password, token, err := clctrl.initializeArgoCD(fake)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if password != "admin-password" {
+				t.Errorf("expected the initial admin password, got %s", password)
+			}
+			if token != tt.wantToken {
+				t.Errorf("expected token %q, got %q", tt.wantToken, token)
+			}
+		})
+	}
+}
+
+func TestInitializeArgoCDWithoutPassword(t *testing.T) {
+	clctrl := &ClusterController{ClusterName: "kf-test", CloudProvider: "civo"}
+
+	_, _, err := clctrl.initializeArgoCD(&fakeArgocdClient{})
+	if err == nil {
+		t.Fatal("expected an error when the initial admin secret has no password")
+	}
+}
+
+func TestVerifyArgoCDCertificate(t *testing.T) {
+	defer func(attempts int, interval time.Duration, verify func(string) error) {
+		argoCDCertificateAttempts, argoCDCertificateInterval, verifyHostCertificate = attempts, interval, verify
+	}(argoCDCertificateAttempts, argoCDCertificateInterval, verifyHostCertificate)
+	argoCDCertificateAttempts = 3
+	argoCDCertificateInterval = time.Millisecond
+
+	tests := []struct {
+		name     string
+		failures int
+		wantErr  bool
+	}{
+		{"issued", 0, false},
+		{"issued while waiting", 2, false},
+		{"never issued", 3, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hosts []string
+			verifyHostCertificate = func(host string) error {
+				hosts = append(hosts, host)
+				if len(hosts) <= tt.failures {
+					return fmt.Errorf("certificate for %s is not valid: x509: certificate signed by unknown authority", host)
+				}
+				return nil
+			}
+			clctrl := &ClusterController{ClusterName: "kf-test", DomainName: "example.com"}
+
+			err := clctrl.VerifyArgoCDCertificate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyArgoCDCertificate() = %v, want error %v", err, tt.wantErr)
+			}
+			if hosts[0] != "argocd.example.com" {
+				t.Errorf("expected argocd.example.com to be verified, got %s", hosts[0])
+			}
+		})
+	}
+}
+
+// fakeArgocdClient is an argocd.Client that keeps the applications it's asked
+// to create instead of applying them
+type fakeArgocdClient struct {
+	Password string
+	Token    string
+
+	// CreateErrors are returned by the first calls to CreateApplication
+	CreateErrors []error
+
+	Applications []*v1alpha1ArgocdApplication.Application
+	Restarts     int
+}
+
+func (f *fakeArgocdClient) InitialAdminPassword() (string, error) {
+	if f.Password == "" {
+		return "", fmt.Errorf("argocd password not found in secret")
+	}
+
+	return f.Password, nil
+}
+
+func (f *fakeArgocdClient) AuthToken(username string, password string) (string, error) {
+	if password != f.Password {
+		return "", fmt.Errorf("invalid argocd credentials for %s", username)
+	}
+
+	return f.Token, nil
+}
+
+func (f *fakeArgocdClient) RestartApplicationSetController() error {
+	f.Restarts++
+
+	return nil
+}
+
+func (f *fakeArgocdClient) CreateApplication(app *v1alpha1ArgocdApplication.Application) (*v1alpha1ArgocdApplication.Application, error) {
+	if len(f.CreateErrors) != 0 {
+		err := f.CreateErrors[0]
+		f.CreateErrors = f.CreateErrors[1:]
+		return nil, err
+	}
+
+	f.Applications = append(f.Applications, app.DeepCopy())
+
+	return app, nil
+}
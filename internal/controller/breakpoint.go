@@ -0,0 +1,101 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"errors"
+	"fmt"
+
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+	"github.com/kubefirst/kubefirst-api/internal/constants"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// ErrPaused is returned by RunStep once the StopAfterStep breakpoint is
+// reached, the create pipeline returns it without marking the cluster failed
+var ErrPaused = errors.New("provisioning paused at the stop_after_step breakpoint")
+
+// provisioningSteps are the steps of each cloud provider's create pipeline,
+// in order, that stop_after_step and resume_from can name
+var provisioningSteps = map[string][]string{
+	"akamai":       {"DomainLivenessTest", "StateStoreCredentials", "StateStoreCreate", "ProbeStateStore", "GitInit", "InitializeBot", "RepositoryPrep", "RunGitTerraform", "RepositoryPush", "CreateCluster", "ConfigureWorkloadIdentity", "ValidateStorageClass", "CreateImagePullSecrets", "ClusterSecretsBootstrap", "InstallArgoCD", "InitializeArgoCD", "DeployRegistryApplication", "WaitForVault", "InitializeVault", "RunVaultTerraform", "WriteVaultSecrets", "RunUsersTerraform", "VerifyArgoCDCertificate", "ApplyPostInstallManifests", "ExportClusterRecord"},
+	"aws":          {"DomainLivenessTest", "StateStoreCredentials", "ProbeStateStore", "GitInit", "InitializeBot", "RepositoryPrep", "RunGitTerraform", "RepositoryPush", "CreateCluster", "DetokenizeKMSKeyID", "WaitForClusterReady", "InstallArgoCD", "InitializeArgoCD", "ConfigureWorkloadIdentity", "ValidateStorageClass", "VerifyEgressIP", "CreateImagePullSecrets", "ClusterSecretsBootstrap", "DeployRegistryApplication", "WaitForVault", "InitializeVault", "RunVaultTerraform", "WriteVaultSecrets", "RunUsersTerraform", "VerifyArgoCDCertificate", "ApplyPostInstallManifests", "ExportClusterRecord"},
+	"civo":         {"DomainLivenessTest", "StateStoreCredentials", "StateStoreCreate", "ProbeStateStore", "GitInit", "InitializeBot", "RepositoryPrep", "RunGitTerraform", "RepositoryPush", "CreateCluster", "ConfigureWorkloadIdentity", "ValidateStorageClass", "CreateImagePullSecrets", "ClusterSecretsBootstrap", "InstallArgoCD", "InitializeArgoCD", "DeployRegistryApplication", "WaitForVault", "InitializeVault", "RunVaultTerraform", "WriteVaultSecrets", "RunUsersTerraform", "VerifyArgoCDCertificate", "ApplyPostInstallManifests", "ExportClusterRecord"},
+	"digitalocean": {"DomainLivenessTest", "StateStoreCredentials", "ProbeStateStore", "GitInit", "InitializeBot", "RepositoryPrep", "RunGitTerraform", "RepositoryPush", "CreateCluster", "WaitForClusterReady", "ConfigureWorkloadIdentity", "ValidateStorageClass", "CreateImagePullSecrets", "ClusterSecretsBootstrap", "InstallArgoCD", "InitializeArgoCD", "DeployRegistryApplication", "WaitForVault", "InitializeVault", "RunVaultTerraform", "WriteVaultSecrets", "RunUsersTerraform", "VerifyArgoCDCertificate", "ApplyPostInstallManifests", "ExportClusterRecord"},
+	"google":       {"DomainLivenessTest", "StateStoreCredentials", "ProbeStateStore", "GitInit", "InitializeBot", "RepositoryPrep", "RunGitTerraform", "RepositoryPush", "CreateCluster", "DetokenizeKMSKeyID", "WaitForClusterReady", "InstallArgoCD", "InitializeArgoCD", "ConfigureWorkloadIdentity", "ValidateStorageClass", "VerifyEgressIP", "CreateImagePullSecrets", "ClusterSecretsBootstrap", "DeployRegistryApplication", "WaitForVault", "InitializeVault", "RunVaultTerraform", "WriteVaultSecrets", "RunUsersTerraform", "VerifyArgoCDCertificate", "ApplyPostInstallManifests", "ExportClusterRecord"},
+	"k3s":          {"DomainLivenessTest", "StateStoreCredentials", "GitInit", "InitializeBot", "RepositoryPrep", "RunGitTerraform", "RepositoryPush", "CreateCluster", "InstallCNI", "WaitForClusterReady", "ConfigureWorkloadIdentity", "ValidateStorageClass", "CreateImagePullSecrets", "ClusterSecretsBootstrap", "InstallArgoCD", "InitializeArgoCD", "DeployRegistryApplication", "WaitForVault", "InitializeVault", "RunVaultTerraform", "WriteVaultSecrets", "RunUsersTerraform", "VerifyArgoCDCertificate", "ApplyPostInstallManifests", "ExportClusterRecord"},
+	"vultr":        {"DomainLivenessTest", "StateStoreCredentials", "ProbeStateStore", "GitInit", "InitializeBot", "RepositoryPrep", "RunGitTerraform", "RepositoryPush", "CreateCluster", "WaitForClusterReady", "ConfigureWorkloadIdentity", "ValidateStorageClass", "CreateImagePullSecrets", "ClusterSecretsBootstrap", "InstallArgoCD", "InitializeArgoCD", "DeployRegistryApplication", "WaitForVault", "InitializeVault", "RunVaultTerraform", "WriteVaultSecrets", "RunUsersTerraform", "VerifyArgoCDCertificate", "ApplyPostInstallManifests", "ExportClusterRecord"},
+}
+
+// validateBreakpoints makes sure stop_after_step and resume_from name steps
+// of the cloud provider's pipeline, and that a pipeline resumed from a step
+// doesn't skip the step it should stop after
+func validateBreakpoints(def *pkgtypes.ClusterDefinition) error {
+	steps := provisioningSteps[def.CloudProvider]
+	for field, step := range map[string]string{"stop_after_step": def.StopAfterStep, "resume_from": def.ResumeFrom} {
+		if step != "" && !pkg.FindStringInSlice(steps, step) {
+			return fmt.Errorf("invalid %s %s: must be one of %v", field, step, steps)
+		}
+	}
+
+	if def.StopAfterStep != "" && def.ResumeFrom != "" && stepIndex(steps, def.StopAfterStep) < stepIndex(steps, def.ResumeFrom) {
+		return fmt.Errorf("stop_after_step %s comes before resume_from %s and would never be reached", def.StopAfterStep, def.ResumeFrom)
+	}
+
+	return nil
+}
+
+// stepIndex returns the position of step in steps, -1 when it's missing
+func stepIndex(steps []string, step string) int {
+	for i, s := range steps {
+		if s == step {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// RunStep runs one step of the create pipeline - steps before ResumeFrom
+// are skipped, and after StopAfterStep the record is marked paused and
+// ErrPaused is returned so the pipeline stops with its resources up
+func (clctrl *ClusterController) RunStep(name string, step func() error) error {
+	if clctrl.resuming {
+		if name != clctrl.ResumeFrom {
+			clctrl.logger().Infof("skipping step %s, resuming from %s", name, clctrl.ResumeFrom)
+			clctrl.publishStep(name, "skipped", "")
+			return nil
+		}
+		clctrl.resuming = false
+	}
+
+	clctrl.publishStep(name, "started", "")
+	err := step()
+	if err != nil {
+		clctrl.publishStep(name, "failed", err.Error())
+		return err
+	}
+	clctrl.publishStep(name, "succeeded", "")
+
+	if name != clctrl.StopAfterStep {
+		return nil
+	}
+
+	clctrl.paused = true
+	clctrl.Cluster.Status = constants.ClusterStatusPaused
+	clctrl.Cluster.InProgress = false
+	clctrl.Cluster.PausedAfterStep = name
+	clctrl.Cluster.LastCondition = fmt.Sprintf("provisioning paused after %s, create the cluster again to continue", name)
+	err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
+	if err != nil {
+		return err
+	}
+	clctrl.logger().Infof("cluster %s paused after step %s", clctrl.ClusterName, name)
+
+	return ErrPaused
+}
@@ -0,0 +1,90 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+
+	"github.com/kubefirst/kubefirst-api/internal/appCatalog"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	"github.com/kubefirst/kubefirst-api/internal/services"
+	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// InstallCatalogApp renders a catalog app template with values, after
+// validating them against the app's parameters, commits the resulting argocd
+// Application to the gitops registry and records it as a service
+func (clctrl *ClusterController) InstallCatalogApp(appName string, values map[string]interface{}) error {
+	err := CheckWritable()
+	if err != nil {
+		return err
+	}
+
+	app, err := appCatalog.Get(appName)
+	if err != nil {
+		return err
+	}
+
+	mergedValues, err := appCatalog.ValidateValues(app, values)
+	if err != nil {
+		return err
+	}
+
+	err = clctrl.VerifyConnectivity()
+	if err != nil {
+		return err
+	}
+
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	_, err = secrets.GetService(clctrl.KubernetesClient, clctrl.ClusterName, appName)
+	if err == nil {
+		return fmt.Errorf("service %s already exists on cluster %s", appName, clctrl.ClusterName)
+	}
+
+	fullDomainName := cl.DomainName
+	if cl.SubdomainName != "" {
+		fullDomainName = fmt.Sprintf("%s.%s", cl.SubdomainName, cl.DomainName)
+	}
+
+	manifest, err := appCatalog.Render(app, appCatalog.RenderContext{
+		ClusterName:  cl.ClusterName,
+		DomainName:   fullDomainName,
+		Destination:  "in-cluster",
+		IngressClass: providerConfigs.ResolveIngressController(cl.IngressController).IngressClass,
+		Project:      "default",
+		Values:       mergedValues,
+	})
+	if err != nil {
+		return err
+	}
+
+	clctrl.logger().Infof("installing catalog app %s on cluster %s", appName, clctrl.ClusterName)
+
+	err = services.AddRegistryApplication(&cl, appName, manifest, "kbot")
+	if err != nil {
+		return err
+	}
+
+	err = secrets.CreateClusterServiceList(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	return secrets.InsertClusterServiceListEntry(clctrl.KubernetesClient, clctrl.ClusterName, &pkgtypes.Service{
+		Name:        app.Name,
+		Default:     false,
+		Description: app.Description,
+		Image:       app.ImageURL,
+		Links:       []string{},
+		CreatedBy:   "kbot",
+	})
+}
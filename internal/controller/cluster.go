@@ -18,8 +18,8 @@ import (
 	digitaloceanext "github.com/kubefirst/kubefirst-api/extensions/digitalocean"
 	googleext "github.com/kubefirst/kubefirst-api/extensions/google"
 	k3sext "github.com/kubefirst/kubefirst-api/extensions/k3s"
-	terraformext "github.com/kubefirst/kubefirst-api/extensions/terraform"
 	vultrext "github.com/kubefirst/kubefirst-api/extensions/vultr"
+	pkg "github.com/kubefirst/kubefirst-api/internal"
 	"github.com/kubefirst/kubefirst-api/internal/constants"
 	"github.com/kubefirst/kubefirst-api/internal/env"
 	gitShim "github.com/kubefirst/kubefirst-api/internal/gitShim"
@@ -28,27 +28,34 @@ import (
 	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
 	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
 	"github.com/kubefirst/metrics-client/pkg/telemetry"
-	log "github.com/rs/zerolog/log"
 	"github.com/thanhpk/randstr"
-	v1 "k8s.io/api/apps/v1"
 )
 
 // CreateCluster
 func (clctrl *ClusterController) CreateCluster() error {
+	err := CheckWritable()
+	if err != nil {
+		return err
+	}
+
 	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
 	if err != nil {
 		return err
 	}
 
 	if !cl.CloudTerraformApplyCheck || cl.CloudTerraformApplyFailedCheck {
+		err = clctrl.ValidateExistingNetwork(&cl)
+		if err != nil {
+			return err
+		}
 
-		log.Info().Msg("creating aws cloud resources with terraform")
+		clctrl.logger().Info("creating aws cloud resources with terraform")
 		tfEntrypoint := clctrl.ProviderConfig.GitopsDir + fmt.Sprintf("/terraform/%s", clctrl.CloudProvider)
 		tfEnvs := map[string]string{}
 
-		telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.CloudTerraformApplyStarted, "")
+		clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.CloudTerraformApplyStarted, "")
 
-		log.Info().Msgf("creating %s cluster", clctrl.CloudProvider)
+		clctrl.logger().Infof("creating %s cluster", clctrl.CloudProvider)
 
 		switch clctrl.CloudProvider {
 		case "akamai":
@@ -78,29 +85,55 @@ func (clctrl *ClusterController) CreateCluster() error {
 		case "k3s":
 			tfEnvs = k3sext.GetK3sTerraformEnvs(tfEnvs, &cl)
 		}
+		tfEnvs = getExistingNetworkTerraformEnvs(tfEnvs, cl.ExistingNetwork)
+		err := checkExistingNetworkTerraform(tfEntrypoint, cl.ExistingNetwork)
+		if err != nil {
+			return err
+		}
+		tfEnvs = getEgressGatewayTerraformEnvs(tfEnvs, cl.EgressGateway)
+		tfEnvs = getCNITerraformEnvs(tfEnvs, cl.CloudProvider, cl.CNI)
+		err = checkCNITerraform(tfEntrypoint, cl.CNI)
+		if err != nil {
+			return err
+		}
+		tfEnvs = getKubernetesVersionTerraformEnvs(tfEnvs, cl.KubernetesVersion)
+		tfEnvs = getTagsTerraformEnvs(tfEnvs, cl.CloudProvider, clusterResourceTags(cl))
+		tfEnvs = getAPIURLTerraformEnvs(tfEnvs, cl.CloudProvider, clusterAPIURL(&cl))
+
+		err = clctrl.runTerraformHooks(terraformHookStagePre, tfEntrypoint)
+		if err != nil {
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.CloudTerraformApplyFailed, err.Error())
+			return err
+		}
 
-		err := terraformext.InitApplyAutoApprove(clctrl.ProviderConfig.TerraformClient, tfEntrypoint, tfEnvs)
+		err = clctrl.terraformApply(tfEntrypoint, tfEnvs)
 		if err != nil {
-			log.Error().Msgf("error applying cloud terraform: %s", err)
-			log.Info().Msg("sleeping 10 seconds before retrying terraform execution once more")
+			clctrl.logger().Errorf("error applying cloud terraform: %s", err)
+			clctrl.logger().Info("sleeping 10 seconds before retrying terraform execution once more")
 			time.Sleep(10 * time.Second)
-			err = terraformext.InitApplyAutoApprove(clctrl.ProviderConfig.TerraformClient, tfEntrypoint, tfEnvs)
+			err = clctrl.terraformApply(tfEntrypoint, tfEnvs)
 			if err != nil {
-				telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.CloudTerraformApplyFailed, err.Error())
+				clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.CloudTerraformApplyFailed, err.Error())
 				msg := fmt.Sprintf("error creating %s resources with terraform %s: %s", clctrl.CloudProvider, tfEntrypoint, err)
-				log.Error().Msg(msg)
+				clctrl.logger().Error(msg)
 				clctrl.Cluster.CloudTerraformApplyFailedCheck = true
 				err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
 				if err != nil {
-					telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.CloudTerraformApplyFailed, err.Error())
+					clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.CloudTerraformApplyFailed, err.Error())
 					return err
 				}
 				return fmt.Errorf(msg)
 			}
 		}
 
-		log.Info().Msgf("created %s cloud resources", clctrl.CloudProvider)
-		telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.CloudTerraformApplyCompleted, "")
+		err = clctrl.runTerraformHooks(terraformHookStagePost, tfEntrypoint)
+		if err != nil {
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.CloudTerraformApplyFailed, err.Error())
+			return err
+		}
+
+		clctrl.logger().Infof("created %s cloud resources", clctrl.CloudProvider)
+		clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.CloudTerraformApplyCompleted, "")
 
 		clctrl.Cluster.CloudTerraformApplyCheck = true
 		clctrl.Cluster.CloudTerraformApplyFailedCheck = false
@@ -178,12 +211,33 @@ func (clctrl *ClusterController) CreateTokens(kind string) interface{} {
 			KubefirstTeam:             clctrl.KubefirstTeam,
 			NodeType:                  clctrl.NodeType,
 			NodeCount:                 clctrl.NodeCount,
+			NodeLabels:                clctrl.NodeLabels,
+			NodeTaints:                clctrl.NodeTaints,
+			ImageOverrides:            clctrl.ImageOverrides,
+			StorageClass:              clctrl.StorageClass,
+			ResourceOverrides:         providerConfigs.ResolveResources(clctrl.ResourceProfile, clctrl.ResourceOverrides),
+			InstallProfile:            clctrl.InstallProfile,
+			ImagePullSecrets:          imagePullSecretNames(clctrl.ImagePullSecrets),
+			IngressController:         clctrl.IngressController,
+			LoadBalancerAnnotations:   clctrl.LoadBalancerAnnotations,
+			TerraformBackend:          clctrl.TerraformBackend,
+			VolumeSizes:               clctrl.VolumeSizes,
+			PodSecurity:               clctrl.PodSecurity,
+			NetworkPolicies:           clctrl.NetworkPolicies,
+			CNI:                       clctrl.CNI,
+			ExternalSecrets:           clctrl.ExternalSecrets,
+			SpotNodePool:              clctrl.SpotNodePool,
+			Autoscaling:               clctrl.Autoscaling,
+			OIDC:                      clctrl.OIDC,
+			ArgoCDSyncPolicy:          clctrl.ArgoCDSyncPolicy,
+			DefaultBranch:             clctrl.DefaultBranch,
+			CommitMessage:             clctrl.CommitMessage,
 			KubefirstVersion:          env.KubefirstVersion,
 			Kubeconfig:                clctrl.ProviderConfig.Kubeconfig, // AWS
 			KubeconfigPath:            clctrl.ProviderConfig.Kubeconfig, // Not AWS
 
-			ArgoCDIngressURL:               fmt.Sprintf("https://argocd.%s", fullDomainName),
-			ArgoCDIngressNoHTTPSURL:        fmt.Sprintf("argocd.%s", fullDomainName),
+			ArgoCDIngressURL:               fmt.Sprintf("https://%s", clctrl.argoCDHost()),
+			ArgoCDIngressNoHTTPSURL:        clctrl.argoCDHost(),
 			ArgoWorkflowsIngressURL:        fmt.Sprintf("https://argo.%s", fullDomainName),
 			ArgoWorkflowsIngressNoHTTPSURL: fmt.Sprintf("argo.%s", fullDomainName),
 			AtlantisIngressURL:             fmt.Sprintf("https://atlantis.%s", fullDomainName),
@@ -213,8 +267,8 @@ func (clctrl *ClusterController) CreateTokens(kind string) interface{} {
 
 			GitopsRepoAtlantisWebhookURL:               clctrl.AtlantisWebhookURL,
 			GitopsRepoNoHTTPSURL:                       fmt.Sprintf("%s/%s/gitops.git", clctrl.GitHost, clctrl.GitAuth.Owner),
-			WorkloadClusterTerraformModuleURL:          fmt.Sprintf("git::https://%s/%s/gitops.git//terraform/%s/modules/workload-cluster?ref=main", clctrl.GitHost, clctrl.GitAuth.Owner, clctrl.CloudProvider),
-			WorkloadClusterBootstrapTerraformModuleURL: fmt.Sprintf("git::https://%s/%s/gitops.git//terraform/%s/modules/bootstrap?ref=main", clctrl.GitHost, clctrl.GitAuth.Owner, clctrl.CloudProvider),
+			WorkloadClusterTerraformModuleURL:          fmt.Sprintf("git::https://%s/%s/gitops.git//terraform/%s/modules/workload-cluster?ref=%s", clctrl.GitHost, clctrl.GitAuth.Owner, clctrl.CloudProvider, clctrl.DefaultBranch),
+			WorkloadClusterBootstrapTerraformModuleURL: fmt.Sprintf("git::https://%s/%s/gitops.git//terraform/%s/modules/bootstrap?ref=%s", clctrl.GitHost, clctrl.GitAuth.Owner, clctrl.CloudProvider, clctrl.DefaultBranch),
 			ClusterId: clctrl.ClusterID,
 
 			// external-dns optionality to provide cloudflare support regardless of cloud provider
@@ -223,7 +277,12 @@ func (clctrl *ClusterController) CreateTokens(kind string) interface{} {
 			ExternalDNSProviderSecretName:   fmt.Sprintf("%s-auth", clctrl.CloudProvider),
 			ExternalDNSProviderSecretKey:    externalDNSProviderSecretKey,
 
-			ContainerRegistryURL: fmt.Sprintf("%s/%s", clctrl.ContainerRegistryHost, clctrl.GitAuth.Owner),
+			ContainerRegistryURL: fmt.Sprintf("%s/%s", clctrl.ContainerRegistryHost, clctrl.MetaphorOwner),
+		}
+
+		// platform external secrets read from the cloud secret manager
+		if clctrl.UsesExternalSecrets() {
+			gitopsTemplateTokens.SecretStoreRef = providerConfigs.ExternalSecretStoreName
 		}
 
 		// Handle provider specific tokens
@@ -253,11 +312,11 @@ func (clctrl *ClusterController) CreateTokens(kind string) interface{} {
 
 			if clctrl.ECR {
 				gitopsTemplateTokens.ContainerRegistryURL = fmt.Sprintf("%s.dkr.ecr.%s.amazonaws.com", *iamCaller.Account, clctrl.CloudRegion)
-				log.Info().Msgf("Using ECR URL %s", gitopsTemplateTokens.ContainerRegistryURL)
+				clctrl.logger().Infof("Using ECR URL %s", gitopsTemplateTokens.ContainerRegistryURL)
 			} else {
 				// moving commented line below to default behavior
 				// gitopsTemplateTokens.ContainerRegistryURL = fmt.Sprintf("%s/%s", clctrl.ContainerRegistryHost, clctrl.GitAuth.Owner)
-				log.Info().Msgf("NOT using ECR but instead %s URL %s", clctrl.GitProvider, gitopsTemplateTokens.ContainerRegistryURL)
+				clctrl.logger().Infof("NOT using ECR but instead %s URL %s", clctrl.GitProvider, gitopsTemplateTokens.ContainerRegistryURL)
 			}
 		case "k3s":
 			gitopsTemplateTokens.K3sServersPrivateIps = clctrl.K3sAuth.K3sServersPrivateIps
@@ -271,7 +330,7 @@ func (clctrl *ClusterController) CreateTokens(kind string) interface{} {
 		metaphorTemplateTokens := &providerConfigs.MetaphorTokenValues{
 			ClusterName:                   clctrl.ClusterName,
 			CloudRegion:                   clctrl.CloudRegion,
-			ContainerRegistryURL:          fmt.Sprintf("%s/%s/metaphor", clctrl.ContainerRegistryHost, clctrl.GitAuth.Owner),
+			ContainerRegistryURL:          fmt.Sprintf("%s/%s/metaphor", clctrl.ContainerRegistryHost, clctrl.MetaphorOwner),
 			DomainName:                    fullDomainName,
 			MetaphorDevelopmentIngressURL: fmt.Sprintf("metaphor-development.%s", fullDomainName),
 			MetaphorStagingIngressURL:     fmt.Sprintf("metaphor-staging.%s", fullDomainName),
@@ -306,6 +365,11 @@ func (clctrl *ClusterController) ClusterSecretsBootstrap() error {
 	}
 	clientSet := kcfg.Clientset
 
+	err = clctrl.VerifyBootstrapPermissions(clientSet)
+	if err != nil {
+		return err
+	}
+
 	// create namespaces
 	err = providerConfigs.K8sNamespaces(clientSet)
 	if err != nil {
@@ -323,7 +387,7 @@ func (clctrl *ClusterController) ClusterSecretsBootstrap() error {
 		case "akamai":
 			err := akamaiext.BootstrapAkamaiMgmtCluster(clientSet, &cl, destinationGitopsRepoGitURL)
 			if err != nil {
-				log.Error().Msgf("error adding kubernetes secrets for bootstrap: %s", err)
+				clctrl.logger().Errorf("error adding kubernetes secrets for bootstrap: %s", err)
 				return err
 			}
 		case "aws":
@@ -334,37 +398,37 @@ func (clctrl *ClusterController) ClusterSecretsBootstrap() error {
 				clctrl.AwsClient,
 			)
 			if err != nil {
-				log.Error().Msgf("error adding kubernetes secrets for bootstrap: %s", err)
+				clctrl.logger().Errorf("error adding kubernetes secrets for bootstrap: %s", err)
 				return err
 			}
 		case "civo":
 			err := civoext.BootstrapCivoMgmtCluster(clientSet, &cl, destinationGitopsRepoGitURL)
 			if err != nil {
-				log.Error().Msgf("error adding kubernetes secrets for bootstrap: %s", err)
+				clctrl.logger().Errorf("error adding kubernetes secrets for bootstrap: %s", err)
 				return err
 			}
 		case "google":
 			err := googleext.BootstrapGoogleMgmtCluster(clientSet, &cl, destinationGitopsRepoGitURL)
 			if err != nil {
-				log.Error().Msgf("error adding kubernetes secrets for bootstrap: %s", err)
+				clctrl.logger().Errorf("error adding kubernetes secrets for bootstrap: %s", err)
 				return err
 			}
 		case "digitalocean":
 			err := digitaloceanext.BootstrapDigitaloceanMgmtCluster(clientSet, &cl, destinationGitopsRepoGitURL)
 			if err != nil {
-				log.Error().Msgf("error adding kubernetes secrets for bootstrap: %s", err)
+				clctrl.logger().Errorf("error adding kubernetes secrets for bootstrap: %s", err)
 				return err
 			}
 		case "vultr":
 			err := vultrext.BootstrapVultrMgmtCluster(clientSet, &cl, destinationGitopsRepoGitURL)
 			if err != nil {
-				log.Error().Msgf("error adding kubernetes secrets for bootstrap: %s", err)
+				clctrl.logger().Errorf("error adding kubernetes secrets for bootstrap: %s", err)
 				return err
 			}
 		case "k3s":
 			err := k3sext.BootstrapK3sMgmtCluster(clientSet, &cl, destinationGitopsRepoGitURL)
 			if err != nil {
-				log.Fatal().Msgf("error adding kubernetes secrets for bootstrap: %s", err)
+				clctrl.logger().Errorf("error adding kubernetes secrets for bootstrap: %s", err)
 				return err
 			}
 		}
@@ -409,7 +473,7 @@ func (clctrl *ClusterController) ContainerRegistryAuth() (string, error) {
 		}
 		containerRegistryAuthToken, err := gitShim.CreateContainerRegistrySecret(&containerRegistryAuth)
 		if err != nil {
-			log.Error().Msgf("error generating container registry authentication: %s", err)
+			clctrl.logger().Errorf("error generating container registry authentication: %s", err)
 			return "", err
 		}
 
@@ -436,62 +500,70 @@ func (clctrl *ClusterController) ContainerRegistryAuth() (string, error) {
 	}
 	containerRegistryAuthToken, err := gitShim.CreateContainerRegistrySecret(&containerRegistryAuth)
 	if err != nil {
-		log.Error().Msgf("error generating container registry authentication: %s", err)
+		clctrl.logger().Errorf("error generating container registry authentication: %s", err)
 		return "", err
 	}
 
 	return containerRegistryAuthToken, nil
 }
 
-// WaitForClusterReady
+// kubeconfigContextProviders are the cloud providers writing the cluster's
+// kubeconfig to a file whose context can be renamed, aws and google clients
+// are built from the cloud credentials instead and have no kubeconfig file
+var kubeconfigContextProviders = []string{"akamai", "civo", "digitalocean", "k3s", "vultr"}
+
+// validateKubeconfigContextName makes sure kubeconfig_context_name is only set
+// for providers that apply it
+func validateKubeconfigContextName(def *pkgtypes.ClusterDefinition) error {
+	if def.KubeconfigContextName == "" || pkg.FindStringInSlice(kubeconfigContextProviders, def.CloudProvider) {
+		return nil
+	}
+
+	return fmt.Errorf("kubeconfig_context_name is not supported for %s: must be one of %v", def.CloudProvider, kubeconfigContextProviders)
+}
+
+// setKubeconfigContext switches the kubeconfig of providers whose kubeconfig
+// holds several contexts to the cluster's
+func (clctrl *ClusterController) setKubeconfigContext() error {
+	if clctrl.KubeconfigContextName != "" && pkg.FindStringInSlice(kubeconfigContextProviders, clctrl.CloudProvider) {
+		return k8s.SetKubeconfigContextName(clctrl.ProviderConfig.Kubeconfig, clctrl.KubeconfigContextName)
+	}
+
+	return nil
+}
+
+// WaitForClusterReady waits for the cluster's dns and then for every node
+// pool of the definition to have its nodes ready
 func (clctrl *ClusterController) WaitForClusterReady() error {
-	var kcfg *k8s.KubernetesClient
+	err := clctrl.setKubeconfigContext()
+	if err != nil {
+		return err
+	}
 
-	switch clctrl.CloudProvider {
-	case "aws":
-		kcfg = awsext.CreateEKSKubeconfig(&clctrl.AwsClient.Config, clctrl.ClusterName)
-	case "civo", "digitalocean", "vultr", "k3s":
-		kcfg = k8s.CreateKubeConfig(false, clctrl.ProviderConfig.Kubeconfig)
-	case "google":
-		var err error
-		kcfg, err = clctrl.GoogleClient.GetContainerClusterAuth(clctrl.ClusterName, []byte(clctrl.GoogleAuth.KeyFile))
-		if err != nil {
-			return err
-		}
+	operations, err := clctrl.clusterOperations()
+	if err != nil {
+		return err
 	}
 
-	var dnsDeployment *v1.Deployment
-	var err error
+	var matchLabel, matchLabelValue string
 	switch clctrl.CloudProvider {
 	case "aws", "civo", "digitalocean", "vultr", "k3s":
-		dnsDeployment, err = k8s.ReturnDeploymentObject(
-			kcfg.Clientset,
-			"kubernetes.io/name",
-			"CoreDNS",
-			"kube-system",
-			300,
-		)
-		if err != nil {
-			log.Error().Msgf("error finding CoreDNS deployment: %s", err)
-			return err
-		}
+		matchLabel, matchLabelValue = "kubernetes.io/name", "CoreDNS"
 	case "google":
-		dnsDeployment, err = k8s.ReturnDeploymentObject(
-			kcfg.Clientset,
-			"k8s-app",
-			"kube-dns",
-			"kube-system",
-			300,
-		)
-		if err != nil {
-			log.Error().Msgf("error finding CoreDNS deployment: %s", err)
-			return err
-		}
+		matchLabel, matchLabelValue = "k8s-app", "kube-dns"
+	default:
+		return fmt.Errorf("waiting for cluster readiness is not supported for %s", clctrl.CloudProvider)
+	}
+
+	err = operations.WaitForDeployment(matchLabel, matchLabelValue, "kube-system", clctrl.timeouts().ClusterCreate)
+	if err != nil {
+		clctrl.logger().Errorf("error waiting for CoreDNS deployment ready state: %s", err)
+		return err
 	}
 
-	_, err = k8s.WaitForDeploymentReady(kcfg.Clientset, dnsDeployment, 120)
+	err = clctrl.waitForNodePools(operations)
 	if err != nil {
-		log.Error().Msgf("error waiting for CoreDNS deployment ready state: %s", err)
+		clctrl.logger().Errorf("error waiting for node pools: %s", err)
 		return err
 	}
 
@@ -0,0 +1,207 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	terraformext "github.com/kubefirst/kubefirst-api/extensions/terraform"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// How a cloud provider runs a custom cni
+const (
+	// cniModeManaged providers get the cni from the cloud terraform, which
+	// selects it or installs it itself
+	cniModeManaged = "managed"
+
+	// cniModeInstalled providers create the cluster without its default cni
+	// and kubefirst applies the cni's manifests before anything else runs,
+	// only for providers whose terraform doesn't wait for ready nodes
+	cniModeInstalled = "installed"
+)
+
+// cniProviders are the cloud providers each cni is supported on and how it's
+// run there - digitalocean clusters already run cilium, google runs it as
+// dataplane v2, civo offers it when the cluster is created and the eks
+// terraform installs it before the node groups, which never become ready
+// without a cni
+var cniProviders = map[string]map[string]string{
+	"cilium": {
+		"aws":          cniModeManaged,
+		"civo":         cniModeManaged,
+		"digitalocean": cniModeManaged,
+		"google":       cniModeManaged,
+		"k3s":          cniModeInstalled,
+	},
+}
+
+// cniReadiness is the deployment each installed cni is ready with
+var cniReadiness = map[string]struct {
+	matchLabel      string
+	matchLabelValue string
+	namespace       string
+}{
+	"cilium": {matchLabel: "io.cilium/app", matchLabelValue: "operator", namespace: "kube-system"},
+}
+
+// cniMode returns how the cloud provider runs the cni, empty for the
+// provider's default cni
+func cniMode(cloudProvider string, cni pkgtypes.CNI) string {
+	return cniProviders[cni.Name][cloudProvider]
+}
+
+// validateCNI makes sure the cloud provider can run the definition's cni and
+// that a manifest url is only set where kubefirst installs it
+func validateCNI(def *pkgtypes.ClusterDefinition) error {
+	cni := def.CNI
+	if cni.Name == "" {
+		if cni.ManifestURL != "" {
+			return fmt.Errorf("cni.manifest_url requires cni.name")
+		}
+		return nil
+	}
+
+	providers, ok := cniProviders[cni.Name]
+	if !ok {
+		names := []string{}
+		for name := range cniProviders {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unsupported cni %s: must be one of %v", cni.Name, names)
+	}
+	mode, ok := providers[def.CloudProvider]
+	if !ok {
+		supported := []string{}
+		for provider := range providers {
+			supported = append(supported, provider)
+		}
+		sort.Strings(supported)
+		return fmt.Errorf("cni %s is not supported for %s: must be one of %v", cni.Name, def.CloudProvider, supported)
+	}
+
+	if cni.ManifestURL == "" {
+		return nil
+	}
+	if mode == cniModeManaged {
+		return fmt.Errorf("cni.manifest_url is not used on %s, which installs %s itself", def.CloudProvider, cni.Name)
+	}
+	parsed, err := url.Parse(cni.ManifestURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return fmt.Errorf("invalid cni.manifest_url %s: must be an absolute http or https url", cni.ManifestURL)
+	}
+
+	return nil
+}
+
+// cniTerraformVariables are the variables getCNITerraformEnvs sets
+var cniTerraformVariables = []string{"cni", "disable_default_cni"}
+
+// checkCNITerraform makes sure the cloud terraform at tfEntrypoint declares
+// the cni variables, a template without them would create the cluster with
+// its default cni
+func checkCNITerraform(tfEntrypoint string, cni pkgtypes.CNI) error {
+	if cni.Name == "" {
+		return nil
+	}
+
+	declared, err := terraformext.DeclaredVariables(tfEntrypoint)
+	if err != nil {
+		return fmt.Errorf("error reading the variables of %s: %s", tfEntrypoint, err)
+	}
+	for _, variable := range cniTerraformVariables {
+		if !declared[variable] {
+			return fmt.Errorf("cni %s is not supported by the gitops template: %s declares no %s variable", cni.Name, tfEntrypoint, variable)
+		}
+	}
+
+	return nil
+}
+
+// getCNITerraformEnvs selects the cluster's cni in the cloud terraform, which
+// leaves the default cni out when kubefirst installs the cni itself
+func getCNITerraformEnvs(envs map[string]string, cloudProvider string, cni pkgtypes.CNI) map[string]string {
+	if cni.Name == "" {
+		return envs
+	}
+
+	envs["TF_VAR_cni"] = cni.Name
+	envs["TF_VAR_disable_default_cni"] = strconv.FormatBool(cniMode(cloudProvider, cni) == cniModeInstalled)
+
+	return envs
+}
+
+// InstallCNI applies the cni's manifests on clusters created without a
+// default cni and waits for it, the nodes only become ready once it runs.
+// The manifests are read from cni.manifest_url, or from cni/<name>.yaml of the
+// gitops repository when it isn't set
+func (clctrl *ClusterController) InstallCNI() error {
+	if cniMode(clctrl.CloudProvider, clctrl.CNI) != cniModeInstalled {
+		return nil
+	}
+
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return err
+	}
+	if cl.CNIInstallCheck {
+		return nil
+	}
+
+	err = clctrl.setKubeconfigContext()
+	if err != nil {
+		return err
+	}
+	operations, err := clctrl.clusterOperations()
+	if err != nil {
+		return err
+	}
+
+	manifest := pkgtypes.PostInstallManifest{URL: clctrl.CNI.ManifestURL}
+	if manifest.URL == "" {
+		manifestPath := filepath.Join(clctrl.ProviderConfig.GitopsDir, "cni", fmt.Sprintf("%s.yaml", clctrl.CNI.Name))
+		content, err := os.ReadFile(manifestPath)
+		if err != nil {
+			return fmt.Errorf("cni.manifest_url is not set and the gitops repository has no %s manifests: %s", clctrl.CNI.Name, err)
+		}
+		manifest.Content = string(content)
+	}
+
+	clctrl.logger().Infof("installing cni %s", clctrl.CNI.Name)
+	err = clctrl.applyPostInstallManifest(operations, manifest)
+	if err != nil {
+		return fmt.Errorf("error applying the %s manifests: %s", clctrl.CNI.Name, err)
+	}
+
+	readiness := cniReadiness[clctrl.CNI.Name]
+	err = operations.WaitForDeployment(readiness.matchLabel, readiness.matchLabelValue, readiness.namespace, clctrl.timeouts().ClusterCreate)
+	if err != nil {
+		return fmt.Errorf("error waiting for cni %s: %s", clctrl.CNI.Name, err)
+	}
+	clctrl.logger().Infof("cni %s is ready", clctrl.CNI.Name)
+
+	// the record may have changed while the cni became ready
+	cl, err = secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return err
+	}
+	cl.CNIInstallCheck = true
+	err = secrets.UpdateCluster(clctrl.KubernetesClient, cl)
+	if err != nil {
+		return err
+	}
+	clctrl.Cluster = cl
+
+	return nil
+}
@@ -0,0 +1,168 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestValidateCNI(t *testing.T) {
+	for name, test := range map[string]struct {
+		def   pkgtypes.ClusterDefinition
+		valid bool
+	}{
+		"default cni": {
+			def:   pkgtypes.ClusterDefinition{CloudProvider: "vultr"},
+			valid: true,
+		},
+		"managed by the provider": {
+			def:   pkgtypes.ClusterDefinition{CloudProvider: "civo", CNI: pkgtypes.CNI{Name: "cilium"}},
+			valid: true,
+		},
+		"installed from a manifest url": {
+			def:   pkgtypes.ClusterDefinition{CloudProvider: "k3s", CNI: pkgtypes.CNI{Name: "cilium", ManifestURL: "https://example.com/cilium.yaml"}},
+			valid: true,
+		},
+		"installed by the eks terraform": {
+			def:   pkgtypes.ClusterDefinition{CloudProvider: "aws", CNI: pkgtypes.CNI{Name: "cilium"}},
+			valid: true,
+		},
+		"installed from the gitops repository": {
+			def:   pkgtypes.ClusterDefinition{CloudProvider: "k3s", CNI: pkgtypes.CNI{Name: "cilium"}},
+			valid: true,
+		},
+		"unknown cni": {
+			def: pkgtypes.ClusterDefinition{CloudProvider: "aws", CNI: pkgtypes.CNI{Name: "weave"}},
+		},
+		"unsupported provider": {
+			def: pkgtypes.ClusterDefinition{CloudProvider: "akamai", CNI: pkgtypes.CNI{Name: "cilium"}},
+		},
+		"manifest url on a managed provider": {
+			def: pkgtypes.ClusterDefinition{CloudProvider: "google", CNI: pkgtypes.CNI{Name: "cilium", ManifestURL: "https://example.com/cilium.yaml"}},
+		},
+		"manifest url on aws": {
+			def: pkgtypes.ClusterDefinition{CloudProvider: "aws", CNI: pkgtypes.CNI{Name: "cilium", ManifestURL: "https://example.com/cilium.yaml"}},
+		},
+		"manifest url without a cni": {
+			def: pkgtypes.ClusterDefinition{CloudProvider: "aws", CNI: pkgtypes.CNI{ManifestURL: "https://example.com/cilium.yaml"}},
+		},
+		"relative manifest url": {
+			def: pkgtypes.ClusterDefinition{CloudProvider: "aws", CNI: pkgtypes.CNI{Name: "cilium", ManifestURL: "cilium.yaml"}},
+		},
+	} {
+		if err := validateCNI(&test.def); (err == nil) != test.valid {
+			t.Errorf("%s: validateCNI() = %v, want valid %v", name, err, test.valid)
+		}
+	}
+}
+
+func TestGetCNITerraformEnvs(t *testing.T) {
+	envs := getCNITerraformEnvs(map[string]string{}, "aws", pkgtypes.CNI{})
+	if len(envs) != 0 {
+		t.Errorf("default cni envs = %v, want none", envs)
+	}
+
+	envs = getCNITerraformEnvs(map[string]string{}, "k3s", pkgtypes.CNI{Name: "cilium"})
+	if envs["TF_VAR_cni"] != "cilium" || envs["TF_VAR_disable_default_cni"] != "true" {
+		t.Errorf("k3s cilium envs = %v, want the default cni disabled", envs)
+	}
+
+	envs = getCNITerraformEnvs(map[string]string{}, "google", pkgtypes.CNI{Name: "cilium"})
+	if envs["TF_VAR_cni"] != "cilium" || envs["TF_VAR_disable_default_cni"] != "false" {
+		t.Errorf("google cilium envs = %v, want the default cni kept", envs)
+	}
+}
+
+func TestCheckCNITerraform(t *testing.T) {
+	cilium := pkgtypes.CNI{Name: "cilium"}
+	for name, test := range map[string]struct {
+		variables string
+		cni       pkgtypes.CNI
+		valid     bool
+	}{
+		"default cni": {
+			variables: "",
+			valid:     true,
+		},
+		"template with the cni variables": {
+			variables: "variable \"cni\" {\n  type = string\n}\n\nvariable \"disable_default_cni\" {\n  type = bool\n}\n",
+			cni:       cilium,
+			valid:     true,
+		},
+		"template without them": {
+			variables: "variable \"cluster_name\" {\n  type = string\n}\n",
+			cni:       cilium,
+		},
+		"template without disable_default_cni": {
+			variables: "variable \"cni\" {}\n",
+			cni:       cilium,
+		},
+	} {
+		dir := t.TempDir()
+		err := os.WriteFile(filepath.Join(dir, "variables.tf"), []byte(test.variables), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkCNITerraform(dir, test.cni); (err == nil) != test.valid {
+			t.Errorf("%s: checkCNITerraform() = %v, want valid %v", name, err, test.valid)
+		}
+	}
+}
+
+func TestInstallCNI(t *testing.T) {
+	store, err := secrets.NewMemoryStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets.SetStore(store)
+	defer secrets.SetStore(nil)
+
+	gitopsDir := t.TempDir()
+	os.MkdirAll(filepath.Join(gitopsDir, "cni"), 0o755)
+	os.WriteFile(filepath.Join(gitopsDir, "cni", "cilium.yaml"), []byte("apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: cilium\n  namespace: kube-system\n"), 0o644)
+
+	err = secrets.InsertCluster(nil, pkgtypes.Cluster{
+		ClusterName:   "kf-cni",
+		CloudProvider: "k3s",
+		// changed after the controller was initialized
+		AlertsEmail: "ops@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operations := &fakeOperations{}
+	useFakeOperations(t, operations)
+	clctrl := &ClusterController{
+		ClusterName:   "kf-cni",
+		CloudProvider: "k3s",
+		CNI:           pkgtypes.CNI{Name: "cilium"},
+		Cluster:       pkgtypes.Cluster{ClusterName: "kf-cni", CloudProvider: "k3s"},
+	}
+	clctrl.ProviderConfig.GitopsDir = gitopsDir
+
+	err = clctrl.InstallCNI()
+	if err != nil {
+		t.Fatalf("InstallCNI() = %s", err)
+	}
+	if len(operations.Applied) != 1 || len(operations.Deployments) != 1 {
+		t.Errorf("applied %d manifests and waited for %v, want the cilium manifest and operator", len(operations.Applied), operations.Deployments)
+	}
+
+	cl, err := secrets.GetCluster(nil, "kf-cni")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cl.CNIInstallCheck || cl.AlertsEmail != "ops@example.com" {
+		t.Errorf("cluster record = %+v, want the cni installed and the rest of the stored record kept", cl)
+	}
+}
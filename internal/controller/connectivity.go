@@ -0,0 +1,49 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// connectivityTimeout bounds the api call VerifyConnectivity makes so an
+// unreachable cluster fails fast
+const connectivityTimeout = 10 * time.Second
+
+// VerifyConnectivity loads the cluster's kubeconfig and asks its api server
+// for its version, day-2 operations call it first so an unreachable cluster
+// fails with a clear error instead of deep inside terraform or kubernetes
+func (clctrl *ClusterController) VerifyConnectivity() error {
+	kcfg, err := clctrl.GetClusterKubernetesClient()
+	if err != nil {
+		return fmt.Errorf("cluster %s is unreachable: error loading its kubeconfig: %s", clctrl.ClusterName, err)
+	}
+	if kcfg == nil || kcfg.RestConfig == nil {
+		return fmt.Errorf("cluster %s is unreachable: no kubeconfig could be loaded", clctrl.ClusterName)
+	}
+
+	config := rest.CopyConfig(kcfg.RestConfig)
+	config.Timeout = connectivityTimeout
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("cluster %s is unreachable: error creating its kubernetes client: %s", clctrl.ClusterName, err)
+	}
+
+	version, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		return fmt.Errorf("cluster %s is unreachable: its api server at %s did not respond: %s", clctrl.ClusterName, config.Host, err)
+	}
+
+	clctrl.logger().Infof("cluster %s is reachable, kubernetes %s", clctrl.ClusterName, version.GitVersion)
+
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kubefirst/kubefirst-api/internal/constants"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	consoleMatchLabel      = "app.kubernetes.io/name"
+	consoleMatchLabelValue = "console"
+)
+
+// consoleURL returns the url the kubefirst console is exposed on
+func (clctrl *ClusterController) consoleURL() string {
+	if clctrl.SubdomainName != "" {
+		return fmt.Sprintf("https://kubefirst.%s.%s", clctrl.SubdomainName, clctrl.DomainName)
+	}
+
+	return fmt.Sprintf("https://kubefirst.%s", clctrl.DomainName)
+}
+
+// WaitForConsole blocks until the kubefirst console Deployment is ready or
+// the timeout is reached
+func (clctrl *ClusterController) WaitForConsole(timeoutSeconds int) error {
+	operations, err := clctrl.clusterOperations()
+	if err != nil {
+		return err
+	}
+
+	clctrl.logger().Info("waiting for kubefirst console Deployment to transition to Running")
+	err = operations.WaitForDeployment(consoleMatchLabel, consoleMatchLabelValue, constants.KubefirstNamespace, timeoutSeconds)
+	if err != nil {
+		return fmt.Errorf("error waiting for kubefirst console to transition to Running: %s", err)
+	}
+
+	clctrl.logger().Infof("kubefirst console is available at %s", clctrl.consoleURL())
+
+	return nil
+}
+
+// ConsoleStatus returns the current state of the kubefirst console Deployment
+// without waiting on it
+func (clctrl *ClusterController) ConsoleStatus() (pkgtypes.ConsoleStatus, error) {
+	status := pkgtypes.ConsoleStatus{
+		URL: clctrl.consoleURL(),
+	}
+
+	kcfg, err := clctrl.GetClusterKubernetesClient()
+	if err != nil {
+		return status, err
+	}
+
+	deployments, err := kcfg.Clientset.AppsV1().Deployments(constants.KubefirstNamespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", consoleMatchLabel, consoleMatchLabelValue),
+	})
+	if err != nil {
+		return status, fmt.Errorf("error listing kubefirst console Deployment: %s", err)
+	}
+
+	if len(deployments.Items) == 0 {
+		return status, nil
+	}
+
+	deployment := deployments.Items[0]
+	status.Created = true
+	status.Replicas = deployment.Status.Replicas
+	status.ReadyReplicas = deployment.Status.ReadyReplicas
+	status.Ready = deployment.Status.Replicas > 0 && deployment.Status.ReadyReplicas == deployment.Status.Replicas
+	if containers := deployment.Spec.Template.Spec.Containers; len(containers) > 0 {
+		status.Image = containers[0].Image
+	}
+
+	return status, nil
+}
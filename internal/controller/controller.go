@@ -10,73 +10,145 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	awsext "github.com/kubefirst/kubefirst-api/extensions/aws"
 	runtime "github.com/kubefirst/kubefirst-api/internal"
 	awsinternal "github.com/kubefirst/kubefirst-api/internal/aws"
 	"github.com/kubefirst/kubefirst-api/internal/constants"
+	"github.com/kubefirst/kubefirst-api/internal/dnsProvider"
 	"github.com/kubefirst/kubefirst-api/internal/env"
+	"github.com/kubefirst/kubefirst-api/internal/gitClient"
 	"github.com/kubefirst/kubefirst-api/internal/github"
 	"github.com/kubefirst/kubefirst-api/internal/gitlab"
 	"github.com/kubefirst/kubefirst-api/internal/k8s"
 	"github.com/kubefirst/kubefirst-api/internal/secrets"
 	"github.com/kubefirst/kubefirst-api/internal/services"
+	apitelemetry "github.com/kubefirst/kubefirst-api/internal/telemetry"
 	"github.com/kubefirst/kubefirst-api/internal/utils"
+	pkgconstants "github.com/kubefirst/kubefirst-api/pkg/constants"
 	google "github.com/kubefirst/kubefirst-api/pkg/google"
 	"github.com/kubefirst/kubefirst-api/pkg/handlers"
 	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
 	"github.com/kubefirst/kubefirst-api/pkg/types"
 	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
 	"github.com/kubefirst/metrics-client/pkg/telemetry"
-	"k8s.io/client-go/kubernetes"
-
-	log "github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"k8s.io/client-go/kubernetes"
 )
 
+// ClusterController drives a single cluster through its provisioning steps.
+//
+// Lifecycle: a controller is created empty, populated with InitController,
+// driven through the provider steps, and released with Close once the caller
+// is done with it. Close stops any port-forwards opened through the controller
+// and releases idle http connections, so callers should defer it right after
+// a successful InitController.
 type ClusterController struct {
 	CloudProvider             string
 	CloudRegion               string
 	ClusterName               string
 	ClusterID                 string
 	ClusterType               string
+	ClusterGroup              string
+	Tags                      map[string]string
 	DomainName                string
 	SubdomainName             string
 	DnsProvider               string
+	DNSTTL                    int
+	ArgoCDHost                string
+	ArgoCDSyncPolicy          pkgtypes.ArgoCDSyncPolicy
+	FeatureGates              map[string]bool
 	UseCloudflareOriginIssuer bool
 	AlertsEmail               string
 
 	// auth
-	AkamaiAuth             pkgtypes.AkamaiAuth
-	AWSAuth                pkgtypes.AWSAuth
-	CivoAuth               pkgtypes.CivoAuth
-	DigitaloceanAuth       pkgtypes.DigitaloceanAuth
-	VultrAuth              pkgtypes.VultrAuth
-	CloudflareAuth         pkgtypes.CloudflareAuth
-	GitAuth                pkgtypes.GitAuth
-	VaultAuth              pkgtypes.VaultAuth
-	GoogleAuth             pkgtypes.GoogleAuth
-	K3sAuth                pkgtypes.K3sAuth
-	AwsAccessKeyID         string
-	AwsSecretAccessKey     string
-	NodeType               string
-	NodeCount              int
-	PostInstallCatalogApps []pkgtypes.GitopsCatalogApp
-	InstallKubefirstPro    bool
+	AkamaiAuth              pkgtypes.AkamaiAuth
+	AWSAuth                 pkgtypes.AWSAuth
+	CivoAuth                pkgtypes.CivoAuth
+	DigitaloceanAuth        pkgtypes.DigitaloceanAuth
+	VultrAuth               pkgtypes.VultrAuth
+	CloudflareAuth          pkgtypes.CloudflareAuth
+	GitAuth                 pkgtypes.GitAuth
+	VaultAuth               pkgtypes.VaultAuth
+	GoogleAuth              pkgtypes.GoogleAuth
+	K3sAuth                 pkgtypes.K3sAuth
+	AwsAccessKeyID          string
+	AwsSecretAccessKey      string
+	NodeType                string
+	NodeCount               int
+	NodeLabels              map[string]string
+	NodeTaints              []pkgtypes.NodeTaint
+	Autoscaling             pkgtypes.NodePoolAutoscaling
+	ImageOverrides          map[string]string
+	ConsoleImage            string
+	StorageClass            string
+	ResourceProfile         string
+	ResourceOverrides       map[string]pkgtypes.ComponentResources
+	InstallProfile          string
+	ExistingNetwork         pkgtypes.ExistingNetwork
+	EgressGateway           pkgtypes.EgressGateway
+	ImagePullSecrets        []pkgtypes.ImagePullSecret
+	IngressController       string
+	KubernetesVersion       string
+	LoadBalancerAnnotations map[string]string
+	TerraformBackend        pkgtypes.TerraformBackend
+	TerraformCloud          pkgtypes.TerraformCloud
+	TerraformHooks          pkgtypes.TerraformHooks
+	WorkloadIdentity        pkgtypes.WorkloadIdentity
+	VolumeSizes             map[string]string
+	PostInstallCatalogApps  []pkgtypes.GitopsCatalogApp
+	PostInstallManifests    []pkgtypes.PostInstallManifest
+	InstallKubefirstPro     bool
+
+	PodSecurity     pkgtypes.PodSecurity
+	NetworkPolicies bool
+	CNI             pkgtypes.CNI
+	ExternalSecrets pkgtypes.ExternalSecrets
+	SpotNodePool    pkgtypes.SpotNodePool
+	Timeouts        pkgtypes.ProvisionTimeouts
+	UserDirectory   pkgtypes.UserDirectory
+	OIDC            pkgtypes.OIDC
+
+	// breakpoints, see RunStep
+	StopAfterStep string
+	ResumeFrom    string
+	resuming      bool
+	paused        bool
 
 	// configs
 	ProviderConfig providerConfigs.ProviderConfig
 
+	// kubeconfig
+	KubeconfigContextName string
+
 	// git
-	GitopsTemplateURL    string
-	GitopsTemplateBranch string
-	GitProvider          string
-	GitProtocol          string
-	GitHost              string
-	GitOwner             string
-	GitUser              string
-	GitToken             string
-	GitlabOwnerGroupID   int
+	GitopsTemplateURL      string
+	GitopsTemplateBranch   string
+	GitopsTemplateCommit   string
+	SkipManifestValidation bool
+	GitProvider            string
+	GitProtocol            string
+	GitHost                string
+	GitOwner               string
+	GitUser                string
+	GitToken               string
+	GitlabOwnerGroupID     int
+
+	// AdoptRepositories and ForceAdoptRepositories push into existing
+	// repositories instead of failing
+	AdoptRepositories      bool
+	ForceAdoptRepositories bool
+	GitopsMirrors          []pkgtypes.GitMirror
+	// MetaphorOwner is the owner the metaphor repository is created under,
+	// GitAuth.Owner when it isn't set separately
+	MetaphorOwner string
+	// DefaultBranch is the branch repositories are pushed to and
+	// CommitMessage the message of their initial commits
+	DefaultBranch string
+	CommitMessage string
 
 	// container registry
 	ContainerRegistryHost string
@@ -84,6 +156,9 @@ type ClusterController struct {
 
 	// http
 	HttpClient *http.Client
+	// ownsHttpClient is set when InitController created HttpClient, only
+	// then Close closes its connections
+	ownsHttpClient bool
 
 	// repositories
 	Repositories []string
@@ -101,28 +176,64 @@ type ClusterController struct {
 	// state store
 	KubefirstStateStoreBucketName string
 	KubefirstArtifactsBucketName  string
+	UseExistingStateStoreBucket   bool
+	StateStoreKMSKey              string
 
 	KubernetesClient *kubernetes.Clientset
 
 	// Telemetry
 	TelemetryEvent telemetry.TelemetryEvent
+	// Telemetry receives the controller's usage events, the default sink is
+	// used when it's nil
+	Telemetry apitelemetry.Telemetry
+
+	// Logger receives the log output of the controller's steps, the global
+	// logger is used when it's nil
+	Logger Logger
 
 	// Provider clients
 	AwsClient    *awsinternal.AWSConfiguration
 	GoogleClient google.GoogleConfiguration
 	Kcfg         *k8s.KubernetesClient
 	Cluster      types.Cluster
+
+	// ManifestValidator checks the gitops manifests before they're pushed,
+	// the default validator is used when it's nil
+	ManifestValidator ManifestValidator
+
+	// port-forwards opened by the controller, closed on Close
+	portForwardMu sync.Mutex
+	portForwards  []chan struct{}
+
+	// set while the controller holds one of the limited provision slots
+	holdsProvisionSlot bool
+
+	// set while the controller publishes to its cluster's event stream
+	streamingEvents bool
 }
 
 // InitController
 func (clctrl *ClusterController) InitController(def *pkgtypes.ClusterDefinition) error {
+	err := CheckWritable()
+	if err != nil {
+		return err
+	}
+
+	err = ValidateDefinition(def)
+	if err != nil {
+		return err
+	}
+
 	// Create k1 dir if it doesn't exist
 	utils.CreateK1Directory(def.ClusterName)
 
 	// Get Environment variables
 	env, _ := env.GetEnv(constants.SilenceGetEnv)
 
-	kcfg := utils.GetKubernetesClient(def.ClusterName)
+	kcfg, err := utils.GetClusterStoreClient(def.ClusterName)
+	if err != nil {
+		return err
+	}
 	clctrl.KubernetesClient = kcfg.Clientset
 
 	// Determine if record already exists
@@ -130,7 +241,7 @@ func (clctrl *ClusterController) InitController(def *pkgtypes.ClusterDefinition)
 	rec, err := secrets.GetCluster(clctrl.KubernetesClient, def.ClusterName)
 	if rec.ClusterID == "" && err != nil {
 		recordExists = false
-		log.Info().Msg("cluster record doesn't exist, continuing")
+		clctrl.logger().Info("cluster record doesn't exist, continuing")
 	}
 
 	logFileName := def.LogFileName
@@ -173,6 +284,10 @@ func (clctrl *ClusterController) InitController(def *pkgtypes.ClusterDefinition)
 		MetricName:        telemetry.ClusterInstallCompleted,
 		UserId:            env.ClusterId,
 	}
+	// Cluster group members report against the group
+	if def.ClusterGroup != "" {
+		telemetryEvent.ParentClusterId = def.ClusterGroup
+	}
 	clctrl.TelemetryEvent = telemetryEvent
 
 	// Copy Cluster Definiion to Cluster Controller
@@ -183,13 +298,75 @@ func (clctrl *ClusterController) InitController(def *pkgtypes.ClusterDefinition)
 	clctrl.ClusterID = clusterID
 	clctrl.DomainName = def.DomainName
 	clctrl.SubdomainName = def.SubdomainName
-	clctrl.DnsProvider = def.DnsProvider
+	clctrl.DnsProvider = dnsProvider.Normalize(def.DnsProvider)
+	clctrl.DNSTTL = def.DNSTTL
+	clctrl.ArgoCDHost = def.ArgoCDHost
+	clctrl.ArgoCDSyncPolicy = def.ArgoCDSyncPolicy
+	clctrl.FeatureGates = def.FeatureGates
 	clctrl.ClusterType = def.Type
-	clctrl.HttpClient = http.DefaultClient
+	clctrl.ClusterGroup = def.ClusterGroup
+	clctrl.Tags = def.Tags
+	if clctrl.HttpClient == nil {
+		clctrl.HttpClient = &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
+		clctrl.ownsHttpClient = true
+	}
 	clctrl.NodeType = def.NodeType
 	clctrl.NodeCount = def.NodeCount
+	clctrl.NodeLabels = def.NodeLabels
+	clctrl.NodeTaints = def.NodeTaints
+	clctrl.Autoscaling = def.Autoscaling
+	clctrl.ImageOverrides = def.ImageOverrides
+	if def.ConsoleImage != "" {
+		// validated with the definition, the console is rendered through its
+		// image override tokens
+		clctrl.ConsoleImage, _ = providerConfigs.ConsoleImage(def.ConsoleImage)
+		clctrl.ImageOverrides = map[string]string{"console": clctrl.ConsoleImage}
+		for component, image := range def.ImageOverrides {
+			clctrl.ImageOverrides[component] = image
+		}
+	}
+	clctrl.StorageClass = def.StorageClass
+	clctrl.ResourceProfile = def.ResourceProfile
+	clctrl.ResourceOverrides = def.ResourceOverrides
+	clctrl.InstallProfile = def.InstallProfile
+	clctrl.ExistingNetwork = def.ExistingNetwork
+	clctrl.EgressGateway = def.EgressGateway
+	clctrl.ImagePullSecrets = def.ImagePullSecrets
+	clctrl.IngressController = def.IngressController
+	clctrl.KubernetesVersion = def.KubernetesVersion
+	clctrl.LoadBalancerAnnotations = def.LoadBalancerAnnotations
+	clctrl.TerraformBackend = def.TerraformBackend
+	clctrl.TerraformCloud = def.TerraformCloud
+	clctrl.TerraformHooks = def.TerraformHooks
+	if clctrl.IngressController == "" {
+		clctrl.IngressController = providerConfigs.DefaultIngressController
+	}
+	clctrl.WorkloadIdentity = def.WorkloadIdentity
+	clctrl.VolumeSizes = def.VolumeSizes
+	clctrl.PodSecurity = def.PodSecurity
+	clctrl.NetworkPolicies = def.NetworkPolicies
+	clctrl.CNI = def.CNI
+	clctrl.SpotNodePool = def.SpotNodePool
+	clctrl.Timeouts = pkgconstants.GetProvisionTimeoutsFor(def.CloudProvider, def.Timeouts)
+	clctrl.UserDirectory = def.UserDirectory
+	clctrl.OIDC = def.OIDC
+	clctrl.StopAfterStep = def.StopAfterStep
+	clctrl.ResumeFrom = def.ResumeFrom
+	clctrl.resuming = def.ResumeFrom != ""
+	if clctrl.SpotNodePool.NodeCount > 0 && clctrl.SpotNodePool.NodeType == "" {
+		clctrl.SpotNodePool.NodeType = def.NodeType
+	}
+	clctrl.ExternalSecrets = def.ExternalSecrets
+	if clctrl.ExternalSecrets.Provider != "" && clctrl.ExternalSecrets.Prefix == "" {
+		clctrl.ExternalSecrets.Prefix = fmt.Sprintf("kubefirst-%s", def.ClusterName)
+	}
+	if clctrl.PodSecurity.Level == "" {
+		clctrl.PodSecurity.Level = providerConfigs.DefaultPodSecurityLevel
+	}
 	clctrl.PostInstallCatalogApps = def.PostInstallCatalogApps
+	clctrl.PostInstallManifests = def.PostInstallManifests
 	clctrl.InstallKubefirstPro = def.InstallKubefirstPro
+	clctrl.KubeconfigContextName = def.KubeconfigContextName
 
 	clctrl.AkamaiAuth = def.AkamaiAuth
 	clctrl.AWSAuth = def.AWSAuth
@@ -200,7 +377,10 @@ func (clctrl *ClusterController) InitController(def *pkgtypes.ClusterDefinition)
 	clctrl.K3sAuth = def.K3sAuth
 	clctrl.CloudflareAuth = def.CloudflareAuth
 
-	clctrl.Repositories = []string{"gitops", "metaphor"}
+	clctrl.Repositories = []string{"gitops"}
+	if clctrl.installsMetaphor() {
+		clctrl.Repositories = append(clctrl.Repositories, metaphorRepository)
+	}
 	clctrl.Teams = []string{"admins", "developers"}
 
 	clctrl.ECR = def.ECR
@@ -212,14 +392,24 @@ func (clctrl *ClusterController) InitController(def *pkgtypes.ClusterDefinition)
 	}
 
 	if def.GitopsTemplateURL != "" {
-		if def.GitopsTemplateBranch != "" {
-			clctrl.GitopsTemplateURL = def.GitopsTemplateURL
-		} else {
-			return fmt.Errorf("must supply branch of gitops templatelog.Fatal().Msg( repo when supplying a gitops template url")
-		}
+		clctrl.GitopsTemplateURL = def.GitopsTemplateURL
 	} else {
 		clctrl.GitopsTemplateURL = "https://github.com/kubefirst/gitops-template.git"
 	}
+
+	// Make sure a user supplied template fork and ref can be cloned before
+	// anything is provisioned
+	if def.GitopsTemplateURL != "" || def.GitopsTemplateBranch != "" {
+		templateRef, err := gitClient.ResolveRef(clctrl.GitopsTemplateBranch, clctrl.GitopsTemplateURL)
+		if err != nil {
+			return fmt.Errorf("invalid gitops template source: %s", err)
+		}
+		if templateRef.IsBranch() && def.GitopsTemplateCommit == "" {
+			clctrl.logger().Warnf("gitops template branch %s is mutable, set gitops_template_commit to make installs reproducible", clctrl.GitopsTemplateBranch)
+		}
+	}
+	clctrl.GitopsTemplateCommit = def.GitopsTemplateCommit
+	clctrl.SkipManifestValidation = def.SkipManifestValidation
 	if def.CloudProvider == "akamai" {
 		clctrl.KubefirstStateStoreBucketName = clctrl.ClusterName
 	} else {
@@ -227,12 +417,28 @@ func (clctrl *ClusterController) InitController(def *pkgtypes.ClusterDefinition)
 	}
 
 	clctrl.KubefirstArtifactsBucketName = fmt.Sprintf("k1-artifacts-%s-%s", clctrl.ClusterName, clusterID)
+
+	// Use a pre-created bucket for both state and artifacts instead of creating them
+	if def.ExistingStateStoreBucket != "" {
+		clctrl.UseExistingStateStoreBucket = true
+		clctrl.KubefirstStateStoreBucketName = def.ExistingStateStoreBucket
+		clctrl.KubefirstArtifactsBucketName = def.ExistingStateStoreBucket
+	}
+
+	// Customer managed encryption keys for the state store, otherwise the
+	// provider's default encryption applies
+	if def.StateStoreKMSKey != "" {
+		clctrl.StateStoreKMSKey = def.StateStoreKMSKey
+	}
 	clctrl.NodeType = def.NodeType
 	clctrl.NodeCount = def.NodeCount
 
 	clctrl.KubefirstTeam = env.KubefirstTeam
 
-	clctrl.AtlantisWebhookSecret = runtime.Random(20)
+	clctrl.AtlantisWebhookSecret = def.AtlantisWebhookSecret
+	if clctrl.AtlantisWebhookSecret == "" {
+		clctrl.AtlantisWebhookSecret = runtime.Random(20)
+	}
 
 	var fullDomainName string
 	if clctrl.SubdomainName != "" {
@@ -240,11 +446,26 @@ func (clctrl *ClusterController) InitController(def *pkgtypes.ClusterDefinition)
 	} else {
 		fullDomainName = clctrl.DomainName
 	}
-	clctrl.AtlantisWebhookURL = fmt.Sprintf("https://atlantis.%s/events", fullDomainName)
+	// an install profile without atlantis gets no atlantis webhook
+	if !runtime.FindStringInSlice(providerConfigs.InstallProfiles[clctrl.InstallProfile], "atlantis") {
+		clctrl.AtlantisWebhookURL = fmt.Sprintf("https://atlantis.%s/events", fullDomainName)
+	}
 
 	// Initialize git parameters
 	clctrl.GitProvider = def.GitProvider
 	clctrl.GitProtocol = def.GitProtocol
+	clctrl.AdoptRepositories = def.AdoptRepositories
+	clctrl.ForceAdoptRepositories = def.ForceAdoptRepositories
+	clctrl.GitopsMirrors = def.GitopsMirrors
+	clctrl.DefaultBranch = gitClient.BranchOrDefault(def.DefaultBranch)
+	clctrl.CommitMessage = def.CommitMessage
+
+	// GitHub App installations authenticate with short lived tokens, mint
+	// one before the credentials are verified
+	_, err = github.RefreshGitAuth(&def.GitAuth)
+	if err != nil {
+		return err
+	}
 	clctrl.GitAuth = def.GitAuth
 
 	err = clctrl.SetGitTokens(*def)
@@ -252,6 +473,11 @@ func (clctrl *ClusterController) InitController(def *pkgtypes.ClusterDefinition)
 		return err
 	}
 
+	clctrl.MetaphorOwner = def.MetaphorOwner
+	if clctrl.MetaphorOwner == "" {
+		clctrl.MetaphorOwner = clctrl.GitAuth.Owner
+	}
+
 	// Instantiate provider configuration
 	switch clctrl.CloudProvider {
 	case "akamai":
@@ -281,6 +507,11 @@ func (clctrl *ClusterController) InitController(def *pkgtypes.ClusterDefinition)
 		clctrl.ProviderConfig.K3sServersArgs = clctrl.K3sAuth.K3sServersArgs
 	}
 
+	if clctrl.separateMetaphorOwner() {
+		clctrl.ProviderConfig.DestinationMetaphorRepoURL = fmt.Sprintf("https://%s/%s/metaphor.git", clctrl.GitHost, clctrl.MetaphorOwner)
+		clctrl.ProviderConfig.DestinationMetaphorRepoGitURL = fmt.Sprintf("git@%s:%s/metaphor.git", clctrl.GitHost, clctrl.MetaphorOwner)
+	}
+
 	// Instantiate provider clients and copy cluster controller to cluster type
 	switch clctrl.CloudProvider {
 	case "aws":
@@ -290,6 +521,7 @@ func (clctrl *ClusterController) InitController(def *pkgtypes.ClusterDefinition)
 				clctrl.AWSAuth.AccessKeyID,
 				clctrl.AWSAuth.SecretAccessKey,
 				clctrl.AWSAuth.SessionToken,
+				clctrl.AWSAuth.APIURL,
 			),
 		}
 	case "google":
@@ -303,41 +535,88 @@ func (clctrl *ClusterController) InitController(def *pkgtypes.ClusterDefinition)
 
 	// Write cluster record if it doesn't exist
 	clctrl.Cluster = pkgtypes.Cluster{
-		ID:                     primitive.NewObjectID(),
-		CreationTimestamp:      fmt.Sprintf("%v", primitive.NewDateTimeFromTime(time.Now().UTC())),
-		Status:                 constants.ClusterStatusProvisioning,
-		AlertsEmail:            clctrl.AlertsEmail,
-		ClusterName:            clctrl.ClusterName,
-		CloudProvider:          clctrl.CloudProvider,
-		CloudRegion:            clctrl.CloudRegion,
-		DomainName:             clctrl.DomainName,
-		SubdomainName:          clctrl.SubdomainName,
-		DnsProvider:            clctrl.DnsProvider,
-		ClusterID:              clctrl.ClusterID,
-		ECR:                    clctrl.ECR,
-		ClusterType:            clctrl.ClusterType,
-		GitopsTemplateURL:      clctrl.GitopsTemplateURL,
-		GitopsTemplateBranch:   clctrl.GitopsTemplateBranch,
-		GitProvider:            clctrl.GitProvider,
-		GitProtocol:            clctrl.GitProtocol,
-		GitHost:                clctrl.GitHost,
-		GitAuth:                clctrl.GitAuth,
-		GitlabOwnerGroupID:     clctrl.GitlabOwnerGroupID,
-		AtlantisWebhookSecret:  clctrl.AtlantisWebhookSecret,
-		AtlantisWebhookURL:     clctrl.AtlantisWebhookURL,
-		KubefirstTeam:          clctrl.KubefirstTeam,
-		AkamaiAuth:             clctrl.AkamaiAuth,
-		AWSAuth:                clctrl.AWSAuth,
-		CivoAuth:               clctrl.CivoAuth,
-		GoogleAuth:             clctrl.GoogleAuth,
-		DigitaloceanAuth:       clctrl.DigitaloceanAuth,
-		VultrAuth:              clctrl.VultrAuth,
-		K3sAuth:                clctrl.K3sAuth,
-		CloudflareAuth:         clctrl.CloudflareAuth,
-		NodeType:               clctrl.NodeType,
-		NodeCount:              clctrl.NodeCount,
-		LogFileName:            def.LogFileName,
-		PostInstallCatalogApps: clctrl.PostInstallCatalogApps,
+		ID:                       primitive.NewObjectID(),
+		CreationTimestamp:        fmt.Sprintf("%v", primitive.NewDateTimeFromTime(time.Now().UTC())),
+		Status:                   constants.ClusterStatusProvisioning,
+		AlertsEmail:              clctrl.AlertsEmail,
+		ClusterName:              clctrl.ClusterName,
+		CloudProvider:            clctrl.CloudProvider,
+		CloudRegion:              clctrl.CloudRegion,
+		DomainName:               clctrl.DomainName,
+		SubdomainName:            clctrl.SubdomainName,
+		DnsProvider:              clctrl.DnsProvider,
+		DNSTTL:                   clctrl.DNSTTL,
+		ArgoCDHost:               clctrl.ArgoCDHost,
+		ArgoCDSyncPolicy:         clctrl.ArgoCDSyncPolicy,
+		FeatureGates:             clctrl.FeatureGates,
+		ClusterID:                clctrl.ClusterID,
+		ECR:                      clctrl.ECR,
+		ClusterType:              clctrl.ClusterType,
+		ClusterGroup:             clctrl.ClusterGroup,
+		Tags:                     clctrl.Tags,
+		GitopsTemplateURL:        clctrl.GitopsTemplateURL,
+		GitopsTemplateBranch:     clctrl.GitopsTemplateBranch,
+		GitopsTemplateCommit:     clctrl.GitopsTemplateCommit,
+		SkipManifestValidation:   clctrl.SkipManifestValidation,
+		GitProvider:              clctrl.GitProvider,
+		GitProtocol:              clctrl.GitProtocol,
+		AdoptRepositories:        clctrl.AdoptRepositories,
+		ForceAdoptRepositories:   clctrl.ForceAdoptRepositories,
+		GitopsMirrors:            clctrl.GitopsMirrors,
+		MetaphorOwner:            clctrl.MetaphorOwner,
+		DefaultBranch:            clctrl.DefaultBranch,
+		CommitMessage:            clctrl.CommitMessage,
+		GitHost:                  clctrl.GitHost,
+		GitAuth:                  clctrl.GitAuth,
+		GitlabOwnerGroupID:       clctrl.GitlabOwnerGroupID,
+		AtlantisWebhookSecret:    clctrl.AtlantisWebhookSecret,
+		AtlantisWebhookURL:       clctrl.AtlantisWebhookURL,
+		KubefirstTeam:            clctrl.KubefirstTeam,
+		AkamaiAuth:               clctrl.AkamaiAuth,
+		AWSAuth:                  clctrl.AWSAuth,
+		CivoAuth:                 clctrl.CivoAuth,
+		GoogleAuth:               clctrl.GoogleAuth,
+		DigitaloceanAuth:         clctrl.DigitaloceanAuth,
+		VultrAuth:                clctrl.VultrAuth,
+		K3sAuth:                  clctrl.K3sAuth,
+		CloudflareAuth:           clctrl.CloudflareAuth,
+		NodeType:                 clctrl.NodeType,
+		NodeCount:                clctrl.NodeCount,
+		NodeLabels:               clctrl.NodeLabels,
+		NodeTaints:               clctrl.NodeTaints,
+		Autoscaling:              clctrl.Autoscaling,
+		NodePoolBounds:           clctrl.nodePoolBounds(),
+		ImageOverrides:           clctrl.ImageOverrides,
+		ConsoleImage:             clctrl.ConsoleImage,
+		StorageClass:             clctrl.StorageClass,
+		ResourceProfile:          clctrl.ResourceProfile,
+		ResourceOverrides:        clctrl.ResourceOverrides,
+		InstallProfile:           clctrl.InstallProfile,
+		ExistingNetwork:          clctrl.ExistingNetwork,
+		EgressGateway:            clctrl.EgressGateway,
+		ImagePullSecrets:         clctrl.ImagePullSecrets,
+		IngressController:        clctrl.IngressController,
+		KubernetesVersion:        clctrl.KubernetesVersion,
+		LoadBalancerAnnotations:  clctrl.LoadBalancerAnnotations,
+		TerraformBackend:         clctrl.TerraformBackend,
+		TerraformCloud:           clctrl.TerraformCloud,
+		TerraformHooks:           clctrl.TerraformHooks,
+		WorkloadIdentity:         clctrl.WorkloadIdentity,
+		VolumeSizes:              clctrl.VolumeSizes,
+		LogFileName:              def.LogFileName,
+		PodSecurity:              clctrl.PodSecurity,
+		NetworkPolicies:          clctrl.NetworkPolicies,
+		CNI:                      clctrl.CNI,
+		ExternalSecrets:          clctrl.ExternalSecrets,
+		SpotNodePool:             clctrl.SpotNodePool,
+		Timeouts:                 clctrl.Timeouts,
+		UserDirectory:            clctrl.UserDirectory,
+		OIDC:                     clctrl.OIDC,
+		PostInstallCatalogApps:   clctrl.PostInstallCatalogApps,
+		PostInstallManifests:     clctrl.PostInstallManifests,
+		KubeconfigContextName:    clctrl.KubeconfigContextName,
+		ExistingStateStoreBucket: def.ExistingStateStoreBucket,
+		StateStoreKMSKey:         clctrl.StateStoreKMSKey,
 	}
 
 	if !recordExists {
@@ -369,6 +648,18 @@ func (clctrl *ClusterController) SetGitTokens(def pkgtypes.ClusterDefinition) er
 
 		clctrl.GitHost = "github.com"
 		clctrl.ContainerRegistryHost = "ghcr.io"
+
+		// installation tokens carry the app's permissions instead of scopes
+		// and act as the app's bot user
+		if def.GitAuth.UsesGitHubApp() {
+			appUser, err := github.VerifyAppInstallation(def.GitAuth.AppID, def.GitAuth.AppInstallationID, def.GitAuth.AppPrivateKey, def.GitAuth.Owner)
+			if err != nil {
+				return err
+			}
+			clctrl.GitAuth.User = appUser
+			break
+		}
+
 		// Verify token scopes
 		err := github.VerifyTokenPermissions(def.GitAuth.Token)
 		if err != nil {
@@ -380,6 +671,18 @@ func (clctrl *ClusterController) SetGitTokens(def pkgtypes.ClusterDefinition) er
 			return err
 		}
 		clctrl.GitAuth.User = githubUser
+
+		// repositories can be split across two owners, the token has to be
+		// able to create repositories under both
+		if def.MetaphorOwner != "" && !strings.EqualFold(def.MetaphorOwner, def.GitAuth.Owner) {
+			githubSession := github.New(def.GitAuth.Token)
+			for _, owner := range []string{def.GitAuth.Owner, def.MetaphorOwner} {
+				err = githubSession.VerifyOwnerAccess(owner)
+				if err != nil {
+					return err
+				}
+			}
+		}
 	case "gitlab":
 		clctrl.GitHost = "gitlab.com"
 		clctrl.ContainerRegistryHost = "registry.gitlab.com"
@@ -417,8 +720,96 @@ func (clctrl *ClusterController) GetCurrentClusterRecord() (pkgtypes.Cluster, er
 	return cl, nil
 }
 
+// openPortForwardPod forwards a local port to a pod, overridden in tests
+var openPortForwardPod = k8s.OpenPortForwardPodWrapper
+
+// OpenPortForward opens a port-forward to the given pod and tracks its stop
+// channel so it is closed when the controller is closed
+func (clctrl *ClusterController) OpenPortForward(kcfg *k8s.KubernetesClient, podName string, namespace string, podPort int, podLocalPort int) {
+	stopChannel := make(chan struct{}, 1)
+
+	clctrl.portForwardMu.Lock()
+	clctrl.portForwards = append(clctrl.portForwards, stopChannel)
+	clctrl.portForwardMu.Unlock()
+
+	openPortForwardPod(
+		kcfg.Clientset,
+		kcfg.RestConfig,
+		podName,
+		namespace,
+		podPort,
+		podLocalPort,
+		stopChannel,
+	)
+}
+
+// ClosePortForwards stops all outstanding port-forwards opened by the controller
+func (clctrl *ClusterController) ClosePortForwards() {
+	clctrl.portForwardMu.Lock()
+	defer clctrl.portForwardMu.Unlock()
+
+	for _, stopChannel := range clctrl.portForwards {
+		close(stopChannel)
+	}
+	clctrl.portForwards = nil
+}
+
+// Close releases resources held by the controller and flushes its telemetry,
+// an HttpClient the embedder set is left open
+// It is safe to call more than once
+func (clctrl *ClusterController) Close() error {
+	clctrl.ClosePortForwards()
+
+	if clctrl.ownsHttpClient && clctrl.HttpClient != nil {
+		clctrl.HttpClient.CloseIdleConnections()
+	}
+
+	if flusher, ok := clctrl.telemetry().(apitelemetry.Flusher); ok {
+		flusher.Flush()
+	}
+
+	return nil
+}
+
+// GetClusterKubernetesClient returns a client for the cluster being provisioned
+func (clctrl *ClusterController) GetClusterKubernetesClient() (*k8s.KubernetesClient, error) {
+	switch clctrl.CloudProvider {
+	case "aws":
+		return awsext.CreateEKSKubeconfig(&clctrl.AwsClient.Config, clctrl.ClusterName), nil
+	case "akamai", "civo", "digitalocean", "k3s", "vultr":
+		return k8s.CreateKubeConfig(false, clctrl.ProviderConfig.Kubeconfig), nil
+	case "google":
+		return clctrl.GoogleClient.GetContainerClusterAuth(clctrl.ClusterName, []byte(clctrl.GoogleAuth.KeyFile))
+	}
+
+	return nil, fmt.Errorf("unsupported cloud provider %s", clctrl.CloudProvider)
+}
+
+// newClusterOperations returns the client for the cluster being provisioned,
+// overridden in tests
+var newClusterOperations = func(clctrl *ClusterController) (k8s.Operations, error) {
+	return clctrl.GetClusterKubernetesClient()
+}
+
+// clusterOperations returns the operations run against the cluster being
+// provisioned
+func (clctrl *ClusterController) clusterOperations() (k8s.Operations, error) {
+	return newClusterOperations(clctrl)
+}
+
+// timeouts returns the controller's provisioning timeouts, unset ones fall
+// back to the cloud provider's defaults
+func (clctrl *ClusterController) timeouts() pkgtypes.ProvisionTimeouts {
+	return pkgconstants.GetProvisionTimeoutsFor(clctrl.CloudProvider, clctrl.Timeouts)
+}
+
 // HandleError implements an error handler for cluster controller objects
 func (clctrl *ClusterController) HandleError(condition string) error {
+	// a pipeline stopped at its breakpoint hasn't failed
+	if clctrl.paused {
+		return nil
+	}
+
 	clctrl.Cluster.InProgress = false
 	clctrl.Cluster.Status = constants.ClusterStatusError
 	clctrl.Cluster.LastCondition = condition
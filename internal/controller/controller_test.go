@@ -0,0 +1,69 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	apitelemetry "github.com/kubefirst/kubefirst-api/internal/telemetry"
+	"github.com/kubefirst/metrics-client/pkg/telemetry"
+)
+
+// idleTransport records whether its idle connections were closed
+type idleTransport struct {
+	http.RoundTripper
+	closed bool
+}
+
+func (t *idleTransport) CloseIdleConnections() {
+	t.closed = true
+}
+
+// countingSink counts the events transmitted to it
+type countingSink struct {
+	transmitted int
+}
+
+func (s *countingSink) Transmit(event telemetry.TelemetryEvent, metricName string, errMsg string) error {
+	s.transmitted++
+	return nil
+}
+
+func (s *countingSink) Close() error {
+	return nil
+}
+
+func TestClose(t *testing.T) {
+	for _, owned := range []bool{false, true} {
+		transport := &idleTransport{}
+		sink := &countingSink{}
+		buffered := apitelemetry.NewBuffered(sink, 10, time.Hour)
+		clctrl := &ClusterController{
+			HttpClient:     &http.Client{Transport: transport},
+			ownsHttpClient: owned,
+			Telemetry:      buffered,
+		}
+
+		err := buffered.Transmit(telemetry.TelemetryEvent{}, telemetry.InitStarted, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = clctrl.Close()
+		if err != nil {
+			t.Fatalf("Close() = %s", err)
+		}
+
+		if transport.closed != owned {
+			t.Errorf("owned %v: idle connections closed = %v", owned, transport.closed)
+		}
+		if sink.transmitted != 1 {
+			t.Errorf("owned %v: %d events transmitted, want the queued event flushed", owned, sink.transmitted)
+		}
+	}
+}
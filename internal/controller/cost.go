@@ -0,0 +1,242 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+	awsinternal "github.com/kubefirst/kubefirst-api/internal/aws"
+	"github.com/kubefirst/kubefirst-api/internal/digitalocean"
+	"github.com/kubefirst/kubefirst-api/internal/vultr"
+	"github.com/kubefirst/kubefirst-api/pkg/akamai"
+	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// nodePriceCacheTTL is how long node prices are reused before the provider's
+// pricing api is queried again
+const nodePriceCacheTTL = time.Hour
+
+// hoursPerMonth converts hourly prices to monthly ones
+const hoursPerMonth = 730
+
+// assumedVolumeSizeGi is used for platform volumes the definition doesn't
+// size, their chart default applies and isn't known before install
+const assumedVolumeSizeGi = 10
+
+const costEstimateNote = "Estimate from list prices, node prices are cached for an hour. Bandwidth, taxes, discounts and usage based charges are excluded."
+
+// costListPrices are the published monthly prices of the resources whose
+// price isn't available from a provider api, in usd
+var costListPrices = map[string]struct {
+	controlPlane  float64
+	loadBalancer  float64
+	storagePerGi  float64
+	objectStorage float64
+}{
+	"akamai":       {loadBalancer: 10, storagePerGi: 0.10, objectStorage: 5},
+	"aws":          {controlPlane: 0.10 * hoursPerMonth, loadBalancer: 16.43, storagePerGi: 0.08},
+	"digitalocean": {loadBalancer: 12, storagePerGi: 0.10, objectStorage: 5},
+	"vultr":        {loadBalancer: 10, storagePerGi: 0.10, objectStorage: 6},
+}
+
+// costUnsupportedProviders are the cloud providers cost can't be estimated
+// for and why
+var costUnsupportedProviders = map[string]string{
+	"civo":   "civo has no pricing api to price the nodes with",
+	"google": "node prices would need the cloud billing catalog api, which isn't supported",
+	"k3s":    "k3s runs on existing servers whose cost isn't known",
+}
+
+// node prices are the same for every account, so a single package level
+// cache is shared between estimates
+var nodePriceCache = struct {
+	sync.Mutex
+	entries map[string]nodePriceCacheEntry
+}{entries: map[string]nodePriceCacheEntry{}}
+
+type nodePriceCacheEntry struct {
+	monthly float64
+	expires time.Time
+}
+
+// EstimateCost estimates the monthly cost of the control plane, node pool,
+// ingress load balancer, platform volumes and state store bucket a cluster
+// definition provisions, node prices come from the provider's pricing api
+func EstimateCost(def *pkgtypes.ClusterDefinition) (pkgtypes.CostEstimate, error) {
+	if reason, ok := costUnsupportedProviders[def.CloudProvider]; ok {
+		return pkgtypes.CostEstimate{}, fmt.Errorf("cost estimates are not available for %s: %s", def.CloudProvider, reason)
+	}
+	listPrices, ok := costListPrices[def.CloudProvider]
+	if !ok {
+		return pkgtypes.CostEstimate{}, fmt.Errorf("cost estimates are not available for %s", def.CloudProvider)
+	}
+	if def.NodeType == "" || def.NodeCount <= 0 {
+		return pkgtypes.CostEstimate{}, fmt.Errorf("node_type and node_count are required to estimate cost")
+	}
+
+	nodeMonthly, err := cachedNodeMonthlyPrice(def)
+	if err != nil {
+		return pkgtypes.CostEstimate{}, fmt.Errorf("error getting the price of %s node %s: %s", def.CloudProvider, def.NodeType, err)
+	}
+
+	estimate := pkgtypes.CostEstimate{
+		CloudProvider: def.CloudProvider,
+		CloudRegion:   def.CloudRegion,
+		Currency:      "USD",
+		Note:          costEstimateNote,
+	}
+	if listPrices.controlPlane > 0 {
+		estimate.Items = append(estimate.Items, costEstimateItem(pkgtypes.CostCategoryControlPlane, "kubernetes control plane", 1, listPrices.controlPlane, "list_price"))
+	}
+	estimate.Items = append(estimate.Items,
+		costEstimateItem(pkgtypes.CostCategoryNodes, fmt.Sprintf("%s nodes", def.NodeType), float64(def.NodeCount), nodeMonthly, "provider_api"),
+		costEstimateItem(pkgtypes.CostCategoryLoadBalancer, "ingress controller load balancer", 1, listPrices.loadBalancer, "list_price"),
+	)
+
+	volumeGi, err := platformVolumeGi(def)
+	if err != nil {
+		return pkgtypes.CostEstimate{}, err
+	}
+	if volumeGi > 0 {
+		estimate.Items = append(estimate.Items, costEstimateItem(pkgtypes.CostCategoryStorage, "platform volumes (GiB)", volumeGi, listPrices.storagePerGi, "list_price"))
+	}
+
+	if listPrices.objectStorage > 0 && def.ExistingStateStoreBucket == "" {
+		estimate.Items = append(estimate.Items, costEstimateItem(pkgtypes.CostCategoryObjectStorage, "state store bucket", 1, listPrices.objectStorage, "list_price"))
+	}
+
+	for _, item := range estimate.Items {
+		estimate.MonthlyTotal += item.Monthly
+	}
+	estimate.MonthlyTotal = roundCents(estimate.MonthlyTotal)
+
+	return estimate, nil
+}
+
+// costEstimateItem returns a line of the estimate
+func costEstimateItem(category string, description string, quantity float64, unitMonthly float64, source string) pkgtypes.CostEstimateItem {
+	return pkgtypes.CostEstimateItem{
+		Category:    category,
+		Description: description,
+		Quantity:    quantity,
+		UnitMonthly: roundCents(unitMonthly),
+		Monthly:     roundCents(quantity * unitMonthly),
+		Source:      source,
+	}
+}
+
+// platformVolumeGi sums the volumes of the platform components the
+// definition installs, unsized volumes are assumed to be assumedVolumeSizeGi
+func platformVolumeGi(def *pkgtypes.ClusterDefinition) (float64, error) {
+	dropped := providerConfigs.InstallProfiles[def.InstallProfile]
+	if def.ExternalSecrets.Provider != "" {
+		dropped = append(append([]string{}, dropped...), providerConfigs.ExternalSecretsReplacedComponents...)
+	}
+
+	components := make([]string, 0, len(providerConfigs.VolumeSizeComponents))
+	for component := range providerConfigs.VolumeSizeComponents {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+
+	var total float64
+	for _, component := range components {
+		if pkg.FindStringInSlice(dropped, component) {
+			continue
+		}
+
+		size, ok := def.VolumeSizes[component]
+		if !ok {
+			total += assumedVolumeSizeGi
+			continue
+		}
+
+		quantity, err := resource.ParseQuantity(size)
+		if err != nil {
+			return 0, fmt.Errorf("invalid volume size %q for %s: %s", size, component, err)
+		}
+		total += math.Ceil(float64(quantity.Value()) / (1 << 30))
+	}
+
+	return total, nil
+}
+
+// cachedNodeMonthlyPrice returns the monthly price of the definition's node
+// type from the cache or the provider's pricing api
+func cachedNodeMonthlyPrice(def *pkgtypes.ClusterDefinition) (float64, error) {
+	key := fmt.Sprintf("%s/%s/%s", def.CloudProvider, def.CloudRegion, def.NodeType)
+
+	nodePriceCache.Lock()
+	entry, ok := nodePriceCache.entries[key]
+	nodePriceCache.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.monthly, nil
+	}
+
+	monthly, err := nodeMonthlyPrice(def)
+	if err != nil {
+		return 0, err
+	}
+
+	nodePriceCache.Lock()
+	nodePriceCache.entries[key] = nodePriceCacheEntry{
+		monthly: monthly,
+		expires: time.Now().Add(nodePriceCacheTTL),
+	}
+	nodePriceCache.Unlock()
+
+	return monthly, nil
+}
+
+// nodeMonthlyPrice queries the provider's pricing api for the monthly price
+// of the definition's node type
+func nodeMonthlyPrice(def *pkgtypes.ClusterDefinition) (float64, error) {
+	switch def.CloudProvider {
+	case "akamai":
+		akamaiConf := akamai.AkamaiConfiguration{
+			Client:  akamai.NewAkamai(def.AkamaiAuth.Token, def.AkamaiAuth.APIURL),
+			Context: context.Background(),
+		}
+		return akamaiConf.GetTypeMonthlyPrice(def.NodeType, def.CloudRegion)
+	case "aws":
+		awsConf := awsinternal.AWSConfiguration{
+			Config: awsinternal.NewAwsV3(def.CloudRegion, def.AWSAuth.AccessKeyID, def.AWSAuth.SecretAccessKey, def.AWSAuth.SessionToken, def.AWSAuth.APIURL),
+		}
+		hourly, err := awsConf.GetInstanceHourlyPrice(def.NodeType, def.CloudRegion)
+		if err != nil {
+			return 0, err
+		}
+		return hourly * hoursPerMonth, nil
+	case "digitalocean":
+		digitaloceanConf := digitalocean.DigitaloceanConfiguration{
+			Client:  digitalocean.NewDigitalocean(def.DigitaloceanAuth.Token, def.DigitaloceanAuth.APIURL),
+			Context: context.Background(),
+		}
+		return digitaloceanConf.GetSizeMonthlyPrice(def.NodeType)
+	case "vultr":
+		vultrConf := vultr.VultrConfiguration{
+			Client:  vultr.NewVultr(def.VultrAuth.Token, def.VultrAuth.APIURL),
+			Context: context.Background(),
+		}
+		return vultrConf.GetPlanMonthlyCost(def.NodeType)
+	}
+
+	return 0, fmt.Errorf("cost estimates are not available for %s", def.CloudProvider)
+}
+
+// roundCents rounds an amount to cents
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,73 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestEstimateCost(t *testing.T) {
+	// seed the node price cache so no pricing api is called
+	nodePriceCache.Lock()
+	for _, key := range []string{"aws/us-east-1/t3.large", "digitalocean/nyc3/s-4vcpu-8gb"} {
+		nodePriceCache.entries[key] = nodePriceCacheEntry{monthly: 60, expires: time.Now().Add(time.Hour)}
+	}
+	nodePriceCache.Unlock()
+
+	for name, test := range map[string]struct {
+		def              pkgtypes.ClusterDefinition
+		wantControlPlane float64
+		wantErr          string
+	}{
+		"aws charges for the control plane": {
+			def:              pkgtypes.ClusterDefinition{CloudProvider: "aws", CloudRegion: "us-east-1", NodeType: "t3.large", NodeCount: 3},
+			wantControlPlane: 73,
+		},
+		"digitalocean control plane is free": {
+			def: pkgtypes.ClusterDefinition{CloudProvider: "digitalocean", CloudRegion: "nyc3", NodeType: "s-4vcpu-8gb", NodeCount: 3},
+		},
+		"google": {
+			def:     pkgtypes.ClusterDefinition{CloudProvider: "google", CloudRegion: "us-east1", NodeType: "e2-standard-4", NodeCount: 3},
+			wantErr: "cost estimates are not available for google: ",
+		},
+		"civo": {
+			def:     pkgtypes.ClusterDefinition{CloudProvider: "civo", CloudRegion: "nyc1", NodeType: "g4s.kube.large", NodeCount: 3},
+			wantErr: "cost estimates are not available for civo: ",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			estimate, err := EstimateCost(&test.def)
+			if test.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), test.wantErr) {
+					t.Fatalf("EstimateCost() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EstimateCost() = %s", err)
+			}
+
+			var controlPlane, total float64
+			for _, item := range estimate.Items {
+				if item.Category == pkgtypes.CostCategoryControlPlane {
+					controlPlane += item.Monthly
+				}
+				total += item.Monthly
+			}
+			if controlPlane != test.wantControlPlane {
+				t.Errorf("control plane = %.2f, want %.2f", controlPlane, test.wantControlPlane)
+			}
+			if roundCents(total) != estimate.MonthlyTotal {
+				t.Errorf("monthly total = %.2f, want the sum of the items %.2f", estimate.MonthlyTotal, total)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kubefirst/kubefirst-api/internal/gitClient"
+	"github.com/kubefirst/kubefirst-api/internal/github"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// Gitops file diff statuses
+const (
+	GitopsFileAdded    = "added"
+	GitopsFileRemoved  = "removed"
+	GitopsFileModified = "modified"
+)
+
+// DiffGitopsTemplate clones the gitops template at templateRef (defaulting
+// to the ref the cluster was created from), prepares and detokenizes it with
+// the cluster's own values and compares it with the HEAD of the cluster's
+// gitops repository so only real template drift is reported
+func (clctrl *ClusterController) DiffGitopsTemplate(templateRef string) (pkgtypes.GitopsTemplateDiff, error) {
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return pkgtypes.GitopsTemplateDiff{}, err
+	}
+	_, err = github.RefreshGitAuth(&cl.GitAuth)
+	if err != nil {
+		return pkgtypes.GitopsTemplateDiff{}, err
+	}
+
+	if templateRef == "" {
+		templateRef = cl.GitopsTemplateBranch
+	}
+
+	workDir, err := os.MkdirTemp("", fmt.Sprintf("gitops-diff-%s-", clctrl.ClusterName))
+	if err != nil {
+		return pkgtypes.GitopsTemplateDiff{}, err
+	}
+	defer os.RemoveAll(workDir)
+
+	clusterDir := filepath.Join(workDir, "cluster")
+	templateDir := filepath.Join(workDir, "template")
+
+	repoURL := clctrl.ProviderConfig.DestinationGitopsRepoURL
+	_, err = gitClient.ClonePrivateRepo(gitClient.BranchOrDefault(cl.DefaultBranch), clusterDir, repoURL, cl.GitAuth.User, cl.GitAuth.Token)
+	if err != nil {
+		return pkgtypes.GitopsTemplateDiff{}, fmt.Errorf("error cloning gitops repository %s: %s", repoURL, err)
+	}
+
+	templateCommit, err := clctrl.renderGitopsTemplate(cl, templateRef, "", templateDir, clusterDir)
+	if err != nil {
+		return pkgtypes.GitopsTemplateDiff{}, err
+	}
+
+	files, err := diffDirectories(templateDir, clusterDir)
+	if err != nil {
+		return pkgtypes.GitopsTemplateDiff{}, err
+	}
+
+	clctrl.logger().Infof("gitops repository for cluster %s differs from template %s in %d files", clctrl.ClusterName, templateRef, len(files))
+
+	return pkgtypes.GitopsTemplateDiff{
+		TemplateURL:    cl.GitopsTemplateURL,
+		TemplateRef:    templateRef,
+		TemplateCommit: templateCommit,
+		Files:          files,
+	}, nil
+}
+
+// renderGitopsTemplate clones the gitops template at templateRef (and commit
+// when set) into templateDir and renders it the same way RepositoryPrep does,
+// so detokenized values and provider specific layout match the cluster's
+// repository cloned at clusterDir - it returns the template commit rendered
+func (clctrl *ClusterController) renderGitopsTemplate(cl pkgtypes.Cluster, templateRef string, commit string, templateDir string, clusterDir string) (string, error) {
+	templateRepo, err := gitClient.CloneRefSetMain(templateRef, templateDir, cl.GitopsTemplateURL, gitClient.BranchOrDefault(cl.DefaultBranch))
+	if err != nil {
+		return "", fmt.Errorf("error cloning gitops template %s at %s: %s", cl.GitopsTemplateURL, templateRef, err)
+	}
+	if commit != "" {
+		err = gitClient.ResetToCommit(templateRepo, commit)
+		if err != nil {
+			return "", err
+		}
+	}
+	templateHead, err := templateRepo.Head()
+	if err != nil {
+		return "", err
+	}
+
+	useCloudflareOriginIssuer := cl.CloudflareAuth.OriginCaIssuerKey != ""
+	apexContentExists := !pathContains(clusterDir, "nginx-apex")
+	err = providerConfigs.AdjustGitopsRepo(
+		clctrl.CloudProvider,
+		clctrl.ClusterName,
+		clctrl.ClusterType,
+		templateDir,
+		clctrl.GitProvider,
+		filepath.Dir(templateDir),
+		apexContentExists,
+		useCloudflareOriginIssuer,
+		clctrl.InstallProfile,
+		clctrl.IngressController,
+		clctrl.NetworkPolicies,
+		clctrl.ExternalSecrets.Provider,
+		clctrl.ArgoCDSyncPolicy,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	err = providerConfigs.DetokenizeGitGitops(
+		templateDir,
+		clctrl.CreateTokens("gitops").(*providerConfigs.GitopsDirectoryValues),
+		cl.GitProtocol,
+		useCloudflareOriginIssuer,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return templateHead.Hash().String(), nil
+}
+
+// diffDirectories compares every file below templateDir and clusterDir,
+// ignoring .git directories, and returns the differing files sorted by path
+func diffDirectories(templateDir string, clusterDir string) ([]pkgtypes.GitopsFileDiff, error) {
+	templateFiles, err := readTree(templateDir)
+	if err != nil {
+		return nil, err
+	}
+	clusterFiles, err := readTree(clusterDir)
+	if err != nil {
+		return nil, err
+	}
+
+	dmp := diffmatchpatch.New()
+	files := []pkgtypes.GitopsFileDiff{}
+	for path, templateContent := range templateFiles {
+		clusterContent, ok := clusterFiles[path]
+		switch {
+		case !ok:
+			files = append(files, pkgtypes.GitopsFileDiff{
+				Path:       path,
+				Status:     GitopsFileAdded,
+				LinesAdded: countLines(templateContent),
+			})
+		case !bytes.Equal(templateContent, clusterContent):
+			a, b, lines := dmp.DiffLinesToChars(string(clusterContent), string(templateContent))
+			diffs := dmp.DiffCharsToLines(dmp.DiffMain(a, b, false), lines)
+
+			fileDiff := pkgtypes.GitopsFileDiff{Path: path, Status: GitopsFileModified}
+			for _, d := range diffs {
+				switch d.Type {
+				case diffmatchpatch.DiffInsert:
+					fileDiff.LinesAdded += countLines([]byte(d.Text))
+				case diffmatchpatch.DiffDelete:
+					fileDiff.LinesRemoved += countLines([]byte(d.Text))
+				}
+			}
+			files = append(files, fileDiff)
+		}
+	}
+	for path, clusterContent := range clusterFiles {
+		if _, ok := templateFiles[path]; !ok {
+			files = append(files, pkgtypes.GitopsFileDiff{
+				Path:         path,
+				Status:       GitopsFileRemoved,
+				LinesRemoved: countLines(clusterContent),
+			})
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	return files, nil
+}
+
+// readTree returns the contents of every file below root keyed by its
+// slash separated relative path
+func readTree(root string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = content
+
+		return nil
+	})
+
+	return files, err
+}
+
+// pathContains reports whether any file or directory below root has name
+// in its path
+func pathContains(root string, name string) bool {
+	found := false
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && strings.Contains(d.Name(), name) {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+
+	return found
+}
+
+// countLines returns the number of lines in content, counting a trailing
+// line without a newline
+func countLines(content []byte) int {
+	if len(content) == 0 {
+		return 0
+	}
+	n := bytes.Count(content, []byte("\n"))
+	if content[len(content)-1] != '\n' {
+		n++
+	}
+
+	return n
+}
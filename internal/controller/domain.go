@@ -7,21 +7,21 @@ See the LICENSE file for more details.
 package controller
 
 import (
-	"context"
 	"fmt"
 
-	cloudflare_api "github.com/cloudflare/cloudflare-go"
-	"github.com/kubefirst/kubefirst-api/internal/civo"
-	"github.com/kubefirst/kubefirst-api/internal/cloudflare"
-	"github.com/kubefirst/kubefirst-api/internal/digitalocean"
 	"github.com/kubefirst/kubefirst-api/internal/dns"
+	"github.com/kubefirst/kubefirst-api/internal/dnsProvider"
 	"github.com/kubefirst/kubefirst-api/internal/secrets"
-	"github.com/kubefirst/kubefirst-api/internal/vultr"
 	"github.com/kubefirst/metrics-client/pkg/telemetry"
-	log "github.com/rs/zerolog/log"
 )
 
-// DomainLivenessTest
+// newDNSProvider returns the dns provider of a cluster, overridden in tests
+var newDNSProvider = dnsProvider.New
+
+// DomainLivenessTest verifies the cluster domain with its dns provider, which
+// may differ from the cloud provider that runs the cluster. Providers that
+// manage zones create the zone first and also resolve a dns-01 challenge
+// record, as cert-manager will
 func (clctrl *ClusterController) DomainLivenessTest() error {
 	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
 	if err != nil {
@@ -29,91 +29,38 @@ func (clctrl *ClusterController) DomainLivenessTest() error {
 	}
 
 	if !cl.DomainLivenessCheck {
-		telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.DomainLivenessStarted, "")
-
-		switch clctrl.DnsProvider {
-		case "aws":
-			domainLiveness := clctrl.AwsClient.TestHostedZoneLiveness(clctrl.DomainName)
-
-			err = clctrl.HandleDomainLiveness(domainLiveness)
-			if err != nil {
-				return err
-			}
-		case "civo":
-			civoConf := civo.CivoConfiguration{
-				Client:  civo.NewCivo(cl.CivoAuth.Token, cl.CloudRegion),
-				Context: context.Background(),
-			}
-
-			// domain id
-			domainId, err := civoConf.GetDNSInfo(clctrl.DomainName, clctrl.CloudRegion)
-			if err != nil {
-				telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.DomainLivenessFailed, err.Error())
-				log.Info().Msg(err.Error())
-			}
-
-			log.Info().Msgf("domainId: %s", domainId)
-			domainLiveness := civoConf.TestDomainLiveness(clctrl.DomainName, domainId, clctrl.CloudRegion)
-
-			err = clctrl.HandleDomainLiveness(domainLiveness)
-			if err != nil {
-				return err
-			}
-		case "cloudflare":
-
-			client, err := cloudflare_api.NewWithAPIToken(clctrl.CloudflareAuth.APIToken)
-			if err != nil {
-				return err
-			}
-
-			cloudflareConf := cloudflare.CloudflareConfiguration{
-				Client:  client,
-				Context: context.Background(),
-			}
+		clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.DomainLivenessStarted, "")
 
-			domainLiveness := cloudflareConf.TestDomainLiveness(clctrl.DomainName)
-
-			err = clctrl.HandleDomainLiveness(domainLiveness)
-			if err != nil {
-				return err
-			}
-		case "digitalocean":
-			digitaloceanConf := digitalocean.DigitaloceanConfiguration{
-				Client:  digitalocean.NewDigitalocean(cl.DigitaloceanAuth.Token),
-				Context: context.Background(),
-			}
-
-			// domain id
-			domainId, err := digitaloceanConf.GetDNSInfo(clctrl.DomainName)
-			if err != nil {
-				log.Info().Msg(err.Error())
-			}
-
-			log.Info().Msgf("domainId: %s", domainId)
-			domainLiveness := digitaloceanConf.TestDomainLiveness(clctrl.DomainName)
+		provider, err := newDNSProvider(clctrl.DnsProvider, dnsProvider.CredentialsFromCluster(&cl))
+		if err != nil {
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.DomainLivenessFailed, err.Error())
+			return err
+		}
 
-			err = clctrl.HandleDomainLiveness(domainLiveness)
+		zones, managesZones := provider.(dnsProvider.ZoneManager)
+		if managesZones {
+			err = zones.CreateZone(clctrl.DomainName)
 			if err != nil {
+				clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.DomainLivenessFailed, err.Error())
 				return err
 			}
-		case "vultr":
-			vultrConf := vultr.VultrConfiguration{
-				Client:  vultr.NewVultr(cl.VultrAuth.Token),
-				Context: context.Background(),
-			}
+		}
 
-			// domain id
-			domainId, err := vultrConf.GetDNSInfo(clctrl.DomainName)
-			if err != nil {
-				log.Info().Msg(err.Error())
-			}
+		domainLiveness, err := provider.TestDomainLiveness(clctrl.DomainName)
+		if err != nil {
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.DomainLivenessFailed, err.Error())
+			clctrl.logger().Info(err.Error())
+		}
 
-			// viper values set in above function
-			log.Info().Msgf("domainId: %s", domainId)
-			domainLiveness := vultrConf.TestDomainLiveness(clctrl.DomainName)
+		err = clctrl.HandleDomainLiveness(domainLiveness)
+		if err != nil {
+			return err
+		}
 
-			err = clctrl.HandleDomainLiveness(domainLiveness)
+		if managesZones {
+			err = zones.DNS01RoundTrip(clctrl.DomainName)
 			if err != nil {
+				clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.DomainLivenessFailed, err.Error())
 				return err
 			}
 		}
@@ -125,9 +72,9 @@ func (clctrl *ClusterController) DomainLivenessTest() error {
 			return err
 		}
 
-		telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.DomainLivenessCompleted, "")
+		clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.DomainLivenessCompleted, "")
 
-		log.Info().Msgf("domain %s verified", clctrl.DomainName)
+		clctrl.logger().Infof("domain %s verified", clctrl.DomainName)
 	}
 
 	return nil
@@ -138,7 +85,7 @@ func (clctrl *ClusterController) HandleDomainLiveness(domainLiveness bool) error
 	if !domainLiveness {
 		foundRecords, err := dns.GetDomainNSRecords(clctrl.DomainName)
 		if err != nil {
-			log.Warn().Msgf("error attempting to get NS records for domain %s: %s", clctrl.DomainName, err)
+			clctrl.logger().Warnf("error attempting to get NS records for domain %s: %s", clctrl.DomainName, err)
 		}
 		msg := fmt.Sprintf("failed to verify domain liveness for domain %s", clctrl.DomainName)
 		if len(foundRecords) != 0 {
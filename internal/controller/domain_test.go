@@ -0,0 +1,90 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kubefirst/kubefirst-api/internal/dnsProvider"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// fakeZoneManager records the calls of the domain liveness step
+type fakeZoneManager struct {
+	calls     []string
+	createErr error
+}
+
+func (p *fakeZoneManager) Name() string { return "fake" }
+
+func (p *fakeZoneManager) ListZones() ([]string, error) { return nil, nil }
+
+func (p *fakeZoneManager) TestDomainLiveness(domainName string) (bool, error) {
+	p.calls = append(p.calls, "liveness "+domainName)
+	return true, nil
+}
+
+func (p *fakeZoneManager) CreateZone(domainName string) error {
+	p.calls = append(p.calls, "zone "+domainName)
+	return p.createErr
+}
+
+func (p *fakeZoneManager) DNS01RoundTrip(domainName string) error {
+	p.calls = append(p.calls, "dns-01 "+domainName)
+	return nil
+}
+
+func TestDomainLivenessTestZoneManager(t *testing.T) {
+	defer func(original func(string, dnsProvider.Credentials) (dnsProvider.DNSProvider, error)) {
+		newDNSProvider = original
+	}(newDNSProvider)
+
+	for name, test := range map[string]struct {
+		createErr error
+		wantCalls []string
+		wantErr   bool
+	}{
+		"zone created": {
+			wantCalls: []string{"zone example.com", "liveness example.com", "dns-01 example.com"},
+		},
+		"zone creation failed": {
+			createErr: errors.New("forbidden"),
+			wantCalls: []string{"zone example.com"},
+			wantErr:   true,
+		},
+	} {
+		store, err := secrets.NewMemoryStore("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		secrets.SetStore(store)
+
+		err = secrets.InsertCluster(nil, pkgtypes.Cluster{ClusterName: "kf-test", DomainName: "example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		provider := &fakeZoneManager{createErr: test.createErr}
+		newDNSProvider = func(string, dnsProvider.Credentials) (dnsProvider.DNSProvider, error) { return provider, nil }
+
+		clctrl := &ClusterController{
+			ClusterName: "kf-test",
+			DomainName:  "example.com",
+			Cluster:     pkgtypes.Cluster{ClusterName: "kf-test", DomainName: "example.com"},
+		}
+		err = clctrl.DomainLivenessTest()
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: DomainLivenessTest() = %v, want error %v", name, err, test.wantErr)
+		}
+		if !reflect.DeepEqual(provider.calls, test.wantCalls) {
+			t.Errorf("%s: calls = %v, want %v", name, provider.calls, test.wantCalls)
+		}
+		secrets.SetStore(nil)
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// egressGatewayProviders are the cloud providers whose terraform can route
+// node traffic through a NAT gateway with a static ip
+var egressGatewayProviders = []string{"aws", "google"}
+
+const (
+	// egressCheckPodName is the pod that reports the cluster's source ip
+	egressCheckPodName = "kubefirst-egress-check"
+
+	egressCheckNamespace = "default"
+	egressCheckImage     = "curlimages/curl:8.4.0"
+
+	// egressCheckURL echoes the public ip a request arrives from
+	egressCheckURL = "https://checkip.amazonaws.com"
+
+	// egressCheckTimeout bounds scheduling, pulling and running the check pod
+	egressCheckTimeout = 5 * time.Minute
+)
+
+// validateEgressGateway checks the egress gateway fields of a definition
+// without calling the cloud provider
+func validateEgressGateway(def *pkgtypes.ClusterDefinition) error {
+	gateway := def.EgressGateway
+	if gateway.EgressIP == "" {
+		if gateway.NatGatewayID != "" {
+			return fmt.Errorf("egress_gateway.nat_gateway_id requires egress_gateway.egress_ip")
+		}
+		return nil
+	}
+
+	if !pkg.FindStringInSlice(egressGatewayProviders, def.CloudProvider) {
+		return fmt.Errorf("egress gateways are not supported for cloud provider %s: must be one of %v", def.CloudProvider, egressGatewayProviders)
+	}
+
+	ip := net.ParseIP(gateway.EgressIP)
+	if ip == nil || ip.To4() == nil {
+		return fmt.Errorf("invalid egress_gateway.egress_ip %s: must be an ipv4 address", gateway.EgressIP)
+	}
+	if ip.IsPrivate() || ip.IsLoopback() || ip.IsUnspecified() {
+		return fmt.Errorf("invalid egress_gateway.egress_ip %s: must be a public address", gateway.EgressIP)
+	}
+
+	return nil
+}
+
+// getEgressGatewayTerraformEnvs routes the node pools through the NAT
+// gateway, an empty gateway id lets the terraform create one with the ip
+func getEgressGatewayTerraformEnvs(envs map[string]string, gateway pkgtypes.EgressGateway) map[string]string {
+	if gateway.EgressIP == "" {
+		return envs
+	}
+
+	envs["TF_VAR_egress_gateway_enabled"] = "true"
+	envs["TF_VAR_egress_ip"] = gateway.EgressIP
+	envs["TF_VAR_egress_nat_gateway_id"] = gateway.NatGatewayID
+
+	return envs
+}
+
+// VerifyEgressIP runs a pod on the new cluster that asks an external service
+// which address its traffic arrives from, and fails when it isn't the
+// configured egress ip
+func (clctrl *ClusterController) VerifyEgressIP() error {
+	if clctrl.EgressGateway.EgressIP == "" {
+		return nil
+	}
+
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return err
+	}
+	if cl.EgressIPVerifiedCheck {
+		return nil
+	}
+
+	kcfg, err := clctrl.GetClusterKubernetesClient()
+	if err != nil {
+		return err
+	}
+	pods := kcfg.Clientset.CoreV1().Pods(egressCheckNamespace)
+
+	ctx, cancel := context.WithTimeout(context.Background(), egressCheckTimeout)
+	defer cancel()
+
+	// a pod left behind by an interrupted check would block the create
+	_ = pods.Delete(ctx, egressCheckPodName, metav1.DeleteOptions{})
+	for {
+		_, err := pods.Get(ctx, egressCheckPodName, metav1.GetOptions{})
+		if err != nil {
+			break
+		}
+		time.Sleep(2 * time.Second)
+	}
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   egressCheckPodName,
+			Labels: map[string]string{"app.kubernetes.io/managed-by": "kubefirst"},
+		},
+		Spec: v1.PodSpec{
+			RestartPolicy: v1.RestartPolicyNever,
+			// node taints from the definition must not keep the check off
+			// the nodes whose traffic it verifies
+			Tolerations: []v1.Toleration{{Operator: v1.TolerationOpExists}},
+			Containers: []v1.Container{{
+				Name:    "curl",
+				Image:   egressCheckImage,
+				Command: []string{"curl", "-sS", "--max-time", "30", egressCheckURL},
+			}},
+		},
+	}
+	_, err = pods.Create(ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("error creating egress check pod: %s", err)
+	}
+	defer pods.Delete(context.Background(), egressCheckPodName, metav1.DeleteOptions{})
+
+	clctrl.logger().Infof("verifying outbound traffic of cluster %s leaves from %s", clctrl.ClusterName, clctrl.EgressGateway.EgressIP)
+
+	var phase v1.PodPhase
+	for phase != v1.PodSucceeded && phase != v1.PodFailed {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out after %s waiting for the egress check pod, last phase %s", egressCheckTimeout, phase)
+		case <-time.After(5 * time.Second):
+		}
+
+		current, err := pods.Get(ctx, egressCheckPodName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("error getting egress check pod: %s", err)
+		}
+		phase = current.Status.Phase
+	}
+
+	stream, err := pods.GetLogs(egressCheckPodName, &v1.PodLogOptions{}).Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("error reading egress check pod logs: %s", err)
+	}
+	defer stream.Close()
+	output, err := io.ReadAll(stream)
+	if err != nil {
+		return fmt.Errorf("error reading egress check pod logs: %s", err)
+	}
+	if phase == v1.PodFailed {
+		return fmt.Errorf("egress check pod could not reach %s: %s", egressCheckURL, strings.TrimSpace(string(output)))
+	}
+
+	sourceIP := strings.TrimSpace(string(output))
+	if sourceIP != clctrl.EgressGateway.EgressIP {
+		return fmt.Errorf("outbound traffic of cluster %s leaves from %s instead of the egress ip %s, check the NAT gateway and node pool routes", clctrl.ClusterName, sourceIP, clctrl.EgressGateway.EgressIP)
+	}
+
+	clctrl.logger().Infof("outbound traffic of cluster %s leaves from egress ip %s", clctrl.ClusterName, sourceIP)
+
+	clctrl.Cluster.EgressIPVerifiedCheck = true
+	return secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
+}
@@ -0,0 +1,211 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// eventBufferSize is how many events a subscriber may fall behind before it
+// is disconnected, so a slow client never holds up provisioning
+var eventBufferSize = 256
+
+// eventHistorySize is how many of the latest events are replayed to a
+// subscriber that joins during an install
+const eventHistorySize = 200
+
+// eventStream fans the provisioning events of one cluster out to its
+// subscribers, it is active between AcquireProvisionSlot and
+// ReleaseProvisionSlot
+type eventStream struct {
+	active      bool
+	history     []pkgtypes.ProvisionEvent
+	subscribers map[chan pkgtypes.ProvisionEvent]struct{}
+}
+
+// eventStreams are the event streams by cluster name, a stream is kept
+// while it's active or has subscribers waiting for an install to start
+var eventStreams = struct {
+	sync.Mutex
+	streams map[string]*eventStream
+}{streams: map[string]*eventStream{}}
+
+// SubscribeProvisionEvents follows the provisioning events of a cluster,
+// replaying the latest ones of an install in progress. The channel is closed
+// after the done event, or early when the subscriber falls more than
+// eventBufferSize events behind. active reports whether this api is
+// provisioning the cluster right now, and unsubscribe must be called once
+// the caller stops reading
+func SubscribeProvisionEvents(clusterName string) (events <-chan pkgtypes.ProvisionEvent, unsubscribe func(), active bool) {
+	eventStreams.Lock()
+	defer eventStreams.Unlock()
+
+	stream, ok := eventStreams.streams[clusterName]
+	if !ok {
+		stream = &eventStream{subscribers: map[chan pkgtypes.ProvisionEvent]struct{}{}}
+		eventStreams.streams[clusterName] = stream
+	}
+
+	ch := make(chan pkgtypes.ProvisionEvent, eventBufferSize+len(stream.history))
+	for _, event := range stream.history {
+		ch <- event
+	}
+	stream.subscribers[ch] = struct{}{}
+
+	unsubscribe = func() {
+		eventStreams.Lock()
+		defer eventStreams.Unlock()
+
+		if _, ok := stream.subscribers[ch]; ok {
+			delete(stream.subscribers, ch)
+			close(ch)
+		}
+		if !stream.active && len(stream.subscribers) == 0 && eventStreams.streams[clusterName] == stream {
+			delete(eventStreams.streams, clusterName)
+		}
+	}
+
+	return ch, unsubscribe, stream.active
+}
+
+// openEvents starts publishing the controller's logs and steps to the
+// subscribers of its cluster
+func (clctrl *ClusterController) openEvents() {
+	eventStreams.Lock()
+	defer eventStreams.Unlock()
+
+	stream, ok := eventStreams.streams[clctrl.ClusterName]
+	if !ok {
+		stream = &eventStream{subscribers: map[chan pkgtypes.ProvisionEvent]struct{}{}}
+		eventStreams.streams[clctrl.ClusterName] = stream
+	}
+	stream.active = true
+	stream.history = nil
+	clctrl.streamingEvents = true
+}
+
+// closeEvents sends the done event with the cluster's status and ends the
+// stream of its subscribers
+func (clctrl *ClusterController) closeEvents() {
+	if !clctrl.streamingEvents {
+		return
+	}
+	clctrl.publishEvent(pkgtypes.ProvisionEvent{
+		Type:    pkgtypes.ProvisionEventDone,
+		Status:  clctrl.Cluster.Status,
+		Message: clctrl.Cluster.LastCondition,
+	})
+	clctrl.streamingEvents = false
+
+	eventStreams.Lock()
+	defer eventStreams.Unlock()
+
+	stream, ok := eventStreams.streams[clctrl.ClusterName]
+	if !ok {
+		return
+	}
+	for ch := range stream.subscribers {
+		delete(stream.subscribers, ch)
+		close(ch)
+	}
+	delete(eventStreams.streams, clctrl.ClusterName)
+}
+
+// publishEvent sends an event to the subscribers of the controller's
+// cluster, disconnecting the ones whose buffer is full
+func (clctrl *ClusterController) publishEvent(event pkgtypes.ProvisionEvent) {
+	if !clctrl.streamingEvents {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	eventStreams.Lock()
+	defer eventStreams.Unlock()
+
+	stream, ok := eventStreams.streams[clctrl.ClusterName]
+	if !ok || !stream.active {
+		return
+	}
+
+	stream.history = append(stream.history, event)
+	if len(stream.history) > eventHistorySize {
+		stream.history = stream.history[len(stream.history)-eventHistorySize:]
+	}
+
+	for ch := range stream.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(stream.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// publishStep publishes the status of a step of the create pipeline
+func (clctrl *ClusterController) publishStep(step string, status string, message string) {
+	clctrl.publishEvent(pkgtypes.ProvisionEvent{
+		Type:    pkgtypes.ProvisionEventStep,
+		Step:    step,
+		Status:  status,
+		Message: message,
+	})
+}
+
+// eventLogger publishes every line logged through it as a log event before
+// handing it to the controller's logger
+type eventLogger struct {
+	Logger
+	clctrl *ClusterController
+}
+
+func (l eventLogger) publish(level string, msg string) {
+	l.clctrl.publishEvent(pkgtypes.ProvisionEvent{
+		Type:    pkgtypes.ProvisionEventLog,
+		Level:   level,
+		Message: msg,
+	})
+}
+
+func (l eventLogger) Info(msg string) {
+	l.Logger.Info(msg)
+	l.publish("info", msg)
+}
+
+func (l eventLogger) Infof(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	l.Logger.Info(msg)
+	l.publish("info", msg)
+}
+
+func (l eventLogger) Warn(msg string) {
+	l.Logger.Warn(msg)
+	l.publish("warn", msg)
+}
+
+func (l eventLogger) Warnf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	l.Logger.Warn(msg)
+	l.publish("warn", msg)
+}
+
+func (l eventLogger) Error(msg string) {
+	l.Logger.Error(msg)
+	l.publish("error", msg)
+}
+
+func (l eventLogger) Errorf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	l.Logger.Error(msg)
+	l.publish("error", msg)
+}
@@ -0,0 +1,63 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"testing"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestProvisionEvents(t *testing.T) {
+	defer func(size int) { eventBufferSize = size }(eventBufferSize)
+	eventBufferSize = 2
+
+	clctrl := &ClusterController{ClusterName: "kf-events"}
+	clctrl.logger().Info("not streamed before the install starts")
+
+	waiting, unsubscribeWaiting, active := SubscribeProvisionEvents("kf-events")
+	defer unsubscribeWaiting()
+	if active {
+		t.Fatal("expected no active install")
+	}
+
+	clctrl.openEvents()
+	clctrl.logger().Infof("creating %s", "kf-events")
+	clctrl.RunStep("GitInit", func() error { return nil })
+
+	// the waiting subscriber fell behind the step events
+	events := []pkgtypes.ProvisionEvent{}
+	for event := range waiting {
+		events = append(events, event)
+	}
+	if len(events) != 2 || events[0].Message != "creating kf-events" {
+		t.Errorf("expected the waiting subscriber to get two events before it was dropped, got %+v", events)
+	}
+
+	late, unsubscribeLate, active := SubscribeProvisionEvents("kf-events")
+	defer unsubscribeLate()
+	if !active {
+		t.Fatal("expected an active install")
+	}
+
+	clctrl.Cluster.Status = "provisioned"
+	clctrl.closeEvents()
+
+	events = []pkgtypes.ProvisionEvent{}
+	for event := range late {
+		events = append(events, event)
+	}
+	if len(events) != 4 {
+		t.Fatalf("expected the replayed events and done, got %+v", events)
+	}
+	if events[2].Step != "GitInit" || events[2].Status != "succeeded" {
+		t.Errorf("expected GitInit to have succeeded, got %+v", events[2])
+	}
+	if events[3].Type != pkgtypes.ProvisionEventDone || events[3].Status != "provisioned" {
+		t.Errorf("expected the done event with the cluster status, got %+v", events[3])
+	}
+}
@@ -0,0 +1,189 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	runtime "github.com/kubefirst/kubefirst-api/internal"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// validateExternalSecrets makes sure the cloud secret manager is supported
+// in the cluster's cloud and that nothing else in the definition configures
+// the vault it replaces
+func validateExternalSecrets(def *pkgtypes.ClusterDefinition) error {
+	provider := def.ExternalSecrets.Provider
+	if provider == "" {
+		if def.ExternalSecrets.Prefix != "" {
+			return fmt.Errorf("external_secrets.provider is required when external_secrets.prefix is set")
+		}
+		return nil
+	}
+
+	cloudProvider, ok := providerConfigs.ExternalSecretsProviders[provider]
+	if !ok {
+		return fmt.Errorf("unsupported external secrets provider %s: must be one of %v", provider, providerConfigs.ExternalSecretsProviderNames())
+	}
+	if cloudProvider != def.CloudProvider {
+		return fmt.Errorf("external secrets provider %s is only supported on %s clusters", provider, cloudProvider)
+	}
+
+	if def.Type != "mgmt" {
+		return fmt.Errorf("external_secrets is only supported for mgmt clusters")
+	}
+
+	for _, component := range providerConfigs.ExternalSecretsReplacedComponents {
+		if _, ok := def.VolumeSizes[component]; ok {
+			return fmt.Errorf("volume_sizes.%s cannot be set with external_secrets: %s is not installed", component, component)
+		}
+		if _, ok := def.ResourceOverrides[component]; ok {
+			return fmt.Errorf("resource_overrides.%s cannot be set with external_secrets: %s is not installed", component, component)
+		}
+		if _, ok := def.PodSecurity.NamespaceOverrides[component]; ok {
+			return fmt.Errorf("pod_security.namespace_overrides.%s cannot be set with external_secrets: %s is not installed", component, component)
+		}
+	}
+
+	return nil
+}
+
+// UsesExternalSecrets reports whether platform secrets are stored in a cloud
+// secret manager instead of vault, the vault steps are skipped when it does
+func (clctrl *ClusterController) UsesExternalSecrets() bool {
+	return clctrl.ExternalSecrets.Provider != ""
+}
+
+// externalSecretName returns the name a platform secret is stored as in the
+// cloud secret manager, google secret ids can't contain slashes
+func (clctrl *ClusterController) externalSecretName(name string) string {
+	if clctrl.ExternalSecrets.Provider == "google-secret-manager" {
+		return fmt.Sprintf("%s-%s", clctrl.ExternalSecrets.Prefix, strings.ReplaceAll(name, "/", "-"))
+	}
+
+	return fmt.Sprintf("%s/%s", clctrl.ExternalSecrets.Prefix, name)
+}
+
+// terraformPlatformSecrets returns the platform secrets the vault terraform
+// writes below vault's secret mount and the users terraform writes below
+// its users mount, keyed by their path with the users ones below users/.
+// The cloud secret manager gets them from WriteExternalSecrets because
+// neither terraform is applied without vault
+func terraformPlatformSecrets(cl pkgtypes.Cluster, base64DockerAuth string, kbotPassword string) map[string]map[string]string {
+	gitProvider := strings.ToUpper(cl.GitProvider)
+	atlantisGitProvider, containerRegistryHost := "GH", "ghcr.io"
+	if cl.GitProvider == "gitlab" {
+		atlantisGitProvider, containerRegistryHost = "GITLAB", "registry.gitlab.com"
+	}
+
+	dockerConfig := fmt.Sprintf(`{"auths":{"%s":{"auth":"%s"}}}`, containerRegistryHost, base64DockerAuth)
+
+	return map[string]map[string]string{
+		"atlantis": {
+			fmt.Sprintf("%s_TOKEN", gitProvider):                           cl.GitAuth.Token,
+			fmt.Sprintf("%s_OWNER", gitProvider):                           cl.GitAuth.Owner,
+			fmt.Sprintf("ATLANTIS_%s_USER", atlantisGitProvider):           cl.GitAuth.User,
+			fmt.Sprintf("ATLANTIS_%s_TOKEN", atlantisGitProvider):          cl.GitAuth.Token,
+			fmt.Sprintf("ATLANTIS_%s_WEBHOOK_SECRET", atlantisGitProvider): cl.AtlantisWebhookSecret,
+			"TF_VAR_atlantis_repo_webhook_secret":                          cl.AtlantisWebhookSecret,
+			"TF_VAR_atlantis_repo_webhook_url":                             cl.AtlantisWebhookURL,
+			"TF_VAR_kbot_ssh_public_key":                                   cl.GitAuth.PublicKey,
+			"TF_VAR_cloudflare_origin_ca_api_key":                          cl.CloudflareAuth.OriginCaIssuerKey,
+			"TF_VAR_cloudflare_api_key":                                    cl.CloudflareAuth.APIToken,
+		},
+		"ci-secrets": {
+			"SSH_PRIVATE_KEY":       cl.GitAuth.PrivateKey,
+			"PERSONAL_ACCESS_TOKEN": cl.GitAuth.Token,
+		},
+		"docker-config": {
+			"config.json": dockerConfig,
+		},
+		fmt.Sprintf("%s/kbot", usersVaultMount): {
+			usersInitialPasswordKey: kbotPassword,
+		},
+	}
+}
+
+// externalPlatformSecrets returns every platform secret of a cluster using
+// a cloud secret manager, the ones WriteVaultSecrets writes to vault and the
+// ones the vault and users terraform write
+func externalPlatformSecrets(cl pkgtypes.Cluster, base64DockerAuth string, kbotPassword string) (map[string]map[string]string, error) {
+	platformSecrets, err := vaultPlatformSecrets(cl)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range terraformPlatformSecrets(cl, base64DockerAuth, kbotPassword) {
+		platformSecrets[name] = values
+	}
+
+	return platformSecrets, nil
+}
+
+// WriteExternalSecrets writes the platform secrets vault holds on clusters
+// without a cloud secret manager to the cloud secret manager as json
+// objects, external secrets operator reads their keys as properties through
+// the cloud-secret-manager ClusterSecretStore. kbot's initial password is
+// generated here since the users terraform that would create it isn't
+// applied
+func (clctrl *ClusterController) WriteExternalSecrets() error {
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	// kept on the record first so a retry writes the same password
+	if cl.VaultAuth.KbotPassword == "" {
+		cl.VaultAuth.KbotPassword = runtime.Random(20)
+		err = secrets.UpdateCluster(clctrl.KubernetesClient, cl)
+		if err != nil {
+			return err
+		}
+		clctrl.VaultAuth.KbotPassword = cl.VaultAuth.KbotPassword
+		clctrl.Cluster = cl
+	}
+
+	base64DockerAuth, _, err := clctrl.containerRegistryDockerAuth()
+	if err != nil {
+		return err
+	}
+
+	platformSecrets, err := externalPlatformSecrets(cl, base64DockerAuth, cl.VaultAuth.KbotPassword)
+	if err != nil {
+		return err
+	}
+
+	for name, values := range platformSecrets {
+		secretName := clctrl.externalSecretName(name)
+
+		data, err := json.Marshal(values)
+		if err != nil {
+			return fmt.Errorf("error encoding secret %s: %s", secretName, err)
+		}
+
+		switch clctrl.ExternalSecrets.Provider {
+		case "aws-secrets-manager":
+			err = clctrl.AwsClient.PutSecretString(secretName, string(data))
+		case "google-secret-manager":
+			err = clctrl.GoogleClient.PutSecret(secretName, data)
+		default:
+			err = fmt.Errorf("unsupported external secrets provider %s", clctrl.ExternalSecrets.Provider)
+		}
+		if err != nil {
+			clctrl.logger().Errorf("error writing platform secret %s to %s: %s", secretName, clctrl.ExternalSecrets.Provider, err)
+			return err
+		}
+	}
+
+	clctrl.logger().Infof("successfully wrote platform secrets to %s below %s", clctrl.ExternalSecrets.Provider, clctrl.ExternalSecrets.Prefix)
+
+	return nil
+}
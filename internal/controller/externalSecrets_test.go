@@ -0,0 +1,77 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestExternalPlatformSecrets(t *testing.T) {
+	for _, gitProvider := range []string{"github", "gitlab"} {
+		cl := pkgtypes.Cluster{
+			CloudProvider:         "aws",
+			DnsProvider:           "cloudflare",
+			GitProvider:           gitProvider,
+			GitAuth:               pkgtypes.GitAuth{Token: "git-token", User: "kbot", Owner: "org", PublicKey: "ssh-ed25519 kbot", PrivateKey: "kbot-private-key"},
+			AtlantisWebhookSecret: "webhook-secret",
+			AtlantisWebhookURL:    "https://atlantis.example.com/events",
+			CloudflareAuth:        pkgtypes.CloudflareAuth{APIToken: "cf-token", OriginCaIssuerKey: "cf-origin"},
+			OIDC:                  pkgtypes.OIDC{IssuerURL: "https://idp.example.com", ClientID: "id", ClientSecret: "secret"},
+			ImagePullSecrets:      []pkgtypes.ImagePullSecret{{Name: "private", Server: "registry.example.com", Username: "user", Password: "password"}},
+		}
+
+		// what a vault cluster gets from WriteVaultSecrets and the terraform
+		want, err := vaultPlatformSecrets(cl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, values := range terraformPlatformSecrets(cl, "docker-auth", "kbot-password") {
+			if _, ok := want[name]; ok {
+				t.Errorf("%s: secret %s is written by both WriteVaultSecrets and the terraform", gitProvider, name)
+			}
+			want[name] = values
+		}
+
+		got, err := externalPlatformSecrets(cl, "docker-auth", "kbot-password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secretKeys(got) != secretKeys(want) {
+			t.Errorf("%s: externalPlatformSecrets() = %s, want %s", gitProvider, secretKeys(got), secretKeys(want))
+		}
+
+		for name, values := range map[string]map[string]string{
+			"atlantis":     {"TF_VAR_atlantis_repo_webhook_secret": "webhook-secret", strings.ToUpper(gitProvider) + "_TOKEN": "git-token"},
+			"ci-secrets":   {"SSH_PRIVATE_KEY": "kbot-private-key"},
+			"users/kbot":   {"initial-password": "kbot-password"},
+			"external-dns": {"token": "cf-token"},
+		} {
+			for key, value := range values {
+				if got[name][key] != value {
+					t.Errorf("%s: secret %s key %s = %q, want %q", gitProvider, name, key, got[name][key], value)
+				}
+			}
+		}
+	}
+}
+
+// secretKeys lists every secret name and key of platformSecrets sorted
+func secretKeys(platformSecrets map[string]map[string]string) string {
+	keys := []string{}
+	for name, values := range platformSecrets {
+		for key := range values {
+			keys = append(keys, name+"."+key)
+		}
+	}
+	sort.Strings(keys)
+
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,82 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// validateFeatureGates makes sure every gate is known
+func validateFeatureGates(gates map[string]bool) error {
+	for gate := range gates {
+		if _, ok := pkgtypes.FeatureGateDefaults[gate]; !ok {
+			return fmt.Errorf("unknown feature gate %s, known gates are %s", gate, strings.Join(knownFeatureGates(), ", "))
+		}
+	}
+
+	return nil
+}
+
+func knownFeatureGates() []string {
+	gates := make([]string, 0, len(pkgtypes.FeatureGateDefaults))
+	for gate := range pkgtypes.FeatureGateDefaults {
+		gates = append(gates, gate)
+	}
+	sort.Strings(gates)
+
+	return gates
+}
+
+// FeatureEnabled reports whether the cluster record enables gate, operations
+// read it from the record they load at their start so a change applies to
+// the next operation without restarting the api
+func (clctrl *ClusterController) FeatureEnabled(gate string) (bool, error) {
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return false, err
+	}
+
+	return cl.FeatureEnabled(gate), nil
+}
+
+// SetFeatureGate enables or disables gate for the cluster, overriding its
+// default
+func (clctrl *ClusterController) SetFeatureGate(gate string, enabled bool) error {
+	err := CheckWritable()
+	if err != nil {
+		return err
+	}
+
+	err = validateFeatureGates(map[string]bool{gate: enabled})
+	if err != nil {
+		return err
+	}
+
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return err
+	}
+	if cl.FeatureGates == nil {
+		cl.FeatureGates = map[string]bool{}
+	}
+	cl.FeatureGates[gate] = enabled
+
+	err = secrets.UpdateCluster(clctrl.KubernetesClient, cl)
+	if err != nil {
+		return fmt.Errorf("error saving feature gate %s for cluster %s: %s", gate, clctrl.ClusterName, err)
+	}
+	clctrl.FeatureGates = cl.FeatureGates
+
+	clctrl.logger().Infof("feature gate %s set to %t", gate, enabled)
+
+	return nil
+}
@@ -16,17 +16,20 @@ import (
 	digitaloceanext "github.com/kubefirst/kubefirst-api/extensions/digitalocean"
 	googleext "github.com/kubefirst/kubefirst-api/extensions/google"
 	k3sext "github.com/kubefirst/kubefirst-api/extensions/k3s"
-	terraformext "github.com/kubefirst/kubefirst-api/extensions/terraform"
 	vultrext "github.com/kubefirst/kubefirst-api/extensions/vultr"
 	gitShim "github.com/kubefirst/kubefirst-api/internal/gitShim"
 	"github.com/kubefirst/kubefirst-api/internal/gitlab"
 	"github.com/kubefirst/kubefirst-api/internal/secrets"
 	"github.com/kubefirst/metrics-client/pkg/telemetry"
-	log "github.com/rs/zerolog/log"
 )
 
 // GitInit
 func (clctrl *ClusterController) GitInit() error {
+	err := clctrl.refreshGitToken()
+	if err != nil {
+		return err
+	}
+
 	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
 	if err != nil {
 		return err
@@ -39,16 +42,34 @@ func (clctrl *ClusterController) GitInit() error {
 			GitToken:     clctrl.GitAuth.Token,
 			GitOwner:     clctrl.GitAuth.Owner,
 			GitProtocol:  clctrl.GitProtocol,
-			Repositories: clctrl.Repositories,
+			Repositories: clctrl.ownerRepositories(),
 			Teams:        clctrl.Teams,
 			GithubOrg:    clctrl.GitAuth.Owner,
 			GitlabGroup:  clctrl.GitAuth.Owner,
+
+			AdoptRepositories:      clctrl.AdoptRepositories,
+			ForceAdoptRepositories: clctrl.ForceAdoptRepositories,
 		}
-		err := gitShim.InitializeGitProvider(&initGitParameters)
+		adopted, adoptedTeams, err := gitShim.InitializeGitProvider(&initGitParameters)
 		if err != nil {
 			return err
 		}
 
+		// the metaphor repository is checked under its own owner
+		if clctrl.separateMetaphorOwner() {
+			metaphorParameters := initGitParameters
+			metaphorParameters.Repositories = []string{metaphorRepository}
+			metaphorParameters.Teams = nil
+			metaphorParameters.GithubOrg = clctrl.MetaphorOwner
+			metaphorAdopted, _, err := gitShim.InitializeGitProvider(&metaphorParameters)
+			if err != nil {
+				return err
+			}
+			adopted = append(adopted, metaphorAdopted...)
+		}
+
+		clctrl.Cluster.AdoptedRepositories = adopted
+		clctrl.Cluster.AdoptedTeams = adoptedTeams
 		clctrl.Cluster.GitInitCheck = true
 		err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
 		if err != nil {
@@ -61,6 +82,11 @@ func (clctrl *ClusterController) GitInit() error {
 
 // RunGitTerraform
 func (clctrl *ClusterController) RunGitTerraform() error {
+	err := clctrl.refreshGitToken()
+	if err != nil {
+		return err
+	}
+
 	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
 	if err != nil {
 		return err
@@ -68,9 +94,9 @@ func (clctrl *ClusterController) RunGitTerraform() error {
 
 	// //* create teams and repositories in github
 
-	telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.GitTerraformApplyStarted, "")
+	clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.GitTerraformApplyStarted, "")
 
-	log.Info().Msgf("Creating %s resources with terraform", clctrl.GitProvider)
+	clctrl.logger().Infof("Creating %s resources with terraform", clctrl.GitProvider)
 
 	tfEntrypoint := clctrl.ProviderConfig.GitopsDir + fmt.Sprintf("/terraform/%s", clctrl.GitProvider)
 	tfEnvs := map[string]string{}
@@ -113,22 +139,51 @@ func (clctrl *ClusterController) RunGitTerraform() error {
 			}
 		}
 
-		err := terraformext.InitApplyAutoApprove(clctrl.ProviderConfig.TerraformClient, tfEntrypoint, tfEnvs)
+		err := clctrl.importAdoptedRepositories(clctrl.terraformAdoptedRepositories(cl.AdoptedRepositories), tfEntrypoint, tfEnvs)
+		if err != nil {
+			return err
+		}
+		err = clctrl.importAdoptedTeams(cl.AdoptedTeams, tfEntrypoint, tfEnvs)
+		if err != nil {
+			return err
+		}
+
+		err = clctrl.runTerraformHooks(terraformHookStagePre, tfEntrypoint)
+		if err != nil {
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.GitTerraformApplyFailed, err.Error())
+			return err
+		}
+
+		err = clctrl.terraformApply(tfEntrypoint, tfEnvs)
 		if err != nil {
-			log.Error().Msgf("error applying git terraform: %s", err)
-			log.Info().Msg("sleeping 10 seconds before retrying terraform execution once more")
+			clctrl.logger().Errorf("error applying git terraform: %s", err)
+			clctrl.logger().Info("sleeping 10 seconds before retrying terraform execution once more")
 			time.Sleep(10 * time.Second)
-			err = terraformext.InitApplyAutoApprove(clctrl.ProviderConfig.TerraformClient, tfEntrypoint, tfEnvs)
+			err = clctrl.terraformApply(tfEntrypoint, tfEnvs)
 			if err != nil {
 				msg := fmt.Sprintf("error creating %s resources with terraform %s: %s", clctrl.GitProvider, tfEntrypoint, err)
-				log.Error().Msg(msg)
-				telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.GitTerraformApplyFailed, err.Error())
+				clctrl.logger().Error(msg)
+				clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.GitTerraformApplyFailed, err.Error())
 				return fmt.Errorf(msg)
 			}
 		}
 
-		log.Info().Msgf("created git projects and groups for %s.com/%s", clctrl.GitProvider, clctrl.GitAuth.Owner)
-		telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.GitTerraformApplyCompleted, "")
+		err = clctrl.runTerraformHooks(terraformHookStagePost, tfEntrypoint)
+		if err != nil {
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.GitTerraformApplyFailed, err.Error())
+			return err
+		}
+
+		clctrl.logger().Infof("created git projects and groups for %s.com/%s", clctrl.GitProvider, clctrl.GitAuth.Owner)
+
+		if clctrl.separateMetaphorOwner() {
+			err = clctrl.createMetaphorRepository(cl.AdoptedRepositories)
+			if err != nil {
+				clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.GitTerraformApplyFailed, err.Error())
+				return err
+			}
+		}
+		clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.GitTerraformApplyCompleted, "")
 
 		clctrl.Cluster.GitTerraformApplyCheck = true
 		err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
@@ -0,0 +1,28 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"github.com/kubefirst/kubefirst-api/internal/github"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+)
+
+// refreshGitToken renews the installation token of a GitHub App before a
+// step that uses the git provider, provisioning can outlast a single token -
+// the cluster record is updated so terraform runs read the new token
+func (clctrl *ClusterController) refreshGitToken() error {
+	changed, err := github.RefreshGitAuth(&clctrl.GitAuth)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+
+	clctrl.Cluster.GitAuth.Token = clctrl.GitAuth.Token
+	return secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
+}
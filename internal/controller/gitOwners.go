@@ -0,0 +1,120 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+	"github.com/kubefirst/kubefirst-api/internal/github"
+	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	log "github.com/rs/zerolog/log"
+)
+
+// metaphorRepository is the name of the application repository kubefirst
+// creates next to the gitops repository
+const metaphorRepository = "metaphor"
+
+// installsMetaphor reports whether the install profile deploys metaphor,
+// without it the metaphor repository isn't created
+func (clctrl *ClusterController) installsMetaphor() bool {
+	return !pkg.FindStringInSlice(providerConfigs.InstallProfiles[clctrl.InstallProfile], metaphorRepository)
+}
+
+// separateMetaphorOwner reports whether the metaphor repository is created
+// under a different owner than the gitops repository
+func (clctrl *ClusterController) separateMetaphorOwner() bool {
+	return clctrl.installsMetaphor() && clctrl.MetaphorOwner != "" && !strings.EqualFold(clctrl.MetaphorOwner, clctrl.GitAuth.Owner)
+}
+
+// ownerRepositories returns the repositories kubefirst creates under the
+// gitops owner
+func (clctrl *ClusterController) ownerRepositories() []string {
+	if !clctrl.separateMetaphorOwner() {
+		return clctrl.Repositories
+	}
+
+	repositories := []string{}
+	for _, repositoryName := range clctrl.Repositories {
+		if repositoryName != metaphorRepository {
+			repositories = append(repositories, repositoryName)
+		}
+	}
+
+	return repositories
+}
+
+// terraformAdoptedRepositories returns the adopted repositories the git
+// terraform manages, which excludes a metaphor repository under its own owner
+func (clctrl *ClusterController) terraformAdoptedRepositories(adopted []string) []string {
+	if !clctrl.separateMetaphorOwner() {
+		return adopted
+	}
+
+	repositories := []string{}
+	for _, repositoryName := range adopted {
+		if repositoryName != metaphorRepository {
+			repositories = append(repositories, repositoryName)
+		}
+	}
+
+	return repositories
+}
+
+// createMetaphorRepository creates the metaphor repository under its
+// separate owner, an adopted repository already exists and is left as is
+func (clctrl *ClusterController) createMetaphorRepository(adopted []string) error {
+	for _, repositoryName := range adopted {
+		if repositoryName == metaphorRepository {
+			return nil
+		}
+	}
+
+	githubSession := github.New(clctrl.GitAuth.Token)
+	return githubSession.CreateEmptyPrivateRepo(clctrl.MetaphorOwner, metaphorRepository, "kubefirst metaphor application")
+}
+
+// removeGithubRepository deletes a github repository, a repository that is
+// already gone isn't an error
+var removeGithubRepository = func(token, owner, name string) error {
+	resp, err := github.New(token).RemoveRepo(owner, name)
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteMetaphorRepository deletes the metaphor repository kubefirst created
+// under its separate owner, which the git terraform destroy doesn't manage,
+// an adopted repository is left in place
+func DeleteMetaphorRepository(cl *pkgtypes.Cluster) error {
+	if cl.GitProvider != "github" || cl.MetaphorOwner == "" || strings.EqualFold(cl.MetaphorOwner, cl.GitAuth.Owner) {
+		return nil
+	}
+	if pkg.FindStringInSlice(providerConfigs.InstallProfiles[cl.InstallProfile], metaphorRepository) {
+		return nil
+	}
+
+	for _, repositoryName := range cl.AdoptedRepositories {
+		if repositoryName == metaphorRepository {
+			log.Info().Msgf("leaving adopted repository %s/%s in place", cl.MetaphorOwner, metaphorRepository)
+			return nil
+		}
+	}
+
+	err := removeGithubRepository(cl.GitAuth.Token, cl.MetaphorOwner, metaphorRepository)
+	if err != nil {
+		return fmt.Errorf("error deleting repository %s/%s: %s", cl.MetaphorOwner, metaphorRepository, err)
+	}
+	log.Info().Msgf("deleted repository %s/%s", cl.MetaphorOwner, metaphorRepository)
+
+	return nil
+}
@@ -0,0 +1,58 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestDeleteMetaphorRepository(t *testing.T) {
+	defer func(original func(string, string, string) error) {
+		removeGithubRepository = original
+	}(removeGithubRepository)
+
+	for name, test := range map[string]struct {
+		cluster     pkgtypes.Cluster
+		wantRemoved []string
+	}{
+		"separate owner": {
+			cluster:     pkgtypes.Cluster{GitProvider: "github", GitAuth: pkgtypes.GitAuth{Owner: "platform"}, MetaphorOwner: "apps"},
+			wantRemoved: []string{"apps/metaphor"},
+		},
+		"same owner": {
+			cluster: pkgtypes.Cluster{GitProvider: "github", GitAuth: pkgtypes.GitAuth{Owner: "platform"}, MetaphorOwner: "Platform"},
+		},
+		"no metaphor owner": {
+			cluster: pkgtypes.Cluster{GitProvider: "github", GitAuth: pkgtypes.GitAuth{Owner: "platform"}},
+		},
+		"install profile without metaphor": {
+			cluster: pkgtypes.Cluster{GitProvider: "github", GitAuth: pkgtypes.GitAuth{Owner: "platform"}, MetaphorOwner: "apps", InstallProfile: "minimal"},
+		},
+		"adopted repository": {
+			cluster: pkgtypes.Cluster{GitProvider: "github", GitAuth: pkgtypes.GitAuth{Owner: "platform"}, MetaphorOwner: "apps", AdoptedRepositories: []string{"metaphor"}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var removed []string
+			removeGithubRepository = func(token, owner, name string) error {
+				removed = append(removed, owner+"/"+name)
+				return nil
+			}
+
+			err := DeleteMetaphorRepository(&test.cluster)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(removed, test.wantRemoved) {
+				t.Errorf("expected %v to be removed, got %v", test.wantRemoved, removed)
+			}
+		})
+	}
+}
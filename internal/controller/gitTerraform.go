@@ -0,0 +1,124 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
+)
+
+// pruneGitTerraform removes what the git terraform of the gitops repository
+// must not create: the metaphor repository when it's created under a
+// separate owner the terraform provider isn't configured for or the install
+// profile leaves metaphor out, and the atlantis webhook when the install
+// profile leaves atlantis out
+func (clctrl *ClusterController) pruneGitTerraform() error {
+	removeMetaphor := clctrl.separateMetaphorOwner() || !clctrl.installsMetaphor()
+	removeAtlantisWebhook := pkg.FindStringInSlice(providerConfigs.InstallProfiles[clctrl.InstallProfile], "atlantis")
+	if !removeMetaphor && !removeAtlantisWebhook {
+		return nil
+	}
+
+	tfDir := filepath.Join(clctrl.ProviderConfig.GitopsDir, "terraform", clctrl.GitProvider)
+	files, err := filepath.Glob(filepath.Join(tfDir, "*.tf"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		updated, removed, err := removeTerraformBlocks(content, file, func(block *hclwrite.Block) bool {
+			return (removeMetaphor && isMetaphorBlock(block)) || (removeAtlantisWebhook && isAtlantisWebhookBlock(block))
+		})
+		if err != nil {
+			return err
+		}
+		if len(removed) == 0 {
+			continue
+		}
+
+		clctrl.logger().Infof("removing %v from git terraform %s", removed, file)
+		err = os.WriteFile(file, updated, 0644)
+		if err != nil {
+			return fmt.Errorf("error writing git terraform %s: %s", file, err)
+		}
+	}
+
+	return nil
+}
+
+// isMetaphorBlock reports whether block is the metaphor repository module or
+// references it
+func isMetaphorBlock(block *hclwrite.Block) bool {
+	labels := block.Labels()
+	if block.Type() == "module" && len(labels) == 1 && labels[0] == metaphorRepository {
+		return true
+	}
+
+	return referencesTraversal(block.Body().BuildTokens(nil), "module", metaphorRepository)
+}
+
+// isAtlantisWebhookBlock reports whether block is a resource pointing a git
+// webhook at atlantis
+func isAtlantisWebhookBlock(block *hclwrite.Block) bool {
+	return block.Type() == "resource" && referencesTraversal(block.Body().BuildTokens(nil), "var", "atlantis_repo_webhook_url")
+}
+
+// removeTerraformBlocks removes the top level blocks of a terraform file
+// remove matches and returns the updated file with the addresses of the
+// removed blocks
+func removeTerraformBlocks(content []byte, filename string, remove func(*hclwrite.Block) bool) ([]byte, []string, error) {
+	f, diags := hclwrite.ParseConfig(content, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, nil, fmt.Errorf("error parsing git terraform %s: %s", filename, diags)
+	}
+
+	removed := []string{}
+	for _, block := range f.Body().Blocks() {
+		if remove(block) {
+			f.Body().RemoveBlock(block)
+			removed = append(removed, blockAddress(block))
+		}
+	}
+
+	return f.Bytes(), removed, nil
+}
+
+// blockAddress returns the type and labels of block, e.g. module.metaphor
+func blockAddress(block *hclwrite.Block) string {
+	address := block.Type()
+	for _, label := range block.Labels() {
+		address = fmt.Sprintf("%s.%s", address, label)
+	}
+
+	return address
+}
+
+// referencesTraversal reports whether the tokens contain a root.name
+// traversal, e.g. module.metaphor
+func referencesTraversal(tokens hclwrite.Tokens, root string, name string) bool {
+	for i := 0; i+2 < len(tokens); i++ {
+		if tokens[i].Type == hclsyntax.TokenIdent && string(tokens[i].Bytes) == root &&
+			tokens[i+1].Type == hclsyntax.TokenDot &&
+			tokens[i+2].Type == hclsyntax.TokenIdent && string(tokens[i+2].Bytes) == name {
+			return true
+		}
+	}
+
+	return false
+}
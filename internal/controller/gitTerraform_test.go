@@ -0,0 +1,125 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// gitTerraform is a git terraform with braces in strings and heredocs
+const gitTerraform = `module "gitops" {
+  source      = "./modules/repository"
+  description = "braces } in { a string"
+}
+
+module "metaphor" {
+  source = "./modules/repository"
+  readme = <<EOT
+an unbalanced { brace
+EOT
+}
+
+resource "github_branch_protection" "metaphor" {
+  repository_id = module.metaphor.repo_name
+  pattern       = "main"
+}
+
+output "metaphor_url" {
+  value = "${module.metaphor.repo_url}/{}"
+}
+
+resource "github_repository_webhook" "gitops_atlantis_webhook" {
+  repository = module.gitops.repo_name
+  configuration {
+    url    = var.atlantis_repo_webhook_url
+    secret = var.atlantis_repo_webhook_secret
+  }
+}
+
+resource "github_team" "developers" {
+  name        = "developers"
+  description = <<-EOT
+    not module.metaphor { or var.atlantis_repo_webhook_url }
+  EOT
+}
+`
+
+func TestPruneGitTerraform(t *testing.T) {
+	metaphorBlocks := []string{`module "metaphor"`, `"github_branch_protection"`, `output "metaphor_url"`}
+	webhookBlocks := []string{`"github_repository_webhook"`}
+	alwaysKept := []string{`module "gitops"`, `"braces } in { a string"`, `resource "github_team" "developers"`, "not module.metaphor { or var.atlantis_repo_webhook_url }"}
+
+	for name, test := range map[string]struct {
+		metaphorOwner  string
+		installProfile string
+		wantRemoved    []string
+		wantKept       []string
+	}{
+		"full profile": {
+			wantKept: append(append([]string{}, metaphorBlocks...), webhookBlocks...),
+		},
+		"separate metaphor owner": {
+			metaphorOwner: "apps",
+			wantRemoved:   metaphorBlocks,
+			wantKept:      webhookBlocks,
+		},
+		"standard profile": {
+			installProfile: "standard",
+			wantRemoved:    metaphorBlocks,
+			wantKept:       webhookBlocks,
+		},
+		"minimal profile": {
+			metaphorOwner:  "apps",
+			installProfile: "minimal",
+			wantRemoved:    append(append([]string{}, metaphorBlocks...), webhookBlocks...),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			gitopsDir := t.TempDir()
+			tfDir := filepath.Join(gitopsDir, "terraform", "github")
+			os.MkdirAll(tfDir, 0o755)
+			tfFile := filepath.Join(tfDir, "main.tf")
+			err := os.WriteFile(tfFile, []byte(gitTerraform), 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			clctrl := &ClusterController{
+				GitProvider:    "github",
+				GitAuth:        pkgtypes.GitAuth{Owner: "platform"},
+				MetaphorOwner:  test.metaphorOwner,
+				InstallProfile: test.installProfile,
+			}
+			clctrl.ProviderConfig.GitopsDir = gitopsDir
+
+			err = clctrl.pruneGitTerraform()
+			if err != nil {
+				t.Fatalf("pruneGitTerraform() = %s", err)
+			}
+
+			updated, err := os.ReadFile(tfFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, gone := range test.wantRemoved {
+				if strings.Contains(string(updated), gone) {
+					t.Errorf("expected %s to be removed, got:\n%s", gone, updated)
+				}
+			}
+			for _, kept := range append(test.wantKept, alwaysKept...) {
+				if !strings.Contains(string(updated), kept) {
+					t.Errorf("expected %s to be kept, got:\n%s", kept, updated)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+	"github.com/kubefirst/kubefirst-api/internal/github"
+	"github.com/kubefirst/kubefirst-api/internal/gitlab"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// atlantisGithubEvents are the github events atlantis plans and applies on
+var atlantisGithubEvents = []string{"issue_comment", "pull_request", "pull_request_review", "push"}
+
+// isAtlantisWebhookURL reports whether hookURL is an atlantis events url,
+// which finds the hook of a cluster whose domain changed
+func isAtlantisWebhookURL(hookURL string) bool {
+	parsed, err := url.Parse(hookURL)
+	if err != nil {
+		return false
+	}
+
+	return strings.HasPrefix(parsed.Host, "atlantis.") && parsed.Path == "/events"
+}
+
+// VerifyGitWebhook checks the atlantis webhook of the cluster's gitops
+// repository exists with the cluster's atlantis url, the events atlantis
+// needs and a secret, and recreates or repairs it when it doesn't. The
+// secret itself can't be read back from the git provider, a repaired hook
+// always gets the cluster's secret
+func (clctrl *ClusterController) VerifyGitWebhook(clusterName string) (pkgtypes.GitWebhookVerification, error) {
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clusterName)
+	if err != nil {
+		return pkgtypes.GitWebhookVerification{}, err
+	}
+	if cl.AtlantisWebhookURL == "" || cl.AtlantisWebhookSecret == "" {
+		return pkgtypes.GitWebhookVerification{}, fmt.Errorf("cluster %s has no atlantis webhook", clusterName)
+	}
+
+	// GitHub App installation tokens expire, the stored one may be stale
+	_, err = github.RefreshGitAuth(&cl.GitAuth)
+	if err != nil {
+		return pkgtypes.GitWebhookVerification{}, err
+	}
+
+	result := pkgtypes.GitWebhookVerification{
+		ClusterName: clusterName,
+		Repository:  fmt.Sprintf("%s/gitops", cl.GitAuth.Owner),
+		URL:         cl.AtlantisWebhookURL,
+	}
+
+	var repair func() error
+	switch cl.GitProvider {
+	case "github":
+		githubSession := github.New(cl.GitAuth.Token)
+		hooks, err := githubSession.ListRepoWebhooks(cl.GitAuth.Owner, "gitops")
+		if err != nil {
+			return result, fmt.Errorf("error listing webhooks of %s: %s", result.Repository, err)
+		}
+
+		// the hook with the cluster's url, or one left on an old atlantis url
+		var hookID int64
+		hookURL := ""
+		for _, hook := range hooks {
+			candidateURL, _ := hook.Config["url"].(string)
+			if candidateURL == cl.AtlantisWebhookURL || (hookID == 0 && isAtlantisWebhookURL(candidateURL)) {
+				hookID = hook.GetID()
+				hookURL = candidateURL
+				if !hook.GetActive() {
+					result.Problems = []string{"webhook is inactive"}
+				} else {
+					result.Problems = []string{}
+				}
+				for _, event := range atlantisGithubEvents {
+					if !pkg.FindStringInSlice(hook.Events, event) && !pkg.FindStringInSlice(hook.Events, "*") {
+						result.Problems = append(result.Problems, fmt.Sprintf("webhook is missing the %s event", event))
+					}
+				}
+				if contentType, _ := hook.Config["content_type"].(string); contentType != "json" {
+					result.Problems = append(result.Problems, fmt.Sprintf("webhook content type is %s, not json", contentType))
+				}
+				if _, ok := hook.Config["secret"]; !ok {
+					result.Problems = append(result.Problems, "webhook has no secret")
+				}
+				if candidateURL == cl.AtlantisWebhookURL {
+					break
+				}
+			}
+		}
+		if hookID != 0 && hookURL != cl.AtlantisWebhookURL {
+			result.Problems = append(result.Problems, fmt.Sprintf("webhook points at %s", hookURL))
+		}
+		if hookID == 0 {
+			result.Problems = append(result.Problems, "webhook is missing")
+			repair = func() error {
+				return githubSession.CreateWebhookRepo(cl.GitAuth.Owner, "gitops", "web", cl.AtlantisWebhookURL, cl.AtlantisWebhookSecret, atlantisGithubEvents)
+			}
+		} else {
+			repair = func() error {
+				return githubSession.EditRepositoryWebhook(cl.GitAuth.Owner, "gitops", hookID, cl.AtlantisWebhookURL, cl.AtlantisWebhookSecret, atlantisGithubEvents)
+			}
+		}
+	case "gitlab":
+		gitlabClient, err := gitlab.NewGitLabClient(cl.GitAuth.Token, cl.GitAuth.Owner)
+		if err != nil {
+			return result, err
+		}
+		result.Repository = fmt.Sprintf("%s/gitops", gitlabClient.ParentGroupPath)
+		projectID, err := gitlabClient.GetProjectID("gitops")
+		if err != nil {
+			return result, err
+		}
+		hooks, err := gitlabClient.ListProjectWebhooks(projectID)
+		if err != nil {
+			return result, fmt.Errorf("error listing webhooks of %s: %s", result.Repository, err)
+		}
+
+		// the hook with the cluster's url, or one left on an old atlantis url
+		hookID := 0
+		hookURL := ""
+		for _, hook := range hooks {
+			if hook.URL == cl.AtlantisWebhookURL || (hookID == 0 && isAtlantisWebhookURL(hook.URL)) {
+				hookID = hook.ID
+				hookURL = hook.URL
+				result.Problems = []string{}
+				if !hook.PushEvents {
+					result.Problems = append(result.Problems, "webhook is missing push events")
+				}
+				if !hook.MergeRequestsEvents {
+					result.Problems = append(result.Problems, "webhook is missing merge request events")
+				}
+				if !hook.NoteEvents {
+					result.Problems = append(result.Problems, "webhook is missing comment events")
+				}
+				if hook.URL == cl.AtlantisWebhookURL {
+					break
+				}
+			}
+		}
+		if hookID != 0 && hookURL != cl.AtlantisWebhookURL {
+			result.Problems = append(result.Problems, fmt.Sprintf("webhook points at %s", hookURL))
+		}
+		if hookID == 0 {
+			result.Problems = append(result.Problems, "webhook is missing")
+			repair = func() error {
+				return gitlabClient.AddProjectWebhook("gitops", cl.AtlantisWebhookURL, cl.AtlantisWebhookSecret)
+			}
+		} else {
+			repair = func() error {
+				return gitlabClient.EditProjectWebhook("gitops", hookID, cl.AtlantisWebhookURL, cl.AtlantisWebhookSecret)
+			}
+		}
+	default:
+		return result, fmt.Errorf("git provider %s is not supported", cl.GitProvider)
+	}
+
+	if len(result.Problems) == 0 {
+		clctrl.logger().Infof("the atlantis webhook of %s is healthy", result.Repository)
+		return result, nil
+	}
+	clctrl.logger().Warnf("the atlantis webhook of %s needs repair: %s", result.Repository, strings.Join(result.Problems, ", "))
+
+	err = CheckWritable()
+	if err != nil {
+		return result, err
+	}
+
+	err = repair()
+	if err != nil {
+		return result, fmt.Errorf("error repairing the atlantis webhook of %s: %s", result.Repository, err)
+	}
+	result.Repaired = true
+	clctrl.logger().Infof("repaired the atlantis webhook of %s", result.Repository)
+
+	return result, nil
+}
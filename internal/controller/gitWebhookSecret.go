@@ -0,0 +1,299 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+	"github.com/kubefirst/kubefirst-api/internal/github"
+	"github.com/kubefirst/kubefirst-api/internal/gitlab"
+	"github.com/kubefirst/kubefirst-api/internal/k8s"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+const (
+	// minWebhookSecretLength is the shortest webhook secret accepted
+	minWebhookSecretLength = 16
+
+	// atlantisSecretName is the kubernetes secret external secrets syncs the
+	// atlantis secret in vault to
+	atlantisSecretName = "atlantis-secrets"
+
+	// atlantisRestartTimeout bounds the rollout of atlantis with a new secret
+	atlantisRestartTimeout = 300
+)
+
+var (
+	// atlantisWebhookSecretKeys are the keys of the atlantis secret holding
+	// the webhook secret, the atlantis server reads the one for its git
+	// provider and the gitops terraform the TF_VAR_ one
+	atlantisWebhookSecretKeys = []string{"ATLANTIS_GH_WEBHOOK_SECRET", "ATLANTIS_GITLAB_WEBHOOK_SECRET", "TF_VAR_atlantis_repo_webhook_secret"}
+
+	// argoCDWebhookSecretKeys are the keys of argocd-secret argo cd checks
+	// git provider webhooks against, when its webhook is set up
+	argoCDWebhookSecretKeys = []string{"webhook.github.secret", "webhook.gitlab.secret"}
+)
+
+// validateWebhookSecret makes sure a webhook secret chosen in the definition
+// is long enough to sign with and survives being passed through env vars
+func validateWebhookSecret(secret string) error {
+	if secret == "" {
+		return nil
+	}
+	if len(secret) < minWebhookSecretLength {
+		return fmt.Errorf("atlantis_webhook_secret must be at least %d characters", minWebhookSecretLength)
+	}
+	if strings.ContainsAny(secret, " \t\r\n") {
+		return fmt.Errorf("atlantis_webhook_secret must not contain whitespace")
+	}
+
+	return nil
+}
+
+// RotateGitWebhookSecret replaces the secret the gitops repository's atlantis
+// webhook is signed with by secret, or a generated one when it's empty. The
+// atlantis secret in vault and its synced copy, argo cd's webhook secret when
+// it has one and the cluster record get the new secret first, then atlantis
+// is restarted with it and the hook at the git provider is updated as soon as
+// atlantis is ready, so deliveries are only rejected for the moment between
+// the two. When atlantis doesn't come back or the hook can't be updated
+// everything is put back to the old secret
+func (clctrl *ClusterController) RotateGitWebhookSecret(clusterName string, secret string) error {
+	err := CheckWritable()
+	if err != nil {
+		return err
+	}
+
+	// vault and atlantis are reached through the controller's kubeconfig
+	if clusterName != clctrl.ClusterName {
+		return fmt.Errorf("controller is initialized for cluster %s, not %s", clctrl.ClusterName, clusterName)
+	}
+
+	err = clctrl.VerifyConnectivity()
+	if err != nil {
+		return err
+	}
+
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clusterName)
+	if err != nil {
+		return err
+	}
+	if cl.AtlantisWebhookURL == "" || cl.AtlantisWebhookSecret == "" {
+		return fmt.Errorf("cluster %s has no atlantis webhook", clusterName)
+	}
+
+	if secret == "" {
+		secret = pkg.Random(20)
+	}
+	err = validateWebhookSecret(secret)
+	if err != nil {
+		return err
+	}
+	if secret == cl.AtlantisWebhookSecret {
+		return fmt.Errorf("the new webhook secret is the current one")
+	}
+
+	// GitHub App installation tokens expire, the stored one may be stale
+	_, err = github.RefreshGitAuth(&cl.GitAuth)
+	if err != nil {
+		return err
+	}
+
+	// the hook is looked up before anything changes so a missing one fails
+	// the rotation up front
+	updateHook, err := atlantisWebhookUpdater(cl)
+	if err != nil {
+		return err
+	}
+
+	oldSecret := cl.AtlantisWebhookSecret
+	rollback := func(cause error) error {
+		clctrl.logger().Warnf("rolling back the webhook secret rotation of cluster %s: %s", clusterName, cause)
+		if err := clctrl.applyWebhookSecret(cl, oldSecret); err != nil {
+			return fmt.Errorf("%s, and the old webhook secret could not be restored: %s", cause, err)
+		}
+		if err := clctrl.saveWebhookSecret(clusterName, oldSecret, cl.AtlantisWebhookSecretRotatedAt); err != nil {
+			return fmt.Errorf("%s, and the old webhook secret could not be restored: %s", cause, err)
+		}
+		if err := clctrl.restartAtlantis(); err != nil {
+			return fmt.Errorf("%s, and atlantis could not be restarted with the old webhook secret: %s", cause, err)
+		}
+		return fmt.Errorf("%s, the old webhook secret is kept", cause)
+	}
+
+	err = clctrl.applyWebhookSecret(cl, secret)
+	if err != nil {
+		return rollback(fmt.Errorf("error updating the in-cluster webhook secret: %s", err))
+	}
+	err = clctrl.saveWebhookSecret(clusterName, secret, time.Now().UTC())
+	if err != nil {
+		return rollback(err)
+	}
+
+	err = clctrl.restartAtlantis()
+	if err != nil {
+		return rollback(err)
+	}
+
+	err = updateHook(secret)
+	if err != nil {
+		return rollback(fmt.Errorf("error updating the atlantis webhook at %s: %s", cl.GitProvider, err))
+	}
+
+	clctrl.logger().Infof("rotated the atlantis webhook secret of cluster %s", clusterName)
+
+	return nil
+}
+
+// atlantisWebhookUpdater finds the atlantis webhook of the cluster's gitops
+// repository and returns a function setting its secret
+func atlantisWebhookUpdater(cl pkgtypes.Cluster) (func(secret string) error, error) {
+	switch cl.GitProvider {
+	case "github":
+		githubSession := github.New(cl.GitAuth.Token)
+		hooks, err := githubSession.ListRepoWebhooks(cl.GitAuth.Owner, "gitops")
+		if err != nil {
+			return nil, fmt.Errorf("error listing webhooks of %s/gitops: %s", cl.GitAuth.Owner, err)
+		}
+		for _, hook := range hooks {
+			if hookURL, _ := hook.Config["url"].(string); hookURL == cl.AtlantisWebhookURL {
+				hookID := hook.GetID()
+				return func(secret string) error {
+					return githubSession.EditRepositoryWebhook(cl.GitAuth.Owner, "gitops", hookID, cl.AtlantisWebhookURL, secret, atlantisGithubEvents)
+				}, nil
+			}
+		}
+	case "gitlab":
+		gitlabClient, err := gitlab.NewGitLabClient(cl.GitAuth.Token, cl.GitAuth.Owner)
+		if err != nil {
+			return nil, err
+		}
+		projectID, err := gitlabClient.GetProjectID("gitops")
+		if err != nil {
+			return nil, err
+		}
+		hooks, err := gitlabClient.ListProjectWebhooks(projectID)
+		if err != nil {
+			return nil, fmt.Errorf("error listing webhooks of %s/gitops: %s", gitlabClient.ParentGroupPath, err)
+		}
+		for _, hook := range hooks {
+			if hook.URL == cl.AtlantisWebhookURL {
+				hookID := hook.ID
+				return func(secret string) error {
+					return gitlabClient.EditProjectWebhook("gitops", hookID, cl.AtlantisWebhookURL, secret)
+				}, nil
+			}
+		}
+	default:
+		return nil, fmt.Errorf("git provider %s is not supported", cl.GitProvider)
+	}
+
+	return nil, fmt.Errorf("the gitops repository has no webhook for %s, repair it with VerifyGitWebhook first", cl.AtlantisWebhookURL)
+}
+
+// applyWebhookSecret writes secret to the atlantis secret in vault, to its
+// kubernetes copy so atlantis doesn't wait for external secrets to sync it,
+// and to argocd-secret when argo cd checks webhooks
+func (clctrl *ClusterController) applyWebhookSecret(cl pkgtypes.Cluster, secret string) error {
+	vaultClient, err := clctrl.clusterVaultClient(cl)
+	if err != nil {
+		return err
+	}
+
+	atlantisSecret, err := vaultClient.KVv2("secret").Get(context.Background(), "atlantis")
+	if err != nil {
+		return fmt.Errorf("error reading the atlantis secret: %s", err)
+	}
+	data := atlantisSecret.Data
+	updated := false
+	for _, key := range atlantisWebhookSecretKeys {
+		if _, ok := data[key]; ok {
+			data[key] = secret
+			updated = true
+		}
+	}
+	if !updated {
+		return fmt.Errorf("the atlantis secret in vault holds no webhook secret")
+	}
+	_, err = vaultClient.KVv2("secret").Put(context.Background(), "atlantis", data)
+	if err != nil {
+		return fmt.Errorf("error writing the atlantis secret: %s", err)
+	}
+
+	kcfg, err := clctrl.GetClusterKubernetesClient()
+	if err != nil {
+		return err
+	}
+	for _, target := range []struct {
+		namespace string
+		name      string
+		keys      []string
+	}{
+		{namespace: pkg.AtlantisNamespace, name: atlantisSecretName, keys: atlantisWebhookSecretKeys},
+		{namespace: "argocd", name: "argocd-secret", keys: argoCDWebhookSecretKeys},
+	} {
+		current, err := k8s.ReadSecretV2(kcfg.Clientset, target.namespace, target.name)
+		if err != nil {
+			clctrl.logger().Warnf("skipping secret %s/%s: %s", target.namespace, target.name, err)
+			continue
+		}
+
+		values := map[string][]byte{}
+		updated := false
+		for key, value := range current {
+			values[key] = []byte(value)
+			if pkg.FindStringInSlice(target.keys, key) {
+				values[key] = []byte(secret)
+				updated = true
+			}
+		}
+		if !updated {
+			continue
+		}
+		err = k8s.UpdateSecretV2(kcfg.Clientset, target.namespace, target.name, values)
+		if err != nil {
+			return fmt.Errorf("error updating secret %s/%s: %s", target.namespace, target.name, err)
+		}
+	}
+
+	return nil
+}
+
+// saveWebhookSecret records the webhook secret on the cluster, the terraform
+// of later runs reads it from there
+func (clctrl *ClusterController) saveWebhookSecret(clusterName string, secret string, rotatedAt time.Time) error {
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clusterName)
+	if err != nil {
+		return err
+	}
+	cl.AtlantisWebhookSecret = secret
+	cl.AtlantisWebhookSecretRotatedAt = rotatedAt
+
+	err = secrets.UpdateCluster(clctrl.KubernetesClient, cl)
+	if err != nil {
+		return fmt.Errorf("error saving the webhook secret: %s", err)
+	}
+	clctrl.Cluster = cl
+	clctrl.AtlantisWebhookSecret = secret
+
+	return nil
+}
+
+// restartAtlantis restarts atlantis so it reads the webhook secret again
+func (clctrl *ClusterController) restartAtlantis() error {
+	kcfg, err := clctrl.GetClusterKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	return k8s.RestartStatefulSet(kcfg.Clientset, pkg.AtlantisNamespace, "atlantis", atlantisRestartTimeout)
+}
@@ -0,0 +1,24 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import "testing"
+
+func TestValidateWebhookSecret(t *testing.T) {
+	for secret, valid := range map[string]bool{
+		"":                          true,
+		"0123456789abcdef":          true,
+		"short":                     false,
+		"0123456789 abcdef":         false,
+		"0123456789abcdef\n":        false,
+		"a-much-longer-secret-123!": true,
+	} {
+		if err := validateWebhookSecret(secret); (err == nil) != valid {
+			t.Errorf("validateWebhookSecret(%q) = %v, want valid %v", secret, err, valid)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// ValidateClusterGroup makes sure the regional clusters of a definition with
+// Regions don't share anything that has to be unique per cluster. Each region
+// gets its own subdomain from ClusterGroupDefinitions, but the gitops and
+// metaphor repositories are named the same for every cluster so each region
+// needs its own git owner, and fields naming a single host or cloud resource
+// can't be shared between regions
+func ValidateClusterGroup(def pkgtypes.ClusterDefinition) error {
+	if len(def.Regions) == 0 {
+		if len(def.RegionGitOwners) != 0 {
+			return fmt.Errorf("region_git_owners requires regions")
+		}
+		return nil
+	}
+
+	if def.GitAuth.AppID != 0 {
+		return fmt.Errorf("regions are not supported with github app authentication, the app installation belongs to a single owner")
+	}
+
+	for field, set := range map[string]bool{
+		"argocd_host":                 def.ArgoCDHost != "",
+		"egress_gateway":              def.EgressGateway.EgressIP != "" || def.EgressGateway.NatGatewayID != "",
+		"existing_network":            def.ExistingNetwork.NetworkID != "",
+		"existing_state_store_bucket": def.ExistingStateStoreBucket != "",
+	} {
+		if set {
+			return fmt.Errorf("%s can't be shared by the clusters of regions", field)
+		}
+	}
+
+	regions := map[string]bool{}
+	owners := map[string]string{}
+	for _, region := range def.Regions {
+		if regions[region] {
+			return fmt.Errorf("region %s is listed more than once", region)
+		}
+		regions[region] = true
+
+		owner := def.RegionGitOwners[region]
+		if owner == "" {
+			return fmt.Errorf("region_git_owners has no git owner for region %s", region)
+		}
+		if other, ok := owners[owner]; ok {
+			return fmt.Errorf("regions %s and %s have the same git owner %s, their repositories would collide", other, region, owner)
+		}
+		owners[owner] = region
+	}
+	for region := range def.RegionGitOwners {
+		if !regions[region] {
+			return fmt.Errorf("region_git_owners names region %s, which is not in regions", region)
+		}
+	}
+
+	return nil
+}
+
+// ClusterGroupDefinitions expands a cluster definition into one definition per
+// region when Regions is set, linking each of them to a cluster group named
+// after the original cluster. Each regional cluster is served from its own
+// subdomain, the region appended to the definition's subdomain, and creates
+// its repositories in the region's git owner
+//
+// A definition without Regions is returned unchanged
+func ClusterGroupDefinitions(def pkgtypes.ClusterDefinition) []pkgtypes.ClusterDefinition {
+	if len(def.Regions) == 0 {
+		return []pkgtypes.ClusterDefinition{def}
+	}
+
+	definitions := make([]pkgtypes.ClusterDefinition, 0, len(def.Regions))
+	for _, region := range def.Regions {
+		regionDef := def
+		regionDef.Regions = nil
+		regionDef.RegionGitOwners = nil
+		regionDef.CloudRegion = region
+		regionDef.ClusterName = fmt.Sprintf("%s-%s", def.ClusterName, region)
+		regionDef.ClusterGroup = def.ClusterName
+		regionDef.SubdomainName = region
+		if def.SubdomainName != "" {
+			regionDef.SubdomainName = fmt.Sprintf("%s-%s", def.SubdomainName, region)
+		}
+		regionDef.GitAuth.Owner = def.RegionGitOwners[region]
+		definitions = append(definitions, regionDef)
+	}
+
+	return definitions
+}
@@ -0,0 +1,84 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"testing"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestValidateClusterGroup(t *testing.T) {
+	owners := map[string]string{"nyc1": "acme-nyc", "fra1": "acme-fra"}
+	for name, test := range map[string]struct {
+		def   pkgtypes.ClusterDefinition
+		valid bool
+	}{
+		"single cluster": {
+			def:   pkgtypes.ClusterDefinition{ClusterName: "prod"},
+			valid: true,
+		},
+		"an owner per region": {
+			def:   pkgtypes.ClusterDefinition{ClusterName: "prod", Regions: []string{"nyc1", "fra1"}, RegionGitOwners: owners},
+			valid: true,
+		},
+		"missing owner": {
+			def: pkgtypes.ClusterDefinition{ClusterName: "prod", Regions: []string{"nyc1", "fra1", "sgp1"}, RegionGitOwners: owners},
+		},
+		"shared owner": {
+			def: pkgtypes.ClusterDefinition{ClusterName: "prod", Regions: []string{"nyc1", "fra1"}, RegionGitOwners: map[string]string{"nyc1": "acme", "fra1": "acme"}},
+		},
+		"duplicate region": {
+			def: pkgtypes.ClusterDefinition{ClusterName: "prod", Regions: []string{"nyc1", "nyc1"}, RegionGitOwners: owners},
+		},
+		"owner for an unlisted region": {
+			def: pkgtypes.ClusterDefinition{ClusterName: "prod", Regions: []string{"nyc1"}, RegionGitOwners: owners},
+		},
+		"owners without regions": {
+			def: pkgtypes.ClusterDefinition{ClusterName: "prod", RegionGitOwners: owners},
+		},
+		"shared argocd host": {
+			def: pkgtypes.ClusterDefinition{ClusterName: "prod", Regions: []string{"nyc1", "fra1"}, RegionGitOwners: owners, ArgoCDHost: "cd.example.com"},
+		},
+		"github app": {
+			def: pkgtypes.ClusterDefinition{ClusterName: "prod", Regions: []string{"nyc1", "fra1"}, RegionGitOwners: owners, GitAuth: pkgtypes.GitAuth{AppID: 1}},
+		},
+	} {
+		if err := ValidateClusterGroup(test.def); (err == nil) != test.valid {
+			t.Errorf("%s: ValidateClusterGroup() = %v, want valid %v", name, err, test.valid)
+		}
+	}
+}
+
+func TestClusterGroupDefinitions(t *testing.T) {
+	definitions := ClusterGroupDefinitions(pkgtypes.ClusterDefinition{
+		ClusterName:     "prod",
+		SubdomainName:   "platform",
+		Regions:         []string{"nyc1", "fra1"},
+		RegionGitOwners: map[string]string{"nyc1": "acme-nyc", "fra1": "acme-fra"},
+		GitAuth:         pkgtypes.GitAuth{Owner: "acme"},
+	})
+	if len(definitions) != 2 {
+		t.Fatalf("got %d definitions, want 2", len(definitions))
+	}
+
+	seen := map[string]bool{}
+	for _, def := range definitions {
+		for _, name := range []string{def.ClusterName, def.SubdomainName, def.GitAuth.Owner} {
+			if seen[name] {
+				t.Errorf("%s is shared between regional clusters", name)
+			}
+			seen[name] = true
+		}
+		if def.ClusterGroup != "prod" || len(def.Regions) != 0 || def.RegionGitOwners != nil {
+			t.Errorf("regional definition %s = %+v, want it linked to group prod without regions", def.ClusterName, def)
+		}
+	}
+	if definitions[1].ClusterName != "prod-fra1" || definitions[1].SubdomainName != "platform-fra1" || definitions[1].GitAuth.Owner != "acme-fra" {
+		t.Errorf("fra1 definition = %s %s %s, want prod-fra1 platform-fra1 acme-fra", definitions[1].ClusterName, definitions[1].SubdomainName, definitions[1].GitAuth.Owner)
+	}
+}
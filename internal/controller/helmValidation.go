@@ -0,0 +1,212 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HelmTemplateValidator renders the helm charts of the registry components
+// with the helm binary at Path, so bad chart values fail before the push
+// instead of the argocd sync. Both the charts argocd Applications install
+// from a chart repository, with their detokenized values, and the charts
+// stored in the repository itself are rendered
+type HelmTemplateValidator struct {
+	Path string
+}
+
+// helmChart is a chart to render with helm template
+type helmChart struct {
+	// source is where the chart is referenced, for the error
+	source string
+	args   []string
+	values string
+}
+
+func (v HelmTemplateValidator) ValidateManifests(dir string) error {
+	charts, err := helmCharts(dir)
+	if err != nil {
+		return err
+	}
+
+	problems := []string{}
+	for _, chart := range charts {
+		err := v.render(chart)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", chart.source, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func (v HelmTemplateValidator) render(chart helmChart) error {
+	args := append([]string{"template"}, chart.args...)
+	if chart.values != "" {
+		valuesFile, err := os.CreateTemp("", "values-*.yaml")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(valuesFile.Name())
+
+		_, err = valuesFile.WriteString(chart.values)
+		valuesFile.Close()
+		if err != nil {
+			return err
+		}
+		args = append(args, "--values", valuesFile.Name())
+	}
+
+	output, err := exec.Command(v.Path, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("helm template failed: %s", strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// helmCharts returns the charts installed by the argocd Applications of the
+// repository at dir and the charts stored in it
+func helmCharts(dir string) ([]helmChart, error) {
+	files, err := manifestFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	charts := []helmChart{}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		relative, _ := filepath.Rel(dir, file)
+
+		// unparseable files are reported by the manifest validator
+		manifests, _ := decodeManifests(content)
+		for _, manifest := range manifests {
+			charts = append(charts, applicationCharts(relative, manifest)...)
+		}
+	}
+
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".git" || d.Name() == "terraform" {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, "Chart.yaml")); err != nil {
+			return nil
+		}
+
+		relative, _ := filepath.Rel(dir, path)
+		charts = append(charts, helmChart{
+			source: fmt.Sprintf("chart %s", relative),
+			args:   []string{filepath.Base(path), path, "--dependency-update"},
+		})
+		return filepath.SkipDir
+	})
+
+	return charts, err
+}
+
+// applicationCharts returns the charts an argocd Application installs from
+// chart repositories
+func applicationCharts(file string, manifest interface{}) []helmChart {
+	app, _ := manifest.(map[string]interface{})
+	apiVersion, _ := app["apiVersion"].(string)
+	if app["kind"] != "Application" || !strings.HasPrefix(apiVersion, "argoproj.io/") {
+		return nil
+	}
+
+	metadata, _ := app["metadata"].(map[string]interface{})
+	appName, _ := metadata["name"].(string)
+	spec, _ := app["spec"].(map[string]interface{})
+	destination, _ := spec["destination"].(map[string]interface{})
+	namespace, _ := destination["namespace"].(string)
+
+	sources := []interface{}{}
+	if source, ok := spec["source"]; ok {
+		sources = append(sources, source)
+	}
+	if multiple, ok := spec["sources"].([]interface{}); ok {
+		sources = append(sources, multiple...)
+	}
+
+	charts := []helmChart{}
+	for _, s := range sources {
+		source, _ := s.(map[string]interface{})
+		chartName, _ := source["chart"].(string)
+		repoURL, _ := source["repoURL"].(string)
+		if chartName == "" || repoURL == "" {
+			continue
+		}
+		version, _ := source["targetRevision"].(string)
+		helm, _ := source["helm"].(map[string]interface{})
+
+		releaseName, _ := helm["releaseName"].(string)
+		if releaseName == "" {
+			releaseName = appName
+		}
+
+		chart := helmChart{
+			source: fmt.Sprintf("chart %s of application %s (%s)", chartName, appName, file),
+			args:   []string{releaseName},
+		}
+		if strings.Contains(repoURL, "://") {
+			chart.args = append(chart.args, chartName, "--repo", repoURL)
+		} else {
+			// argocd takes oci registries without a scheme
+			chart.args = append(chart.args, fmt.Sprintf("oci://%s/%s", strings.TrimSuffix(repoURL, "/"), chartName))
+		}
+		if version != "" && version != "*" {
+			chart.args = append(chart.args, "--version", version)
+		}
+		if namespace != "" {
+			chart.args = append(chart.args, "--namespace", namespace)
+		}
+
+		chart.values, _ = helm["values"].(string)
+		if valuesObject, ok := helm["valuesObject"]; ok && chart.values == "" {
+			values, err := yaml.Marshal(valuesObject)
+			if err == nil {
+				chart.values = string(values)
+			}
+		}
+		parameters, _ := helm["parameters"].([]interface{})
+		for _, p := range parameters {
+			parameter, _ := p.(map[string]interface{})
+			name, _ := parameter["name"].(string)
+			// an unset value would be rendered as <nil>
+			if name == "" || parameter["value"] == nil {
+				continue
+			}
+			flag := "--set"
+			if forceString, _ := parameter["forceString"].(bool); forceString {
+				flag = "--set-string"
+			}
+			chart.args = append(chart.args, flag, fmt.Sprintf("%s=%v", name, parameter["value"]))
+		}
+
+		charts = append(charts, chart)
+	}
+
+	return charts
+}
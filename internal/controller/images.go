@@ -0,0 +1,36 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
+)
+
+// validateImageOverrides makes sure image overrides only reference known
+// platform components and are in repository:tag form
+func validateImageOverrides(overrides map[string]string) error {
+	for component, image := range overrides {
+		if _, ok := providerConfigs.ImageOverrideComponents[component]; !ok {
+			components := make([]string, 0, len(providerConfigs.ImageOverrideComponents))
+			for c := range providerConfigs.ImageOverrideComponents {
+				components = append(components, c)
+			}
+			sort.Strings(components)
+			return fmt.Errorf("unknown image override component %s: must be one of %v", component, components)
+		}
+
+		_, _, err := providerConfigs.SplitImage(image)
+		if err != nil {
+			return fmt.Errorf("invalid image override for %s: %s", component, err)
+		}
+	}
+
+	return nil
+}
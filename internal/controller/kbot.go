@@ -10,11 +10,15 @@ import (
 	"github.com/kubefirst/kubefirst-api/internal/secrets"
 	pkg "github.com/kubefirst/kubefirst-api/pkg/utils"
 	"github.com/kubefirst/metrics-client/pkg/telemetry"
-	log "github.com/rs/zerolog/log"
 )
 
 // InitializeBot
 func (clctrl *ClusterController) InitializeBot() error {
+	err := clctrl.refreshGitToken()
+	if err != nil {
+		return err
+	}
+
 	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
 	if err != nil {
 		return err
@@ -24,8 +28,8 @@ func (clctrl *ClusterController) InitializeBot() error {
 
 		clctrl.GitAuth.PrivateKey, clctrl.GitAuth.PublicKey, err = pkg.CreateSshKeyPair()
 		if err != nil {
-			log.Error().Msgf("error generating ssh keys: %s", err)
-			telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.KbotSetupFailed, err.Error())
+			clctrl.logger().Errorf("error generating ssh keys: %s", err)
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.KbotSetupFailed, err.Error())
 			return err
 		}
 
@@ -18,6 +18,11 @@ import (
 
 // DetokenizeKMSKeyID
 func (clctrl *ClusterController) DetokenizeKMSKeyID() error {
+	err := clctrl.refreshGitToken()
+	if err != nil {
+		return err
+	}
+
 	cl, err := clctrl.GetCurrentClusterRecord()
 	if err != nil {
 		return err
@@ -48,7 +48,7 @@ func (clctrl *ClusterController) ExportClusterRecord() error {
 	cluster, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
 
 	if err != nil {
-		log.Error().Msgf("Error exporting cluster record: %s", err)
+		clctrl.logger().Errorf("Error exporting cluster record: %s", err)
 		clctrl.HandleError(err.Error())
 		return err
 	}
@@ -98,6 +98,11 @@ func (clctrl *ClusterController) ExportClusterRecord() error {
 
 // ExportClusterRecord will export cluster record to mgmt cluster
 func (clctrl *ClusterController) CreateVirtualClusters() error {
+	err := CheckWritable()
+	if err != nil {
+		return err
+	}
+
 	time.Sleep(time.Minute * 2)
 	var fullDomainName string
 
@@ -113,9 +118,9 @@ func (clctrl *ClusterController) CreateVirtualClusters() error {
 		consoleCloudUrl = "http://localhost:3000"
 	}
 
-	err := pkg.IsAppAvailable(fmt.Sprintf("%s/api/proxyHealth", consoleCloudUrl), "kubefirst api")
+	err = pkg.IsAppAvailable(fmt.Sprintf("%s/api/proxyHealth", consoleCloudUrl), "kubefirst api")
 	if err != nil {
-		log.Error().Msgf("unable to wait for kubefirst console: %s", err)
+		clctrl.logger().Errorf("unable to wait for kubefirst console: %s", err)
 		clctrl.HandleError(err.Error())
 		return err
 	}
@@ -135,7 +140,7 @@ func (clctrl *ClusterController) CreateVirtualClusters() error {
 
 	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/proxy", consoleCloudUrl), bytes.NewReader(payload))
 	if err != nil {
-		log.Error().Msgf("unable to create default clusters: %s", err)
+		clctrl.logger().Errorf("unable to create default clusters: %s", err)
 		clctrl.HandleError(err.Error())
 		return err
 	}
@@ -144,7 +149,7 @@ func (clctrl *ClusterController) CreateVirtualClusters() error {
 
 	res, err := httpClient.Do(req)
 	if err != nil {
-		log.Error().Msgf("unable to create default clusters: %s", err)
+		clctrl.logger().Errorf("unable to create default clusters: %s", err)
 		clctrl.HandleError(err.Error())
 		return err
 	}
@@ -155,12 +160,12 @@ func (clctrl *ClusterController) CreateVirtualClusters() error {
 	}
 
 	if res.StatusCode != http.StatusOK {
-		log.Error().Msgf("unable to create default clusters: %s %s", err, body)
+		clctrl.logger().Errorf("unable to create default clusters: %s %s", err, body)
 		clctrl.HandleError(err.Error())
 		return err
 	}
 
-	log.Info().Msg("cluster creation complete")
+	clctrl.logger().Info("cluster creation complete")
 
 	return nil
 }
@@ -0,0 +1,318 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	awsinternal "github.com/kubefirst/kubefirst-api/internal/aws"
+	"github.com/kubefirst/kubefirst-api/internal/civo"
+	"github.com/kubefirst/kubefirst-api/internal/digitalocean"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	"github.com/kubefirst/kubefirst-api/internal/vultr"
+	"github.com/kubefirst/kubefirst-api/pkg/akamai"
+	google "github.com/kubefirst/kubefirst-api/pkg/google"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// kubernetesVersionRegex matches a kubernetes minor or patch version with an
+// optional provider suffix, e.g. 1.27, 1.27.4 or 1.27.4-do.0
+var kubernetesVersionRegex = regexp.MustCompile(`^v?[0-9]+\.[0-9]+(\.[0-9]+)?([-+][0-9A-Za-z.+-]+)?$`)
+
+// validateKubernetesVersion makes sure the kubernetes version looks like one,
+// whether the cloud offers it is checked in preflight
+func validateKubernetesVersion(version string) error {
+	if version == "" {
+		return nil
+	}
+	if !kubernetesVersionRegex.MatchString(version) {
+		return fmt.Errorf("invalid kubernetes_version %s: must be a version such as 1.27 or 1.27.4", version)
+	}
+
+	return nil
+}
+
+// getKubernetesVersionTerraformEnvs pins the cluster's kubernetes version, the
+// cloud terraform uses the provider's default when it isn't set
+func getKubernetesVersionTerraformEnvs(envs map[string]string, version string) map[string]string {
+	if version == "" {
+		return envs
+	}
+
+	envs["TF_VAR_kubernetes_version"] = version
+
+	return envs
+}
+
+// kubernetesVersionOffered reports whether version is one of offered, a
+// version also matches the offered versions it's a prefix of so 1.27 matches
+// 1.27.4 and 1.27.4 matches 1.27.4-do.0
+func kubernetesVersionOffered(version string, offered []string) bool {
+	version = strings.TrimPrefix(version, "v")
+	for _, candidate := range offered {
+		candidate = strings.TrimPrefix(candidate, "v")
+		if candidate == version ||
+			strings.HasPrefix(candidate, version+".") ||
+			strings.HasPrefix(candidate, version+"-") ||
+			strings.HasPrefix(candidate, version+"+") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// kubernetesVersionCacheTTL is how long a provider's kubernetes versions are
+// reused before its api is queried again
+const kubernetesVersionCacheTTL = 15 * time.Minute
+
+// the versions a provider offers are the same for every account in a region,
+// so they're cached by provider and region
+var kubernetesVersionCache = struct {
+	sync.Mutex
+	entries map[string]kubernetesVersionCacheEntry
+}{entries: map[string]kubernetesVersionCacheEntry{}}
+
+type kubernetesVersionCacheEntry struct {
+	versions []string
+	expires  time.Time
+}
+
+// listKubernetesVersions returns the kubernetes versions the definition's
+// cloud provider creates clusters with, cached for kubernetesVersionCacheTTL
+func listKubernetesVersions(def *pkgtypes.ClusterDefinition) ([]string, error) {
+	key := fmt.Sprintf("%s/%s", def.CloudProvider, def.CloudRegion)
+
+	kubernetesVersionCache.Lock()
+	entry, ok := kubernetesVersionCache.entries[key]
+	kubernetesVersionCache.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.versions, nil
+	}
+
+	versions, err := fetchKubernetesVersions(def)
+	if err != nil {
+		return nil, err
+	}
+
+	kubernetesVersionCache.Lock()
+	kubernetesVersionCache.entries[key] = kubernetesVersionCacheEntry{
+		versions: versions,
+		expires:  time.Now().Add(kubernetesVersionCacheTTL),
+	}
+	kubernetesVersionCache.Unlock()
+
+	return versions, nil
+}
+
+// fetchKubernetesVersions asks the definition's cloud provider which
+// kubernetes versions it creates clusters with
+func fetchKubernetesVersions(def *pkgtypes.ClusterDefinition) ([]string, error) {
+	switch def.CloudProvider {
+	case "akamai":
+		akamaiConf := akamai.AkamaiConfiguration{
+			Client:  akamai.NewAkamai(def.AkamaiAuth.Token, def.AkamaiAuth.APIURL),
+			Context: context.Background(),
+		}
+		return akamaiConf.ListKubernetesVersions()
+	case "aws":
+		awsConf := awsinternal.AWSConfiguration{
+			Config: awsinternal.NewAwsV3(def.CloudRegion, def.AWSAuth.AccessKeyID, def.AWSAuth.SecretAccessKey, def.AWSAuth.SessionToken, def.AWSAuth.APIURL),
+		}
+		return awsConf.ListKubernetesVersions()
+	case "civo":
+		civoConf := civo.CivoConfiguration{
+			Client:  civo.NewCivo(def.CivoAuth.Token, def.CloudRegion, def.CivoAuth.APIURL),
+			Context: context.Background(),
+		}
+		return civoConf.ListKubernetesVersions()
+	case "digitalocean":
+		digitaloceanConf := digitalocean.DigitaloceanConfiguration{
+			Client:  digitalocean.NewDigitalocean(def.DigitaloceanAuth.Token, def.DigitaloceanAuth.APIURL),
+			Context: context.Background(),
+		}
+		return digitaloceanConf.ListKubernetesVersions()
+	case "google":
+		googleConf := google.GoogleConfiguration{
+			Context: context.Background(),
+			Project: def.GoogleAuth.ProjectId,
+			Region:  def.CloudRegion,
+			KeyFile: def.GoogleAuth.KeyFile,
+		}
+		return googleConf.ListKubernetesVersions()
+	case "vultr":
+		vultrConf := vultr.VultrConfiguration{
+			Client:  vultr.NewVultr(def.VultrAuth.Token, def.VultrAuth.APIURL),
+			Context: context.Background(),
+		}
+		return vultrConf.ListKubernetesVersions()
+	}
+
+	return nil, nil
+}
+
+// preflightKubernetesVersion confirms the cloud provider creates clusters
+// with the definition's kubernetes version, k3s installs whichever version it
+// is given and isn't checked
+func preflightKubernetesVersion(def *pkgtypes.ClusterDefinition) error {
+	if def.KubernetesVersion == "" || def.CloudProvider == "k3s" {
+		return nil
+	}
+
+	offered, err := listKubernetesVersions(def)
+	if err != nil {
+		return fmt.Errorf("error listing %s kubernetes versions: %s", def.CloudProvider, err)
+	}
+	if !kubernetesVersionOffered(def.KubernetesVersion, offered) {
+		return fmt.Errorf("kubernetes version %s is not supported by %s in %s, supported versions are: %s", def.KubernetesVersion, def.CloudProvider, def.CloudRegion, strings.Join(offered, ", "))
+	}
+
+	return nil
+}
+
+// kubernetesSemver is the major, minor and patch of a kubernetes version, the
+// patch is -1 for versions that name only a minor version
+type kubernetesSemver struct {
+	major, minor, patch int
+}
+
+// parseKubernetesVersion reads the major, minor and patch from versions such
+// as 1.27, v1.27.4 or 1.27.4-do.0, ignoring any provider suffix
+func parseKubernetesVersion(version string) (kubernetesSemver, bool) {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return kubernetesSemver{}, false
+	}
+	semver := kubernetesSemver{patch: -1}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return kubernetesSemver{}, false
+		}
+		switch i {
+		case 0:
+			semver.major = n
+		case 1:
+			semver.minor = n
+		case 2:
+			semver.patch = n
+		}
+	}
+
+	return semver, true
+}
+
+// kubernetesUpgradeTargets returns the offered versions current can be
+// upgraded to: later patches of its minor version and the next minor version,
+// kubernetes doesn't support skipping minor versions. Offered versions naming
+// the same release (1.27.4 and 1.27.4-do.0) are listed once, the first wins
+func kubernetesUpgradeTargets(current string, offered []string) []string {
+	from, ok := parseKubernetesVersion(current)
+	if !ok {
+		return []string{}
+	}
+
+	type target struct {
+		version string
+		semver  kubernetesSemver
+	}
+	seen := map[kubernetesSemver]bool{}
+	targets := []target{}
+	for _, version := range offered {
+		to, ok := parseKubernetesVersion(version)
+		if !ok || to.major != from.major || seen[to] {
+			continue
+		}
+		if (to.minor == from.minor && to.patch > from.patch) || to.minor == from.minor+1 {
+			seen[to] = true
+			targets = append(targets, target{version: version, semver: to})
+		}
+	}
+
+	sort.SliceStable(targets, func(i, j int) bool {
+		if targets[i].semver.minor != targets[j].semver.minor {
+			return targets[i].semver.minor < targets[j].semver.minor
+		}
+		return targets[i].semver.patch < targets[j].semver.patch
+	})
+
+	versions := []string{}
+	for _, target := range targets {
+		versions = append(versions, target.version)
+	}
+
+	return versions
+}
+
+// ListUpgradeTargets returns the kubernetes versions the cluster's cloud
+// provider can upgrade it to from the version its api server reports, so
+// unsupported jumps are never offered. The provider's versions are cached for
+// kubernetesVersionCacheTTL
+func (clctrl *ClusterController) ListUpgradeTargets(clusterName string) (pkgtypes.KubernetesUpgradeTargets, error) {
+	result := pkgtypes.KubernetesUpgradeTargets{ClusterName: clusterName, Targets: []string{}}
+
+	if clusterName != clctrl.ClusterName {
+		return result, fmt.Errorf("controller is initialized for cluster %s, not %s", clctrl.ClusterName, clusterName)
+	}
+	if clctrl.CloudProvider == "k3s" {
+		return result, fmt.Errorf("upgrade targets are not available for k3s, it installs whichever version it's given")
+	}
+
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clusterName)
+	if err != nil {
+		return result, err
+	}
+
+	kcfg, err := clctrl.GetClusterKubernetesClient()
+	if err != nil || kcfg == nil || kcfg.RestConfig == nil {
+		return result, fmt.Errorf("error loading the kubeconfig of cluster %s: %v", clusterName, err)
+	}
+	config := rest.CopyConfig(kcfg.RestConfig)
+	config.Timeout = connectivityTimeout
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return result, fmt.Errorf("error creating the kubernetes client of cluster %s: %s", clusterName, err)
+	}
+	serverVersion, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		return result, fmt.Errorf("error getting the kubernetes version of cluster %s: %s", clusterName, err)
+	}
+	result.CurrentVersion = serverVersion.GitVersion
+
+	offered, err := listKubernetesVersions(&pkgtypes.ClusterDefinition{
+		CloudProvider:    cl.CloudProvider,
+		CloudRegion:      cl.CloudRegion,
+		AkamaiAuth:       cl.AkamaiAuth,
+		AWSAuth:          cl.AWSAuth,
+		CivoAuth:         cl.CivoAuth,
+		DigitaloceanAuth: cl.DigitaloceanAuth,
+		GoogleAuth:       cl.GoogleAuth,
+		VultrAuth:        cl.VultrAuth,
+	})
+	if err != nil {
+		return result, fmt.Errorf("error listing %s kubernetes versions: %s", cl.CloudProvider, err)
+	}
+
+	result.Targets = kubernetesUpgradeTargets(result.CurrentVersion, offered)
+	clctrl.logger().Infof("cluster %s runs kubernetes %s, upgrade targets: %v", clusterName, result.CurrentVersion, result.Targets)
+
+	return result, nil
+}
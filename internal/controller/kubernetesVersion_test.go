@@ -0,0 +1,47 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKubernetesVersionOffered(t *testing.T) {
+	offered := []string{"1.26.9-do.0", "1.26.9", "1.27.4-do.0", "1.27.4", "v1.28.2"}
+
+	for version, want := range map[string]bool{
+		"1.27":        true,
+		"1.27.4":      true,
+		"1.27.4-do.0": true,
+		"v1.26":       true,
+		"1.28":        true,
+		"1.2":         false,
+		"1.27.5":      false,
+		"1.29":        false,
+	} {
+		if got := kubernetesVersionOffered(version, offered); got != want {
+			t.Errorf("kubernetesVersionOffered(%q) = %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestKubernetesUpgradeTargets(t *testing.T) {
+	offered := []string{"1.29.1-do.0", "1.29.1", "1.28.2-do.0", "1.28.2", "1.27.6-do.0", "1.27.6", "1.27.4-do.0", "1.27.4", "1.26.9"}
+
+	got := kubernetesUpgradeTargets("v1.27.4", offered)
+	want := []string{"1.27.6-do.0", "1.28.2-do.0"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("kubernetesUpgradeTargets(v1.27.4) = %v, want %v", got, want)
+	}
+
+	got = kubernetesUpgradeTargets("v1.27.3-eks-a5df82a", []string{"1.26", "1.27", "1.28", "1.29"})
+	want = []string{"1.28"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("kubernetesUpgradeTargets(v1.27.3-eks-a5df82a) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/sirupsen/logrus"
+)
+
+// Logger receives the log output of a cluster controller's steps, set
+// ClusterController.Logger to capture or correlate the logs of a single
+// cluster - the global zerolog logger is used when it's nil
+type Logger interface {
+	Info(msg string)
+	Infof(format string, args ...interface{})
+	Warn(msg string)
+	Warnf(format string, args ...interface{})
+	Error(msg string)
+	Errorf(format string, args ...interface{})
+}
+
+// logger returns the controller's logger, falling back to the global logger,
+// that also publishes to the cluster's event stream while it's provisioning
+func (clctrl *ClusterController) logger() Logger {
+	var logger Logger = globalLogger{}
+	if clctrl.Logger != nil {
+		logger = clctrl.Logger
+	}
+	if !clctrl.streamingEvents {
+		return logger
+	}
+
+	// the event logger adds a frame between the caller and the logger
+	if skipper, ok := logger.(callerSkipper); ok {
+		logger = skipper.skipCallerFrames(1)
+	}
+
+	return eventLogger{Logger: logger, clctrl: clctrl}
+}
+
+// Log returns the logger the controller's steps log to, for the provider
+// code running them
+func (clctrl *ClusterController) Log() Logger {
+	return clctrl.logger()
+}
+
+// callerSkipper is implemented by the loggers that report their caller, so
+// a logger wrapping them can skip its own frames
+type callerSkipper interface {
+	skipCallerFrames(n int) Logger
+}
+
+// globalLogger writes to the global zerolog logger at call time so changes
+// to it after the controller was created are honoured
+type globalLogger struct {
+	skip int
+}
+
+func (l globalLogger) skipCallerFrames(n int) Logger {
+	return globalLogger{skip: l.skip + n}
+}
+
+func (l globalLogger) Info(msg string) {
+	log.Info().CallerSkipFrame(1 + l.skip).Msg(msg)
+}
+
+func (l globalLogger) Infof(format string, args ...interface{}) {
+	log.Info().CallerSkipFrame(1+l.skip).Msgf(format, args...)
+}
+
+func (l globalLogger) Warn(msg string) {
+	log.Warn().CallerSkipFrame(1 + l.skip).Msg(msg)
+}
+
+func (l globalLogger) Warnf(format string, args ...interface{}) {
+	log.Warn().CallerSkipFrame(1+l.skip).Msgf(format, args...)
+}
+
+func (l globalLogger) Error(msg string) {
+	log.Error().CallerSkipFrame(1 + l.skip).Msg(msg)
+}
+
+func (l globalLogger) Errorf(format string, args ...interface{}) {
+	log.Error().CallerSkipFrame(1+l.skip).Msgf(format, args...)
+}
+
+// ZerologLogger adapts a zerolog logger, e.g. one carrying request or
+// cluster fields, to Logger
+type ZerologLogger struct {
+	Logger zerolog.Logger
+	skip   int
+}
+
+// NewZerologLogger returns a Logger writing to logger
+func NewZerologLogger(logger zerolog.Logger) ZerologLogger {
+	return ZerologLogger{Logger: logger}
+}
+
+func (l ZerologLogger) Info(msg string) {
+	l.Logger.Info().CallerSkipFrame(1 + l.skip).Msg(msg)
+}
+
+func (l ZerologLogger) Infof(format string, args ...interface{}) {
+	l.Logger.Info().CallerSkipFrame(1+l.skip).Msgf(format, args...)
+}
+
+func (l ZerologLogger) Warn(msg string) {
+	l.Logger.Warn().CallerSkipFrame(1 + l.skip).Msg(msg)
+}
+
+func (l ZerologLogger) Warnf(format string, args ...interface{}) {
+	l.Logger.Warn().CallerSkipFrame(1+l.skip).Msgf(format, args...)
+}
+
+func (l ZerologLogger) Error(msg string) {
+	l.Logger.Error().CallerSkipFrame(1 + l.skip).Msg(msg)
+}
+
+func (l ZerologLogger) Errorf(format string, args ...interface{}) {
+	l.Logger.Error().CallerSkipFrame(1+l.skip).Msgf(format, args...)
+}
+
+func (l ZerologLogger) skipCallerFrames(n int) Logger {
+	return ZerologLogger{Logger: l.Logger, skip: l.skip + n}
+}
+
+// LogrusLogger adapts a logrus logger or entry to Logger
+type LogrusLogger struct {
+	Logger logrus.FieldLogger
+}
+
+// NewLogrusLogger returns a Logger writing to logger
+func NewLogrusLogger(logger logrus.FieldLogger) LogrusLogger {
+	return LogrusLogger{Logger: logger}
+}
+
+func (l LogrusLogger) Info(msg string) {
+	l.Logger.Info(msg)
+}
+
+func (l LogrusLogger) Infof(format string, args ...interface{}) {
+	l.Logger.Infof(format, args...)
+}
+
+func (l LogrusLogger) Warn(msg string) {
+	l.Logger.Warn(msg)
+}
+
+func (l LogrusLogger) Warnf(format string, args ...interface{}) {
+	l.Logger.Warnf(format, args...)
+}
+
+func (l LogrusLogger) Error(msg string) {
+	l.Logger.Error(msg)
+}
+
+func (l LogrusLogger) Errorf(format string, args ...interface{}) {
+	l.Logger.Errorf(format, args...)
+}
@@ -0,0 +1,135 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/kubefirst/kubefirst-api/internal/constants"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// componentLogSource locates the pods of a platform component
+type componentLogSource struct {
+	namespace     string
+	labelSelector string
+}
+
+// componentLogSources are the components whose logs can be streamed
+var componentLogSources = map[string]componentLogSource{
+	"argocd":  {namespace: "argocd", labelSelector: "app.kubernetes.io/part-of=argocd"},
+	"console": {namespace: constants.KubefirstNamespace, labelSelector: fmt.Sprintf("%s=%s", consoleMatchLabel, consoleMatchLabelValue)},
+	"vault":   {namespace: "vault", labelSelector: "app.kubernetes.io/instance=vault"},
+}
+
+// componentLogStream is the combined log stream of every container of a
+// component, closing it stops all underlying requests
+type componentLogStream struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (s *componentLogStream) Close() error {
+	s.cancel()
+	return s.PipeReader.Close()
+}
+
+// StreamComponentLogs returns the logs of every container of a platform
+// component (argocd, console or vault) on the cluster, each line prefixed
+// with its pod and container - the caller must close the stream
+func (clctrl *ClusterController) StreamComponentLogs(component string, opts pkgtypes.ComponentLogOptions) (io.ReadCloser, error) {
+	source, ok := componentLogSources[component]
+	if !ok {
+		components := make([]string, 0, len(componentLogSources))
+		for c := range componentLogSources {
+			components = append(components, c)
+		}
+		sort.Strings(components)
+		return nil, fmt.Errorf("unknown component %s: must be one of %v", component, components)
+	}
+
+	kcfg, err := clctrl.GetClusterKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := kcfg.Clientset.CoreV1().Pods(source.namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: source.labelSelector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing %s pods: %s", component, err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, fmt.Errorf("no %s pods found in namespace %s on cluster %s", component, source.namespace, clctrl.ClusterName)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reader, writer := io.Pipe()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		streams int
+	)
+	for _, pod := range pods.Items {
+		for _, container := range pod.Spec.Containers {
+			podLogOptions := &v1.PodLogOptions{
+				Container: container.Name,
+				Follow:    opts.Follow,
+			}
+			if opts.TailLines > 0 {
+				podLogOptions.TailLines = &opts.TailLines
+			}
+			if !opts.SinceTime.IsZero() {
+				podLogOptions.SinceTime = &metav1.Time{Time: opts.SinceTime}
+			}
+
+			stream, err := kcfg.Clientset.CoreV1().Pods(source.namespace).GetLogs(pod.Name, podLogOptions).Stream(ctx)
+			if err != nil {
+				clctrl.logger().Warnf("error streaming logs for %s/%s: %s", pod.Name, container.Name, err)
+				continue
+			}
+
+			streams++
+			wg.Add(1)
+			go func(prefix string, stream io.ReadCloser) {
+				defer wg.Done()
+				defer stream.Close()
+
+				scanner := bufio.NewScanner(stream)
+				for scanner.Scan() {
+					mu.Lock()
+					_, err := fmt.Fprintf(writer, "[%s] %s\n", prefix, scanner.Text())
+					mu.Unlock()
+					if err != nil {
+						return
+					}
+				}
+			}(fmt.Sprintf("%s/%s", pod.Name, container.Name), stream)
+		}
+	}
+
+	if streams == 0 {
+		cancel()
+		writer.Close()
+		return nil, fmt.Errorf("unable to stream logs for any %s container on cluster %s", component, clctrl.ClusterName)
+	}
+
+	go func() {
+		wg.Wait()
+		writer.Close()
+	}()
+
+	return &componentLogStream{PipeReader: reader, cancel: cancel}, nil
+}
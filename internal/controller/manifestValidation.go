@@ -0,0 +1,297 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestValidator checks the kubernetes manifests of a repository before
+// it is pushed, so manifests argocd can't apply never reach the cluster
+type ManifestValidator interface {
+	// ValidateManifests returns an error describing every invalid manifest
+	// of the repository checked out at dir
+	ValidateManifests(dir string) error
+}
+
+// YAMLValidator makes sure every manifest parses and that documents with a
+// kind also have an apiVersion and a name
+type YAMLValidator struct{}
+
+// KubeconformValidator validates the manifests against their kubernetes
+// schemas with the kubeconform binary at Path, Args replace the default
+// -summary -ignore-missing-schemas flags. Only documents with an apiVersion
+// and a kind are passed to kubeconform, values files, atlantis.yaml and
+// other plain yaml are left to the YAMLValidator
+type KubeconformValidator struct {
+	Path string
+	Args []string
+}
+
+// ManifestValidators runs every validator and reports the problems of all
+// of them
+type ManifestValidators []ManifestValidator
+
+func (validators ManifestValidators) ValidateManifests(dir string) error {
+	problems := []string{}
+	for _, validator := range validators {
+		err := validator.ValidateManifests(dir)
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// defaultManifestValidator checks the yaml and also uses kubeconform when
+// it is installed, helm charts are rendered when renderHelm is set and helm
+// is installed
+func defaultManifestValidator(renderHelm bool) ManifestValidator {
+	validators := ManifestValidators{YAMLValidator{}}
+	path, err := exec.LookPath("kubeconform")
+	if err == nil {
+		validators = append(validators, KubeconformValidator{Path: path})
+	}
+
+	if !renderHelm {
+		return validators
+	}
+	path, err = exec.LookPath("helm")
+	if err == nil {
+		validators = append(validators, HelmTemplateValidator{Path: path})
+	}
+
+	return validators
+}
+
+// validateGitopsManifests runs the controller's manifest validator over the
+// gitops repository, unless the cluster skips validation, renderHelm adds
+// the helm charts to the default validator
+func (clctrl *ClusterController) validateGitopsManifests(skip bool, renderHelm bool) error {
+	if skip {
+		clctrl.logger().Warn("skipping gitops manifest validation")
+		return nil
+	}
+
+	validator := clctrl.ManifestValidator
+	if validator == nil {
+		validator = defaultManifestValidator(renderHelm)
+	}
+
+	clctrl.logger().Info("validating gitops manifests")
+	err := validator.ValidateManifests(clctrl.ProviderConfig.GitopsDir)
+	if err != nil {
+		return fmt.Errorf("the gitops repository has invalid manifests, fix the template or set skip_manifest_validation: %s", err)
+	}
+
+	return nil
+}
+
+func (YAMLValidator) ValidateManifests(dir string) error {
+	files, err := manifestFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	problems := []string{}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		relative, _ := filepath.Rel(dir, file)
+
+		manifests, err := decodeManifests(content)
+		for i, manifest := range manifests {
+			problem := validateManifest(manifest)
+			if problem != "" {
+				problems = append(problems, fmt.Sprintf("%s (document %d): %s", relative, i+1, problem))
+			}
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", relative, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// decodeManifests decodes the yaml documents of a file, up to the first one
+// that doesn't parse
+func decodeManifests(content []byte) ([]interface{}, error) {
+	manifests := []interface{}{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var manifest interface{}
+		err := decoder.Decode(&manifest)
+		if errors.Is(err, io.EOF) {
+			return manifests, nil
+		}
+		if err != nil {
+			return manifests, err
+		}
+		manifests = append(manifests, manifest)
+	}
+}
+
+// validateManifest checks a decoded yaml document that declares a kind
+func validateManifest(manifest interface{}) string {
+	object, ok := manifest.(map[string]interface{})
+	if !ok || object["kind"] == nil {
+		// values files and other plain yaml
+		return ""
+	}
+
+	apiVersion, _ := object["apiVersion"].(string)
+	if apiVersion == "" {
+		return fmt.Sprintf("%v has no apiVersion", object["kind"])
+	}
+	// kustomize files have no metadata
+	if strings.HasPrefix(apiVersion, "kustomize.config.k8s.io/") {
+		return ""
+	}
+
+	metadata, _ := object["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	generateName, _ := metadata["generateName"].(string)
+	if name == "" && generateName == "" {
+		return fmt.Sprintf("%v has no metadata.name", object["kind"])
+	}
+
+	return ""
+}
+
+func (v KubeconformValidator) ValidateManifests(dir string) error {
+	files, err := manifestFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	// the kubernetes documents of every file are copied to the same relative
+	// path below staging, so kubeconform reports the repository's paths
+	staging, err := os.MkdirTemp("", "kubeconform")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	stagedFiles := []string{}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		manifests, err := kubernetesManifests(content)
+		if err != nil || len(manifests) == 0 {
+			// yaml that doesn't parse is reported by the YAMLValidator
+			continue
+		}
+
+		relative, _ := filepath.Rel(dir, file)
+		stagedFile := filepath.Join(staging, relative)
+		err = os.MkdirAll(filepath.Dir(stagedFile), 0o755)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(stagedFile, manifests, 0o644)
+		if err != nil {
+			return err
+		}
+		stagedFiles = append(stagedFiles, relative)
+	}
+	if len(stagedFiles) == 0 {
+		return nil
+	}
+
+	args := v.Args
+	if args == nil {
+		args = []string{"-summary", "-ignore-missing-schemas"}
+	}
+	cmd := exec.Command(v.Path, append(args, stagedFiles...)...)
+	cmd.Dir = staging
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("kubeconform: %s: %s", err, strings.TrimSpace(strings.ReplaceAll(string(output), staging+"/", "")))
+	}
+
+	return nil
+}
+
+// kubernetesManifests returns the documents of a yaml file that have an
+// apiVersion and a kind, encoded as a multi document yaml file
+func kubernetesManifests(content []byte) ([]byte, error) {
+	manifests, err := decodeManifests(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, manifest := range manifests {
+		object, ok := manifest.(map[string]interface{})
+		if !ok || object["apiVersion"] == nil || object["kind"] == nil {
+			continue
+		}
+
+		data, err := yaml.Marshal(object)
+		if err != nil {
+			return nil, err
+		}
+		if buf.Len() > 0 {
+			buf.WriteString("---\n")
+		}
+		buf.Write(data)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// manifestFiles lists the yaml files of a repository, leaving out git and
+// terraform directories and helm charts whose templates aren't yaml until
+// they're rendered
+func manifestFiles(dir string) ([]string, error) {
+	files := []string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || d.Name() == "terraform" {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "Chart.yaml")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+			files = append(files, path)
+		}
+		return nil
+	})
+
+	return files, err
+}
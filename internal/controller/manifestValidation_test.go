@@ -0,0 +1,159 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestYAMLValidator(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"registry/app.yaml":                "apiVersion: argoproj.io/v1alpha1\nkind: Application\nmetadata:\n  name: registry\n",
+		"registry/kustomization.yaml":      "apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nresources:\n- app.yaml\n",
+		"components/values.yaml":           "replicas: 2\n",
+		"charts/metaphor/Chart.yaml":       "name: metaphor\n",
+		"charts/metaphor/templates/x.yaml": "{{ .Values.broken }\n",
+		"terraform/modules/x.yaml":         "not: [valid\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, []byte(content), 0o644)
+	}
+
+	err := YAMLValidator{}.ValidateManifests(dir)
+	if err != nil {
+		t.Fatalf("expected valid manifests, got %s", err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "registry/broken.yaml"), []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: <CLUSTER_NAME\n  namespace: [\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "registry/unnamed.yaml"), []byte("---\nkind: Namespace\n---\napiVersion: v1\nkind: Namespace\nmetadata: {}\n"), 0o644)
+
+	err = YAMLValidator{}.ValidateManifests(dir)
+	if err == nil {
+		t.Fatal("expected invalid manifests to be reported")
+	}
+	for _, want := range []string{"registry/broken.yaml", "registry/unnamed.yaml (document 1): Namespace has no apiVersion", "registry/unnamed.yaml (document 2): Namespace has no metadata.name"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %s", want, err)
+		}
+	}
+}
+
+func TestApplicationCharts(t *testing.T) {
+	manifests, err := decodeManifests([]byte(`apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: cert-manager
+spec:
+  destination:
+    namespace: cert-manager
+  source:
+    repoURL: https://charts.jetstack.io
+    chart: cert-manager
+    targetRevision: v1.11.0
+    helm:
+      values: |-
+        installCRDs: true
+      parameters:
+      - name: replicaCount
+        value: "2"
+      - name: image.tag
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: registry
+spec:
+  source:
+    repoURL: https://github.com/kubefirst/gitops.git
+    path: registry/clusters/kf-test
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	charts := []helmChart{}
+	for _, manifest := range manifests {
+		charts = append(charts, applicationCharts("registry/cert-manager.yaml", manifest)...)
+	}
+	if len(charts) != 1 {
+		t.Fatalf("expected only the cert-manager chart, got %v", charts)
+	}
+
+	want := "cert-manager cert-manager --repo https://charts.jetstack.io --version v1.11.0 --namespace cert-manager --set replicaCount=2"
+	if got := strings.Join(charts[0].args, " "); got != want {
+		t.Errorf("expected args %q, got %q", want, got)
+	}
+	if charts[0].values != "installCRDs: true" {
+		t.Errorf("expected the application's values, got %q", charts[0].values)
+	}
+	if !strings.Contains(charts[0].source, "application cert-manager") {
+		t.Errorf("expected the source to name the application, got %q", charts[0].source)
+	}
+}
+
+func TestKubeconformValidator(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"registry/app.yaml":      "apiVersion: argoproj.io/v1alpha1\nkind: Application\nmetadata:\n  name: registry\n",
+		"registry/mixed.yaml":    "replicas: 2\n---\napiVersion: v1\nkind: Namespace\nmetadata:\n  name: mixed\n",
+		"atlantis.yaml":          "version: 3\nprojects:\n- dir: terraform/vault\n",
+		"components/values.yaml": "replicas: 2\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, []byte(content), 0o644)
+	}
+
+	// stands in for kubeconform, recording the files and documents it gets
+	record := filepath.Join(t.TempDir(), "record")
+	kubeconform := filepath.Join(t.TempDir(), "kubeconform")
+	os.WriteFile(kubeconform, []byte("#!/bin/sh\nfor f in \"$@\"; do echo \"== $f\"; cat \"$f\"; done > "+record+"\n"), 0o755)
+
+	err := KubeconformValidator{Path: kubeconform, Args: []string{}}.ValidateManifests(dir)
+	if err != nil {
+		t.Fatalf("expected valid manifests, got %s", err)
+	}
+	recorded, err := os.ReadFile(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"== registry/app.yaml", "== registry/mixed.yaml", "name: mixed"} {
+		if !strings.Contains(string(recorded), want) {
+			t.Errorf("expected kubeconform to get %q, got:\n%s", want, recorded)
+		}
+	}
+	for _, unwanted := range []string{"atlantis.yaml", "values.yaml", "replicas"} {
+		if strings.Contains(string(recorded), unwanted) {
+			t.Errorf("expected kubeconform not to get %q, got:\n%s", unwanted, recorded)
+		}
+	}
+}
+
+func TestDefaultManifestValidatorHelmOptIn(t *testing.T) {
+	// a helm on the PATH is only used when the cluster opts in
+	helm := filepath.Join(t.TempDir(), "helm")
+	os.WriteFile(helm, []byte("#!/bin/sh\n"), 0o755)
+	t.Setenv("PATH", filepath.Dir(helm))
+
+	for _, renderHelm := range []bool{false, true} {
+		rendered := false
+		for _, validator := range defaultManifestValidator(renderHelm).(ManifestValidators) {
+			_, ok := validator.(HelmTemplateValidator)
+			rendered = rendered || ok
+		}
+		if rendered != renderHelm {
+			t.Errorf("defaultManifestValidator(%v) renders helm charts: %v", renderHelm, rendered)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/kubefirst/kubefirst-api/internal/k8s"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// validatePostInstallManifests makes sure each post install manifest has
+// either an http(s) url or inline content
+func validatePostInstallManifests(manifests []pkgtypes.PostInstallManifest) error {
+	for i, manifest := range manifests {
+		if (manifest.URL == "") == (manifest.Content == "") {
+			return fmt.Errorf("post install manifest %d must set exactly one of url or content", i)
+		}
+		if manifest.URL != "" {
+			u, err := url.Parse(manifest.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return fmt.Errorf("post install manifest %d has invalid url %s", i, manifest.URL)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ApplyPostInstallManifests applies the user supplied post install manifests
+// once the gitops registry has synced - failures are recorded per manifest on
+// the cluster record and do not fail the install, the manifests are applied
+// again until every one of them succeeds
+func (clctrl *ClusterController) ApplyPostInstallManifests() error {
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	if !cl.PostInstallManifestsCheck && len(clctrl.PostInstallManifests) != 0 {
+		operations, err := clctrl.clusterOperations()
+		if err != nil {
+			return err
+		}
+
+		results := make([]pkgtypes.PostInstallManifestResult, 0, len(clctrl.PostInstallManifests))
+		applied := true
+		for i, manifest := range clctrl.PostInstallManifests {
+			result := pkgtypes.PostInstallManifestResult{Source: manifest.URL}
+			if result.Source == "" {
+				result.Source = fmt.Sprintf("inline manifest %d", i)
+			}
+
+			err = clctrl.applyPostInstallManifest(operations, manifest)
+			if err != nil {
+				clctrl.logger().Errorf("error applying post install manifest %s: %s", result.Source, err)
+				result.Error = err.Error()
+				applied = false
+			} else {
+				clctrl.logger().Infof("applied post install manifest %s", result.Source)
+				result.Applied = true
+			}
+			results = append(results, result)
+		}
+
+		clctrl.Cluster.PostInstallManifestResults = results
+		// failed manifests are applied again on the next run
+		clctrl.Cluster.PostInstallManifestsCheck = applied
+		err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyPostInstallManifest fetches a single manifest if needed and applies
+// every document it contains
+func (clctrl *ClusterController) applyPostInstallManifest(operations k8s.Operations, manifest pkgtypes.PostInstallManifest) error {
+	content := []byte(manifest.Content)
+	if manifest.URL != "" {
+		res, err := clctrl.HttpClient.Get(manifest.URL)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected status fetching %s: %s", manifest.URL, res.Status)
+		}
+
+		content, err = io.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+	}
+
+	documents, err := k8s.SplitYAML(bytes.NewBuffer(content))
+	if err != nil {
+		return fmt.Errorf("error parsing manifest: %s", err)
+	}
+
+	namespace := manifest.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	return operations.ApplyObjects(namespace, documents)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestApplyPostInstallManifests(t *testing.T) {
+	manifests := []pkgtypes.PostInstallManifest{
+		{Content: "apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: bootstrap\n"},
+		{Content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n", Namespace: "platform"},
+	}
+
+	for name, test := range map[string]struct {
+		applyErr       error
+		wantCheck      bool
+		wantNamespaces []string
+	}{
+		"all applied": {
+			wantCheck:      true,
+			wantNamespaces: []string{"default", "platform"},
+		},
+		"apply failed": {
+			applyErr:       errors.New("forbidden"),
+			wantCheck:      false,
+			wantNamespaces: []string{"default", "platform"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store, err := secrets.NewMemoryStore("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			secrets.SetStore(store)
+			defer secrets.SetStore(nil)
+
+			err = secrets.InsertCluster(nil, pkgtypes.Cluster{ClusterName: "kf-manifests"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			operations := &fakeOperations{ApplyError: test.applyErr}
+			useFakeOperations(t, operations)
+			clctrl := &ClusterController{
+				ClusterName:          "kf-manifests",
+				Cluster:              pkgtypes.Cluster{ClusterName: "kf-manifests"},
+				PostInstallManifests: manifests,
+			}
+
+			err = clctrl.ApplyPostInstallManifests()
+			if err != nil {
+				t.Fatalf("ApplyPostInstallManifests() = %s", err)
+			}
+			if !reflect.DeepEqual(operations.ApplyNamespaces, test.wantNamespaces) {
+				t.Errorf("applied to namespaces %v, want %v", operations.ApplyNamespaces, test.wantNamespaces)
+			}
+
+			cl, err := secrets.GetCluster(nil, "kf-manifests")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cl.PostInstallManifestsCheck != test.wantCheck || len(cl.PostInstallManifestResults) != len(manifests) {
+				t.Errorf("cluster record check = %t with %d results, want %t with %d", cl.PostInstallManifestsCheck, len(cl.PostInstallManifestResults), test.wantCheck, len(manifests))
+			}
+		})
+	}
+}
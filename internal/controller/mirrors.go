@@ -0,0 +1,111 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitConfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttps "github.com/go-git/go-git/v5/plumbing/transport/http"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// gitopsMirrorRemotePrefix prefixes the names of the gitops repository's
+// mirror remotes so they can't clash with the git provider remotes
+const gitopsMirrorRemotePrefix = "mirror-"
+
+// validateGitopsMirrors checks mirrors have unique names and https urls
+func validateGitopsMirrors(mirrors []pkgtypes.GitMirror) error {
+	names := map[string]bool{}
+	for _, mirror := range mirrors {
+		if mirror.Name == "" {
+			return fmt.Errorf("gitops mirror name is required")
+		}
+		if names[mirror.Name] {
+			return fmt.Errorf("duplicate gitops mirror name %s", mirror.Name)
+		}
+		names[mirror.Name] = true
+
+		u, err := url.Parse(mirror.URL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("invalid url for gitops mirror %s: expected an https git url", mirror.Name)
+		}
+	}
+
+	return nil
+}
+
+// pushGitopsMirrors mirrors every branch of the gitops repository to each
+// configured mirror - a failing mirror doesn't fail the install, the outcome
+// of each push is returned to be recorded on the cluster
+func (clctrl *ClusterController) pushGitopsMirrors(gitopsRepo *git.Repository) []pkgtypes.GitMirrorStatus {
+	statuses := make([]pkgtypes.GitMirrorStatus, 0, len(clctrl.GitopsMirrors))
+
+	for _, mirror := range clctrl.GitopsMirrors {
+		status := pkgtypes.GitMirrorStatus{
+			Name: mirror.Name,
+			URL:  mirror.URL,
+		}
+
+		err := pushGitopsMirror(gitopsRepo, mirror)
+		if err != nil {
+			clctrl.logger().Warnf("error pushing gitops repository to mirror %s: %s", mirror.Name, err)
+			status.Error = err.Error()
+		} else {
+			clctrl.logger().Infof("pushed gitops repository to mirror %s", mirror.Name)
+			status.Pushed = true
+			status.PushedAt = time.Now().UTC().Format(time.RFC3339)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+// pushGitopsMirror points the mirror's remote at its url and force pushes
+// all branches to it
+func pushGitopsMirror(gitopsRepo *git.Repository, mirror pkgtypes.GitMirror) error {
+	remoteName := gitopsMirrorRemotePrefix + mirror.Name
+
+	err := gitopsRepo.DeleteRemote(remoteName)
+	if err != nil && !errors.Is(err, git.ErrRemoteNotFound) {
+		return err
+	}
+
+	_, err = gitopsRepo.CreateRemote(&gitConfig.RemoteConfig{
+		Name: remoteName,
+		URLs: []string{mirror.URL},
+	})
+	if err != nil {
+		return fmt.Errorf("error adding remote %s: %s", remoteName, err)
+	}
+
+	var auth transport.AuthMethod
+	if mirror.Username != "" || mirror.Password != "" {
+		auth = &githttps.BasicAuth{
+			Username: mirror.Username,
+			Password: mirror.Password,
+		}
+	}
+
+	err = gitopsRepo.Push(&git.PushOptions{
+		RemoteName: remoteName,
+		RefSpecs:   []gitConfig.RefSpec{"+refs/heads/*:refs/heads/*"},
+		Auth:       auth,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+
+	return nil
+}
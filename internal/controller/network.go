@@ -0,0 +1,233 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+
+	terraformext "github.com/kubefirst/kubefirst-api/extensions/terraform"
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+	awsinternal "github.com/kubefirst/kubefirst-api/internal/aws"
+	"github.com/kubefirst/kubefirst-api/internal/civo"
+	"github.com/kubefirst/kubefirst-api/internal/digitalocean"
+	"github.com/kubefirst/kubefirst-api/internal/vultr"
+	google "github.com/kubefirst/kubefirst-api/pkg/google"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	log "github.com/rs/zerolog/log"
+)
+
+// existingNetworkProviders are the cloud providers whose terraform can
+// consume a pre-existing network
+var existingNetworkProviders = []string{"aws", "civo", "digitalocean", "google", "vultr"}
+
+const (
+	// awsAddressesPerNode covers the node and the pod addresses the vpc cni
+	// hands out from the same subnets
+	awsAddressesPerNode = 32
+
+	// networkAddressHeadroom covers load balancers and rolling node
+	// replacements on providers with overlay pod networking
+	networkAddressHeadroom = 8
+)
+
+// validateExistingNetwork checks the existing network fields of a definition
+// without calling the cloud provider
+func validateExistingNetwork(def *pkgtypes.ClusterDefinition) error {
+	network := def.ExistingNetwork
+	if network.NetworkID == "" {
+		if len(network.SubnetIDs) != 0 {
+			return fmt.Errorf("existing_network.subnet_ids requires existing_network.network_id")
+		}
+		return nil
+	}
+
+	if !pkg.FindStringInSlice(existingNetworkProviders, def.CloudProvider) {
+		return fmt.Errorf("existing networks are not supported for cloud provider %s: must be one of %v", def.CloudProvider, existingNetworkProviders)
+	}
+
+	switch def.CloudProvider {
+	case "civo", "digitalocean", "vultr":
+		if len(network.SubnetIDs) != 0 {
+			return fmt.Errorf("%s networks have no subnets, existing_network.subnet_ids must be empty", def.CloudProvider)
+		}
+	}
+
+	return nil
+}
+
+// requiredNetworkAddresses is the number of free addresses an existing
+// network needs to host the cluster's nodes
+func requiredNetworkAddresses(cloudProvider string, nodeCount int) int {
+	if cloudProvider == "aws" {
+		return nodeCount * awsAddressesPerNode
+	}
+
+	return nodeCount + networkAddressHeadroom
+}
+
+// checkExistingNetwork confirms the existing network named in the definition
+// exists and has room for the requested nodes
+func checkExistingNetwork(def *pkgtypes.ClusterDefinition) error {
+	network := def.ExistingNetwork
+	if network.NetworkID == "" {
+		return nil
+	}
+
+	var available int
+	var cidrs []string
+	var err error
+
+	switch def.CloudProvider {
+	case "aws":
+		awsConf := awsinternal.AWSConfiguration{
+			Config: awsinternal.NewAwsV3(def.CloudRegion, def.AWSAuth.AccessKeyID, def.AWSAuth.SecretAccessKey, def.AWSAuth.SessionToken, def.AWSAuth.APIURL),
+		}
+		available, err = awsConf.GetVPCAvailableAddresses(network.NetworkID, network.SubnetIDs)
+	case "civo":
+		civoConf := civo.CivoConfiguration{
+			Client:  civo.NewCivo(def.CivoAuth.Token, def.CloudRegion, def.CivoAuth.APIURL),
+			Context: context.Background(),
+		}
+		var cidr string
+		cidr, err = civoConf.GetNetworkCIDR(network.NetworkID)
+		cidrs = []string{cidr}
+	case "digitalocean":
+		digitaloceanConf := digitalocean.DigitaloceanConfiguration{
+			Client:  digitalocean.NewDigitalocean(def.DigitaloceanAuth.Token, def.DigitaloceanAuth.APIURL),
+			Context: context.Background(),
+		}
+		var cidr string
+		cidr, err = digitaloceanConf.GetVPCIPRange(network.NetworkID)
+		cidrs = []string{cidr}
+	case "google":
+		googleConf := google.GoogleConfiguration{
+			Context: context.Background(),
+			Project: def.GoogleAuth.ProjectId,
+			Region:  def.CloudRegion,
+			KeyFile: def.GoogleAuth.KeyFile,
+		}
+		cidrs, err = googleConf.GetSubnetworkCIDRs(network.NetworkID, network.SubnetIDs)
+	case "vultr":
+		vultrConf := vultr.VultrConfiguration{
+			Client:  vultr.NewVultr(def.VultrAuth.Token, def.VultrAuth.APIURL),
+			Context: context.Background(),
+		}
+		var cidr string
+		cidr, err = vultrConf.GetVPCSubnet(network.NetworkID)
+		cidrs = []string{cidr}
+	default:
+		return fmt.Errorf("existing networks are not supported for cloud provider %s", def.CloudProvider)
+	}
+	if err != nil {
+		return err
+	}
+
+	// providers that only report ranges are sized by their usable addresses
+	for _, cidr := range cidrs {
+		count, err := cidrAddressCount(cidr)
+		if err != nil {
+			return err
+		}
+		available += count
+	}
+
+	required := requiredNetworkAddresses(def.CloudProvider, def.NodeCount)
+	if available < required {
+		return fmt.Errorf("existing network %s has %d available addresses but %d are required for %d nodes", network.NetworkID, available, required, def.NodeCount)
+	}
+	log.Info().Msgf("existing network %s has %d available addresses, %d required", network.NetworkID, available, required)
+
+	return nil
+}
+
+// cidrAddressCount returns the usable ipv4 addresses of a cidr, excluding the
+// network and broadcast addresses
+func cidrAddressCount(cidr string) (int, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid network range %q: %s", cidr, err)
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	if bits-ones >= 31 {
+		return 1 << 30, nil
+	}
+	count := (1 << (bits - ones)) - 2
+	if count < 0 {
+		count = 0
+	}
+
+	return count, nil
+}
+
+// ValidateExistingNetwork runs the existing network check against the
+// cluster record before the cloud terraform consumes it
+func (clctrl *ClusterController) ValidateExistingNetwork(cl *pkgtypes.Cluster) error {
+	if cl.ExistingNetwork.NetworkID == "" {
+		return nil
+	}
+
+	return checkExistingNetwork(&pkgtypes.ClusterDefinition{
+		CloudProvider:    cl.CloudProvider,
+		CloudRegion:      cl.CloudRegion,
+		NodeCount:        cl.NodeCount,
+		ExistingNetwork:  cl.ExistingNetwork,
+		AWSAuth:          cl.AWSAuth,
+		CivoAuth:         cl.CivoAuth,
+		DigitaloceanAuth: cl.DigitaloceanAuth,
+		GoogleAuth:       cl.GoogleAuth,
+		VultrAuth:        cl.VultrAuth,
+	})
+}
+
+// existingNetworkTerraformVariables are the variables
+// getExistingNetworkTerraformEnvs sets
+var existingNetworkTerraformVariables = []string{"create_network", "existing_network_id", "existing_subnet_ids"}
+
+// checkExistingNetworkTerraform makes sure the cloud terraform at
+// tfEntrypoint declares the existing network variables, a template without
+// them would ignore them and create a new network
+func checkExistingNetworkTerraform(tfEntrypoint string, network pkgtypes.ExistingNetwork) error {
+	if network.NetworkID == "" {
+		return nil
+	}
+
+	declared, err := terraformext.DeclaredVariables(tfEntrypoint)
+	if err != nil {
+		return fmt.Errorf("error reading the variables of %s: %s", tfEntrypoint, err)
+	}
+	for _, variable := range existingNetworkTerraformVariables {
+		if !declared[variable] {
+			return fmt.Errorf("existing_network is not supported by the gitops template: %s declares no %s variable", tfEntrypoint, variable)
+		}
+	}
+
+	return nil
+}
+
+// getExistingNetworkTerraformEnvs tells the cloud terraform to use the
+// existing network and subnets instead of creating its own
+func getExistingNetworkTerraformEnvs(envs map[string]string, network pkgtypes.ExistingNetwork) map[string]string {
+	if network.NetworkID == "" {
+		return envs
+	}
+
+	subnetIDs := network.SubnetIDs
+	if subnetIDs == nil {
+		subnetIDs = []string{}
+	}
+	encodedSubnetIDs, _ := json.Marshal(subnetIDs)
+
+	envs["TF_VAR_create_network"] = "false"
+	envs["TF_VAR_existing_network_id"] = network.NetworkID
+	envs["TF_VAR_existing_subnet_ids"] = string(encodedSubnetIDs)
+
+	return envs
+}
@@ -0,0 +1,51 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestCheckExistingNetworkTerraform(t *testing.T) {
+	network := pkgtypes.ExistingNetwork{NetworkID: "vpc-0123", SubnetIDs: []string{"subnet-a"}}
+	for name, test := range map[string]struct {
+		variables string
+		network   pkgtypes.ExistingNetwork
+		valid     bool
+	}{
+		"new network": {
+			variables: "",
+			valid:     true,
+		},
+		"template with the network variables": {
+			variables: "variable \"create_network\" {\n  type = bool\n}\n\nvariable \"existing_network_id\" {\n  type = string\n}\n\nvariable \"existing_subnet_ids\" {\n  type = list(string)\n}\n",
+			network:   network,
+			valid:     true,
+		},
+		"template without them": {
+			variables: "variable \"cluster_name\" {\n  type = string\n}\n",
+			network:   network,
+		},
+		"template without create_network": {
+			variables: "variable \"existing_network_id\" {}\n\nvariable \"existing_subnet_ids\" {}\n",
+			network:   network,
+		},
+	} {
+		dir := t.TempDir()
+		err := os.WriteFile(filepath.Join(dir, "variables.tf"), []byte(test.variables), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkExistingNetworkTerraform(dir, test.network); (err == nil) != test.valid {
+			t.Errorf("%s: checkExistingNetworkTerraform() = %v, want valid %v", name, err, test.valid)
+		}
+	}
+}
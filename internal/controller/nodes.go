@@ -0,0 +1,207 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+	"github.com/kubefirst/kubefirst-api/internal/k8s"
+	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// nodePoolPollInterval is how often WaitForClusterReady checks the node
+// pools after the control plane is up
+var nodePoolPollInterval = 10 * time.Second
+
+// validTaintEffects are the taint effects supported by kubernetes
+var validTaintEffects = []string{"NoSchedule", "PreferNoSchedule", "NoExecute"}
+
+// validateNodeLabels makes sure node labels are valid kubernetes labels
+func validateNodeLabels(labels map[string]string) error {
+	for key, value := range labels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid node label key %s: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("invalid node label value %s for key %s: %s", value, key, strings.Join(errs, ", "))
+		}
+	}
+
+	return nil
+}
+
+// validateNodeTaints makes sure node taints have valid keys, values and effects
+func validateNodeTaints(taints []pkgtypes.NodeTaint) error {
+	for _, taint := range taints {
+		if errs := validation.IsQualifiedName(taint.Key); len(errs) > 0 {
+			return fmt.Errorf("invalid node taint key %s: %s", taint.Key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(taint.Value); len(errs) > 0 {
+			return fmt.Errorf("invalid node taint value %s for key %s: %s", taint.Value, taint.Key, strings.Join(errs, ", "))
+		}
+
+		validEffect := false
+		for _, effect := range validTaintEffects {
+			if taint.Effect == effect {
+				validEffect = true
+				break
+			}
+		}
+		if !validEffect {
+			return fmt.Errorf("invalid node taint effect %s for key %s: must be one of %v", taint.Effect, taint.Key, validTaintEffects)
+		}
+	}
+
+	return nil
+}
+
+// validateSpotNodePool makes sure spot instances are offered by the cloud
+// provider and the pool has nodes, whether the node type is offered as spot
+// in the region is checked by the spot preflight check
+func validateSpotNodePool(def *pkgtypes.ClusterDefinition) error {
+	pool := def.SpotNodePool
+	if pool.NodeCount == 0 && pool.NodeType == "" {
+		return nil
+	}
+
+	if !pkg.FindStringInSlice(providerConfigs.SpotProviders, def.CloudProvider) {
+		return fmt.Errorf("spot node pools are not supported for %s: must be one of %v", def.CloudProvider, providerConfigs.SpotProviders)
+	}
+	if pool.NodeCount < 1 {
+		return fmt.Errorf("spot_node_pool.node_count must be at least 1")
+	}
+
+	return nil
+}
+
+// validateNodePoolAutoscaling makes sure the cloud provider can autoscale
+// node pools and each autoscaling pool's bounds hold its node count
+func validateNodePoolAutoscaling(def *pkgtypes.ClusterDefinition) error {
+	for field, pool := range map[string]struct {
+		nodeCount   int
+		autoscaling pkgtypes.NodePoolAutoscaling
+	}{
+		"autoscaling":                {nodeCount: def.NodeCount, autoscaling: def.Autoscaling},
+		"spot_node_pool.autoscaling": {nodeCount: def.SpotNodePool.NodeCount, autoscaling: def.SpotNodePool.Autoscaling},
+	} {
+		if pool.autoscaling.MinNodes == 0 && pool.autoscaling.MaxNodes == 0 {
+			continue
+		}
+
+		if !pkg.FindStringInSlice(providerConfigs.AutoscalingProviders, def.CloudProvider) {
+			return fmt.Errorf("node pool autoscaling is not supported for %s: must be one of %v", def.CloudProvider, providerConfigs.AutoscalingProviders)
+		}
+		if pool.autoscaling.MaxNodes == 0 {
+			return fmt.Errorf("%s.max_nodes is required with %s.min_nodes", field, field)
+		}
+
+		bounds := providerConfigs.ResolveNodePoolBounds("", pool.nodeCount, pool.autoscaling)
+		if bounds.MinNodes < 1 {
+			return fmt.Errorf("%s.min_nodes must be at least 1", field)
+		}
+		if bounds.MinNodes > bounds.MaxNodes {
+			return fmt.Errorf("%s.min_nodes %d must not be greater than max_nodes %d", field, bounds.MinNodes, bounds.MaxNodes)
+		}
+		if pool.nodeCount < bounds.MinNodes || pool.nodeCount > bounds.MaxNodes {
+			return fmt.Errorf("the node count %d of %s must be between min_nodes %d and max_nodes %d", pool.nodeCount, field, bounds.MinNodes, bounds.MaxNodes)
+		}
+	}
+
+	return nil
+}
+
+// nodePoolBounds returns the node counts each node pool of the definition is
+// kept between
+func (clctrl *ClusterController) nodePoolBounds() []pkgtypes.NodePoolBounds {
+	if clctrl.CloudProvider == "k3s" {
+		return nil
+	}
+
+	bounds := []pkgtypes.NodePoolBounds{providerConfigs.ResolveNodePoolBounds("default", clctrl.NodeCount, clctrl.Autoscaling)}
+	if clctrl.SpotNodePool.NodeCount > 0 {
+		bounds = append(bounds, providerConfigs.ResolveNodePoolBounds("spot", clctrl.SpotNodePool.NodeCount, clctrl.SpotNodePool.Autoscaling))
+	}
+
+	return bounds
+}
+
+// nodePool is a node pool of the definition and the nodes it should have
+type nodePool struct {
+	name  string
+	nodes int
+	// member reports whether a node belongs to the pool
+	member func(labels map[string]string) bool
+}
+
+// nodePools returns the node pools of the definition, k3s clusters are
+// built on existing servers and have none to wait for
+func (clctrl *ClusterController) nodePools() []nodePool {
+	if clctrl.CloudProvider == "k3s" {
+		return nil
+	}
+
+	isSpot := func(labels map[string]string) bool {
+		return labels[providerConfigs.SpotNodeLabelKey] == providerConfigs.SpotNodeLabelValue
+	}
+	// an autoscaling pool may already be scaling down to its minimum
+	pools := []nodePool{}
+	for _, bounds := range clctrl.nodePoolBounds() {
+		member := func(labels map[string]string) bool { return !isSpot(labels) }
+		if bounds.Pool == "spot" {
+			member = isSpot
+		}
+		pools = append(pools, nodePool{name: bounds.Pool, nodes: bounds.MinNodes, member: member})
+	}
+
+	return pools
+}
+
+// waitForNodePools waits until every node pool of the definition has its
+// node count of ready nodes, so later steps don't schedule onto a pool that
+// is still provisioning
+func (clctrl *ClusterController) waitForNodePools(operations k8s.Operations) error {
+	pools := clctrl.nodePools()
+	deadline := time.Now().Add(time.Duration(clctrl.timeouts().NodeReady) * time.Second)
+	for {
+		nodes, err := operations.NodeStatuses()
+		if err != nil {
+			return err
+		}
+
+		waiting := []string{}
+		for _, pool := range pools {
+			if pool.nodes < 1 {
+				continue
+			}
+			ready := 0
+			for _, node := range nodes {
+				if node.Ready && pool.member(node.Labels) {
+					ready++
+				}
+			}
+			if ready < pool.nodes {
+				waiting = append(waiting, fmt.Sprintf("node pool %s has %d of %d nodes ready", pool.name, ready, pool.nodes))
+			}
+		}
+
+		if len(waiting) == 0 {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("timed out waiting for node pools: %s", strings.Join(waiting, ", "))
+		}
+		for _, progress := range waiting {
+			clctrl.logger().Infof("waiting for %s", progress)
+		}
+		time.Sleep(nodePoolPollInterval)
+	}
+}
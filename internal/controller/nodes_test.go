@@ -0,0 +1,49 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"testing"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestValidateNodePoolAutoscaling(t *testing.T) {
+	for name, test := range map[string]struct {
+		def   pkgtypes.ClusterDefinition
+		valid bool
+	}{
+		"no autoscaling": {
+			def:   pkgtypes.ClusterDefinition{CloudProvider: "k3s", NodeCount: 3},
+			valid: true,
+		},
+		"bounds hold the node count": {
+			def:   pkgtypes.ClusterDefinition{CloudProvider: "aws", NodeCount: 3, Autoscaling: pkgtypes.NodePoolAutoscaling{MinNodes: 2, MaxNodes: 6}},
+			valid: true,
+		},
+		"min defaults to 1": {
+			def:   pkgtypes.ClusterDefinition{CloudProvider: "digitalocean", NodeCount: 1, Autoscaling: pkgtypes.NodePoolAutoscaling{MaxNodes: 4}},
+			valid: true,
+		},
+		"unsupported provider": {
+			def: pkgtypes.ClusterDefinition{CloudProvider: "k3s", NodeCount: 3, Autoscaling: pkgtypes.NodePoolAutoscaling{MinNodes: 1, MaxNodes: 6}},
+		},
+		"min without max": {
+			def: pkgtypes.ClusterDefinition{CloudProvider: "aws", NodeCount: 3, Autoscaling: pkgtypes.NodePoolAutoscaling{MinNodes: 2}},
+		},
+		"min greater than max": {
+			def: pkgtypes.ClusterDefinition{CloudProvider: "aws", NodeCount: 3, Autoscaling: pkgtypes.NodePoolAutoscaling{MinNodes: 5, MaxNodes: 4}},
+		},
+		"node count outside the bounds": {
+			def: pkgtypes.ClusterDefinition{CloudProvider: "google", NodeCount: 3, SpotNodePool: pkgtypes.SpotNodePool{NodeCount: 8, Autoscaling: pkgtypes.NodePoolAutoscaling{MinNodes: 1, MaxNodes: 4}}},
+		},
+	} {
+		if err := validateNodePoolAutoscaling(&test.def); (err == nil) != test.valid {
+			t.Errorf("%s: validateNodePoolAutoscaling() = %v, want valid %v", name, err, test.valid)
+		}
+	}
+}
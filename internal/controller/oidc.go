@@ -0,0 +1,75 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/kubefirst/kubefirst-api/internal/httpCommon"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// oidcSecretPath is the platform secret holding the oidc client, argocd and
+// the console read the client secret from it through external secrets
+const oidcSecretPath = "oidc"
+
+// validateOIDC checks the oidc fields of a definition without calling the
+// identity provider
+func validateOIDC(oidc pkgtypes.OIDC) error {
+	if oidc.IssuerURL == "" {
+		if oidc.ClientID != "" || oidc.ClientSecret != "" {
+			return fmt.Errorf("oidc.issuer_url is required when oidc.client_id or oidc.client_secret is set")
+		}
+		return nil
+	}
+
+	u, err := url.Parse(oidc.IssuerURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("invalid oidc.issuer_url %s: must be an https url", oidc.IssuerURL)
+	}
+	if oidc.ClientID == "" || oidc.ClientSecret == "" {
+		return fmt.Errorf("oidc.client_id and oidc.client_secret are required when oidc.issuer_url is set")
+	}
+
+	return nil
+}
+
+// preflightOIDC confirms the issuer serves an openid configuration that
+// names it as the issuer, argocd and the console reject it otherwise
+func preflightOIDC(def *pkgtypes.ClusterDefinition) error {
+	issuerURL := def.OIDC.IssuerURL
+	if issuerURL == "" {
+		return nil
+	}
+
+	discoveryURL := fmt.Sprintf("%s/.well-known/openid-configuration", strings.TrimSuffix(issuerURL, "/"))
+	res, err := httpCommon.CustomHttpClient(false).Get(discoveryURL)
+	if err != nil {
+		return fmt.Errorf("oidc issuer %s is not reachable: %s", issuerURL, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc issuer %s returned %s for %s", issuerURL, res.Status, discoveryURL)
+	}
+
+	var discovery struct {
+		Issuer string `json:"issuer"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&discovery)
+	if err != nil {
+		return fmt.Errorf("error decoding openid configuration of %s: %s", issuerURL, err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != strings.TrimSuffix(issuerURL, "/") {
+		return fmt.Errorf("oidc issuer %s identifies itself as %s, use that as oidc.issuer_url", issuerURL, discovery.Issuer)
+	}
+
+	return nil
+}
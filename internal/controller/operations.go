@@ -0,0 +1,156 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kubefirst/kubefirst-api/internal/constants"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Day-2 operation types
+const (
+	OperationClusterDelete = "cluster-delete"
+	OperationGitopsUpgrade = "gitops-upgrade"
+	OperationUserSync      = "user-sync"
+	OperationVaultSync     = "vault-sync"
+)
+
+// activeOperations holds the running operations so they can be marked failed
+// when the api shuts down before they finish
+var activeOperations = struct {
+	sync.Mutex
+	operations map[string]activeOperation
+}{operations: map[string]activeOperation{}}
+
+type activeOperation struct {
+	clctrl    *ClusterController
+	operation pkgtypes.Operation
+}
+
+// SubmitOperation records a day-2 operation of the cluster as pending and
+// runs it in the background, the record is then marked running and finally
+// succeeded with the json of run's result or failed with its error
+func (clctrl *ClusterController) SubmitOperation(operationType string, run func() (interface{}, error)) (pkgtypes.Operation, error) {
+	err := CheckWritable()
+	if err != nil {
+		return pkgtypes.Operation{}, err
+	}
+	if ShuttingDown() {
+		return pkgtypes.Operation{}, fmt.Errorf("the api is shutting down, operation %s was not submitted", operationType)
+	}
+
+	operation := pkgtypes.Operation{
+		ID:          primitive.NewObjectID().Hex(),
+		ClusterName: clctrl.ClusterName,
+		Type:        operationType,
+		Status:      constants.OperationStatusPending,
+		CreatedAt:   time.Now().UTC(),
+	}
+	err = secrets.InsertOperation(clctrl.KubernetesClient, operation)
+	if err != nil {
+		return pkgtypes.Operation{}, fmt.Errorf("error recording operation %s: %s", operationType, err)
+	}
+
+	activeOperations.Lock()
+	activeOperations.operations[operation.ID] = activeOperation{clctrl: clctrl, operation: operation}
+	activeOperations.Unlock()
+
+	go clctrl.runOperation(operation, run)
+
+	return operation, nil
+}
+
+// SubmitGitopsUpgrade submits UpgradeGitops to targetRef as an operation
+func (clctrl *ClusterController) SubmitGitopsUpgrade(targetRef string) (pkgtypes.Operation, error) {
+	return clctrl.SubmitOperation(OperationGitopsUpgrade, func() (interface{}, error) {
+		return clctrl.UpgradeGitops(targetRef, false)
+	})
+}
+
+// SubmitUserSync submits SyncDirectoryUsers as an operation
+func (clctrl *ClusterController) SubmitUserSync() (pkgtypes.Operation, error) {
+	return clctrl.SubmitOperation(OperationUserSync, func() (interface{}, error) {
+		return clctrl.SyncDirectoryUsers(false)
+	})
+}
+
+func (clctrl *ClusterController) runOperation(operation pkgtypes.Operation, run func() (interface{}, error)) {
+	startedAt := time.Now().UTC()
+	operation.Status = constants.OperationStatusRunning
+	operation.StartedAt = &startedAt
+	activeOperations.Lock()
+	activeOperations.operations[operation.ID] = activeOperation{clctrl: clctrl, operation: operation}
+	activeOperations.Unlock()
+	err := secrets.UpdateOperation(clctrl.KubernetesClient, operation)
+	if err != nil {
+		clctrl.logger().Warnf("error marking operation %s running: %s", operation.ID, err)
+	}
+	clctrl.logger().Infof("running %s operation %s", operation.Type, operation.ID)
+
+	result, err := run()
+	if err == nil && result != nil {
+		operation.Result, err = json.Marshal(result)
+	}
+
+	finishedAt := time.Now().UTC()
+	operation.FinishedAt = &finishedAt
+	if err != nil {
+		operation.Status = constants.OperationStatusFailed
+		operation.Error = err.Error()
+		clctrl.logger().Errorf("%s operation %s failed: %s", operation.Type, operation.ID, err)
+	} else {
+		operation.Status = constants.OperationStatusSucceeded
+		clctrl.logger().Infof("%s operation %s succeeded", operation.Type, operation.ID)
+	}
+
+	activeOperations.Lock()
+	defer activeOperations.Unlock()
+
+	// an operation finishing after the shutdown deadline was already marked
+	// interrupted
+	if _, ok := activeOperations.operations[operation.ID]; !ok {
+		return
+	}
+	delete(activeOperations.operations, operation.ID)
+
+	err = secrets.UpdateOperation(clctrl.KubernetesClient, operation)
+	if err != nil {
+		clctrl.logger().Errorf("error saving operation %s: %s", operation.ID, err)
+	}
+	clctrl.notifyOperation(operation)
+}
+
+// interruptOperations marks the operations still running when the api shuts
+// down as failed, since nothing will finish them
+func interruptOperations() {
+	activeOperations.Lock()
+	defer activeOperations.Unlock()
+
+	for id, active := range activeOperations.operations {
+		delete(activeOperations.operations, id)
+
+		finishedAt := time.Now().UTC()
+		operation := active.operation
+		operation.Status = constants.OperationStatusFailed
+		operation.Error = "interrupted by an api shutdown"
+		operation.FinishedAt = &finishedAt
+		err := secrets.UpdateOperation(active.clctrl.KubernetesClient, operation)
+		if err != nil {
+			active.clctrl.logger().Errorf("error marking operation %s interrupted: %s", operation.ID, err)
+			continue
+		}
+		active.clctrl.logger().Warnf("%s operation %s interrupted", operation.Type, operation.ID)
+		active.clctrl.notifyOperation(operation)
+	}
+}
@@ -0,0 +1,294 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kubefirst/kubefirst-api/internal/k8s"
+	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// useFakeOperations runs the controller's cluster operations against fake
+// for the rest of the test
+func useFakeOperations(t *testing.T, fake *fakeOperations) {
+	original := newClusterOperations
+	newClusterOperations = func(*ClusterController) (k8s.Operations, error) { return fake, nil }
+	t.Cleanup(func() { newClusterOperations = original })
+}
+
+func TestWaitForClusterReady(t *testing.T) {
+	tests := []struct {
+		cloudProvider  string
+		wantDeployment string
+	}{
+		{"aws", "kube-system/kubernetes.io/name=CoreDNS"},
+		{"civo", "kube-system/kubernetes.io/name=CoreDNS"},
+		{"google", "kube-system/k8s-app=kube-dns"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cloudProvider, func(t *testing.T) {
+			fake := &fakeOperations{}
+			useFakeOperations(t, fake)
+			clctrl := &ClusterController{ClusterName: "kf-test", CloudProvider: tt.cloudProvider}
+
+			err := clctrl.WaitForClusterReady()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(fake.Deployments) != 1 || fake.Deployments[0] != tt.wantDeployment {
+				t.Errorf("expected to wait for %s, got %v", tt.wantDeployment, fake.Deployments)
+			}
+		})
+	}
+}
+
+func TestSetKubeconfigContext(t *testing.T) {
+	for cloudProvider, wantContext := range map[string]string{
+		"civo":   "kf-test",
+		"vultr":  "kf-test",
+		"google": "admin@kf-test",
+	} {
+		kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+		config := clientcmdapi.NewConfig()
+		config.Contexts["admin@kf-test"] = &clientcmdapi.Context{Cluster: "kf-test", AuthInfo: "admin"}
+		config.CurrentContext = "admin@kf-test"
+		err := clientcmd.WriteToFile(*config, kubeconfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		clctrl := &ClusterController{
+			ClusterName:           "kf-test",
+			CloudProvider:         cloudProvider,
+			KubeconfigContextName: "kf-test",
+			ProviderConfig:        providerConfigs.ProviderConfig{Kubeconfig: kubeconfig},
+		}
+		err = clctrl.setKubeconfigContext()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", cloudProvider, err)
+		}
+
+		config, err = clientcmd.LoadFromFile(kubeconfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.CurrentContext != wantContext {
+			t.Errorf("%s: current context = %s, want %s", cloudProvider, config.CurrentContext, wantContext)
+		}
+	}
+}
+
+func TestValidateKubeconfigContextName(t *testing.T) {
+	for name, test := range map[string]struct {
+		def   pkgtypes.ClusterDefinition
+		valid bool
+	}{
+		"unset": {
+			def:   pkgtypes.ClusterDefinition{CloudProvider: "aws"},
+			valid: true,
+		},
+		"kubeconfig file": {
+			def:   pkgtypes.ClusterDefinition{CloudProvider: "digitalocean", KubeconfigContextName: "kf-test"},
+			valid: true,
+		},
+		"aws": {
+			def: pkgtypes.ClusterDefinition{CloudProvider: "aws", KubeconfigContextName: "kf-test"},
+		},
+		"google": {
+			def: pkgtypes.ClusterDefinition{CloudProvider: "google", KubeconfigContextName: "kf-test"},
+		},
+	} {
+		if err := validateKubeconfigContextName(&test.def); (err == nil) != test.valid {
+			t.Errorf("%s: validateKubeconfigContextName() = %v, want valid %v", name, err, test.valid)
+		}
+	}
+}
+
+func TestWaitForClusterReadyFailure(t *testing.T) {
+	fake := &fakeOperations{
+		DeploymentErrors: map[string]error{"CoreDNS": fmt.Errorf("timed out")},
+	}
+	useFakeOperations(t, fake)
+	clctrl := &ClusterController{ClusterName: "kf-test", CloudProvider: "aws"}
+
+	err := clctrl.WaitForClusterReady()
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected the deployment error, got %v", err)
+	}
+}
+
+func TestWaitForNodePools(t *testing.T) {
+	defer func(interval time.Duration) { nodePoolPollInterval = interval }(nodePoolPollInterval)
+	nodePoolPollInterval = 100 * time.Millisecond
+
+	spot := map[string]string{"kubefirst.io/capacity-type": "spot"}
+	fake := &fakeOperations{
+		Nodes: []k8s.NodeStatus{
+			{Name: "node-1", Ready: true},
+			{Name: "node-2", Ready: true},
+			{Name: "spot-1", Labels: spot, Ready: true},
+			{Name: "spot-2", Labels: spot},
+		},
+	}
+	useFakeOperations(t, fake)
+	clctrl := &ClusterController{
+		ClusterName:   "kf-test",
+		CloudProvider: "aws",
+		NodeCount:     2,
+		SpotNodePool:  pkgtypes.SpotNodePool{NodeCount: 2},
+		Timeouts:      pkgtypes.ProvisionTimeouts{NodeReady: 1},
+	}
+
+	err := clctrl.WaitForClusterReady()
+	if err == nil || !strings.Contains(err.Error(), "node pool spot has 1 of 2 nodes ready") {
+		t.Fatalf("expected the spot pool to be reported, got %v", err)
+	}
+	if strings.Contains(err.Error(), "node pool default") {
+		t.Errorf("expected the default pool to be ready, got %s", err)
+	}
+
+	fake.Nodes[3].Ready = true
+	err = clctrl.WaitForClusterReady()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestWaitForConsole(t *testing.T) {
+	fake := &fakeOperations{}
+	useFakeOperations(t, fake)
+	clctrl := &ClusterController{ClusterName: "kf-test", CloudProvider: "civo", DomainName: "example.com"}
+
+	err := clctrl.WaitForConsole(60)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := "kubefirst/app.kubernetes.io/name=console"
+	if len(fake.Deployments) != 1 || fake.Deployments[0] != want {
+		t.Errorf("expected to wait for %s, got %v", want, fake.Deployments)
+	}
+}
+
+func TestApplyPostInstallManifest(t *testing.T) {
+	fake := &fakeOperations{}
+	clctrl := &ClusterController{ClusterName: "kf-test"}
+
+	manifest := pkgtypes.PostInstallManifest{
+		Content: `apiVersion: v1
+kind: Namespace
+metadata:
+  name: team-a
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: team-a
+data:
+  key: value
+`,
+	}
+
+	err := clctrl.applyPostInstallManifest(fake, manifest)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(fake.Applied) != 2 {
+		t.Fatalf("expected 2 applied documents, got %d", len(fake.Applied))
+	}
+	if !strings.Contains(string(fake.Applied[0]), "kind: Namespace") || !strings.Contains(string(fake.Applied[1]), "kind: ConfigMap") {
+		t.Errorf("unexpected applied documents: %s", fake.Applied)
+	}
+}
+
+func TestOpenPortForward(t *testing.T) {
+	var portForwards []string
+	defer func(original func(*kubernetes.Clientset, *rest.Config, string, string, int, int, chan struct{})) {
+		openPortForwardPod = original
+	}(openPortForwardPod)
+	openPortForwardPod = func(_ *kubernetes.Clientset, _ *rest.Config, podName string, namespace string, podPort int, podLocalPort int, _ chan struct{}) {
+		portForwards = append(portForwards, fmt.Sprintf("%s/%s:%d->%d", namespace, podName, podLocalPort, podPort))
+	}
+	clctrl := &ClusterController{ClusterName: "kf-test"}
+
+	clctrl.OpenPortForward(&k8s.KubernetesClient{}, "vault-0", "vault", 8200, 8200)
+	defer clctrl.ClosePortForwards()
+
+	if len(portForwards) != 1 || portForwards[0] != "vault/vault-0:8200->8200" {
+		t.Errorf("expected a port-forward to vault/vault-0, got %v", portForwards)
+	}
+}
+
+// fakeOperations is a k8s.Operations that records the calls instead
+// of running them against a cluster
+type fakeOperations struct {
+	mu sync.Mutex
+
+	// DeploymentErrors fails WaitForDeployment for the matchLabelValue keys
+	DeploymentErrors map[string]error
+	// ApplyError fails every ApplyObjects call
+	ApplyError error
+	// Nodes are returned by NodeStatuses, NodesError fails it
+	Nodes      []k8s.NodeStatus
+	NodesError error
+
+	// Deployments holds the waited for deployments as namespace/label=value
+	Deployments []string
+	// PortForwards holds the forwarded pods as namespace/pod:localPort->podPort
+	PortForwards []string
+	Applied      [][]byte
+	// ApplyNamespaces holds the namespace of every ApplyObjects call
+	ApplyNamespaces []string
+}
+
+func (f *fakeOperations) WaitForDeployment(matchLabel string, matchLabelValue string, namespace string, timeoutSeconds int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Deployments = append(f.Deployments, fmt.Sprintf("%s/%s=%s", namespace, matchLabel, matchLabelValue))
+
+	return f.DeploymentErrors[matchLabelValue]
+}
+
+func (f *fakeOperations) OpenPortForward(podName string, namespace string, podPort int, localPort int, stopChannel chan struct{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.PortForwards = append(f.PortForwards, fmt.Sprintf("%s/%s:%d->%d", namespace, podName, localPort, podPort))
+}
+
+func (f *fakeOperations) ApplyObjects(namespace string, yamlData [][]byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.ApplyNamespaces = append(f.ApplyNamespaces, namespace)
+	if f.ApplyError != nil {
+		return f.ApplyError
+	}
+	f.Applied = append(f.Applied, yamlData...)
+
+	return nil
+}
+
+func (f *fakeOperations) NodeStatuses() ([]k8s.NodeStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]k8s.NodeStatus{}, f.Nodes...), f.NodesError
+}
@@ -0,0 +1,261 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	awsinternal "github.com/kubefirst/kubefirst-api/internal/aws"
+	"github.com/kubefirst/kubefirst-api/internal/civo"
+	"github.com/kubefirst/kubefirst-api/internal/constants"
+	"github.com/kubefirst/kubefirst-api/internal/digitalocean"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// orphanTagKey is the tag the cloud terraform receives with the name of the
+// cluster it provisions, so its resources can be told apart from others
+const orphanTagKey = "kubefirst-cluster"
+
+// orphanReportMaxAge is how long a dry run report can be used to delete the
+// resources it lists
+const orphanReportMaxAge = time.Hour
+
+// orphanReports are the dry run reports by id, a report is used up by the
+// deletion it confirms
+var orphanReports = struct {
+	sync.Mutex
+	reports map[string]pkgtypes.OrphanReport
+}{reports: map[string]pkgtypes.OrphanReport{}}
+
+// cloudResource is a kubefirst resource of the cloud provider account,
+// delete is nil for resources that have to be deleted by hand
+type cloudResource struct {
+	pkgtypes.OrphanedResource
+	delete func() error
+}
+
+// DetectOrphans lists the kubefirst resources of the controller's cloud
+// provider account and region that no live cluster record owns. Resources
+// are kubefirst's when they carry the kubefirst-cluster tag, their names
+// aren't considered. Only the controller's cluster store is consulted, so
+// the resources of clusters another api instance or the cli created are
+// reported as orphans too. Without a report id nothing is deleted and the
+// returned report's id confirms the deletion of its resources within the
+// hour - only resources the dry run listed that are still orphaned are
+// deleted
+func (clctrl *ClusterController) DetectOrphans(deleteReportID string) (pkgtypes.OrphanReport, error) {
+	dryRun := deleteReportID == ""
+	listed := map[string]bool{}
+	if !dryRun {
+		err := CheckWritable()
+		if err != nil {
+			return pkgtypes.OrphanReport{}, err
+		}
+
+		orphanReports.Lock()
+		dryRunReport, ok := orphanReports.reports[deleteReportID]
+		delete(orphanReports.reports, deleteReportID)
+		orphanReports.Unlock()
+		if !ok || time.Since(dryRunReport.CreatedAt) > orphanReportMaxAge {
+			return pkgtypes.OrphanReport{}, fmt.Errorf("dry run report %s not found or expired, detect orphans without a report id first", deleteReportID)
+		}
+		if dryRunReport.CloudProvider != clctrl.CloudProvider || dryRunReport.CloudRegion != clctrl.CloudRegion {
+			return pkgtypes.OrphanReport{}, fmt.Errorf("dry run report %s is for %s %s", deleteReportID, dryRunReport.CloudProvider, dryRunReport.CloudRegion)
+		}
+		for _, resource := range dryRunReport.Resources {
+			listed[resourceKey(resource)] = true
+		}
+	}
+
+	resources, err := clctrl.kubefirstCloudResources()
+	if err != nil {
+		return pkgtypes.OrphanReport{}, err
+	}
+	clusters, err := secrets.GetClusters(clctrl.KubernetesClient)
+	if err != nil {
+		return pkgtypes.OrphanReport{}, err
+	}
+
+	report := pkgtypes.OrphanReport{
+		ID:            primitive.NewObjectID().Hex(),
+		CloudProvider: clctrl.CloudProvider,
+		CloudRegion:   clctrl.CloudRegion,
+		DryRun:        dryRun,
+		CreatedAt:     time.Now().UTC(),
+		Resources:     []pkgtypes.OrphanedResource{},
+	}
+	for _, resource := range findOrphans(resources, clusters) {
+		orphan := resource.OrphanedResource
+		if !dryRun {
+			if !listed[resourceKey(orphan)] {
+				continue
+			}
+			if resource.delete == nil {
+				orphan.Error = "automatic deletion is not supported, delete it with the cloud provider"
+			} else {
+				clctrl.logger().Infof("deleting orphaned %s %s of cluster %s", orphan.Kind, orphan.Name, orphan.ClusterName)
+				err := resource.delete()
+				if err != nil {
+					orphan.Error = err.Error()
+				} else {
+					orphan.Deleted = true
+				}
+			}
+		}
+		report.Resources = append(report.Resources, orphan)
+	}
+
+	if dryRun {
+		orphanReports.Lock()
+		orphanReports.reports[report.ID] = report
+		orphanReports.Unlock()
+	}
+	clctrl.logger().Infof("found %d orphaned %s resources in %s", len(report.Resources), clctrl.CloudProvider, clctrl.CloudRegion)
+
+	return report, nil
+}
+
+// findOrphans returns the tagged resources no live cluster record owns,
+// deleted clusters don't own their resources anymore. Resources without the
+// cluster of their tag aren't kubefirst's, whatever they're named
+func findOrphans(resources []cloudResource, clusters []pkgtypes.Cluster) []cloudResource {
+	ownedClusters := map[string]bool{}
+	for _, cl := range clusters {
+		if cl.Status == constants.ClusterStatusDeleted {
+			continue
+		}
+		ownedClusters[cl.ClusterName] = true
+		for _, workloadCluster := range cl.WorkloadClusters {
+			ownedClusters[workloadCluster.ClusterName] = true
+		}
+	}
+
+	orphans := []cloudResource{}
+	for _, resource := range resources {
+		if resource.ClusterName == "" {
+			continue
+		}
+		if !ownedClusters[resource.ClusterName] {
+			orphans = append(orphans, resource)
+		}
+	}
+
+	return orphans
+}
+
+// kubefirstCloudResources lists the kubefirst resources of the controller's
+// cloud provider account and region
+func (clctrl *ClusterController) kubefirstCloudResources() ([]cloudResource, error) {
+	resources := []cloudResource{}
+
+	switch clctrl.CloudProvider {
+	case "aws":
+		awsConf := awsinternal.AWSConfiguration{
+			Config: awsinternal.NewAwsV3(clctrl.CloudRegion, clctrl.AWSAuth.AccessKeyID, clctrl.AWSAuth.SecretAccessKey, clctrl.AWSAuth.SessionToken, clctrl.AWSAuth.APIURL),
+		}
+		buckets, err := awsConf.ListTaggedBuckets(orphanTagKey)
+		if err != nil {
+			return nil, err
+		}
+		for _, bucket := range buckets {
+			name := bucket.Name
+			resources = append(resources, cloudResource{
+				OrphanedResource: pkgtypes.OrphanedResource{Kind: "bucket", ID: name, Name: name, ClusterName: bucket.Value},
+				delete:           func() error { return awsConf.DeleteBucket(name) },
+			})
+		}
+		clusters, err := awsConf.ListTaggedClusters(orphanTagKey)
+		if err != nil {
+			return nil, err
+		}
+		for _, cluster := range clusters {
+			// eks clusters have to be deleted with their node groups
+			resources = append(resources, cloudResource{
+				OrphanedResource: pkgtypes.OrphanedResource{Kind: "cluster", ID: cluster.ID, Name: cluster.Name, ClusterName: cluster.Value},
+			})
+		}
+		addresses, err := awsConf.ListTaggedAddresses(orphanTagKey)
+		if err != nil {
+			return nil, err
+		}
+		for _, address := range addresses {
+			allocationID := address.ID
+			resources = append(resources, cloudResource{
+				OrphanedResource: pkgtypes.OrphanedResource{Kind: "ip", ID: allocationID, Name: address.Name, ClusterName: address.Value},
+				delete:           func() error { return awsConf.ReleaseAddress(allocationID) },
+			})
+		}
+	case "civo":
+		civoConf := civo.CivoConfiguration{
+			Client:  civo.NewCivo(clctrl.CivoAuth.Token, clctrl.CloudRegion, clctrl.CivoAuth.APIURL),
+			Context: context.Background(),
+		}
+		// civo object stores can't be tagged
+		clusters, err := civoConf.ListKubernetesClusters()
+		if err != nil {
+			return nil, err
+		}
+		for _, cluster := range clusters {
+			if clusterName, ok := taggedClusterName(cluster.Tags); ok {
+				clusterID := cluster.ID
+				resources = append(resources, cloudResource{
+					OrphanedResource: pkgtypes.OrphanedResource{Kind: "cluster", ID: clusterID, Name: cluster.Name, ClusterName: clusterName},
+					delete:           func() error { return civoConf.DeleteKubernetesCluster(clusterID) },
+				})
+			}
+		}
+	case "digitalocean":
+		digitaloceanConf := digitalocean.DigitaloceanConfiguration{
+			Client:  digitalocean.NewDigitalocean(clctrl.DigitaloceanAuth.Token, clctrl.DigitaloceanAuth.APIURL),
+			Context: context.Background(),
+		}
+		clusters, err := digitaloceanConf.ListKubernetesClusters()
+		if err != nil {
+			return nil, err
+		}
+		for _, cluster := range clusters {
+			if cluster.RegionSlug != clctrl.CloudRegion {
+				continue
+			}
+			if clusterName, ok := taggedClusterName(cluster.Tags); ok {
+				clusterID := cluster.ID
+				resources = append(resources, cloudResource{
+					OrphanedResource: pkgtypes.OrphanedResource{Kind: "cluster", ID: clusterID, Name: cluster.Name, ClusterName: clusterName},
+					delete:           func() error { return digitaloceanConf.DeleteKubernetesCluster(clusterID) },
+				})
+			}
+		}
+	// vultr object storages can't be tagged
+	default:
+		return nil, fmt.Errorf("orphan detection is not supported for %s", clctrl.CloudProvider)
+	}
+
+	return resources, nil
+}
+
+// taggedClusterName returns the cluster named by the kubefirst-cluster tag
+// of a provider with key:value string tags
+func taggedClusterName(tags []string) (string, bool) {
+	for _, tag := range tags {
+		if clusterName, ok := strings.CutPrefix(tag, orphanTagKey+":"); ok && clusterName != "" {
+			return clusterName, true
+		}
+	}
+
+	return "", false
+}
+
+// resourceKey identifies a resource across reports
+func resourceKey(resource pkgtypes.OrphanedResource) string {
+	return resource.Kind + "/" + resource.ID
+}
@@ -0,0 +1,42 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"testing"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestFindOrphans(t *testing.T) {
+	clusters := []pkgtypes.Cluster{
+		{ClusterName: "kf-live", ClusterID: "abc123", Status: "provisioned", WorkloadClusters: []pkgtypes.WorkloadCluster{{ClusterName: "kf-workload"}}},
+		{ClusterName: "kf-gone", ClusterID: "def456", Status: "deleted"},
+	}
+
+	resources := []cloudResource{}
+	for _, r := range []pkgtypes.OrphanedResource{
+		{Kind: "bucket", ID: "1", Name: "k1-state-store-kf-live-abc123", ClusterName: "kf-live"},
+		{Kind: "bucket", ID: "2", Name: "k1-artifacts-kf-gone-def456", ClusterName: "kf-gone"},
+		// named like a kubefirst bucket without the kubefirst-cluster tag
+		{Kind: "bucket", ID: "3", Name: "k1-state-store-kf-other-zzz999"},
+		{Kind: "cluster", ID: "4", Name: "kf-live", ClusterName: "kf-live"},
+		{Kind: "cluster", ID: "5", Name: "kf-workload", ClusterName: "kf-workload"},
+		{Kind: "cluster", ID: "6", Name: "kf-gone", ClusterName: "kf-gone"},
+	} {
+		resources = append(resources, cloudResource{OrphanedResource: r})
+	}
+
+	orphans := findOrphans(resources, clusters)
+	ids := []string{}
+	for _, orphan := range orphans {
+		ids = append(ids, orphan.ID)
+	}
+	if len(ids) != 2 || ids[0] != "2" || ids[1] != "6" {
+		t.Errorf("expected the resources of the deleted cluster, got %v", ids)
+	}
+}
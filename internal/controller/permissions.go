@@ -0,0 +1,89 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// bootstrapPermissions are the cluster wide permissions the bootstrap and
+// the argocd install that follows it need, close to cluster-admin
+var bootstrapPermissions = []authorizationv1.ResourceAttributes{
+	{Verb: "get", Resource: "namespaces"},
+	{Verb: "create", Resource: "namespaces"},
+	{Verb: "get", Resource: "secrets"},
+	{Verb: "create", Resource: "secrets"},
+	{Verb: "get", Resource: "serviceaccounts"},
+	{Verb: "create", Resource: "serviceaccounts"},
+	{Verb: "create", Resource: "configmaps"},
+	{Verb: "create", Resource: "services"},
+	{Verb: "create", Group: "apps", Resource: "deployments"},
+	{Verb: "create", Group: "apps", Resource: "statefulsets"},
+	{Verb: "create", Group: "apiextensions.k8s.io", Resource: "customresourcedefinitions"},
+	{Verb: "create", Group: "rbac.authorization.k8s.io", Resource: "clusterroles"},
+	{Verb: "create", Group: "rbac.authorization.k8s.io", Resource: "clusterrolebindings"},
+	{Verb: "bind", Group: "rbac.authorization.k8s.io", Resource: "clusterroles"},
+	{Verb: "escalate", Group: "rbac.authorization.k8s.io", Resource: "clusterroles"},
+}
+
+// MissingBootstrapPermissions asks the api server with a
+// SelfSubjectAccessReview per bootstrap permission whether the kubeconfig's
+// identity holds it, and returns the ones it doesn't as "verb resource"
+func MissingBootstrapPermissions(clientset kubernetes.Interface) ([]string, error) {
+	missing := []string{}
+	for _, permission := range bootstrapPermissions {
+		attributes := permission
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &attributes,
+			},
+		}
+
+		result, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(context.Background(), review, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error reviewing access to %s: %s", permissionName(permission), err)
+		}
+		if !result.Status.Allowed {
+			missing = append(missing, permissionName(permission))
+		}
+	}
+
+	return missing, nil
+}
+
+// permissionName formats a permission like kubectl auth can-i
+func permissionName(permission authorizationv1.ResourceAttributes) string {
+	resource := permission.Resource
+	if permission.Group != "" {
+		resource = fmt.Sprintf("%s.%s", resource, permission.Group)
+	}
+
+	return fmt.Sprintf("%s %s", permission.Verb, resource)
+}
+
+// VerifyBootstrapPermissions fails with the missing permissions when the
+// kubeconfig's identity can't bootstrap the cluster, rather than letting a
+// scoped down kubeconfig fail partway through
+func (clctrl *ClusterController) VerifyBootstrapPermissions(clientset kubernetes.Interface) error {
+	missing, err := MissingBootstrapPermissions(clientset)
+	if err != nil {
+		return err
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("the kubeconfig for cluster %s lacks permissions needed to bootstrap it, cluster-admin is expected - missing: %s", clctrl.ClusterName, strings.Join(missing, ", "))
+	}
+
+	clctrl.logger().Infof("kubeconfig for cluster %s has the permissions needed to bootstrap it", clctrl.ClusterName)
+
+	return nil
+}
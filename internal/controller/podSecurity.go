@@ -0,0 +1,40 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// validatePodSecurity makes sure the cluster wide level and every namespace
+// override are pod security standard levels, and that overrides only
+// reference platform namespaces the gitops template labels
+func validatePodSecurity(podSecurity pkgtypes.PodSecurity) error {
+	if podSecurity.Level != "" && !providerConfigs.ValidPodSecurityLevel(podSecurity.Level) {
+		return fmt.Errorf("invalid pod security level %s: must be one of %v", podSecurity.Level, providerConfigs.PodSecurityLevels)
+	}
+
+	for namespace, level := range podSecurity.NamespaceOverrides {
+		if _, ok := providerConfigs.PodSecurityNamespaces[namespace]; !ok {
+			namespaces := make([]string, 0, len(providerConfigs.PodSecurityNamespaces))
+			for n := range providerConfigs.PodSecurityNamespaces {
+				namespaces = append(namespaces, n)
+			}
+			sort.Strings(namespaces)
+			return fmt.Errorf("unknown pod security namespace override %s: must be one of %v", namespace, namespaces)
+		}
+		if !providerConfigs.ValidPodSecurityLevel(level) {
+			return fmt.Errorf("invalid pod security level %s for namespace %s: must be one of %v", level, namespace, providerConfigs.PodSecurityLevels)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,326 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+	awsinternal "github.com/kubefirst/kubefirst-api/internal/aws"
+	"github.com/kubefirst/kubefirst-api/internal/civo"
+	"github.com/kubefirst/kubefirst-api/internal/digitalocean"
+	"github.com/kubefirst/kubefirst-api/internal/dnsProvider"
+	"github.com/kubefirst/kubefirst-api/internal/github"
+	"github.com/kubefirst/kubefirst-api/internal/gitlab"
+	"github.com/kubefirst/kubefirst-api/internal/objectStorage"
+	"github.com/kubefirst/kubefirst-api/internal/vultr"
+	google "github.com/kubefirst/kubefirst-api/pkg/google"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	log "github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PreflightCheck validates a cluster definition, the supplied credentials,
+// account quota and dns zone without creating a cluster record, any cloud
+// resources or dns records - checks named in skip (see pkgtypes.PreflightCheck*) are
+// reported as skipped
+func PreflightCheck(def *pkgtypes.ClusterDefinition, skip ...string) pkgtypes.PreflightReport {
+	checks := []struct {
+		name string
+		run  func(*pkgtypes.ClusterDefinition) error
+	}{
+		{pkgtypes.PreflightCheckDefinition, ValidateDefinition},
+		{pkgtypes.PreflightCheckAPIURL, preflightAPIURL},
+		{pkgtypes.PreflightCheckCredentials, preflightCredentials},
+		{pkgtypes.PreflightCheckQuota, preflightQuota},
+		{pkgtypes.PreflightCheckDNS, preflightDNS},
+		{pkgtypes.PreflightCheckNetwork, checkExistingNetwork},
+		{pkgtypes.PreflightCheckBackend, preflightTerraformBackend},
+		{pkgtypes.PreflightCheckSpot, preflightSpot},
+		{pkgtypes.PreflightCheckOIDC, preflightOIDC},
+		{pkgtypes.PreflightCheckStateStore, preflightStateStore},
+		{pkgtypes.PreflightCheckKubernetes, preflightKubernetesVersion},
+	}
+
+	report := pkgtypes.PreflightReport{Passed: true}
+	for _, check := range checks {
+		result := pkgtypes.PreflightCheckResult{Name: check.name}
+
+		if pkg.FindStringInSlice(skip, check.name) {
+			result.Skipped = true
+			report.Checks = append(report.Checks, result)
+			continue
+		}
+
+		err := check.run(def)
+		if err != nil {
+			log.Warn().Msgf("preflight check %s failed for cluster %s: %s", check.name, def.ClusterName, err)
+			result.Message = err.Error()
+			report.Passed = false
+		} else {
+			result.Passed = true
+		}
+		report.Checks = append(report.Checks, result)
+	}
+
+	return report
+}
+
+// preflightCredentials confirms the git token has the required scopes and
+// the cloud credentials can authenticate
+func preflightCredentials(def *pkgtypes.ClusterDefinition) error {
+	var missingScopes []string
+	var err error
+	switch def.GitProvider {
+	case "github":
+		if def.GitAuth.UsesGitHubApp() {
+			_, err = github.VerifyAppInstallation(def.GitAuth.AppID, def.GitAuth.AppInstallationID, def.GitAuth.AppPrivateKey, def.GitAuth.Owner)
+		} else {
+			missingScopes, err = github.GetMissingTokenScopes(def.GitAuth.Token)
+		}
+	case "gitlab":
+		missingScopes, err = gitlab.GetMissingTokenScopes(def.GitAuth.Token)
+	}
+	if err != nil {
+		return fmt.Errorf("error verifying %s token: %s", def.GitProvider, err)
+	}
+	if len(missingScopes) != 0 {
+		return fmt.Errorf("the supplied %s token is missing authorization scopes - please add: %v", def.GitProvider, missingScopes)
+	}
+
+	if def.GitProvider == "github" && def.MetaphorOwner != "" && !strings.EqualFold(def.MetaphorOwner, def.GitAuth.Owner) {
+		githubSession := github.New(def.GitAuth.Token)
+		for _, owner := range []string{def.GitAuth.Owner, def.MetaphorOwner} {
+			err = githubSession.VerifyOwnerAccess(owner)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	switch def.CloudProvider {
+	case "aws":
+		awsConf := awsinternal.AWSConfiguration{
+			Config: awsinternal.NewAwsV3(def.CloudRegion, def.AWSAuth.AccessKeyID, def.AWSAuth.SecretAccessKey, def.AWSAuth.SessionToken, def.AWSAuth.APIURL),
+		}
+		_, err = awsConf.GetCallerIdentity()
+	case "civo":
+		civoConf := civo.CivoConfiguration{
+			Client:  civo.NewCivo(def.CivoAuth.Token, def.CloudRegion, def.CivoAuth.APIURL),
+			Context: context.Background(),
+		}
+		_, err = civoConf.GetRegions(def.CloudRegion)
+	case "digitalocean":
+		digitaloceanConf := digitalocean.DigitaloceanConfiguration{
+			Client:  digitalocean.NewDigitalocean(def.DigitaloceanAuth.Token, def.DigitaloceanAuth.APIURL),
+			Context: context.Background(),
+		}
+		_, err = digitaloceanConf.GetRegions()
+	case "google":
+		googleConf := google.GoogleConfiguration{
+			Context: context.Background(),
+			Project: def.GoogleAuth.ProjectId,
+			Region:  def.CloudRegion,
+			KeyFile: def.GoogleAuth.KeyFile,
+		}
+		_, err = googleConf.GetRegions()
+	case "vultr":
+		vultrConf := vultr.VultrConfiguration{
+			Client:  vultr.NewVultr(def.VultrAuth.Token, def.VultrAuth.APIURL),
+			Context: context.Background(),
+		}
+		_, err = vultrConf.GetDNSDomains()
+	}
+	if err != nil {
+		return fmt.Errorf("error verifying %s credentials: %s", def.CloudProvider, err)
+	}
+
+	// dns can be managed by a different provider than compute, in which case
+	// its credentials are verified separately
+	if def.DnsProvider != "" && dnsProvider.Normalize(def.DnsProvider) != def.CloudProvider {
+		provider, err := dnsProvider.New(def.DnsProvider, dnsProvider.CredentialsFromDefinition(def))
+		if err != nil {
+			return err
+		}
+		return dnsProvider.VerifyCredentials(provider)
+	}
+
+	return nil
+}
+
+// preflightQuota confirms the account has room for the requested nodes
+// where the provider exposes that information
+func preflightQuota(def *pkgtypes.ClusterDefinition) error {
+	switch def.CloudProvider {
+	case "civo":
+		civoConf := civo.CivoConfiguration{
+			Client:  civo.NewCivo(def.CivoAuth.Token, def.CloudRegion, def.CivoAuth.APIURL),
+			Context: context.Background(),
+		}
+		return civoConf.CheckInstanceQuota(def.NodeCount)
+	case "vultr":
+		vultrConf := vultr.VultrConfiguration{
+			Client:  vultr.NewVultr(def.VultrAuth.Token, def.VultrAuth.APIURL),
+			Context: context.Background(),
+		}
+		return vultrConf.ValidateRegionAndInstanceType(def.CloudRegion, def.NodeType)
+	}
+
+	return nil
+}
+
+// preflightSpot confirms the spot node pool's node type is offered as a spot
+// instance in the definition's region, on google that it's offered in the
+// region at all
+func preflightSpot(def *pkgtypes.ClusterDefinition) error {
+	if def.SpotNodePool.NodeCount == 0 {
+		return nil
+	}
+
+	nodeType := def.SpotNodePool.NodeType
+	if nodeType == "" {
+		nodeType = def.NodeType
+	}
+
+	var offered bool
+	var err error
+	switch def.CloudProvider {
+	case "aws":
+		awsConf := awsinternal.AWSConfiguration{
+			Config: awsinternal.NewAwsV3(def.CloudRegion, def.AWSAuth.AccessKeyID, def.AWSAuth.SecretAccessKey, def.AWSAuth.SessionToken, def.AWSAuth.APIURL),
+		}
+		offered, err = awsConf.SpotInstanceTypeOffered(nodeType)
+	case "google":
+		googleConf := google.GoogleConfiguration{
+			Context: context.Background(),
+			Project: def.GoogleAuth.ProjectId,
+			Region:  def.CloudRegion,
+			KeyFile: def.GoogleAuth.KeyFile,
+		}
+		offered, err = googleConf.MachineTypeOfferedInRegion(nodeType, def.CloudRegion)
+	default:
+		return fmt.Errorf("spot node pools are not supported for %s", def.CloudProvider)
+	}
+	if err != nil {
+		return fmt.Errorf("error checking spot availability of %s in %s: %s", nodeType, def.CloudRegion, err)
+	}
+	if !offered {
+		return fmt.Errorf("%s is not offered as a spot instance in %s", nodeType, def.CloudRegion)
+	}
+
+	return nil
+}
+
+// lookupNS resolves the nameservers of a domain, overridden in tests
+var lookupNS = net.LookupNS
+
+// preflightDNS confirms the definition's dns provider hosts a zone for the
+// domain and the domain is delegated, it only reads - the liveness round-trip
+// writing records runs during provisioning
+func preflightDNS(def *pkgtypes.ClusterDefinition) error {
+	if def.DnsProvider == "" {
+		return nil
+	}
+
+	provider, err := newDNSProvider(def.DnsProvider, dnsProvider.CredentialsFromDefinition(def))
+	if err != nil {
+		return err
+	}
+
+	zones, err := provider.ListZones()
+	if err != nil {
+		return fmt.Errorf("error listing the %s dns zones: %s", provider.Name(), err)
+	}
+	hosted := false
+	for _, zone := range zones {
+		if strings.EqualFold(strings.TrimSuffix(zone, "."), def.DomainName) {
+			hosted = true
+			break
+		}
+	}
+	if !hosted {
+		return fmt.Errorf("%s dns has no zone for domain %s", provider.Name(), def.DomainName)
+	}
+
+	nameservers, err := lookupNS(def.DomainName)
+	if err != nil || len(nameservers) == 0 {
+		return fmt.Errorf("domain %s doesn't resolve any nameservers: %v", def.DomainName, err)
+	}
+
+	return nil
+}
+
+// preflightTerraformBackend confirms a custom terraform backend's storage can
+// be reached before any state is written to it
+func preflightTerraformBackend(def *pkgtypes.ClusterDefinition) error {
+	backend := def.TerraformBackend
+
+	switch backend.Type {
+	case "s3":
+		awsConf := awsinternal.AWSConfiguration{
+			Config: awsinternal.NewAwsV3(backend.Config["region"], def.AWSAuth.AccessKeyID, def.AWSAuth.SecretAccessKey, def.AWSAuth.SessionToken, def.AWSAuth.APIURL),
+		}
+		return awsConf.VerifyBucketAccess(backend.Config["bucket"])
+	case "gcs":
+		googleConf := google.GoogleConfiguration{
+			Context: context.Background(),
+			Project: def.GoogleAuth.ProjectId,
+			Region:  def.CloudRegion,
+			KeyFile: def.GoogleAuth.KeyFile,
+		}
+		return googleConf.VerifyBucketAccess(backend.Config["bucket"], []byte(def.GoogleAuth.KeyFile))
+	case "azurerm":
+		endpoint := fmt.Sprintf("https://%s.blob.core.windows.net", backend.Config["storage_account_name"])
+		client := http.Client{Timeout: 10 * time.Second}
+		res, err := client.Get(endpoint)
+		if err != nil {
+			return fmt.Errorf("azure storage account %s is unreachable: %s", backend.Config["storage_account_name"], err)
+		}
+		res.Body.Close()
+	}
+
+	return nil
+}
+
+// preflightStateStore runs the provisioning state store probe against an
+// existing state store bucket, new buckets are probed once they're created
+func preflightStateStore(def *pkgtypes.ClusterDefinition) error {
+	if def.ExistingStateStoreBucket == "" {
+		return nil
+	}
+
+	key := fmt.Sprintf("kubefirst-probe-%s", primitive.NewObjectID().Hex())
+
+	switch def.CloudProvider {
+	case "aws":
+		awsConf := awsinternal.AWSConfiguration{
+			Config: awsinternal.NewAwsV3(def.CloudRegion, def.AWSAuth.AccessKeyID, def.AWSAuth.SecretAccessKey, def.AWSAuth.SessionToken, def.AWSAuth.APIURL),
+		}
+		return awsConf.ProbeBucket(def.ExistingStateStoreBucket, key)
+	case "digitalocean":
+		creds := pkgtypes.StateStoreCredentials{
+			AccessKeyID:     def.DigitaloceanAuth.SpacesKey,
+			SecretAccessKey: def.DigitaloceanAuth.SpacesSecret,
+		}
+		return objectStorage.ProbeBucket(&creds, fmt.Sprintf("%s.digitaloceanspaces.com", "nyc3"), def.ExistingStateStoreBucket, key)
+	case "google":
+		googleConf := google.GoogleConfiguration{
+			Context: context.Background(),
+			Project: def.GoogleAuth.ProjectId,
+			Region:  def.CloudRegion,
+			KeyFile: def.GoogleAuth.KeyFile,
+		}
+		return googleConf.ProbeBucket(def.ExistingStateStoreBucket, key, []byte(def.GoogleAuth.KeyFile))
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/kubefirst/kubefirst-api/internal/dnsProvider"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// fakeDNSProvider serves zones and fails the test when records are written
+type fakeDNSProvider struct {
+	t        *testing.T
+	zones    []string
+	zonesErr error
+}
+
+func (p *fakeDNSProvider) Name() string { return "fake" }
+
+func (p *fakeDNSProvider) ListZones() ([]string, error) { return p.zones, p.zonesErr }
+
+func (p *fakeDNSProvider) TestDomainLiveness(domainName string) (bool, error) {
+	p.t.Errorf("the liveness round-trip writing records ran for %s", domainName)
+	return true, nil
+}
+
+func TestPreflightDNS(t *testing.T) {
+	defer func(original func(string, dnsProvider.Credentials) (dnsProvider.DNSProvider, error)) {
+		newDNSProvider = original
+	}(newDNSProvider)
+	defer func(original func(string) ([]*net.NS, error)) {
+		lookupNS = original
+	}(lookupNS)
+
+	for name, test := range map[string]struct {
+		zones       []string
+		zonesErr    error
+		nameservers []*net.NS
+		valid       bool
+	}{
+		"hosted and delegated": {
+			zones:       []string{"other.com", "Example.com."},
+			nameservers: []*net.NS{{Host: "ns1.example.net."}},
+			valid:       true,
+		},
+		"no zone": {
+			zones:       []string{"other.com"},
+			nameservers: []*net.NS{{Host: "ns1.example.net."}},
+		},
+		"zones unreadable": {
+			zonesErr: errors.New("forbidden"),
+		},
+		"not delegated": {
+			zones: []string{"example.com"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			newDNSProvider = func(name string, creds dnsProvider.Credentials) (dnsProvider.DNSProvider, error) {
+				return &fakeDNSProvider{t: t, zones: test.zones, zonesErr: test.zonesErr}, nil
+			}
+			lookupNS = func(domainName string) ([]*net.NS, error) {
+				if test.nameservers == nil {
+					return nil, errors.New("no such host")
+				}
+				return test.nameservers, nil
+			}
+
+			err := preflightDNS(&pkgtypes.ClusterDefinition{DnsProvider: "cloudflare", DomainName: "example.com"})
+			if (err == nil) != test.valid {
+				t.Errorf("preflightDNS() = %v, want valid %t", err, test.valid)
+			}
+		})
+	}
+}
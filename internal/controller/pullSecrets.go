@@ -0,0 +1,167 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+)
+
+// imagePullSecretsVaultPath is the kv path below the secret mount that
+// registry credentials are written to, one entry per pull secret
+const imagePullSecretsVaultPath = "registry-credentials"
+
+// validateImagePullSecrets makes sure every pull secret has a unique valid
+// name, a registry, credentials and at least one valid namespace
+func validateImagePullSecrets(pullSecrets []pkgtypes.ImagePullSecret) error {
+	names := map[string]bool{}
+	for _, pullSecret := range pullSecrets {
+		if errs := validation.IsDNS1123Subdomain(pullSecret.Name); len(errs) != 0 {
+			return fmt.Errorf("invalid image pull secret name %q: %s", pullSecret.Name, strings.Join(errs, ", "))
+		}
+		if names[pullSecret.Name] {
+			return fmt.Errorf("duplicate image pull secret name %s", pullSecret.Name)
+		}
+		names[pullSecret.Name] = true
+
+		if pullSecret.Server == "" {
+			return fmt.Errorf("image pull secret %s is missing the registry server", pullSecret.Name)
+		}
+		if pullSecret.Username == "" || pullSecret.Password == "" {
+			return fmt.Errorf("image pull secret %s requires a username and password", pullSecret.Name)
+		}
+		if len(pullSecret.Namespaces) == 0 {
+			return fmt.Errorf("image pull secret %s requires at least one namespace", pullSecret.Name)
+		}
+		for _, namespace := range pullSecret.Namespaces {
+			if errs := validation.IsDNS1123Label(namespace); len(errs) != 0 {
+				return fmt.Errorf("invalid namespace %q for image pull secret %s: %s", namespace, pullSecret.Name, strings.Join(errs, ", "))
+			}
+		}
+	}
+
+	return nil
+}
+
+// imagePullSecretNames returns the names of the pull secrets for
+// detokenizing into the gitops registry
+func imagePullSecretNames(pullSecrets []pkgtypes.ImagePullSecret) []string {
+	names := make([]string, 0, len(pullSecrets))
+	for _, pullSecret := range pullSecrets {
+		names = append(names, pullSecret.Name)
+	}
+
+	return names
+}
+
+// dockerConfigJSON renders the .dockerconfigjson content for a pull secret
+func dockerConfigJSON(pullSecret pkgtypes.ImagePullSecret) ([]byte, error) {
+	type dockerConfigEntry struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Email    string `json:"email,omitempty"`
+		Auth     string `json:"auth"`
+	}
+
+	return json.Marshal(map[string]map[string]dockerConfigEntry{
+		"auths": {
+			pullSecret.Server: {
+				Username: pullSecret.Username,
+				Password: pullSecret.Password,
+				Email:    pullSecret.Email,
+				Auth:     b64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", pullSecret.Username, pullSecret.Password))),
+			},
+		},
+	})
+}
+
+// CreateImagePullSecrets creates a docker config secret for every private
+// registry in each of its namespaces so platform and workload images can be
+// pulled as soon as the registry syncs
+func (clctrl *ClusterController) CreateImagePullSecrets() error {
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	if len(clctrl.ImagePullSecrets) == 0 || cl.ImagePullSecretsCheck {
+		return nil
+	}
+
+	kcfg, err := clctrl.GetClusterKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	for _, pullSecret := range clctrl.ImagePullSecrets {
+		data, err := dockerConfigJSON(pullSecret)
+		if err != nil {
+			return fmt.Errorf("error rendering image pull secret %s: %s", pullSecret.Name, err)
+		}
+
+		for _, namespace := range pullSecret.Namespaces {
+			err = ensureImagePullSecret(kcfg.Clientset, pullSecret.Name, namespace, data)
+			if err != nil {
+				return err
+			}
+		}
+		clctrl.logger().Infof("created image pull secret %s for %s", pullSecret.Name, pullSecret.Server)
+	}
+
+	clctrl.Cluster.ImagePullSecretsCheck = true
+	err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ensureImagePullSecret creates or updates a docker config secret, and its
+// namespace
+func ensureImagePullSecret(clientset *kubernetes.Clientset, name string, namespace string, dockerConfig []byte) error {
+	ctx := context.Background()
+
+	_, err := clientset.CoreV1().Namespaces().Create(ctx, &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: namespace},
+	}, metav1.CreateOptions{})
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return fmt.Errorf("error creating namespace %s: %s", namespace, err)
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Type: v1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			v1.DockerConfigJsonKey: dockerConfig,
+		},
+	}
+
+	_, err = clientset.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
+	if k8serrors.IsAlreadyExists(err) {
+		_, err = clientset.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("error creating image pull secret %s/%s: %s", namespace, name, err)
+	}
+
+	return nil
+}
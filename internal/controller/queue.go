@@ -0,0 +1,233 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/kubefirst/kubefirst-api/internal/constants"
+	"github.com/kubefirst/kubefirst-api/internal/env"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+)
+
+// ErrLeftProvisionQueue is returned by AcquireProvisionSlot when the cluster
+// was taken out of the provision queue, its record holds the reason
+var ErrLeftProvisionQueue = errors.New("cluster left the provision queue before it was provisioned")
+
+// provisionQueue limits how many clusters are provisioned at the same time,
+// the limit comes from MAX_CONCURRENT_PROVISIONS unless set with
+// SetMaxConcurrentProvisions and 0 means unlimited
+var provisionQueue = struct {
+	sync.Mutex
+	limit    int
+	limitSet bool
+	running  int
+	waiting  []*provisionWaiter
+}{}
+
+// provisionWaiter is a controller queued for a provision slot, left is
+// closed when it's taken out of the queue and done once its record says so
+type provisionWaiter struct {
+	clusterName string
+	ready       chan struct{}
+	moved       chan struct{}
+	left        chan struct{}
+	done        chan struct{}
+	reason      string
+}
+
+// SetMaxConcurrentProvisions sets how many clusters may be provisioned at the
+// same time, 0 removes the limit
+func SetMaxConcurrentProvisions(limit int) {
+	provisionQueue.Lock()
+	defer provisionQueue.Unlock()
+
+	provisionQueue.limit = limit
+	provisionQueue.limitSet = true
+	dispatchProvisions()
+}
+
+// AcquireProvisionSlot blocks until the controller may start provisioning,
+// while waiting the cluster record is marked queued with its queue position.
+// The cluster's events stay open until ReleaseProvisionSlot unless it fails
+func (clctrl *ClusterController) AcquireProvisionSlot() (err error) {
+	if ShuttingDown() {
+		return fmt.Errorf("the api is shutting down, cluster %s was not provisioned", clctrl.ClusterName)
+	}
+	clctrl.openEvents()
+	// subscribers get the done event when the cluster leaves the queue or
+	// the slot can't be taken, closing twice is a no-op
+	defer func() {
+		if err != nil {
+			clctrl.closeEvents()
+		}
+	}()
+
+	provisionQueue.Lock()
+	if !provisionQueue.limitSet {
+		env, _ := env.GetEnv(constants.SilenceGetEnv)
+		provisionQueue.limit = env.MaxConcurrentProvisions
+		provisionQueue.limitSet = true
+	}
+
+	if len(provisionQueue.waiting) == 0 && (provisionQueue.limit <= 0 || provisionQueue.running < provisionQueue.limit) {
+		provisionQueue.running++
+		provisionQueue.Unlock()
+		clctrl.holdsProvisionSlot = true
+		trackProvision(clctrl)
+		return nil
+	}
+
+	waiter := &provisionWaiter{
+		clusterName: clctrl.ClusterName,
+		ready:       make(chan struct{}),
+		moved:       make(chan struct{}, 1),
+		left:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	provisionQueue.waiting = append(provisionQueue.waiting, waiter)
+	position := len(provisionQueue.waiting)
+	provisionQueue.Unlock()
+
+	for {
+		clctrl.logger().Infof("cluster %s queued for provisioning at position %d", clctrl.ClusterName, position)
+		clctrl.Cluster.Status = constants.ClusterStatusQueued
+		clctrl.Cluster.InProgress = true
+		clctrl.Cluster.QueuePosition = position
+		err := secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
+		if err != nil {
+			clctrl.logger().Warnf("error updating queue position for cluster %s: %s", clctrl.ClusterName, err)
+		}
+
+		select {
+		case <-waiter.ready:
+			clctrl.holdsProvisionSlot = true
+			trackProvision(clctrl)
+			clctrl.Cluster.Status = constants.ClusterStatusProvisioning
+			clctrl.Cluster.QueuePosition = 0
+			err := secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
+			if err != nil {
+				// callers only release a slot they acquired
+				clctrl.ReleaseProvisionSlot()
+				return err
+			}
+			return nil
+		case <-waiter.moved:
+			provisionQueue.Lock()
+			for i, w := range provisionQueue.waiting {
+				if w == waiter {
+					position = i + 1
+				}
+			}
+			provisionQueue.Unlock()
+		case <-waiter.left:
+			clctrl.logger().Infof("cluster %s left the provision queue: %s", clctrl.ClusterName, waiter.reason)
+			clctrl.Cluster.Status = constants.ClusterStatusError
+			clctrl.Cluster.InProgress = false
+			clctrl.Cluster.QueuePosition = 0
+			clctrl.Cluster.LastCondition = waiter.reason
+			err := secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
+			if err != nil {
+				clctrl.logger().Warnf("error recording that cluster %s left the provision queue: %s", clctrl.ClusterName, err)
+			}
+			close(waiter.done)
+			return ErrLeftProvisionQueue
+		}
+	}
+}
+
+// LeaveProvisionQueue takes a queued cluster out of the provision queue so it
+// is never provisioned, e.g. because it's deleted. Its record is marked
+// failed with reason and no longer in progress before LeaveProvisionQueue
+// returns, false means the cluster wasn't queued
+func LeaveProvisionQueue(clusterName string, reason string) bool {
+	provisionQueue.Lock()
+	var waiter *provisionWaiter
+	for i, w := range provisionQueue.waiting {
+		if w.clusterName == clusterName {
+			waiter = w
+			provisionQueue.waiting = append(provisionQueue.waiting[:i], provisionQueue.waiting[i+1:]...)
+			break
+		}
+	}
+	if waiter != nil {
+		waiter.reason = reason
+		close(waiter.left)
+		notifyQueueMoved()
+	}
+	provisionQueue.Unlock()
+
+	if waiter == nil {
+		return false
+	}
+	<-waiter.done
+
+	return true
+}
+
+// emptyProvisionQueue takes every queued cluster out of the provision queue
+func emptyProvisionQueue(reason string) {
+	provisionQueue.Lock()
+	waiting := provisionQueue.waiting
+	provisionQueue.waiting = nil
+	for _, waiter := range waiting {
+		waiter.reason = reason
+		close(waiter.left)
+	}
+	provisionQueue.Unlock()
+
+	for _, waiter := range waiting {
+		<-waiter.done
+	}
+}
+
+// ReleaseProvisionSlot frees the controller's provision slot for the next
+// queued cluster
+func (clctrl *ClusterController) ReleaseProvisionSlot() {
+	clctrl.closeEvents()
+	if !clctrl.holdsProvisionSlot {
+		return
+	}
+	clctrl.holdsProvisionSlot = false
+	untrackProvision(clctrl)
+
+	provisionQueue.Lock()
+	defer provisionQueue.Unlock()
+
+	provisionQueue.running--
+	dispatchProvisions()
+}
+
+// dispatchProvisions starts queued provisions while slots are free and tells
+// the remaining waiters their position changed, provisionQueue must be locked
+func dispatchProvisions() {
+	started := false
+	for len(provisionQueue.waiting) != 0 && (provisionQueue.limit <= 0 || provisionQueue.running < provisionQueue.limit) {
+		waiter := provisionQueue.waiting[0]
+		provisionQueue.waiting = provisionQueue.waiting[1:]
+		provisionQueue.running++
+		close(waiter.ready)
+		started = true
+	}
+
+	if started {
+		notifyQueueMoved()
+	}
+}
+
+// notifyQueueMoved tells the waiters their position changed, provisionQueue
+// must be locked
+func notifyQueueMoved() {
+	for _, waiter := range provisionQueue.waiting {
+		select {
+		case waiter.moved <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,199 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kubefirst/kubefirst-api/internal/constants"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestProvisionQueue(t *testing.T) {
+	store, err := secrets.NewMemoryStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets.SetStore(store)
+	defer secrets.SetStore(nil)
+
+	SetMaxConcurrentProvisions(1)
+	defer func() {
+		provisionQueue.Lock()
+		provisionQueue.limit = 0
+		provisionQueue.limitSet = false
+		provisionQueue.Unlock()
+	}()
+
+	controllers := map[string]*ClusterController{}
+	for _, name := range []string{"kf-first", "kf-second", "kf-third"} {
+		cl := pkgtypes.Cluster{ClusterName: name, Status: constants.ClusterStatusProvisioning}
+		err := secrets.InsertCluster(nil, cl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		controllers[name] = &ClusterController{ClusterName: name, Cluster: cl}
+	}
+
+	queued := func(name string, position int) func() bool {
+		return func() bool {
+			cl, err := secrets.GetCluster(nil, name)
+			return err == nil && cl.Status == constants.ClusterStatusQueued && cl.InProgress && cl.QueuePosition == position
+		}
+	}
+	acquire := func(name string) chan error {
+		acquired := make(chan error, 1)
+		go func() { acquired <- controllers[name].AcquireProvisionSlot() }()
+		return acquired
+	}
+
+	// the only slot is free
+	err = controllers["kf-first"].AcquireProvisionSlot()
+	if err != nil {
+		t.Fatalf("kf-first: unexpected error: %s", err)
+	}
+
+	second := acquire("kf-second")
+	waitFor(t, "kf-second queued at position 1", queued("kf-second", 1))
+	third := acquire("kf-third")
+	waitFor(t, "kf-third queued at position 2", queued("kf-third", 2))
+
+	// the first queued cluster gets the freed slot, the other one moves up
+	controllers["kf-first"].ReleaseProvisionSlot()
+	select {
+	case err := <-second:
+		if err != nil {
+			t.Fatalf("kf-second: unexpected error: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("kf-second did not get the released slot")
+	}
+	select {
+	case <-third:
+		t.Fatal("kf-third started provisioning without a free slot")
+	default:
+	}
+	waitFor(t, "kf-third moved to position 1", queued("kf-third", 1))
+
+	events, unsubscribe, active := SubscribeProvisionEvents("kf-third")
+	defer unsubscribe()
+	if !active {
+		t.Fatal("expected the events of queued kf-third to be open")
+	}
+
+	if !LeaveProvisionQueue("kf-third", "deleted") {
+		t.Fatal("expected kf-third to be queued")
+	}
+	if err := <-third; !errors.Is(err, ErrLeftProvisionQueue) {
+		t.Errorf("kf-third: AcquireProvisionSlot() = %v, want %v", err, ErrLeftProvisionQueue)
+	}
+	var last pkgtypes.ProvisionEvent
+	for open := true; open; {
+		select {
+		case event, ok := <-events:
+			if ok {
+				last = event
+			}
+			open = ok
+		case <-time.After(5 * time.Second):
+			t.Fatal("kf-third: the events were not closed")
+		}
+	}
+	if last.Type != pkgtypes.ProvisionEventDone || last.Status != constants.ClusterStatusError {
+		t.Errorf("kf-third: last event = %+v, want done with the error status", last)
+	}
+	cl, err := secrets.GetCluster(nil, "kf-third")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cl.InProgress || cl.QueuePosition != 0 || cl.Status != constants.ClusterStatusError || cl.LastCondition != "deleted" {
+		t.Errorf("kf-third record = %s %v %d %q, want it out of the queue", cl.Status, cl.InProgress, cl.QueuePosition, cl.LastCondition)
+	}
+	if LeaveProvisionQueue("kf-second", "deleted") {
+		t.Error("kf-second is provisioning, it should not have been queued")
+	}
+
+	controllers["kf-second"].ReleaseProvisionSlot()
+	provisionQueue.Lock()
+	running, waiting := provisionQueue.running, len(provisionQueue.waiting)
+	provisionQueue.Unlock()
+	if running != 0 || waiting != 0 {
+		t.Errorf("queue has %d running and %d waiting, want it empty", running, waiting)
+	}
+}
+
+// waitFor polls condition until it holds or fails the test after 5 seconds
+func waitFor(t *testing.T, description string, condition func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", description)
+}
+
+func TestProvisionSlotReleasedOnUpdateFailure(t *testing.T) {
+	store, err := secrets.NewMemoryStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets.SetStore(store)
+	defer secrets.SetStore(nil)
+
+	SetMaxConcurrentProvisions(1)
+	defer func() {
+		provisionQueue.Lock()
+		provisionQueue.limit = 0
+		provisionQueue.limitSet = false
+		provisionQueue.Unlock()
+	}()
+
+	controllers := map[string]*ClusterController{}
+	for _, name := range []string{"kf-first", "kf-second"} {
+		cl := pkgtypes.Cluster{ClusterName: name, Status: constants.ClusterStatusProvisioning}
+		err := secrets.InsertCluster(nil, cl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		controllers[name] = &ClusterController{ClusterName: name, Cluster: cl}
+	}
+
+	err = controllers["kf-first"].AcquireProvisionSlot()
+	if err != nil {
+		t.Fatalf("kf-first: unexpected error: %s", err)
+	}
+	second := make(chan error, 1)
+	go func() { second <- controllers["kf-second"].AcquireProvisionSlot() }()
+	waitFor(t, "kf-second queued", func() bool {
+		cl, err := secrets.GetCluster(nil, "kf-second")
+		return err == nil && cl.Status == constants.ClusterStatusQueued
+	})
+
+	// the record can't be updated once kf-second gets the slot
+	err = secrets.DeleteCluster(nil, "kf-second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	controllers["kf-first"].ReleaseProvisionSlot()
+	if err := <-second; err == nil {
+		t.Fatal("kf-second: expected the record update to fail")
+	}
+
+	provisionQueue.Lock()
+	running, waiting := provisionQueue.running, len(provisionQueue.waiting)
+	provisionQueue.Unlock()
+	if running != 0 || waiting != 0 {
+		t.Errorf("queue has %d running and %d waiting, want the slot released", running, waiting)
+	}
+	if controllers["kf-second"].holdsProvisionSlot {
+		t.Error("kf-second still holds the provision slot")
+	}
+}
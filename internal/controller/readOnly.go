@@ -0,0 +1,60 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/kubefirst/kubefirst-api/internal/constants"
+	"github.com/kubefirst/kubefirst-api/internal/env"
+)
+
+// ErrReadOnly is returned by mutating operations when the api runs in
+// read-only mode
+var ErrReadOnly = errors.New("kubefirst-api is running in read-only mode and does not allow changes to clusters")
+
+// readOnlyMode holds whether mutating operations are refused, it comes from
+// READ_ONLY unless set with SetReadOnly
+var readOnlyMode = struct {
+	sync.Mutex
+	enabled bool
+	set     bool
+}{}
+
+// SetReadOnly turns read-only mode on or off
+func SetReadOnly(enabled bool) {
+	readOnlyMode.Lock()
+	defer readOnlyMode.Unlock()
+
+	readOnlyMode.enabled = enabled
+	readOnlyMode.set = true
+}
+
+// ReadOnly reports whether the api runs in read-only mode
+func ReadOnly() bool {
+	readOnlyMode.Lock()
+	defer readOnlyMode.Unlock()
+
+	if !readOnlyMode.set {
+		env, _ := env.GetEnv(constants.SilenceGetEnv)
+		readOnlyMode.enabled = env.ReadOnly
+		readOnlyMode.set = true
+	}
+
+	return readOnlyMode.enabled
+}
+
+// CheckWritable returns ErrReadOnly when the api runs in read-only mode, it
+// guards every operation that creates, changes or deletes cluster resources
+func CheckWritable() error {
+	if ReadOnly() {
+		return ErrReadOnly
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+
+	health "github.com/argoproj/gitops-engine/pkg/health"
+	"github.com/kubefirst/kubefirst-api/internal/constants"
+	"github.com/kubefirst/kubefirst-api/internal/k8s"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// refreshArgoCDTimeoutSeconds bounds how long RefreshStatus waits on argocd,
+// a running argocd answers immediately
+const refreshArgoCDTimeoutSeconds = 10
+
+// RefreshStatus inspects the live cluster and its components and rewrites the
+// status of the cluster record to provisioned, degraded, deleting or unknown,
+// it is the manual way to repair a record that drifted from reality
+func (clctrl *ClusterController) RefreshStatus(clusterName string) (pkgtypes.ClusterStatusRefresh, error) {
+	result := pkgtypes.ClusterStatusRefresh{ClusterName: clusterName}
+
+	if clusterName != clctrl.ClusterName {
+		return result, fmt.Errorf("controller is initialized for cluster %s, not %s", clctrl.ClusterName, clusterName)
+	}
+
+	err := CheckWritable()
+	if err != nil {
+		return result, err
+	}
+
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clusterName)
+	if err != nil {
+		return result, err
+	}
+	result.Before = cl.Status
+
+	if cl.InProgress {
+		return result, fmt.Errorf("cluster %s has an active process running, its status is not refreshed", clusterName)
+	}
+
+	result.After, result.Reasons = clctrl.liveStatus(cl)
+	result.Changed = result.After != result.Before
+	if !result.Changed {
+		clctrl.logger().Infof("cluster %s status %s matches the live cluster", clusterName, result.Before)
+		return result, nil
+	}
+
+	clctrl.logger().Infof("refreshing cluster %s status from %s to %s: %v", clusterName, result.Before, result.After, result.Reasons)
+	cl.Status = result.After
+	if result.After == constants.ClusterStatusProvisioned {
+		cl.LastCondition = ""
+	}
+	err = secrets.UpdateCluster(clctrl.KubernetesClient, cl)
+	if err != nil {
+		return result, fmt.Errorf("error updating status of cluster %s: %s", clusterName, err)
+	}
+	clctrl.Cluster = cl
+
+	return result, nil
+}
+
+// liveStatus derives the cluster status from the live cluster, a cluster that
+// is being deleted stays deleting for as long as it exists and an unreachable
+// deleted cluster stays deleted. Argo CD, the console and the services only
+// run on management clusters, a reachable workload cluster is provisioned
+func (clctrl *ClusterController) liveStatus(cl pkgtypes.Cluster) (string, []string) {
+	current := cl.Status
+	err := clctrl.VerifyConnectivity()
+	if err != nil {
+		if current == constants.ClusterStatusDeleting || current == constants.ClusterStatusDeleted {
+			return current, []string{err.Error()}
+		}
+		return constants.ClusterStatusUnknown, []string{err.Error()}
+	}
+
+	if current == constants.ClusterStatusDeleting {
+		return constants.ClusterStatusDeleting, nil
+	}
+	if cl.ClusterType != "mgmt" {
+		return constants.ClusterStatusProvisioned, nil
+	}
+
+	kcfg, err := clctrl.GetClusterKubernetesClient()
+	if err != nil {
+		return constants.ClusterStatusUnknown, []string{err.Error()}
+	}
+
+	reasons := []string{}
+	_, err = k8s.VerifyArgoCDReadiness(kcfg.Clientset, true, refreshArgoCDTimeoutSeconds)
+	if err != nil {
+		reasons = append(reasons, fmt.Sprintf("argocd is not ready: %s", err))
+	}
+
+	console, err := clctrl.ConsoleStatus()
+	switch {
+	case err != nil:
+		reasons = append(reasons, err.Error())
+	case !console.Created:
+		reasons = append(reasons, "kubefirst console is not deployed")
+	case !console.Ready:
+		reasons = append(reasons, fmt.Sprintf("kubefirst console is not ready, %d of %d replicas ready", console.ReadyReplicas, console.Replicas))
+	}
+
+	statuses, err := clctrl.ServiceStatuses()
+	if err != nil {
+		reasons = append(reasons, fmt.Sprintf("error getting service statuses: %s", err))
+	}
+	for _, status := range statuses {
+		if status.Found && status.HealthStatus != string(health.HealthStatusHealthy) {
+			reasons = append(reasons, fmt.Sprintf("service %s is %s", status.Name, status.HealthStatus))
+		}
+	}
+
+	if len(reasons) != 0 {
+		return constants.ClusterStatusDegraded, reasons
+	}
+
+	return constants.ClusterStatusProvisioned, nil
+}
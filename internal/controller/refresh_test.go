@@ -0,0 +1,56 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubefirst/kubefirst-api/internal/constants"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestRefreshStatusWorkloadCluster(t *testing.T) {
+	store, err := secrets.NewMemoryStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets.SetStore(store)
+	defer secrets.SetStore(nil)
+
+	// the api server of a workload cluster, without argocd or the console
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/version" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"gitVersion": "v1.27.0"}`))
+	}))
+	defer server.Close()
+
+	err = secrets.InsertCluster(nil, pkgtypes.Cluster{ClusterName: "kf-workload", CloudProvider: "civo", ClusterType: "workload", Status: constants.ClusterStatusError})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clctrl := &ClusterController{
+		ClusterName:    "kf-workload",
+		CloudProvider:  "civo",
+		ProviderConfig: providerConfigs.ProviderConfig{Kubeconfig: testKubeconfig(t, server.URL)},
+	}
+	result, err := clctrl.RefreshStatus("kf-workload")
+	if err != nil {
+		t.Fatalf("RefreshStatus() = %s", err)
+	}
+	if result.After != constants.ClusterStatusProvisioned || len(result.Reasons) != 0 {
+		t.Errorf("status = %s %v, want provisioned", result.After, result.Reasons)
+	}
+}
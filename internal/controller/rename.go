@@ -0,0 +1,240 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	argocdapi "github.com/argoproj/argo-cd/v2/pkg/client/clientset/versioned"
+	"github.com/go-git/go-git/v5"
+	githttps "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/kubefirst/kubefirst-api/internal/constants"
+	"github.com/kubefirst/kubefirst-api/internal/gitClient"
+	"github.com/kubefirst/kubefirst-api/internal/github"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// registryPath returns the gitops repository directory argocd syncs a
+// cluster's registry from
+func (clctrl *ClusterController) registryPath(clusterName string) string {
+	if clctrl.CloudProvider == "k3d" {
+		return fmt.Sprintf("registry/%s", clusterName)
+	}
+
+	return fmt.Sprintf("registry/clusters/%s", clusterName)
+}
+
+// RenameCluster renames the cluster record and its gitops registry directory
+// from oldName to newName, the move is committed and pushed and the argocd
+// registry Application is pointed at the new directory
+//
+// The cloud cluster and the resources terraform created keep their names, so
+// providers whose kubernetes cluster the api looks up by name can't be
+// renamed. The controller has to be initialized for oldName and should be
+// initialized again for newName afterwards.
+func (clctrl *ClusterController) RenameCluster(oldName string, newName string) error {
+	err := CheckWritable()
+	if err != nil {
+		return err
+	}
+
+	if clctrl.ClusterName != oldName {
+		return fmt.Errorf("the controller is initialized for cluster %s, not %s", clctrl.ClusterName, oldName)
+	}
+	if errs := validation.IsDNS1123Label(newName); len(errs) != 0 {
+		return fmt.Errorf("cluster name %s is not a valid dns label: %s", newName, strings.Join(errs, ", "))
+	}
+	if newName == oldName {
+		return fmt.Errorf("cluster %s already has that name", oldName)
+	}
+	_, err = secrets.GetCluster(clctrl.KubernetesClient, newName)
+	if err == nil {
+		return fmt.Errorf("a cluster named %s already exists", newName)
+	}
+
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, oldName)
+	if err != nil {
+		return err
+	}
+	switch cl.CloudProvider {
+	case "aws", "google", "k3d":
+		return fmt.Errorf("%s clusters can't be renamed, the api finds the kubernetes cluster by its name", cl.CloudProvider)
+	}
+	if cl.Status != constants.ClusterStatusProvisioned {
+		return fmt.Errorf("cluster %s is %s, only provisioned clusters can be renamed", oldName, cl.Status)
+	}
+
+	err = clctrl.VerifyConnectivity()
+	if err != nil {
+		return err
+	}
+
+	// GitHub App installation tokens expire, the stored one may be stale
+	_, err = github.RefreshGitAuth(&cl.GitAuth)
+	if err != nil {
+		return err
+	}
+
+	clctrl.logger().Infof("renaming cluster %s to %s", oldName, newName)
+
+	err = clctrl.renameRegistry(oldName, newName, cl.DefaultBranch, cl.GitAuth.User, cl.GitAuth.Token)
+	if err != nil {
+		return err
+	}
+
+	kcfg, err := clctrl.GetClusterKubernetesClient()
+	if err != nil {
+		return err
+	}
+	argocdClient, err := argocdapi.NewForConfig(kcfg.RestConfig)
+	if err != nil {
+		return err
+	}
+	registryApplication, err := argocdClient.ArgoprojV1alpha1().Applications("argocd").Get(context.Background(), "registry", metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting registry application: %s", err)
+	}
+	registryApplication.Spec.Source.Path = clctrl.registryPath(newName)
+	_, err = argocdClient.ArgoprojV1alpha1().Applications("argocd").Update(context.Background(), registryApplication, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("error updating registry application path: %s", err)
+	}
+
+	cl.ClusterName = newName
+	err = clctrl.renameClusterRecords(oldName, cl)
+	if err != nil {
+		return err
+	}
+
+	// the kubeconfig and working copies live in the cluster's k1 directory
+	newK1Dir := filepath.Join(filepath.Dir(clctrl.ProviderConfig.K1Dir), newName)
+	err = os.Rename(clctrl.ProviderConfig.K1Dir, newK1Dir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error renaming %s: %s", clctrl.ProviderConfig.K1Dir, err)
+	}
+
+	clctrl.ClusterName = newName
+	clctrl.Cluster = cl
+
+	clctrl.logger().Infof("renamed cluster %s to %s", oldName, newName)
+
+	return nil
+}
+
+// renameClusterRecords stores cl, already renamed, and moves the service list
+// and operations of oldName to it before the old record is deleted
+func (clctrl *ClusterController) renameClusterRecords(oldName string, cl pkgtypes.Cluster) error {
+	err := secrets.InsertCluster(clctrl.KubernetesClient, cl)
+	if err != nil {
+		return err
+	}
+
+	err = secrets.RenameClusterServiceList(clctrl.KubernetesClient, oldName, cl.ClusterName)
+	if err != nil {
+		return fmt.Errorf("error moving the service list of cluster %s: %s", oldName, err)
+	}
+
+	operations, err := secrets.GetOperations(clctrl.KubernetesClient, oldName)
+	if err != nil {
+		return err
+	}
+	for _, operation := range operations {
+		operation.ClusterName = cl.ClusterName
+		err = secrets.UpdateOperation(clctrl.KubernetesClient, operation)
+		if err != nil {
+			return fmt.Errorf("error moving operation %s of cluster %s: %s", operation.ID, oldName, err)
+		}
+	}
+
+	return secrets.DeleteCluster(clctrl.KubernetesClient, oldName)
+}
+
+// renameRegistry moves the cluster's registry directory in the gitops
+// repository and rewrites the references to its path, terraform is left
+// alone since its state is stored under the original name
+func (clctrl *ClusterController) renameRegistry(oldName string, newName string, defaultBranch string, gitUser string, gitToken string) error {
+	workDir, err := os.MkdirTemp("", fmt.Sprintf("gitops-rename-%s-", oldName))
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	repoURL := clctrl.ProviderConfig.DestinationGitopsRepoURL
+	repo, err := gitClient.ClonePrivateRepo(gitClient.BranchOrDefault(defaultBranch), workDir, repoURL, gitUser, gitToken)
+	if err != nil {
+		return fmt.Errorf("error cloning gitops repository %s: %s", repoURL, err)
+	}
+
+	oldPath := clctrl.registryPath(oldName)
+	newPath := clctrl.registryPath(newName)
+	err = os.Rename(filepath.Join(workDir, filepath.FromSlash(oldPath)), filepath.Join(workDir, filepath.FromSlash(newPath)))
+	if err != nil {
+		return fmt.Errorf("error moving %s: %s", oldPath, err)
+	}
+
+	// only whole names, a sibling cluster's path may start with the old one
+	reference := regexp.MustCompile(regexp.QuoteMeta(oldPath) + `([^a-z0-9-]|$)`)
+	err = filepath.Walk(workDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if fi.Name() == ".git" || fi.Name() == "terraform" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !reference.Match(content) {
+			return nil
+		}
+		return os.WriteFile(path, reference.ReplaceAll(content, []byte(newPath+"${1}")), fi.Mode())
+	})
+	if err != nil {
+		return fmt.Errorf("error rewriting references to %s: %s", oldPath, err)
+	}
+
+	// AddGlob doesn't stage the removal of the old directory
+	w, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	err = w.AddWithOptions(&git.AddOptions{All: true})
+	if err != nil {
+		return fmt.Errorf("error staging the move of %s: %s", oldPath, err)
+	}
+
+	err = gitClient.Commit(repo, fmt.Sprintf("renaming cluster %s to %s", oldName, newName))
+	if err != nil {
+		return err
+	}
+
+	err = repo.Push(&git.PushOptions{
+		RemoteName: "origin",
+		Auth: &githttps.BasicAuth{
+			Username: gitUser,
+			Password: gitToken,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error pushing gitops repository %s: %s", repoURL, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"testing"
+
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestRenameClusterRecords(t *testing.T) {
+	store, err := secrets.NewMemoryStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets.SetStore(store)
+	defer secrets.SetStore(nil)
+
+	err = secrets.InsertCluster(nil, pkgtypes.Cluster{ClusterName: "kf-old", CloudProvider: "civo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = secrets.CreateClusterServiceList(nil, "kf-old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = secrets.InsertClusterServiceListEntry(nil, "kf-old", &pkgtypes.Service{Name: "metaphor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, operation := range []pkgtypes.Operation{{ID: "op-1", ClusterName: "kf-old"}, {ID: "op-2", ClusterName: "kf-other"}} {
+		err = secrets.InsertOperation(nil, operation)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clctrl := &ClusterController{ClusterName: "kf-old", CloudProvider: "civo"}
+	err = clctrl.renameClusterRecords("kf-old", pkgtypes.Cluster{ClusterName: "kf-new", CloudProvider: "civo"})
+	if err != nil {
+		t.Fatalf("renameClusterRecords() = %s", err)
+	}
+
+	if _, err := secrets.GetCluster(nil, "kf-old"); err == nil {
+		t.Error("expected the kf-old record to be deleted")
+	}
+	if _, err := secrets.GetCluster(nil, "kf-new"); err != nil {
+		t.Errorf("expected the kf-new record: %s", err)
+	}
+
+	services, err := secrets.GetServices(nil, "kf-new")
+	if err != nil || services.ClusterName != "kf-new" || len(services.Services) != 1 || services.Services[0].Name != "metaphor" {
+		t.Errorf("kf-new services = %+v, %v, want the metaphor service", services, err)
+	}
+	if old, _ := secrets.GetServices(nil, "kf-old"); old.ClusterName != "" {
+		t.Errorf("expected the kf-old service list to be removed, got %+v", old)
+	}
+
+	operations, err := secrets.GetOperations(nil, "kf-new")
+	if err != nil || len(operations) != 1 || operations[0].ID != "op-1" {
+		t.Errorf("kf-new operations = %+v, %v, want op-1", operations, err)
+	}
+	if old, _ := secrets.GetOperations(nil, "kf-old"); len(old) != 0 {
+		t.Errorf("expected no kf-old operations, got %+v", old)
+	}
+	if other, _ := secrets.GetOperation(nil, "op-2"); other.ClusterName != "kf-other" {
+		t.Errorf("op-2 cluster = %s, want kf-other", other.ClusterName)
+	}
+}
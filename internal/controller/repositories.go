@@ -0,0 +1,142 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/kubefirst/kubefirst-api/internal/github"
+	"github.com/kubefirst/kubefirst-api/internal/gitlab"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// DeleteRepositories deletes the gitops and metaphor repositories of a
+// cluster without touching the cluster or its cloud resources. Without
+// confirm nothing is deleted and the result lists what would be, adopted
+// repositories existed before the cluster and are always kept
+func (clctrl *ClusterController) DeleteRepositories(clusterName string, confirm bool) (pkgtypes.RepositoryDeletion, error) {
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clusterName)
+	if err != nil {
+		return pkgtypes.RepositoryDeletion{}, err
+	}
+
+	// GitHub App installation tokens expire, the stored one may be stale
+	_, err = github.RefreshGitAuth(&cl.GitAuth)
+	if err != nil {
+		return pkgtypes.RepositoryDeletion{}, err
+	}
+
+	adopted := map[string]bool{}
+	for _, repositoryName := range cl.AdoptedRepositories {
+		adopted[repositoryName] = true
+	}
+
+	var repositoryExists func(owner string, name string) (bool, error)
+	var deleteRepository func(owner string, name string) error
+	switch cl.GitProvider {
+	case "github":
+		githubSession := github.New(cl.GitAuth.Token)
+		repositoryExists = func(owner string, name string) (bool, error) {
+			return githubSession.CheckRepoExists(owner, name) == http.StatusOK, nil
+		}
+		deleteRepository = func(owner string, name string) error {
+			_, err := githubSession.RemoveRepo(owner, name)
+			return err
+		}
+	case "gitlab":
+		gitlabClient, err := gitlab.NewGitLabClient(cl.GitAuth.Token, cl.GitAuth.Owner)
+		if err != nil {
+			return pkgtypes.RepositoryDeletion{}, err
+		}
+		repositoryExists = func(owner string, name string) (bool, error) {
+			return gitlabClient.CheckProjectExists(name)
+		}
+		deleteRepository = func(owner string, name string) error {
+			// A project with container registry repositories can't be deleted
+			registries, err := gitlabClient.GetProjectContainerRegistryRepositories(name)
+			if err != nil {
+				return err
+			}
+			for _, registry := range registries {
+				err := gitlabClient.DeleteContainerRegistryRepository(name, registry.ID)
+				if err != nil {
+					return err
+				}
+			}
+			return gitlabClient.DeleteProject(name)
+		}
+	default:
+		return pkgtypes.RepositoryDeletion{}, fmt.Errorf("git provider %s is not supported", cl.GitProvider)
+	}
+
+	result := pkgtypes.RepositoryDeletion{
+		Confirmed:    confirm,
+		Repositories: []string{},
+		Skipped:      map[string]string{},
+	}
+	type repository struct{ owner, name string }
+	deletions := []repository{}
+	for _, repositoryName := range []string{"gitops", metaphorRepository} {
+		owner := cl.GitAuth.Owner
+		if repositoryName == metaphorRepository && cl.GitProvider == "github" && cl.MetaphorOwner != "" {
+			owner = cl.MetaphorOwner
+		}
+		fullName := fmt.Sprintf("%s/%s", owner, repositoryName)
+
+		if adopted[repositoryName] {
+			result.Skipped[fullName] = "adopted, it existed before the cluster"
+			continue
+		}
+		exists, err := repositoryExists(owner, repositoryName)
+		if err != nil {
+			return pkgtypes.RepositoryDeletion{}, fmt.Errorf("error checking repository %s: %s", fullName, err)
+		}
+		if !exists {
+			result.Skipped[fullName] = "not found"
+			continue
+		}
+
+		result.Repositories = append(result.Repositories, fullName)
+		deletions = append(deletions, repository{owner: owner, name: repositoryName})
+	}
+	sort.Strings(result.Repositories)
+
+	if !confirm || len(deletions) == 0 {
+		return result, nil
+	}
+
+	err = CheckWritable()
+	if err != nil {
+		return pkgtypes.RepositoryDeletion{}, err
+	}
+
+	for _, r := range deletions {
+		clctrl.logger().Infof("deleting %s repository %s/%s of cluster %s", cl.GitProvider, r.owner, r.name, clusterName)
+
+		err := deleteRepository(r.owner, r.name)
+		if err != nil {
+			return pkgtypes.RepositoryDeletion{}, fmt.Errorf("error deleting repository %s/%s: %s", r.owner, r.name, err)
+		}
+
+		// Record every deletion as it happens so a failure part way is accounted for
+		cl.DeletedRepositories = append(cl.DeletedRepositories, pkgtypes.DeletedRepository{
+			Owner:     r.owner,
+			Name:      r.name,
+			DeletedAt: time.Now().UTC(),
+		})
+		err = secrets.UpdateCluster(clctrl.KubernetesClient, cl)
+		if err != nil {
+			return pkgtypes.RepositoryDeletion{}, err
+		}
+	}
+
+	return result, nil
+}
@@ -20,12 +20,17 @@ import (
 	"github.com/kubefirst/kubefirst-api/internal/vultr"
 	google "github.com/kubefirst/kubefirst-api/pkg/google"
 	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
 	"github.com/kubefirst/metrics-client/pkg/telemetry"
-	log "github.com/rs/zerolog/log"
 )
 
 // RepositoryPrep
 func (clctrl *ClusterController) RepositoryPrep() error {
+	err := clctrl.refreshGitToken()
+	if err != nil {
+		return err
+	}
+
 	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
 	if err != nil {
 		return err
@@ -39,11 +44,13 @@ func (clctrl *ClusterController) RepositoryPrep() error {
 	// TODO Implement an interface so we can call GetDomainApexContent on the clustercotroller
 
 	if !cl.GitopsReadyCheck {
-		log.Info().Msg("initializing the gitops repository - this may take several minutes")
+		clctrl.logger().Info("initializing the gitops repository - this may take several minutes")
+
+		var templateCommit string
 
 		switch clctrl.CloudProvider {
 		case "akamai":
-			err := providerConfigs.PrepareGitRepositories(
+			templateCommit, err = providerConfigs.PrepareGitRepositories(
 				clctrl.CloudProvider,
 				clctrl.GitProvider,
 				clctrl.ClusterName,
@@ -52,6 +59,7 @@ func (clctrl *ClusterController) RepositoryPrep() error {
 				clctrl.ProviderConfig.GitopsDir,
 				clctrl.GitopsTemplateBranch,
 				clctrl.GitopsTemplateURL,
+				clctrl.GitopsTemplateCommit,
 				clctrl.ProviderConfig.DestinationMetaphorRepoURL,
 				clctrl.ProviderConfig.K1Dir,
 				clctrl.CreateTokens("gitops").(*providerConfigs.GitopsDirectoryValues), //tokens created on the fly
@@ -65,7 +73,7 @@ func (clctrl *ClusterController) RepositoryPrep() error {
 				return err
 			}
 		case "aws":
-			err := providerConfigs.PrepareGitRepositories(
+			templateCommit, err = providerConfigs.PrepareGitRepositories(
 				clctrl.CloudProvider,
 				clctrl.GitProvider,
 				clctrl.ClusterName,
@@ -74,6 +82,7 @@ func (clctrl *ClusterController) RepositoryPrep() error {
 				clctrl.ProviderConfig.GitopsDir,
 				clctrl.GitopsTemplateBranch,
 				clctrl.GitopsTemplateURL,
+				clctrl.GitopsTemplateCommit,
 				clctrl.ProviderConfig.DestinationMetaphorRepoURL,
 				clctrl.ProviderConfig.K1Dir,
 				clctrl.CreateTokens("gitops").(*providerConfigs.GitopsDirectoryValues), // tokens created on the fly
@@ -87,7 +96,7 @@ func (clctrl *ClusterController) RepositoryPrep() error {
 				return err
 			}
 		case "civo":
-			err := providerConfigs.PrepareGitRepositories(
+			templateCommit, err = providerConfigs.PrepareGitRepositories(
 				clctrl.CloudProvider,
 				clctrl.GitProvider,
 				clctrl.ClusterName,
@@ -96,6 +105,7 @@ func (clctrl *ClusterController) RepositoryPrep() error {
 				clctrl.ProviderConfig.GitopsDir,
 				clctrl.GitopsTemplateBranch,
 				clctrl.GitopsTemplateURL,
+				clctrl.GitopsTemplateCommit,
 				clctrl.ProviderConfig.DestinationMetaphorRepoURL,
 				clctrl.ProviderConfig.K1Dir,
 				clctrl.CreateTokens("gitops").(*providerConfigs.GitopsDirectoryValues), // tokens created on the fly
@@ -109,7 +119,7 @@ func (clctrl *ClusterController) RepositoryPrep() error {
 				return err
 			}
 		case "google":
-			err := providerConfigs.PrepareGitRepositories(
+			templateCommit, err = providerConfigs.PrepareGitRepositories(
 				clctrl.CloudProvider,
 				clctrl.GitProvider,
 				clctrl.ClusterName,
@@ -118,6 +128,7 @@ func (clctrl *ClusterController) RepositoryPrep() error {
 				clctrl.ProviderConfig.GitopsDir,
 				clctrl.GitopsTemplateBranch,
 				clctrl.GitopsTemplateURL,
+				clctrl.GitopsTemplateCommit,
 				clctrl.ProviderConfig.DestinationMetaphorRepoURL,
 				clctrl.ProviderConfig.K1Dir,
 				clctrl.CreateTokens("gitops").(*providerConfigs.GitopsDirectoryValues), // tokens created on the fly
@@ -131,7 +142,7 @@ func (clctrl *ClusterController) RepositoryPrep() error {
 				return err
 			}
 		case "digitalocean":
-			err = providerConfigs.PrepareGitRepositories(
+			templateCommit, err = providerConfigs.PrepareGitRepositories(
 				clctrl.CloudProvider,
 				clctrl.GitProvider,
 				clctrl.ClusterName,
@@ -140,6 +151,7 @@ func (clctrl *ClusterController) RepositoryPrep() error {
 				clctrl.ProviderConfig.GitopsDir,
 				clctrl.GitopsTemplateBranch,
 				clctrl.GitopsTemplateURL,
+				clctrl.GitopsTemplateCommit,
 				clctrl.ProviderConfig.DestinationMetaphorRepoURL,
 				clctrl.ProviderConfig.K1Dir,
 				clctrl.CreateTokens("gitops").(*providerConfigs.GitopsDirectoryValues), // tokens created on the fly
@@ -153,7 +165,7 @@ func (clctrl *ClusterController) RepositoryPrep() error {
 				return err
 			}
 		case "vultr":
-			err = providerConfigs.PrepareGitRepositories(
+			templateCommit, err = providerConfigs.PrepareGitRepositories(
 				clctrl.CloudProvider,
 				clctrl.GitProvider,
 				clctrl.ClusterName,
@@ -162,6 +174,7 @@ func (clctrl *ClusterController) RepositoryPrep() error {
 				clctrl.ProviderConfig.GitopsDir,
 				clctrl.GitopsTemplateBranch,
 				clctrl.GitopsTemplateURL,
+				clctrl.GitopsTemplateCommit,
 				clctrl.ProviderConfig.DestinationMetaphorRepoURL,
 				clctrl.ProviderConfig.K1Dir,
 				clctrl.CreateTokens("gitops").(*providerConfigs.GitopsDirectoryValues), // tokens created on the fly
@@ -176,7 +189,7 @@ func (clctrl *ClusterController) RepositoryPrep() error {
 			}
 
 		case "k3s":
-			err = providerConfigs.PrepareGitRepositories(
+			templateCommit, err = providerConfigs.PrepareGitRepositories(
 				clctrl.CloudProvider,
 				clctrl.GitProvider,
 				clctrl.ClusterName,
@@ -185,6 +198,7 @@ func (clctrl *ClusterController) RepositoryPrep() error {
 				clctrl.ProviderConfig.GitopsDir,
 				clctrl.GitopsTemplateBranch,
 				clctrl.GitopsTemplateURL,
+				clctrl.GitopsTemplateCommit,
 				clctrl.ProviderConfig.DestinationMetaphorRepoURL,
 				clctrl.ProviderConfig.K1Dir,
 				clctrl.CreateTokens("gitops").(*providerConfigs.GitopsDirectoryValues), // tokens created on the fly
@@ -199,6 +213,11 @@ func (clctrl *ClusterController) RepositoryPrep() error {
 			}
 		}
 
+		err = clctrl.pruneGitTerraform()
+		if err != nil {
+			return err
+		}
+
 		if !clctrl.InstallKubefirstPro {
 			kubefirstComponentsLocation := fmt.Sprintf("%s/registry/clusters/%s/components/kubefirst", clctrl.ProviderConfig.GitopsDir, clctrl.ClusterName)
 			kubefirstRegistryLocation := fmt.Sprintf("%s/registry/clusters/%s/kubefirst.yaml", clctrl.ProviderConfig.GitopsDir, clctrl.ClusterName)
@@ -207,6 +226,7 @@ func (clctrl *ClusterController) RepositoryPrep() error {
 			os.Remove(kubefirstRegistryLocation)
 		}
 
+		clctrl.Cluster.GitopsTemplateResolvedCommit = templateCommit
 		clctrl.Cluster.GitopsReadyCheck = true
 		err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
 
@@ -214,7 +234,7 @@ func (clctrl *ClusterController) RepositoryPrep() error {
 			return err
 		}
 
-		log.Info().Msg("gitops repository initialized")
+		clctrl.logger().Info("gitops repository initialized")
 	}
 
 	return nil
@@ -222,6 +242,11 @@ func (clctrl *ClusterController) RepositoryPrep() error {
 
 // RepositoryPush
 func (clctrl *ClusterController) RepositoryPush() error {
+	err := clctrl.refreshGitToken()
+	if err != nil {
+		return err
+	}
+
 	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
 	if err != nil {
 		return err
@@ -232,15 +257,22 @@ func (clctrl *ClusterController) RepositoryPush() error {
 		gitopsDir := clctrl.ProviderConfig.GitopsDir
 		metaphorDir := clctrl.ProviderConfig.MetaphorDir
 
-		telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.GitopsRepoPushStarted, "")
+		clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.GitopsRepoPushStarted, "")
+
+		err = clctrl.validateGitopsManifests(cl.SkipManifestValidation, cl.FeatureEnabled(pkgtypes.FeatureGateHelmTemplateValidation))
+		if err != nil {
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.GitopsRepoPushFailed, err.Error())
+			return err
+		}
+
 		gitopsRepo, err := git.PlainOpen(gitopsDir)
 		if err != nil {
-			log.Info().Msgf("error opening repo at: %s", gitopsDir)
+			clctrl.logger().Infof("error opening repo at: %s", gitopsDir)
 		}
 
 		metaphorRepo, err := git.PlainOpen(metaphorDir)
 		if err != nil {
-			log.Info().Msgf("error opening repo at: %s", metaphorDir)
+			clctrl.logger().Infof("error opening repo at: %s", metaphorDir)
 		}
 
 		// For GitLab, we currently need to add an ssh key to the authenticating user
@@ -252,7 +284,7 @@ func (clctrl *ClusterController) RepositoryPush() error {
 
 			keys, err := gitlabClient.GetUserSSHKeys()
 			if err != nil {
-				log.Error().Msgf("unable to check for ssh keys in gitlab: %s", err.Error())
+				clctrl.logger().Errorf("unable to check for ssh keys in gitlab: %s", err.Error())
 			}
 
 			keyName := "kbot-ssh-key"
@@ -260,18 +292,18 @@ func (clctrl *ClusterController) RepositoryPush() error {
 			for _, key := range keys {
 				if key.Title == keyName {
 					if strings.Contains(key.Key, strings.TrimSuffix(clctrl.GitAuth.PublicKey, "\n")) {
-						log.Info().Msgf("ssh key %s already exists and key is up to date, continuing", keyName)
+						clctrl.logger().Infof("ssh key %s already exists and key is up to date, continuing", keyName)
 						keyFound = true
 					} else {
-						log.Error().Msgf("ssh key %s already exists and key data has drifted - please remove before continuing", keyName)
+						clctrl.logger().Errorf("ssh key %s already exists and key data has drifted - please remove before continuing", keyName)
 					}
 				}
 			}
 			if !keyFound {
-				log.Info().Msgf("creating ssh key %s...", keyName)
+				clctrl.logger().Infof("creating ssh key %s...", keyName)
 				err := gitlabClient.AddUserSSHKey(keyName, clctrl.GitAuth.PublicKey)
 				if err != nil {
-					log.Error().Msgf("error adding ssh key %s: %s", keyName, err.Error())
+					clctrl.logger().Errorf("error adding ssh key %s: %s", keyName, err.Error())
 				}
 			}
 		}
@@ -284,34 +316,49 @@ func (clctrl *ClusterController) RepositoryPush() error {
 					Username: clctrl.GitAuth.User,
 					Password: clctrl.GitAuth.Token,
 				},
+				Force: clctrl.forcePushRepository(cl.AdoptedRepositories, "gitops"),
 			},
 		)
 		if err != nil {
 			msg := fmt.Sprintf("error pushing detokenized gitops repository to remote %s: %s", clctrl.ProviderConfig.DestinationGitopsRepoURL, err)
-			telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.GitopsRepoPushFailed, err.Error())
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.GitopsRepoPushFailed, err.Error())
 			return fmt.Errorf(msg)
 		}
 
-		// push metaphor repo to remote
-		err = metaphorRepo.Push(
-			&git.PushOptions{
-				RemoteName: "origin",
-				Auth: &githttps.BasicAuth{
-					Username: clctrl.GitAuth.User,
-					Password: clctrl.GitAuth.Token,
+		// push metaphor repo to remote, the install profile may leave it out
+		if clctrl.installsMetaphor() {
+			err = metaphorRepo.Push(
+				&git.PushOptions{
+					RemoteName: "origin",
+					Auth: &githttps.BasicAuth{
+						Username: clctrl.GitAuth.User,
+						Password: clctrl.GitAuth.Token,
+					},
+					Force: clctrl.forcePushRepository(cl.AdoptedRepositories, "metaphor"),
 				},
-			},
-		)
-		if err != nil {
-			msg := fmt.Sprintf("error pushing detokenized metaphor repository to remote %s: %s", clctrl.ProviderConfig.DestinationMetaphorRepoURL, err)
-			telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.GitopsRepoPushFailed, err.Error())
-			return fmt.Errorf(msg)
+			)
+			if err != nil {
+				msg := fmt.Sprintf("error pushing detokenized metaphor repository to remote %s: %s", clctrl.ProviderConfig.DestinationMetaphorRepoURL, err)
+				clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.GitopsRepoPushFailed, err.Error())
+				return fmt.Errorf(msg)
+			}
+		}
+
+		if !clctrl.installsMetaphor() {
+			clctrl.logger().Infof("successfully pushed gitops repository to git@%s/%s", clctrl.GitHost, clctrl.GitAuth.Owner)
+		} else if clctrl.separateMetaphorOwner() {
+			clctrl.logger().Infof("successfully pushed gitops repository to git@%s/%s and metaphor repository to git@%s/%s", clctrl.GitHost, clctrl.GitAuth.Owner, clctrl.GitHost, clctrl.MetaphorOwner)
+		} else {
+			clctrl.logger().Infof("successfully pushed gitops and metaphor repositories to git@%s/%s", clctrl.GitHost, clctrl.GitAuth.Owner)
+		}
+
+		if len(clctrl.GitopsMirrors) != 0 {
+			clctrl.Cluster.GitopsMirrorStatuses = clctrl.pushGitopsMirrors(gitopsRepo)
 		}
 
-		log.Info().Msgf("successfully pushed gitops and metaphor repositories to git@%s/%s", clctrl.GitHost, clctrl.GitAuth.Owner)
 		// todo delete the local gitops repo and re-clone it
 		// todo that way we can stop worrying about which origin we're going to push to
-		telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.GitopsRepoPushCompleted, "")
+		clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.GitopsRepoPushCompleted, "")
 
 		clctrl.Cluster.GitopsPushedCheck = true
 		err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
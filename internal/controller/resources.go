@@ -0,0 +1,90 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// validateResourceOverrides makes sure the resource profile exists, overrides
+// only reference known components and that every request and limit, once
+// merged with the profile, is a valid quantity with the request not above
+// the limit
+func validateResourceOverrides(profile string, overrides map[string]pkgtypes.ComponentResources) error {
+	if profile != "" {
+		if _, ok := providerConfigs.ResourceProfiles[profile]; !ok {
+			profiles := make([]string, 0, len(providerConfigs.ResourceProfiles))
+			for p := range providerConfigs.ResourceProfiles {
+				profiles = append(profiles, p)
+			}
+			sort.Strings(profiles)
+			return fmt.Errorf("unknown resource profile %s: must be one of %v", profile, profiles)
+		}
+	}
+
+	for component := range overrides {
+		if _, ok := providerConfigs.ResourceOverrideComponents[component]; !ok {
+			components := make([]string, 0, len(providerConfigs.ResourceOverrideComponents))
+			for c := range providerConfigs.ResourceOverrideComponents {
+				components = append(components, c)
+			}
+			sort.Strings(components)
+			return fmt.Errorf("unknown resource override component %s: must be one of %v", component, components)
+		}
+	}
+
+	for component, resources := range providerConfigs.ResolveResources(profile, overrides) {
+		err := validateRequestLimit(component, "cpu", resources.CPURequest, resources.CPULimit)
+		if err != nil {
+			return err
+		}
+		err = validateRequestLimit(component, "memory", resources.MemoryRequest, resources.MemoryLimit)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateRequestLimit parses a request/limit pair, either of which may be
+// empty
+func validateRequestLimit(component, kind, request, limit string) error {
+	var requestQuantity, limitQuantity resource.Quantity
+	var err error
+
+	if request != "" {
+		requestQuantity, err = resource.ParseQuantity(request)
+		if err != nil {
+			return fmt.Errorf("invalid %s request %q for %s: %s", kind, request, component, err)
+		}
+		if requestQuantity.Sign() <= 0 {
+			return fmt.Errorf("invalid %s request %q for %s: must be greater than zero", kind, request, component)
+		}
+	}
+
+	if limit != "" {
+		limitQuantity, err = resource.ParseQuantity(limit)
+		if err != nil {
+			return fmt.Errorf("invalid %s limit %q for %s: %s", kind, limit, component, err)
+		}
+		if limitQuantity.Sign() <= 0 {
+			return fmt.Errorf("invalid %s limit %q for %s: must be greater than zero", kind, limit, component)
+		}
+	}
+
+	if request != "" && limit != "" && requestQuantity.Cmp(limitQuantity) > 0 {
+		return fmt.Errorf("%s request %s for %s is greater than its limit %s", kind, request, component, limit)
+	}
+
+	return nil
+}
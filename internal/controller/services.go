@@ -0,0 +1,215 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	synccommon "github.com/argoproj/gitops-engine/pkg/sync/common"
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+	"github.com/kubefirst/kubefirst-api/internal/argocd"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	"github.com/kubefirst/kubefirst-api/internal/services"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// AddService adds a gitops catalog app to the cluster as a service, the app
+// is committed to the gitops registry so argocd reconciles it and recorded in
+// the cluster's service list
+func (clctrl *ClusterController) AddService(def pkgtypes.ServiceDefinition) error {
+	if errs := validation.IsDNS1123Label(def.Name); len(errs) != 0 {
+		return fmt.Errorf("invalid service name %q: %s", def.Name, strings.Join(errs, ", "))
+	}
+
+	err := CheckWritable()
+	if err != nil {
+		return err
+	}
+
+	err = clctrl.VerifyConnectivity()
+	if err != nil {
+		return err
+	}
+
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	apps, err := secrets.GetGitopsCatalogApps(clctrl.KubernetesClient)
+	if err != nil {
+		return err
+	}
+
+	appDef, err := services.FindCatalogApp(apps, def.Name)
+	if err != nil {
+		return err
+	}
+
+	if pkg.FindStringInSlice(appDef.CloudDenylist, cl.CloudProvider) {
+		return fmt.Errorf("service %s is not supported on %s", def.Name, cl.CloudProvider)
+	}
+	if pkg.FindStringInSlice(appDef.GitDenylist, cl.GitProvider) {
+		return fmt.Errorf("service %s is not supported with %s", def.Name, cl.GitProvider)
+	}
+
+	err = services.ValidateServiceRequest(&appDef, &def.GitopsCatalogAppCreateRequest)
+	if err != nil {
+		return err
+	}
+
+	targetCluster := clctrl.ClusterName
+	if def.WorkloadClusterName != "" {
+		targetCluster = def.WorkloadClusterName
+	}
+
+	existingServices, err := secrets.GetServices(clctrl.KubernetesClient, targetCluster)
+	if err == nil {
+		for _, svc := range existingServices.Services {
+			if svc.Name == def.Name {
+				return fmt.Errorf("service %s already exists on cluster %s", def.Name, targetCluster)
+			}
+		}
+	}
+
+	if def.User == "" {
+		def.User = "kbot"
+	}
+
+	clctrl.logger().Infof("adding service %s to cluster %s", def.Name, targetCluster)
+
+	return services.CreateService(&cl, def.Name, &appDef, &def.GitopsCatalogAppCreateRequest, false)
+}
+
+// RemoveService removes a service added from the gitops catalog, deleting it
+// from the gitops registry and the cluster's service list - default services
+// cannot be removed
+func (clctrl *ClusterController) RemoveService(serviceName string, def pkgtypes.GitopsCatalogAppDeleteRequest) error {
+	err := CheckWritable()
+	if err != nil {
+		return err
+	}
+
+	err = clctrl.VerifyConnectivity()
+	if err != nil {
+		return err
+	}
+
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	targetCluster := clctrl.ClusterName
+	if def.WorkloadClusterName != "" {
+		targetCluster = def.WorkloadClusterName
+	}
+
+	svc, err := secrets.GetService(clctrl.KubernetesClient, targetCluster, serviceName)
+	if err != nil {
+		return err
+	}
+	if svc.Default {
+		return fmt.Errorf("service %s is a default service and cannot be removed", serviceName)
+	}
+
+	if def.User == "" {
+		def.User = "kbot"
+	}
+
+	clctrl.logger().Infof("removing service %s from cluster %s", serviceName, targetCluster)
+
+	return services.DeleteService(&cl, serviceName, def)
+}
+
+// ListServices returns the services recorded for the cluster
+func (clctrl *ClusterController) ListServices() ([]pkgtypes.Service, error) {
+	clusterServices, err := secrets.GetServices(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	return clusterServices.Services, nil
+}
+
+// ServiceStatus returns the argocd sync and health status of a service, a
+// service without an argocd application, e.g. a default service, is reported
+// as not found
+func (clctrl *ClusterController) ServiceStatus(serviceName string) (pkgtypes.ServiceStatus, error) {
+	status := pkgtypes.ServiceStatus{Name: serviceName}
+
+	_, err := secrets.GetService(clctrl.KubernetesClient, clctrl.ClusterName, serviceName)
+	if err != nil {
+		return status, err
+	}
+
+	kcfg, err := clctrl.GetClusterKubernetesClient()
+	if err != nil {
+		return status, err
+	}
+
+	app, err := argocd.GetApplication(kcfg.Clientset, serviceName)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return status, nil
+		}
+		return status, fmt.Errorf("error getting argocd application %s: %s", serviceName, err)
+	}
+
+	status.Found = true
+	status.SyncStatus = string(app.Status.Sync.Status)
+	status.HealthStatus = string(app.Status.Health.Status)
+	status.HealthMessage = app.Status.Health.Message
+	status.Revision = app.Status.Sync.Revision
+
+	for _, condition := range app.Status.Conditions {
+		if condition.IsError() {
+			status.SyncErrors = append(status.SyncErrors, fmt.Sprintf("%s: %s", condition.Type, condition.Message))
+		}
+	}
+
+	if op := app.Status.OperationState; op != nil {
+		if op.FinishedAt != nil {
+			finishedAt := op.FinishedAt.Time
+			status.LastSyncedAt = &finishedAt
+		}
+		if op.Phase == synccommon.OperationFailed || op.Phase == synccommon.OperationError {
+			status.SyncErrors = append(status.SyncErrors, op.Message)
+		}
+		if op.SyncResult != nil {
+			for _, resource := range op.SyncResult.Resources {
+				if resource.Status == synccommon.ResultCodeSyncFailed {
+					status.SyncErrors = append(status.SyncErrors, fmt.Sprintf("%s/%s: %s", resource.Kind, resource.Name, resource.Message))
+				}
+			}
+		}
+	}
+
+	return status, nil
+}
+
+// ServiceStatuses returns the status of every service recorded for the cluster
+func (clctrl *ClusterController) ServiceStatuses() ([]pkgtypes.ServiceStatus, error) {
+	clusterServices, err := clctrl.ListServices()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]pkgtypes.ServiceStatus, 0, len(clusterServices))
+	for _, svc := range clusterServices {
+		status, err := clctrl.ServiceStatus(svc.Name)
+		if err != nil {
+			return statuses, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
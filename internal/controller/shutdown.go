@@ -0,0 +1,152 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kubefirst/kubefirst-api/internal/constants"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	log "github.com/rs/zerolog/log"
+)
+
+// shutdownPollInterval is how often Shutdown checks whether in-progress
+// provisions have finished
+const shutdownPollInterval = time.Second
+
+// activeProvisions tracks the controllers holding a provision slot so they
+// can be checkpointed when the api shuts down
+var activeProvisions = struct {
+	sync.Mutex
+	shuttingDown bool
+	controllers  map[*ClusterController]struct{}
+}{controllers: map[*ClusterController]struct{}{}}
+
+// trackProvision registers a controller that started provisioning, clearing
+// the interruption or pause of a resumed install
+func trackProvision(clctrl *ClusterController) {
+	activeProvisions.Lock()
+	activeProvisions.controllers[clctrl] = struct{}{}
+	activeProvisions.Unlock()
+
+	if clctrl.Cluster.Status == constants.ClusterStatusInterrupted {
+		clctrl.logger().Infof("resuming cluster %s interrupted during %s", clctrl.ClusterName, clctrl.Cluster.InterruptedStep)
+		clctrl.Cluster.Status = constants.ClusterStatusProvisioning
+		clctrl.Cluster.InterruptedStep = ""
+		clctrl.Cluster.LastCondition = ""
+		err := secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
+		if err != nil {
+			clctrl.logger().Warnf("error clearing interruption of cluster %s: %s", clctrl.ClusterName, err)
+		}
+	}
+	if clctrl.Cluster.Status == constants.ClusterStatusPaused {
+		clctrl.logger().Infof("continuing cluster %s paused after %s", clctrl.ClusterName, clctrl.Cluster.PausedAfterStep)
+		clctrl.Cluster.Status = constants.ClusterStatusProvisioning
+		clctrl.Cluster.PausedAfterStep = ""
+		clctrl.Cluster.LastCondition = ""
+		err := secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
+		if err != nil {
+			clctrl.logger().Warnf("error clearing pause of cluster %s: %s", clctrl.ClusterName, err)
+		}
+	}
+}
+
+// untrackProvision removes a controller that stopped provisioning
+func untrackProvision(clctrl *ClusterController) {
+	activeProvisions.Lock()
+	defer activeProvisions.Unlock()
+
+	delete(activeProvisions.controllers, clctrl)
+}
+
+// ShuttingDown reports whether Shutdown was called, new provisions are
+// refused from then on
+func ShuttingDown() bool {
+	activeProvisions.Lock()
+	defer activeProvisions.Unlock()
+
+	return activeProvisions.shuttingDown
+}
+
+// Shutdown stops new provisions and operations, takes queued clusters out of
+// the provision queue and gives in-progress ones up to gracePeriod to
+// finish - provisions still running afterwards have
+// their current step recorded and their record marked interrupted so a later
+// create resumes them, operations are marked failed
+func Shutdown(gracePeriod time.Duration) {
+	activeProvisions.Lock()
+	activeProvisions.shuttingDown = true
+	activeProvisions.Unlock()
+
+	// queued clusters would only start to be interrupted, they're failed
+	// right away and a later create queues them again
+	emptyProvisionQueue("the api shut down while the cluster was queued for provisioning, create the cluster again")
+
+	deadline := time.Now().Add(gracePeriod)
+	for {
+		activeProvisions.Lock()
+		remaining := len(activeProvisions.controllers)
+		activeProvisions.Unlock()
+		activeOperations.Lock()
+		remainingOperations := len(activeOperations.operations)
+		activeOperations.Unlock()
+
+		if remaining == 0 && remainingOperations == 0 {
+			log.Info().Msg("no provisions or operations in progress, shutting down")
+			return
+		}
+		if !time.Now().Before(deadline) {
+			break
+		}
+		log.Info().Msgf("waiting for %d in-progress provisions and %d operations before shutting down", remaining, remainingOperations)
+		time.Sleep(shutdownPollInterval)
+	}
+
+	interruptOperations()
+
+	activeProvisions.Lock()
+	defer activeProvisions.Unlock()
+
+	for clctrl := range activeProvisions.controllers {
+		err := clctrl.checkpointInterrupted()
+		if err != nil {
+			clctrl.logger().Errorf("error checkpointing cluster %s: %s", clctrl.ClusterName, err)
+		}
+	}
+}
+
+// checkpointInterrupted records the first incomplete step of the cluster and
+// marks its record interrupted
+func (clctrl *ClusterController) checkpointInterrupted() error {
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	step := "unknown"
+	for _, s := range clusterStepTimeline(cl) {
+		if !s.Completed {
+			step = s.Step
+			break
+		}
+	}
+
+	cl.Status = constants.ClusterStatusInterrupted
+	cl.InProgress = false
+	cl.InterruptedStep = step
+	cl.LastCondition = fmt.Sprintf("provisioning was interrupted by an api shutdown during %s, create the cluster again to resume", step)
+
+	err = secrets.UpdateCluster(clctrl.KubernetesClient, cl)
+	if err != nil {
+		return err
+	}
+	clctrl.logger().Warnf("cluster %s interrupted during %s", clctrl.ClusterName, step)
+
+	return nil
+}
@@ -9,19 +9,17 @@ package controller
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"strings"
 
 	"github.com/kubefirst/kubefirst-api/internal/civo"
 	"github.com/kubefirst/kubefirst-api/internal/digitalocean"
+	"github.com/kubefirst/kubefirst-api/internal/objectStorage"
 	"github.com/kubefirst/kubefirst-api/internal/secrets"
 	"github.com/kubefirst/kubefirst-api/internal/vultr"
 	"github.com/kubefirst/kubefirst-api/pkg/akamai"
 	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
 	"github.com/kubefirst/metrics-client/pkg/telemetry"
-	"github.com/linode/linodego"
-	log "github.com/rs/zerolog/log"
-	"golang.org/x/oauth2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // StateStoreCredentials
@@ -33,21 +31,54 @@ func (clctrl *ClusterController) StateStoreCredentials() error {
 
 	var stateStoreData pkgtypes.StateStoreCredentials
 
-	telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.StateStoreCredentialsCreateStarted, "")
+	clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.StateStoreCredentialsCreateStarted, "")
 
 	if !cl.StateStoreCredsCheck {
 		switch clctrl.CloudProvider {
 		case "akamai":
-			log.Info().Msg("object storage credentials created during bucket create")
+			clctrl.logger().Info("object storage credentials created during bucket create")
 		case "aws":
-			kubefirstStateStoreBucket, err := clctrl.AwsClient.CreateBucket(clctrl.KubefirstStateStoreBucketName)
-			if err != nil {
-				return err
-			}
-
-			kubefirstArtifactsBucket, err := clctrl.AwsClient.CreateBucket(clctrl.KubefirstArtifactsBucketName)
-			if err != nil {
-				return err
+			stateStoreBucketName := clctrl.KubefirstStateStoreBucketName
+			artifactsBucketName := clctrl.KubefirstArtifactsBucketName
+
+			if clctrl.UseExistingStateStoreBucket {
+				err = clctrl.AwsClient.VerifyBucketAccess(clctrl.KubefirstStateStoreBucketName)
+				if err != nil {
+					clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.StateStoreCredentialsCreateFailed, err.Error())
+					return err
+				}
+			} else {
+				kubefirstStateStoreBucket, err := clctrl.AwsClient.CreateBucket(clctrl.KubefirstStateStoreBucketName)
+				if err != nil {
+					return err
+				}
+
+				kubefirstArtifactsBucket, err := clctrl.AwsClient.CreateBucket(clctrl.KubefirstArtifactsBucketName)
+				if err != nil {
+					return err
+				}
+
+				stateStoreBucketName = strings.ReplaceAll(*kubefirstStateStoreBucket.Location, "/", "")
+				artifactsBucketName = strings.ReplaceAll(*kubefirstArtifactsBucket.Location, "/", "")
+
+				// the kubefirst-cluster tag lets DetectOrphans find the buckets
+				for _, bucketName := range []string{clctrl.KubefirstStateStoreBucketName, clctrl.KubefirstArtifactsBucketName} {
+					err = clctrl.AwsClient.TagBucket(bucketName, clusterResourceTags(cl))
+					if err != nil {
+						clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.StateStoreCredentialsCreateFailed, err.Error())
+						return err
+					}
+				}
+
+				if clctrl.StateStoreKMSKey != "" {
+					for _, bucketName := range []string{clctrl.KubefirstStateStoreBucketName, clctrl.KubefirstArtifactsBucketName} {
+						err = clctrl.AwsClient.SetBucketEncryption(bucketName, clctrl.StateStoreKMSKey)
+						if err != nil {
+							clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.StateStoreCredentialsCreateFailed, err.Error())
+							return err
+						}
+					}
+				}
 			}
 
 			stateStoreData = pkgtypes.StateStoreCredentials{
@@ -58,27 +89,27 @@ func (clctrl *ClusterController) StateStoreCredentials() error {
 			}
 
 			clctrl.Cluster.StateStoreDetails = pkgtypes.StateStoreDetails{
-				AWSStateStoreBucket: strings.ReplaceAll(*kubefirstStateStoreBucket.Location, "/", ""),
-				AWSArtifactsBucket:  strings.ReplaceAll(*kubefirstArtifactsBucket.Location, "/", ""),
+				AWSStateStoreBucket: stateStoreBucketName,
+				AWSArtifactsBucket:  artifactsBucketName,
 				Hostname:            "s3.amazonaws.com",
 				Name:                clctrl.KubefirstStateStoreBucketName,
 			}
 			err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
 
 			if err != nil {
-				telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.StateStoreCredentialsCreateFailed, err.Error())
+				clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.StateStoreCredentialsCreateFailed, err.Error())
 				return err
 			}
 		case "civo":
 			civoConf := civo.CivoConfiguration{
-				Client:  civo.NewCivo(cl.CivoAuth.Token, cl.CloudRegion),
+				Client:  civo.NewCivo(cl.CivoAuth.Token, cl.CloudRegion, cl.CivoAuth.APIURL),
 				Context: context.Background(),
 			}
 
 			creds, err := civoConf.GetAccessCredentials(clctrl.KubefirstStateStoreBucketName, clctrl.CloudRegion)
 			if err != nil {
-				telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.StateStoreCredentialsCreateFailed, err.Error())
-				log.Error().Msg(err.Error())
+				clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.StateStoreCredentialsCreateFailed, err.Error())
+				clctrl.logger().Error(err.Error())
 			}
 
 			stateStoreData = pkgtypes.StateStoreCredentials{
@@ -89,7 +120,7 @@ func (clctrl *ClusterController) StateStoreCredentials() error {
 			}
 		case "digitalocean":
 			digitaloceanConf := digitalocean.DigitaloceanConfiguration{
-				Client:  digitalocean.NewDigitalocean(cl.DigitaloceanAuth.Token),
+				Client:  digitalocean.NewDigitalocean(cl.DigitaloceanAuth.Token, cl.DigitaloceanAuth.APIURL),
 				Context: context.Background(),
 			}
 
@@ -98,11 +129,15 @@ func (clctrl *ClusterController) StateStoreCredentials() error {
 				SecretAccessKey: cl.DigitaloceanAuth.SpacesSecret,
 				Endpoint:        fmt.Sprintf("%s.digitaloceanspaces.com", "nyc3"),
 			}
-			err = digitaloceanConf.CreateSpaceBucket(creds, clctrl.KubefirstStateStoreBucketName)
+			if clctrl.UseExistingStateStoreBucket {
+				err = digitaloceanConf.VerifySpaceBucket(creds, clctrl.KubefirstStateStoreBucketName)
+			} else {
+				err = digitaloceanConf.CreateSpaceBucket(creds, clctrl.KubefirstStateStoreBucketName)
+			}
 			if err != nil {
 				msg := fmt.Sprintf("error creating spaces bucket %s: %s", clctrl.KubefirstStateStoreBucketName, err)
-				log.Error().Msg(msg)
-				telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.StateStoreCredentialsCreateFailed, err.Error())
+				clctrl.logger().Error(msg)
+				clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.StateStoreCredentialsCreateFailed, err.Error())
 				return fmt.Errorf(msg)
 			}
 
@@ -125,17 +160,34 @@ func (clctrl *ClusterController) StateStoreCredentials() error {
 		case "google":
 			// State is stored in a non s3 compliant gcs backend and thus the ADC provided will be used.
 
+			if clctrl.UseExistingStateStoreBucket {
+				err = clctrl.GoogleClient.VerifyBucketAccess(clctrl.KubefirstStateStoreBucketName, []byte(clctrl.GoogleAuth.KeyFile))
+				if err != nil {
+					clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.StateStoreCreateFailed, err.Error())
+					return err
+				}
+				break
+			}
+
 			// state store bucket created
 			_, err := clctrl.GoogleClient.CreateBucket(clctrl.KubefirstStateStoreBucketName, []byte(clctrl.GoogleAuth.KeyFile))
 			if err != nil {
 				msg := fmt.Sprintf("error creating google bucket %s: %s", clctrl.KubefirstStateStoreBucketName, err)
-				telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.StateStoreCreateFailed, msg)
+				clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.StateStoreCreateFailed, msg)
 				return fmt.Errorf(msg)
 			}
 
+			if clctrl.StateStoreKMSKey != "" {
+				err = clctrl.GoogleClient.SetBucketEncryption(clctrl.KubefirstStateStoreBucketName, clctrl.StateStoreKMSKey, []byte(clctrl.GoogleAuth.KeyFile))
+				if err != nil {
+					clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.StateStoreCreateFailed, err.Error())
+					return err
+				}
+			}
+
 		case "vultr":
 			vultrConf := vultr.VultrConfiguration{
-				Client:  vultr.NewVultr(cl.VultrAuth.Token),
+				Client:  vultr.NewVultr(cl.VultrAuth.Token, cl.VultrAuth.APIURL),
 				Context: context.Background(),
 				Region:  cl.CloudRegion,
 				// https://www.vultr.com/docs/vultr-object-storage/
@@ -144,8 +196,8 @@ func (clctrl *ClusterController) StateStoreCredentials() error {
 
 			objst, err := vultrConf.CreateObjectStorage(clctrl.KubefirstStateStoreBucketName)
 			if err != nil {
-				telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.StateStoreCreateFailed, err.Error())
-				log.Error().Msg(err.Error())
+				clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.StateStoreCreateFailed, err.Error())
+				clctrl.logger().Error(err.Error())
 				return err
 			}
 			err = vultrConf.CreateObjectStorageBucket(vultr.VultrBucketCredentials{
@@ -154,7 +206,7 @@ func (clctrl *ClusterController) StateStoreCredentials() error {
 				Endpoint:        objst.S3Hostname,
 			}, clctrl.KubefirstStateStoreBucketName)
 			if err != nil {
-				telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.StateStoreCredentialsCreateFailed, err.Error())
+				clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.StateStoreCredentialsCreateFailed, err.Error())
 				return fmt.Errorf("error creating vultr state storage bucket: %s", err)
 			}
 
@@ -185,8 +237,8 @@ func (clctrl *ClusterController) StateStoreCredentials() error {
 			return err
 		}
 
-		telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.CloudCredentialsCheckCompleted, "")
-		log.Info().Msgf("%s object storage credentials created and set", clctrl.CloudProvider)
+		clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.CloudCredentialsCheckCompleted, "")
+		clctrl.logger().Infof("%s object storage credentials created and set", clctrl.CloudProvider)
 	}
 
 	return nil
@@ -202,28 +254,17 @@ func (clctrl *ClusterController) StateStoreCreate() error {
 	if !cl.StateStoreCreateCheck {
 		switch clctrl.CloudProvider {
 		case "akamai":
-
-			tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: cl.AkamaiAuth.Token})
-
-			oauth2Client := &http.Client{
-				Transport: &oauth2.Transport{
-					Source: tokenSource,
-				},
-			}
-
-			linodego.NewClient(oauth2Client)
-
 			akamaiConf := akamai.AkamaiConfiguration{
-				Client:  linodego.NewClient(oauth2Client),
+				Client:  akamai.NewAkamai(cl.AkamaiAuth.Token, cl.AkamaiAuth.APIURL),
 				Context: context.Background(),
 			}
 
-			telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.StateStoreCreateStarted, "")
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.StateStoreCreateStarted, "")
 
 			bucketAndCreds, err := akamaiConf.CreateObjectStorageBucketAndKeys(cl.ClusterName)
 			if err != nil {
-				telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.StateStoreCreateFailed, err.Error())
-				log.Error().Msg(err.Error())
+				clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.StateStoreCreateFailed, err.Error())
+				clctrl.logger().Error(err.Error())
 				return err
 			}
 
@@ -240,24 +281,24 @@ func (clctrl *ClusterController) StateStoreCreate() error {
 				return err
 			}
 
-			telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.StateStoreCreateCompleted, "")
-			log.Info().Msgf("%s state store bucket created", clctrl.CloudProvider)
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.StateStoreCreateCompleted, "")
+			clctrl.logger().Infof("%s state store bucket created", clctrl.CloudProvider)
 		case "civo":
 
 			civoConf := civo.CivoConfiguration{
-				Client:  civo.NewCivo(cl.CivoAuth.Token, cl.CloudRegion),
+				Client:  civo.NewCivo(cl.CivoAuth.Token, cl.CloudRegion, cl.CivoAuth.APIURL),
 				Context: context.Background(),
 			}
 
-			telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.StateStoreCreateStarted, "")
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.StateStoreCreateStarted, "")
 
 			accessKeyId := cl.StateStoreCredentials.AccessKeyID
-			log.Info().Msgf("access key id %s", accessKeyId)
+			clctrl.logger().Infof("access key id %s", accessKeyId)
 
 			bucket, err := civoConf.CreateStorageBucket(accessKeyId, clctrl.KubefirstStateStoreBucketName, clctrl.CloudRegion)
 			if err != nil {
-				telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.StateStoreCreateFailed, err.Error())
-				log.Error().Msg(err.Error())
+				clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.StateStoreCreateFailed, err.Error())
+				clctrl.logger().Error(err.Error())
 				return err
 			}
 
@@ -275,10 +316,50 @@ func (clctrl *ClusterController) StateStoreCreate() error {
 				return err
 			}
 
-			telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.StateStoreCreateCompleted, "")
-			log.Info().Msgf("%s state store bucket created", clctrl.CloudProvider)
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.StateStoreCreateCompleted, "")
+			clctrl.logger().Infof("%s state store bucket created", clctrl.CloudProvider)
 		}
 	}
 
 	return nil
 }
+
+// ProbeStateStore writes, reads back and deletes an object in the state store
+// bucket so missing credentials or permissions fail the install before any
+// cloud resources are created rather than midway through terraform
+func (clctrl *ClusterController) ProbeStateStore() error {
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("kubefirst-probe-%s", primitive.NewObjectID().Hex())
+
+	switch clctrl.CloudProvider {
+	case "aws":
+		err = clctrl.AwsClient.ProbeBucket(clctrl.KubefirstStateStoreBucketName, key)
+	case "google":
+		err = clctrl.GoogleClient.ProbeBucket(clctrl.KubefirstStateStoreBucketName, key, []byte(clctrl.GoogleAuth.KeyFile))
+	case "akamai", "civo", "digitalocean", "vultr":
+		bucketName := cl.StateStoreDetails.Name
+		err = objectStorage.ProbeBucket(&cl.StateStoreCredentials, stateStoreEndpoint(cl.StateStoreDetails.Hostname, bucketName), bucketName, key)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("the %s state store failed its health probe, check the object storage credentials and permissions: %s", clctrl.CloudProvider, err)
+	}
+
+	clctrl.logger().Infof("%s state store is readable and writable", clctrl.CloudProvider)
+
+	return nil
+}
+
+// stateStoreEndpoint returns the s3 endpoint of a state store hostname, which
+// some providers record as a url or a virtual-hosted bucket address
+func stateStoreEndpoint(hostname string, bucketName string) string {
+	endpoint := strings.TrimPrefix(strings.TrimPrefix(hostname, "https://"), "http://")
+	endpoint, _, _ = strings.Cut(endpoint, "/")
+
+	return strings.TrimPrefix(endpoint, bucketName+".")
+}
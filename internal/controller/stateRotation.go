@@ -0,0 +1,289 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	terraformext "github.com/kubefirst/kubefirst-api/extensions/terraform"
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+	"github.com/kubefirst/kubefirst-api/internal/civo"
+	"github.com/kubefirst/kubefirst-api/internal/k8s"
+	"github.com/kubefirst/kubefirst-api/internal/objectStorage"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	"github.com/kubefirst/kubefirst-api/internal/vault"
+	"github.com/kubefirst/kubefirst-api/internal/vultr"
+	"github.com/kubefirst/kubefirst-api/pkg/akamai"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// stateStoreRotationProviders are the cloud providers whose state store keys
+// are issued for the cluster, the others use the cloud account credentials
+var stateStoreRotationProviders = []string{"akamai", "civo", "vultr"}
+
+// stateStoreProbeAttempts and stateStoreProbeInterval bound how long keys
+// replaced in place are given to work once issued
+var (
+	stateStoreProbeAttempts = 6
+	stateStoreProbeInterval = 10 * time.Second
+)
+
+// RotateStateStoreCredentials issues new object storage keys for the cluster's
+// state store and moves everything that uses them over: the cluster record,
+// the atlantis secret in vault and the backends of the local terraform
+// entrypoints. Keys that can live side by side (akamai) are only revoked once
+// the new ones are in place, keys replaced in place (civo, vultr) are saved to
+// the cluster record as soon as they're issued so the state is never left
+// without working credentials
+func (clctrl *ClusterController) RotateStateStoreCredentials() error {
+	err := CheckWritable()
+	if err != nil {
+		return err
+	}
+
+	if !pkg.FindStringInSlice(stateStoreRotationProviders, clctrl.CloudProvider) {
+		return fmt.Errorf("rotating the state store credentials is not supported for %s, its state store uses the cloud account credentials", clctrl.CloudProvider)
+	}
+
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return err
+	}
+	if !cl.StateStoreCredsCheck {
+		return fmt.Errorf("cluster %s has no state store credentials to rotate", clctrl.ClusterName)
+	}
+
+	// the new keys have to reach the atlantis secret in vault
+	err = clctrl.VerifyConnectivity()
+	if err != nil {
+		return err
+	}
+
+	oldCreds := cl.StateStoreCredentials
+	bucketName := cl.StateStoreDetails.Name
+	endpoint := stateStoreEndpoint(cl.StateStoreDetails.Hostname, bucketName)
+
+	var newCreds pkgtypes.StateStoreCredentials
+	var revokeOld, revokeNew func() error
+	switch clctrl.CloudProvider {
+	case "akamai":
+		akamaiConf := akamai.AkamaiConfiguration{
+			Client:  akamai.NewAkamai(cl.AkamaiAuth.Token, cl.AkamaiAuth.APIURL),
+			Context: context.Background(),
+		}
+		// the hostname is <bucket>.<cluster>.linodeobjects.com
+		objectStorageCluster, _, _ := strings.Cut(endpoint, ".")
+		newCreds, err = akamaiConf.CreateObjectStorageBucketKeys(bucketName, objectStorageCluster)
+		revokeOld = func() error { return akamaiConf.DeleteObjectStorageKey(oldCreds.AccessKeyID) }
+		revokeNew = func() error { return akamaiConf.DeleteObjectStorageKey(newCreds.AccessKeyID) }
+	case "civo":
+		civoConf := civo.CivoConfiguration{
+			Client:  civo.NewCivo(cl.CivoAuth.Token, cl.CloudRegion, cl.CivoAuth.APIURL),
+			Context: context.Background(),
+		}
+		creds, rotateErr := civoConf.RotateAccessCredentials(oldCreds.ID, cl.CloudRegion)
+		newCreds = pkgtypes.StateStoreCredentials{
+			AccessKeyID:     creds.AccessKeyID,
+			SecretAccessKey: creds.SecretAccessKeyID,
+			Name:            creds.Name,
+			ID:              creds.ID,
+		}
+		err = rotateErr
+	case "vultr":
+		vultrConf := vultr.VultrConfiguration{
+			Client:  vultr.NewVultr(cl.VultrAuth.Token, cl.VultrAuth.APIURL),
+			Context: context.Background(),
+		}
+		keys, rotateErr := vultrConf.RegenerateObjectStorageKeys(oldCreds.ID)
+		newCreds = pkgtypes.StateStoreCredentials{
+			AccessKeyID:     keys.S3AccessKey,
+			SecretAccessKey: keys.S3SecretKey,
+			Name:            oldCreds.Name,
+			ID:              oldCreds.ID,
+		}
+		err = rotateErr
+	}
+	if err != nil {
+		return err
+	}
+	clctrl.logger().Infof("issued new %s state store credentials for cluster %s", clctrl.CloudProvider, clctrl.ClusterName)
+
+	// the old keys stopped working, the new ones are the only way to the state
+	if revokeOld == nil {
+		err = clctrl.saveStateStoreCredentials(&cl, newCreds)
+		if err != nil {
+			return err
+		}
+	}
+
+	key := fmt.Sprintf("kubefirst-probe-%s", primitive.NewObjectID().Hex())
+	for attempt := 1; ; attempt++ {
+		err = objectStorage.ProbeBucket(&newCreds, endpoint, bucketName, key)
+		if err == nil || attempt == stateStoreProbeAttempts {
+			break
+		}
+		clctrl.logger().Warnf("waiting for the new state store credentials to work: %s", err)
+		time.Sleep(stateStoreProbeInterval)
+	}
+	if err != nil {
+		if revokeNew != nil {
+			if revokeErr := revokeNew(); revokeErr != nil {
+				clctrl.logger().Warnf("error revoking the unused state store credentials: %s", revokeErr)
+			}
+			return fmt.Errorf("the new state store credentials failed their health probe, the old ones are kept: %s", err)
+		}
+		return fmt.Errorf("the new state store credentials failed their health probe: %s", err)
+	}
+
+	if revokeOld != nil {
+		err = clctrl.saveStateStoreCredentials(&cl, newCreds)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = clctrl.updateAtlantisStateStoreCredentials(cl, newCreds)
+	if err != nil {
+		return fmt.Errorf("state store credentials rotated but the atlantis secret was not updated: %s", err)
+	}
+
+	err = clctrl.reinitializeStateStoreBackends(newCreds)
+	if err != nil {
+		return fmt.Errorf("state store credentials rotated but terraform could not be re-initialized: %s", err)
+	}
+
+	if revokeOld != nil {
+		err = revokeOld()
+		if err != nil {
+			return fmt.Errorf("state store credentials rotated but the old ones were not revoked: %s", err)
+		}
+	}
+
+	clctrl.logger().Infof("rotated the state store credentials of cluster %s", clctrl.ClusterName)
+
+	return nil
+}
+
+// saveStateStoreCredentials records new state store credentials on the
+// cluster record read for the rotation
+func (clctrl *ClusterController) saveStateStoreCredentials(cl *pkgtypes.Cluster, creds pkgtypes.StateStoreCredentials) error {
+	cl.StateStoreCredentials = creds
+	cl.StateStoreCredentialsRotatedAt = time.Now().UTC()
+
+	err := secrets.UpdateCluster(clctrl.KubernetesClient, *cl)
+	if err != nil {
+		return fmt.Errorf("error saving the rotated state store credentials: %s", err)
+	}
+	clctrl.Cluster = *cl
+
+	return nil
+}
+
+// updateAtlantisStateStoreCredentials replaces the state store credentials
+// in the atlantis secret in vault, the source of the atlantis terraform
+// environment
+func (clctrl *ClusterController) updateAtlantisStateStoreCredentials(cl pkgtypes.Cluster, creds pkgtypes.StateStoreCredentials) error {
+	vaultClient, err := clctrl.clusterVaultClient(cl)
+	if err != nil {
+		return err
+	}
+
+	atlantisSecret, err := vaultClient.KVv2("secret").Get(context.Background(), "atlantis")
+	if err != nil {
+		return fmt.Errorf("error reading the atlantis secret: %s", err)
+	}
+
+	data := atlantisSecret.Data
+	updated := false
+	for key, value := range map[string]string{
+		"AWS_ACCESS_KEY_ID":            creds.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY":        creds.SecretAccessKey,
+		"TF_VAR_aws_access_key_id":     creds.AccessKeyID,
+		"TF_VAR_aws_secret_access_key": creds.SecretAccessKey,
+	} {
+		if _, ok := data[key]; ok {
+			data[key] = value
+			updated = true
+		}
+	}
+	if !updated {
+		clctrl.logger().Warn("the atlantis secret holds no state store credentials, skipping")
+		return nil
+	}
+
+	_, err = vaultClient.KVv2("secret").Put(context.Background(), "atlantis", data)
+	if err != nil {
+		return fmt.Errorf("error writing the atlantis secret: %s", err)
+	}
+	clctrl.logger().Info("updated the state store credentials in the atlantis secret")
+
+	return nil
+}
+
+// clusterVaultClient returns a client of the cluster's vault authenticated
+// with the root token kept in the cluster
+func (clctrl *ClusterController) clusterVaultClient(cl pkgtypes.Cluster) (*vaultapi.Client, error) {
+	kcfg, err := clctrl.GetClusterKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	vaultSecret, err := k8s.ReadSecretV2(kcfg.Clientset, vault.VaultNamespace, vault.VaultSecretName)
+	if err != nil || vaultSecret == nil {
+		return nil, fmt.Errorf("error getting vault token: %s", err)
+	}
+
+	fullDomainName := cl.DomainName
+	if cl.SubdomainName != "" {
+		fullDomainName = fmt.Sprintf("%s.%s", cl.SubdomainName, cl.DomainName)
+	}
+	vaultClient, err := vaultapi.NewClient(&vaultapi.Config{
+		Address: fmt.Sprintf("https://vault.%s", fullDomainName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error initializing vault client: %s", err)
+	}
+	vaultClient.SetToken(vaultSecret["root-token"])
+
+	return vaultClient, nil
+}
+
+// reinitializeStateStoreBackends re-initializes the terraform entrypoints of
+// the local gitops repository with the new credentials and reads their state,
+// entrypoints whose state isn't in the state store are left alone
+func (clctrl *ClusterController) reinitializeStateStoreBackends(creds pkgtypes.StateStoreCredentials) error {
+	if terraformext.TerraformCloudEnabled(clctrl.TerraformCloud) || clctrl.TerraformBackend.Type != "" {
+		clctrl.logger().Info("terraform state is not kept in the state store, skipping terraform re-initialization")
+		return nil
+	}
+
+	tfEnvs := map[string]string{
+		"AWS_ACCESS_KEY_ID":     creds.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY": creds.SecretAccessKey,
+		"AWS_SESSION_TOKEN":     "",
+	}
+	for _, entrypoint := range []string{clctrl.CloudProvider, clctrl.GitProvider, "vault", "users"} {
+		tfEntrypoint := fmt.Sprintf("%s/terraform/%s", clctrl.ProviderConfig.GitopsDir, entrypoint)
+		if _, err := os.Stat(tfEntrypoint); err != nil {
+			continue
+		}
+
+		err := terraformext.InitReconfigure(clctrl.ProviderConfig.TerraformClient, tfEntrypoint, tfEnvs)
+		if err != nil {
+			return fmt.Errorf("error re-initializing %s: %s", tfEntrypoint, err)
+		}
+		clctrl.logger().Infof("re-initialized %s with the new state store credentials", tfEntrypoint)
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestRotateStateStoreCredentialsProbeFailure(t *testing.T) {
+	defer func(attempts int) { stateStoreProbeAttempts = attempts }(stateStoreProbeAttempts)
+	stateStoreProbeAttempts = 1
+
+	store, err := secrets.NewMemoryStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets.SetStore(store)
+	defer secrets.SetStore(nil)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// also stands in for the cluster's api server
+		if r.URL.Path == "/version" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"gitVersion": "v1.27.0"}`))
+			return
+		}
+		if r.Method != http.MethodPost || r.URL.Path != "/v2/object-storage/objst-1/regenerate-keys" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"s3_credentials": {"s3_access_key": "new-key", "s3_secret_key": "new-secret"}}`))
+	}))
+	defer server.Close()
+
+	err = secrets.InsertCluster(nil, pkgtypes.Cluster{
+		ClusterName:           "kf-test",
+		CloudProvider:         "vultr",
+		VultrAuth:             pkgtypes.VultrAuth{Token: "token", APIURL: server.URL},
+		StateStoreCredsCheck:  true,
+		StateStoreCredentials: pkgtypes.StateStoreCredentials{AccessKeyID: "old-key", SecretAccessKey: "old-secret", ID: "objst-1"},
+		// nothing listens there, so the probe of the new keys fails
+		StateStoreDetails: pkgtypes.StateStoreDetails{Name: "k1-state-store-kf-test", Hostname: "127.0.0.1:1"},
+		// changed after the controller was initialized
+		AlertsEmail: "ops@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clctrl := &ClusterController{
+		ClusterName:    "kf-test",
+		CloudProvider:  "vultr",
+		Cluster:        pkgtypes.Cluster{ClusterName: "kf-test", CloudProvider: "vultr"},
+		ProviderConfig: providerConfigs.ProviderConfig{Kubeconfig: testKubeconfig(t, server.URL)},
+	}
+	err = clctrl.RotateStateStoreCredentials()
+	if err == nil {
+		t.Fatal("expected the probe of the new credentials to fail")
+	}
+
+	cl, err := secrets.GetCluster(nil, "kf-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cl.StateStoreCredentials.AccessKeyID != "new-key" || cl.StateStoreCredentials.SecretAccessKey != "new-secret" {
+		t.Errorf("state store credentials = %+v, want the regenerated keys", cl.StateStoreCredentials)
+	}
+	if cl.StateStoreCredentialsRotatedAt.IsZero() {
+		t.Error("expected the rotation time to be recorded")
+	}
+	if cl.AlertsEmail != "ops@example.com" || !cl.StateStoreCredsCheck {
+		t.Errorf("cluster record = %+v, want the rest of the stored record kept", cl)
+	}
+}
+
+func TestRotateStateStoreCredentialsUnreachableCluster(t *testing.T) {
+	store, err := secrets.NewMemoryStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets.SetStore(store)
+	defer secrets.SetStore(nil)
+
+	regenerated := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		regenerated = true
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	err = secrets.InsertCluster(nil, pkgtypes.Cluster{
+		ClusterName:           "kf-test",
+		CloudProvider:         "vultr",
+		VultrAuth:             pkgtypes.VultrAuth{Token: "token", APIURL: server.URL},
+		StateStoreCredsCheck:  true,
+		StateStoreCredentials: pkgtypes.StateStoreCredentials{AccessKeyID: "old-key", SecretAccessKey: "old-secret", ID: "objst-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clctrl := &ClusterController{
+		ClusterName:   "kf-test",
+		CloudProvider: "vultr",
+		// nothing listens there
+		ProviderConfig: providerConfigs.ProviderConfig{Kubeconfig: testKubeconfig(t, "https://127.0.0.1:1")},
+	}
+	err = clctrl.RotateStateStoreCredentials()
+	if err == nil {
+		t.Fatal("expected the unreachable cluster to fail the rotation")
+	}
+	if regenerated {
+		t.Error("expected the keys to be kept when the cluster is unreachable")
+	}
+}
+
+// testKubeconfig writes a kubeconfig for the api server at server
+func testKubeconfig(t *testing.T, server string) string {
+	t.Helper()
+	config := clientcmdapi.NewConfig()
+	config.Clusters["kf-test"] = &clientcmdapi.Cluster{Server: server}
+	config.AuthInfos["admin"] = &clientcmdapi.AuthInfo{}
+	config.Contexts["kf-test"] = &clientcmdapi.Context{Cluster: "kf-test", AuthInfo: "admin"}
+	config.CurrentContext = "kf-test"
+
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	err := clientcmd.WriteToFile(*config, kubeconfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return kubeconfig
+}
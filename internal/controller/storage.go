@@ -0,0 +1,71 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// validateVolumeSizes makes sure volume sizes only reference known stateful
+// components and are valid kubernetes quantities
+func validateVolumeSizes(sizes map[string]string) error {
+	for component, size := range sizes {
+		if _, ok := providerConfigs.VolumeSizeComponents[component]; !ok {
+			components := make([]string, 0, len(providerConfigs.VolumeSizeComponents))
+			for c := range providerConfigs.VolumeSizeComponents {
+				components = append(components, c)
+			}
+			sort.Strings(components)
+			return fmt.Errorf("unknown volume size component %s: must be one of %v", component, components)
+		}
+
+		quantity, err := resource.ParseQuantity(size)
+		if err != nil {
+			return fmt.Errorf("invalid volume size %q for %s: %s", size, component, err)
+		}
+		if quantity.Sign() <= 0 {
+			return fmt.Errorf("invalid volume size %q for %s: must be greater than zero", size, component)
+		}
+	}
+
+	return nil
+}
+
+// ValidateStorageClass confirms the storage class requested in the definition
+// exists on the new cluster before the registry creates volumes with it
+func (clctrl *ClusterController) ValidateStorageClass() error {
+	if clctrl.StorageClass == "" {
+		return nil
+	}
+
+	kcfg, err := clctrl.GetClusterKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	storageClasses, err := kcfg.Clientset.StorageV1().StorageClasses().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing storage classes: %s", err)
+	}
+
+	available := make([]string, 0, len(storageClasses.Items))
+	for _, sc := range storageClasses.Items {
+		if sc.Name == clctrl.StorageClass {
+			clctrl.logger().Infof("storage class %s found on cluster %s", clctrl.StorageClass, clctrl.ClusterName)
+			return nil
+		}
+		available = append(available, sc.Name)
+	}
+
+	return fmt.Errorf("storage class %s does not exist on cluster %s, available storage classes: %v", clctrl.StorageClass, clctrl.ClusterName, available)
+}
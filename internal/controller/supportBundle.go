@@ -0,0 +1,343 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+const (
+	// supportBundleLogTailLines is the default number of recent log lines
+	// collected per component
+	supportBundleLogTailLines = 500
+
+	// supportBundleMaxLogFileSize caps how much of each api log file is
+	// collected, the most recent content is kept
+	supportBundleMaxLogFileSize = 5 * 1024 * 1024
+
+	// supportBundleLogTimeout bounds how long component logs are read
+	supportBundleLogTimeout = 30 * time.Second
+
+	redactedValue = "[REDACTED]"
+)
+
+// DefaultSupportBundleRedactions are the field names whose values are
+// redacted from support bundles when no list is supplied, a field is redacted
+// when its name contains one of them
+var DefaultSupportBundleRedactions = []string{
+	"token",
+	"password",
+	"secret",
+	"private",
+	"ssh_privatekey",
+	"key_file",
+	"access_key",
+	"api_key",
+	"kubeconfig",
+	"origin_ca_issuer",
+}
+
+// supportBundleStep is one entry of the step timeline, in provisioning order
+type supportBundleStep struct {
+	Step      string `json:"step"`
+	Completed bool   `json:"completed"`
+}
+
+// GenerateSupportBundle collects the cluster record, step timeline, api and
+// terraform logs, service statuses and recent component logs into a gzipped
+// tar below the cluster's k1 directory, with secrets redacted, and returns its
+// path - sections that can't be collected are recorded as errors in the
+// bundle instead of failing it
+func (clctrl *ClusterController) GenerateSupportBundle(opts pkgtypes.SupportBundleOptions) (string, error) {
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return "", err
+	}
+
+	redact := opts.Redact
+	if len(redact) == 0 {
+		redact = DefaultSupportBundleRedactions
+	}
+	tailLines := opts.LogTailLines
+	if tailLines <= 0 {
+		tailLines = supportBundleLogTailLines
+	}
+
+	record, secretValues, err := redactClusterRecord(cl, redact)
+	if err != nil {
+		return "", err
+	}
+	scrub := func(content []byte) []byte {
+		s := string(content)
+		for _, value := range secretValues {
+			s = strings.ReplaceAll(s, value, redactedValue)
+		}
+		return []byte(s)
+	}
+
+	k1Dir := clctrl.ProviderConfig.K1Dir
+	if k1Dir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		k1Dir = filepath.Join(homeDir, ".k1", clctrl.ClusterName)
+	}
+	err = os.MkdirAll(k1Dir, 0700)
+	if err != nil {
+		return "", fmt.Errorf("error creating %s: %s", k1Dir, err)
+	}
+
+	bundlePath := filepath.Join(k1Dir, fmt.Sprintf("support-bundle-%s-%s.tar.gz", clctrl.ClusterName, time.Now().UTC().Format("20060102T150405Z")))
+	bundleFile, err := os.OpenFile(bundlePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", fmt.Errorf("error creating support bundle %s: %s", bundlePath, err)
+	}
+	defer bundleFile.Close()
+
+	gzipWriter := gzip.NewWriter(bundleFile)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	addFile := func(name string, content []byte) error {
+		err := tarWriter.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0600,
+			Size:    int64(len(content)),
+			ModTime: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tarWriter.Write(content)
+		return err
+	}
+	addJSON := func(name string, v interface{}, collectErr error) error {
+		if collectErr != nil {
+			return addFile(strings.TrimSuffix(name, ".json")+".error.txt", scrub([]byte(collectErr.Error())))
+		}
+		content, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		return addFile(name, scrub(content))
+	}
+
+	err = addFile("cluster.json", record)
+	if err != nil {
+		return "", err
+	}
+
+	err = addJSON("timeline.json", clusterStepTimeline(cl), nil)
+	if err != nil {
+		return "", err
+	}
+
+	statuses, statusErr := clctrl.ServiceStatuses()
+	err = addJSON("services.json", statuses, statusErr)
+	if err != nil {
+		return "", err
+	}
+
+	// the api session logs include the output of every terraform run
+	logFiles, _ := filepath.Glob(filepath.Join(filepath.Dir(k1Dir), "logs", "*"))
+	for _, logFile := range logFiles {
+		content, err := readFileTail(logFile, supportBundleMaxLogFileSize)
+		if err != nil {
+			clctrl.logger().Warnf("skipping log file %s in support bundle: %s", logFile, err)
+			continue
+		}
+		err = addFile(filepath.Join("logs", "api", filepath.Base(logFile)), scrub(content))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	components := make([]string, 0, len(componentLogSources))
+	for component := range componentLogSources {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	for _, component := range components {
+		content, err := clctrl.readComponentLogs(component, tailLines)
+		name := filepath.Join("logs", "components", fmt.Sprintf("%s.log", component))
+		if err != nil {
+			name = filepath.Join("logs", "components", fmt.Sprintf("%s.error.txt", component))
+			content = []byte(err.Error())
+		}
+		err = addFile(name, scrub(content))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = tarWriter.Close()
+	if err != nil {
+		return "", err
+	}
+	err = gzipWriter.Close()
+	if err != nil {
+		return "", err
+	}
+
+	clctrl.logger().Infof("wrote support bundle for cluster %s to %s", clctrl.ClusterName, bundlePath)
+
+	return bundlePath, nil
+}
+
+// redactClusterRecord returns the cluster record as json with the values of
+// matching fields redacted, along with the redacted values so they can be
+// scrubbed from logs
+func redactClusterRecord(cl pkgtypes.Cluster, redact []string) ([]byte, []string, error) {
+	content, err := json.Marshal(cl)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var record map[string]interface{}
+	err = json.Unmarshal(content, &record)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	secretValues := []string{}
+	var walk func(v interface{}, redacted bool) interface{}
+	walk = func(v interface{}, redacted bool) interface{} {
+		switch value := v.(type) {
+		case map[string]interface{}:
+			for key, child := range value {
+				value[key] = walk(child, redacted || matchesRedaction(key, redact))
+			}
+			return value
+		case []interface{}:
+			for i, child := range value {
+				value[i] = walk(child, redacted)
+			}
+			return value
+		case string:
+			if redacted && value != "" {
+				secretValues = append(secretValues, value)
+				return redactedValue
+			}
+			return value
+		default:
+			return value
+		}
+	}
+	walk(record, false)
+
+	// replace longer values first so a secret containing another is fully scrubbed
+	sort.Slice(secretValues, func(i, j int) bool { return len(secretValues[i]) > len(secretValues[j]) })
+
+	redactedRecord, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return redactedRecord, secretValues, nil
+}
+
+func matchesRedaction(key string, redact []string) bool {
+	key = strings.ToLower(key)
+	for _, r := range redact {
+		if r != "" && strings.Contains(key, strings.ToLower(r)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clusterStepTimeline lists the cluster record's provisioning step checks,
+// starting at InstallToolsCheck, in the order they're declared which follows
+// the provisioning order - failure and delete markers are left out
+func clusterStepTimeline(cl pkgtypes.Cluster) []supportBundleStep {
+	steps := []supportBundleStep{}
+	value := reflect.ValueOf(cl)
+	started := false
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Name == "InstallToolsCheck" {
+			started = true
+		}
+		if !started || field.Type.Kind() != reflect.Bool || !strings.HasSuffix(field.Name, "Check") {
+			continue
+		}
+		if strings.HasSuffix(field.Name, "FailedCheck") || strings.Contains(field.Name, "Delete") {
+			continue
+		}
+		step := field.Name
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag != "" {
+			step = tag
+		}
+		steps = append(steps, supportBundleStep{Step: step, Completed: value.Field(i).Bool()})
+	}
+
+	return steps
+}
+
+// readComponentLogs reads the most recent log lines of a component without
+// following
+func (clctrl *ClusterController) readComponentLogs(component string, tailLines int64) ([]byte, error) {
+	stream, err := clctrl.StreamComponentLogs(component, pkgtypes.ComponentLogOptions{TailLines: tailLines})
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), supportBundleLogTimeout)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		stream.Close()
+	}()
+
+	content, err := io.ReadAll(stream)
+	if err != nil && ctx.Err() == nil {
+		return content, err
+	}
+
+	return content, nil
+}
+
+// readFileTail returns at most the last max bytes of a file
+func readFileTail(path string, max int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > max {
+		_, err = file.Seek(info.Size()-max, io.SeekStart)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return io.ReadAll(file)
+}
@@ -0,0 +1,48 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestRedactClusterRecord(t *testing.T) {
+	cl := pkgtypes.Cluster{
+		ClusterName:           "kf-test",
+		DomainName:            "example.com",
+		GitAuth:               pkgtypes.GitAuth{User: "kbot", Token: "git-token", PrivateKey: "kbot-private-key", AppPrivateKey: "app-private-key"},
+		K3sAuth:               pkgtypes.K3sAuth{K3sSshUser: "root", K3sSshPrivateKey: "k3s-ssh-private-key"},
+		VaultAuth:             pkgtypes.VaultAuth{RootToken: "vault-root-token", KbotPassword: "kbot-password"},
+		AtlantisWebhookSecret: "webhook-secret",
+	}
+
+	record, secretValues, err := redactClusterRecord(cl, DefaultSupportBundleRedactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, value := range []string{"git-token", "kbot-private-key", "app-private-key", "k3s-ssh-private-key", "vault-root-token", "kbot-password", "webhook-secret"} {
+		if strings.Contains(string(record), value) {
+			t.Errorf("expected %s to be redacted from the record", value)
+		}
+		found := false
+		for _, secretValue := range secretValues {
+			found = found || secretValue == value
+		}
+		if !found {
+			t.Errorf("expected %s to be scrubbed from the logs", value)
+		}
+	}
+	for _, value := range []string{"kf-test", "example.com", "kbot", "root"} {
+		if !strings.Contains(string(record), value) {
+			t.Errorf("expected %s to be kept in the record", value)
+		}
+	}
+}
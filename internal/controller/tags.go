@@ -0,0 +1,140 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// tagConstraint describes the tags a cloud provider accepts - providers with
+// key/value tags take a map, the others take "key:value" strings
+type tagConstraint struct {
+	keyValue    bool
+	maxKey      int
+	maxValue    int
+	keyRegex    *regexp.Regexp
+	valueRegex  *regexp.Regexp
+	description string
+}
+
+var (
+	awsTagRegex    = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+	googleKeyRegex = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+	googleValRegex = regexp.MustCompile(`^[a-z0-9_-]*$`)
+	stringTagRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]*$`)
+)
+
+// tagConstraints holds the tag constraints of each cloud provider, string
+// tags are limited by the combined "key:value" length
+var tagConstraints = map[string]tagConstraint{
+	"akamai":       {maxKey: 50, keyRegex: stringTagRegex, valueRegex: stringTagRegex, description: "letters, numbers, _ and -, 3 to 50 characters as key:value"},
+	"aws":          {keyValue: true, maxKey: 128, maxValue: 256, keyRegex: awsTagRegex, valueRegex: awsTagRegex, description: "letters, numbers, spaces and _ . : / = + - @"},
+	"civo":         {maxKey: 255, keyRegex: stringTagRegex, valueRegex: stringTagRegex, description: "letters, numbers, _ and -"},
+	"digitalocean": {maxKey: 255, keyRegex: stringTagRegex, valueRegex: stringTagRegex, description: "letters, numbers, _ and -"},
+	"google":       {keyValue: true, maxKey: 63, maxValue: 63, keyRegex: googleKeyRegex, valueRegex: googleValRegex, description: "lowercase letters, numbers, _ and -, keys start with a letter"},
+	"k3s":          {maxKey: 255, keyRegex: stringTagRegex, valueRegex: stringTagRegex, description: "letters, numbers, _ and -"},
+	"vultr":        {maxKey: 255, keyRegex: stringTagRegex, valueRegex: stringTagRegex, description: "letters, numbers, _ and -"},
+}
+
+// validateTags checks tags against the constraints of the cloud provider
+// they're applied in
+func validateTags(cloudProvider string, tags map[string]string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	constraint, ok := tagConstraints[cloudProvider]
+	if !ok {
+		return fmt.Errorf("tags are not supported for %s", cloudProvider)
+	}
+
+	for key, value := range tags {
+		if key == "" {
+			return fmt.Errorf("tag keys cannot be empty")
+		}
+		if key == orphanTagKey {
+			return fmt.Errorf("invalid tag %s: it is set by kubefirst", key)
+		}
+		if !constraint.keyRegex.MatchString(key) || !constraint.valueRegex.MatchString(value) {
+			return fmt.Errorf("invalid tag %s=%s for %s: tags may only contain %s", key, value, cloudProvider, constraint.description)
+		}
+
+		if constraint.keyValue {
+			if len(key) > constraint.maxKey || len(value) > constraint.maxValue {
+				return fmt.Errorf("invalid tag %s=%s for %s: keys are limited to %d and values to %d characters", key, value, cloudProvider, constraint.maxKey, constraint.maxValue)
+			}
+			if cloudProvider == "aws" && strings.HasPrefix(strings.ToLower(key), "aws:") {
+				return fmt.Errorf("invalid tag %s: the aws: prefix is reserved", key)
+			}
+			continue
+		}
+
+		tag := stringTag(key, value)
+		if len(tag) > constraint.maxKey || (cloudProvider == "akamai" && len(tag) < 3) {
+			return fmt.Errorf("invalid tag %s for %s: tags may only contain %s", tag, cloudProvider, constraint.description)
+		}
+	}
+
+	return nil
+}
+
+// stringTag renders a tag for providers without key/value tags
+func stringTag(key string, value string) string {
+	if value == "" {
+		return key
+	}
+
+	return fmt.Sprintf("%s:%s", key, value)
+}
+
+// clusterResourceTags returns the cluster's tags with the kubefirst-cluster
+// tag DetectOrphans recognizes its resources by. Akamai limits string tags
+// to 50 characters and is left out
+func clusterResourceTags(cl pkgtypes.Cluster) map[string]string {
+	if cl.CloudProvider == "akamai" {
+		return cl.Tags
+	}
+
+	tags := map[string]string{orphanTagKey: cl.ClusterName}
+	for key, value := range cl.Tags {
+		tags[key] = value
+	}
+
+	return tags
+}
+
+// getTagsTerraformEnvs passes the cluster's tags to the cloud terraform as
+// TF_VAR_tags, a map on aws and google and a list of key:value strings on
+// providers with plain string tags
+func getTagsTerraformEnvs(envs map[string]string, cloudProvider string, tags map[string]string) map[string]string {
+	if len(tags) == 0 {
+		return envs
+	}
+
+	if tagConstraints[cloudProvider].keyValue {
+		encodedTags, _ := json.Marshal(tags)
+		envs["TF_VAR_tags"] = string(encodedTags)
+		return envs
+	}
+
+	stringTags := make([]string, 0, len(tags))
+	for key, value := range tags {
+		stringTags = append(stringTags, stringTag(key, value))
+	}
+	sort.Strings(stringTags)
+
+	encodedTags, _ := json.Marshal(stringTags)
+	envs["TF_VAR_tags"] = string(encodedTags)
+
+	return envs
+}
@@ -0,0 +1,21 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	apitelemetry "github.com/kubefirst/kubefirst-api/internal/telemetry"
+)
+
+// telemetry returns the controller's telemetry sink, falling back to the
+// default sink, segment unless the embedder replaced it
+func (clctrl *ClusterController) telemetry() apitelemetry.Telemetry {
+	if clctrl.Telemetry != nil {
+		return clctrl.Telemetry
+	}
+
+	return apitelemetry.Default()
+}
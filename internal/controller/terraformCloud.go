@@ -0,0 +1,54 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"path/filepath"
+
+	terraformext "github.com/kubefirst/kubefirst-api/extensions/terraform"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// terraformApply applies the entrypoint locally, or as a terraform cloud run
+// when the cluster executes terraform in terraform cloud - the run is
+// recorded on the cluster as soon as it's queued so its url can be followed
+func (clctrl *ClusterController) terraformApply(tfEntrypoint string, tfEnvs map[string]string) error {
+	if !terraformext.TerraformCloudEnabled(clctrl.TerraformCloud) {
+		return terraformext.InitApplyAutoApprove(clctrl.ProviderConfig.TerraformClient, tfEntrypoint, tfEnvs)
+	}
+
+	run, err := terraformext.CloudApplyAutoApprove(clctrl.TerraformCloud, clctrl.ClusterName, tfEntrypoint, tfEnvs, func(run pkgtypes.TerraformCloudRun) {
+		clctrl.recordTerraformCloudRun(tfEntrypoint, run)
+	})
+	if run.RunID != "" {
+		clctrl.recordTerraformCloudRun(tfEntrypoint, run)
+	}
+
+	return err
+}
+
+// recordTerraformCloudRun stores the entrypoint's latest terraform cloud run
+// on the cluster record, failures are logged since the run itself carries on
+func (clctrl *ClusterController) recordTerraformCloudRun(tfEntrypoint string, run pkgtypes.TerraformCloudRun) {
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		clctrl.logger().Warnf("error recording terraform cloud run %s: %s", run.URL, err)
+		return
+	}
+	clctrl.Cluster = cl
+
+	if clctrl.Cluster.TerraformCloudRuns == nil {
+		clctrl.Cluster.TerraformCloudRuns = map[string]pkgtypes.TerraformCloudRun{}
+	}
+	clctrl.Cluster.TerraformCloudRuns[filepath.Base(tfEntrypoint)] = run
+
+	err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
+	if err != nil {
+		clctrl.logger().Warnf("error recording terraform cloud run %s: %s", run.URL, err)
+	}
+}
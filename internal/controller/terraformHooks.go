@@ -0,0 +1,209 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+	"github.com/kubefirst/kubefirst-api/internal/constants"
+	"github.com/kubefirst/kubefirst-api/internal/env"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+const (
+	terraformHookStagePre  = "pre"
+	terraformHookStagePost = "post"
+
+	// terraformHookTimeout bounds hooks that don't set timeout_seconds
+	terraformHookTimeout = 5 * time.Minute
+
+	// terraformHookOutputLimit is how much of the end of a hook's output is
+	// kept on the cluster record
+	terraformHookOutputLimit = 4096
+)
+
+// terraformHooksMode holds whether definitions may run terraform hooks on the
+// api host, it comes from ALLOW_TERRAFORM_HOOKS unless set with
+// SetTerraformHooksAllowed
+var terraformHooksMode = struct {
+	sync.Mutex
+	allowed bool
+	set     bool
+}{}
+
+// SetTerraformHooksAllowed turns running terraform hooks on or off
+func SetTerraformHooksAllowed(allowed bool) {
+	terraformHooksMode.Lock()
+	defer terraformHooksMode.Unlock()
+
+	terraformHooksMode.allowed = allowed
+	terraformHooksMode.set = true
+}
+
+// TerraformHooksAllowed reports whether the operator allowed terraform hooks,
+// they run commands from the cluster definition on the api host
+func TerraformHooksAllowed() bool {
+	terraformHooksMode.Lock()
+	defer terraformHooksMode.Unlock()
+
+	if !terraformHooksMode.set {
+		env, _ := env.GetEnv(constants.SilenceGetEnv)
+		terraformHooksMode.allowed = env.AllowTerraformHooks
+		terraformHooksMode.set = true
+	}
+
+	return terraformHooksMode.allowed
+}
+
+// validateTerraformHooks makes sure hooks are allowed, every hook has a
+// command and only names entrypoints the cluster has
+func validateTerraformHooks(def *pkgtypes.ClusterDefinition) error {
+	if len(def.TerraformHooks.Pre) == 0 && len(def.TerraformHooks.Post) == 0 {
+		return nil
+	}
+	if !TerraformHooksAllowed() {
+		return fmt.Errorf("terraform_hooks are not allowed on this api, set ALLOW_TERRAFORM_HOOKS to run them")
+	}
+
+	entrypoints := []string{def.CloudProvider, def.GitProvider, "vault", "users"}
+
+	for stage, hooks := range map[string][]pkgtypes.TerraformHook{
+		terraformHookStagePre:  def.TerraformHooks.Pre,
+		terraformHookStagePost: def.TerraformHooks.Post,
+	} {
+		for i, hook := range hooks {
+			if strings.TrimSpace(hook.Command) == "" {
+				return fmt.Errorf("terraform_hooks.%s[%d].command is required", stage, i)
+			}
+			if hook.TimeoutSeconds < 0 {
+				return fmt.Errorf("terraform_hooks.%s[%d].timeout_seconds must not be negative", stage, i)
+			}
+			for _, entrypoint := range hook.Entrypoints {
+				if !pkg.FindStringInSlice(entrypoints, entrypoint) {
+					return fmt.Errorf("terraform_hooks.%s[%d] names unknown entrypoint %s: must be one of %v", stage, i, entrypoint, entrypoints)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// runTerraformHooks runs the stage's hooks for the terraform entrypoint in
+// order, records each run on the cluster and stops at the first failure
+func (clctrl *ClusterController) runTerraformHooks(stage string, tfEntrypoint string) error {
+	hooks := clctrl.TerraformHooks.Pre
+	if stage == terraformHookStagePost {
+		hooks = clctrl.TerraformHooks.Post
+	}
+	// records saved before hooks were turned off keep their hooks
+	if len(hooks) > 0 && !TerraformHooksAllowed() {
+		return fmt.Errorf("cluster %s has terraform hooks but they are not allowed on this api, set ALLOW_TERRAFORM_HOOKS to run them", clctrl.ClusterName)
+	}
+
+	entrypoint := filepath.Base(tfEntrypoint)
+	for _, hook := range hooks {
+		if len(hook.Entrypoints) > 0 && !pkg.FindStringInSlice(hook.Entrypoints, entrypoint) {
+			continue
+		}
+
+		clctrl.logger().Infof("running %s-terraform hook for %s: %s", stage, entrypoint, hook.Command)
+		result, err := clctrl.runTerraformHook(hook, stage, tfEntrypoint)
+		clctrl.recordTerraformHookResult(result)
+		if err != nil {
+			clctrl.logger().Errorf("%s-terraform hook for %s failed: %s\n%s", stage, entrypoint, err, result.Output)
+			return fmt.Errorf("%s-terraform hook %q for %s failed: %s", stage, hook.Command, entrypoint, err)
+		}
+		clctrl.logger().Infof("%s-terraform hook for %s succeeded:\n%s", stage, entrypoint, result.Output)
+	}
+
+	return nil
+}
+
+// runTerraformHook runs a single hook with sh, the hook is told which cluster
+// and entrypoint it runs for through KUBEFIRST_* environment variables. It
+// only gets PATH from the api's environment, which holds its credentials
+func (clctrl *ClusterController) runTerraformHook(hook pkgtypes.TerraformHook, stage string, tfEntrypoint string) (pkgtypes.TerraformHookResult, error) {
+	result := pkgtypes.TerraformHookResult{
+		Entrypoint: filepath.Base(tfEntrypoint),
+		Stage:      stage,
+		Command:    hook.Command,
+		RanAt:      time.Now().UTC(),
+	}
+
+	dir := tfEntrypoint
+	if hook.WorkingDir != "" {
+		dir = hook.WorkingDir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(clctrl.ProviderConfig.GitopsDir, dir)
+		}
+	}
+
+	timeout := terraformHookTimeout
+	if hook.TimeoutSeconds > 0 {
+		timeout = time.Duration(hook.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", hook.Command)
+	cmd.Dir = dir
+	// processes the hook started can hold its output open after it's killed
+	cmd.WaitDelay = 2 * time.Second
+	cmd.Env = []string{
+		fmt.Sprintf("PATH=%s", os.Getenv("PATH")),
+		fmt.Sprintf("KUBEFIRST_CLUSTER_NAME=%s", clctrl.ClusterName),
+		fmt.Sprintf("KUBEFIRST_CLOUD_PROVIDER=%s", clctrl.CloudProvider),
+		fmt.Sprintf("KUBEFIRST_GIT_PROVIDER=%s", clctrl.GitProvider),
+		fmt.Sprintf("KUBEFIRST_TERRAFORM_ENTRYPOINT=%s", result.Entrypoint),
+		fmt.Sprintf("KUBEFIRST_TERRAFORM_DIR=%s", tfEntrypoint),
+		fmt.Sprintf("KUBEFIRST_HOOK_STAGE=%s", stage),
+	}
+
+	output, err := cmd.CombinedOutput()
+	result.Output = string(output)
+	if len(result.Output) > terraformHookOutputLimit {
+		result.Output = result.Output[len(result.Output)-terraformHookOutputLimit:]
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	result.Passed = true
+
+	return result, nil
+}
+
+// recordTerraformHookResult appends a hook run to the cluster record, failures
+// are logged since the hook already ran
+func (clctrl *ClusterController) recordTerraformHookResult(result pkgtypes.TerraformHookResult) {
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		clctrl.logger().Warnf("error recording terraform hook result: %s", err)
+		return
+	}
+	clctrl.Cluster = cl
+
+	clctrl.Cluster.TerraformHookResults = append(clctrl.Cluster.TerraformHookResults, result)
+
+	err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
+	if err != nil {
+		clctrl.logger().Warnf("error recording terraform hook result: %s", err)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestRunTerraformHook(t *testing.T) {
+	tfEntrypoint := t.TempDir()
+	clctrl := &ClusterController{ClusterName: "kf-test"}
+
+	result, err := clctrl.runTerraformHook(pkgtypes.TerraformHook{Command: "echo $KUBEFIRST_CLUSTER_NAME $KUBEFIRST_HOOK_STAGE; pwd"}, terraformHookStagePre, tfEntrypoint)
+	if err != nil || !result.Passed {
+		t.Fatalf("runTerraformHook() = %+v, %v, want passed", result, err)
+	}
+	if !strings.Contains(result.Output, "kf-test pre") || !strings.Contains(result.Output, tfEntrypoint) {
+		t.Errorf("runTerraformHook() output = %q, want the cluster name, stage and entrypoint directory", result.Output)
+	}
+
+	result, err = clctrl.runTerraformHook(pkgtypes.TerraformHook{Command: "echo failing; exit 3"}, terraformHookStagePost, tfEntrypoint)
+	if err == nil || result.Passed || !strings.Contains(result.Output, "failing") {
+		t.Errorf("runTerraformHook() = %+v, %v, want a failure with its output", result, err)
+	}
+
+	_, err = clctrl.runTerraformHook(pkgtypes.TerraformHook{Command: "sleep 30", TimeoutSeconds: 1}, terraformHookStagePre, tfEntrypoint)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("runTerraformHook() error = %v, want a timeout", err)
+	}
+
+	t.Setenv("CIVO_TOKEN", "secret-token")
+	result, err = clctrl.runTerraformHook(pkgtypes.TerraformHook{Command: "env"}, terraformHookStagePre, tfEntrypoint)
+	if err != nil {
+		t.Fatalf("runTerraformHook() error = %v", err)
+	}
+	if strings.Contains(result.Output, "secret-token") || !strings.Contains(result.Output, "PATH=") {
+		t.Errorf("runTerraformHook() environment = %q, want PATH and the KUBEFIRST_* variables only", result.Output)
+	}
+}
+
+func TestValidateTerraformHooksAllowed(t *testing.T) {
+	defer SetTerraformHooksAllowed(false)
+
+	def := &pkgtypes.ClusterDefinition{
+		CloudProvider:  "civo",
+		GitProvider:    "github",
+		TerraformHooks: pkgtypes.TerraformHooks{Pre: []pkgtypes.TerraformHook{{Command: "true"}}},
+	}
+	for allowed, valid := range map[bool]bool{false: false, true: true} {
+		SetTerraformHooksAllowed(allowed)
+		if err := validateTerraformHooks(def); (err == nil) != valid {
+			t.Errorf("allowed %v: validateTerraformHooks() = %v, want valid %v", allowed, err, valid)
+		}
+	}
+
+	clctrl := &ClusterController{ClusterName: "kf-test", TerraformHooks: def.TerraformHooks}
+	SetTerraformHooksAllowed(false)
+	if err := clctrl.runTerraformHooks(terraformHookStagePre, t.TempDir()); err == nil {
+		t.Error("runTerraformHooks() ran hooks that are not allowed")
+	}
+}
@@ -14,7 +14,6 @@ import (
 	awsinternal "github.com/kubefirst/kubefirst-api/pkg/aws"
 	google "github.com/kubefirst/kubefirst-api/pkg/google"
 	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
-	log "github.com/rs/zerolog/log"
 )
 
 // DownloadTools
@@ -27,7 +26,7 @@ func (clctrl *ClusterController) DownloadTools(toolsDir string) error {
 	}
 
 	if !cl.InstallToolsCheck {
-		log.Info().Msg("installing kubefirst dependencies")
+		clctrl.logger().Info("installing kubefirst dependencies")
 
 		switch cl.CloudProvider {
 		case "akamai":
@@ -40,7 +39,7 @@ func (clctrl *ClusterController) DownloadTools(toolsDir string) error {
 				toolsDir,
 			)
 			if err != nil {
-				log.Error().Msgf("error downloading dependencies: %s", err)
+				clctrl.logger().Errorf("error downloading dependencies: %s", err)
 				return err
 			}
 		case "aws":
@@ -50,7 +49,7 @@ func (clctrl *ClusterController) DownloadTools(toolsDir string) error {
 				providerConfigs.TerraformClientVersion,
 			)
 			if err != nil {
-				log.Error().Msgf("error downloading dependencies: %s", err)
+				clctrl.logger().Errorf("error downloading dependencies: %s", err)
 				return err
 			}
 		case "civo":
@@ -63,7 +62,7 @@ func (clctrl *ClusterController) DownloadTools(toolsDir string) error {
 				toolsDir,
 			)
 			if err != nil {
-				log.Error().Msgf("error downloading dependencies: %s", err)
+				clctrl.logger().Errorf("error downloading dependencies: %s", err)
 				return err
 			}
 		case "google":
@@ -76,7 +75,7 @@ func (clctrl *ClusterController) DownloadTools(toolsDir string) error {
 				toolsDir,
 			)
 			if err != nil {
-				log.Error().Msgf("error downloading dependencies: %s", err)
+				clctrl.logger().Errorf("error downloading dependencies: %s", err)
 				return err
 			}
 		case "digitalocean":
@@ -89,7 +88,7 @@ func (clctrl *ClusterController) DownloadTools(toolsDir string) error {
 				toolsDir,
 			)
 			if err != nil {
-				log.Error().Msgf("error downloading dependencies: %s", err)
+				clctrl.logger().Errorf("error downloading dependencies: %s", err)
 				return err
 			}
 		case "vultr":
@@ -102,7 +101,7 @@ func (clctrl *ClusterController) DownloadTools(toolsDir string) error {
 				toolsDir,
 			)
 			if err != nil {
-				log.Error().Msgf("error downloading dependencies: %s", err)
+				clctrl.logger().Errorf("error downloading dependencies: %s", err)
 				return err
 			}
 
@@ -118,11 +117,11 @@ func (clctrl *ClusterController) DownloadTools(toolsDir string) error {
 				toolsDir,
 			)
 			if err != nil {
-				log.Fatal().Msgf("error downloading dependencies: %s", err)
+				clctrl.logger().Errorf("error downloading dependencies: %s", err)
 				return err
 			}
 		}
-		log.Info().Msg("dependency downloads complete")
+		clctrl.logger().Info("dependency downloads complete")
 
 		clctrl.Cluster.InstallToolsCheck = true
 		err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
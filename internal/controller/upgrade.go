@@ -0,0 +1,249 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	githttps "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/kubefirst/kubefirst-api/internal/gitClient"
+	"github.com/kubefirst/kubefirst-api/internal/github"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// UpgradeGitops merges the changes between the gitops template the cluster was
+// created from and the template at targetRef into the cluster's gitops
+// repository, then commits and pushes them so argocd rolls them out
+//
+// Files the cluster has not modified take the new template content, files
+// changed on both sides are merged when the template changes apply cleanly
+// and are otherwise reported as conflicts and left untouched. With dryRun the
+// merge is only previewed.
+func (clctrl *ClusterController) UpgradeGitops(targetRef string, dryRun bool) (pkgtypes.GitopsUpgradeResult, error) {
+	if !dryRun {
+		err := CheckWritable()
+		if err != nil {
+			return pkgtypes.GitopsUpgradeResult{}, err
+		}
+
+		err = clctrl.VerifyConnectivity()
+		if err != nil {
+			return pkgtypes.GitopsUpgradeResult{}, err
+		}
+	}
+
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return pkgtypes.GitopsUpgradeResult{}, err
+	}
+	_, err = github.RefreshGitAuth(&cl.GitAuth)
+	if err != nil {
+		return pkgtypes.GitopsUpgradeResult{}, err
+	}
+
+	workDir, err := os.MkdirTemp("", fmt.Sprintf("gitops-upgrade-%s-", clctrl.ClusterName))
+	if err != nil {
+		return pkgtypes.GitopsUpgradeResult{}, err
+	}
+	defer os.RemoveAll(workDir)
+
+	clusterDir := filepath.Join(workDir, "cluster")
+	baseDir := filepath.Join(workDir, "base")
+	targetDir := filepath.Join(workDir, "target")
+
+	repoURL := clctrl.ProviderConfig.DestinationGitopsRepoURL
+	clusterRepo, err := gitClient.ClonePrivateRepo(gitClient.BranchOrDefault(cl.DefaultBranch), clusterDir, repoURL, cl.GitAuth.User, cl.GitAuth.Token)
+	if err != nil {
+		return pkgtypes.GitopsUpgradeResult{}, fmt.Errorf("error cloning gitops repository %s: %s", repoURL, err)
+	}
+
+	_, err = clctrl.renderGitopsTemplate(cl, cl.GitopsTemplateBranch, cl.GitopsTemplateResolvedCommit, baseDir, clusterDir)
+	if err != nil {
+		return pkgtypes.GitopsUpgradeResult{}, err
+	}
+	targetCommit, err := clctrl.renderGitopsTemplate(cl, targetRef, "", targetDir, clusterDir)
+	if err != nil {
+		return pkgtypes.GitopsUpgradeResult{}, err
+	}
+
+	base, err := readTree(baseDir)
+	if err != nil {
+		return pkgtypes.GitopsUpgradeResult{}, err
+	}
+	target, err := readTree(targetDir)
+	if err != nil {
+		return pkgtypes.GitopsUpgradeResult{}, err
+	}
+	ours, err := readTree(clusterDir)
+	if err != nil {
+		return pkgtypes.GitopsUpgradeResult{}, err
+	}
+
+	result := pkgtypes.GitopsUpgradeResult{
+		TemplateRef:    targetRef,
+		TemplateCommit: targetCommit,
+		DryRun:         dryRun,
+		Files:          []pkgtypes.GitopsFileDiff{},
+		Conflicts:      []string{},
+	}
+
+	// files that only exist in the cluster's repository were added by its
+	// users and are never touched
+	paths := []string{}
+	for path := range base {
+		paths = append(paths, path)
+	}
+	for path := range target {
+		if _, ok := base[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	mergeChanged := cl.FeatureEnabled(pkgtypes.FeatureGateGitopsUpgradeMerge)
+
+	dmp := diffmatchpatch.New()
+	merged := map[string][]byte{}
+	for _, path := range paths {
+		baseContent, inBase := base[path]
+		targetContent, inTarget := target[path]
+		ourContent, inOurs := ours[path]
+
+		switch {
+		case sameFile(baseContent, inBase, targetContent, inTarget):
+			// template unchanged
+			continue
+		case sameFile(ourContent, inOurs, targetContent, inTarget):
+			// already up to date
+			continue
+		case sameFile(ourContent, inOurs, baseContent, inBase):
+			// not modified by the cluster, take the template content
+			if inTarget {
+				merged[path] = targetContent
+			} else {
+				merged[path] = nil
+			}
+		case inBase && inTarget && inOurs && mergeChanged:
+			patches := dmp.PatchMake(string(baseContent), string(targetContent))
+			content, applied := dmp.PatchApply(patches, string(ourContent))
+			clean := true
+			for _, ok := range applied {
+				clean = clean && ok
+			}
+			if !clean {
+				result.Conflicts = append(result.Conflicts, path)
+				continue
+			}
+			merged[path] = []byte(content)
+		default:
+			result.Conflicts = append(result.Conflicts, path)
+			continue
+		}
+
+		fileDiff := pkgtypes.GitopsFileDiff{Path: path, Status: GitopsFileModified}
+		switch {
+		case merged[path] == nil:
+			fileDiff.Status = GitopsFileRemoved
+			fileDiff.LinesRemoved = countLines(ourContent)
+		case !inOurs:
+			fileDiff.Status = GitopsFileAdded
+			fileDiff.LinesAdded = countLines(merged[path])
+		default:
+			a, b, lines := dmp.DiffLinesToChars(string(ourContent), string(merged[path]))
+			for _, d := range dmp.DiffCharsToLines(dmp.DiffMain(a, b, false), lines) {
+				switch d.Type {
+				case diffmatchpatch.DiffInsert:
+					fileDiff.LinesAdded += countLines([]byte(d.Text))
+				case diffmatchpatch.DiffDelete:
+					fileDiff.LinesRemoved += countLines([]byte(d.Text))
+				}
+			}
+		}
+		result.Files = append(result.Files, fileDiff)
+	}
+
+	if len(result.Conflicts) != 0 {
+		clctrl.logger().Warnf("gitops upgrade of cluster %s to %s has conflicts requiring manual resolution: %v", clctrl.ClusterName, targetRef, result.Conflicts)
+	}
+
+	if dryRun || len(merged) == 0 {
+		return result, nil
+	}
+
+	w, err := clusterRepo.Worktree()
+	if err != nil {
+		return result, err
+	}
+	for path, content := range merged {
+		fullPath := filepath.Join(clusterDir, filepath.FromSlash(path))
+		if content == nil {
+			_, err = w.Remove(path)
+			if err != nil {
+				return result, fmt.Errorf("error removing %s: %s", path, err)
+			}
+			continue
+		}
+
+		err = os.MkdirAll(filepath.Dir(fullPath), 0o755)
+		if err != nil {
+			return result, err
+		}
+		err = os.WriteFile(fullPath, content, 0o644)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	err = gitClient.Commit(clusterRepo, fmt.Sprintf("upgrading gitops repository to gitops-template %s (%s)", targetRef, targetCommit))
+	if err != nil {
+		return result, err
+	}
+
+	err = clusterRepo.Push(&git.PushOptions{
+		RemoteName: "origin",
+		Auth: &githttps.BasicAuth{
+			Username: cl.GitAuth.User,
+			Password: cl.GitAuth.Token,
+		},
+	})
+	if err != nil {
+		return result, fmt.Errorf("error pushing gitops repository %s: %s", repoURL, err)
+	}
+
+	head, err := clusterRepo.Head()
+	if err != nil {
+		return result, err
+	}
+	result.Commit = head.Hash().String()
+
+	clctrl.logger().Infof("upgraded gitops repository for cluster %s to %s at %s", clctrl.ClusterName, targetRef, result.Commit)
+
+	// conflicting files still differ from the new template, keep the old base
+	// so the next upgrade can merge them once resolved
+	if len(result.Conflicts) == 0 {
+		cl.GitopsTemplateBranch = targetRef
+		cl.GitopsTemplateResolvedCommit = targetCommit
+		err = secrets.UpdateCluster(clctrl.KubernetesClient, cl)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// sameFile reports whether two optional file contents are equal
+func sameFile(a []byte, aExists bool, b []byte, bExists bool) bool {
+	return aExists == bExists && bytes.Equal(a, b)
+}
@@ -0,0 +1,147 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/kubefirst/kubefirst-api/internal/scim"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// directoryUser is a user as the users terraform's directory_users variable
+// expects it
+type directoryUser struct {
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// validateUserDirectory checks the user directory fields of a definition
+// without calling the identity provider
+func validateUserDirectory(def *pkgtypes.ClusterDefinition) error {
+	directory := def.UserDirectory
+	if directory.SCIMURL == "" {
+		if directory.Token != "" || directory.Group != "" {
+			return fmt.Errorf("user_directory.scim_url is required when user_directory.token or user_directory.group is set")
+		}
+		return nil
+	}
+
+	u, err := url.Parse(directory.SCIMURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("invalid user_directory.scim_url %s: must be an https url", directory.SCIMURL)
+	}
+	if directory.Token == "" {
+		return fmt.Errorf("user_directory.token is required when user_directory.scim_url is set")
+	}
+	if def.ExternalSecrets.Provider != "" {
+		return fmt.Errorf("user_directory cannot be set with external_secrets: the users terraform is not applied")
+	}
+
+	return nil
+}
+
+// getDirectoryUsersTerraformEnvs passes the directory's users to the users
+// terraform keyed by username, which creates the ones it doesn't manage yet
+// and removes the ones no longer listed
+func getDirectoryUsersTerraformEnvs(envs map[string]string, users []pkgtypes.DirectoryUser) (map[string]string, error) {
+	byUsername := map[string]directoryUser{}
+	for _, user := range users {
+		byUsername[user.Username] = directoryUser{
+			Email:     user.Email,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+		}
+	}
+
+	value, err := json.Marshal(byUsername)
+	if err != nil {
+		return envs, err
+	}
+	envs["TF_VAR_directory_users"] = string(value)
+
+	return envs, nil
+}
+
+// SyncDirectoryUsers compares the user directory with the users the users
+// terraform created and applies the users terraform again to add and remove
+// the difference, with dryRun the difference is only listed
+func (clctrl *ClusterController) SyncDirectoryUsers(dryRun bool) (pkgtypes.UserSyncResult, error) {
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return pkgtypes.UserSyncResult{}, err
+	}
+	if cl.UserDirectory.SCIMURL == "" {
+		return pkgtypes.UserSyncResult{}, fmt.Errorf("cluster %s has no user directory", clctrl.ClusterName)
+	}
+
+	users, err := scim.ListUsers(cl.UserDirectory)
+	if err != nil {
+		return pkgtypes.UserSyncResult{}, err
+	}
+
+	result := pkgtypes.UserSyncResult{
+		DryRun:  dryRun,
+		Added:   []string{},
+		Removed: []string{},
+	}
+	inDirectory := map[string]bool{}
+	for _, user := range users {
+		inDirectory[user.Username] = true
+	}
+	// kbot and the users the gitops repository defines are kept by the
+	// users terraform, only users synced from the directory are removed
+	provisioned := map[string]bool{}
+	for _, user := range cl.ProvisionedUsers {
+		provisioned[user.Username] = true
+		if user.Directory && !inDirectory[user.Username] {
+			result.Removed = append(result.Removed, user.Username)
+		}
+	}
+	for _, user := range users {
+		if !provisioned[user.Username] {
+			result.Added = append(result.Added, user.Username)
+		}
+	}
+	sort.Strings(result.Removed)
+
+	if dryRun || (len(result.Added) == 0 && len(result.Removed) == 0) {
+		return result, nil
+	}
+
+	err = CheckWritable()
+	if err != nil {
+		return pkgtypes.UserSyncResult{}, err
+	}
+
+	err = clctrl.VerifyConnectivity()
+	if err != nil {
+		return pkgtypes.UserSyncResult{}, err
+	}
+
+	clctrl.logger().Infof("syncing users of cluster %s from its user directory: adding %d, removing %d", clctrl.ClusterName, len(result.Added), len(result.Removed))
+
+	clctrl.UserDirectory = cl.UserDirectory
+	clctrl.Cluster = cl
+	clctrl.Cluster.UsersTerraformApplyCheck = false
+	err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
+	if err != nil {
+		return pkgtypes.UserSyncResult{}, err
+	}
+
+	err = clctrl.RunUsersTerraform()
+	if err != nil {
+		return pkgtypes.UserSyncResult{}, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,55 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestSyncDirectoryUsersDryRun(t *testing.T) {
+	store, err := secrets.NewMemoryStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets.SetStore(store)
+	defer secrets.SetStore(nil)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/scim+json")
+		_, _ = w.Write([]byte(`{"totalResults": 2, "Resources": [{"id": "1", "userName": "alice"}, {"id": "2", "userName": "carol"}]}`))
+	}))
+	defer server.Close()
+
+	err = secrets.InsertCluster(nil, pkgtypes.Cluster{
+		ClusterName:   "kf-test",
+		UserDirectory: pkgtypes.UserDirectory{SCIMURL: server.URL, Token: "token"},
+		ProvisionedUsers: []pkgtypes.ProvisionedUser{
+			{Username: "kbot"},
+			{Username: "admin"},
+			{Username: "alice", Directory: true},
+			{Username: "bob", Directory: true},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clctrl := &ClusterController{ClusterName: "kf-test"}
+	result, err := clctrl.SyncDirectoryUsers(true)
+	if err != nil {
+		t.Fatalf("SyncDirectoryUsers() = %s", err)
+	}
+	if !reflect.DeepEqual(result.Added, []string{"carol"}) || !reflect.DeepEqual(result.Removed, []string{"bob"}) {
+		t.Errorf("added %v, removed %v, want carol added and bob removed", result.Added, result.Removed)
+	}
+}
@@ -7,6 +7,9 @@ See the LICENSE file for more details.
 package controller
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
 	"time"
 
 	akamaiext "github.com/kubefirst/kubefirst-api/extensions/akamai"
@@ -18,13 +21,33 @@ import (
 	terraformext "github.com/kubefirst/kubefirst-api/extensions/terraform"
 	vultrext "github.com/kubefirst/kubefirst-api/extensions/vultr"
 	"github.com/kubefirst/kubefirst-api/internal/k8s"
+	"github.com/kubefirst/kubefirst-api/internal/scim"
 	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	"github.com/kubefirst/kubefirst-api/internal/vault"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
 	"github.com/kubefirst/metrics-client/pkg/telemetry"
-	log "github.com/rs/zerolog/log"
+)
+
+const (
+	// usersVaultMount is the kv mount the users terraform stores initial
+	// credentials in, one secret per username
+	usersVaultMount = "users"
+
+	usersInitialPasswordKey = "initial-password"
 )
 
 // RunUsersTerraform
 func (clctrl *ClusterController) RunUsersTerraform() error {
+	if clctrl.UsesExternalSecrets() {
+		clctrl.logger().Infof("skipping users terraform: platform secrets are stored in %s", clctrl.ExternalSecrets.Provider)
+		return nil
+	}
+
+	err := clctrl.refreshGitToken()
+	if err != nil {
+		return err
+	}
+
 	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
 	if err != nil {
 		return err
@@ -46,8 +69,8 @@ func (clctrl *ClusterController) RunUsersTerraform() error {
 			}
 		}
 
-		telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.UsersTerraformApplyStarted, "")
-		log.Info().Msg("applying users terraform")
+		clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.UsersTerraformApplyStarted, "")
+		clctrl.logger().Info("applying users terraform")
 
 		tfEnvs := map[string]string{}
 		var tfEntrypoint, terraformClient string
@@ -75,26 +98,64 @@ func (clctrl *ClusterController) RunUsersTerraform() error {
 			tfEnvs = k3sext.GetK3sTerraformEnvs(tfEnvs, &cl)
 			tfEnvs = k3sext.GetUsersTerraformEnvs(kcfg.Clientset, &cl, tfEnvs)
 		}
+		fromDirectory := map[string]bool{}
+		if cl.UserDirectory.SCIMURL != "" {
+			directoryUsers, err := scim.ListUsers(cl.UserDirectory)
+			if err != nil {
+				return fmt.Errorf("error listing users of the user directory: %s", err)
+			}
+			for _, user := range directoryUsers {
+				fromDirectory[user.Username] = true
+			}
+			tfEnvs, err = getDirectoryUsersTerraformEnvs(tfEnvs, directoryUsers)
+			if err != nil {
+				return err
+			}
+			clctrl.logger().Infof("syncing %d users from the user directory", len(directoryUsers))
+		}
 		tfEntrypoint = clctrl.ProviderConfig.GitopsDir + "/terraform/users"
 		terraformClient = clctrl.ProviderConfig.TerraformClient
+		err = clctrl.runTerraformHooks(terraformHookStagePre, tfEntrypoint)
+		if err != nil {
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.UsersTerraformApplyFailed, err.Error())
+			return err
+		}
 		err = terraformext.InitApplyAutoApprove(terraformClient, tfEntrypoint, tfEnvs)
 		if err != nil {
-			log.Error().Msgf("error applying users terraform: %s", err)
-			log.Info().Msg("sleeping 10 seconds before retrying terraform execution once more")
+			clctrl.logger().Errorf("error applying users terraform: %s", err)
+			clctrl.logger().Info("sleeping 10 seconds before retrying terraform execution once more")
 			time.Sleep(10 * time.Second)
 			err = terraformext.InitApplyAutoApprove(terraformClient, tfEntrypoint, tfEnvs)
 			if err != nil {
-				log.Error().Msgf("error applying users terraform: %s", err)
-				telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.UsersTerraformApplyFailed, err.Error())
+				clctrl.logger().Errorf("error applying users terraform: %s", err)
+				clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.UsersTerraformApplyFailed, err.Error())
 				return err
 			}
 		}
-		log.Info().Msg("executed users terraform successfully")
-		telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.UsersTerraformApplyCompleted, "")
+		err = clctrl.runTerraformHooks(terraformHookStagePost, tfEntrypoint)
+		if err != nil {
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.UsersTerraformApplyFailed, err.Error())
+			return err
+		}
+		clctrl.logger().Info("executed users terraform successfully")
+		clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.UsersTerraformApplyCompleted, "")
 
 		clctrl.VaultAuth.RootToken = tfEnvs["VAULT_TOKEN"]
 
 		clctrl.Cluster.VaultAuth.RootToken = clctrl.VaultAuth.RootToken
+
+		// the users exist at this point, failing to list them only loses
+		// the record of where their credentials are
+		users, err := provisionedUsers(terraformClient, tfEntrypoint, tfEnvs)
+		if err != nil {
+			clctrl.logger().Warnf("error reading the users created by the users terraform: %s", err)
+		} else {
+			for i := range users {
+				users[i].Directory = fromDirectory[users[i].Username]
+			}
+			clctrl.Cluster.ProvisionedUsers = users
+			clctrl.logger().Infof("users terraform created %d users, their initial credentials are stored in vault", len(users))
+		}
 		err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
 		if err != nil {
 			return err
@@ -103,7 +164,7 @@ func (clctrl *ClusterController) RunUsersTerraform() error {
 		// Set kbot password in object
 		err = clctrl.GetUserPassword("kbot")
 		if err != nil {
-			log.Info().Msgf("error fetching kbot password: %s", err)
+			clctrl.logger().Infof("error fetching kbot password: %s", err)
 		}
 
 		clctrl.Cluster.UsersTerraformApplyCheck = true
@@ -115,3 +176,61 @@ func (clctrl *ClusterController) RunUsersTerraform() error {
 
 	return nil
 }
+
+// provisionedUsers reads the users the users terraform created from its
+// users output, a list of usernames or a map keyed by username, and falls
+// back to the secrets under vault's users mount when there's no such output
+func provisionedUsers(terraformClient string, tfEntrypoint string, tfEnvs map[string]string) ([]pkgtypes.ProvisionedUser, error) {
+	outputs, err := terraformext.InitOutput(terraformClient, tfEntrypoint, tfEnvs)
+	if err != nil {
+		return nil, err
+	}
+
+	usernames, err := usernamesFromOutput(outputs["users"].Value)
+	if err != nil {
+		return nil, err
+	}
+	if len(usernames) == 0 {
+		usernames, err = vault.Conf.ListUsers(vault.VaultDefaultAddress, tfEnvs["VAULT_TOKEN"])
+		if err != nil {
+			return nil, fmt.Errorf("error listing vault users: %s", err)
+		}
+	}
+	sort.Strings(usernames)
+
+	users := make([]pkgtypes.ProvisionedUser, 0, len(usernames))
+	for _, username := range usernames {
+		users = append(users, pkgtypes.ProvisionedUser{
+			Username:   username,
+			VaultMount: usersVaultMount,
+			VaultPath:  fmt.Sprintf("%s/%s", usersVaultMount, username),
+			VaultKey:   usersInitialPasswordKey,
+		})
+	}
+
+	return users, nil
+}
+
+// usernamesFromOutput returns the usernames of a users output value, only
+// the keys of a map are read so credentials in its values are never touched
+func usernamesFromOutput(value json.RawMessage) ([]string, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	var usernames []string
+	if json.Unmarshal(value, &usernames) == nil {
+		return usernames, nil
+	}
+
+	var byUsername map[string]json.RawMessage
+	err := json.Unmarshal(value, &byUsername)
+	if err != nil {
+		return nil, fmt.Errorf("the users output must be a list of usernames or a map keyed by username")
+	}
+	for username := range byUsername {
+		usernames = append(usernames, username)
+	}
+
+	return usernames, nil
+}
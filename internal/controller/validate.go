@@ -0,0 +1,319 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+	"github.com/kubefirst/kubefirst-api/internal/dnsProvider"
+	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// gitCommitRegex matches abbreviated and full git commit shas
+var gitCommitRegex = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// gitBranchRegex matches the branch names accepted for default_branch, a
+// conservative subset of what git allows
+var gitBranchRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+(/[A-Za-z0-9_.-]+)*$`)
+
+// ValidateDefinition checks a cluster definition for values that would
+// otherwise only fail part way through provisioning - it does not contact
+// any external service
+func ValidateDefinition(def *pkgtypes.ClusterDefinition) error {
+	err := pkgtypes.ValidateSchemaVersion(def.SchemaVersion)
+	if err != nil {
+		return err
+	}
+
+	if def.GitopsTemplateURL != "" && def.GitopsTemplateBranch == "" {
+		return fmt.Errorf("must supply branch of gitops template repo when supplying a gitops template url")
+	}
+
+	if def.GitopsTemplateCommit != "" && !gitCommitRegex.MatchString(def.GitopsTemplateCommit) {
+		return fmt.Errorf("invalid gitops template commit %s, expected a commit sha", def.GitopsTemplateCommit)
+	}
+
+	if def.DefaultBranch != "" && (!gitBranchRegex.MatchString(def.DefaultBranch) || strings.Contains(def.DefaultBranch, "..") || strings.HasSuffix(def.DefaultBranch, ".lock")) {
+		return fmt.Errorf("invalid default branch %s", def.DefaultBranch)
+	}
+
+	if def.DnsProvider != "" && !pkg.FindStringInSlice(dnsProvider.SupportedDNSProviders, dnsProvider.Normalize(def.DnsProvider)) {
+		return fmt.Errorf("unsupported dns provider %s, must be one of %v", def.DnsProvider, dnsProvider.SupportedDNSProviders)
+	}
+	if def.DnsProvider != "" && def.CloudProvider != "k3d" {
+		err := dnsProvider.ValidateCloudProvider(def.DnsProvider, def.CloudProvider)
+		if err != nil {
+			return err
+		}
+	}
+
+	if def.DNSTTL != 0 {
+		if def.DnsProvider == "" {
+			return fmt.Errorf("dns_ttl requires a dns_provider")
+		}
+		err := dnsProvider.ValidateRecordTTL(def.DnsProvider, def.DNSTTL)
+		if err != nil {
+			return err
+		}
+	}
+
+	for field, seconds := range map[string]int{
+		"timeouts.cluster_create": def.Timeouts.ClusterCreate,
+		"timeouts.node_ready":     def.Timeouts.NodeReady,
+		"timeouts.vault_ready":    def.Timeouts.VaultReady,
+	} {
+		if seconds < 0 {
+			return fmt.Errorf("invalid %s %d, must be a positive number of seconds", field, seconds)
+		}
+	}
+
+	if def.ExistingStateStoreBucket != "" {
+		switch def.CloudProvider {
+		case "aws", "digitalocean", "google":
+		default:
+			return fmt.Errorf("using an existing state store bucket is not supported for %s", def.CloudProvider)
+		}
+	}
+
+	if def.StateStoreKMSKey != "" {
+		switch def.CloudProvider {
+		case "aws", "google":
+		default:
+			return fmt.Errorf("state store kms key encryption is not supported for %s", def.CloudProvider)
+		}
+		// the encryption of a bucket kubefirst didn't create is left alone
+		if def.ExistingStateStoreBucket != "" {
+			return fmt.Errorf("state_store_kms_key can't be used with existing_state_store_bucket, configure the encryption of the existing bucket instead")
+		}
+	}
+
+	err = validateKubeconfigContextName(def)
+	if err != nil {
+		return err
+	}
+
+	err = validateTags(def.CloudProvider, def.Tags)
+	if err != nil {
+		return err
+	}
+
+	err = validateNodeLabels(def.NodeLabels)
+	if err != nil {
+		return err
+	}
+
+	err = validateNodeTaints(def.NodeTaints)
+	if err != nil {
+		return err
+	}
+
+	err = validateSpotNodePool(def)
+	if err != nil {
+		return err
+	}
+
+	err = validateNodePoolAutoscaling(def)
+	if err != nil {
+		return err
+	}
+
+	err = validateCNI(def)
+	if err != nil {
+		return err
+	}
+
+	if def.ConsoleImage != "" {
+		if _, ok := def.ImageOverrides["console"]; ok {
+			return fmt.Errorf("set either console_image or image_overrides.console, not both")
+		}
+		_, err = providerConfigs.ConsoleImage(def.ConsoleImage)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = validateImageOverrides(def.ImageOverrides)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := providerConfigs.InstallProfiles[def.InstallProfile]; def.InstallProfile != "" && !ok {
+		return fmt.Errorf("unknown install profile %s: must be one of %v", def.InstallProfile, providerConfigs.InstallProfileNames())
+	}
+
+	if _, ok := providerConfigs.IngressControllers[def.IngressController]; def.IngressController != "" && !ok {
+		return fmt.Errorf("unsupported ingress controller %s: must be one of %v", def.IngressController, providerConfigs.IngressControllerNames())
+	}
+
+	err = validateLoadBalancerAnnotations(def.LoadBalancerAnnotations)
+	if err != nil {
+		return err
+	}
+
+	err = validateKubernetesVersion(def.KubernetesVersion)
+	if err != nil {
+		return err
+	}
+
+	err = validateAPIURL(def)
+	if err != nil {
+		return err
+	}
+
+	err = providerConfigs.ValidateTerraformBackend(def.TerraformBackend)
+	if err != nil {
+		return err
+	}
+
+	if def.TerraformCloud.Organization == "" && (def.TerraformCloud.Hostname != "" || def.TerraformCloud.WorkspacePrefix != "") {
+		return fmt.Errorf("terraform_cloud.organization is required when terraform cloud is configured")
+	}
+
+	err = validateTerraformHooks(def)
+	if err != nil {
+		return err
+	}
+
+	err = validateGitopsMirrors(def.GitopsMirrors)
+	if err != nil {
+		return err
+	}
+
+	err = validateWebhookSecret(def.AtlantisWebhookSecret)
+	if err != nil {
+		return err
+	}
+
+	if def.MetaphorOwner != "" && def.GitProvider != "github" {
+		return fmt.Errorf("metaphor_owner is only supported with github")
+	}
+
+	if def.GitAuth.UsesGitHubApp() {
+		if def.GitProvider != "github" {
+			return fmt.Errorf("git_auth.app_id is only supported with github")
+		}
+		if def.GitAuth.AppInstallationID == 0 || def.GitAuth.AppPrivateKey == "" {
+			return fmt.Errorf("git_auth.app_installation_id and git_auth.app_private_key are required with git_auth.app_id")
+		}
+		// an app installation belongs to a single account
+		if def.MetaphorOwner != "" && !strings.EqualFold(def.MetaphorOwner, def.GitAuth.Owner) {
+			return fmt.Errorf("metaphor_owner is not supported with github app authentication: an app installation covers a single owner")
+		}
+	}
+
+	if def.ForceAdoptRepositories && !def.AdoptRepositories {
+		return fmt.Errorf("force_adopt_repositories requires adopt_repositories")
+	}
+
+	if def.AdoptRepositories && def.TerraformCloud.Organization != "" {
+		return fmt.Errorf("adopt_repositories is not supported with terraform_cloud: adopted repositories are imported into local terraform state")
+	}
+
+	if def.TerraformCloud.Organization != "" && def.TerraformBackend.Type != "" {
+		return fmt.Errorf("terraform_backend and terraform_cloud cannot both be set: terraform cloud stores the state of its runs")
+	}
+
+	err = validateImagePullSecrets(def.ImagePullSecrets)
+	if err != nil {
+		return err
+	}
+
+	err = validateExistingNetwork(def)
+	if err != nil {
+		return err
+	}
+
+	err = validateEgressGateway(def)
+	if err != nil {
+		return err
+	}
+
+	err = validateBreakpoints(def)
+	if err != nil {
+		return err
+	}
+
+	err = validateResourceOverrides(def.ResourceProfile, def.ResourceOverrides)
+	if err != nil {
+		return err
+	}
+
+	err = validateVolumeSizes(def.VolumeSizes)
+	if err != nil {
+		return err
+	}
+
+	err = validatePodSecurity(def.PodSecurity)
+	if err != nil {
+		return err
+	}
+
+	err = validateExternalSecrets(def)
+	if err != nil {
+		return err
+	}
+
+	err = validateUserDirectory(def)
+	if err != nil {
+		return err
+	}
+
+	err = validateOIDC(def.OIDC)
+	if err != nil {
+		return err
+	}
+
+	if def.NetworkPolicies && def.Type != "mgmt" {
+		return fmt.Errorf("network_policies is only supported for mgmt clusters")
+	}
+
+	err = validateWorkloadIdentity(def.WorkloadIdentity, def.CloudProvider)
+	if err != nil {
+		return err
+	}
+
+	err = validatePostInstallManifests(def.PostInstallManifests)
+	if err != nil {
+		return err
+	}
+
+	if def.ArgoCDHost != "" {
+		err = validateArgoCDHost(def.ArgoCDHost, def.DomainName)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = validateArgoCDSyncPolicy(def.ArgoCDSyncPolicy)
+	if err != nil {
+		return err
+	}
+
+	err = validateFeatureGates(def.FeatureGates)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateLoadBalancerAnnotations makes sure the load balancer annotations
+// have valid kubernetes annotation keys
+func validateLoadBalancerAnnotations(annotations map[string]string) error {
+	for key := range annotations {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid load balancer annotation key %s: %s", key, strings.Join(errs, ", "))
+		}
+	}
+
+	return nil
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/kubefirst/kubefirst-api/internal/k8s"
 	"github.com/kubefirst/kubefirst-api/internal/secrets"
 	vault "github.com/kubefirst/kubefirst-api/internal/vault"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
 	"github.com/kubefirst/metrics-client/pkg/telemetry"
 	log "github.com/rs/zerolog/log"
 	v1 "k8s.io/api/core/v1"
@@ -60,6 +61,11 @@ func (clctrl *ClusterController) GetUserPassword(user string) error {
 
 // InitializeVault
 func (clctrl *ClusterController) InitializeVault() error {
+	if clctrl.UsesExternalSecrets() {
+		clctrl.logger().Infof("skipping vault initialization: platform secrets are stored in %s", clctrl.ExternalSecrets.Provider)
+		return nil
+	}
+
 	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
 	if err != nil {
 		return err
@@ -83,7 +89,7 @@ func (clctrl *ClusterController) InitializeVault() error {
 			}
 		}
 
-		telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.VaultInitializationStarted, "")
+		clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.VaultInitializationStarted, "")
 
 		switch clctrl.CloudProvider {
 		case "aws", "google":
@@ -137,11 +143,11 @@ func (clctrl *ClusterController) InitializeVault() error {
 			_, err = k8s.WaitForJobComplete(kcfg.Clientset, job, 240)
 			if err != nil {
 				msg := fmt.Sprintf("could not run vault unseal job: %s", err)
-				telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.VaultInitializationFailed, err.Error())
-				log.Error().Msg(msg)
+				clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.VaultInitializationFailed, err.Error())
+				clctrl.logger().Error(msg)
 			}
 		}
-		telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.VaultInitializationCompleted, "")
+		clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.VaultInitializationCompleted, "")
 
 		clctrl.Cluster.VaultInitializedCheck = true
 		err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
@@ -155,98 +161,61 @@ func (clctrl *ClusterController) InitializeVault() error {
 
 // RunVaultTerraform
 func (clctrl *ClusterController) RunVaultTerraform() error {
+	if clctrl.UsesExternalSecrets() {
+		clctrl.logger().Infof("skipping vault terraform: platform secrets are stored in %s", clctrl.ExternalSecrets.Provider)
+		return nil
+	}
+
+	err := clctrl.refreshGitToken()
+	if err != nil {
+		return err
+	}
+
 	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
 	if err != nil {
 		return err
 	}
 
 	if !cl.VaultTerraformApplyCheck {
-		var kcfg *k8s.KubernetesClient
-
-		switch clctrl.CloudProvider {
-		case "aws":
-			kcfg = awsext.CreateEKSKubeconfig(&clctrl.AwsClient.Config, clctrl.ClusterName)
-		case "akamai", "civo", "digitalocean", "k3s", "vultr":
-			kcfg = k8s.CreateKubeConfig(false, clctrl.ProviderConfig.Kubeconfig)
-		case "google":
-			var err error
-			kcfg, err = clctrl.GoogleClient.GetContainerClusterAuth(clctrl.ClusterName, []byte(clctrl.GoogleAuth.KeyFile))
-			if err != nil {
-				return err
-			}
-		}
-
-		telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.VaultTerraformApplyStarted, "")
-
-		tfEnvs := map[string]string{}
-
-		// Common TfEnvs
-		var usernamePasswordString, base64DockerAuth, registryAuth string
-
-		if clctrl.GitProvider == "gitlab" {
-			registryAuth, err = clctrl.ContainerRegistryAuth()
-			if err != nil {
-				return err
-			}
-
-			usernamePasswordString = fmt.Sprintf("%s:%s", "container-registry-auth", registryAuth)
-			base64DockerAuth = base64.StdEncoding.EncodeToString([]byte(usernamePasswordString))
-		} else {
-			usernamePasswordString = fmt.Sprintf("%s:%s", clctrl.GitAuth.User, clctrl.GitAuth.Token)
-			base64DockerAuth = base64.StdEncoding.EncodeToString([]byte(usernamePasswordString))
-		}
-
-		tfEnvs["TF_VAR_b64_docker_auth"] = base64DockerAuth
+		clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.VaultTerraformApplyStarted, "")
 
-		if clctrl.GitProvider == "gitlab" {
-			tfEnvs["TF_VAR_container_registry_auth"] = registryAuth
-			tfEnvs["TF_VAR_owner_group_id"] = strconv.Itoa(clctrl.GitlabOwnerGroupID)
-		}
-
-		// Specific TfEnvs
-		switch clctrl.CloudProvider {
-		case "akamai":
-			tfEnvs = akamaiext.GetVaultTerraformEnvs(kcfg.Clientset, &cl, tfEnvs)
-			tfEnvs = akamaiext.GetAkamaiTerraformEnvs(tfEnvs, &cl)
-		case "aws":
-			tfEnvs = awsext.GetVaultTerraformEnvs(kcfg.Clientset, &cl, tfEnvs)
-			tfEnvs = awsext.GetAwsTerraformEnvs(tfEnvs, &cl)
-		case "civo":
-			tfEnvs = civoext.GetVaultTerraformEnvs(kcfg.Clientset, &cl, tfEnvs)
-			tfEnvs = civoext.GetCivoTerraformEnvs(tfEnvs, &cl)
-		case "google":
-			tfEnvs = googleext.GetVaultTerraformEnvs(kcfg.Clientset, &cl, tfEnvs)
-			tfEnvs = googleext.GetGoogleTerraformEnvs(tfEnvs, &cl)
-		case "digitalocean":
-			tfEnvs = digitaloceanext.GetVaultTerraformEnvs(kcfg.Clientset, &cl, tfEnvs)
-			tfEnvs = digitaloceanext.GetDigitaloceanTerraformEnvs(tfEnvs, &cl)
-		case "vultr":
-			tfEnvs = vultrext.GetVaultTerraformEnvs(kcfg.Clientset, &cl, tfEnvs)
-			tfEnvs = vultrext.GetVultrTerraformEnvs(tfEnvs, &cl)
-		case "k3s":
-			tfEnvs = k3sext.GetVaultTerraformEnvs(kcfg.Clientset, &cl, tfEnvs)
-			tfEnvs = k3sext.GetK3sTerraformEnvs(tfEnvs, &cl)
+		tfEnvs, err := clctrl.vaultTerraformEnvs(cl)
+		if err != nil {
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.VaultTerraformApplyFailed, err.Error())
+			return err
 		}
 
 		tfEntrypoint := clctrl.ProviderConfig.GitopsDir + "/terraform/vault"
 		terraformClient := clctrl.ProviderConfig.TerraformClient
 
-		log.Info().Msg("configuring vault with terraform")
+		err = clctrl.runTerraformHooks(terraformHookStagePre, tfEntrypoint)
+		if err != nil {
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.VaultTerraformApplyFailed, err.Error())
+			return err
+		}
+
+		clctrl.logger().Info("configuring vault with terraform")
 		err = terraformext.InitApplyAutoApprove(terraformClient, tfEntrypoint, tfEnvs)
 		if err != nil {
-			log.Error().Msgf("error applying vault terraform: %s", err)
-			log.Info().Msg("sleeping 10 seconds before retrying terraform execution once more")
+			clctrl.logger().Errorf("error applying vault terraform: %s", err)
+			clctrl.logger().Info("sleeping 10 seconds before retrying terraform execution once more")
 			time.Sleep(10 * time.Second)
 			err = terraformext.InitApplyAutoApprove(terraformClient, tfEntrypoint, tfEnvs)
 			if err != nil {
-				log.Error().Msgf("error applying vault terraform: %s", err)
-				telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.VaultTerraformApplyFailed, err.Error())
+				clctrl.logger().Errorf("error applying vault terraform: %s", err)
+				clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.VaultTerraformApplyFailed, err.Error())
 				return err
 			}
 		}
 
-		log.Info().Msg("vault terraform executed successfully")
-		telemetry.SendEvent(clctrl.TelemetryEvent, telemetry.VaultTerraformApplyCompleted, "")
+		err = clctrl.runTerraformHooks(terraformHookStagePost, tfEntrypoint)
+		if err != nil {
+			clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.VaultTerraformApplyFailed, err.Error())
+			return err
+		}
+
+		clctrl.logger().Info("vault terraform executed successfully")
+		clctrl.telemetry().Transmit(clctrl.TelemetryEvent, telemetry.VaultTerraformApplyCompleted, "")
 
 		clctrl.Cluster.VaultTerraformApplyCheck = true
 		err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
@@ -258,7 +227,90 @@ func (clctrl *ClusterController) RunVaultTerraform() error {
 	return nil
 }
 
+// vaultTerraformEnvs returns the environment the vault terraform of the
+// cluster is applied with
+func (clctrl *ClusterController) vaultTerraformEnvs(cl pkgtypes.Cluster) (map[string]string, error) {
+	var kcfg *k8s.KubernetesClient
+	var err error
+
+	switch clctrl.CloudProvider {
+	case "aws":
+		kcfg = awsext.CreateEKSKubeconfig(&clctrl.AwsClient.Config, clctrl.ClusterName)
+	case "akamai", "civo", "digitalocean", "k3s", "vultr":
+		kcfg = k8s.CreateKubeConfig(false, clctrl.ProviderConfig.Kubeconfig)
+	case "google":
+		kcfg, err = clctrl.GoogleClient.GetContainerClusterAuth(clctrl.ClusterName, []byte(clctrl.GoogleAuth.KeyFile))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tfEnvs := map[string]string{}
+
+	// Common TfEnvs
+	base64DockerAuth, registryAuth, err := clctrl.containerRegistryDockerAuth()
+	if err != nil {
+		return nil, err
+	}
+
+	tfEnvs["TF_VAR_b64_docker_auth"] = base64DockerAuth
+
+	if clctrl.GitProvider == "gitlab" {
+		tfEnvs["TF_VAR_container_registry_auth"] = registryAuth
+		tfEnvs["TF_VAR_owner_group_id"] = strconv.Itoa(clctrl.GitlabOwnerGroupID)
+	}
+
+	// Specific TfEnvs
+	switch clctrl.CloudProvider {
+	case "akamai":
+		tfEnvs = akamaiext.GetVaultTerraformEnvs(kcfg.Clientset, &cl, tfEnvs)
+		tfEnvs = akamaiext.GetAkamaiTerraformEnvs(tfEnvs, &cl)
+	case "aws":
+		tfEnvs = awsext.GetVaultTerraformEnvs(kcfg.Clientset, &cl, tfEnvs)
+		tfEnvs = awsext.GetAwsTerraformEnvs(tfEnvs, &cl)
+	case "civo":
+		tfEnvs = civoext.GetVaultTerraformEnvs(kcfg.Clientset, &cl, tfEnvs)
+		tfEnvs = civoext.GetCivoTerraformEnvs(tfEnvs, &cl)
+	case "google":
+		tfEnvs = googleext.GetVaultTerraformEnvs(kcfg.Clientset, &cl, tfEnvs)
+		tfEnvs = googleext.GetGoogleTerraformEnvs(tfEnvs, &cl)
+	case "digitalocean":
+		tfEnvs = digitaloceanext.GetVaultTerraformEnvs(kcfg.Clientset, &cl, tfEnvs)
+		tfEnvs = digitaloceanext.GetDigitaloceanTerraformEnvs(tfEnvs, &cl)
+	case "vultr":
+		tfEnvs = vultrext.GetVaultTerraformEnvs(kcfg.Clientset, &cl, tfEnvs)
+		tfEnvs = vultrext.GetVultrTerraformEnvs(tfEnvs, &cl)
+	case "k3s":
+		tfEnvs = k3sext.GetVaultTerraformEnvs(kcfg.Clientset, &cl, tfEnvs)
+		tfEnvs = k3sext.GetK3sTerraformEnvs(tfEnvs, &cl)
+	}
+
+	return tfEnvs, nil
+}
+
+// containerRegistryDockerAuth returns the base64 encoded docker auth of the
+// cluster's container registry and, for gitlab, the deploy token it's
+// made of
+func (clctrl *ClusterController) containerRegistryDockerAuth() (string, string, error) {
+	if clctrl.GitProvider == "gitlab" {
+		registryAuth, err := clctrl.ContainerRegistryAuth()
+		if err != nil {
+			return "", "", err
+		}
+
+		usernamePasswordString := fmt.Sprintf("%s:%s", "container-registry-auth", registryAuth)
+		return base64.StdEncoding.EncodeToString([]byte(usernamePasswordString)), registryAuth, nil
+	}
+
+	usernamePasswordString := fmt.Sprintf("%s:%s", clctrl.GitAuth.User, clctrl.GitAuth.Token)
+	return base64.StdEncoding.EncodeToString([]byte(usernamePasswordString)), "", nil
+}
+
 func (clctrl *ClusterController) WriteVaultSecrets() error {
+	if clctrl.UsesExternalSecrets() {
+		return clctrl.WriteExternalSecrets()
+	}
+
 	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
 	if err != nil {
 		return err
@@ -270,28 +322,10 @@ func (clctrl *ClusterController) WriteVaultSecrets() error {
 		Address: vaultAddr,
 	})
 	if err != nil {
-		log.Error().Msgf("error creating vault client: %s", err)
+		clctrl.logger().Errorf("error creating vault client: %s", err)
 		return err
 	}
 
-	var externalDnsToken string
-	switch cl.DnsProvider {
-	case "akamai":
-		externalDnsToken = cl.AkamaiAuth.Token
-	case "civo":
-		externalDnsToken = cl.CivoAuth.Token
-	case "vultr":
-		externalDnsToken = cl.VultrAuth.Token
-	case "digitalocean":
-		externalDnsToken = cl.DigitaloceanAuth.Token
-	case "aws":
-		externalDnsToken = "implement with cluster management"
-	case "google":
-		externalDnsToken = "implement with cluster management"
-	case "cloudflare":
-		externalDnsToken = cl.CloudflareAuth.APIToken
-	}
-	//
 	var kcfg *k8s.KubernetesClient
 	switch clctrl.CloudProvider {
 	case "aws":
@@ -311,7 +345,7 @@ func (clctrl *ClusterController) WriteVaultSecrets() error {
 	var vaultRootToken string
 	vaultUnsealSecretData, err := k8s.ReadSecretV2(clientset, "vault", "vault-unseal-secret")
 	if err != nil {
-		log.Error().Msgf("error reading vault-unseal-secret: %s", err)
+		clctrl.logger().Errorf("error reading vault-unseal-secret: %s", err)
 	}
 	if len(vaultUnsealSecretData) != 0 {
 		vaultRootToken = vaultUnsealSecretData["root-token"]
@@ -331,13 +365,21 @@ func (clctrl *ClusterController) WriteVaultSecrets() error {
 		k8s.CreateSecretV2(kcfg.Clientset, secretToCreate)
 	}
 
-	_, err = vaultClient.KVv2("secret").Put(context.Background(), "external-dns", map[string]interface{}{
-		"token": externalDnsToken,
-	})
-
-	_, err = vaultClient.KVv2("secret").Put(context.Background(), "cloudflare", map[string]interface{}{
-		"origin-ca-api-key": cl.CloudflareAuth.OriginCaIssuerKey,
-	})
+	platformSecrets, err := vaultPlatformSecrets(cl)
+	if err != nil {
+		return err
+	}
+	for path, values := range platformSecrets {
+		data := map[string]interface{}{}
+		for key, value := range values {
+			data[key] = value
+		}
+		_, err = vaultClient.KVv2("secret").Put(context.Background(), path, data)
+		if err != nil {
+			clctrl.logger().Errorf("error writing secret %s to vault: %s", path, err)
+			return err
+		}
+	}
 
 	// _, err = vaultClient.KVv2("secret").Put(context.Background(), "crossplane", map[string]interface{}{
 	// 	"username": cl.GitAuth.User,
@@ -345,29 +387,84 @@ func (clctrl *ClusterController) WriteVaultSecrets() error {
 	// })
 
 	if cl.CloudProvider == "google" {
-		log.Info().Msg("writing google specific secrets to vault secret store")
+		clctrl.logger().Info("writing google specific secrets to vault secret store")
 		homeDir, err := os.UserHomeDir()
 		if err != nil {
-			log.Fatal().Msgf("error getting home path: %s", err)
+			clctrl.logger().Errorf("error getting home path: %s", err)
+			return err
 		}
 		if err := writeGoogleSecrets(homeDir, vaultClient); err != nil {
-			log.Error().Msgf("error writing Google secrets to vault: %s", err)
+			clctrl.logger().Errorf("error writing Google secrets to vault: %s", err)
 			return err
 		}
-		log.Info().Msg("successfully wrote google specific secrets to vault")
+		clctrl.logger().Info("successfully wrote google specific secrets to vault")
 	}
 
 	if err != nil {
-		log.Error().Msgf("error writing secret to vault: %s", err)
+		clctrl.logger().Errorf("error writing secret to vault: %s", err)
 		return err
 	}
 
-	log.Info().Msg("successfully wrote platform secrets to vault secret store")
+	clctrl.logger().Info("successfully wrote platform secrets to vault secret store")
 	return nil
 }
 
+// vaultPlatformSecrets returns the platform secrets WriteVaultSecrets
+// writes below vault's secret mount, keyed by their path
+func vaultPlatformSecrets(cl pkgtypes.Cluster) (map[string]map[string]string, error) {
+	var externalDnsToken string
+	switch cl.DnsProvider {
+	case "akamai":
+		externalDnsToken = cl.AkamaiAuth.Token
+	case "civo":
+		externalDnsToken = cl.CivoAuth.Token
+	case "vultr":
+		externalDnsToken = cl.VultrAuth.Token
+	case "digitalocean":
+		externalDnsToken = cl.DigitaloceanAuth.Token
+	case "cloudflare":
+		externalDnsToken = cl.CloudflareAuth.APIToken
+	}
+
+	platformSecrets := map[string]map[string]string{
+		"cloudflare": {"origin-ca-api-key": cl.CloudflareAuth.OriginCaIssuerKey},
+	}
+	// external-dns uses the cluster's service account for route53 and cloud dns
+	if externalDnsToken != "" {
+		platformSecrets["external-dns"] = map[string]string{"token": externalDnsToken}
+	}
+
+	if cl.OIDC.IssuerURL != "" {
+		platformSecrets[oidcSecretPath] = map[string]string{
+			"issuer-url":    cl.OIDC.IssuerURL,
+			"client-id":     cl.OIDC.ClientID,
+			"client-secret": cl.OIDC.ClientSecret,
+		}
+	}
+
+	for _, pullSecret := range cl.ImagePullSecrets {
+		data, err := dockerConfigJSON(pullSecret)
+		if err != nil {
+			return nil, fmt.Errorf("error rendering image pull secret %s: %s", pullSecret.Name, err)
+		}
+		platformSecrets[fmt.Sprintf("%s/%s", imagePullSecretsVaultPath, pullSecret.Name)] = map[string]string{
+			"server":               pullSecret.Server,
+			"username":             pullSecret.Username,
+			"password":             pullSecret.Password,
+			v1.DockerConfigJsonKey: string(data),
+		}
+	}
+
+	return platformSecrets, nil
+}
+
 // WaitForVault
 func (clctrl *ClusterController) WaitForVault() error {
+	if clctrl.UsesExternalSecrets() {
+		clctrl.logger().Infof("skipping waiting for vault: platform secrets are stored in %s", clctrl.ExternalSecrets.Provider)
+		return nil
+	}
+
 	var kcfg *k8s.KubernetesClient
 
 	switch clctrl.CloudProvider {
@@ -383,20 +480,28 @@ func (clctrl *ClusterController) WaitForVault() error {
 		}
 	}
 
+	// the vault_ready timeout covers argocd creating the statefulset and the
+	// statefulset becoming ready
+	timeout := clctrl.timeouts().VaultReady
+	started := time.Now()
 	vaultStatefulSet, err := k8s.ReturnStatefulSetObject(
 		kcfg.Clientset,
 		"app.kubernetes.io/instance",
 		"vault",
 		"vault",
-		1200,
+		timeout,
 	)
 	if err != nil {
-		log.Error().Msgf("error finding Vault StatefulSet: %s", err)
+		clctrl.logger().Errorf("error finding Vault StatefulSet: %s", err)
 		return err
 	}
-	_, err = k8s.WaitForStatefulSetReady(kcfg.Clientset, vaultStatefulSet, 300, true)
+	remaining := timeout - int(time.Since(started).Seconds())
+	if remaining < 1 {
+		remaining = 1
+	}
+	_, err = k8s.WaitForStatefulSetReady(kcfg.Clientset, vaultStatefulSet, remaining, true)
 	if err != nil {
-		log.Error().Msgf("error waiting for Vault StatefulSet ready state: %s", err)
+		clctrl.logger().Errorf("error waiting for Vault StatefulSet ready state: %s", err)
 		return err
 	}
 
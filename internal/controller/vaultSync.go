@@ -0,0 +1,128 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	terraformext "github.com/kubefirst/kubefirst-api/extensions/terraform"
+	"github.com/kubefirst/kubefirst-api/internal/gitClient"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// SyncVault submits an operation applying the cluster's vault terraform
+// again, repairing vault configuration that drifted from the gitops
+// repository without reinstalling. The terraform is planned first and
+// nothing is applied when the plan deletes or replaces a resource, so a
+// healthy vault is left untouched. Otherwise the saved plan is applied, so
+// exactly the reviewed changes are made
+func (clctrl *ClusterController) SyncVault(clusterName string) (pkgtypes.Operation, error) {
+	// vault is reached through a port-forward on the controller's kubeconfig
+	if clusterName != clctrl.ClusterName {
+		return pkgtypes.Operation{}, fmt.Errorf("controller is initialized for cluster %s, not %s", clctrl.ClusterName, clusterName)
+	}
+	if clctrl.UsesExternalSecrets() {
+		return pkgtypes.Operation{}, fmt.Errorf("cluster %s stores its platform secrets in %s, it has no vault to sync", clusterName, clctrl.ExternalSecrets.Provider)
+	}
+
+	return clctrl.SubmitOperation(OperationVaultSync, func() (interface{}, error) {
+		return clctrl.syncVault()
+	})
+}
+
+func (clctrl *ClusterController) syncVault() (pkgtypes.VaultSyncResult, error) {
+	result := pkgtypes.VaultSyncResult{Changes: []string{}}
+
+	err := clctrl.VerifyConnectivity()
+	if err != nil {
+		return result, err
+	}
+
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return result, err
+	}
+	if cl.InProgress {
+		return result, fmt.Errorf("cluster %s has an active process running, its vault is not synced", clctrl.ClusterName)
+	}
+	// before the first apply vault is configured by resuming the install
+	if !cl.VaultTerraformApplyCheck {
+		return result, fmt.Errorf("the vault terraform of cluster %s was never applied, resume its install instead", clctrl.ClusterName)
+	}
+
+	err = clctrl.refreshGitToken()
+	if err != nil {
+		return result, err
+	}
+
+	// the api may have restarted since the install and lost its local copy
+	// of the gitops repository
+	tfEntrypoint := clctrl.ProviderConfig.GitopsDir + "/terraform/vault"
+	if _, err := os.Stat(tfEntrypoint); os.IsNotExist(err) {
+		repoURL := clctrl.ProviderConfig.DestinationGitopsRepoURL
+		clctrl.logger().Infof("cloning gitops repository %s for the vault terraform", repoURL)
+		_, err = gitClient.ClonePrivateRepo(gitClient.BranchOrDefault(cl.DefaultBranch), clctrl.ProviderConfig.GitopsDir, repoURL, clctrl.GitAuth.User, clctrl.GitAuth.Token)
+		if err != nil {
+			return result, fmt.Errorf("error cloning gitops repository %s: %s", repoURL, err)
+		}
+	}
+
+	kcfg, err := clctrl.GetClusterKubernetesClient()
+	if err != nil {
+		return result, err
+	}
+	clctrl.OpenPortForward(kcfg, "vault-0", "vault", 8200, 8200)
+	defer clctrl.ClosePortForwards()
+
+	tfEnvs, err := clctrl.vaultTerraformEnvs(cl)
+	if err != nil {
+		return result, err
+	}
+	plan, err := terraformext.InitPlan(clctrl.ProviderConfig.TerraformClient, tfEntrypoint, tfEnvs)
+	if err != nil {
+		return result, fmt.Errorf("error planning vault terraform: %s", err)
+	}
+	defer plan.Close()
+
+	changes := plan.Changes
+	destroyed := []string{}
+	for _, change := range changes {
+		result.Changes = append(result.Changes, fmt.Sprintf("%s (%s)", change.Address, strings.Join(change.Actions, ", ")))
+		if change.Destroys() {
+			destroyed = append(destroyed, change.Address)
+		}
+	}
+	if len(destroyed) > 0 {
+		return result, fmt.Errorf("the vault terraform would delete or replace %s, review the plan and apply it by hand", strings.Join(destroyed, ", "))
+	}
+	if len(changes) == 0 {
+		clctrl.logger().Infof("vault of cluster %s matches its terraform, nothing to sync", clctrl.ClusterName)
+		return result, nil
+	}
+
+	clctrl.logger().Infof("syncing vault of cluster %s: %d changes", clctrl.ClusterName, len(changes))
+
+	err = clctrl.runTerraformHooks(terraformHookStagePre, tfEntrypoint)
+	if err != nil {
+		return result, err
+	}
+	err = plan.Apply()
+	if err != nil {
+		return result, fmt.Errorf("error applying the vault terraform plan: %s", err)
+	}
+	result.Applied = true
+	err = clctrl.runTerraformHooks(terraformHookStagePost, tfEntrypoint)
+	if err != nil {
+		return result, err
+	}
+	clctrl.logger().Infof("synced vault of cluster %s", clctrl.ClusterName)
+
+	return result, nil
+}
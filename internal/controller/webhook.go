@@ -0,0 +1,105 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kubefirst/kubefirst-api/internal/constants"
+	"github.com/kubefirst/kubefirst-api/internal/env"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// webhookAttempts is how many times an event is posted before giving up
+const webhookAttempts = 3
+
+// webhookURL is the url events are posted to, it comes from WEBHOOK_URL
+// unless set with SetWebhookURL and an empty url sends no events
+var webhookURL = struct {
+	sync.Mutex
+	url string
+	set bool
+}{}
+
+// SetWebhookURL sets the url operation events are posted to, an empty url
+// stops sending them
+func SetWebhookURL(url string) {
+	webhookURL.Lock()
+	defer webhookURL.Unlock()
+
+	webhookURL.url = url
+	webhookURL.set = true
+}
+
+func getWebhookURL() string {
+	webhookURL.Lock()
+	defer webhookURL.Unlock()
+
+	if !webhookURL.set {
+		env, _ := env.GetEnv(constants.SilenceGetEnv)
+		webhookURL.url = env.WebhookURL
+		webhookURL.set = true
+	}
+
+	return webhookURL.url
+}
+
+// notifyOperation posts the event of a finished operation to the webhook
+// url, failures are only logged so they never fail the operation
+func (clctrl *ClusterController) notifyOperation(operation pkgtypes.Operation) {
+	url := getWebhookURL()
+	if url == "" {
+		return
+	}
+
+	event := pkgtypes.OperationEvent{
+		Event:     fmt.Sprintf("operation.%s", operation.Status),
+		Operation: operation,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		clctrl.logger().Errorf("error marshalling %s event of operation %s: %s", event.Event, operation.ID, err)
+		return
+	}
+
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		err = postWebhook(url, payload)
+		if err == nil {
+			return
+		}
+		if attempt < webhookAttempts {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}
+	clctrl.logger().Warnf("error sending %s event of operation %s to the webhook: %s", event.Event, operation.ID, err)
+}
+
+func postWebhook(url string, payload []byte) error {
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("the webhook answered %s", response.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestNotifyOperation(t *testing.T) {
+	events := []pkgtypes.OperationEvent{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := pkgtypes.OperationEvent{}
+		err := json.NewDecoder(r.Body).Decode(&event)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		events = append(events, event)
+	}))
+	defer server.Close()
+
+	SetWebhookURL(server.URL)
+	defer SetWebhookURL("")
+
+	clctrl := &ClusterController{ClusterName: "kf-test"}
+	clctrl.notifyOperation(pkgtypes.Operation{
+		ID:          "6523f0c2a1b4c5d6e7f80912",
+		ClusterName: "kf-test",
+		Type:        OperationUserSync,
+		Status:      "failed",
+		Error:       "no user directory",
+	})
+
+	if len(events) != 1 {
+		t.Fatalf("expected one event, got %d", len(events))
+	}
+	if events[0].Event != "operation.failed" || events[0].Operation.ID != "6523f0c2a1b4c5d6e7f80912" || events[0].Operation.Error != "no user directory" {
+		t.Errorf("unexpected event %+v", events[0])
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	awsRoleArnAnnotation           = "eks.amazonaws.com/role-arn"
+	googleServiceAccountAnnotation = "iam.gke.io/gcp-service-account"
+)
+
+var (
+	awsRoleArnRegex           = regexp.MustCompile(`^arn:aws[a-z-]*:iam::[0-9]{12}:role/[\w+=,.@/-]+$`)
+	googleServiceAccountRegex = regexp.MustCompile(`^[a-z][a-z0-9-]{4,28}[a-z0-9]@[a-z0-9-]+\.iam\.gserviceaccount\.com$`)
+)
+
+// validateWorkloadIdentity makes sure workload identity is only requested on
+// providers that support it and that every binding is well formed
+func validateWorkloadIdentity(workloadIdentity pkgtypes.WorkloadIdentity, cloudProvider string) error {
+	if !workloadIdentity.Enabled {
+		if len(workloadIdentity.Bindings) != 0 {
+			return fmt.Errorf("workload identity bindings require workload identity to be enabled")
+		}
+		return nil
+	}
+
+	var identityRegex *regexp.Regexp
+	switch cloudProvider {
+	case "aws":
+		identityRegex = awsRoleArnRegex
+	case "google":
+		identityRegex = googleServiceAccountRegex
+	default:
+		return fmt.Errorf("workload identity is not supported for %s", cloudProvider)
+	}
+
+	for _, binding := range workloadIdentity.Bindings {
+		if errs := validation.IsDNS1123Label(binding.Namespace); len(errs) != 0 {
+			return fmt.Errorf("invalid workload identity namespace %q: %s", binding.Namespace, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsDNS1123Subdomain(binding.ServiceAccount); len(errs) != 0 {
+			return fmt.Errorf("invalid workload identity service account %q: %s", binding.ServiceAccount, strings.Join(errs, ", "))
+		}
+		if !identityRegex.MatchString(binding.CloudIdentity) {
+			return fmt.Errorf("invalid workload identity cloud identity %q for %s", binding.CloudIdentity, cloudProvider)
+		}
+	}
+
+	return nil
+}
+
+// ConfigureWorkloadIdentity trusts the cluster's oidc issuer for each
+// binding's cloud identity and creates the annotated kubernetes service
+// accounts so in-cluster components assume cloud roles without static keys
+func (clctrl *ClusterController) ConfigureWorkloadIdentity() error {
+	cl, err := secrets.GetCluster(clctrl.KubernetesClient, clctrl.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	if !clctrl.WorkloadIdentity.Enabled || cl.WorkloadIdentityCheck {
+		return nil
+	}
+
+	kcfg, err := clctrl.GetClusterKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	switch clctrl.CloudProvider {
+	case "aws":
+		issuerURL, err := clctrl.AwsClient.GetEKSOIDCIssuer(clctrl.ClusterName)
+		if err != nil {
+			return err
+		}
+
+		providerArn, err := clctrl.AwsClient.EnsureOIDCProvider(issuerURL)
+		if err != nil {
+			return err
+		}
+
+		for _, binding := range clctrl.WorkloadIdentity.Bindings {
+			err = clctrl.AwsClient.AddWebIdentityTrust(binding.CloudIdentity, providerArn, issuerURL, binding.Namespace, binding.ServiceAccount)
+			if err != nil {
+				return err
+			}
+
+			err = ensureAnnotatedServiceAccount(kcfg.Clientset, binding, awsRoleArnAnnotation)
+			if err != nil {
+				return err
+			}
+		}
+	case "google":
+		workloadPool, err := clctrl.GoogleClient.GetWorkloadPool(clctrl.ClusterName)
+		if err != nil {
+			return err
+		}
+		if workloadPool == "" {
+			return fmt.Errorf("workload identity is not enabled on gke cluster %s", clctrl.ClusterName)
+		}
+
+		for _, binding := range clctrl.WorkloadIdentity.Bindings {
+			err = clctrl.GoogleClient.BindWorkloadIdentity(binding.CloudIdentity, workloadPool, binding.Namespace, binding.ServiceAccount, []byte(clctrl.GoogleAuth.KeyFile))
+			if err != nil {
+				return err
+			}
+
+			err = ensureAnnotatedServiceAccount(kcfg.Clientset, binding, googleServiceAccountAnnotation)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("workload identity is not supported for %s", clctrl.CloudProvider)
+	}
+
+	clctrl.logger().Infof("configured workload identity for cluster %s", clctrl.ClusterName)
+
+	clctrl.Cluster.WorkloadIdentityCheck = true
+	err = secrets.UpdateCluster(clctrl.KubernetesClient, clctrl.Cluster)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ensureAnnotatedServiceAccount creates or updates the binding's service
+// account, and its namespace, with the cloud identity annotation
+func ensureAnnotatedServiceAccount(clientset *kubernetes.Clientset, binding pkgtypes.WorkloadIdentityBinding, annotation string) error {
+	ctx := context.Background()
+
+	_, err := clientset.CoreV1().Namespaces().Create(ctx, &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: binding.Namespace},
+	}, metav1.CreateOptions{})
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return fmt.Errorf("error creating namespace %s: %s", binding.Namespace, err)
+	}
+
+	serviceAccounts := clientset.CoreV1().ServiceAccounts(binding.Namespace)
+	sa, err := serviceAccounts.Get(ctx, binding.ServiceAccount, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = serviceAccounts.Create(ctx, &v1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        binding.ServiceAccount,
+				Namespace:   binding.Namespace,
+				Annotations: map[string]string{annotation: binding.CloudIdentity},
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("error creating service account %s/%s: %s", binding.Namespace, binding.ServiceAccount, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting service account %s/%s: %s", binding.Namespace, binding.ServiceAccount, err)
+	}
+
+	if sa.Annotations == nil {
+		sa.Annotations = map[string]string{}
+	}
+	sa.Annotations[annotation] = binding.CloudIdentity
+	_, err = serviceAccounts.Update(ctx, sa, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("error updating service account %s/%s: %s", binding.Namespace, binding.ServiceAccount, err)
+	}
+
+	return nil
+}
@@ -8,14 +8,32 @@ package digitalocean
 
 import (
 	"fmt"
+	"net/url"
+	"os"
 	"sort"
+	"strings"
 
 	"github.com/digitalocean/godo"
 )
 
-func NewDigitalocean(digitalOceanToken string) *godo.Client {
+// APIURLVar is the environment variable overriding the digitalocean api url
+// of definitions that don't set digitalocean_auth.api_url, the digitalocean
+// terraform provider reads it too
+const APIURLVar = "DIGITALOCEAN_API_URL"
+
+func NewDigitalocean(digitalOceanToken string, apiURL string) *godo.Client {
 	digitaloceanClient := godo.NewFromToken(digitalOceanToken)
 
+	if apiURL == "" {
+		apiURL = os.Getenv(APIURLVar)
+	}
+	if apiURL != "" {
+		// api paths are resolved relative to the base url
+		if baseURL, err := url.Parse(strings.TrimSuffix(apiURL, "/") + "/"); err == nil {
+			digitaloceanClient.BaseURL = baseURL
+		}
+	}
+
 	return digitaloceanClient
 }
 
@@ -56,3 +74,13 @@ func (c *DigitaloceanConfiguration) ValidateRegion(region string) error {
 
 	return nil
 }
+
+// GetVPCIPRange returns the ip range of an existing vpc
+func (c *DigitaloceanConfiguration) GetVPCIPRange(vpcID string) (string, error) {
+	vpc, _, err := c.Client.VPCs.Get(c.Context, vpcID)
+	if err != nil {
+		return "", fmt.Errorf("error getting digitalocean vpc %s: %s", vpcID, err)
+	}
+
+	return vpc.IPRange, nil
+}
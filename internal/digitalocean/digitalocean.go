@@ -46,6 +46,22 @@ func (c *DigitaloceanConfiguration) ListInstances() ([]string, error) {
 	return instanceNames, nil
 }
 
+// GetSizeMonthlyPrice returns the monthly price in usd of a droplet size
+func (c *DigitaloceanConfiguration) GetSizeMonthlyPrice(slug string) (float64, error) {
+	sizes, _, err := c.Client.Sizes.List(context.Background(), &godo.ListOptions{PerPage: 200})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, size := range sizes {
+		if size.Slug == slug {
+			return size.PriceMonthly, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%q is not a valid digitalocean size", slug)
+}
+
 func (c *DigitaloceanConfiguration) GetKubeconfig(clusterName string) ([]byte, error) {
 	clusters, _, err := c.Client.Kubernetes.List(context.Background(), &godo.ListOptions{})
 
@@ -26,7 +26,7 @@ func (c *DigitaloceanConfiguration) TestDomainLiveness(domainName string) bool {
 		Name:     doRecordName,
 		Type:     "TXT",
 		Data:     doRecordValue,
-		TTL:      600,
+		TTL:      c.recordTTL(),
 		Priority: *godo.PtrTo(100),
 	}
 
@@ -70,3 +70,51 @@ func (c *DigitaloceanConfiguration) DeleteKubernetesClusterVolumes(resources *go
 
 	return nil
 }
+
+// ListKubernetesClusters returns the account's kubernetes clusters
+func (c *DigitaloceanConfiguration) ListKubernetesClusters() ([]*godo.KubernetesCluster, error) {
+	clusters := []*godo.KubernetesCluster{}
+	opts := &godo.ListOptions{PerPage: 200}
+	for {
+		page, resp, err := c.Client.Kubernetes.List(c.Context, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error listing kubernetes clusters: %s", err)
+		}
+		clusters = append(clusters, page...)
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			return clusters, nil
+		}
+		current, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+		opts.Page = current + 1
+	}
+}
+
+// DeleteKubernetesCluster deletes a kubernetes cluster by id with its load
+// balancers and volumes
+func (c *DigitaloceanConfiguration) DeleteKubernetesCluster(clusterID string) error {
+	_, err := c.Client.Kubernetes.DeleteDangerous(c.Context, clusterID)
+	if err != nil {
+		return fmt.Errorf("error deleting kubernetes cluster %s: %s", clusterID, err)
+	}
+
+	return nil
+}
+
+// ListKubernetesVersions returns the kubernetes versions new clusters can be
+// created with, both as slugs (1.27.4-do.0) and upstream versions (1.27.4)
+func (c *DigitaloceanConfiguration) ListKubernetesVersions() ([]string, error) {
+	options, _, err := c.Client.Kubernetes.GetOptions(c.Context)
+	if err != nil {
+		return nil, fmt.Errorf("error listing kubernetes versions: %s", err)
+	}
+
+	versions := []string{}
+	for _, version := range options.Versions {
+		versions = append(versions, version.Slug, version.KubernetesVersion)
+	}
+
+	return versions, nil
+}
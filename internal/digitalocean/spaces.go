@@ -36,3 +36,25 @@ func (c *DigitaloceanConfiguration) CreateSpaceBucket(cr DigitaloceanSpacesCrede
 
 	return nil
 }
+
+// VerifySpaceBucket confirms an existing bucket can be reached with the
+// provided spaces credentials
+func (c *DigitaloceanConfiguration) VerifySpaceBucket(cr DigitaloceanSpacesCredentials, bucketName string) error {
+	minioClient, err := minio.New(cr.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cr.AccessKey, cr.SecretAccessKey, ""),
+		Secure: true,
+	})
+	if err != nil {
+		return fmt.Errorf("error initializing minio client for digitalocean: %s", err)
+	}
+
+	exists, err := minioClient.BucketExists(context.Background(), bucketName)
+	if err != nil {
+		return fmt.Errorf("bucket %s for %s is inaccessible: %s", bucketName, cr.Endpoint, err)
+	}
+	if !exists {
+		return fmt.Errorf("bucket %s for %s does not exist", bucketName, cr.Endpoint)
+	}
+
+	return nil
+}
@@ -15,6 +15,8 @@ import (
 type DigitaloceanConfiguration struct {
 	Client  *godo.Client
 	Context context.Context
+	// RecordTTL is the ttl of the dns records created, 0 keeps 600 seconds
+	RecordTTL int
 }
 
 type DigitaloceanSpacesCredentials struct {
@@ -22,3 +24,11 @@ type DigitaloceanSpacesCredentials struct {
 	SecretAccessKey string
 	Endpoint        string
 }
+
+func (c *DigitaloceanConfiguration) recordTTL() int {
+	if c.RecordTTL > 0 {
+		return c.RecordTTL
+	}
+
+	return 600
+}
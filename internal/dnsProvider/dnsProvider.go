@@ -0,0 +1,225 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package dnsProvider
+
+import (
+	"context"
+	"fmt"
+
+	cloudflare_api "github.com/cloudflare/cloudflare-go"
+	awsinternal "github.com/kubefirst/kubefirst-api/internal/aws"
+	"github.com/kubefirst/kubefirst-api/internal/civo"
+	"github.com/kubefirst/kubefirst-api/internal/cloudflare"
+	"github.com/kubefirst/kubefirst-api/internal/digitalocean"
+	"github.com/kubefirst/kubefirst-api/internal/vultr"
+	google "github.com/kubefirst/kubefirst-api/pkg/google"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// SupportedDNSProviders lists the dns providers a cluster can use, see
+// ValidateCloudProvider for the cloud providers each one works with
+var SupportedDNSProviders = []string{"aws", "civo", "cloudflare", "digitalocean", "google", "vultr"}
+
+// DNSProvider manages the zone for a cluster's domain independently of the
+// cloud provider that runs the cluster
+type DNSProvider interface {
+	// Name returns the dns provider name
+	Name() string
+	// ListZones returns the domains managed by the provider account
+	ListZones() ([]string, error)
+	// TestDomainLiveness creates a test record in the zone for domainName
+	// and reports whether it resolves
+	TestDomainLiveness(domainName string) (bool, error)
+}
+
+// ZoneManager is implemented by dns providers that can manage zones and
+// records directly in addition to the liveness check, the domain liveness
+// step creates the zone before the liveness check and runs the dns-01 round
+// trip after it
+type ZoneManager interface {
+	// CreateZone creates the zone for domainName, reusing an existing one
+	CreateZone(domainName string) error
+	// DNS01RoundTrip publishes and resolves an acme dns-01 challenge record
+	DNS01RoundTrip(domainName string) error
+}
+
+// Credentials holds the auth for every supported dns provider, only the one
+// for the selected provider needs to be set, and the ttl of the records the
+// provider creates where 0 keeps the provider's default
+type Credentials struct {
+	Region           string
+	RecordTTL        int
+	AWSAuth          pkgtypes.AWSAuth
+	CivoAuth         pkgtypes.CivoAuth
+	CloudflareAuth   pkgtypes.CloudflareAuth
+	DigitaloceanAuth pkgtypes.DigitaloceanAuth
+	GoogleAuth       pkgtypes.GoogleAuth
+	VultrAuth        pkgtypes.VultrAuth
+}
+
+// CredentialsFromDefinition returns the dns credentials of a cluster definition
+func CredentialsFromDefinition(def *pkgtypes.ClusterDefinition) Credentials {
+	return Credentials{
+		Region:           def.CloudRegion,
+		RecordTTL:        def.DNSTTL,
+		AWSAuth:          def.AWSAuth,
+		CivoAuth:         def.CivoAuth,
+		CloudflareAuth:   def.CloudflareAuth,
+		DigitaloceanAuth: def.DigitaloceanAuth,
+		GoogleAuth:       def.GoogleAuth,
+		VultrAuth:        def.VultrAuth,
+	}
+}
+
+// CredentialsFromCluster returns the dns credentials of a cluster record
+func CredentialsFromCluster(cl *pkgtypes.Cluster) Credentials {
+	return Credentials{
+		Region:           cl.CloudRegion,
+		RecordTTL:        cl.DNSTTL,
+		AWSAuth:          cl.AWSAuth,
+		CivoAuth:         cl.CivoAuth,
+		CloudflareAuth:   cl.CloudflareAuth,
+		DigitaloceanAuth: cl.DigitaloceanAuth,
+		GoogleAuth:       cl.GoogleAuth,
+		VultrAuth:        cl.VultrAuth,
+	}
+}
+
+// Normalize maps dns provider aliases to their supported name
+func Normalize(name string) string {
+	if name == "route53" {
+		return "aws"
+	}
+
+	return name
+}
+
+// New returns the DNSProvider for name, or an error when the provider is
+// unsupported or its credentials are missing
+func New(name string, creds Credentials) (DNSProvider, error) {
+	switch Normalize(name) {
+	case "aws":
+		if creds.AWSAuth.AccessKeyID == "" || creds.AWSAuth.SecretAccessKey == "" {
+			return nil, fmt.Errorf("missing aws credentials for route53 dns")
+		}
+		return &route53Provider{conf: awsinternal.AWSConfiguration{
+			Config:    awsinternal.NewAwsV3(creds.Region, creds.AWSAuth.AccessKeyID, creds.AWSAuth.SecretAccessKey, creds.AWSAuth.SessionToken, creds.AWSAuth.APIURL),
+			RecordTTL: creds.RecordTTL,
+		}}, nil
+	case "civo":
+		if creds.CivoAuth.Token == "" {
+			return nil, fmt.Errorf("missing civo credentials for civo dns")
+		}
+		return &civoProvider{region: creds.Region, conf: civo.CivoConfiguration{
+			Client:    civo.NewCivo(creds.CivoAuth.Token, creds.Region, creds.CivoAuth.APIURL),
+			Context:   context.Background(),
+			RecordTTL: creds.RecordTTL,
+		}}, nil
+	case "cloudflare":
+		if creds.CloudflareAuth.APIToken == "" {
+			return nil, fmt.Errorf("missing cloudflare api token for cloudflare dns")
+		}
+		client, err := cloudflare_api.NewWithAPIToken(creds.CloudflareAuth.APIToken)
+		if err != nil {
+			return nil, err
+		}
+		return &cloudflareProvider{
+			accountID: creds.CloudflareAuth.AccountID,
+			conf: cloudflare.CloudflareConfiguration{
+				Client:    client,
+				Context:   context.Background(),
+				RecordTTL: creds.RecordTTL,
+			},
+		}, nil
+	case "digitalocean":
+		if creds.DigitaloceanAuth.Token == "" {
+			return nil, fmt.Errorf("missing digitalocean credentials for digitalocean dns")
+		}
+		return &digitaloceanProvider{conf: digitalocean.DigitaloceanConfiguration{
+			Client:    digitalocean.NewDigitalocean(creds.DigitaloceanAuth.Token, creds.DigitaloceanAuth.APIURL),
+			Context:   context.Background(),
+			RecordTTL: creds.RecordTTL,
+		}}, nil
+	case "google":
+		if creds.GoogleAuth.KeyFile == "" {
+			return nil, fmt.Errorf("missing google credentials for google cloud dns")
+		}
+		return &googleProvider{conf: google.GoogleConfiguration{
+			Context:   context.Background(),
+			Project:   creds.GoogleAuth.ProjectId,
+			Region:    creds.Region,
+			KeyFile:   creds.GoogleAuth.KeyFile,
+			RecordTTL: creds.RecordTTL,
+		}}, nil
+	case "vultr":
+		if creds.VultrAuth.Token == "" {
+			return nil, fmt.Errorf("missing vultr credentials for vultr dns")
+		}
+		return &vultrProvider{conf: vultr.VultrConfiguration{
+			Client:    vultr.NewVultr(creds.VultrAuth.Token, creds.VultrAuth.APIURL),
+			Context:   context.Background(),
+			RecordTTL: creds.RecordTTL,
+		}}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported dns provider %s, must be one of %v", name, SupportedDNSProviders)
+}
+
+// MinRecordTTL is the shortest record ttl in seconds each dns provider
+// accepts
+var MinRecordTTL = map[string]int{
+	"aws":          1,
+	"civo":         600,
+	"cloudflare":   60,
+	"digitalocean": 30,
+	"google":       1,
+	"vultr":        60,
+}
+
+// maxRecordTTL keeps record ttls to a day
+const maxRecordTTL = 86400
+
+// ValidateRecordTTL makes sure a record ttl is accepted by the dns provider,
+// 0 keeps the provider's default
+func ValidateRecordTTL(name string, ttl int) error {
+	if ttl == 0 {
+		return nil
+	}
+
+	min, ok := MinRecordTTL[Normalize(name)]
+	if !ok {
+		return fmt.Errorf("unsupported dns provider %s, must be one of %v", name, SupportedDNSProviders)
+	}
+	if ttl < min || ttl > maxRecordTTL {
+		return fmt.Errorf("dns_ttl for %s has to be between %d and %d seconds, got %d", name, min, maxRecordTTL, ttl)
+	}
+
+	return nil
+}
+
+// ValidateCloudProvider makes sure external-dns can be given credentials for
+// the dns provider on the cloud provider. The gitops template wires
+// cloudflare on every cloud, the other dns providers only get the credentials
+// of the cloud the cluster runs on
+func ValidateCloudProvider(name string, cloudProvider string) error {
+	name = Normalize(name)
+	if name == "cloudflare" || name == cloudProvider {
+		return nil
+	}
+
+	return fmt.Errorf("dns provider %s is not supported on %s, use cloudflare or %s dns", name, cloudProvider, cloudProvider)
+}
+
+// VerifyCredentials confirms the provider's credentials can read its zones
+func VerifyCredentials(provider DNSProvider) error {
+	_, err := provider.ListZones()
+	if err != nil {
+		return fmt.Errorf("error verifying %s dns credentials: %s", provider.Name(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package dnsProvider
+
+import "testing"
+
+func TestValidateCloudProvider(t *testing.T) {
+	for _, test := range []struct {
+		dnsProvider   string
+		cloudProvider string
+		valid         bool
+	}{
+		{"cloudflare", "vultr", true},
+		{"cloudflare", "k3s", true},
+		{"vultr", "vultr", true},
+		{"route53", "aws", true},
+		{"google", "google", true},
+		{"aws", "vultr", false},
+		{"google", "civo", false},
+		{"civo", "digitalocean", false},
+		{"vultr", "akamai", false},
+	} {
+		err := ValidateCloudProvider(test.dnsProvider, test.cloudProvider)
+		if (err == nil) != test.valid {
+			t.Errorf("ValidateCloudProvider(%s, %s) = %v, want valid %v", test.dnsProvider, test.cloudProvider, err, test.valid)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package dnsProvider
+
+import (
+	awsinternal "github.com/kubefirst/kubefirst-api/internal/aws"
+	"github.com/kubefirst/kubefirst-api/internal/civo"
+	"github.com/kubefirst/kubefirst-api/internal/cloudflare"
+	"github.com/kubefirst/kubefirst-api/internal/digitalocean"
+	"github.com/kubefirst/kubefirst-api/internal/vultr"
+	google "github.com/kubefirst/kubefirst-api/pkg/google"
+)
+
+// route53Provider manages dns with aws route53
+type route53Provider struct {
+	conf awsinternal.AWSConfiguration
+}
+
+func (p *route53Provider) Name() string { return "aws" }
+
+func (p *route53Provider) ListZones() ([]string, error) {
+	return p.conf.GetHostedZones()
+}
+
+func (p *route53Provider) TestDomainLiveness(domainName string) (bool, error) {
+	return p.conf.TestHostedZoneLiveness(domainName), nil
+}
+
+// civoProvider manages dns with civo
+type civoProvider struct {
+	conf   civo.CivoConfiguration
+	region string
+}
+
+func (p *civoProvider) Name() string { return "civo" }
+
+func (p *civoProvider) ListZones() ([]string, error) {
+	return p.conf.GetDNSDomains(p.region)
+}
+
+func (p *civoProvider) TestDomainLiveness(domainName string) (bool, error) {
+	domainId, err := p.conf.GetDNSInfo(domainName, p.region)
+	if err != nil {
+		return false, err
+	}
+
+	return p.conf.TestDomainLiveness(domainName, domainId, p.region), nil
+}
+
+// cloudflareProvider manages dns with cloudflare
+type cloudflareProvider struct {
+	conf      cloudflare.CloudflareConfiguration
+	accountID string
+}
+
+func (p *cloudflareProvider) Name() string { return "cloudflare" }
+
+func (p *cloudflareProvider) ListZones() ([]string, error) {
+	return p.conf.GetDNSDomains()
+}
+
+func (p *cloudflareProvider) TestDomainLiveness(domainName string) (bool, error) {
+	return p.conf.TestDomainLiveness(domainName), nil
+}
+
+// CreateZone needs the account id, without one an existing zone is expected
+// and the liveness check reports a missing one
+func (p *cloudflareProvider) CreateZone(domainName string) error {
+	if p.accountID == "" {
+		return nil
+	}
+	_, err := p.conf.CreateZone(domainName, p.accountID)
+	return err
+}
+
+func (p *cloudflareProvider) DNS01RoundTrip(domainName string) error {
+	return p.conf.DNS01RoundTrip(domainName)
+}
+
+// digitaloceanProvider manages dns with digitalocean
+type digitaloceanProvider struct {
+	conf digitalocean.DigitaloceanConfiguration
+}
+
+func (p *digitaloceanProvider) Name() string { return "digitalocean" }
+
+func (p *digitaloceanProvider) ListZones() ([]string, error) {
+	return p.conf.GetDNSDomains()
+}
+
+func (p *digitaloceanProvider) TestDomainLiveness(domainName string) (bool, error) {
+	return p.conf.TestDomainLiveness(domainName), nil
+}
+
+// googleProvider manages dns with google cloud dns
+type googleProvider struct {
+	conf google.GoogleConfiguration
+}
+
+func (p *googleProvider) Name() string { return "google" }
+
+func (p *googleProvider) ListZones() ([]string, error) {
+	return p.conf.GetDNSDomains()
+}
+
+func (p *googleProvider) TestDomainLiveness(domainName string) (bool, error) {
+	return p.conf.TestHostedZoneLiveness(domainName), nil
+}
+
+// vultrProvider manages dns with vultr
+type vultrProvider struct {
+	conf vultr.VultrConfiguration
+}
+
+func (p *vultrProvider) Name() string { return "vultr" }
+
+func (p *vultrProvider) ListZones() ([]string, error) {
+	return p.conf.GetDNSDomains()
+}
+
+func (p *vultrProvider) TestDomainLiveness(domainName string) (bool, error) {
+	return p.conf.TestDomainLiveness(domainName), nil
+}
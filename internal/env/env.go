@@ -7,25 +7,46 @@ import (
 )
 
 type Env struct {
-	ServerPort            string `env:"SERVER_PORT" envDefault:"8081"`
-	K1AccessToken         string `env:"K1_ACCESS_TOKEN"`
-	KubefirstVersion      string `env:"KUBEFIRST_VERSION" envDefault:"main"`
-	CloudProvider         string `env:"CLOUD_PROVIDER"`
-	ClusterId             string `env:"CLUSTER_ID"`
-	ClusterType           string `env:"CLUSTER_TYPE"`
-	DomainName            string `env:"DOMAIN_NAME"`
-	GitProvider           string `env:"GIT_PROVIDER"`
-	InstallMethod         string `env:"INSTALL_METHOD"`
-	KubefirstTeam         string `env:"KUBEFIRST_TEAM" envDefault:"undefined"`
-	KubefirstTeamInfo     string `env:"KUBEFIRST_TEAM_INFO"`
-	AWSRegion             string `env:"AWS_REGION"`
-	AWSProfile            string `env:"AWS_PROFILE"`
-	IsClusterZero         string `env:"IS_CLUSTER_ZERO"`
-	ParentClusterId       string `env:"PARENT_CLUSTER_ID"`
-	InCluster             string `env:"IN_CLUSTER" envDefault:"false"`
-	EnterpriseApiUrl      string `env:"ENTERPRISE_API_URL"`
-	K1LocalDebug          string `env:"K1_LOCAL_DEBUG"`
-	K1LocalKubeconfigPath string `env:"K1_LOCAL_KUBECONFIG_PATH"`
+	ServerPort              string `env:"SERVER_PORT" envDefault:"8081"`
+	K1AccessToken           string `env:"K1_ACCESS_TOKEN"`
+	KubefirstVersion        string `env:"KUBEFIRST_VERSION" envDefault:"main"`
+	CloudProvider           string `env:"CLOUD_PROVIDER"`
+	ClusterId               string `env:"CLUSTER_ID"`
+	ClusterType             string `env:"CLUSTER_TYPE"`
+	DomainName              string `env:"DOMAIN_NAME"`
+	GitProvider             string `env:"GIT_PROVIDER"`
+	InstallMethod           string `env:"INSTALL_METHOD"`
+	KubefirstTeam           string `env:"KUBEFIRST_TEAM" envDefault:"undefined"`
+	KubefirstTeamInfo       string `env:"KUBEFIRST_TEAM_INFO"`
+	AWSRegion               string `env:"AWS_REGION"`
+	AWSProfile              string `env:"AWS_PROFILE"`
+	IsClusterZero           string `env:"IS_CLUSTER_ZERO"`
+	ParentClusterId         string `env:"PARENT_CLUSTER_ID"`
+	InCluster               string `env:"IN_CLUSTER" envDefault:"false"`
+	EnterpriseApiUrl        string `env:"ENTERPRISE_API_URL"`
+	K1LocalDebug            string `env:"K1_LOCAL_DEBUG"`
+	K1LocalKubeconfigPath   string `env:"K1_LOCAL_KUBECONFIG_PATH"`
+	MaxConcurrentProvisions int    `env:"MAX_CONCURRENT_PROVISIONS" envDefault:"0"`
+	ShutdownGracePeriod     int    `env:"SHUTDOWN_GRACE_PERIOD" envDefault:"60"`
+	ReadOnly                bool   `env:"READ_ONLY" envDefault:"false"`
+	WebhookURL              string `env:"WEBHOOK_URL"`
+	AllowTerraformHooks     bool   `env:"ALLOW_TERRAFORM_HOOKS" envDefault:"false"`
+
+	// StoreBackend is kubernetes to keep records as secrets, or memory with
+	// an optional StorePath json file for local and single node installs
+	StoreBackend string `env:"STORE_BACKEND" envDefault:"kubernetes"`
+	StorePath    string `env:"STORE_PATH"`
+	// StoreEncryptionKey is the base64 32 byte key sensitive cluster record
+	// fields are encrypted with
+	StoreEncryptionKey string `env:"STORE_ENCRYPTION_KEY"`
+
+	// the connection to the kubernetes api server holding the cluster store
+	StoreTLSCAFile   string  `env:"STORE_TLS_CA_FILE"`
+	StoreTLSCertFile string  `env:"STORE_TLS_CERT_FILE"`
+	StoreTLSKeyFile  string  `env:"STORE_TLS_KEY_FILE"`
+	StoreQPS         float32 `env:"STORE_QPS" envDefault:"0"`
+	StoreBurst       int     `env:"STORE_BURST" envDefault:"0"`
+	StoreTimeout     int     `env:"STORE_TIMEOUT" envDefault:"0"`
 }
 
 func GetEnv(silent bool) (Env, error) {
@@ -24,6 +24,8 @@ func TestEnv(t *testing.T) {
 	os.Setenv("IS_CLUSTER_ZERO", "true")
 	os.Setenv("IN_CLUSTER", "false")
 	os.Setenv("ENTERPRISE_API_URL", "enterprise_api_url")
+	os.Setenv("STORE_TLS_CERT_FILE", "store_tls_cert_file")
+	os.Setenv("STORE_QPS", "20.5")
 
 	defer func() {
 		os.Unsetenv("SERVER_PORT")
@@ -42,6 +44,8 @@ func TestEnv(t *testing.T) {
 		os.Unsetenv("IS_CLUSTER_ZERO")
 		os.Unsetenv("IN_CLUSTER")
 		os.Unsetenv("ENTERPRISE_API_URL")
+		os.Unsetenv("STORE_TLS_CERT_FILE")
+		os.Unsetenv("STORE_QPS")
 	}()
 
 	env := Env{}
@@ -113,4 +117,12 @@ func TestEnv(t *testing.T) {
 	if env.EnterpriseApiUrl != "enterprise_api_url" {
 		t.Errorf("expected EnterpriseApiUrl to be 'enterprise_api_url', but got '%s'", env.EnterpriseApiUrl)
 	}
+
+	if env.StoreTLSCertFile != "store_tls_cert_file" {
+		t.Errorf("expected StoreTLSCertFile to be 'store_tls_cert_file', but got '%s'", env.StoreTLSCertFile)
+	}
+
+	if env.StoreQPS != 20.5 {
+		t.Errorf("expected StoreQPS to be 20.5, but got %v", env.StoreQPS)
+	}
 }
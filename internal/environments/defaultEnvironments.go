@@ -30,7 +30,10 @@ func NewEnvironment(envDef types.Environment) (types.Environment, error) {
 	// Create new environment
 	envDef.CreationTimestamp = fmt.Sprintf("%v", primitive.NewDateTimeFromTime(time.Now().UTC()))
 
-	kcfg := utils.GetKubernetesClient("TODO: Secrets")
+	kcfg, err := utils.GetClusterStoreClient("TODO: Secrets")
+	if err != nil {
+		return types.Environment{}, err
+	}
 	newEnv, err := secrets.InsertEnvironment(kcfg.Clientset, envDef)
 
 	return newEnv, err
@@ -60,7 +63,10 @@ func CreateDefaultClusters(mgmtCluster types.Cluster) error {
 
 	defaultClusters := []types.WorkloadCluster{}
 
-	kcfg := utils.GetKubernetesClient("TODO: Secrets")
+	kcfg, err := utils.GetClusterStoreClient("TODO: Secrets")
+	if err != nil {
+		return err
+	}
 
 	secrets.CreateSecretReference(kcfg.Clientset, secrets.KUBEFIRST_ENVIRONMENTS_SECRET_NAME, types.SecretListReference{
 		Name: "environments",
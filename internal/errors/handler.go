@@ -16,13 +16,16 @@ import (
 // HandleClusterError implements an error handler for standalone cluster objects
 func HandleClusterError(cl *pkgtypes.Cluster, condition string) error {
 
-	kcfg := utils.GetKubernetesClient(cl.ClusterName)
+	kcfg, err := utils.GetClusterStoreClient(cl.ClusterName)
+	if err != nil {
+		return err
+	}
 
 	cl.InProgress = false
 	cl.Status = constants.ClusterStatusError
 	cl.LastCondition = condition
 
-	err := secrets.UpdateCluster(kcfg.Clientset, *cl)
+	err = secrets.UpdateCluster(kcfg.Clientset, *cl)
 
 	if err != nil {
 		return err
@@ -18,19 +18,34 @@ import (
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	githttps "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
 )
 
-func Clone(gitRef, repoLocalPath, repoURL string) (*git.Repository, error) {
+// DefaultBranch is the branch kubefirst repositories are pushed to unless a
+// cluster configures its own
+const DefaultBranch = "main"
 
-	// kubefirst tags do not contain a `v` prefix, to use the library requires the v to be valid
-	isSemVer := semver.IsValid(gitRef)
+// BranchOrDefault returns branch, or DefaultBranch when branch is empty
+func BranchOrDefault(branch string) string {
+	if branch == "" {
+		return DefaultBranch
+	}
 
-	var refName plumbing.ReferenceName
+	return branch
+}
 
-	if isSemVer {
-		refName = plumbing.NewTagReferenceName(gitRef)
-	} else {
-		refName = plumbing.NewBranchReferenceName(gitRef)
+func Clone(gitRef, repoLocalPath, repoURL string) (*git.Repository, error) {
+
+	refName, err := ResolveRef(gitRef, repoURL)
+	if err != nil {
+		log.Warn().Msgf("unable to resolve git ref %s, guessing reference type: %s", gitRef, err)
+
+		// kubefirst tags do not contain a `v` prefix, to use the library requires the v to be valid
+		if semver.IsValid(gitRef) {
+			refName = plumbing.NewTagReferenceName(gitRef)
+		} else {
+			refName = plumbing.NewBranchReferenceName(gitRef)
+		}
 	}
 
 	repo, err := git.PlainClone(repoLocalPath, false, &git.CloneOptions{
@@ -45,6 +60,36 @@ func Clone(gitRef, repoLocalPath, repoURL string) (*git.Repository, error) {
 	return repo, nil
 }
 
+// ResolveRef returns the full reference name of gitRef in the remote
+// repository at repoURL, branches take precedence over tags of the same name
+func ResolveRef(gitRef, repoURL string) (plumbing.ReferenceName, error) {
+	remote := git.NewRemote(memory.NewStorage(), &gitConfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{repoURL},
+	})
+
+	refs, err := remote.List(&git.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error listing refs of %s: %s", repoURL, err)
+	}
+
+	var tagRef plumbing.ReferenceName
+	for _, ref := range refs {
+		switch ref.Name() {
+		case plumbing.NewBranchReferenceName(gitRef):
+			return ref.Name(), nil
+		case plumbing.NewTagReferenceName(gitRef):
+			tagRef = ref.Name()
+		}
+	}
+
+	if tagRef == "" {
+		return "", fmt.Errorf("git ref %s not found in %s", gitRef, repoURL)
+	}
+
+	return tagRef, nil
+}
+
 func ClonePrivateRepo(gitRef string, repoLocalPath string, repoURL string, userName string, token string) (*git.Repository, error) {
 
 	// kubefirst tags do not contain a `v` prefix, to use the library requires the v to be valid
@@ -74,7 +119,8 @@ func ClonePrivateRepo(gitRef string, repoLocalPath string, repoURL string, userN
 	return repo, nil
 }
 
-func CloneRefSetMain(gitRef, repoLocalPath, repoURL string) (*git.Repository, error) {
+// CloneRefSetMain clones gitRef of repoURL and checks it out as mainBranch
+func CloneRefSetMain(gitRef, repoLocalPath, repoURL, mainBranch string) (*git.Repository, error) {
 
 	log.Info().Msgf("cloning url: %s - git ref: %s", repoURL, gitRef)
 
@@ -84,8 +130,8 @@ func CloneRefSetMain(gitRef, repoLocalPath, repoURL string) (*git.Repository, er
 		return nil, err
 	}
 
-	if gitRef != "main" {
-		repo, err = SetRefToMainBranch(repo)
+	if gitRef != mainBranch {
+		repo, err = SetRefToMainBranch(repo, mainBranch)
 		if err != nil {
 			return nil, fmt.Errorf("error setting main branch from git ref: %s", gitRef)
 		}
@@ -99,10 +145,41 @@ func CloneRefSetMain(gitRef, repoLocalPath, repoURL string) (*git.Repository, er
 	return repo, nil
 }
 
-// SetRefToMainBranch sets the provided gitRef (branch or tag) to the main branch
-func SetRefToMainBranch(repo *git.Repository) (*git.Repository, error) {
+// MoveHeadToBranch points mainBranch at the current commit, checks it out and
+// removes the branch HEAD previously tracked, whichever name git init gave it
+func MoveHeadToBranch(repo *git.Repository, mainBranch string) (*git.Repository, error) {
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return nil, fmt.Errorf("error reading HEAD: %s", err)
+	}
+	sourceBranch := head.Target()
+
+	repo, err = SetRefToMainBranch(repo, mainBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	if !sourceBranch.IsBranch() || sourceBranch == plumbing.NewBranchReferenceName(mainBranch) {
+		return repo, nil
+	}
+	if _, err := repo.Storer.Reference(sourceBranch); err != nil {
+		if err == plumbing.ErrReferenceNotFound {
+			return repo, nil
+		}
+		return nil, fmt.Errorf("error reading previous git ref %s: %s", sourceBranch.Short(), err)
+	}
+	err = repo.Storer.RemoveReference(sourceBranch)
+	if err != nil {
+		return nil, fmt.Errorf("error removing previous git ref: %s", err)
+	}
+	return repo, nil
+}
+
+// SetRefToMainBranch sets the checked out gitRef (branch or tag) to
+// mainBranch and checks it out
+func SetRefToMainBranch(repo *git.Repository, mainBranch string) (*git.Repository, error) {
 	w, _ := repo.Worktree()
-	branchName := plumbing.NewBranchReferenceName("main")
+	branchName := plumbing.NewBranchReferenceName(mainBranch)
 	headRef, err := repo.Head()
 	if err != nil {
 		return nil, fmt.Errorf("error Setting reference: %s", err)
@@ -116,11 +193,35 @@ func SetRefToMainBranch(repo *git.Repository) (*git.Repository, error) {
 
 	err = w.Checkout(&git.CheckoutOptions{Branch: ref.Name()})
 	if err != nil {
-		return nil, fmt.Errorf("error checking out main: %s", err)
+		return nil, fmt.Errorf("error checking out %s: %s", mainBranch, err)
 	}
 	return repo, nil
 }
 
+// ResetToCommit moves the checked out branch of repo to commit, which must
+// already have been cloned
+func ResetToCommit(repo *git.Repository, commit string) error {
+	hash, err := repo.ResolveRevision(plumbing.Revision(commit))
+	if err != nil {
+		return fmt.Errorf("commit %s not found: %s", commit, err)
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	err = w.Reset(&git.ResetOptions{
+		Commit: *hash,
+		Mode:   git.HardReset,
+	})
+	if err != nil {
+		return fmt.Errorf("error resetting to commit %s: %s", commit, err)
+	}
+
+	return nil
+}
+
 func AddRemote(newGitRemoteURL, remoteName string, repo *git.Repository) error {
 
 	log.Info().Msgf("git remote add %s %s", remoteName, newGitRemoteURL)
@@ -0,0 +1,84 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package gitClient
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// initRepo creates a repository whose HEAD tracks initialBranch, the way
+// git init does depending on init.defaultBranch, with a single commit
+func initRepo(t *testing.T, initialBranch string) *git.Repository {
+	t.Helper()
+
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("error initializing repository: %s", err)
+	}
+	head := plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(initialBranch))
+	if err := repo.Storer.SetReference(head); err != nil {
+		t.Fatalf("error setting HEAD: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("metaphor\n"), 0644); err != nil {
+		t.Fatalf("error writing file: %s", err)
+	}
+	if err := Commit(repo, "init commit"); err != nil {
+		t.Fatalf("error committing: %s", err)
+	}
+	return repo
+}
+
+func TestMoveHeadToBranch(t *testing.T) {
+	tests := []struct {
+		name          string
+		initialBranch string
+		mainBranch    string
+	}{
+		{name: "init.defaultBranch=master", initialBranch: "master", mainBranch: "main"},
+		{name: "init.defaultBranch=main", initialBranch: "main", mainBranch: "main"},
+		{name: "custom default branch from master", initialBranch: "master", mainBranch: "trunk"},
+		{name: "custom default branch from main", initialBranch: "main", mainBranch: "trunk"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := initRepo(t, tt.initialBranch)
+
+			repo, err := MoveHeadToBranch(repo, tt.mainBranch)
+			if err != nil {
+				t.Fatalf("MoveHeadToBranch() error = %s", err)
+			}
+
+			head, err := repo.Head()
+			if err != nil {
+				t.Fatalf("error reading HEAD: %s", err)
+			}
+			if want := plumbing.NewBranchReferenceName(tt.mainBranch); head.Name() != want {
+				t.Errorf("HEAD = %s, want %s", head.Name(), want)
+			}
+
+			branches, err := repo.Branches()
+			if err != nil {
+				t.Fatalf("error listing branches: %s", err)
+			}
+			var names []string
+			branches.ForEach(func(ref *plumbing.Reference) error {
+				names = append(names, ref.Name().Short())
+				return nil
+			})
+			if len(names) != 1 || names[0] != tt.mainBranch {
+				t.Errorf("branches = %v, want [%s]", names, tt.mainBranch)
+			}
+		})
+	}
+}
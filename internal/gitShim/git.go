@@ -14,6 +14,7 @@ import (
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/kubefirst/kubefirst-api/internal/gitClient"
+	"github.com/kubefirst/kubefirst-api/internal/github"
 	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
 	"github.com/rs/zerolog/log"
 )
@@ -55,7 +56,7 @@ func PrepareMgmtCluster(cluster pkgtypes.Cluster) error {
 		}
 	}
 
-	gitopsRepo, err := gitClient.CloneRefSetMain(cluster.GitopsTemplateBranch, gitopsDir, cluster.GitopsTemplateURL)
+	gitopsRepo, err := gitClient.CloneRefSetMain(cluster.GitopsTemplateBranch, gitopsDir, cluster.GitopsTemplateURL, gitClient.BranchOrDefault(cluster.DefaultBranch))
 	if err != nil {
 		log.Fatal().Msgf("error cloning repository: %s", err)
 
@@ -78,9 +79,14 @@ func PrepareMgmtCluster(cluster pkgtypes.Cluster) error {
 }
 
 func PrepareGitEnvironment(cluster *pkgtypes.Cluster, gitopsDir string) error {
+	// GitHub App installation tokens expire, the stored one may be stale
+	_, err := github.RefreshGitAuth(&cluster.GitAuth)
+	if err != nil {
+		return err
+	}
 
 	repoUrl := fmt.Sprintf("https://%s/%s/gitops", cluster.GitHost, cluster.GitAuth.Owner)
-	_, err := gitClient.ClonePrivateRepo("main", gitopsDir, repoUrl, cluster.GitAuth.User, cluster.GitAuth.Token)
+	_, err = gitClient.ClonePrivateRepo(gitClient.BranchOrDefault(cluster.DefaultBranch), gitopsDir, repoUrl, cluster.GitAuth.User, cluster.GitAuth.Token)
 	if err != nil {
 		log.Fatal().Msgf("error cloning repository: %s", err)
 
@@ -23,10 +23,21 @@ type GitInitParameters struct {
 	Teams        []string
 	GithubOrg    string
 	GitlabGroup  string
+
+	// AdoptRepositories pushes into repositories that already exist when
+	// they're empty and adopts existing teams, ForceAdoptRepositories adopts
+	// non-empty repositories as well
+	AdoptRepositories      bool
+	ForceAdoptRepositories bool
 }
 
-// InitializeGitProvider
-func InitializeGitProvider(p *GitInitParameters) error {
+// InitializeGitProvider checks the repositories and teams kubefirst creates
+// don't exist yet and returns the existing repositories and teams that were
+// adopted
+func InitializeGitProvider(p *GitInitParameters) ([]string, []string, error) {
+	adopted := []string{}
+	adoptedTeams := []string{}
+
 	switch p.GitProvider {
 	case "github":
 		githubSession := github.New(p.GitToken)
@@ -43,6 +54,22 @@ func InitializeGitProvider(p *GitInitParameters) error {
 
 			if responseStatusCode == repositoryExistsStatusCode {
 				log.Info().Msgf("repository https://github.com/%s/%s exists", p.GithubOrg, repositoryName)
+
+				if p.AdoptRepositories {
+					empty, err := githubSession.IsRepoEmpty(p.GithubOrg, repositoryName)
+					if err != nil {
+						return nil, nil, err
+					}
+					if empty || p.ForceAdoptRepositories {
+						log.Info().Msgf("adopting existing repository https://github.com/%s/%s (empty: %t)", p.GithubOrg, repositoryName, empty)
+						adopted = append(adopted, repositoryName)
+						continue
+					}
+					errorMsg = errorMsg + fmt.Sprintf("https://github.com/%s/%s (not empty, set force_adopt_repositories to overwrite it)\n\t", p.GithubOrg, repositoryName)
+					newRepositoryExists = true
+					continue
+				}
+
 				errorMsg = errorMsg + fmt.Sprintf("https://github.com/%s/%s\n\t", p.GithubOrg, repositoryName)
 				newRepositoryExists = true
 			} else if responseStatusCode == repositoryDoesNotExistStatusCode {
@@ -50,7 +77,7 @@ func InitializeGitProvider(p *GitInitParameters) error {
 			}
 		}
 		if newRepositoryExists {
-			return fmt.Errorf(errorMsg)
+			return nil, nil, fmt.Errorf(errorMsg)
 		}
 
 		newTeamExists := false
@@ -65,6 +92,11 @@ func InitializeGitProvider(p *GitInitParameters) error {
 
 			if responseStatusCode == teamExistsStatusCode {
 				log.Info().Msgf("team https://github.com/%s/%s exists", p.GithubOrg, teamName)
+				if p.AdoptRepositories {
+					log.Info().Msgf("adopting existing team https://github.com/orgs/%s/teams/%s", p.GithubOrg, teamName)
+					adoptedTeams = append(adoptedTeams, teamName)
+					continue
+				}
 				errorMsg = errorMsg + fmt.Sprintf("https://github.com/orgs/%s/teams/%s\n\t", p.GithubOrg, teamName)
 				newTeamExists = true
 			} else if responseStatusCode == teamDoesNotExistStatusCode {
@@ -72,12 +104,12 @@ func InitializeGitProvider(p *GitInitParameters) error {
 			}
 		}
 		if newTeamExists {
-			return fmt.Errorf(errorMsg)
+			return nil, nil, fmt.Errorf(errorMsg)
 		}
 	case "gitlab":
 		gitlabClient, err := gitlab.NewGitLabClient(p.GitToken, p.GitlabGroup)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 
 		// Check for existing base projects
@@ -87,9 +119,19 @@ func InitializeGitProvider(p *GitInitParameters) error {
 		}
 		for _, repositoryName := range p.Repositories {
 			for _, project := range projects {
-				if project.Name == repositoryName {
-					return fmt.Errorf("project %s already exists and will need to be deleted before continuing", repositoryName)
+				if project.Name != repositoryName {
+					continue
 				}
+
+				if p.AdoptRepositories && (project.EmptyRepo || p.ForceAdoptRepositories) {
+					log.Info().Msgf("adopting existing project %s (empty: %t)", repositoryName, project.EmptyRepo)
+					adopted = append(adopted, repositoryName)
+					continue
+				}
+				if p.AdoptRepositories {
+					return nil, nil, fmt.Errorf("project %s already exists and is not empty, set force_adopt_repositories to overwrite it", repositoryName)
+				}
+				return nil, nil, fmt.Errorf("project %s already exists and will need to be deleted before continuing", repositoryName)
 			}
 		}
 
@@ -101,12 +143,18 @@ func InitializeGitProvider(p *GitInitParameters) error {
 		}
 		for _, teamName := range p.Repositories {
 			for _, sg := range subgroups {
-				if sg.Name == teamName {
-					return fmt.Errorf("subgroup %s already exists and will need to be deleted before continuing", teamName)
+				if sg.Name != teamName {
+					continue
+				}
+
+				if p.AdoptRepositories {
+					log.Info().Msgf("subgroup %s already exists, adopting it", teamName)
+					continue
 				}
+				return nil, nil, fmt.Errorf("subgroup %s already exists and will need to be deleted before continuing", teamName)
 			}
 		}
 	}
 
-	return nil
+	return adopted, adoptedTeams, nil
 }
@@ -0,0 +1,123 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bradleyfalzon/ghinstallation/v2"
+	"github.com/google/go-github/v45/github"
+	"github.com/kubefirst/kubefirst-api/internal/httpCommon"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// appTokenRefreshWindow is how long before expiry an installation token is
+// replaced, long enough for a terraform apply or a push to finish with it
+const appTokenRefreshWindow = time.Minute * 15
+
+type appToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+// appTokens caches installation tokens by app and installation id so they
+// are only minted when the previous one is about to expire
+var appTokens = struct {
+	sync.Mutex
+	tokens map[string]appToken
+}{tokens: map[string]appToken{}}
+
+// newAppClient returns a client authenticated as the GitHub App itself
+func newAppClient(appID int64, privateKey string) (*github.Client, error) {
+	transport, err := ghinstallation.NewAppsTransport(httpCommon.NewRateLimitHttpClient().Transport, appID, []byte(privateKey))
+	if err != nil {
+		return nil, fmt.Errorf("error loading github app private key: %s", err)
+	}
+	return github.NewClient(&http.Client{Transport: transport}), nil
+}
+
+// AppInstallationToken returns an installation token for the GitHub App that
+// is valid for at least appTokenRefreshWindow, minting a new one when needed
+func AppInstallationToken(appID int64, installationID int64, privateKey string) (string, error) {
+	key := fmt.Sprintf("%d/%d", appID, installationID)
+
+	appTokens.Lock()
+	defer appTokens.Unlock()
+
+	cached, ok := appTokens.tokens[key]
+	if ok && time.Until(cached.expiresAt) > appTokenRefreshWindow {
+		return cached.token, nil
+	}
+
+	client, err := newAppClient(appID, privateKey)
+	if err != nil {
+		return "", err
+	}
+	token, _, err := client.Apps.CreateInstallationToken(context.Background(), installationID, nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating token for github app installation %d: %s", installationID, err)
+	}
+
+	appTokens.tokens[key] = appToken{
+		token:     token.GetToken(),
+		expiresAt: token.GetExpiresAt(),
+	}
+	log.Info().Msgf("minted github app installation token for installation %d, expires at %s", installationID, token.GetExpiresAt().Format(time.RFC3339))
+
+	return token.GetToken(), nil
+}
+
+// VerifyAppInstallation confirms the GitHub App is installed on owner and
+// returns the bot login its installation tokens act as
+func VerifyAppInstallation(appID int64, installationID int64, privateKey string, owner string) (string, error) {
+	client, err := newAppClient(appID, privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	app, _, err := client.Apps.Get(context.Background(), "")
+	if err != nil {
+		return "", fmt.Errorf("error getting github app %d: %s", appID, err)
+	}
+
+	installation, _, err := client.Apps.GetInstallation(context.Background(), installationID)
+	if err != nil {
+		return "", fmt.Errorf("error getting github app installation %d: %s", installationID, err)
+	}
+	account := installation.GetAccount().GetLogin()
+	if !strings.EqualFold(account, owner) {
+		return "", fmt.Errorf("github app installation %d belongs to %s, not %s", installationID, account, owner)
+	}
+
+	return fmt.Sprintf("%s[bot]", app.GetSlug()), nil
+}
+
+// RefreshGitAuth replaces the token in auth with a current installation token
+// when it is configured for GitHub App authentication, and reports whether
+// the token changed
+func RefreshGitAuth(auth *pkgtypes.GitAuth) (bool, error) {
+	if !auth.UsesGitHubApp() {
+		return false, nil
+	}
+
+	token, err := AppInstallationToken(auth.AppID, auth.AppInstallationID, auth.AppPrivateKey)
+	if err != nil {
+		return false, err
+	}
+	if token == auth.Token {
+		return false, nil
+	}
+	auth.Token = token
+
+	return true, nil
+}
@@ -36,25 +36,43 @@ var (
 // VerifyTokenPermissions compares scope of the provided token to the required
 // scopes for kubefirst functionality
 func VerifyTokenPermissions(githubToken string) error {
+	missingScopes, err := GetMissingTokenScopes(githubToken)
+	if err != nil {
+		return err
+	}
+
+	// Report on any missing scopes
+	if len(missingScopes) != 0 {
+		return fmt.Errorf("the supplied github token is missing authorization scopes - please add: %v", missingScopes)
+	}
+
+	return nil
+}
+
+// GetMissingTokenScopes returns the scopes required for kubefirst functionality
+// (creating repositories, managing webhooks and teams) that the provided token
+// does not have
+func GetMissingTokenScopes(githubToken string) ([]string, error) {
 	req, err := http.NewRequest(http.MethodGet, githubApiUrl, nil)
 	if err != nil {
 		log.Info().Msg("error setting github owner permissions request")
+		return nil, err
 	}
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", githubToken))
 
 	res, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	defer res.Body.Close()
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf(
+		return nil, fmt.Errorf(
 			"something went wrong calling GitHub API, http status code is: %d, and response is: %q",
 			res.StatusCode,
 			string(body),
@@ -76,10 +94,5 @@ func VerifyTokenPermissions(githubToken string) error {
 		}
 	}
 
-	// Report on any missing scopes
-	if len(missingScopes) != 0 {
-		return fmt.Errorf("the supplied github token is missing authorization scopes - please add: %v", missingScopes)
-	}
-
-	return nil
+	return missingScopes, nil
 }
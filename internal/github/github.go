@@ -13,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/kubefirst/kubefirst-api/internal/httpCommon"
 	"github.com/rs/zerolog/log"
 
 	"github.com/google/go-github/v45/github"
@@ -32,7 +33,8 @@ func New(token string) GithubSession {
 		log.Fatal().Msg("Unauthorized: No token present")
 	}
 	var gSession GithubSession
-	gSession.context = context.Background()
+	// Back off and retry when github rate limits requests
+	gSession.context = context.WithValue(context.Background(), oauth2.HTTPClient, httpCommon.NewRateLimitHttpClient())
 	gSession.staticToken = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
 	gSession.oauthClient = oauth2.NewClient(gSession.context, gSession.staticToken)
 	gSession.gitClient = github.NewClient(gSession.oauthClient)
@@ -82,6 +84,57 @@ func (g GithubSession) CreatePrivateRepo(org string, name string, description st
 	return nil
 }
 
+// CreateEmptyPrivateRepo creates a private repository without an initial
+// commit so an existing history can be pushed into it, an owner matching the
+// authenticated user creates it under the user instead of an organization
+func (g GithubSession) CreateEmptyPrivateRepo(owner string, name string, description string) error {
+	user, _, err := g.gitClient.Users.Get(g.context, "")
+	if err != nil {
+		return fmt.Errorf("error getting authenticated github user: %s", err)
+	}
+
+	org := owner
+	if strings.EqualFold(user.GetLogin(), owner) {
+		org = ""
+	}
+
+	isPrivate := true
+	_, _, err = g.gitClient.Repositories.Create(g.context, org, &github.Repository{
+		Name:        &name,
+		Private:     &isPrivate,
+		Description: &description,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating private repo %s/%s: %s", owner, name, err)
+	}
+	log.Info().Msgf("created repository https://github.com/%s/%s", owner, name)
+
+	return nil
+}
+
+// VerifyOwnerAccess checks the token can create repositories under owner,
+// which is either the authenticated user or an organization the user is an
+// active member of
+func (g GithubSession) VerifyOwnerAccess(owner string) error {
+	user, _, err := g.gitClient.Users.Get(g.context, "")
+	if err != nil {
+		return fmt.Errorf("error getting authenticated github user: %s", err)
+	}
+	if strings.EqualFold(user.GetLogin(), owner) {
+		return nil
+	}
+
+	membership, _, err := g.gitClient.Organizations.GetOrgMembership(g.context, "", owner)
+	if err != nil {
+		return fmt.Errorf("the github token has no access to organization %s: %s", owner, err)
+	}
+	if membership.GetState() != "active" {
+		return fmt.Errorf("the github token's membership in organization %s is %s, it must be active", owner, membership.GetState())
+	}
+
+	return nil
+}
+
 // RemoveRepo Removes a repository based on repository owner and name. It returns github.Response that hold http data,
 // as http status code, the caller can make use of the http status code to validate the response.
 func (g GithubSession) RemoveRepo(owner string, name string) (*github.Response, error) {
@@ -292,6 +345,22 @@ func (g GithubSession) CheckRepoExists(owner string, name string) int {
 	return response.StatusCode
 }
 
+// IsRepoEmpty reports whether a repository has no commits yet
+func (g GithubSession) IsRepoEmpty(owner string, name string) (bool, error) {
+	commits, response, err := g.gitClient.Repositories.ListCommits(g.context, owner, name, &github.CommitsListOptions{
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	// github answers 409 Conflict for repositories without commits
+	if response != nil && response.StatusCode == http.StatusConflict {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error listing commits of %s/%s: %s", owner, name, err)
+	}
+
+	return len(commits) == 0, nil
+}
+
 // GetRepo - Always returns a status code for whether a team exists or not
 func (g GithubSession) CheckTeamExists(owner string, name string) int {
 	_, response, _ := g.gitClient.Teams.GetTeamBySlug(g.context, owner, name)
@@ -324,6 +393,27 @@ func (g GithubSession) DeleteRepositoryWebhook(owner string, repository string,
 	return nil
 }
 
+// EditRepositoryWebhook replaces the url, secret and events of an existing
+// webhook and activates it
+func (g GithubSession) EditRepositoryWebhook(owner string, repository string, hookID int64, hookURL string, hookSecret string, hookEvents []string) error {
+	active := true
+	_, _, err := g.gitClient.Repositories.EditHook(g.context, owner, repository, hookID, &github.Hook{
+		Active: &active,
+		Events: hookEvents,
+		Config: map[string]interface{}{
+			"content_type": "json",
+			"insecure_ssl": 0,
+			"url":          hookURL,
+			"secret":       hookSecret,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error editing webhook %d of %s/%s: %v", hookID, owner, repository, err)
+	}
+
+	return nil
+}
+
 // ListRepoWebhooks returns all webhooks for a repository
 func (g GithubSession) ListRepoWebhooks(owner string, repo string) ([]*github.Hook, error) {
 	container := make([]*github.Hook, 0)
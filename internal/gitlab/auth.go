@@ -16,14 +16,14 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-const (
+var (
 	gitlabApiUrl = "https://gitlab.com/api/v4"
-)
 
-var (
+	// api is needed to create projects, deploy keys and webhooks, the
+	// repository scopes to push the gitops and metaphor repositories and the
+	// registry scopes to push and clean up the metaphor container images
 	requiredScopes = []string{
-		"read_api",
-		"read_user",
+		"api",
 		"read_repository",
 		"write_repository",
 		"read_registry",
@@ -34,25 +34,43 @@ var (
 // VerifyTokenPermissions compares scope of the provided token to the required
 // scopes for kubefirst functionality
 func VerifyTokenPermissions(gitlabToken string) error {
+	missingScopes, err := GetMissingTokenScopes(gitlabToken)
+	if err != nil {
+		return err
+	}
+
+	// Report on any missing scopes
+	if len(missingScopes) != 0 {
+		return fmt.Errorf("the supplied gitlab token is missing authorization scopes - please add: %v", missingScopes)
+	}
+
+	return nil
+}
+
+// GetMissingTokenScopes returns the scopes required for kubefirst functionality
+// (creating projects, managing webhooks and groups) that the provided token
+// does not have
+func GetMissingTokenScopes(gitlabToken string) ([]string, error) {
 	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/personal_access_tokens/self", gitlabApiUrl), nil)
 	if err != nil {
 		log.Info().Msg("error setting gitlab owner permissions request")
+		return nil, err
 	}
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", gitlabToken))
 
 	res, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	defer res.Body.Close()
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf(
+		return nil, fmt.Errorf(
 			"something went wrong calling GitLab API, http status code is: %d, and response is: %q",
 			res.StatusCode,
 			string(body),
@@ -60,35 +78,21 @@ func VerifyTokenPermissions(gitlabToken string) error {
 	}
 
 	// Get token scopes
-	var responseJson interface{}
-	err = json.Unmarshal(body, &responseJson)
-	if err != nil {
-		return err
-	}
-	responseJsonMap := responseJson.(map[string]interface{})
-	scopes := responseJsonMap["scopes"].([]interface{})
-	scopesSlice := make([]string, 0)
-	for _, s := range scopes {
-		scopesSlice = append(scopesSlice, string(s.(string)))
+	var token struct {
+		Scopes []string `json:"scopes"`
 	}
-
-	// api allows all access so we won't need to check the rest
-	if pkg.FindStringInSlice(scopesSlice, "api") {
-		return nil
+	err = json.Unmarshal(body, &token)
+	if err != nil {
+		return nil, err
 	}
 
 	// Compare token scopes to required scopes
 	missingScopes := make([]string, 0)
 	for _, ts := range requiredScopes {
-		if !pkg.FindStringInSlice(scopesSlice, ts) {
+		if !pkg.FindStringInSlice(token.Scopes, ts) {
 			missingScopes = append(missingScopes, ts)
 		}
 	}
 
-	// Report on any missing scopes
-	if !pkg.FindStringInSlice(scopesSlice, "api") && len(missingScopes) != 0 {
-		return fmt.Errorf("the supplied github token is missing authorization scopes - please add: %v", missingScopes)
-	}
-
-	return nil
+	return missingScopes, nil
 }
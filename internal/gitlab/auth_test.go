@@ -0,0 +1,72 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package gitlab
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGetMissingTokenScopes(t *testing.T) {
+	defer func(original string) {
+		gitlabApiUrl = original
+	}(gitlabApiUrl)
+
+	for name, test := range map[string]struct {
+		status      int
+		scopes      []string
+		wantMissing []string
+		wantErr     bool
+	}{
+		"all scopes": {
+			status:      http.StatusOK,
+			scopes:      []string{"api", "read_repository", "write_repository", "read_registry", "write_registry", "read_user"},
+			wantMissing: []string{},
+		},
+		"api only": {
+			status:      http.StatusOK,
+			scopes:      []string{"api"},
+			wantMissing: []string{"read_repository", "write_repository", "read_registry", "write_registry"},
+		},
+		"no registry scopes": {
+			status:      http.StatusOK,
+			scopes:      []string{"api", "read_repository", "write_repository"},
+			wantMissing: []string{"read_registry", "write_registry"},
+		},
+		"no scopes": {
+			status:      http.StatusOK,
+			wantMissing: []string{"api", "read_repository", "write_repository", "read_registry", "write_registry"},
+		},
+		"invalid token": {
+			status:  http.StatusUnauthorized,
+			wantErr: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/personal_access_tokens/self" || r.Header.Get("Authorization") != "Bearer glpat-token" {
+					t.Errorf("unexpected request %s with authorization %q", r.URL.Path, r.Header.Get("Authorization"))
+				}
+				w.WriteHeader(test.status)
+				json.NewEncoder(w).Encode(map[string][]string{"scopes": test.scopes})
+			}))
+			defer server.Close()
+			gitlabApiUrl = server.URL
+
+			missing, err := GetMissingTokenScopes("glpat-token")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("GetMissingTokenScopes() error = %v, want error %t", err, test.wantErr)
+			}
+			if !test.wantErr && !reflect.DeepEqual(missing, test.wantMissing) {
+				t.Errorf("GetMissingTokenScopes() = %v, want %v", missing, test.wantMissing)
+			}
+		})
+	}
+}
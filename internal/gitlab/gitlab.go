@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/kubefirst/kubefirst-api/internal/httpCommon"
 	"github.com/rs/zerolog/log"
 	"github.com/xanzy/go-gitlab"
 )
@@ -17,7 +18,7 @@ import (
 // NewGitLabClient instantiates a wrapper to communicate with GitLab
 // It sets the path and ID of the group under which resources will be managed
 func NewGitLabClient(token string, parentGroupName string) (GitLabWrapper, error) {
-	git, err := gitlab.NewClient(token)
+	git, err := gitlab.NewClient(token, gitlab.WithHTTPClient(httpCommon.NewRateLimitHttpClient()))
 	if err != nil {
 		return GitLabWrapper{}, fmt.Errorf("error instantiating gitlab client: %s", err)
 	}
@@ -112,6 +113,23 @@ func (gl *GitLabWrapper) GetProjectID(projectName string) (int, error) {
 	return 0, fmt.Errorf("could not get project ID for project %s", projectName)
 }
 
+// DeleteProject deletes a project of the parent group, its container
+// registry repositories have to be removed first
+func (gl *GitLabWrapper) DeleteProject(projectName string) error {
+	projectID, err := gl.GetProjectID(projectName)
+	if err != nil {
+		return err
+	}
+
+	_, err = gl.Client.Projects.DeleteProject(projectID)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("deleted project %s", projectName)
+
+	return nil
+}
+
 // GetProjects for a specific parent group by ID
 func (gl *GitLabWrapper) GetProjects() ([]gitlab.Project, error) {
 	container := make([]gitlab.Project, 0)
@@ -432,6 +450,53 @@ func (gl *GitLabWrapper) DeleteProjectWebhook(projectName string, url string) er
 	return nil
 }
 
+// AddProjectWebhook creates a project webhook to url for push, merge request
+// and comment events, signed with token
+func (gl *GitLabWrapper) AddProjectWebhook(projectName string, url string, token string) error {
+	projectID, err := gl.GetProjectID(projectName)
+	if err != nil {
+		return err
+	}
+
+	enabled := true
+	_, _, err = gl.Client.Projects.AddProjectHook(projectID, &gitlab.AddProjectHookOptions{
+		URL:                 &url,
+		Token:               &token,
+		PushEvents:          &enabled,
+		MergeRequestsEvents: &enabled,
+		NoteEvents:          &enabled,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating webhook %s for project %s: %s", url, projectName, err)
+	}
+	log.Info().Msgf("created hook %s/%s", projectName, url)
+
+	return nil
+}
+
+// EditProjectWebhook replaces the url and token of an existing project
+// webhook and enables push, merge request and comment events
+func (gl *GitLabWrapper) EditProjectWebhook(projectName string, hookID int, url string, token string) error {
+	projectID, err := gl.GetProjectID(projectName)
+	if err != nil {
+		return err
+	}
+
+	enabled := true
+	_, _, err = gl.Client.Projects.EditProjectHook(projectID, hookID, &gitlab.EditProjectHookOptions{
+		URL:                 &url,
+		Token:               &token,
+		PushEvents:          &enabled,
+		MergeRequestsEvents: &enabled,
+		NoteEvents:          &enabled,
+	})
+	if err != nil {
+		return fmt.Errorf("error editing webhook %d of project %s: %s", hookID, projectName, err)
+	}
+
+	return nil
+}
+
 // ListProjectWebhooks returns all webhooks for a project
 func (gl *GitLabWrapper) ListProjectWebhooks(projectID int) ([]gitlab.ProjectHook, error) {
 	container := make([]gitlab.ProjectHook, 0)
@@ -0,0 +1,134 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package httpCommon
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	rateLimitMaxRetries = 5
+	rateLimitMaxWait    = 15 * time.Minute
+	rateLimitMinWait    = 1 * time.Second
+)
+
+// RateLimitTransport is an http.RoundTripper that waits out git provider rate
+// limits and retries the request once the reset window has passed
+//
+// It understands the headers returned by GitHub (X-RateLimit-*) and GitLab
+// (RateLimit-*) as well as Retry-After
+type RateLimitTransport struct {
+	Base       http.RoundTripper
+	MaxRetries int
+	MaxWait    time.Duration
+}
+
+// NewRateLimitHttpClient returns an http client that backs off when rate limited
+func NewRateLimitHttpClient() *http.Client {
+	return &http.Client{
+		Transport: &RateLimitTransport{
+			Base:       http.DefaultTransport,
+			MaxRetries: rateLimitMaxRetries,
+			MaxWait:    rateLimitMaxWait,
+		},
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, err := base.RoundTrip(req)
+		if err != nil {
+			return res, err
+		}
+
+		wait, limited := rateLimitWait(res, time.Now())
+		if !limited || attempt >= t.MaxRetries {
+			return res, nil
+		}
+
+		// Requests with a body can only be retried if it can be replayed
+		if req.Body != nil && req.GetBody == nil {
+			return res, nil
+		}
+
+		if t.MaxWait > 0 && wait > t.MaxWait {
+			log.Warn().Msgf("rate limited by %s, reset in %s exceeds maximum wait of %s - not retrying", req.URL.Host, wait, t.MaxWait)
+			return res, nil
+		}
+
+		log.Warn().Msgf("rate limited by %s, backing off for %s before retrying (attempt %d of %d)", req.URL.Host, wait, attempt+1, t.MaxRetries)
+		res.Body.Close()
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// rateLimitWait returns how long to wait before retrying a rate limited
+// response, and whether the response was rate limited at all
+func rateLimitWait(res *http.Response, now time.Time) (time.Duration, bool) {
+	if res.StatusCode != http.StatusTooManyRequests && res.StatusCode != http.StatusForbidden {
+		return 0, false
+	}
+
+	if retryAfter := res.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return clampWait(time.Duration(seconds) * time.Second), true
+		}
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			return clampWait(date.Sub(now)), true
+		}
+	}
+
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		if res.Header.Get(prefix+"Remaining") != "0" {
+			continue
+		}
+		reset, err := strconv.ParseInt(res.Header.Get(prefix+"Reset"), 10, 64)
+		if err != nil {
+			return rateLimitMinWait, true
+		}
+		return clampWait(time.Unix(reset, 0).Sub(now)), true
+	}
+
+	// A 403 without rate limit headers is a permissions error
+	if res.StatusCode == http.StatusForbidden {
+		return 0, false
+	}
+
+	return rateLimitMinWait, true
+}
+
+func clampWait(wait time.Duration) time.Duration {
+	if wait < rateLimitMinWait {
+		return rateLimitMinWait
+	}
+
+	return wait
+}
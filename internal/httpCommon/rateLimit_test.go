@@ -0,0 +1,100 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package httpCommon
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+	reset := strconv.FormatInt(now.Add(90*time.Second).Unix(), 10)
+
+	for name, test := range map[string]struct {
+		status  int
+		headers map[string]string
+		wait    time.Duration
+		limited bool
+	}{
+		"ok": {
+			status: http.StatusOK,
+		},
+		"retry-after seconds": {
+			status:  http.StatusTooManyRequests,
+			headers: map[string]string{"Retry-After": "30"},
+			wait:    30 * time.Second,
+			limited: true,
+		},
+		"retry-after date": {
+			status:  http.StatusTooManyRequests,
+			headers: map[string]string{"Retry-After": now.Add(2 * time.Minute).Format(http.TimeFormat)},
+			wait:    2 * time.Minute,
+			limited: true,
+		},
+		"retry-after in the past": {
+			status:  http.StatusTooManyRequests,
+			headers: map[string]string{"Retry-After": now.Add(-time.Minute).Format(http.TimeFormat)},
+			wait:    rateLimitMinWait,
+			limited: true,
+		},
+		"unparseable retry-after": {
+			status:  http.StatusTooManyRequests,
+			headers: map[string]string{"Retry-After": "soon"},
+			wait:    rateLimitMinWait,
+			limited: true,
+		},
+		"github reset": {
+			status:  http.StatusForbidden,
+			headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset},
+			wait:    90 * time.Second,
+			limited: true,
+		},
+		"gitlab reset": {
+			status:  http.StatusTooManyRequests,
+			headers: map[string]string{"RateLimit-Remaining": "0", "RateLimit-Reset": reset},
+			wait:    90 * time.Second,
+			limited: true,
+		},
+		"retry-after takes precedence over reset": {
+			status:  http.StatusTooManyRequests,
+			headers: map[string]string{"Retry-After": "10", "X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset},
+			wait:    10 * time.Second,
+			limited: true,
+		},
+		"unparseable reset": {
+			status:  http.StatusForbidden,
+			headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "later"},
+			wait:    rateLimitMinWait,
+			limited: true,
+		},
+		"remaining requests on 403": {
+			status:  http.StatusForbidden,
+			headers: map[string]string{"X-RateLimit-Remaining": "10", "X-RateLimit-Reset": reset},
+		},
+		"403 without headers": {
+			status: http.StatusForbidden,
+		},
+		"429 without headers": {
+			status:  http.StatusTooManyRequests,
+			wait:    rateLimitMinWait,
+			limited: true,
+		},
+	} {
+		res := &http.Response{StatusCode: test.status, Header: http.Header{}}
+		for key, value := range test.headers {
+			res.Header.Set(key, value)
+		}
+
+		wait, limited := rateLimitWait(res, now)
+		if wait != test.wait || limited != test.limited {
+			t.Errorf("%s: rateLimitWait() = %s, %v, want %s, %v", name, wait, limited, test.wait, test.limited)
+		}
+	}
+}
@@ -13,7 +13,6 @@ import (
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
-	"github.com/go-git/go-git/v5/plumbing"
 	pkg "github.com/kubefirst/kubefirst-api/internal"
 	"github.com/kubefirst/kubefirst-api/internal/gitClient"
 	cp "github.com/otiai10/copy"
@@ -93,7 +92,7 @@ func AdjustGitopsRepo(cloudProvider, clusterName, clusterType, gitopsRepoDir, gi
 	return nil
 }
 
-func AdjustMetaphorRepo(destinationMetaphorRepoGitURL, gitopsRepoDir, metaphorRepoName, gitProvider, k1Dir string) error {
+func AdjustMetaphorRepo(destinationMetaphorRepoGitURL, gitopsRepoDir, metaphorRepoName, gitProvider, k1Dir string, defaultBranch string) error {
 
 	//* create ~/.k1/metaphor
 	metaphorDir := fmt.Sprintf("%s/metaphor", k1Dir)
@@ -177,16 +176,11 @@ func AdjustMetaphorRepo(destinationMetaphorRepoGitURL, gitopsRepoDir, metaphorRe
 		return err
 	}
 
-	metaphorRepo, err = gitClient.SetRefToMainBranch(metaphorRepo)
+	metaphorRepo, err = gitClient.MoveHeadToBranch(metaphorRepo, gitClient.BranchOrDefault(defaultBranch))
 	if err != nil {
 		return err
 	}
 
-	// remove old git ref
-	err = metaphorRepo.Storer.RemoveReference(plumbing.NewBranchReferenceName("master"))
-	if err != nil {
-		return fmt.Errorf("error removing previous git ref: %s", err)
-	}
 	// create remote
 	_, err = metaphorRepo.CreateRemote(&config.RemoteConfig{
 		Name: "origin",
@@ -145,6 +145,7 @@ type GitopsDirectoryValues struct {
 	CloudProvider                 string
 	ClusterId                     string
 	KubeconfigPath                string
+	DefaultBranch                 string
 }
 
 type MetaphorTokenValues struct {
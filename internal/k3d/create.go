@@ -124,7 +124,8 @@ func PrepareGitRepositories(
 ) error {
 
 	//* clone the gitops-template repo
-	gitopsRepo, err := gitClient.CloneRefSetMain(gitopsTemplateBranch, gitopsDir, gitopsTemplateURL)
+	defaultBranch := gitClient.BranchOrDefault(gitopsTokens.DefaultBranch)
+	gitopsRepo, err := gitClient.CloneRefSetMain(gitopsTemplateBranch, gitopsDir, gitopsTemplateURL, defaultBranch)
 	if err != nil {
 		log.Panic().Msgf("error opening repo at: %s, err: %v", gitopsDir, err)
 	}
@@ -151,7 +152,7 @@ func PrepareGitRepositories(
 
 	// ! metaphor
 	// * adjust the content for the gitops repo
-	err = AdjustMetaphorRepo(DestinationMetaphorRepoURL, gitopsDir, metaphorRepoName, gitProvider, k1Dir)
+	err = AdjustMetaphorRepo(DestinationMetaphorRepoURL, gitopsDir, metaphorRepoName, gitProvider, k1Dir, defaultBranch)
 	if err != nil {
 		return err
 	}
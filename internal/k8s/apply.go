@@ -32,7 +32,8 @@ import (
 var decUnstructured = yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
 
 // ApplyObjects parses a structured Kubernetes-compatible yaml file and applies
-// its objects to a target Kubernetes cluster
+// its objects to a target Kubernetes cluster, namespaced objects without a
+// namespace are applied to namespace
 func (kcl KubernetesClient) ApplyObjects(namespace string, yamlData [][]byte) error {
 	log.Info().Msgf("applying objects against kubernetes cluster")
 
@@ -66,7 +67,10 @@ func (kcl KubernetesClient) ApplyObjects(namespace string, yamlData [][]byte) er
 		// REST interface for the GVR
 		var dr dynamic.ResourceInterface
 		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
-			// namespaced resources should specify the namespace
+			// namespaced resources without a namespace go to the given one
+			if obj.GetNamespace() == "" {
+				obj.SetNamespace(namespace)
+			}
 			dr = dyn.Resource(mapping.Resource).Namespace(obj.GetNamespace())
 		} else {
 			// for cluster-wide resources
@@ -128,6 +132,11 @@ func (kcl KubernetesClient) ReadYAMLFile(filepath string) (string, error) {
 
 // SplitYAMLFile takes a separated (---) yaml doc and returns [][]byte
 func (kcl KubernetesClient) SplitYAMLFile(yamlData *bytes.Buffer) ([][]byte, error) {
+	return SplitYAML(yamlData)
+}
+
+// SplitYAML takes a separated (---) yaml doc and returns [][]byte
+func SplitYAML(yamlData *bytes.Buffer) ([][]byte, error) {
 	dec := goyaml.NewDecoder(bytes.NewReader(yamlData.Bytes()))
 
 	var res [][]byte
@@ -8,6 +8,7 @@ package k8s
 
 import (
 	// b64 "encoding/base64"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -17,6 +18,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/client-go/util/homedir"
 )
 
@@ -83,6 +85,64 @@ func CreateKubeConfig(inCluster bool, kubeConfigPath string) *KubernetesClient {
 	}
 }
 
+// SetKubeconfigContextName renames the current context of the kubeconfig at
+// kubeConfigPath, together with the cluster and user entries it references,
+// so that multiple clusters can be merged into one kubeconfig without their
+// names colliding
+func SetKubeconfigContextName(kubeConfigPath string, contextName string) error {
+	config, err := clientcmd.LoadFromFile(kubeConfigPath)
+	if err != nil {
+		return fmt.Errorf("error loading kubeconfig %s: %s", kubeConfigPath, err)
+	}
+
+	currentContext := config.CurrentContext
+	if currentContext == contextName {
+		return nil
+	}
+
+	context, ok := config.Contexts[currentContext]
+	if !ok {
+		return fmt.Errorf("current context %q not found in kubeconfig %s", currentContext, kubeConfigPath)
+	}
+
+	// entries shared with another context are copied instead of moved
+	shared := func(name string, references func(*clientcmdapi.Context) string) bool {
+		for otherName, other := range config.Contexts {
+			if otherName != currentContext && references(other) == name {
+				return true
+			}
+		}
+		return false
+	}
+	if cluster, ok := config.Clusters[context.Cluster]; ok && context.Cluster != contextName {
+		if !shared(context.Cluster, func(c *clientcmdapi.Context) string { return c.Cluster }) {
+			delete(config.Clusters, context.Cluster)
+		}
+		config.Clusters[contextName] = cluster
+		context.Cluster = contextName
+	}
+	if user, ok := config.AuthInfos[context.AuthInfo]; ok && context.AuthInfo != contextName {
+		if !shared(context.AuthInfo, func(c *clientcmdapi.Context) string { return c.AuthInfo }) {
+			delete(config.AuthInfos, context.AuthInfo)
+		}
+		config.AuthInfos[contextName] = user
+		context.AuthInfo = contextName
+	}
+
+	delete(config.Contexts, currentContext)
+	config.Contexts[contextName] = context
+	config.CurrentContext = contextName
+
+	err = clientcmd.WriteToFile(*config, kubeConfigPath)
+	if err != nil {
+		return fmt.Errorf("error writing kubeconfig %s: %s", kubeConfigPath, err)
+	}
+
+	log.Infof("renamed kubeconfig context %s to %s", currentContext, contextName)
+
+	return nil
+}
+
 // returnKubeConfigPath generates the path in the filesystem to kubeconfig
 func returnKubeConfigPath(kubeConfigPath string) string {
 	var kubeconfig string
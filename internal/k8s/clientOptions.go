@@ -0,0 +1,108 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package k8s
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ClientOptions tune how a kubernetes client connects to its api server,
+// e.g. to present a client certificate and trust a private certificate
+// authority. Zero values keep the in-cluster or kubeconfig settings
+type ClientOptions struct {
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	QPS      float32
+	Burst    int
+	Timeout  time.Duration
+}
+
+// IsZero reports whether no option is set
+func (o ClientOptions) IsZero() bool {
+	return o == ClientOptions{}
+}
+
+// Validate checks the certificate files exist and the client certificate
+// and key are set together
+func (o ClientOptions) Validate() error {
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return fmt.Errorf("a client certificate and key have to be set together")
+	}
+	for _, file := range []string{o.CAFile, o.CertFile, o.KeyFile} {
+		if file == "" {
+			continue
+		}
+		_, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %s", file, err)
+		}
+	}
+	if o.QPS < 0 || o.Burst < 0 || o.Timeout < 0 {
+		return fmt.Errorf("qps, burst and timeout can't be negative")
+	}
+
+	return nil
+}
+
+// CreateKubeConfigWithOptions is CreateKubeConfig with the connection options
+// applied to the rest config, errors are returned instead of logged
+func CreateKubeConfigWithOptions(inCluster bool, kubeConfigPath string, opts ClientOptions) (*KubernetesClient, error) {
+	err := opts.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var config *rest.Config
+	kubeconfig := "in-cluster"
+	if inCluster {
+		config, err = rest.InClusterConfig()
+	} else {
+		kubeconfig = returnKubeConfigPath(kubeConfigPath)
+		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating kubernetes config: %s", err)
+	}
+
+	if opts.CAFile != "" {
+		config.TLSClientConfig.CAFile = opts.CAFile
+		config.TLSClientConfig.CAData = nil
+	}
+	if opts.CertFile != "" {
+		config.TLSClientConfig.CertFile = opts.CertFile
+		config.TLSClientConfig.CertData = nil
+		config.TLSClientConfig.KeyFile = opts.KeyFile
+		config.TLSClientConfig.KeyData = nil
+	}
+	if opts.QPS != 0 {
+		config.QPS = opts.QPS
+	}
+	if opts.Burst != 0 {
+		config.Burst = opts.Burst
+	}
+	if opts.Timeout != 0 {
+		config.Timeout = opts.Timeout
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating kubernetes client: %s", err)
+	}
+
+	return &KubernetesClient{
+		Clientset:      clientset,
+		RestConfig:     config,
+		KubeConfigPath: kubeconfig,
+	}, nil
+}
@@ -0,0 +1,56 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package k8s
+
+import (
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestSetKubeconfigContextName(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["lke1234"] = &clientcmdapi.Cluster{Server: "https://lke1234.example.com"}
+	config.Clusters["shared"] = &clientcmdapi.Cluster{Server: "https://shared.example.com"}
+	config.AuthInfos["lke1234-admin"] = &clientcmdapi.AuthInfo{Token: "token"}
+	config.AuthInfos["shared-admin"] = &clientcmdapi.AuthInfo{Token: "shared-token"}
+	config.Contexts["lke1234-ctx"] = &clientcmdapi.Context{Cluster: "lke1234", AuthInfo: "shared-admin"}
+	config.Contexts["other-ctx"] = &clientcmdapi.Context{Cluster: "shared", AuthInfo: "shared-admin"}
+	config.CurrentContext = "lke1234-ctx"
+
+	kubeconfigPath := filepath.Join(t.TempDir(), "kubeconfig")
+	err := clientcmd.WriteToFile(*config, kubeconfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SetKubeconfigContextName(kubeconfigPath, "kf-dev")
+	if err != nil {
+		t.Fatalf("SetKubeconfigContextName() = %s", err)
+	}
+
+	renamed, err := clientcmd.LoadFromFile(kubeconfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	context, ok := renamed.Contexts["kf-dev"]
+	if renamed.CurrentContext != "kf-dev" || !ok || renamed.Contexts["lke1234-ctx"] != nil {
+		t.Fatalf("contexts = %v with current %s, want kf-dev to replace lke1234-ctx", renamed.Contexts, renamed.CurrentContext)
+	}
+	if context.Cluster != "kf-dev" || renamed.Clusters["kf-dev"] == nil || renamed.Clusters["lke1234"] != nil {
+		t.Errorf("cluster entries = %v referenced as %s, want lke1234 renamed to kf-dev", renamed.Clusters, context.Cluster)
+	}
+	// the user is also used by other-ctx, so it's copied
+	if context.AuthInfo != "kf-dev" || renamed.AuthInfos["kf-dev"] == nil || renamed.AuthInfos["shared-admin"] == nil {
+		t.Errorf("user entries = %v referenced as %s, want shared-admin copied to kf-dev", renamed.AuthInfos, context.AuthInfo)
+	}
+	if renamed.Contexts["other-ctx"].AuthInfo != "shared-admin" || renamed.Clusters["shared"] == nil {
+		t.Errorf("other-ctx = %+v, want it unchanged", renamed.Contexts["other-ctx"])
+	}
+}
@@ -18,6 +18,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/remotecommand"
@@ -499,3 +500,39 @@ func watchForStatefulSetPodReady(clientset *kubernetes.Clientset, namespace stri
 		}
 	}
 }
+
+// RestartStatefulSet rolls the Pods of a StatefulSet the way kubectl rollout
+// restart does and waits for every replica to be updated and ready
+func RestartStatefulSet(clientset *kubernetes.Clientset, namespace string, statefulSetName string, timeoutSeconds int) error {
+	patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"kubectl.kubernetes.io/restartedAt":"%s"}}}}}`, time.Now().UTC().Format(time.RFC3339))
+	statefulset, err := clientset.AppsV1().StatefulSets(namespace).Patch(context.Background(), statefulSetName, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("error restarting StatefulSet %s/%s: %s", namespace, statefulSetName, err)
+	}
+	log.Info().Msgf("restarting %s StatefulSet - this could take up to %v seconds", statefulSetName, timeoutSeconds)
+
+	deadline := time.Now().Add(time.Duration(timeoutSeconds) * time.Second)
+	for {
+		current, err := clientset.AppsV1().StatefulSets(namespace).Get(context.Background(), statefulSetName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("error getting StatefulSet %s/%s: %s", namespace, statefulSetName, err)
+		}
+
+		replicas := int32(1)
+		if current.Spec.Replicas != nil {
+			replicas = *current.Spec.Replicas
+		}
+		if current.Status.ObservedGeneration >= statefulset.Generation &&
+			current.Status.UpdateRevision == current.Status.CurrentRevision &&
+			current.Status.UpdatedReplicas == replicas &&
+			current.Status.ReadyReplicas == replicas {
+			log.Info().Msgf("StatefulSet %s/%s restarted", namespace, statefulSetName)
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the operation timed out while waiting for StatefulSet %s/%s to restart", namespace, statefulSetName)
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
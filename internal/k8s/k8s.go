@@ -2,6 +2,7 @@ package k8s
 
 import (
 	"context"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -56,3 +57,47 @@ func UpdateSecretV2(clientset *kubernetes.Clientset, namespace string, secretNam
 	log.Info().Msgf("updated Secret %s in Namespace %s\n", currentSecret.Name, currentSecret.Namespace)
 	return nil
 }
+
+// UpdateSecretLabelsV2 replaces the labels under labelPrefix on a Kubernetes
+// Secret with labels, leaving other labels untouched
+func UpdateSecretLabelsV2(clientset *kubernetes.Clientset, namespace string, secretName string, labelPrefix string, labels map[string]string) error {
+	currentSecret, err := clientset.CoreV1().Secrets(namespace).Get(context.Background(), secretName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if currentSecret.Labels == nil {
+		currentSecret.Labels = map[string]string{}
+	}
+	for key := range currentSecret.Labels {
+		if strings.HasPrefix(key, labelPrefix) {
+			delete(currentSecret.Labels, key)
+		}
+	}
+	for key, value := range labels {
+		currentSecret.Labels[key] = value
+	}
+
+	_, err = clientset.CoreV1().Secrets(namespace).Update(context.Background(), currentSecret, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListSecretNamesV2 returns the names of the Secrets in namespace matching
+// labelSelector
+func ListSecretNamesV2(clientset *kubernetes.Clientset, namespace string, labelSelector string) ([]string, error) {
+	secrets, err := clientset.CoreV1().Secrets(namespace).List(context.Background(), metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(secrets.Items))
+	for _, secret := range secrets.Items {
+		names = append(names, secret.Name)
+	}
+
+	return names, nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Operations are the cluster operations the controller's steps run against
+// the provisioned cluster, KubernetesClient implements them and tests can
+// substitute a fake
+type Operations interface {
+	// WaitForDeployment finds the deployment labelled matchLabel=matchLabelValue
+	// in namespace and waits for it to become ready
+	WaitForDeployment(matchLabel string, matchLabelValue string, namespace string, timeoutSeconds int) error
+	// OpenPortForward forwards localPort to podPort of the pod until
+	// stopChannel is closed
+	OpenPortForward(podName string, namespace string, podPort int, localPort int, stopChannel chan struct{})
+	// ApplyObjects applies the yaml documents
+	ApplyObjects(namespace string, yamlData [][]byte) error
+	// NodeStatuses returns the labels and readiness of every node
+	NodeStatuses() ([]NodeStatus, error)
+}
+
+// NodeStatus is the readiness of a node
+type NodeStatus struct {
+	Name   string
+	Labels map[string]string
+	Ready  bool
+}
+
+func (kcl KubernetesClient) WaitForDeployment(matchLabel string, matchLabelValue string, namespace string, timeoutSeconds int) error {
+	deployment, err := ReturnDeploymentObject(kcl.Clientset, matchLabel, matchLabelValue, namespace, timeoutSeconds)
+	if err != nil {
+		return fmt.Errorf("error finding deployment %s=%s in namespace %s: %s", matchLabel, matchLabelValue, namespace, err)
+	}
+
+	_, err = WaitForDeploymentReady(kcl.Clientset, deployment, timeoutSeconds)
+	if err != nil {
+		return fmt.Errorf("error waiting for deployment %s in namespace %s: %s", deployment.Name, namespace, err)
+	}
+
+	return nil
+}
+
+func (kcl KubernetesClient) OpenPortForward(podName string, namespace string, podPort int, localPort int, stopChannel chan struct{}) {
+	OpenPortForwardPodWrapper(kcl.Clientset, kcl.RestConfig, podName, namespace, podPort, localPort, stopChannel)
+}
+
+func (kcl KubernetesClient) NodeStatuses() ([]NodeStatus, error) {
+	nodes, err := kcl.Clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing nodes: %s", err)
+	}
+
+	statuses := make([]NodeStatus, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		status := NodeStatus{Name: node.Name, Labels: node.Labels}
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == v1.NodeReady {
+				status.Ready = condition.Status == v1.ConditionTrue
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
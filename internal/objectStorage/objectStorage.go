@@ -7,6 +7,7 @@ See the LICENSE file for more details.
 package objectStorage
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -93,6 +94,56 @@ func PutClusterObject(cr *pkgtypes.StateStoreCredentials, d *pkgtypes.StateStore
 	return nil
 }
 
+// ProbeBucket writes, reads back and deletes an object in an s3 compatible
+// bucket to confirm the credentials can store state in it
+func ProbeBucket(cr *pkgtypes.StateStoreCredentials, endpoint string, bucketName string, key string) error {
+	ctx := context.Background()
+
+	// Initialize minio client
+	minioClient, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cr.AccessKeyID, cr.SecretAccessKey, cr.SessionToken),
+		Secure: true,
+	})
+	if err != nil {
+		return fmt.Errorf("error initializing minio client: %s", err)
+	}
+
+	log.Info().Msgf("probing bucket %s at %s", bucketName, endpoint)
+	body := []byte(key)
+	_, err = minioClient.PutObject(ctx, bucketName, key, bytes.NewReader(body), int64(len(body)), minio.PutObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("error writing to bucket %s at %s: %s", bucketName, endpoint, err)
+	}
+
+	readErr := func() error {
+		reader, err := minioClient.GetObject(ctx, bucketName, key, minio.GetObjectOptions{})
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		got, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(got, body) {
+			return fmt.Errorf("the object read back differs from the one written")
+		}
+		return nil
+	}()
+
+	// the probe object is removed even when it couldn't be read back
+	err = minioClient.RemoveObject(ctx, bucketName, key, minio.RemoveObjectOptions{})
+	if readErr != nil {
+		return fmt.Errorf("error reading from bucket %s at %s: %s", bucketName, endpoint, readErr)
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting from bucket %s at %s: %s", bucketName, endpoint, err)
+	}
+
+	return nil
+}
+
 // GetClusterObject imports a cluster definition as json
 func GetClusterObject(cr *pkgtypes.StateStoreCredentials, d *pkgtypes.StateStoreDetails, localFilePath string, remoteFilePath string, secure bool) error {
 	ctx := context.Background()
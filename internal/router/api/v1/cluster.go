@@ -8,17 +8,21 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	civoruntime "github.com/kubefirst/kubefirst-api/internal/civo"
 	"github.com/kubefirst/kubefirst-api/internal/constants"
+	"github.com/kubefirst/kubefirst-api/internal/controller"
 	digioceanruntime "github.com/kubefirst/kubefirst-api/internal/digitalocean"
 	"github.com/kubefirst/kubefirst-api/internal/env"
 	environments "github.com/kubefirst/kubefirst-api/internal/environments"
 	"github.com/kubefirst/kubefirst-api/internal/gitShim"
+	"github.com/kubefirst/kubefirst-api/internal/github"
 	"github.com/kubefirst/kubefirst-api/internal/k8s"
 	"github.com/kubefirst/kubefirst-api/internal/secrets"
 	"github.com/kubefirst/kubefirst-api/internal/services"
@@ -50,6 +54,10 @@ import (
 // @Param Authorization header string true "API key" default(Bearer <API key>)
 // DeleteCluster handles a request to delete a cluster
 func DeleteCluster(c *gin.Context) {
+	if rejectReadOnly(c) {
+		return
+	}
+
 	clusterName, param := c.Params.Get("cluster_name")
 	if !param {
 		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
@@ -58,7 +66,15 @@ func DeleteCluster(c *gin.Context) {
 		return
 	}
 
-	kcfg := utils.GetKubernetesClient(clusterName)
+	kcfg, ok := clusterStoreClient(c, clusterName)
+	if !ok {
+		return
+	}
+
+	// a queued create must not provision the cluster once it's deleted
+	if controller.LeaveProvisionQueue(clusterName, "the cluster was deleted while it was queued for provisioning") {
+		log.Info().Msgf("removed cluster %s from the provision queue", clusterName)
+	}
 
 	// Delete cluster
 	rec, err := secrets.GetCluster(kcfg.Clientset, clusterName)
@@ -69,6 +85,16 @@ func DeleteCluster(c *gin.Context) {
 		return
 	}
 
+	// git terraform is destroyed with the stored credentials, a GitHub App
+	// installation token may have expired since the cluster was created
+	_, err = github.RefreshGitAuth(&rec.GitAuth)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: err.Error(),
+		})
+		return
+	}
+
 	env, _ := env.GetEnv(constants.SilenceGetEnv)
 
 	telemetryEvent := telemetry.TelemetryEvent{
@@ -103,63 +129,38 @@ func DeleteCluster(c *gin.Context) {
 		}
 	}
 
+	var deleteCluster func(*pkgtypes.Cluster, telemetry.TelemetryEvent) error
 	switch rec.CloudProvider {
 	case "aws":
-		go func() {
-			err := aws.DeleteAWSCluster(&rec, telemetryEvent)
-			if err != nil {
-				log.Error().Msgf(err.Error())
-			}
-		}()
-
-		c.JSON(http.StatusAccepted, types.JSONSuccessResponse{
-			Message: "cluster delete enqueued",
-		})
+		deleteCluster = aws.DeleteAWSCluster
 	case "civo":
-		go func() {
-			err := civo.DeleteCivoCluster(&rec, telemetryEvent)
-			if err != nil {
-				log.Error().Msgf(err.Error())
-			}
-		}()
-
-		c.JSON(http.StatusAccepted, types.JSONSuccessResponse{
-			Message: "cluster delete enqueued",
-		})
+		deleteCluster = civo.DeleteCivoCluster
 	case "digitalocean":
-		go func() {
-			err := digitalocean.DeleteDigitaloceanCluster(&rec, telemetryEvent)
-			if err != nil {
-				log.Error().Msgf(err.Error())
-			}
-		}()
-
-		c.JSON(http.StatusAccepted, types.JSONSuccessResponse{
-			Message: "cluster delete enqueued",
-		})
+		deleteCluster = digitalocean.DeleteDigitaloceanCluster
 	case "vultr":
-		go func() {
-			err := vultr.DeleteVultrCluster(&rec, telemetryEvent)
-			if err != nil {
-				log.Error().Msgf(err.Error())
-			}
-		}()
-
-		c.JSON(http.StatusAccepted, types.JSONSuccessResponse{
-			Message: "cluster delete enqueued",
-		})
+		deleteCluster = vultr.DeleteVultrCluster
 	case "google":
-		go func() {
-			err := google.DeleteGoogleCluster(&rec, telemetryEvent)
-			if err != nil {
-				log.Error().Msgf(err.Error())
-			}
-		}()
+		deleteCluster = google.DeleteGoogleCluster
+	default:
+		return
+	}
 
-		c.JSON(http.StatusAccepted, types.JSONSuccessResponse{
-			Message: "cluster delete enqueued",
+	// the delete is tracked as an operation so its completion is recorded
+	// and posted to the webhook like other day-2 operations
+	clctrl := &controller.ClusterController{ClusterName: clusterName, CloudProvider: rec.CloudProvider, KubernetesClient: kcfg.Clientset}
+	operation, err := clctrl.SubmitOperation(controller.OperationClusterDelete, func() (interface{}, error) {
+		return nil, deleteCluster(&rec, telemetryEvent)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: err.Error(),
 		})
+		return
 	}
+
+	c.JSON(http.StatusAccepted, types.JSONSuccessResponse{
+		Message: fmt.Sprintf("cluster delete enqueued as operation %s", operation.ID),
+	})
 }
 
 // GetCluster godoc
@@ -183,7 +184,10 @@ func GetCluster(c *gin.Context) {
 		return
 	}
 
-	kcfg := utils.GetKubernetesClient(clusterName)
+	kcfg, ok := clusterStoreClient(c, clusterName)
+	if !ok {
+		return
+	}
 
 	// Retrieve cluster info
 	cluster, err := secrets.GetCluster(kcfg.Clientset, clusterName)
@@ -203,16 +207,32 @@ func GetCluster(c *gin.Context) {
 // @Tags cluster
 // @Accept json
 // @Produce json
+// @Param	tag	query	[]string	false	"Only return clusters with this tag, as key=value - repeat to require several tags"
 // @Success 200 {object} []pkgtypes.Cluster
 // @Failure 400 {object} types.JSONFailureResponse
 // @Router /cluster [get]
 // @Param Authorization header string true "API key" default(Bearer <API key>)
 // GetClusters returns all known configured clusters
 func GetClusters(c *gin.Context) {
-	kcfg := utils.GetKubernetesClient("TODO: SECRETS")
+	tags := map[string]string{}
+	for _, tag := range c.QueryArray("tag") {
+		key, value, found := strings.Cut(tag, "=")
+		if !found || key == "" {
+			c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+				Message: fmt.Sprintf("invalid tag filter %q, expected key=value", tag),
+			})
+			return
+		}
+		tags[key] = value
+	}
+
+	kcfg, ok := clusterStoreClient(c, "TODO: SECRETS")
+	if !ok {
+		return
+	}
 
 	// Retrieve all clusters info
-	allClusters, err := secrets.GetClusters(kcfg.Clientset)
+	allClusters, err := secrets.GetClustersByTags(kcfg.Clientset, tags)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
 			Message: err.Error(),
@@ -223,6 +243,90 @@ func GetClusters(c *gin.Context) {
 	c.JSON(http.StatusOK, allClusters)
 }
 
+// GetClusterGroup godoc
+// @Summary Return the clusters of a multi-region cluster group
+// @Description Return the clusters of a multi-region cluster group along with their aggregated status
+// @Tags cluster
+// @Accept json
+// @Produce json
+// @Param	group_name	path	string	true	"Cluster group name"
+// @Success 200 {object} pkgtypes.ClusterGroup
+// @Failure 400 {object} types.JSONFailureResponse
+// @Router /cluster-group/:group_name [get]
+// @Param Authorization header string true "API key" default(Bearer <API key>)
+// GetClusterGroup returns a cluster group and its aggregated status
+func GetClusterGroup(c *gin.Context) {
+	groupName, param := c.Params.Get("group_name")
+	if !param {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: ":group_name not provided",
+		})
+		return
+	}
+
+	kcfg, ok := clusterStoreClient(c, groupName)
+	if !ok {
+		return
+	}
+
+	clusterGroup, err := secrets.GetClusterGroup(kcfg.Clientset, groupName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, clusterGroup)
+}
+
+// GetFleetMetrics godoc
+// @Summary Return statistics across all clusters
+// @Description Return cluster counts by status, cloud provider and region, the total node count, the oldest cluster and the install success rate of the clusters created in a time range, the last 30 days by default
+// @Tags cluster
+// @Accept json
+// @Produce json
+// @Param	from	query	string	false	"Start of the time range (RFC 3339)"
+// @Param	to	query	string	false	"End of the time range (RFC 3339)"
+// @Success 200 {object} pkgtypes.FleetMetrics
+// @Failure 400 {object} types.JSONFailureResponse
+// @Router /fleet-metrics [get]
+// @Param Authorization header string true "API key" default(Bearer <API key>)
+// GetFleetMetrics returns statistics across all clusters
+func GetFleetMetrics(c *gin.Context) {
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -30)
+	for param, value := range map[string]*time.Time{"from": &from, "to": &to} {
+		s := c.Query(param)
+		if s == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+				Message: fmt.Sprintf("invalid %s %q, expected an RFC 3339 time", param, s),
+			})
+			return
+		}
+		*value = parsed
+	}
+
+	kcfg, ok := clusterStoreClient(c, "")
+	if !ok {
+		return
+	}
+
+	metrics, err := secrets.GetFleetMetrics(kcfg.Clientset, from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, metrics)
+}
+
 // PostCreateCluster godoc
 // @Summary Create a Kubefirst cluster
 // @Description Create a Kubefirst cluster
@@ -237,6 +341,10 @@ func GetClusters(c *gin.Context) {
 // @Param Authorization header string true "API key" default(Bearer <API key>)
 // PostCreateCluster handles a request to create a cluster
 func PostCreateCluster(c *gin.Context) {
+	if rejectReadOnly(c) {
+		return
+	}
+
 	clusterName, param := c.Params.Get("cluster_name")
 	if !param || string(clusterName) == ":cluster_name" {
 		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
@@ -256,34 +364,37 @@ func PostCreateCluster(c *gin.Context) {
 	}
 	clusterDefinition.ClusterName = clusterName
 
-	kcfg := utils.GetKubernetesClient(clusterName)
+	err = controller.ValidateClusterGroup(clusterDefinition)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	kcfg, ok := clusterStoreClient(c, clusterName)
+	if !ok {
+		return
+	}
 
 	// Create
 	// If create is in progress, return error
-	// Retrieve cluster info
-	cluster, err := secrets.GetCluster(kcfg.Clientset, clusterName)
-	if err != nil {
-		log.Info().Msgf("cluster %s does not exist, continuing", clusterName)
-	} else {
-		if cluster.InProgress {
+	// Retrieve cluster info, every regional cluster of a group is checked
+	var cluster pkgtypes.Cluster
+	for _, definition := range controller.ClusterGroupDefinitions(clusterDefinition) {
+		existing, err := secrets.GetCluster(kcfg.Clientset, definition.ClusterName)
+		if err != nil {
+			log.Info().Msgf("cluster %s does not exist, continuing", definition.ClusterName)
+			continue
+		}
+		if existing.InProgress {
 			c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
-				Message: fmt.Sprintf("%s has an active process running and another create cannot be enqeued", clusterName),
+				Message: fmt.Sprintf("%s has an active process running and another create cannot be enqeued", definition.ClusterName),
 			})
 			return
 		}
-		if cluster.LastCondition != "" {
-			cluster.LastCondition = ""
-			err = secrets.UpdateCluster(kcfg.Clientset, cluster)
-			if err != nil {
-				log.Warn().Msgf("error updating cluster last_condition field: %s", err)
-			}
-		}
-		if cluster.Status == constants.ClusterStatusError {
-			cluster.Status = constants.ClusterStatusProvisioning
-			err = secrets.UpdateCluster(kcfg.Clientset, cluster)
-			if err != nil {
-				log.Warn().Msgf("error updating cluster status field: %s", err)
-			}
+		if cluster.ClusterName == "" {
+			cluster = existing
 		}
 	}
 
@@ -300,6 +411,39 @@ func PostCreateCluster(c *gin.Context) {
 		clusterDefinition.GitAuth = cluster.GitAuth
 	}
 
+	// the definition is checked before anything is enqueued, the checks that
+	// need the cloud or git provider run in the background
+	for _, definition := range controller.ClusterGroupDefinitions(clusterDefinition) {
+		err = controller.ValidateDefinition(&definition)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	for _, definition := range controller.ClusterGroupDefinitions(clusterDefinition) {
+		existing, err := secrets.GetCluster(kcfg.Clientset, definition.ClusterName)
+		if err != nil {
+			continue
+		}
+		if existing.LastCondition != "" {
+			existing.LastCondition = ""
+			err = secrets.UpdateCluster(kcfg.Clientset, existing)
+			if err != nil {
+				log.Warn().Msgf("error updating cluster last_condition field: %s", err)
+			}
+		}
+		if existing.Status == constants.ClusterStatusError {
+			existing.Status = constants.ClusterStatusProvisioning
+			err = secrets.UpdateCluster(kcfg.Clientset, existing)
+			if err != nil {
+				log.Warn().Msgf("error updating cluster status field: %s", err)
+			}
+		}
+	}
+
 	// Determine authentication type
 	useSecretForAuth := false
 	k1AuthSecret := map[string]string{}
@@ -349,12 +493,9 @@ func PostCreateCluster(c *gin.Context) {
 				return
 			}
 		}
-		go func() {
-			err = akamai.CreateAkamaiCluster(&clusterDefinition)
-			if err != nil {
-				log.Error().Msgf(err.Error())
-			}
-		}()
+		for _, definition := range controller.ClusterGroupDefinitions(clusterDefinition) {
+			go createCluster(akamai.CreateAkamaiCluster, definition)
+		}
 
 		c.JSON(http.StatusAccepted, types.JSONSuccessResponse{
 			Message: "cluster create enqueued",
@@ -383,12 +524,9 @@ func PostCreateCluster(c *gin.Context) {
 				return
 			}
 		}
-		go func() {
-			err = aws.CreateAWSCluster(&clusterDefinition)
-			if err != nil {
-				log.Error().Msgf(err.Error())
-			}
-		}()
+		for _, definition := range controller.ClusterGroupDefinitions(clusterDefinition) {
+			go createCluster(aws.CreateAWSCluster, definition)
+		}
 
 		c.JSON(http.StatusAccepted, types.JSONSuccessResponse{
 			Message: "cluster create enqueued",
@@ -413,12 +551,9 @@ func PostCreateCluster(c *gin.Context) {
 				return
 			}
 		}
-		go func() {
-			err = civo.CreateCivoCluster(&clusterDefinition)
-			if err != nil {
-				log.Error().Msgf(err.Error())
-			}
-		}()
+		for _, definition := range controller.ClusterGroupDefinitions(clusterDefinition) {
+			go createCluster(civo.CreateCivoCluster, definition)
+		}
 
 		c.JSON(http.StatusAccepted, types.JSONSuccessResponse{
 			Message: "cluster create enqueued",
@@ -447,12 +582,9 @@ func PostCreateCluster(c *gin.Context) {
 				return
 			}
 		}
-		go func() {
-			err = digitalocean.CreateDigitaloceanCluster(&clusterDefinition)
-			if err != nil {
-				log.Error().Msgf(err.Error())
-			}
-		}()
+		for _, definition := range controller.ClusterGroupDefinitions(clusterDefinition) {
+			go createCluster(digitalocean.CreateDigitaloceanCluster, definition)
+		}
 
 		c.JSON(http.StatusAccepted, types.JSONSuccessResponse{
 			Message: "cluster create enqueued",
@@ -477,12 +609,9 @@ func PostCreateCluster(c *gin.Context) {
 				return
 			}
 		}
-		go func() {
-			err = vultr.CreateVultrCluster(&clusterDefinition)
-			if err != nil {
-				log.Error().Msgf(err.Error())
-			}
-		}()
+		for _, definition := range controller.ClusterGroupDefinitions(clusterDefinition) {
+			go createCluster(vultr.CreateVultrCluster, definition)
+		}
 
 		c.JSON(http.StatusAccepted, types.JSONSuccessResponse{
 			Message: "cluster create enqueued",
@@ -508,12 +637,9 @@ func PostCreateCluster(c *gin.Context) {
 				return
 			}
 		}
-		go func() {
-			err = google.CreateGoogleCluster(&clusterDefinition)
-			if err != nil {
-				log.Error().Msgf(err.Error())
-			}
-		}()
+		for _, definition := range controller.ClusterGroupDefinitions(clusterDefinition) {
+			go createCluster(google.CreateGoogleCluster, definition)
+		}
 
 		c.JSON(http.StatusAccepted, types.JSONSuccessResponse{
 			Message: "cluster create enqueued",
@@ -552,12 +678,9 @@ func PostCreateCluster(c *gin.Context) {
 				return
 			}
 		}
-		go func() {
-			err = k3s.CreateK3sCluster(&clusterDefinition)
-			if err != nil {
-				log.Fatal().Msg(err.Error())
-			}
-		}()
+		for _, definition := range controller.ClusterGroupDefinitions(clusterDefinition) {
+			go createCluster(k3s.CreateK3sCluster, definition)
+		}
 
 		c.JSON(http.StatusAccepted, types.JSONSuccessResponse{
 			Message: "cluster create enqueued",
@@ -565,6 +688,41 @@ func PostCreateCluster(c *gin.Context) {
 	}
 }
 
+// createCluster runs a provider's cluster create, a failure is logged and
+// recorded on the cluster so the other clusters of a group keep going
+func createCluster(create func(*pkgtypes.ClusterDefinition, controller.Logger) error, definition pkgtypes.ClusterDefinition) {
+	err := create(&definition, nil)
+	if err == nil {
+		return
+	}
+	log.Error().Msgf("error creating cluster %s: %s", definition.ClusterName, err)
+	// the record already says why the cluster left the queue or that it
+	// paused at its breakpoint
+	if errors.Is(err, controller.ErrLeftProvisionQueue) || errors.Is(err, controller.ErrPaused) {
+		return
+	}
+
+	// failures before the cluster record exists are only logged
+	kcfg, getErr := utils.GetClusterStoreClient(definition.ClusterName)
+	if getErr != nil {
+		log.Warn().Msgf("error recording the create failure of cluster %s: %s", definition.ClusterName, getErr)
+		return
+	}
+	cluster, getErr := secrets.GetCluster(kcfg.Clientset, definition.ClusterName)
+	if getErr != nil || cluster.Status == constants.ClusterStatusDeleted {
+		return
+	}
+	if cluster.LastCondition == "" {
+		cluster.LastCondition = err.Error()
+	}
+	cluster.Status = constants.ClusterStatusError
+	cluster.InProgress = false
+	updateErr := secrets.UpdateCluster(kcfg.Clientset, cluster)
+	if updateErr != nil {
+		log.Warn().Msgf("error recording the create failure of cluster %s: %s", definition.ClusterName, updateErr)
+	}
+}
+
 // PostExportCluster godoc
 // @Summary Export a Kubefirst cluster database entry
 // @Description Export a Kubefirst cluster database entry
@@ -586,7 +744,10 @@ func GetExportCluster(c *gin.Context) {
 		return
 	}
 
-	kcfg := utils.GetKubernetesClient(clusterName)
+	kcfg, ok := clusterStoreClient(c, clusterName)
+	if !ok {
+		return
+	}
 
 	// get cluster object
 	cluster, err := secrets.GetCluster(kcfg.Clientset, clusterName)
@@ -684,7 +845,7 @@ func GetClusterKubeConfig(c *gin.Context) {
 		}
 
 		civoConfig := civoruntime.CivoConfiguration{
-			Client:  civoruntime.NewCivo(kubeConfigRequest.CivoAuth.Token, kubeConfigRequest.CloudRegion),
+			Client:  civoruntime.NewCivo(kubeConfigRequest.CivoAuth.Token, kubeConfigRequest.CloudRegion, kubeConfigRequest.CivoAuth.APIURL),
 			Context: context.Background(),
 		}
 
@@ -702,7 +863,7 @@ func GetClusterKubeConfig(c *gin.Context) {
 
 	case "digitalocean":
 		digitaloceanConf := digioceanruntime.DigitaloceanConfiguration{
-			Client:  digioceanruntime.NewDigitalocean(kubeConfigRequest.DigitaloceanAuth.Token),
+			Client:  digioceanruntime.NewDigitalocean(kubeConfigRequest.DigitaloceanAuth.Token, kubeConfigRequest.DigitaloceanAuth.APIURL),
 			Context: context.Background(),
 		}
 
@@ -721,7 +882,7 @@ func GetClusterKubeConfig(c *gin.Context) {
 	case "vultr":
 
 		vultrConf := vultrruntime.VultrConfiguration{
-			Client:  vultrruntime.NewVultr(kubeConfigRequest.VultrAuth.Token),
+			Client:  vultrruntime.NewVultr(kubeConfigRequest.VultrAuth.Token, kubeConfigRequest.VultrAuth.APIURL),
 			Context: context.Background(),
 		}
 
@@ -759,6 +920,10 @@ func GetClusterKubeConfig(c *gin.Context) {
 // @Param Authorization header string true "API key" default(Bearer <API key>)
 // PostImportCluster handles a request to import a cluster
 func PostImportCluster(c *gin.Context) {
+	if rejectReadOnly(c) {
+		return
+	}
+
 	// Bind to variable as application/json, handle error
 	var cluster pkgtypes.Cluster
 	err := c.Bind(&cluster)
@@ -769,7 +934,10 @@ func PostImportCluster(c *gin.Context) {
 		return
 	}
 
-	kcfg := utils.GetKubernetesClient(cluster.ClusterName)
+	kcfg, ok := clusterStoreClient(c, cluster.ClusterName)
+	if !ok {
+		return
+	}
 
 	// Insert the cluster into the target database
 	err = secrets.InsertCluster(kcfg.Clientset, cluster)
@@ -782,7 +950,7 @@ func PostImportCluster(c *gin.Context) {
 
 	// Create default service entries
 	log.Info().Msg("Adding default services")
-	err = services.AddDefaultServices(&cluster)
+	_, err = services.EnsureDefaultServices(&cluster)
 	if err != nil {
 		log.Error().Msgf("error adding default service entries for cluster %s: %s", cluster.ClusterName, err)
 	}
@@ -827,6 +995,10 @@ func PostImportCluster(c *gin.Context) {
 // @Param Authorization header string true "API key" default(Bearer <API key>)
 // PostResetClusterProgress removes a cluster progress marker from a cluster entry
 func PostResetClusterProgress(c *gin.Context) {
+	if rejectReadOnly(c) {
+		return
+	}
+
 	clusterName, param := c.Params.Get("cluster_name")
 	if !param {
 		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
@@ -835,7 +1007,10 @@ func PostResetClusterProgress(c *gin.Context) {
 		return
 	}
 
-	kcfg := utils.GetKubernetesClient(clusterName)
+	kcfg, ok := clusterStoreClient(c, clusterName)
+	if !ok {
+		return
+	}
 	// Get Cluster
 
 	cluster, _ := secrets.GetCluster(kcfg.Clientset, clusterName)
@@ -867,6 +1042,10 @@ func PostResetClusterProgress(c *gin.Context) {
 // @Param Authorization header string true "API key" default(Bearer <API key>)
 // PostCreateVcluster handles a request to create default virtual cluster for the mgmt cluster
 func PostCreateVcluster(c *gin.Context) {
+	if rejectReadOnly(c) {
+		return
+	}
+
 	clusterName, param := c.Params.Get("cluster_name")
 	if !param {
 		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
@@ -875,7 +1054,10 @@ func PostCreateVcluster(c *gin.Context) {
 		return
 	}
 
-	kcfg := utils.GetKubernetesClient(clusterName)
+	kcfg, ok := clusterStoreClient(c, clusterName)
+	if !ok {
+		return
+	}
 
 	cluster, err := secrets.GetCluster(kcfg.Clientset, clusterName)
 	if err != nil {
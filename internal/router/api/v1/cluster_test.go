@@ -0,0 +1,64 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package api
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubefirst/kubefirst-api/internal/constants"
+	"github.com/kubefirst/kubefirst-api/internal/controller"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestCreateClusterRecordsFailure(t *testing.T) {
+	store, err := secrets.NewMemoryStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets.SetStore(store)
+	defer secrets.SetStore(nil)
+
+	// the records are in the memory store, the client is never used
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	err = os.WriteFile(kubeconfig, []byte("apiVersion: v1\nkind: Config\nclusters:\n- name: local\n  cluster:\n    server: https://127.0.0.1:1\ncontexts:\n- name: local\n  context:\n    cluster: local\ncurrent-context: local\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("K1_LOCAL_DEBUG", "true")
+	t.Setenv("K1_LOCAL_KUBECONFIG_PATH", kubeconfig)
+
+	for name, test := range map[string]struct {
+		status     string
+		err        error
+		wantStatus string
+	}{
+		"failure":              {constants.ClusterStatusProvisioning, errors.New("boom"), constants.ClusterStatusError},
+		"paused":               {constants.ClusterStatusPaused, fmt.Errorf("GitInit: %w", controller.ErrPaused), constants.ClusterStatusPaused},
+		"left provision queue": {constants.ClusterStatusError, controller.ErrLeftProvisionQueue, constants.ClusterStatusError},
+	} {
+		clusterName := "kf-" + fmt.Sprint(len(name))
+		err := secrets.InsertCluster(nil, pkgtypes.Cluster{ClusterName: clusterName, Status: test.status, InProgress: test.status == constants.ClusterStatusProvisioning})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		createCluster(func(*pkgtypes.ClusterDefinition, controller.Logger) error { return test.err }, pkgtypes.ClusterDefinition{ClusterName: clusterName})
+
+		cl, err := secrets.GetCluster(nil, clusterName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cl.Status != test.wantStatus || cl.InProgress {
+			t.Errorf("%s: cluster status = %s (in progress %v), want %s", name, cl.Status, cl.InProgress, test.wantStatus)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubefirst/kubefirst-api/internal/controller"
+	"github.com/kubefirst/kubefirst-api/internal/types"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// PostEstimateClusterCost godoc
+// @Summary Estimate the monthly cost of a Kubefirst cluster definition
+// @Description Estimate the monthly cost of the nodes, load balancer, volumes and state store a cluster definition provisions
+// @Tags cluster
+// @Accept json
+// @Produce json
+// @Param	cluster_name	path	string	true	"Cluster name"
+// @Param	definition	body	types.ClusterDefinition	true	"Cluster create request in JSON format"
+// @Success 200 {object} pkgtypes.CostEstimate
+// @Failure 400 {object} types.JSONFailureResponse
+// @Router /cluster/:cluster_name/cost-estimate [post]
+// @Param Authorization header string true "API key" default(Bearer <API key>)
+// PostEstimateClusterCost estimates the monthly cost of a cluster definition
+func PostEstimateClusterCost(c *gin.Context) {
+	clusterName, param := c.Params.Get("cluster_name")
+	if !param || string(clusterName) == ":cluster_name" {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: ":cluster_name not provided",
+		})
+		return
+	}
+
+	var clusterDefinition pkgtypes.ClusterDefinition
+	err := c.Bind(&clusterDefinition)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: err.Error(),
+		})
+		return
+	}
+	clusterDefinition.ClusterName = clusterName
+
+	estimate, err := controller.EstimateCost(&clusterDefinition)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, estimate)
+}
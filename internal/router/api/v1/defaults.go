@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/kubefirst/kubefirst-api/pkg/constants"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
 )
 
 func GetCloudProviderDefaults(c *gin.Context) {
@@ -12,3 +13,15 @@ func GetCloudProviderDefaults(c *gin.Context) {
 
 	c.JSON(http.StatusOK, cloudDefaults)
 }
+
+// GetProvisionTimeoutDefaults returns the default provisioning timeouts of
+// every cloud provider, a definition's timeouts override them
+func GetProvisionTimeoutDefaults(c *gin.Context) {
+	c.JSON(http.StatusOK, constants.GetProvisionTimeouts())
+}
+
+// GetClusterDefinitionSchema returns the json schema of a cluster definition
+// so clients can render forms and validate input before creating a cluster
+func GetClusterDefinitionSchema(c *gin.Context) {
+	c.JSON(http.StatusOK, pkgtypes.ClusterDefinitionJSONSchema())
+}
@@ -34,7 +34,7 @@ func PostValidateDigitalOceanDomain(c *gin.Context) {
 	}
 
 	digitaloceanConf := digitalocean.DigitaloceanConfiguration{
-		Client:  digitalocean.NewDigitalocean(request.Token),
+		Client:  digitalocean.NewDigitalocean(request.Token, request.APIURL),
 		Context: context.Background(),
 	}
 
@@ -19,9 +19,9 @@ import (
 	"github.com/kubefirst/kubefirst-api/internal/digitalocean"
 	"github.com/kubefirst/kubefirst-api/internal/types"
 	"github.com/kubefirst/kubefirst-api/internal/vultr"
+	"github.com/kubefirst/kubefirst-api/pkg/akamai"
 	"github.com/kubefirst/kubefirst-api/pkg/google"
 	"github.com/linode/linodego"
-	"golang.org/x/oauth2"
 )
 
 // PostDomains godoc
@@ -59,15 +59,7 @@ func PostDomains(c *gin.Context) {
 
 	switch dnsProvider {
 	case "akamai":
-		tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: domainListRequest.AkamaiAuth.Token})
-
-		oauth2Client := &http.Client{
-			Transport: &oauth2.Transport{
-				Source: tokenSource,
-			},
-		}
-
-		client := linodego.NewClient(oauth2Client)
+		client := akamai.NewAkamai(domainListRequest.AkamaiAuth.Token, domainListRequest.AkamaiAuth.APIURL)
 
 		domains, err := client.ListDomains(context.Background(), &linodego.ListOptions{})
 		if err != nil {
@@ -99,6 +91,7 @@ func PostDomains(c *gin.Context) {
 				domainListRequest.AWSAuth.AccessKeyID,
 				domainListRequest.AWSAuth.SecretAccessKey,
 				domainListRequest.AWSAuth.SessionToken,
+				domainListRequest.AWSAuth.APIURL,
 			),
 		}
 
@@ -150,7 +143,7 @@ func PostDomains(c *gin.Context) {
 			return
 		}
 		civoConf := civo.CivoConfiguration{
-			Client:  civo.NewCivo(domainListRequest.CivoAuth.Token, domainListRequest.CloudRegion),
+			Client:  civo.NewCivo(domainListRequest.CivoAuth.Token, domainListRequest.CloudRegion, domainListRequest.CivoAuth.APIURL),
 			Context: context.Background(),
 		}
 
@@ -170,7 +163,7 @@ func PostDomains(c *gin.Context) {
 			return
 		}
 		digitaloceanConf := digitalocean.DigitaloceanConfiguration{
-			Client:  digitalocean.NewDigitalocean(domainListRequest.DigitaloceanAuth.Token),
+			Client:  digitalocean.NewDigitalocean(domainListRequest.DigitaloceanAuth.Token, domainListRequest.DigitaloceanAuth.APIURL),
 			Context: context.Background(),
 		}
 
@@ -190,7 +183,7 @@ func PostDomains(c *gin.Context) {
 			return
 		}
 		vultrConf := vultr.VultrConfiguration{
-			Client:  vultr.NewVultr(domainListRequest.VultrAuth.Token),
+			Client:  vultr.NewVultr(domainListRequest.VultrAuth.Token, domainListRequest.VultrAuth.APIURL),
 			Context: context.Background(),
 		}
 
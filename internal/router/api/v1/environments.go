@@ -9,12 +9,14 @@ import (
 	environments "github.com/kubefirst/kubefirst-api/internal/environments"
 	"github.com/kubefirst/kubefirst-api/internal/secrets"
 	"github.com/kubefirst/kubefirst-api/internal/types"
-	"github.com/kubefirst/kubefirst-api/internal/utils"
 	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
 )
 
 func GetEnvironments(c *gin.Context) {
-	kcfg := utils.GetKubernetesClient("TODO: SECRETS")
+	kcfg, ok := clusterStoreClient(c, "TODO: SECRETS")
+	if !ok {
+		return
+	}
 	environments, err := secrets.GetEnvironments(kcfg.Clientset)
 
 	if err != nil {
@@ -28,6 +30,9 @@ func GetEnvironments(c *gin.Context) {
 }
 
 func CreateEnvironment(c *gin.Context) {
+	if rejectReadOnly(c) {
+		return
+	}
 
 	// Bind to variable as application/json, handle error
 	var environmentDefinition pkgtypes.Environment
@@ -52,6 +57,10 @@ func CreateEnvironment(c *gin.Context) {
 }
 
 func DeleteEnvironment(c *gin.Context) {
+	if rejectReadOnly(c) {
+		return
+	}
+
 	envId, param := c.Params.Get("environment_id")
 
 	if !param {
@@ -61,7 +70,10 @@ func DeleteEnvironment(c *gin.Context) {
 		return
 	}
 
-	kcfg := utils.GetKubernetesClient("TODO: SECRETS")
+	kcfg, ok := clusterStoreClient(c, "TODO: SECRETS")
+	if !ok {
+		return
+	}
 	err := secrets.DeleteEnvironment(kcfg.Clientset, envId)
 
 	if err != nil {
@@ -78,6 +90,10 @@ func DeleteEnvironment(c *gin.Context) {
 }
 
 func UpdateEnvironment(c *gin.Context) {
+	if rejectReadOnly(c) {
+		return
+	}
+
 	envId, param := c.Params.Get("environment_id")
 
 	if !param {
@@ -103,7 +119,10 @@ func UpdateEnvironment(c *gin.Context) {
 		return
 	}
 
-	kcfg := utils.GetKubernetesClient("TODO: SECRETS")
+	kcfg, ok := clusterStoreClient(c, "TODO: SECRETS")
+	if !ok {
+		return
+	}
 	updateErr := secrets.UpdateEnvironment(kcfg.Clientset, envId, environmentUpdate)
 
 	if updateErr != nil {
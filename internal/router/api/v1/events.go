@@ -0,0 +1,99 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubefirst/kubefirst-api/internal/controller"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	"github.com/kubefirst/kubefirst-api/internal/types"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// eventKeepAliveInterval is how often an idle event stream sends a comment
+// so proxies don't close it during long steps
+var eventKeepAliveInterval = 15 * time.Second
+
+// GetClusterEvents godoc
+// @Summary Stream the provisioning events of a cluster
+// @Description Stream the log lines and step changes of a cluster's install over server-sent events until it finishes
+// @Tags cluster
+// @Produce text/event-stream
+// @Param	cluster_name	path	string	true	"Cluster name"
+// @Success 200 {object} pkgtypes.ProvisionEvent
+// @Failure 400 {object} types.JSONFailureResponse
+// @Router /cluster/:cluster_name/events [get]
+// @Param Authorization header string true "API key" default(Bearer <API key>)
+// GetClusterEvents follows a cluster's install to completion
+func GetClusterEvents(c *gin.Context) {
+	clusterName, param := c.Params.Get("cluster_name")
+	if !param {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: ":cluster_name not provided",
+		})
+		return
+	}
+
+	// subscribe before reading the record so an install finishing in between
+	// still ends the stream with its done event
+	events, unsubscribe, active := controller.SubscribeProvisionEvents(clusterName)
+	defer unsubscribe()
+
+	kcfg, ok := clusterStoreClient(c, clusterName)
+	if !ok {
+		return
+	}
+	cluster, err := secrets.GetCluster(kcfg.Clientset, clusterName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: fmt.Sprintf("cluster %s not found", clusterName),
+		})
+		return
+	}
+
+	setHeaders(c)
+
+	// nothing to follow, report where the cluster stands
+	if !active && !cluster.InProgress {
+		c.SSEvent(pkgtypes.ProvisionEventDone, pkgtypes.ProvisionEvent{
+			Type:    pkgtypes.ProvisionEventDone,
+			Time:    time.Now().UTC(),
+			Status:  cluster.Status,
+			Message: cluster.LastCondition,
+		})
+		c.Writer.Flush()
+		return
+	}
+
+	keepAlive := time.NewTicker(eventKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				c.SSEvent("error", "the client fell too far behind the event stream, reconnect to resume")
+				c.Writer.Flush()
+				return
+			}
+			c.SSEvent(event.Type, event)
+			c.Writer.Flush()
+			if event.Type == pkgtypes.ProvisionEventDone {
+				return
+			}
+		case <-keepAlive.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			c.Writer.Flush()
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
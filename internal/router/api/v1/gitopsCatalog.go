@@ -13,7 +13,6 @@ import (
 
 	"github.com/kubefirst/kubefirst-api/internal/secrets"
 	"github.com/kubefirst/kubefirst-api/internal/types"
-	"github.com/kubefirst/kubefirst-api/internal/utils"
 )
 
 // GetGitopsCatalogApps godoc
@@ -44,7 +43,10 @@ func GetGitopsCatalogApps(c *gin.Context) {
 		return
 	}
 
-	kcfg := utils.GetKubernetesClient(clusterName)
+	kcfg, ok := clusterStoreClient(c, clusterName)
+	if !ok {
+		return
+	}
 	cluster, err := secrets.GetCluster(kcfg.Clientset, clusterName)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
@@ -76,7 +78,14 @@ func GetGitopsCatalogApps(c *gin.Context) {
 // @Param Authorization header string true "API key" default(Bearer <API key>)
 // UpdateGitopsCatalogApps updates the list of available Kubefirst gitops catalog applications
 func UpdateGitopsCatalogApps(c *gin.Context) {
-	kcfg := utils.GetKubernetesClient("TODO: Secrets")
+	if rejectReadOnly(c) {
+		return
+	}
+
+	kcfg, ok := clusterStoreClient(c, "TODO: Secrets")
+	if !ok {
+		return
+	}
 	err := secrets.UpdateGitopsCatalogApps(kcfg.Clientset)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
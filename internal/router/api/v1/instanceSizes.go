@@ -12,10 +12,10 @@ import (
 	"github.com/kubefirst/kubefirst-api/internal/digitalocean"
 	"github.com/kubefirst/kubefirst-api/internal/types"
 	"github.com/kubefirst/kubefirst-api/internal/vultr"
+	"github.com/kubefirst/kubefirst-api/pkg/akamai"
 	"github.com/kubefirst/kubefirst-api/pkg/aws"
 	"github.com/kubefirst/kubefirst-api/pkg/google"
 	"github.com/linode/linodego"
-	"golang.org/x/oauth2"
 )
 
 func ListInstanceSizesForRegion(c *gin.Context) {
@@ -49,7 +49,7 @@ func ListInstanceSizesForRegion(c *gin.Context) {
 		}
 
 		civoConfig := civo.CivoConfiguration{
-			Client:  civo.NewCivo(instanceSizesRequest.CivoAuth.Token, instanceSizesRequest.CloudRegion),
+			Client:  civo.NewCivo(instanceSizesRequest.CivoAuth.Token, instanceSizesRequest.CloudRegion, instanceSizesRequest.CivoAuth.APIURL),
 			Context: context.Background(),
 		}
 
@@ -87,6 +87,7 @@ func ListInstanceSizesForRegion(c *gin.Context) {
 					instanceSizesRequest.AWSAuth.AccessKeyID,
 					instanceSizesRequest.AWSAuth.SecretAccessKey,
 					instanceSizesRequest.AWSAuth.SessionToken,
+					instanceSizesRequest.AWSAuth.APIURL,
 				),
 			}
 		}
@@ -117,7 +118,7 @@ func ListInstanceSizesForRegion(c *gin.Context) {
 		}
 
 		digitaloceanConf := digitalocean.DigitaloceanConfiguration{
-			Client:  digitalocean.NewDigitalocean(instanceSizesRequest.DigitaloceanAuth.Token),
+			Client:  digitalocean.NewDigitalocean(instanceSizesRequest.DigitaloceanAuth.Token, instanceSizesRequest.DigitaloceanAuth.APIURL),
 			Context: context.Background(),
 		}
 
@@ -142,11 +143,11 @@ func ListInstanceSizesForRegion(c *gin.Context) {
 		}
 
 		vultrConf := vultr.VultrConfiguration{
-			Client:  vultr.NewVultr(instanceSizesRequest.VultrAuth.Token),
+			Client:  vultr.NewVultr(instanceSizesRequest.VultrAuth.Token, instanceSizesRequest.VultrAuth.APIURL),
 			Context: context.Background(),
 		}
 
-		instances, err := vultrConf.ListInstances()
+		instances, err := vultrConf.ListInstanceTypes(instanceSizesRequest.CloudRegion)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
 				Message: err.Error(),
@@ -194,15 +195,7 @@ func ListInstanceSizesForRegion(c *gin.Context) {
 		instanceSizesResponse.InstanceSizes = instances
 
 	case "akamai":
-		tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: instanceSizesRequest.AkamaiAuth.Token})
-
-		oauth2Client := &http.Client{
-			Transport: &oauth2.Transport{
-				Source: tokenSource,
-			},
-		}
-
-		client := linodego.NewClient(oauth2Client)
+		client := akamai.NewAkamai(instanceSizesRequest.AkamaiAuth.Token, instanceSizesRequest.AkamaiAuth.APIURL)
 
 		instances, err := client.ListTypes(context.Background(), &linodego.ListOptions{})
 		if err != nil {
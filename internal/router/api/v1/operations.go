@@ -0,0 +1,97 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	"github.com/kubefirst/kubefirst-api/internal/types"
+)
+
+// GetClusterOperations godoc
+// @Summary Return the day-2 operations of a cluster
+// @Description Return the day-2 operations submitted for a cluster, oldest first
+// @Tags cluster
+// @Accept json
+// @Produce json
+// @Param	cluster_name	path	string	true	"Cluster name"
+// @Success 200 {object} []pkgtypes.Operation
+// @Failure 400 {object} types.JSONFailureResponse
+// @Router /cluster/:cluster_name/operations [get]
+// @Param Authorization header string true "API key" default(Bearer <API key>)
+// GetClusterOperations returns the operations of a cluster
+func GetClusterOperations(c *gin.Context) {
+	clusterName, param := c.Params.Get("cluster_name")
+	if !param {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: ":cluster_name not provided",
+		})
+		return
+	}
+
+	kcfg, ok := clusterStoreClient(c, clusterName)
+	if !ok {
+		return
+	}
+
+	operations, err := secrets.GetOperations(kcfg.Clientset, clusterName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, operations)
+}
+
+// GetClusterOperation godoc
+// @Summary Return a day-2 operation of a cluster
+// @Description Return the status and result of a day-2 operation submitted for a cluster
+// @Tags cluster
+// @Accept json
+// @Produce json
+// @Param	cluster_name	path	string	true	"Cluster name"
+// @Param	operation_id	path	string	true	"Operation ID"
+// @Success 200 {object} pkgtypes.Operation
+// @Failure 404 {object} types.JSONFailureResponse
+// @Router /cluster/:cluster_name/operations/:operation_id [get]
+// @Param Authorization header string true "API key" default(Bearer <API key>)
+// GetClusterOperation returns an operation of a cluster
+func GetClusterOperation(c *gin.Context) {
+	clusterName, param := c.Params.Get("cluster_name")
+	if !param {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: ":cluster_name not provided",
+		})
+		return
+	}
+	operationID, param := c.Params.Get("operation_id")
+	if !param {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: ":operation_id not provided",
+		})
+		return
+	}
+
+	kcfg, ok := clusterStoreClient(c, clusterName)
+	if !ok {
+		return
+	}
+
+	operation, err := secrets.GetOperation(kcfg.Clientset, operationID)
+	if err != nil || operation.ClusterName != clusterName {
+		c.JSON(http.StatusNotFound, types.JSONFailureResponse{
+			Message: "operation not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, operation)
+}
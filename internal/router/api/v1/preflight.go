@@ -0,0 +1,58 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubefirst/kubefirst-api/internal/controller"
+	"github.com/kubefirst/kubefirst-api/internal/types"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// PostPreflightCluster godoc
+// @Summary Validate a Kubefirst cluster definition without creating it
+// @Description Validate a Kubefirst cluster definition, credentials, quota and dns without creating it
+// @Tags cluster
+// @Accept json
+// @Produce json
+// @Param	cluster_name	path	string	true	"Cluster name"
+// @Param	skip	query	string	false	"Comma separated preflight checks to skip (definition, credentials, quota, dns, network, terraform_backend, spot, oidc, state_store, kubernetes_version, api_url)"
+// @Param	definition	body	types.ClusterDefinition	true	"Cluster create request in JSON format"
+// @Success 200 {object} pkgtypes.PreflightReport
+// @Failure 400 {object} types.JSONFailureResponse
+// @Router /cluster/:cluster_name/preflight [post]
+// @Param Authorization header string true "API key" default(Bearer <API key>)
+// PostPreflightCluster runs the preflight checks for a cluster definition
+func PostPreflightCluster(c *gin.Context) {
+	clusterName, param := c.Params.Get("cluster_name")
+	if !param || string(clusterName) == ":cluster_name" {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: ":cluster_name not provided",
+		})
+		return
+	}
+
+	var clusterDefinition pkgtypes.ClusterDefinition
+	err := c.Bind(&clusterDefinition)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: err.Error(),
+		})
+		return
+	}
+	clusterDefinition.ClusterName = clusterName
+
+	var skip []string
+	if s := c.Query("skip"); s != "" {
+		skip = strings.Split(s, ",")
+	}
+
+	c.JSON(http.StatusOK, controller.PreflightCheck(&clusterDefinition, skip...))
+}
@@ -18,10 +18,10 @@ import (
 	"github.com/kubefirst/kubefirst-api/internal/digitalocean"
 	"github.com/kubefirst/kubefirst-api/internal/types"
 	"github.com/kubefirst/kubefirst-api/internal/vultr"
+	"github.com/kubefirst/kubefirst-api/pkg/akamai"
 	"github.com/kubefirst/kubefirst-api/pkg/aws"
 	"github.com/kubefirst/kubefirst-api/pkg/google"
 	"github.com/linode/linodego"
-	"golang.org/x/oauth2"
 )
 
 // PostRegions godoc
@@ -79,6 +79,7 @@ func PostRegions(c *gin.Context) {
 					regionListRequest.AWSAuth.AccessKeyID,
 					regionListRequest.AWSAuth.SecretAccessKey,
 					regionListRequest.AWSAuth.SessionToken,
+					regionListRequest.AWSAuth.APIURL,
 				),
 			}
 		}
@@ -99,7 +100,7 @@ func PostRegions(c *gin.Context) {
 			return
 		}
 		civoConf := civo.CivoConfiguration{
-			Client:  civo.NewCivo(regionListRequest.CivoAuth.Token, regionListRequest.CloudRegion),
+			Client:  civo.NewCivo(regionListRequest.CivoAuth.Token, regionListRequest.CloudRegion, regionListRequest.CivoAuth.APIURL),
 			Context: context.Background(),
 		}
 
@@ -119,7 +120,7 @@ func PostRegions(c *gin.Context) {
 			return
 		}
 		digitaloceanConf := digitalocean.DigitaloceanConfiguration{
-			Client:  digitalocean.NewDigitalocean(regionListRequest.DigitaloceanAuth.Token),
+			Client:  digitalocean.NewDigitalocean(regionListRequest.DigitaloceanAuth.Token, regionListRequest.DigitaloceanAuth.APIURL),
 			Context: context.Background(),
 		}
 
@@ -139,11 +140,11 @@ func PostRegions(c *gin.Context) {
 			return
 		}
 		vultrConf := vultr.VultrConfiguration{
-			Client:  vultr.NewVultr(regionListRequest.VultrAuth.Token),
+			Client:  vultr.NewVultr(regionListRequest.VultrAuth.Token, regionListRequest.VultrAuth.APIURL),
 			Context: context.Background(),
 		}
 
-		regions, err := vultrConf.GetRegions()
+		regions, err := vultrConf.ListRegions()
 		if err != nil {
 			c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
 				Message: err.Error(),
@@ -178,15 +179,7 @@ func PostRegions(c *gin.Context) {
 		regionListResponse.Regions = []string{"on-premise (compatibilty-mode)"}
 
 	case "akamai":
-		tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: regionListRequest.AkamaiAuth.Token})
-
-		oauth2Client := &http.Client{
-			Transport: &oauth2.Transport{
-				Source: tokenSource,
-			},
-		}
-
-		client := linodego.NewClient(oauth2Client)
+		client := akamai.NewAkamai(regionListRequest.AkamaiAuth.Token, regionListRequest.AkamaiAuth.APIURL)
 
 		regions, err := client.ListRegions(context.Background(), &linodego.ListOptions{})
 		if err != nil {
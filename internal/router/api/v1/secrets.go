@@ -52,6 +52,10 @@ func GetClusterSecret(c *gin.Context) {
 }
 
 func CreateClusterSecret(c *gin.Context) {
+	if rejectReadOnly(c) {
+		return
+	}
+
 	clusterName, param := c.Params.Get("cluster_name")
 	if !param {
 		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
@@ -110,6 +114,10 @@ func CreateClusterSecret(c *gin.Context) {
 }
 
 func UpdateClusterSecret(c *gin.Context) {
+	if rejectReadOnly(c) {
+		return
+	}
+
 	clusterName, param := c.Params.Get("cluster_name")
 	if !param {
 		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
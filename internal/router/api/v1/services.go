@@ -15,8 +15,6 @@ import (
 	"github.com/kubefirst/kubefirst-api/internal/services"
 	"github.com/kubefirst/kubefirst-api/internal/types"
 	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
-
-	"github.com/kubefirst/kubefirst-api/internal/utils"
 )
 
 // GetServices godoc
@@ -40,7 +38,10 @@ func GetServices(c *gin.Context) {
 		return
 	}
 
-	kcfg := utils.GetKubernetesClient(clusterName)
+	kcfg, ok := clusterStoreClient(c, clusterName)
+	if !ok {
+		return
+	}
 
 	// Retrieve all services info
 	allServices, err := secrets.GetServices(kcfg.Clientset, clusterName)
@@ -69,6 +70,10 @@ func GetServices(c *gin.Context) {
 // @Param Authorization header string true "API key" default(Bearer <API key>)
 // PostAddServiceToCluster handles a request to add a service to a cluster based on a gitops catalog app
 func PostAddServiceToCluster(c *gin.Context) {
+	if rejectReadOnly(c) {
+		return
+	}
+
 	clusterName, param := c.Params.Get("cluster_name")
 	if !param {
 		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
@@ -85,7 +90,10 @@ func PostAddServiceToCluster(c *gin.Context) {
 		return
 	}
 
-	kcfg := utils.GetKubernetesClient(clusterName)
+	kcfg, ok := clusterStoreClient(c, clusterName)
+	if !ok {
+		return
+	}
 
 	// Verify cluster exists
 	_, err := secrets.GetCluster(kcfg.Clientset, clusterName)
@@ -104,20 +112,10 @@ func PostAddServiceToCluster(c *gin.Context) {
 		})
 		return
 	}
-	valid, hasKeys := false, false
-	var appDef pkgtypes.GitopsCatalogApp
-	for _, app := range apps.Apps {
-		if app.Name == serviceName {
-			valid = true
-			appDef = app
-			if app.SecretKeys != nil {
-				hasKeys = true
-			}
-		}
-	}
-	if !valid {
+	appDef, err := services.FindCatalogApp(apps, serviceName)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
-			Message: fmt.Sprintf("service %s is not valid", serviceName),
+			Message: err.Error(),
 		})
 		return
 	}
@@ -133,38 +131,12 @@ func PostAddServiceToCluster(c *gin.Context) {
 	}
 
 	// Verify any required secrets are present and not empty
-	if hasKeys {
-		if serviceDefinition.SecretKeys == nil {
-			c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
-				Message: fmt.Sprintf("service %s has required secret keys that cannot be empty, check your request and try again", serviceName),
-			})
-			return
-		}
-
-		var providedKeys []string
-		for _, key := range serviceDefinition.SecretKeys {
-			providedKeys = append(providedKeys, key.Name)
-		}
-
-		for _, key := range appDef.SecretKeys {
-			found := utils.FindStringInSlice(providedKeys, key.Name)
-			if !found {
-				c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
-					Message: fmt.Sprintf("%s is a required secret key", key.Name),
-				})
-				return
-			}
-			for _, subkey := range serviceDefinition.SecretKeys {
-				if key.Name == subkey.Name {
-					if subkey.Value == "" {
-						c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
-							Message: fmt.Sprintf("%s is a required secret key and its value cannot be empty", subkey.Name),
-						})
-						return
-					}
-				}
-			}
-		}
+	err = services.ValidateServiceRequest(&appDef, &serviceDefinition)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: err.Error(),
+		})
+		return
 	}
 
 	// Generate and apply
@@ -220,7 +192,10 @@ func PostValidateService(c *gin.Context) {
 		return
 	}
 
-	kcfg := utils.GetKubernetesClient(clusterName)
+	kcfg, ok := clusterStoreClient(c, clusterName)
+	if !ok {
+		return
+	}
 
 	// Verify cluster exists
 	_, err := secrets.GetCluster(kcfg.Clientset, clusterName)
@@ -299,6 +274,10 @@ func PostValidateService(c *gin.Context) {
 // @Param Authorization header string true "API key" default(Bearer <API key>)
 // DeleteServiceFromCluster handles a request to remove a gitops catalog application from a cluster
 func DeleteServiceFromCluster(c *gin.Context) {
+	if rejectReadOnly(c) {
+		return
+	}
+
 	clusterName, param := c.Params.Get("cluster_name")
 	if !param {
 		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
@@ -315,7 +294,10 @@ func DeleteServiceFromCluster(c *gin.Context) {
 		return
 	}
 
-	kcfg := utils.GetKubernetesClient(clusterName)
+	kcfg, ok := clusterStoreClient(c, clusterName)
+	if !ok {
+		return
+	}
 
 	// Verify cluster exists
 	cl, err := secrets.GetCluster(kcfg.Clientset, clusterName)
@@ -369,3 +351,52 @@ func DeleteServiceFromCluster(c *gin.Context) {
 		Message: fmt.Sprintf("service %s has been deleted", serviceName),
 	})
 }
+
+// PostAddDefaultServices godoc
+// @Summary Re-run adding the default service entries for a cluster
+// @Description Re-run adding the default service entries for a cluster, entries that already exist are skipped
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param	cluster_name	path	string	true	"Cluster name"
+// @Success 200 {object} []types.DefaultServiceResult
+// @Failure 400 {object} types.JSONFailureResponse
+// @Router /cluster/:cluster_name/services/defaults [post]
+// @Param Authorization header string true "API key" default(Bearer <API key>)
+// PostAddDefaultServices handles a request to add any missing default services to a cluster
+func PostAddDefaultServices(c *gin.Context) {
+	if rejectReadOnly(c) {
+		return
+	}
+
+	clusterName, param := c.Params.Get("cluster_name")
+	if !param {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: ":cluster_name not provided",
+		})
+		return
+	}
+
+	kcfg, ok := clusterStoreClient(c, clusterName)
+	if !ok {
+		return
+	}
+
+	cluster, err := secrets.GetCluster(kcfg.Clientset, clusterName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: "cluster not found",
+		})
+		return
+	}
+
+	results, err := services.EnsureDefaultServices(&cluster)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.JSONFailureResponse{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
@@ -13,8 +13,8 @@ import (
 	"github.com/kubefirst/kubefirst-api/internal/constants"
 	"github.com/kubefirst/kubefirst-api/internal/env"
 	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	apitelemetry "github.com/kubefirst/kubefirst-api/internal/telemetry"
 	"github.com/kubefirst/kubefirst-api/internal/types"
-	"github.com/kubefirst/kubefirst-api/internal/utils"
 	"github.com/kubefirst/metrics-client/pkg/telemetry"
 )
 
@@ -37,7 +37,10 @@ func PostTelemetry(c *gin.Context) {
 		})
 		return
 	}
-	kcfg := utils.GetKubernetesClient(clusterName)
+	kcfg, ok := clusterStoreClient(c, clusterName)
+	if !ok {
+		return
+	}
 
 	// Retrieve cluster info
 	cl, err := secrets.GetCluster(kcfg.Clientset, clusterName)
@@ -76,7 +79,7 @@ func PostTelemetry(c *gin.Context) {
 		return
 	}
 
-	telemetry.SendEvent(telEvent, req.Event, "")
+	apitelemetry.Transmit(telEvent, req.Event, "")
 
 	c.JSON(http.StatusOK, true)
 }
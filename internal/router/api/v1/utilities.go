@@ -10,7 +10,10 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kubefirst/kubefirst-api/internal/controller"
+	"github.com/kubefirst/kubefirst-api/internal/k8s"
 	"github.com/kubefirst/kubefirst-api/internal/types"
+	"github.com/kubefirst/kubefirst-api/internal/utils"
 )
 
 // getHealth godoc
@@ -26,3 +29,31 @@ func GetHealth(c *gin.Context) {
 		Status: "healthz",
 	})
 }
+
+// rejectReadOnly responds with 403 and returns true when the api runs in
+// read-only mode, handlers that change clusters or stored records call it first
+func rejectReadOnly(c *gin.Context) bool {
+	err := controller.CheckWritable()
+	if err != nil {
+		c.JSON(http.StatusForbidden, types.JSONFailureResponse{
+			Message: err.Error(),
+		})
+		return true
+	}
+
+	return false
+}
+
+// clusterStoreClient returns the client of the cluster store, it responds
+// with 500 and returns false when the store can't be connected to
+func clusterStoreClient(c *gin.Context, clusterName string) (*k8s.KubernetesClient, bool) {
+	kcfg, err := utils.GetClusterStoreClient(clusterName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.JSONFailureResponse{
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+
+	return kcfg, true
+}
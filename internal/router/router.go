@@ -51,9 +51,21 @@ func SetupRouter() *gin.Engine {
 		v1.GET("/cluster/:cluster_name", middleware.ValidateAPIKey(), router.GetCluster)
 		v1.DELETE("/cluster/:cluster_name", middleware.ValidateAPIKey(), router.DeleteCluster)
 		v1.POST("/cluster/:cluster_name", middleware.ValidateAPIKey(), router.PostCreateCluster)
+		v1.POST("/cluster/:cluster_name/preflight", middleware.ValidateAPIKey(), router.PostPreflightCluster)
+		v1.POST("/cluster/:cluster_name/cost-estimate", middleware.ValidateAPIKey(), router.PostEstimateClusterCost)
 		v1.GET("/cluster/:cluster_name/export", middleware.ValidateAPIKey(), router.GetExportCluster)
 		v1.POST("/cluster/:cluster_name/reset_progress", middleware.ValidateAPIKey(), router.PostResetClusterProgress)
 		v1.POST("/cluster/:cluster_name/vclusters", middleware.ValidateAPIKey(), router.PostCreateVcluster)
+		v1.POST("/cluster/:cluster_name/services/defaults", middleware.ValidateAPIKey(), router.PostAddDefaultServices)
+		v1.GET("/cluster/:cluster_name/events", middleware.ValidateAPIKey(), router.GetClusterEvents)
+		v1.GET("/cluster/:cluster_name/operations", middleware.ValidateAPIKey(), router.GetClusterOperations)
+		v1.GET("/cluster/:cluster_name/operations/:operation_id", middleware.ValidateAPIKey(), router.GetClusterOperation)
+
+		// Cluster groups
+		v1.GET("/cluster-group/:group_name", middleware.ValidateAPIKey(), router.GetClusterGroup)
+
+		// Fleet
+		v1.GET("/fleet-metrics", middleware.ValidateAPIKey(), router.GetFleetMetrics)
 
 		// KubeConfig
 		v1.POST("/kubeconfig/:cloud_provider", middleware.ValidateAPIKey(), router.GetClusterKubeConfig)
@@ -92,6 +104,10 @@ func SetupRouter() *gin.Engine {
 
 		// Default instance size and node count for supported cloud providers
 		v1.GET("/cloud-defaults", middleware.ValidateAPIKey(), router.GetCloudProviderDefaults)
+		v1.GET("/cloud-defaults/timeouts", middleware.ValidateAPIKey(), router.GetProvisionTimeoutDefaults)
+
+		// JSON schema of the cluster definition accepted when creating a cluster
+		v1.GET("/cluster-definition/schema", middleware.ValidateAPIKey(), router.GetClusterDefinitionSchema)
 
 		// Environments
 		v1.GET("/environment", middleware.ValidateAPIKey(), router.GetEnvironments)
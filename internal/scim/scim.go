@@ -0,0 +1,181 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/kubefirst/kubefirst-api/internal/httpCommon"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// pageSize is the number of resources requested per page, identity
+// providers cap it at their own maximum
+const pageSize = 100
+
+type listResponse struct {
+	TotalResults int               `json:"totalResults"`
+	Resources    []json.RawMessage `json:"Resources"`
+}
+
+type user struct {
+	ID       string `json:"id"`
+	UserName string `json:"userName"`
+	// Active is left out by some identity providers, users are active then
+	Active *bool `json:"active"`
+	Name   struct {
+		GivenName  string `json:"givenName"`
+		FamilyName string `json:"familyName"`
+	} `json:"name"`
+	Emails []struct {
+		Value   string `json:"value"`
+		Primary bool   `json:"primary"`
+	} `json:"emails"`
+}
+
+type group struct {
+	DisplayName string `json:"displayName"`
+	Members     []struct {
+		Value string `json:"value"`
+	} `json:"members"`
+}
+
+// ListUsers returns the active users of a SCIM 2.0 directory sorted by
+// username, limited to the members of the directory's group when it's set
+func ListUsers(directory pkgtypes.UserDirectory) ([]pkgtypes.DirectoryUser, error) {
+	baseURL := strings.TrimSuffix(directory.SCIMURL, "/")
+
+	var members map[string]bool
+	if directory.Group != "" {
+		var err error
+		members, err = groupMembers(baseURL, directory.Token, directory.Group)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resources, err := list(baseURL, directory.Token, "Users", "")
+	if err != nil {
+		return nil, err
+	}
+
+	users := []pkgtypes.DirectoryUser{}
+	for _, resource := range resources {
+		var u user
+		err := json.Unmarshal(resource, &u)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding scim user: %s", err)
+		}
+		if u.UserName == "" || (u.Active != nil && !*u.Active) {
+			continue
+		}
+		if members != nil && !members[u.ID] {
+			continue
+		}
+
+		users = append(users, pkgtypes.DirectoryUser{
+			Username:  u.UserName,
+			Email:     primaryEmail(u),
+			FirstName: u.Name.GivenName,
+			LastName:  u.Name.FamilyName,
+		})
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+
+	return users, nil
+}
+
+// groupMembers returns the ids of the members of the group with displayName
+func groupMembers(baseURL string, token string, displayName string) (map[string]bool, error) {
+	filter := fmt.Sprintf("displayName eq %q", displayName)
+	resources, err := list(baseURL, token, "Groups", filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(resources) == 0 {
+		return nil, fmt.Errorf("scim group %s not found", displayName)
+	}
+
+	var g group
+	err = json.Unmarshal(resources[0], &g)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding scim group %s: %s", displayName, err)
+	}
+
+	members := map[string]bool{}
+	for _, member := range g.Members {
+		members[member.Value] = true
+	}
+
+	return members, nil
+}
+
+// list pages through a SCIM resource type
+func list(baseURL string, token string, resourceType string, filter string) ([]json.RawMessage, error) {
+	httpClient := httpCommon.CustomHttpClient(false)
+
+	resources := []json.RawMessage{}
+	startIndex := 1
+	for {
+		query := url.Values{}
+		query.Set("startIndex", fmt.Sprint(startIndex))
+		query.Set("count", fmt.Sprint(pageSize))
+		if filter != "" {
+			query.Set("filter", filter)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/%s?%s", baseURL, resourceType, query.Encode()), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Set("Accept", "application/scim+json")
+
+		res, err := httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("error listing scim %s: %s", resourceType, err)
+		}
+
+		var page listResponse
+		if res.StatusCode != http.StatusOK {
+			res.Body.Close()
+			return nil, fmt.Errorf("error listing scim %s: %s", resourceType, res.Status)
+		}
+		err = json.NewDecoder(res.Body).Decode(&page)
+		res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error decoding scim %s: %s", resourceType, err)
+		}
+
+		resources = append(resources, page.Resources...)
+		startIndex += len(page.Resources)
+		if len(page.Resources) == 0 || len(resources) >= page.TotalResults {
+			return resources, nil
+		}
+	}
+}
+
+// primaryEmail returns the user's primary email, or the first one
+func primaryEmail(u user) string {
+	for _, email := range u.Emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(u.Emails) != 0 {
+		return u.Emails[0].Value
+	}
+
+	return ""
+}
@@ -0,0 +1,63 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package secrets
+
+import (
+	"fmt"
+
+	"github.com/kubefirst/kubefirst-api/internal/constants"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// GetClusterGroup returns all clusters belonging to a cluster group along with
+// the status aggregated across them
+func GetClusterGroup(clientSet *kubernetes.Clientset, groupName string) (pkgtypes.ClusterGroup, error) {
+	clusterGroup := pkgtypes.ClusterGroup{
+		Name:     groupName,
+		Clusters: []pkgtypes.Cluster{},
+	}
+
+	clusters, err := GetClusters(clientSet)
+	if err != nil {
+		return clusterGroup, err
+	}
+
+	for _, cluster := range clusters {
+		if cluster.ClusterGroup == groupName {
+			clusterGroup.Clusters = append(clusterGroup.Clusters, cluster)
+		}
+	}
+
+	if len(clusterGroup.Clusters) == 0 {
+		return clusterGroup, fmt.Errorf("cluster group %s not found", groupName)
+	}
+
+	clusterGroup.Status = clusterGroupStatus(clusterGroup.Clusters)
+
+	return clusterGroup, nil
+}
+
+// clusterGroupStatus reports the least healthy status of the clusters in a group
+func clusterGroupStatus(clusters []pkgtypes.Cluster) string {
+	status := constants.ClusterStatusProvisioned
+
+	for _, cluster := range clusters {
+		switch cluster.Status {
+		case constants.ClusterStatusError:
+			return constants.ClusterStatusError
+		case constants.ClusterStatusDeleting, constants.ClusterStatusProvisioning, constants.ClusterStatusQueued:
+			status = cluster.Status
+		case constants.ClusterStatusDeleted:
+			if status == constants.ClusterStatusProvisioned {
+				status = cluster.Status
+			}
+		}
+	}
+
+	return status
+}
@@ -9,20 +9,31 @@ package secrets
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/kubefirst/kubefirst-api/internal/k8s"
 	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
 	log "github.com/rs/zerolog/log"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
 )
 
 const KUBEFIRST_CLUSTERS_SECRET_NAME = "kubefirst-clusters"
 const KUBEFIRST_CLUSTER_PREFIX = "kubefirst-cluster"
 
+// clusterTagLabelPrefix prefixes the labels indexing a cluster's tags on its
+// secret so clusters can be listed by tag with a label selector
+const clusterTagLabelPrefix = "tags.kubefirst.io/"
+
 // DeleteCluster
 func DeleteCluster(clientSet *kubernetes.Clientset, clusterName string) error {
+	if store != nil {
+		return store.DeleteCluster(clusterName)
+	}
+
 	err := DeleteSecretReference(clientSet, KUBEFIRST_CLUSTERS_SECRET_NAME, clusterName)
 	if err != nil {
 		return fmt.Errorf("error deleting cluster %s reference", clusterName)
@@ -38,31 +49,84 @@ func DeleteCluster(clientSet *kubernetes.Clientset, clusterName string) error {
 	return nil
 }
 
+// writesDisabled reports whether GetCluster must leave the records it
+// migrates or finds unencrypted as they are, see SetWritesDisabled
+var writesDisabled = func() bool { return false }
+
+// SetWritesDisabled sets the check GetCluster consults before writing back a
+// record it migrated or found unencrypted, a read-only api points it at its
+// read-only mode so reads never change the store
+func SetWritesDisabled(disabled func() bool) {
+	writesDisabled = disabled
+}
+
 // GetCluster
 func GetCluster(clientSet *kubernetes.Clientset, clusterName string) (pkgtypes.Cluster, error) {
+	if store != nil {
+		return store.GetCluster(clusterName)
+	}
+
+	cluster, migrated, plainText, err := readCluster(clientSet, clusterName)
+	if err != nil {
+		return cluster, err
+	}
+
+	// with writes disabled the migrated record is only returned, it's
+	// migrated again on every read until a writable api saves it
+	if (migrated || plainText) && !writesDisabled() {
+		err = UpdateCluster(clientSet, cluster)
+		if err != nil {
+			log.Warn().Msgf("error saving migrated cluster %s: %s", clusterName, err)
+		} else if migrated {
+			log.Info().Msgf("migrated cluster %s to schema version %v", clusterName, cluster.SchemaVersion)
+		} else {
+			log.Info().Msgf("encrypted the sensitive fields of cluster %s", clusterName)
+		}
+	}
+
+	return cluster, nil
+}
+
+// readCluster reads a cluster secret, reporting whether its record was
+// migrated and whether it holds sensitive values that should be encrypted
+func readCluster(clientSet *kubernetes.Clientset, clusterName string) (pkgtypes.Cluster, bool, bool, error) {
 	cluster := pkgtypes.Cluster{}
 
 	clusterSecret, err := k8s.ReadSecretV2Old(clientSet, "kubefirst", fmt.Sprintf("%s-%s", KUBEFIRST_CLUSTER_PREFIX, clusterName))
 	if err != nil {
-		return cluster, fmt.Errorf("secret not found: %s", err)
+		return cluster, false, false, fmt.Errorf("secret not found: %s", err)
 	}
 	jsonString, _ := MapToStructuredJSON(clusterSecret)
 
-	jsonData, err := json.Marshal(jsonString)
+	record, _ := jsonString.(map[string]interface{})
+	plainText, err := decryptClusterRecord(record)
 	if err != nil {
-		return cluster, fmt.Errorf("error marshalling json: %s", err)
+		return cluster, false, false, fmt.Errorf("unable to read cluster %s: %s", clusterName, err)
+	}
+	migrated, err := MigrateClusterRecord(record)
+	if err != nil {
+		return cluster, false, false, fmt.Errorf("unable to read cluster %s: %s", clusterName, err)
+	}
+
+	jsonData, err := json.Marshal(record)
+	if err != nil {
+		return cluster, false, false, fmt.Errorf("error marshalling json: %s", err)
 	}
 
 	err = json.Unmarshal([]byte(jsonData), &cluster)
 	if err != nil {
-		return cluster, fmt.Errorf("unable to cast cluster: %s", err)
+		return cluster, false, false, fmt.Errorf("unable to cast cluster: %s", err)
 	}
 
-	return cluster, nil
+	return cluster, migrated, plainText, nil
 }
 
 // GetCluster
 func GetClusters(clientSet *kubernetes.Clientset) ([]pkgtypes.Cluster, error) {
+	if store != nil {
+		return store.GetClusters()
+	}
+
 	clusterList := []pkgtypes.Cluster{}
 	clusterReferenceList, _ := GetSecretReference(clientSet, KUBEFIRST_CLUSTERS_SECRET_NAME)
 	for _, clusterName := range clusterReferenceList.List {
@@ -78,6 +142,10 @@ func GetClusters(clientSet *kubernetes.Clientset) ([]pkgtypes.Cluster, error) {
 
 // InsertCluster
 func InsertCluster(clientSet *kubernetes.Clientset, cl pkgtypes.Cluster) error {
+	if store != nil {
+		return store.InsertCluster(cl)
+	}
+
 	_, err := GetSecretReference(clientSet, KUBEFIRST_CLUSTERS_SECRET_NAME)
 
 	if err != nil {
@@ -92,13 +160,17 @@ func InsertCluster(clientSet *kubernetes.Clientset, cl pkgtypes.Cluster) error {
 		}
 	}
 
-	bytes, _ := json.Marshal(cl)
+	bytes, err := marshalClusterRecord(cl)
+	if err != nil {
+		return err
+	}
 	secretValuesMap, _ := ParseJSONToMap(string(bytes))
 
 	secretToCreate := &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      fmt.Sprintf("%s-%s", KUBEFIRST_CLUSTER_PREFIX, cl.ClusterName),
 			Namespace: "kubefirst",
+			Labels:    clusterTagLabels(cl.Tags),
 		},
 		Data: secretValuesMap,
 	}
@@ -114,14 +186,106 @@ func InsertCluster(clientSet *kubernetes.Clientset, cl pkgtypes.Cluster) error {
 
 // UpdateCluster
 func UpdateCluster(clientSet *kubernetes.Clientset, cluster pkgtypes.Cluster) error {
-	bytes, _ := json.Marshal(cluster)
+	if store != nil {
+		return store.UpdateCluster(cluster)
+	}
+
+	bytes, err := marshalClusterRecord(cluster)
+	if err != nil {
+		return err
+	}
 	secretValuesMap, _ := ParseJSONToMap(string(bytes))
 
-	err := k8s.UpdateSecretV2(clientSet, "kubefirst", fmt.Sprintf("%s-%s", KUBEFIRST_CLUSTER_PREFIX, cluster.ClusterName), secretValuesMap)
+	err = k8s.UpdateSecretV2(clientSet, "kubefirst", fmt.Sprintf("%s-%s", KUBEFIRST_CLUSTER_PREFIX, cluster.ClusterName), secretValuesMap)
 
 	if err != nil {
 		return fmt.Errorf("error updating kubernetes secret: %s", err)
 	}
 
+	if len(cluster.Tags) != 0 {
+		err = k8s.UpdateSecretLabelsV2(clientSet, "kubefirst", fmt.Sprintf("%s-%s", KUBEFIRST_CLUSTER_PREFIX, cluster.ClusterName), clusterTagLabelPrefix, clusterTagLabels(cluster.Tags))
+		if err != nil {
+			return fmt.Errorf("error labelling kubernetes secret: %s", err)
+		}
+	}
+
 	return nil
 }
+
+// GetClustersByTags returns the clusters carrying every one of tags - tags
+// that are valid kubernetes labels are matched with a label selector, the
+// rest, and every tag with a Store, by reading every cluster
+func GetClustersByTags(clientSet *kubernetes.Clientset, tags map[string]string) ([]pkgtypes.Cluster, error) {
+	if len(tags) == 0 {
+		return GetClusters(clientSet)
+	}
+
+	labels := clusterTagLabels(tags)
+	if store != nil || len(labels) != len(tags) {
+		clusters, err := GetClusters(clientSet)
+		if err != nil {
+			return nil, err
+		}
+
+		return filterClustersByTags(clusters, tags), nil
+	}
+
+	selector := make([]string, 0, len(labels))
+	for key, value := range labels {
+		selector = append(selector, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(selector)
+
+	secretNames, err := k8s.ListSecretNamesV2(clientSet, "kubefirst", strings.Join(selector, ","))
+	if err != nil {
+		return nil, fmt.Errorf("error listing clusters by tag: %s", err)
+	}
+
+	clusterList := []pkgtypes.Cluster{}
+	for _, secretName := range secretNames {
+		cluster, err := GetCluster(clientSet, strings.TrimPrefix(secretName, KUBEFIRST_CLUSTER_PREFIX+"-"))
+		if err != nil {
+			log.Warn().Msgf("error reading cluster %s: %s", secretName, err)
+			continue
+		}
+
+		clusterList = append(clusterList, cluster)
+	}
+
+	return filterClustersByTags(clusterList, tags), nil
+}
+
+// clusterTagLabels returns the labels indexing tags, tags that can't be
+// expressed as a kubernetes label are left out
+func clusterTagLabels(tags map[string]string) map[string]string {
+	labels := map[string]string{}
+	for key, value := range tags {
+		labelKey := clusterTagLabelPrefix + key
+		if len(validation.IsQualifiedName(labelKey)) != 0 || len(validation.IsValidLabelValue(value)) != 0 {
+			continue
+		}
+		labels[labelKey] = value
+	}
+
+	return labels
+}
+
+// filterClustersByTags keeps the clusters carrying every one of tags
+func filterClustersByTags(clusters []pkgtypes.Cluster, tags map[string]string) []pkgtypes.Cluster {
+	filtered := []pkgtypes.Cluster{}
+	for _, cluster := range clusters {
+		matches := true
+		for key, value := range tags {
+			if tag, ok := cluster.Tags[key]; !ok || tag != value {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			filtered = append(filtered, cluster)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,323 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kubefirst/kubefirst-api/internal/constants"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	log "github.com/rs/zerolog/log"
+	"k8s.io/client-go/kubernetes"
+)
+
+// encryptedValuePrefix marks a stored value as encrypted with AES-256-GCM,
+// the rest is the base64 nonce and ciphertext
+const encryptedValuePrefix = "enc:v1:"
+
+// EncryptedClusterFields are the json paths of the cluster record fields
+// that are encrypted at rest once an encryption key is set, a [] segment
+// applies the rest of the path to every element of a list
+var EncryptedClusterFields = []string{
+	"akamai_auth.token",
+	"aws_auth.secret_access_key",
+	"aws_auth.session_token",
+	"civo_auth.token",
+	"do_auth.token",
+	"do_auth.spaces_secret",
+	"vultr_auth.token",
+	"cloudflare_auth.token",
+	"cloudflare_auth.api_token",
+	"cloudflare_auth.origin_ca_issuer_key",
+	"git_auth.git_token",
+	"git_auth.private_key",
+	"git_auth.app_private_key",
+	"vault_auth.root_token",
+	"vault_auth.kbot_password",
+	"google_auth.key_file",
+	"k3s_auth.ssh_privatekey",
+	"state_store_credentials.secret_access_key",
+	"state_store_credentials.session_token",
+	"atlantis_webhook_secret",
+	"argocd_password",
+	"argocd_auth_token",
+	"user_directory.token",
+	"oidc.client_secret",
+	"image_pull_secrets[].password",
+	"gitops_mirrors[].password",
+	"workload_clusters[].git_auth.git_token",
+	"workload_clusters[].git_auth.private_key",
+	"workload_clusters[].git_auth.app_private_key",
+}
+
+// recordCipher encrypts the EncryptedClusterFields, nil stores them in
+// plain text
+var recordCipher cipher.AEAD
+
+// SetEncryptionKey sets the 32 byte AES-256 key the sensitive cluster
+// record fields are encrypted with, a nil key stores them in plain text
+func SetEncryptionKey(key []byte) error {
+	if key == nil {
+		recordCipher = nil
+		return nil
+	}
+	if len(key) != 32 {
+		return fmt.Errorf("the record encryption key has to be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	recordCipher = gcm
+
+	return nil
+}
+
+// marshalClusterRecord returns the json of a cluster record at the current
+// schema version with its sensitive fields encrypted
+func marshalClusterRecord(cl pkgtypes.Cluster) ([]byte, error) {
+	cl.SchemaVersion = pkgtypes.ClusterSchemaVersion
+	content, err := json.Marshal(cl)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling cluster %s: %s", cl.ClusterName, err)
+	}
+	if recordCipher == nil {
+		return content, nil
+	}
+
+	record := map[string]interface{}{}
+	err = json.Unmarshal(content, &record)
+	if err != nil {
+		return nil, err
+	}
+	err = transformClusterRecord(record, encryptValue)
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting cluster %s: %s", cl.ClusterName, err)
+	}
+
+	return json.Marshal(record)
+}
+
+// decryptClusterRecord decrypts the encrypted fields of a stored cluster
+// record in place and reports whether it holds sensitive values in plain
+// text that should be encrypted, e.g. ones stored before a key was set
+func decryptClusterRecord(record map[string]interface{}) (bool, error) {
+	plainText := false
+	err := transformClusterRecord(record, func(value string) (string, error) {
+		if !strings.HasPrefix(value, encryptedValuePrefix) {
+			plainText = plainText || recordCipher != nil
+			return value, nil
+		}
+		return decryptValue(value)
+	})
+
+	return plainText, err
+}
+
+// transformClusterRecord applies transform to every string value at the
+// EncryptedClusterFields paths of record
+func transformClusterRecord(record map[string]interface{}, transform func(string) (string, error)) error {
+	for _, field := range EncryptedClusterFields {
+		err := transformPath(record, strings.Split(field, "."), transform)
+		if err != nil {
+			return fmt.Errorf("%s: %s", field, err)
+		}
+	}
+
+	return nil
+}
+
+func transformPath(node map[string]interface{}, path []string, transform func(string) (string, error)) error {
+	key := strings.TrimSuffix(path[0], "[]")
+	value, ok := node[key]
+	if !ok || value == nil {
+		return nil
+	}
+
+	switch {
+	case key != path[0]:
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		for _, element := range list {
+			child, ok := element.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			err := transformPath(child, path[1:], transform)
+			if err != nil {
+				return err
+			}
+		}
+	case len(path) > 1:
+		child, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		return transformPath(child, path[1:], transform)
+	default:
+		s, ok := value.(string)
+		if !ok || s == "" {
+			return nil
+		}
+		transformed, err := transform(s)
+		if err != nil {
+			return err
+		}
+		node[key] = transformed
+	}
+
+	return nil
+}
+
+func encryptValue(value string) (string, error) {
+	if strings.HasPrefix(value, encryptedValuePrefix) {
+		return value, nil
+	}
+
+	nonce := make([]byte, recordCipher.NonceSize())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+	sealed := recordCipher.Seal(nonce, nonce, []byte(value), nil)
+
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptValue(value string) (string, error) {
+	if recordCipher == nil {
+		return "", fmt.Errorf("the value is encrypted and no record encryption key is set")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedValuePrefix))
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %s", err)
+	}
+	nonceSize := recordCipher.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	plain, err := recordCipher.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to decrypt, the record encryption key may have changed: %s", err)
+	}
+
+	return string(plain), nil
+}
+
+// EncryptClusterRecords is a one time maintenance migration for records
+// stored before an encryption key was set, it rewrites every cluster record
+// that holds sensitive fields in plain text so they are encrypted. With
+// redactDeleted, the sensitive fields of deleted clusters are cleared instead
+// since nothing uses them anymore. Only counts are logged
+func EncryptClusterRecords(clientSet *kubernetes.Clientset, redactDeleted bool) (pkgtypes.RecordEncryptionResult, error) {
+	result := pkgtypes.RecordEncryptionResult{}
+	if recordCipher == nil && !redactDeleted {
+		return result, fmt.Errorf("no record encryption key is set")
+	}
+
+	var clusters []pkgtypes.Cluster
+	plainText := map[string]bool{}
+	if store != nil {
+		// a store encrypts whatever it writes, rewrite every record
+		var err error
+		clusters, err = store.GetClusters()
+		if err != nil {
+			return result, err
+		}
+		for _, cl := range clusters {
+			plainText[cl.ClusterName] = recordCipher != nil
+		}
+	} else {
+		clusterReferenceList, err := GetSecretReference(clientSet, KUBEFIRST_CLUSTERS_SECRET_NAME)
+		if err != nil {
+			return result, err
+		}
+		for _, clusterName := range clusterReferenceList.List {
+			cl, _, needsEncryption, err := readCluster(clientSet, clusterName)
+			if err != nil {
+				return result, err
+			}
+			clusters = append(clusters, cl)
+			plainText[clusterName] = needsEncryption
+		}
+	}
+
+	for _, cl := range clusters {
+		result.Clusters++
+
+		redact := false
+		if redactDeleted && cl.Status == constants.ClusterStatusDeleted {
+			var err error
+			cl, redact, err = redactCluster(cl)
+			if err != nil {
+				return result, err
+			}
+		}
+		if !redact && !plainText[cl.ClusterName] {
+			continue
+		}
+
+		err := UpdateCluster(clientSet, cl)
+		if err != nil {
+			return result, err
+		}
+		if redact {
+			result.Redacted++
+		} else {
+			result.Encrypted++
+		}
+	}
+
+	log.Info().Msgf("cluster record encryption: %d records, %d encrypted, %d redacted", result.Clusters, result.Encrypted, result.Redacted)
+
+	return result, nil
+}
+
+// redactCluster clears the EncryptedClusterFields of a cluster and reports
+// whether any of them was set
+func redactCluster(cl pkgtypes.Cluster) (pkgtypes.Cluster, bool, error) {
+	content, err := json.Marshal(cl)
+	if err != nil {
+		return cl, false, err
+	}
+	record := map[string]interface{}{}
+	err = json.Unmarshal(content, &record)
+	if err != nil {
+		return cl, false, err
+	}
+	changed := false
+	err = transformClusterRecord(record, func(string) (string, error) {
+		changed = true
+		return "", nil
+	})
+	if err != nil || !changed {
+		return cl, false, err
+	}
+
+	content, err = json.Marshal(record)
+	if err != nil {
+		return cl, false, err
+	}
+	redacted := pkgtypes.Cluster{}
+	err = json.Unmarshal(content, &redacted)
+
+	return redacted, true, err
+}
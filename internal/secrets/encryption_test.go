@@ -0,0 +1,162 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package secrets
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// jsonField returns the field of t whose json name is name
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if strings.Split(field.Tag.Get("json"), ",")[0] == name {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+func TestEncryptedClusterFieldsExist(t *testing.T) {
+	for _, path := range EncryptedClusterFields {
+		current := reflect.TypeOf(pkgtypes.Cluster{})
+		for _, segment := range strings.Split(path, ".") {
+			field, ok := jsonField(current, strings.TrimSuffix(segment, "[]"))
+			if !ok {
+				t.Fatalf("%s: no field %s in %s", path, segment, current.Name())
+			}
+			current = field.Type
+			if strings.HasSuffix(segment, "[]") {
+				if current.Kind() != reflect.Slice {
+					t.Fatalf("%s: %s is not a list", path, segment)
+				}
+				current = current.Elem()
+			}
+		}
+		if current.Kind() != reflect.String {
+			t.Errorf("%s: expected a string field, got %s", path, current.Kind())
+		}
+	}
+}
+
+func TestClusterRecordEncryption(t *testing.T) {
+	err := SetEncryptionKey(bytes.Repeat([]byte("k"), 32))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer SetEncryptionKey(nil)
+
+	s, _ := NewMemoryStore("")
+	cl := pkgtypes.Cluster{
+		ClusterName:      "kf-test",
+		GitAuth:          pkgtypes.GitAuth{Token: "ghp_secret-token", Owner: "kubefirst-test"},
+		VaultAuth:        pkgtypes.VaultAuth{RootToken: "hvs.root-token"},
+		ArgoCDPassword:   "argocd-password",
+		ImagePullSecrets: []pkgtypes.ImagePullSecret{{Name: "registry", Password: "registry-password"}},
+	}
+	err = s.InsertCluster(cl)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	stored := string(s.clusters["kf-test"])
+	for _, plain := range []string{"ghp_secret-token", "hvs.root-token", "argocd-password", "registry-password"} {
+		if strings.Contains(stored, plain) {
+			t.Errorf("stored record contains %s in plain text", plain)
+		}
+	}
+	if !strings.Contains(stored, "kubefirst-test") {
+		t.Errorf("expected fields outside the encrypted set to stay readable")
+	}
+
+	got, err := s.GetCluster("kf-test")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got.GitAuth.Token != cl.GitAuth.Token || got.VaultAuth.RootToken != cl.VaultAuth.RootToken ||
+		got.ArgoCDPassword != cl.ArgoCDPassword || got.ImagePullSecrets[0].Password != "registry-password" {
+		t.Errorf("expected the decrypted cluster, got %+v", got)
+	}
+
+	SetEncryptionKey(bytes.Repeat([]byte("x"), 32))
+	_, err = s.GetCluster("kf-test")
+	if err == nil {
+		t.Error("expected an error decrypting with a different key")
+	}
+
+	SetEncryptionKey(nil)
+	_, err = s.GetCluster("kf-test")
+	if err == nil {
+		t.Error("expected an error reading encrypted fields without a key")
+	}
+}
+
+func TestDecryptClusterRecordReportsPlainText(t *testing.T) {
+	record := map[string]interface{}{"git_auth": map[string]interface{}{"git_token": "ghp_plain"}}
+
+	plainText, err := decryptClusterRecord(record)
+	if err != nil || plainText {
+		t.Errorf("expected plain text to be fine without a key, got %v (%v)", plainText, err)
+	}
+
+	SetEncryptionKey(bytes.Repeat([]byte("k"), 32))
+	defer SetEncryptionKey(nil)
+
+	plainText, err = decryptClusterRecord(record)
+	if err != nil || !plainText {
+		t.Errorf("expected plain text to be reported with a key, got %v (%v)", plainText, err)
+	}
+}
+
+func TestEncryptClusterRecords(t *testing.T) {
+	s, _ := NewMemoryStore("")
+	SetStore(s)
+	defer SetStore(nil)
+
+	_, err := EncryptClusterRecords(nil, false)
+	if err == nil {
+		t.Fatal("expected an error encrypting without a key")
+	}
+
+	s.InsertCluster(pkgtypes.Cluster{ClusterName: "kf-live", Status: "provisioned", GitAuth: pkgtypes.GitAuth{Token: "ghp_live"}})
+	s.InsertCluster(pkgtypes.Cluster{ClusterName: "kf-gone", Status: "deleted", GitAuth: pkgtypes.GitAuth{Token: "ghp_gone", Owner: "kubefirst-test"}})
+
+	SetEncryptionKey(bytes.Repeat([]byte("k"), 32))
+	defer SetEncryptionKey(nil)
+
+	result, err := EncryptClusterRecords(nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := pkgtypes.RecordEncryptionResult{Clusters: 2, Encrypted: 1, Redacted: 1}
+	if result != want {
+		t.Errorf("expected %+v, got %+v", want, result)
+	}
+
+	if strings.Contains(string(s.clusters["kf-live"]), "ghp_live") {
+		t.Error("expected the live cluster's token to be encrypted")
+	}
+	live, _ := s.GetCluster("kf-live")
+	if live.GitAuth.Token != "ghp_live" {
+		t.Errorf("expected the live cluster's token to be kept, got %q", live.GitAuth.Token)
+	}
+	gone, _ := s.GetCluster("kf-gone")
+	if gone.GitAuth.Token != "" || gone.GitAuth.Owner != "kubefirst-test" {
+		t.Errorf("expected only the deleted cluster's token to be cleared, got %+v", gone.GitAuth)
+	}
+
+	result, _ = EncryptClusterRecords(nil, true)
+	if result.Redacted != 0 {
+		t.Errorf("expected a redacted cluster not to be redacted again, got %+v", result)
+	}
+}
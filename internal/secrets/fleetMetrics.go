@@ -0,0 +1,89 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package secrets
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kubefirst/kubefirst-api/internal/constants"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// GetFleetMetrics returns statistics across all clusters that aren't deleted
+// and the install success rate of the clusters created between from and to
+func GetFleetMetrics(clientSet *kubernetes.Clientset, from time.Time, to time.Time) (pkgtypes.FleetMetrics, error) {
+	if to.Before(from) {
+		return pkgtypes.FleetMetrics{}, fmt.Errorf("the end of the time range %s is before its start %s", to, from)
+	}
+
+	clusters, err := GetClusters(clientSet)
+	if err != nil {
+		return pkgtypes.FleetMetrics{}, err
+	}
+
+	return fleetMetrics(clusters, from, to), nil
+}
+
+// fleetMetrics aggregates the clusters in a single pass, an install counts as
+// succeeded once the cluster is provisioned, even when it's degraded since,
+// and as failed when the cluster is in error
+func fleetMetrics(clusters []pkgtypes.Cluster, from time.Time, to time.Time) pkgtypes.FleetMetrics {
+	metrics := pkgtypes.FleetMetrics{
+		From:       from.UTC(),
+		To:         to.UTC(),
+		ByStatus:   map[string]int{},
+		ByProvider: map[string]int{},
+		ByRegion:   map[string]int{},
+	}
+
+	for _, cluster := range clusters {
+		createdAt, created := clusterCreatedAt(cluster)
+		if created && !createdAt.Before(from) && !createdAt.After(to) {
+			switch cluster.Status {
+			case constants.ClusterStatusProvisioned, constants.ClusterStatusDegraded:
+				metrics.InstallsSucceeded++
+			case constants.ClusterStatusError:
+				metrics.InstallsFailed++
+			}
+		}
+
+		if cluster.Status == constants.ClusterStatusDeleted {
+			continue
+		}
+
+		metrics.Total++
+		metrics.ByStatus[cluster.Status]++
+		metrics.ByProvider[cluster.CloudProvider]++
+		metrics.ByRegion[fmt.Sprintf("%s/%s", cluster.CloudProvider, cluster.CloudRegion)]++
+		metrics.TotalNodes += cluster.NodeCount + cluster.SpotNodePool.NodeCount
+
+		if created && (metrics.OldestCluster == "" || createdAt.Before(metrics.OldestClusterCreatedAt)) {
+			metrics.OldestCluster = cluster.ClusterName
+			metrics.OldestClusterCreatedAt = createdAt
+		}
+	}
+
+	if finished := metrics.InstallsSucceeded + metrics.InstallsFailed; finished > 0 {
+		metrics.InstallSuccessRate = float64(metrics.InstallsSucceeded) / float64(finished)
+	}
+
+	return metrics
+}
+
+// clusterCreatedAt parses the creation timestamp of a cluster record, stored
+// as milliseconds since the epoch
+func clusterCreatedAt(cluster pkgtypes.Cluster) (time.Time, bool) {
+	millis, err := strconv.ParseInt(cluster.CreationTimestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.UnixMilli(millis).UTC(), true
+}
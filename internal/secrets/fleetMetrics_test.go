@@ -0,0 +1,60 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package secrets
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestGetFleetMetrics(t *testing.T) {
+	s, err := NewMemoryStore("")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	SetStore(s)
+	defer SetStore(nil)
+
+	now := time.Now().UTC()
+	createdAt := func(t time.Time) string { return fmt.Sprint(t.UnixMilli()) }
+	for _, cluster := range []pkgtypes.Cluster{
+		{ClusterName: "kf-old", Status: "provisioned", CloudProvider: "aws", CloudRegion: "us-east-1", NodeCount: 3, CreationTimestamp: createdAt(now.AddDate(0, -6, 0))},
+		{ClusterName: "kf-new", Status: "degraded", CloudProvider: "aws", CloudRegion: "us-east-1", NodeCount: 2, SpotNodePool: pkgtypes.SpotNodePool{NodeCount: 2}, CreationTimestamp: createdAt(now.AddDate(0, 0, -2))},
+		{ClusterName: "kf-failed", Status: "error", CloudProvider: "civo", CloudRegion: "nyc1", NodeCount: 4, CreationTimestamp: createdAt(now.AddDate(0, 0, -1))},
+		{ClusterName: "kf-gone", Status: "deleted", CloudProvider: "civo", CloudRegion: "nyc1", NodeCount: 4, CreationTimestamp: createdAt(now.AddDate(-1, 0, 0))},
+	} {
+		err = InsertCluster(nil, cluster)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	metrics, err := GetFleetMetrics(nil, now.AddDate(0, 0, -30), now)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if metrics.Total != 3 || metrics.ByProvider["aws"] != 2 || metrics.ByRegion["civo/nyc1"] != 1 || metrics.ByStatus["deleted"] != 0 {
+		t.Errorf("unexpected counts: %+v", metrics)
+	}
+	if metrics.TotalNodes != 11 {
+		t.Errorf("expected 11 nodes, got %d", metrics.TotalNodes)
+	}
+	if metrics.OldestCluster != "kf-old" {
+		t.Errorf("expected kf-old to be the oldest cluster, got %s", metrics.OldestCluster)
+	}
+	if metrics.InstallsSucceeded != 1 || metrics.InstallsFailed != 1 || metrics.InstallSuccessRate != 0.5 {
+		t.Errorf("expected one of two installs in the range to succeed, got %+v", metrics)
+	}
+
+	_, err = GetFleetMetrics(nil, now, now.AddDate(0, 0, -1))
+	if err == nil {
+		t.Error("expected a time range ending before it starts to be rejected")
+	}
+}
@@ -24,6 +24,10 @@ const KUBEFIRST_CATALOG_SECRET_NAME = "kubefirst-catalog"
 
 // CreateGitopsCatalogApps
 func CreateGitopsCatalogApps(clientSet *kubernetes.Clientset, catalogApps types.GitopsCatalogApps) error {
+	if store != nil {
+		return store.SaveGitopsCatalogApps(catalogApps)
+	}
+
 	bytes, _ := json.Marshal(catalogApps)
 	secretValuesMap, _ := ParseJSONToMap(string(bytes))
 
@@ -46,6 +50,10 @@ func CreateGitopsCatalogApps(clientSet *kubernetes.Clientset, catalogApps types.
 
 // GetGitopsCatalogApps
 func GetGitopsCatalogApps(clientSet *kubernetes.Clientset) (types.GitopsCatalogApps, error) {
+	if store != nil {
+		return store.GetGitopsCatalogApps()
+	}
+
 	catalogApps := types.GitopsCatalogApps{}
 
 	kubefirstSecrets, err := k8s.ReadSecretV2Old(clientSet, "kubefirst", KUBEFIRST_CATALOG_SECRET_NAME)
@@ -101,6 +109,9 @@ func UpdateGitopsCatalogApps(clientSet *kubernetes.Clientset) error {
 		}
 	} else {
 		catalogApps.Apps = mpapps.Apps
+		if store != nil {
+			return store.SaveGitopsCatalogApps(catalogApps)
+		}
 
 		bytes, _ := json.Marshal(catalogApps)
 		secretValuesMap, _ := ParseJSONToMap(string(bytes))
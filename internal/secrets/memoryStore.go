@@ -0,0 +1,373 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/kubefirst/kubefirst-api/pkg/types"
+	log "github.com/rs/zerolog/log"
+)
+
+// MemoryStore keeps the records in memory for tests and single node
+// installs, with a path they are also written to a json file after every
+// change and read back on startup
+type MemoryStore struct {
+	mu   sync.Mutex
+	path string
+
+	clusterNames []string
+	clusters     map[string]json.RawMessage
+	services     map[string]types.ClusterServiceList
+	catalogApps  *types.GitopsCatalogApps
+	operations   []types.Operation
+}
+
+// memoryStoreFile is the json file a MemoryStore persists to
+type memoryStoreFile struct {
+	Clusters    []json.RawMessage                   `json:"clusters"`
+	Services    map[string]types.ClusterServiceList `json:"services"`
+	CatalogApps *types.GitopsCatalogApps            `json:"catalog_apps,omitempty"`
+	Operations  []types.Operation                   `json:"operations,omitempty"`
+}
+
+// NewMemoryStore returns an empty MemoryStore, or one holding the records
+// of the file at path when it exists
+func NewMemoryStore(path string) (*MemoryStore, error) {
+	s := &MemoryStore{
+		path:         path,
+		clusterNames: []string{},
+		clusters:     map[string]json.RawMessage{},
+		services:     map[string]types.ClusterServiceList{},
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading store %s: %s", path, err)
+	}
+
+	file := memoryStoreFile{}
+	err = json.Unmarshal(content, &file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading store %s: %s", path, err)
+	}
+
+	for _, record := range file.Clusters {
+		cl, err := unmarshalClusterRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("error reading store %s: %s", path, err)
+		}
+		if _, ok := s.clusters[cl.ClusterName]; ok {
+			return nil, fmt.Errorf("error reading store %s: cluster %s is stored twice", path, cl.ClusterName)
+		}
+		s.clusterNames = append(s.clusterNames, cl.ClusterName)
+		err = s.putCluster(cl)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if file.Services != nil {
+		s.services = file.Services
+	}
+	s.catalogApps = file.CatalogApps
+	s.operations = file.Operations
+
+	log.Info().Msgf("read %d clusters from store %s", len(s.clusterNames), path)
+
+	return s, nil
+}
+
+func (s *MemoryStore) GetCluster(clusterName string) (types.Cluster, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.clusters[clusterName]
+	if !ok {
+		return types.Cluster{}, fmt.Errorf("cluster %s not found", clusterName)
+	}
+
+	return unmarshalClusterRecord(record)
+}
+
+func (s *MemoryStore) GetClusters() ([]types.Cluster, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clusterList := []types.Cluster{}
+	for _, clusterName := range s.clusterNames {
+		cl, err := unmarshalClusterRecord(s.clusters[clusterName])
+		if err != nil {
+			return nil, err
+		}
+		clusterList = append(clusterList, cl)
+	}
+
+	return clusterList, nil
+}
+
+func (s *MemoryStore) InsertCluster(cl types.Cluster) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.clusters[cl.ClusterName]; ok {
+		return fmt.Errorf("cluster %s already exists", cl.ClusterName)
+	}
+	s.clusterNames = append(s.clusterNames, cl.ClusterName)
+
+	err := s.putCluster(cl)
+	if err != nil {
+		return err
+	}
+
+	return s.save()
+}
+
+func (s *MemoryStore) UpdateCluster(cl types.Cluster) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.clusters[cl.ClusterName]; !ok {
+		return fmt.Errorf("cluster %s not found", cl.ClusterName)
+	}
+
+	err := s.putCluster(cl)
+	if err != nil {
+		return err
+	}
+
+	return s.save()
+}
+
+func (s *MemoryStore) DeleteCluster(clusterName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.clusters[clusterName]; !ok {
+		return fmt.Errorf("cluster %s not found", clusterName)
+	}
+	delete(s.clusters, clusterName)
+
+	clusterNames := []string{}
+	for _, name := range s.clusterNames {
+		if name != clusterName {
+			clusterNames = append(clusterNames, name)
+		}
+	}
+	s.clusterNames = clusterNames
+
+	return s.save()
+}
+
+func (s *MemoryStore) GetServices(clusterName string) (types.ClusterServiceList, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clusterServices, ok := s.services[clusterName]
+	if !ok {
+		return types.ClusterServiceList{}, nil
+	}
+	clusterServices.Services = append([]types.Service{}, clusterServices.Services...)
+
+	return clusterServices, nil
+}
+
+func (s *MemoryStore) SaveServices(clusterServices types.ClusterServiceList) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clusterServices.Services = append([]types.Service{}, clusterServices.Services...)
+	s.services[clusterServices.ClusterName] = clusterServices
+
+	return s.save()
+}
+
+func (s *MemoryStore) DeleteServices(clusterName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.services, clusterName)
+
+	return s.save()
+}
+
+func (s *MemoryStore) GetGitopsCatalogApps() (types.GitopsCatalogApps, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.catalogApps == nil {
+		return types.GitopsCatalogApps{}, fmt.Errorf("gitops catalog not found")
+	}
+	catalogApps := *s.catalogApps
+	catalogApps.Apps = append([]types.GitopsCatalogApp{}, catalogApps.Apps...)
+
+	return catalogApps, nil
+}
+
+func (s *MemoryStore) SaveGitopsCatalogApps(catalogApps types.GitopsCatalogApps) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	catalogApps.Apps = append([]types.GitopsCatalogApp{}, catalogApps.Apps...)
+	s.catalogApps = &catalogApps
+
+	return s.save()
+}
+
+func (s *MemoryStore) GetOperation(id string) (types.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, operation := range s.operations {
+		if operation.ID == id {
+			return operation, nil
+		}
+	}
+
+	return types.Operation{}, fmt.Errorf("operation %s not found", id)
+}
+
+func (s *MemoryStore) GetOperations(clusterName string) ([]types.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	operations := []types.Operation{}
+	for _, operation := range s.operations {
+		if clusterName == "" || operation.ClusterName == clusterName {
+			operations = append(operations, operation)
+		}
+	}
+
+	return operations, nil
+}
+
+func (s *MemoryStore) InsertOperation(operation types.Operation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.operations {
+		if existing.ID == operation.ID {
+			return fmt.Errorf("operation %s already exists", operation.ID)
+		}
+	}
+	s.operations = append(s.operations, operation)
+
+	return s.save()
+}
+
+func (s *MemoryStore) UpdateOperation(operation types.Operation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.operations {
+		if existing.ID == operation.ID {
+			s.operations[i] = operation
+			return s.save()
+		}
+	}
+
+	return fmt.Errorf("operation %s not found", operation.ID)
+}
+
+// putCluster stores a copy of the cluster at the current schema version, so
+// later changes by the caller don't leak into the store
+func (s *MemoryStore) putCluster(cl types.Cluster) error {
+	record, err := marshalClusterRecord(cl)
+	if err != nil {
+		return err
+	}
+	s.clusters[cl.ClusterName] = record
+
+	return nil
+}
+
+// save writes the records to the store file, replacing it in one rename so
+// a crash never leaves a partial file behind
+func (s *MemoryStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	file := memoryStoreFile{
+		Clusters:    []json.RawMessage{},
+		Services:    s.services,
+		CatalogApps: s.catalogApps,
+		Operations:  s.operations,
+	}
+	for _, clusterName := range s.clusterNames {
+		file.Clusters = append(file.Clusters, s.clusters[clusterName])
+	}
+	content, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("error marshalling store: %s", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("error writing store %s: %s", s.path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Chmod(0o600)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing store %s: %s", s.path, err)
+	}
+
+	err = os.Rename(tmp.Name(), s.path)
+	if err != nil {
+		return fmt.Errorf("error writing store %s: %s", s.path, err)
+	}
+
+	return nil
+}
+
+// unmarshalClusterRecord reads a stored cluster, decrypting its sensitive
+// fields and migrating records written by older versions
+func unmarshalClusterRecord(record json.RawMessage) (types.Cluster, error) {
+	cl := types.Cluster{}
+
+	fields := map[string]interface{}{}
+	err := json.Unmarshal(record, &fields)
+	if err != nil {
+		return cl, fmt.Errorf("unable to cast cluster: %s", err)
+	}
+	_, err = decryptClusterRecord(fields)
+	if err != nil {
+		return cl, err
+	}
+	_, err = MigrateClusterRecord(fields)
+	if err != nil {
+		return cl, err
+	}
+
+	migrated, err := json.Marshal(fields)
+	if err != nil {
+		return cl, fmt.Errorf("error marshalling json: %s", err)
+	}
+	err = json.Unmarshal(migrated, &cl)
+	if err != nil {
+		return cl, fmt.Errorf("unable to cast cluster: %s", err)
+	}
+
+	return cl, nil
+}
@@ -0,0 +1,193 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package secrets
+
+import (
+	"path/filepath"
+	"testing"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestMemoryStoreClusters(t *testing.T) {
+	s, err := NewMemoryStore("")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	SetStore(s)
+	defer SetStore(nil)
+
+	err = InsertCluster(nil, pkgtypes.Cluster{ClusterName: "kf-one", Status: "provisioning", Tags: map[string]string{"team": "a"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = InsertCluster(nil, pkgtypes.Cluster{ClusterName: "kf-two", Status: "provisioned"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = InsertCluster(nil, pkgtypes.Cluster{ClusterName: "kf-one"})
+	if err == nil {
+		t.Fatal("expected an error inserting an existing cluster")
+	}
+
+	cl, err := GetCluster(nil, "kf-one")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cl.SchemaVersion != pkgtypes.ClusterSchemaVersion {
+		t.Errorf("expected schema version %d, got %d", pkgtypes.ClusterSchemaVersion, cl.SchemaVersion)
+	}
+
+	// changing a read cluster must not change the stored one
+	cl.Tags["team"] = "b"
+	cl.Status = "provisioned"
+	again, _ := GetCluster(nil, "kf-one")
+	if again.Status != "provisioning" || again.Tags["team"] != "a" {
+		t.Errorf("stored cluster changed without an update: %+v", again)
+	}
+
+	err = UpdateCluster(nil, cl)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tagged, err := GetClustersByTags(nil, map[string]string{"team": "b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(tagged) != 1 || tagged[0].ClusterName != "kf-one" {
+		t.Errorf("expected kf-one tagged team=b, got %v", tagged)
+	}
+
+	err = UpdateCluster(nil, pkgtypes.Cluster{ClusterName: "kf-missing"})
+	if err == nil {
+		t.Fatal("expected an error updating a missing cluster")
+	}
+
+	err = DeleteCluster(nil, "kf-one")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	clusters, _ := GetClusters(nil)
+	if len(clusters) != 1 || clusters[0].ClusterName != "kf-two" {
+		t.Errorf("expected only kf-two, got %v", clusters)
+	}
+	_, err = GetCluster(nil, "kf-one")
+	if err == nil {
+		t.Fatal("expected an error getting a deleted cluster")
+	}
+}
+
+func TestMemoryStoreServices(t *testing.T) {
+	s, _ := NewMemoryStore("")
+	SetStore(s)
+	defer SetStore(nil)
+
+	err := InsertClusterServiceListEntry(nil, "kf-test", &pkgtypes.Service{Name: "argocd"})
+	if err == nil {
+		t.Fatal("expected an error adding a service without a service list")
+	}
+
+	err = CreateClusterServiceList(nil, "kf-test")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, name := range []string{"argocd", "vault"} {
+		err = InsertClusterServiceListEntry(nil, "kf-test", &pkgtypes.Service{Name: name})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	err = DeleteClusterServiceListEntry(nil, "kf-test", &pkgtypes.Service{Name: "argocd"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	clusterServices, _ := GetServices(nil, "kf-test")
+	if len(clusterServices.Services) != 1 || clusterServices.Services[0].Name != "vault" {
+		t.Errorf("expected only vault, got %v", clusterServices.Services)
+	}
+	_, err = GetService(nil, "kf-test", "vault")
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestMemoryStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+
+	s, err := NewMemoryStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = s.InsertCluster(pkgtypes.Cluster{ClusterName: "kf-test", DomainName: "example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = s.SaveServices(pkgtypes.ClusterServiceList{ClusterName: "kf-test", Services: []pkgtypes.Service{{Name: "vault"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = s.SaveGitopsCatalogApps(pkgtypes.GitopsCatalogApps{Apps: []pkgtypes.GitopsCatalogApp{{Name: "datadog"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	reopened, err := NewMemoryStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cl, err := reopened.GetCluster("kf-test")
+	if err != nil || cl.DomainName != "example.com" {
+		t.Errorf("expected kf-test with its domain, got %+v (%v)", cl, err)
+	}
+	clusterServices, _ := reopened.GetServices("kf-test")
+	if len(clusterServices.Services) != 1 {
+		t.Errorf("expected the saved service, got %v", clusterServices.Services)
+	}
+	catalogApps, err := reopened.GetGitopsCatalogApps()
+	if err != nil || len(catalogApps.Apps) != 1 {
+		t.Errorf("expected the saved catalog, got %v (%v)", catalogApps.Apps, err)
+	}
+}
+
+func TestMemoryStoreOperations(t *testing.T) {
+	s, _ := NewMemoryStore("")
+	SetStore(s)
+	defer SetStore(nil)
+
+	for _, operation := range []pkgtypes.Operation{
+		{ID: "op-1", ClusterName: "kf-one", Status: "pending"},
+		{ID: "op-2", ClusterName: "kf-two", Status: "pending"},
+	} {
+		err := InsertOperation(nil, operation)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	err := InsertOperation(nil, pkgtypes.Operation{ID: "op-1"})
+	if err == nil {
+		t.Fatal("expected an error inserting an existing operation")
+	}
+
+	err = UpdateOperation(nil, pkgtypes.Operation{ID: "op-1", ClusterName: "kf-one", Status: "succeeded"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	operation, err := GetOperation(nil, "op-1")
+	if err != nil || operation.Status != "succeeded" {
+		t.Errorf("expected the updated operation, got %+v (%v)", operation, err)
+	}
+
+	operations, _ := GetOperations(nil, "kf-two")
+	if len(operations) != 1 || operations[0].ID != "op-2" {
+		t.Errorf("expected only op-2 for kf-two, got %v", operations)
+	}
+	operations, _ = GetOperations(nil, "")
+	if len(operations) != 2 {
+		t.Errorf("expected every operation, got %v", operations)
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package secrets
+
+import (
+	"fmt"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// clusterMigration upgrades a stored cluster record by one schema version
+type clusterMigration func(record map[string]interface{})
+
+// clusterMigrations holds the migration from schema version i to i+1 at
+// index i
+var clusterMigrations = []clusterMigration{
+	migrateClusterV0ToV1,
+}
+
+// MigrateClusterRecord upgrades a stored cluster record to
+// pkgtypes.ClusterSchemaVersion and reports whether anything was changed
+func MigrateClusterRecord(record map[string]interface{}) (bool, error) {
+	version, err := recordSchemaVersion(record)
+	if err != nil {
+		return false, err
+	}
+
+	err = pkgtypes.ValidateSchemaVersion(version)
+	if err != nil {
+		return false, err
+	}
+
+	if version == pkgtypes.ClusterSchemaVersion {
+		return false, nil
+	}
+
+	for v := version; v < pkgtypes.ClusterSchemaVersion; v++ {
+		clusterMigrations[v](record)
+		record["schema_version"] = v + 1
+	}
+
+	return true, nil
+}
+
+// recordSchemaVersion returns the schema version of a stored cluster record,
+// records written before versioning was introduced are version 0
+func recordSchemaVersion(record map[string]interface{}) (int, error) {
+	value, ok := record["schema_version"]
+	if !ok || value == nil {
+		return 0, nil
+	}
+
+	switch v := value.(type) {
+	case float64:
+		return int(v), nil
+	case int:
+		return v, nil
+	default:
+		return 0, fmt.Errorf("invalid schema_version %v", value)
+	}
+}
+
+// migrateClusterV0ToV1 fills in fields that unversioned records could omit
+// because older releases only supported a single value for them
+func migrateClusterV0ToV1(record map[string]interface{}) {
+	if s, _ := record["git_protocol"].(string); s == "" {
+		record["git_protocol"] = "ssh"
+	}
+	if s, _ := record["cluster_type"].(string); s == "" {
+		record["cluster_type"] = "mgmt"
+	}
+}
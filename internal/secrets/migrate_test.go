@@ -0,0 +1,91 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package secrets
+
+import (
+	"reflect"
+	"testing"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestMigrateClusterRecord(t *testing.T) {
+	tests := []struct {
+		name         string
+		record       map[string]interface{}
+		want         map[string]interface{}
+		wantMigrated bool
+		wantErr      bool
+	}{
+		{
+			name: "unversioned record is migrated to v1",
+			record: map[string]interface{}{
+				"cluster_name": "kubefirst",
+			},
+			want: map[string]interface{}{
+				"cluster_name":   "kubefirst",
+				"git_protocol":   "ssh",
+				"cluster_type":   "mgmt",
+				"schema_version": 1,
+			},
+			wantMigrated: true,
+		},
+		{
+			name: "v0 migration keeps existing values",
+			record: map[string]interface{}{
+				"cluster_name":   "kubefirst",
+				"git_protocol":   "https",
+				"cluster_type":   "workload",
+				"schema_version": float64(0),
+			},
+			want: map[string]interface{}{
+				"cluster_name":   "kubefirst",
+				"git_protocol":   "https",
+				"cluster_type":   "workload",
+				"schema_version": 1,
+			},
+			wantMigrated: true,
+		},
+		{
+			name: "current record is unchanged",
+			record: map[string]interface{}{
+				"cluster_name":   "kubefirst",
+				"schema_version": float64(pkgtypes.ClusterSchemaVersion),
+			},
+			want: map[string]interface{}{
+				"cluster_name":   "kubefirst",
+				"schema_version": float64(pkgtypes.ClusterSchemaVersion),
+			},
+			wantMigrated: false,
+		},
+		{
+			name: "future record is rejected",
+			record: map[string]interface{}{
+				"cluster_name":   "kubefirst",
+				"schema_version": float64(pkgtypes.ClusterSchemaVersion + 1),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrated, err := MigrateClusterRecord(tt.record)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MigrateClusterRecord() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if migrated != tt.wantMigrated {
+				t.Errorf("MigrateClusterRecord() migrated = %v, want %v", migrated, tt.wantMigrated)
+			}
+			if !reflect.DeepEqual(tt.record, tt.want) {
+				t.Errorf("MigrateClusterRecord() record = %v, want %v", tt.record, tt.want)
+			}
+		})
+	}
+}
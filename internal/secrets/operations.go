@@ -0,0 +1,139 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kubefirst/kubefirst-api/internal/k8s"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const KUBEFIRST_OPERATION_PREFIX = "kubefirst-operation"
+
+// operationClusterLabel links an operation secret to its cluster so a
+// cluster's operations can be listed with a label selector
+const operationClusterLabel = "operations.kubefirst.io/cluster"
+
+// GetOperation
+func GetOperation(clientSet *kubernetes.Clientset, id string) (pkgtypes.Operation, error) {
+	if store != nil {
+		return store.GetOperation(id)
+	}
+
+	operation := pkgtypes.Operation{}
+
+	operationSecret, err := k8s.ReadSecretV2Old(clientSet, "kubefirst", fmt.Sprintf("%s-%s", KUBEFIRST_OPERATION_PREFIX, id))
+	if err != nil {
+		return operation, fmt.Errorf("operation %s not found: %s", id, err)
+	}
+	jsonString, _ := MapToStructuredJSON(operationSecret)
+
+	jsonData, err := json.Marshal(jsonString)
+	if err != nil {
+		return operation, fmt.Errorf("error marshalling json: %s", err)
+	}
+
+	err = json.Unmarshal(jsonData, &operation)
+	if err != nil {
+		return operation, fmt.Errorf("unable to cast operation %s: %s", id, err)
+	}
+
+	return operation, nil
+}
+
+// GetOperations returns the operations of a cluster oldest first, or those
+// of every cluster when clusterName is empty
+func GetOperations(clientSet *kubernetes.Clientset, clusterName string) ([]pkgtypes.Operation, error) {
+	if store != nil {
+		return store.GetOperations(clusterName)
+	}
+
+	selector := operationClusterLabel
+	if clusterName != "" {
+		selector = fmt.Sprintf("%s=%s", operationClusterLabel, clusterName)
+	}
+	secretNames, err := k8s.ListSecretNamesV2(clientSet, "kubefirst", selector)
+	if err != nil {
+		return nil, fmt.Errorf("error listing operations: %s", err)
+	}
+
+	operations := []pkgtypes.Operation{}
+	for _, secretName := range secretNames {
+		operation, err := GetOperation(clientSet, strings.TrimPrefix(secretName, KUBEFIRST_OPERATION_PREFIX+"-"))
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, operation)
+	}
+	sort.SliceStable(operations, func(i, j int) bool {
+		return operations[i].CreatedAt.Before(operations[j].CreatedAt)
+	})
+
+	return operations, nil
+}
+
+// InsertOperation
+func InsertOperation(clientSet *kubernetes.Clientset, operation pkgtypes.Operation) error {
+	if store != nil {
+		return store.InsertOperation(operation)
+	}
+
+	bytes, err := json.Marshal(operation)
+	if err != nil {
+		return fmt.Errorf("error marshalling operation %s: %s", operation.ID, err)
+	}
+	secretValuesMap, _ := ParseJSONToMap(string(bytes))
+
+	secretToCreate := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", KUBEFIRST_OPERATION_PREFIX, operation.ID),
+			Namespace: "kubefirst",
+			Labels:    map[string]string{operationClusterLabel: operation.ClusterName},
+		},
+		Data: secretValuesMap,
+	}
+
+	err = k8s.CreateSecretV2(clientSet, secretToCreate)
+	if err != nil {
+		return fmt.Errorf("error creating kubernetes secret: %s", err)
+	}
+
+	return nil
+}
+
+// UpdateOperation
+func UpdateOperation(clientSet *kubernetes.Clientset, operation pkgtypes.Operation) error {
+	if store != nil {
+		return store.UpdateOperation(operation)
+	}
+
+	bytes, err := json.Marshal(operation)
+	if err != nil {
+		return fmt.Errorf("error marshalling operation %s: %s", operation.ID, err)
+	}
+	secretValuesMap, _ := ParseJSONToMap(string(bytes))
+
+	err = k8s.UpdateSecretV2(clientSet, "kubefirst", fmt.Sprintf("%s-%s", KUBEFIRST_OPERATION_PREFIX, operation.ID), secretValuesMap)
+	if err != nil {
+		return fmt.Errorf("error updating kubernetes secret: %s", err)
+	}
+
+	// the label follows the cluster when it's renamed
+	err = k8s.UpdateSecretLabelsV2(clientSet, "kubefirst", fmt.Sprintf("%s-%s", KUBEFIRST_OPERATION_PREFIX, operation.ID), operationClusterLabel, map[string]string{operationClusterLabel: operation.ClusterName})
+	if err != nil {
+		return fmt.Errorf("error updating kubernetes secret labels: %s", err)
+	}
+
+	return nil
+}
@@ -29,6 +29,9 @@ func CreateClusterServiceList(clientSet *kubernetes.Clientset, clusterName strin
 			ClusterName: clusterName,
 			Services:    []types.Service{},
 		}
+		if store != nil {
+			return store.SaveServices(clusterServices)
+		}
 
 		bytes, _ := json.Marshal(clusterServices)
 		secretValuesMap, _ := ParseJSONToMap(string(bytes))
@@ -65,6 +68,12 @@ func DeleteClusterServiceListEntry(clientSet *kubernetes.Clientset, clusterName
 	}
 
 	clusterServices.Services = filteredServiceList
+	if store != nil {
+		if clusterServices.ClusterName == "" {
+			return fmt.Errorf("error deleting service list entry %s: no service list for cluster %s", def.Name, clusterName)
+		}
+		return store.SaveServices(clusterServices)
+	}
 
 	bytes, err := json.Marshal(clusterServices)
 	secretValuesMap, _ := ParseJSONToMap(string(bytes))
@@ -95,6 +104,10 @@ func GetService(clientSet *kubernetes.Clientset, clusterName string, serviceName
 
 // GetServices returns services associated with a given cluster
 func GetServices(clientSet *kubernetes.Clientset, clusterName string) (types.ClusterServiceList, error) {
+	if store != nil {
+		return store.GetServices(clusterName)
+	}
+
 	clusterServices := types.ClusterServiceList{}
 
 	kubefirstSecrets, err := k8s.ReadSecretV2Old(clientSet, "kubefirst", fmt.Sprintf("%s-%s", KUBEFIRST_SERVICES_PREFIX, clusterName))
@@ -119,6 +132,12 @@ func InsertClusterServiceListEntry(clientSet *kubernetes.Clientset, clusterName
 	// Find
 	clusterServices, err := GetServices(clientSet, clusterName)
 	clusterServices.Services = append(clusterServices.Services, *def)
+	if store != nil {
+		if clusterServices.ClusterName == "" {
+			return fmt.Errorf("error adding service list entry %s: no service list for cluster %s", def.Name, clusterName)
+		}
+		return store.SaveServices(clusterServices)
+	}
 
 	bytes, err := json.Marshal(clusterServices)
 	secretValuesMap, _ := ParseJSONToMap(string(bytes))
@@ -132,3 +151,40 @@ func InsertClusterServiceListEntry(clientSet *kubernetes.Clientset, clusterName
 
 	return nil
 }
+
+// RenameClusterServiceList moves a cluster's service list from oldName to
+// newName, a cluster without a service list is left alone
+func RenameClusterServiceList(clientSet *kubernetes.Clientset, oldName string, newName string) error {
+	clusterServices, err := GetServices(clientSet, oldName)
+	if err != nil || clusterServices.ClusterName == "" {
+		return nil
+	}
+	clusterServices.ClusterName = newName
+
+	if store != nil {
+		err = store.SaveServices(clusterServices)
+		if err != nil {
+			return err
+		}
+		return store.DeleteServices(oldName)
+	}
+
+	bytes, err := json.Marshal(clusterServices)
+	if err != nil {
+		return fmt.Errorf("error marshalling service list of cluster %s: %s", newName, err)
+	}
+	secretValuesMap, _ := ParseJSONToMap(string(bytes))
+
+	err = k8s.CreateSecretV2(clientSet, &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", KUBEFIRST_SERVICES_PREFIX, newName),
+			Namespace: "kubefirst",
+		},
+		Data: secretValuesMap,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating kubernetes service secret: %s", err)
+	}
+
+	return k8s.DeleteSecretV2(clientSet, "kubefirst", fmt.Sprintf("%s-%s", KUBEFIRST_SERVICES_PREFIX, oldName))
+}
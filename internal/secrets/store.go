@@ -0,0 +1,44 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package secrets
+
+import (
+	"github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// Store is an alternate backend for the records this package otherwise
+// keeps as secrets in the kubefirst namespace. With a store set, the cluster,
+// service, gitops catalog and operation functions use it and ignore their
+// clientSet
+type Store interface {
+	GetCluster(clusterName string) (types.Cluster, error)
+	GetClusters() ([]types.Cluster, error)
+	InsertCluster(cl types.Cluster) error
+	UpdateCluster(cl types.Cluster) error
+	DeleteCluster(clusterName string) error
+
+	GetServices(clusterName string) (types.ClusterServiceList, error)
+	SaveServices(clusterServices types.ClusterServiceList) error
+	DeleteServices(clusterName string) error
+
+	GetGitopsCatalogApps() (types.GitopsCatalogApps, error)
+	SaveGitopsCatalogApps(catalogApps types.GitopsCatalogApps) error
+
+	GetOperation(id string) (types.Operation, error)
+	GetOperations(clusterName string) ([]types.Operation, error)
+	InsertOperation(operation types.Operation) error
+	UpdateOperation(operation types.Operation) error
+}
+
+// store is nil while records are kept in kubernetes secrets
+var store Store
+
+// SetStore switches the records to s, nil switches back to kubernetes
+// secrets. It is meant to be called once at startup
+func SetStore(s Store) {
+	store = s
+}
@@ -0,0 +1,88 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package services
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-git/go-git/v5"
+	githttps "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/kubefirst/kubefirst-api/internal/gitClient"
+	"github.com/kubefirst/kubefirst-api/internal/gitShim"
+	"github.com/kubefirst/kubefirst-api/internal/github"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	log "github.com/rs/zerolog/log"
+)
+
+// AddRegistryApplication commits an argocd Application manifest to the
+// cluster's gitops registry as <name>.yaml so the registry app reconciles it
+func AddRegistryApplication(cl *pkgtypes.Cluster, name string, manifest []byte, user string) error {
+	// GitHub App installation tokens expire, the stored one may be stale
+	_, err := github.RefreshGitAuth(&cl.GitAuth)
+	if err != nil {
+		return err
+	}
+
+	homeDir, _ := os.UserHomeDir()
+	tmpGitopsDir := fmt.Sprintf("%s/.k1/%s/%s/gitops", homeDir, cl.ClusterName, name)
+
+	err = os.RemoveAll(tmpGitopsDir)
+	if err != nil {
+		return fmt.Errorf("error removing gitops dir %s: %s", tmpGitopsDir, err)
+	}
+
+	err = gitShim.PrepareGitEnvironment(cl, tmpGitopsDir)
+	if err != nil {
+		return fmt.Errorf("cluster %s - error preparing git environment %s: %s", cl.ClusterName, tmpGitopsDir, err)
+	}
+
+	gitopsRepo, err := git.PlainOpen(tmpGitopsDir)
+	if err != nil {
+		return fmt.Errorf("cluster %s - error opening gitops repo: %s", cl.ClusterName, err)
+	}
+
+	auth := &githttps.BasicAuth{
+		Username: cl.GitAuth.User,
+		Password: cl.GitAuth.Token,
+	}
+
+	err = gitShim.PullWithAuth(gitopsRepo, "origin", gitClient.BranchOrDefault(cl.DefaultBranch), auth)
+	if err != nil {
+		log.Warn().Msgf("cluster %s - error pulling gitops repo: %s", cl.ClusterName, err)
+	}
+
+	registryPath := fmt.Sprintf("%s/%s", tmpGitopsDir, getRegistryPath(cl.ClusterName, cl.CloudProvider, false))
+	applicationFile := fmt.Sprintf("%s/%s.yaml", registryPath, name)
+	if _, err := os.Stat(applicationFile); err == nil {
+		return fmt.Errorf("cluster %s - registry already contains %s.yaml", cl.ClusterName, name)
+	}
+
+	err = os.MkdirAll(registryPath, 0755)
+	if err != nil {
+		return fmt.Errorf("cluster %s - error creating registry directory: %s", cl.ClusterName, err)
+	}
+	err = os.WriteFile(applicationFile, manifest, 0644)
+	if err != nil {
+		return fmt.Errorf("cluster %s - error writing %s: %s", cl.ClusterName, applicationFile, err)
+	}
+
+	err = gitClient.Commit(gitopsRepo, fmt.Sprintf("adding %s to the cluster %s on behalf of %s", name, cl.ClusterName, user))
+	if err != nil {
+		return fmt.Errorf("cluster %s - error committing %s: %s", cl.ClusterName, applicationFile, err)
+	}
+
+	err = gitopsRepo.Push(&git.PushOptions{
+		RemoteName: "origin",
+		Auth:       auth,
+	})
+	if err != nil {
+		return fmt.Errorf("cluster %s - error pushing commit for %s: %s", cl.ClusterName, applicationFile, err)
+	}
+
+	return nil
+}
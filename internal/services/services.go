@@ -22,8 +22,10 @@ import (
 	"github.com/go-git/go-git/v5"
 	githttps "github.com/go-git/go-git/v5/plumbing/transport/http"
 	vaultapi "github.com/hashicorp/vault/api"
+	pkg "github.com/kubefirst/kubefirst-api/internal"
 	"github.com/kubefirst/kubefirst-api/internal/constants"
 	"github.com/kubefirst/kubefirst-api/internal/gitShim"
+	"github.com/kubefirst/kubefirst-api/internal/github"
 	"github.com/kubefirst/kubefirst-api/internal/secrets"
 	internalutils "github.com/kubefirst/kubefirst-api/internal/utils"
 	"github.com/kubefirst/kubefirst-api/pkg/common"
@@ -47,12 +49,18 @@ func CreateService(cl *pkgtypes.Cluster, serviceName string, appDef *pkgtypes.Gi
 		return fmt.Errorf("cluster %s - unable to deploy service %s to cluster: cannot deploy services to a cluster in %s state", cl.ClusterName, serviceName, cl.Status)
 	}
 
+	// GitHub App installation tokens expire, the stored one may be stale
+	_, err := github.RefreshGitAuth(&cl.GitAuth)
+	if err != nil {
+		return err
+	}
+
 	homeDir, _ := os.UserHomeDir()
 	tmpGitopsDir := fmt.Sprintf("%s/.k1/%s/%s/gitops", homeDir, cl.ClusterName, serviceName)
 	tmpGitopsCatalogDir := fmt.Sprintf("%s/.k1/%s/%s/gitops-catalog", homeDir, cl.ClusterName, serviceName)
 
 	// Remove gitops dir
-	err := os.RemoveAll(tmpGitopsDir)
+	err = os.RemoveAll(tmpGitopsDir)
 	if err != nil {
 		log.Fatal().Msgf("error removing gitops dir %s: %s", tmpGitopsDir, err)
 		return err
@@ -148,7 +156,7 @@ func CreateService(cl *pkgtypes.Cluster, serviceName string, appDef *pkgtypes.Gi
 	err = gitShim.PullWithAuth(
 		gitopsRepo,
 		"origin",
-		"main",
+		gitClient.BranchOrDefault(cl.DefaultBranch),
 		&githttps.BasicAuth{
 			Username: cl.GitAuth.User,
 			Password: cl.GitAuth.Token,
@@ -201,18 +209,23 @@ func CreateService(cl *pkgtypes.Cluster, serviceName string, appDef *pkgtypes.Gi
 		return fmt.Errorf("cluster %s - error pushing commit for service file: %s", clusterName, err)
 	}
 
-	existingService, _ := secrets.GetServices(kcfg.Clientset, clusterName)
+	storeKcfg, err := internalutils.GetClusterStoreClient(cl.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	existingService, _ := secrets.GetServices(storeKcfg.Clientset, clusterName)
 
 	if existingService.ClusterName == "" {
 		// Add to list
-		err = secrets.CreateClusterServiceList(kcfg.Clientset, clusterName)
+		err = secrets.CreateClusterServiceList(storeKcfg.Clientset, clusterName)
 		if err != nil {
 			return err
 		}
 	}
 
 	// Update list
-	err = secrets.InsertClusterServiceListEntry(kcfg.Clientset, clusterName, &pkgtypes.Service{
+	err = secrets.InsertClusterServiceListEntry(storeKcfg.Clientset, clusterName, &pkgtypes.Service{
 		Name:        serviceName,
 		Default:     false,
 		Description: appDef.Description,
@@ -236,7 +249,7 @@ func CreateService(cl *pkgtypes.Cluster, serviceName string, appDef *pkgtypes.Gi
 	}
 
 	// Sync registry
-	argoCDHost := fmt.Sprintf("https://argocd.%s", fullDomainName)
+	argoCDHost := fmt.Sprintf("https://%s", argoCDHostname(cl, fullDomainName))
 	if cl.CloudProvider == "k3d" {
 		argoCDHost = "http://argocd-server.argocd.svc.cluster.local"
 	}
@@ -291,13 +304,22 @@ func CreateService(cl *pkgtypes.Cluster, serviceName string, appDef *pkgtypes.Gi
 func DeleteService(cl *pkgtypes.Cluster, serviceName string, def pkgtypes.GitopsCatalogAppDeleteRequest) error {
 	var gitopsRepo *git.Repository
 
+	// GitHub App installation tokens expire, the stored one may be stale
+	_, err := github.RefreshGitAuth(&cl.GitAuth)
+	if err != nil {
+		return err
+	}
+
 	clusterName := cl.ClusterName
 
 	if def.WorkloadClusterName != "" {
 		clusterName = def.WorkloadClusterName
 	}
 
-	kcfg := internalutils.GetKubernetesClient(clusterName)
+	kcfg, err := internalutils.GetClusterStoreClient(clusterName)
+	if err != nil {
+		return err
+	}
 
 	// Remove from list
 	svc, err := secrets.GetService(kcfg.Clientset, clusterName, serviceName)
@@ -331,7 +353,7 @@ func DeleteService(cl *pkgtypes.Cluster, serviceName string, def pkgtypes.Gitops
 		err = gitShim.PullWithAuth(
 			gitopsRepo,
 			cl.GitProvider,
-			"main",
+			gitClient.BranchOrDefault(cl.DefaultBranch),
 			&githttps.BasicAuth{
 				Username: cl.GitAuth.User,
 				Password: cl.GitAuth.Token,
@@ -397,6 +419,12 @@ func ValidateService(cl *pkgtypes.Cluster, serviceName string, def *pkgtypes.Git
 
 	var gitopsRepo *git.Repository
 
+	// GitHub App installation tokens expire, the stored one may be stale
+	_, err := github.RefreshGitAuth(&cl.GitAuth)
+	if err != nil {
+		return err, false
+	}
+
 	clusterName := cl.ClusterName
 
 	if def.WorkloadClusterName != "" {
@@ -407,7 +435,7 @@ func ValidateService(cl *pkgtypes.Cluster, serviceName string, def *pkgtypes.Git
 	tmpGitopsDir := fmt.Sprintf("%s/.k1/%s/%s/gitops", homeDir, cl.ClusterName, serviceName)
 
 	// Remove gitops dir
-	err := os.RemoveAll(tmpGitopsDir)
+	err = os.RemoveAll(tmpGitopsDir)
 	if err != nil {
 		log.Fatal().Msgf("error removing gitops dir %s: %s", tmpGitopsDir, err)
 		return err, false
@@ -427,7 +455,7 @@ func ValidateService(cl *pkgtypes.Cluster, serviceName string, def *pkgtypes.Git
 	err = gitShim.PullWithAuth(
 		gitopsRepo,
 		cl.GitProvider,
-		"main",
+		gitClient.BranchOrDefault(cl.DefaultBranch),
 		&githttps.BasicAuth{
 			Username: cl.GitAuth.User,
 			Password: cl.GitAuth.Token,
@@ -447,13 +475,61 @@ func ValidateService(cl *pkgtypes.Cluster, serviceName string, def *pkgtypes.Git
 	return nil, canDeleleteService
 }
 
-// AddDefaultServices
-func AddDefaultServices(cl *pkgtypes.Cluster) error {
-	kcfg := internalutils.GetKubernetesClient(cl.ClusterName)
+const (
+	defaultServicesAttempts = 5
+	defaultServicesBackoff  = 2 * time.Second
+)
+
+// EnsureDefaultServices adds the default service entries for a cluster,
+// retrying failed entries with exponential backoff, and records the outcome
+// on the cluster record - it is safe to re-run for an existing cluster
+func EnsureDefaultServices(cl *pkgtypes.Cluster) ([]pkgtypes.DefaultServiceResult, error) {
+	var results []pkgtypes.DefaultServiceResult
+	var err error
+
+	backoff := defaultServicesBackoff
+	for attempt := 1; attempt <= defaultServicesAttempts; attempt++ {
+		results, err = AddDefaultServices(cl)
+		if err == nil {
+			break
+		}
+
+		if attempt < defaultServicesAttempts {
+			log.Warn().Msgf("cluster %s - attempt %d to add default services failed, retrying in %s: %s", cl.ClusterName, attempt, backoff, err)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	cl.DefaultServiceResults = results
+	kcfg, updateErr := internalutils.GetClusterStoreClient(cl.ClusterName)
+	if updateErr == nil {
+		updateErr = secrets.UpdateCluster(kcfg.Clientset, *cl)
+	}
+	if updateErr != nil {
+		log.Error().Msgf("cluster %s - error recording default service results: %s", cl.ClusterName, updateErr)
+	}
+
+	return results, err
+}
 
-	err := secrets.CreateClusterServiceList(kcfg.Clientset, cl.ClusterName)
+// AddDefaultServices adds the default service entries for a cluster in a
+// single attempt, skipping entries that already exist, and reports which
+// entries were added - an error is returned if any entry failed
+func AddDefaultServices(cl *pkgtypes.Cluster) ([]pkgtypes.DefaultServiceResult, error) {
+	kcfg, err := internalutils.GetClusterStoreClient(cl.ClusterName)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	err = secrets.CreateClusterServiceList(kcfg.Clientset, cl.ClusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	existingServices, err := secrets.GetServices(kcfg.Clientset, cl.ClusterName)
+	if err != nil {
+		return nil, err
 	}
 
 	var fullDomainName string
@@ -488,7 +564,7 @@ func AddDefaultServices(cl *pkgtypes.Cluster) error {
 			Default:     true,
 			Description: "A Gitops oriented continuous delivery tool for managing all of our applications across our Kubernetes clusters.",
 			Image:       "https://assets.kubefirst.com/console/argocd.svg",
-			Links:       []string{fmt.Sprintf("https://argocd.%s", fullDomainName)},
+			Links:       []string{fmt.Sprintf("https://%s", argoCDHostname(cl, fullDomainName))},
 			Status:      "",
 			CreatedBy:   "kbot",
 		},
@@ -523,14 +599,40 @@ func AddDefaultServices(cl *pkgtypes.Cluster) error {
 		},
 	}
 
+	results := make([]pkgtypes.DefaultServiceResult, 0, len(defaults))
+	failed := []string{}
 	for _, svc := range defaults {
-		err := secrets.InsertClusterServiceListEntry(kcfg.Clientset, cl.ClusterName, &svc)
-		if err != nil {
-			return err
+		// components left out by the install profile aren't deployed
+		if pkg.FindStringInSlice(providerConfigs.InstallProfiles[cl.InstallProfile], strings.ToLower(svc.Name)) {
+			continue
+		}
+
+		result := pkgtypes.DefaultServiceResult{Name: svc.Name, Added: true}
+
+		exists := false
+		for _, existing := range existingServices.Services {
+			if existing.Name == svc.Name {
+				exists = true
+				break
+			}
+		}
+
+		if !exists {
+			err := secrets.InsertClusterServiceListEntry(kcfg.Clientset, cl.ClusterName, &svc)
+			if err != nil {
+				result.Added = false
+				result.Error = err.Error()
+				failed = append(failed, svc.Name)
+			}
 		}
+		results = append(results, result)
 	}
 
-	return nil
+	if len(failed) != 0 {
+		return results, fmt.Errorf("cluster %s - failed to add default services: %v", cl.ClusterName, failed)
+	}
+
+	return results, nil
 }
 
 func DetokenizeConfigKeys(serviceFilePath string, configKeys []pkgtypes.GitopsCatalogAppKeys) error {
@@ -569,3 +671,12 @@ func getRegistryPath(clusterName string, cloudProvider string, isTemplate bool)
 		return fmt.Sprintf("registry/clusters/%s", clusterName)
 	}
 }
+
+// argoCDHostname returns the hostname argocd is exposed on for a cluster
+func argoCDHostname(cl *pkgtypes.Cluster, fullDomainName string) string {
+	if cl.ArgoCDHost != "" {
+		return cl.ArgoCDHost
+	}
+
+	return fmt.Sprintf("argocd.%s", fullDomainName)
+}
@@ -0,0 +1,55 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package services
+
+import (
+	"fmt"
+
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// FindCatalogApp returns the gitops catalog app called serviceName
+func FindCatalogApp(apps pkgtypes.GitopsCatalogApps, serviceName string) (pkgtypes.GitopsCatalogApp, error) {
+	for _, app := range apps.Apps {
+		if app.Name == serviceName {
+			return app, nil
+		}
+	}
+
+	return pkgtypes.GitopsCatalogApp{}, fmt.Errorf("service %s is not valid", serviceName)
+}
+
+// ValidateServiceRequest makes sure a request supplies every secret key the
+// gitops catalog app requires
+func ValidateServiceRequest(appDef *pkgtypes.GitopsCatalogApp, req *pkgtypes.GitopsCatalogAppCreateRequest) error {
+	if appDef.SecretKeys == nil {
+		return nil
+	}
+
+	if req.SecretKeys == nil {
+		return fmt.Errorf("service %s has required secret keys that cannot be empty, check your request and try again", appDef.Name)
+	}
+
+	var providedKeys []string
+	for _, key := range req.SecretKeys {
+		providedKeys = append(providedKeys, key.Name)
+	}
+
+	for _, key := range appDef.SecretKeys {
+		if !pkg.FindStringInSlice(providedKeys, key.Name) {
+			return fmt.Errorf("%s is a required secret key", key.Name)
+		}
+		for _, subkey := range req.SecretKeys {
+			if key.Name == subkey.Name && subkey.Value == "" {
+				return fmt.Errorf("%s is a required secret key and its value cannot be empty", subkey.Name)
+			}
+		}
+	}
+
+	return nil
+}
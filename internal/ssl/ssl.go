@@ -8,6 +8,7 @@ package ssl
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -105,3 +106,20 @@ func Backup(backupDir, domainName, k1Dir, kubeconfigPath string) error {
 	}
 	return nil
 }
+
+// VerifyHostCertificate dials the host over tls and verifies the certificate
+// it serves is valid for that host
+func VerifyHostCertificate(host string) error {
+	conn, err := tls.Dial("tcp", fmt.Sprintf("%s:443", host), nil)
+	if err != nil {
+		return fmt.Errorf("error connecting to %s: %s", host, err)
+	}
+	defer conn.Close()
+
+	err = conn.VerifyHostname(host)
+	if err != nil {
+		return fmt.Errorf("certificate for %s is not valid: %s", host, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package telemetry
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kubefirst/metrics-client/pkg/telemetry"
+	log "github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultBufferSize is how many events the default sink holds between
+	// flushes
+	defaultBufferSize = 256
+
+	// defaultFlushInterval is how often the default sink flushes its events
+	defaultFlushInterval = 5 * time.Second
+)
+
+type bufferedEvent struct {
+	event      telemetry.TelemetryEvent
+	metricName string
+	errMsg     string
+}
+
+// Buffered queues events and transmits them to its sink in batches from the
+// background, on an interval and on Close, so transmitting never waits on
+// the network. Events that arrive while the buffer is full are dropped and
+// counted instead of blocking
+type Buffered struct {
+	sink    Telemetry
+	events  chan bufferedEvent
+	dropped atomic.Int64
+
+	// mu keeps Transmit from queueing onto a closed buffer
+	mu     sync.RWMutex
+	closed bool
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewBuffered returns a sink holding up to size events and transmitting them
+// to sink every interval
+func NewBuffered(sink Telemetry, size int, interval time.Duration) *Buffered {
+	b := &Buffered{
+		sink:   sink,
+		events: make(chan bufferedEvent, size),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go b.run(interval)
+
+	return b
+}
+
+// Transmit queues the event, it's dropped when the buffer is full or closed
+func (b *Buffered) Transmit(event telemetry.TelemetryEvent, metricName string, errMsg string) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		b.dropped.Add(1)
+		return nil
+	}
+
+	select {
+	case b.events <- bufferedEvent{event: event, metricName: metricName, errMsg: errMsg}:
+	default:
+		b.dropped.Add(1)
+	}
+
+	return nil
+}
+
+// Dropped returns how many events were dropped because the buffer was full
+// or closed
+func (b *Buffered) Dropped() int64 {
+	return b.dropped.Load()
+}
+
+// Close transmits the queued events, then closes the sink
+func (b *Buffered) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.stop)
+	b.mu.Unlock()
+
+	<-b.done
+	if dropped := b.Dropped(); dropped > 0 {
+		log.Warn().Msgf("%d telemetry events were dropped, the buffer was full", dropped)
+	}
+
+	return b.sink.Close()
+}
+
+// Flush transmits the events queued so far without closing the buffer
+func (b *Buffered) Flush() {
+	b.flush()
+}
+
+func (b *Buffered) run(interval time.Duration) {
+	defer close(b.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.flush()
+		case <-b.stop:
+			b.flush()
+			return
+		}
+	}
+}
+
+// flush transmits the events queued so far, the ones queued meanwhile wait
+// for the next flush. Flush and the background flush can run at once, so the
+// queue is read without blocking
+func (b *Buffered) flush() {
+	for pending := len(b.events); pending > 0; pending-- {
+		var queued bufferedEvent
+		select {
+		case queued = <-b.events:
+		default:
+			return
+		}
+		err := b.sink.Transmit(queued.event, queued.metricName, queued.errMsg)
+		if err != nil {
+			log.Warn().Msgf("error transmitting telemetry event %s: %s", queued.metricName, err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package telemetry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kubefirst/metrics-client/pkg/telemetry"
+)
+
+func TestBufferedFlushesOnClose(t *testing.T) {
+	sink := &recordingSink{}
+	buffered := NewBuffered(sink, 2, time.Hour)
+
+	for _, metric := range []string{telemetry.InitStarted, telemetry.InitCompleted, telemetry.KubefirstHeartbeat} {
+		if err := buffered.Transmit(telemetry.TelemetryEvent{}, metric, ""); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if buffered.Dropped() != 1 {
+		t.Errorf("expected the event overflowing the buffer to be dropped, got %d dropped", buffered.Dropped())
+	}
+
+	if err := buffered.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(sink.metrics) != 2 || sink.metrics[0] != telemetry.InitStarted || sink.metrics[1] != telemetry.InitCompleted {
+		t.Errorf("expected the queued events to be transmitted in order on close, got %v", sink.metrics)
+	}
+	if !sink.closed {
+		t.Error("expected the sink to be closed")
+	}
+
+	_ = buffered.Transmit(telemetry.TelemetryEvent{}, telemetry.KubefirstHeartbeat, "")
+	if buffered.Dropped() != 2 {
+		t.Errorf("expected events after close to be dropped, got %d dropped", buffered.Dropped())
+	}
+}
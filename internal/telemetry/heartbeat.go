@@ -12,11 +12,11 @@ import (
 
 func Heartbeat(event telemetry.TelemetryEvent) {
 
-	telemetry.SendEvent(event, telemetry.KubefirstHeartbeat, "")
+	Transmit(event, telemetry.KubefirstHeartbeat, "")
 	HeartbeatWorkloadClusters(event)
 
 	for range time.Tick(time.Second * 300) {
-		telemetry.SendEvent(event, telemetry.KubefirstHeartbeat, "")
+		Transmit(event, telemetry.KubefirstHeartbeat, "")
 		HeartbeatWorkloadClusters(event)
 	}
 }
@@ -28,7 +28,10 @@ func HeartbeatWorkloadClusters(event telemetry.TelemetryEvent) error {
 		return nil
 	}
 
-	kcfg := utils.GetKubernetesClient("")
+	kcfg, err := utils.GetClusterStoreClient("")
+	if err != nil {
+		return err
+	}
 
 	clusters, _ := secrets.GetClusters(kcfg.Clientset)
 
@@ -54,7 +57,7 @@ func HeartbeatWorkloadClusters(event telemetry.TelemetryEvent) error {
 						MetricName:        telemetry.KubefirstHeartbeat,
 					}
 
-					telemetry.SendEvent(telemetryEvent, telemetry.KubefirstHeartbeat, "")
+					Transmit(telemetryEvent, telemetry.KubefirstHeartbeat, "")
 				}
 			}
 		}
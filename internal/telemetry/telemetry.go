@@ -0,0 +1,116 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package telemetry
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/kubefirst/metrics-client/pkg/telemetry"
+)
+
+// Telemetry is a sink usage events are transmitted to, e.g. segment, an
+// internal analytics service or an otlp collector
+type Telemetry interface {
+	// Transmit sends event as metricName, errMsg describes a failure
+	Transmit(event telemetry.TelemetryEvent, metricName string, errMsg string) error
+	// Close flushes the events the sink still holds and releases it
+	Close() error
+}
+
+// Flusher is implemented by sinks holding events back, Flush transmits them
+// without closing the sink
+type Flusher interface {
+	Flush()
+}
+
+// Segment transmits events to segment through the kubefirst metrics client,
+// it honors USE_TELEMETRY=false and is the default sink
+type Segment struct{}
+
+func (Segment) Transmit(event telemetry.TelemetryEvent, metricName string, errMsg string) error {
+	return telemetry.SendEvent(event, metricName, errMsg)
+}
+
+// Close is a no-op, the metrics client flushes every event as it's sent
+func (Segment) Close() error {
+	return nil
+}
+
+// multi transmits every event to each of its sinks
+type multi []Telemetry
+
+// Multi returns a sink transmitting every event to each of sinks, e.g. an
+// own sink alongside segment, a failing sink doesn't keep events from the
+// others
+func Multi(sinks ...Telemetry) Telemetry {
+	return multi(sinks)
+}
+
+func (m multi) Transmit(event telemetry.TelemetryEvent, metricName string, errMsg string) error {
+	errs := []error{}
+	for _, sink := range m {
+		if err := sink.Transmit(event, metricName, errMsg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m multi) Flush() {
+	for _, sink := range m {
+		if flusher, ok := sink.(Flusher); ok {
+			flusher.Flush()
+		}
+	}
+}
+
+func (m multi) Close() error {
+	errs := []error{}
+	for _, sink := range m {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// the default sink is created on first use so its flushing only starts in
+// processes that transmit
+var defaultSink = struct {
+	sync.Mutex
+	sink Telemetry
+}{}
+
+// SetDefault replaces the sink the heartbeat, the telemetry endpoint, cluster
+// deletion and controllers without their own Telemetry transmit to, nil
+// restores buffered segment. The previous sink isn't closed
+func SetDefault(sink Telemetry) {
+	defaultSink.Lock()
+	defaultSink.sink = sink
+	defaultSink.Unlock()
+}
+
+// Default returns the sink events are transmitted to by default, segment
+// behind a buffer unless SetDefault replaced it
+func Default() Telemetry {
+	defaultSink.Lock()
+	defer defaultSink.Unlock()
+
+	if defaultSink.sink == nil {
+		defaultSink.sink = NewBuffered(Segment{}, defaultBufferSize, defaultFlushInterval)
+	}
+
+	return defaultSink.sink
+}
+
+// Transmit sends event as metricName to the default sink
+func Transmit(event telemetry.TelemetryEvent, metricName string, errMsg string) error {
+	return Default().Transmit(event, metricName, errMsg)
+}
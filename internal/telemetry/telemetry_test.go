@@ -0,0 +1,64 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package telemetry
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kubefirst/metrics-client/pkg/telemetry"
+)
+
+type recordingSink struct {
+	metrics []string
+	err     error
+	closed  bool
+}
+
+func (s *recordingSink) Transmit(event telemetry.TelemetryEvent, metricName string, errMsg string) error {
+	s.metrics = append(s.metrics, metricName)
+	return s.err
+}
+
+func (s *recordingSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestMulti(t *testing.T) {
+	failing := &recordingSink{err: fmt.Errorf("sink unavailable")}
+	working := &recordingSink{}
+	sink := Multi(failing, working)
+
+	err := sink.Transmit(telemetry.TelemetryEvent{}, telemetry.ClusterInstallCompleted, "")
+	if err == nil {
+		t.Error("expected the failing sink's error to be returned")
+	}
+	if len(working.metrics) != 1 || working.metrics[0] != telemetry.ClusterInstallCompleted {
+		t.Errorf("expected the event to reach every sink, got %v", working.metrics)
+	}
+
+	if err := sink.Close(); err != nil || !failing.closed || !working.closed {
+		t.Errorf("expected every sink to be closed, got %v", err)
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	sink := &recordingSink{}
+	SetDefault(sink)
+	defer SetDefault(nil)
+
+	_ = Transmit(telemetry.TelemetryEvent{}, telemetry.KubefirstHeartbeat, "")
+	if len(sink.metrics) != 1 {
+		t.Errorf("expected the default sink to receive the event, got %v", sink.metrics)
+	}
+
+	SetDefault(nil)
+	if _, ok := Default().(*Buffered); !ok {
+		t.Errorf("expected SetDefault(nil) to restore buffered segment, got %T", Default())
+	}
+}
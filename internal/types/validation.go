@@ -36,7 +36,8 @@ type CloudflareDomainValidationResponse struct {
 
 // DigitalOceanDomainValidationRequest /digitalocean/domain/validate required parameters
 type DigitalOceanDomainValidationRequest struct {
-	Token string `json:"token"`
+	Token  string `json:"token"`
+	APIURL string `json:"api_url,omitempty"`
 }
 
 // DigitalOceanDomainValidationResponse is the response for the /digitalocean/domain/validate route
@@ -104,9 +104,13 @@ var BackupResolver = &net.Resolver{
 
 // ScheduledGitopsCatalogUpdate
 func ScheduledGitopsCatalogUpdate() {
-	kcfg := GetKubernetesClient("")
+	kcfg, err := GetClusterStoreClient("")
+	if err != nil {
+		log.Warn().Msg(err.Error())
+		return
+	}
 
-	err := secrets.UpdateGitopsCatalogApps(kcfg.Clientset)
+	err = secrets.UpdateGitopsCatalogApps(kcfg.Clientset)
 	if err != nil {
 		log.Warn().Msg(err.Error())
 	}
@@ -131,6 +135,34 @@ func ValidateAuthenticationFields(s map[string]string) error {
 
 // GetKubernetesClient for cluster zero and existing cluster
 func GetKubernetesClient(clusterName string) *k8s.KubernetesClient {
+	inCluster, kubeconfigPath := kubernetesClientConfig(clusterName)
+
+	return k8s.CreateKubeConfig(inCluster, kubeconfigPath)
+}
+
+// GetClusterStoreClient returns the client of the kubernetes api server
+// holding the cluster store, connected with the store options set through the
+// environment. Other requests to the cluster use GetKubernetesClient
+func GetClusterStoreClient(clusterName string) (*k8s.KubernetesClient, error) {
+	env, _ := env.GetEnv(constants.SilenceGetEnv)
+	inCluster, kubeconfigPath := kubernetesClientConfig(clusterName)
+
+	storeOptions := clusterStoreOptions(env)
+	if storeOptions.IsZero() {
+		return k8s.CreateKubeConfig(inCluster, kubeconfigPath), nil
+	}
+
+	kcfg, err := k8s.CreateKubeConfigWithOptions(inCluster, kubeconfigPath, storeOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to the cluster store: %s", err)
+	}
+
+	return kcfg, nil
+}
+
+// kubernetesClientConfig returns whether the api runs in the cluster and the
+// kubeconfig of clusterName otherwise
+func kubernetesClientConfig(clusterName string) (bool, string) {
 	// Get Environment variables
 	env, _ := env.GetEnv(constants.SilenceGetEnv)
 
@@ -148,9 +180,52 @@ func GetKubernetesClient(clusterName string) *k8s.KubernetesClient {
 		kubeconfigPath = env.K1LocalKubeconfigPath
 	}
 
-	kcfg := k8s.CreateKubeConfig(inCluster, kubeconfigPath)
+	return inCluster, kubeconfigPath
+}
+
+// clusterStoreOptions returns the connection options of the cluster store
+// client set through the environment
+func clusterStoreOptions(env env.Env) k8s.ClientOptions {
+	return k8s.ClientOptions{
+		CAFile:   env.StoreTLSCAFile,
+		CertFile: env.StoreTLSCertFile,
+		KeyFile:  env.StoreTLSKeyFile,
+		QPS:      env.StoreQPS,
+		Burst:    env.StoreBurst,
+		Timeout:  time.Duration(env.StoreTimeout) * time.Second,
+	}
+}
+
+// VerifyClusterStore connects to the kubernetes api server holding the
+// cluster store and reads from the kubefirst namespace, so a misconfigured
+// connection fails at startup rather than on the first request. Outside a
+// cluster and local debug the store follows each cluster's kubeconfig and
+// there is nothing to verify up front
+func VerifyClusterStore() error {
+	env, _ := env.GetEnv(constants.SilenceGetEnv)
+
+	inCluster := env.InCluster == "true"
+	if !inCluster && env.K1LocalDebug != "true" {
+		return nil
+	}
+	kubeconfigPath := ""
+	if !inCluster {
+		kubeconfigPath = env.K1LocalKubeconfigPath
+	}
+
+	kcfg, err := k8s.CreateKubeConfigWithOptions(inCluster, kubeconfigPath, clusterStoreOptions(env))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err = kcfg.Clientset.CoreV1().Secrets("kubefirst").List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return fmt.Errorf("unable to read the kubefirst namespace from %s: %s", kcfg.RestConfig.Host, err)
+	}
 
-	return kcfg
+	return nil
 }
 
 func CreateKubefirstNamespace(clientSet *kubernetes.Clientset) error {
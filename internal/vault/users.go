@@ -8,6 +8,7 @@ package vault
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	vaultapi "github.com/hashicorp/vault/api"
@@ -36,5 +37,47 @@ func (conf *VaultConfiguration) GetUserPassword(endpoint string, token string, u
 		return "", err
 	}
 
-	return resp.Data[key].(string), nil
+	password, ok := resp.Data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret users/%s has no %s", username, key)
+	}
+
+	return password, nil
+}
+
+// ListUsers returns the usernames that have secrets at the users mount path
+func (conf *VaultConfiguration) ListUsers(endpoint string, token string) ([]string, error) {
+	conf.Config.Address = endpoint
+
+	vaultClient, err := vaultapi.NewClient(&conf.Config)
+	if err != nil {
+		return nil, err
+	}
+	vaultClient.SetToken(token)
+	if strings.Contains(endpoint, "http://") {
+		vaultClient.CloneConfig().ConfigureTLS(&vaultapi.TLSConfig{
+			Insecure: true,
+		})
+	}
+
+	secret, err := vaultClient.Logical().List("users/metadata")
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return []string{}, nil
+	}
+
+	keys, ok := secret.Data["keys"].([]interface{})
+	if !ok {
+		return []string{}, nil
+	}
+	users := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if username, ok := key.(string); ok && !strings.HasSuffix(username, "/") {
+			users = append(users, username)
+		}
+	}
+
+	return users, nil
 }
@@ -0,0 +1,147 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package vultr
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+	"github.com/vultr/govultr/v3"
+)
+
+// catalogCacheTTL is how long region and plan listings are reused before the
+// Vultr API is queried again
+const catalogCacheTTL = 5 * time.Minute
+
+// Vultr's region and plan listings are the same for every account, so a
+// single package level cache is shared between configurations
+var catalogCache = struct {
+	sync.Mutex
+	entries map[string]catalogCacheEntry
+}{entries: map[string]catalogCacheEntry{}}
+
+type catalogCacheEntry struct {
+	values  []string
+	expires time.Time
+}
+
+// ListRegions returns the IDs of all available Vultr regions
+func (c *VultrConfiguration) ListRegions() ([]string, error) {
+	return cachedCatalog("regions", func() ([]string, error) {
+		regions, _, _, err := c.Client.Region.List(c.Context, &govultr.ListOptions{PerPage: 500})
+		if err != nil {
+			return nil, err
+		}
+
+		regionList := make([]string, 0, len(regions))
+		for _, region := range regions {
+			regionList = append(regionList, region.ID)
+		}
+
+		return regionList, nil
+	})
+}
+
+// ListInstanceTypes returns the IDs of the plans that can be deployed to region
+func (c *VultrConfiguration) ListInstanceTypes(region string) ([]string, error) {
+	return cachedCatalog(fmt.Sprintf("plans/%s", region), func() ([]string, error) {
+		// can pass empty string to list all plans for second arg to List
+		plans, _, _, err := c.Client.Plan.List(c.Context, "", &govultr.ListOptions{
+			Region:  region,
+			PerPage: 500,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		planNames := make([]string, 0, len(plans))
+		for _, plan := range plans {
+			planNames = append(planNames, plan.ID)
+		}
+
+		return planNames, nil
+	})
+}
+
+// ValidateRegionAndInstanceType confirms that region exists and that
+// instanceType can be deployed to it
+func (c *VultrConfiguration) ValidateRegionAndInstanceType(region string, instanceType string) error {
+	regions, err := c.ListRegions()
+	if err != nil {
+		return fmt.Errorf("error listing vultr regions: %s", err)
+	}
+	if !pkg.FindStringInSlice(regions, region) {
+		return fmt.Errorf("%q is not a valid vultr region", region)
+	}
+
+	if instanceType == "" {
+		return nil
+	}
+
+	instanceTypes, err := c.ListInstanceTypes(region)
+	if err != nil {
+		return fmt.Errorf("error listing vultr plans for region %s: %s", region, err)
+	}
+	if !pkg.FindStringInSlice(instanceTypes, instanceType) {
+		return fmt.Errorf("%q is not a valid vultr plan for region %s", instanceType, region)
+	}
+
+	return nil
+}
+
+// GetPlanMonthlyCost returns the monthly cost in usd of a plan
+func (c *VultrConfiguration) GetPlanMonthlyCost(plan string) (float64, error) {
+	plans, _, _, err := c.Client.Plan.List(c.Context, "", &govultr.ListOptions{PerPage: 500})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, p := range plans {
+		if p.ID == plan {
+			return float64(p.MonthlyCost), nil
+		}
+	}
+
+	return 0, fmt.Errorf("%q is not a valid vultr plan", plan)
+}
+
+// cachedCatalog returns the cached values for key or calls fetch and caches
+// the result when there is no unexpired entry
+func cachedCatalog(key string, fetch func() ([]string, error)) ([]string, error) {
+	catalogCache.Lock()
+	entry, ok := catalogCache.entries[key]
+	catalogCache.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.values, nil
+	}
+
+	values, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	catalogCache.Lock()
+	catalogCache.entries[key] = catalogCacheEntry{
+		values:  values,
+		expires: time.Now().Add(catalogCacheTTL),
+	}
+	catalogCache.Unlock()
+
+	return values, nil
+}
+
+// GetVPCSubnet returns the ipv4 subnet of an existing vpc in cidr notation
+func (c *VultrConfiguration) GetVPCSubnet(vpcID string) (string, error) {
+	vpc, _, err := c.Client.VPC.Get(c.Context, vpcID)
+	if err != nil {
+		return "", fmt.Errorf("error getting vultr vpc %s: %s", vpcID, err)
+	}
+
+	return fmt.Sprintf("%s/%d", vpc.V4Subnet, vpc.V4SubnetMask), nil
+}
@@ -8,16 +8,28 @@ package vultr
 
 import (
 	"context"
+	"os"
 
 	"github.com/vultr/govultr/v3"
 	"golang.org/x/oauth2"
 )
 
-func NewVultr(vultrApiKey string) *govultr.Client {
+// APIURLVar is the environment variable overriding the vultr api url of
+// definitions that don't set vultr_auth.api_url
+const APIURLVar = "VULTR_API_URL"
+
+func NewVultr(vultrApiKey string, apiURL string) *govultr.Client {
 	config := &oauth2.Config{}
 	ctx := context.Background()
 	ts := config.TokenSource(ctx, &oauth2.Token{AccessToken: vultrApiKey})
 	vultrClient := govultr.NewClient(oauth2.NewClient(ctx, ts))
 
+	if apiURL == "" {
+		apiURL = os.Getenv(APIURLVar)
+	}
+	if apiURL != "" {
+		_ = vultrClient.SetBaseURL(apiURL)
+	}
+
 	return vultrClient
 }
@@ -117,3 +117,14 @@ func (c *VultrConfiguration) GetKubeconfig(clusterName string)(string, error) {
 
 	return kubeConfig.KubeConfig, nil
 }
+
+// ListKubernetesVersions returns the kubernetes versions new clusters can be
+// created with
+func (c *VultrConfiguration) ListKubernetesVersions() ([]string, error) {
+	versions, _, err := c.Client.Kubernetes.GetVersions(c.Context)
+	if err != nil {
+		return nil, fmt.Errorf("error listing kubernetes versions: %s", err)
+	}
+
+	return versions.Versions, nil
+}
@@ -94,6 +94,17 @@ func (c *VultrConfiguration) DeleteObjectStorage(storeName string) error {
 	return nil
 }
 
+// RegenerateObjectStorageKeys issues new s3 keys for an object storage
+// subscription, the old keys stop working
+func (c *VultrConfiguration) RegenerateObjectStorageKeys(id string) (govultr.S3Keys, error) {
+	keys, _, err := c.Client.ObjectStorage.RegenerateKeys(c.Context, id)
+	if err != nil {
+		return govultr.S3Keys{}, fmt.Errorf("error regenerating keys of object storage %s: %s", id, err)
+	}
+
+	return *keys, nil
+}
+
 // GetObjectStorage retrieves all Vultr object storage resources
 func (c *VultrConfiguration) GetObjectStorage() ([]govultr.ObjectStorage, error) {
 	objst, _, _, err := c.Client.ObjectStorage.List(c.Context, &govultr.ListOptions{
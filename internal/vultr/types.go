@@ -17,6 +17,8 @@ type VultrConfiguration struct {
 	Context             context.Context
 	Region              string
 	ObjectStorageRegion string
+	// RecordTTL is the ttl of the dns records created, 0 keeps 600 seconds
+	RecordTTL int
 }
 
 type VultrBucketCredentials struct {
@@ -24,3 +26,11 @@ type VultrBucketCredentials struct {
 	SecretAccessKey string
 	Endpoint        string
 }
+
+func (c *VultrConfiguration) recordTTL() int {
+	if c.RecordTTL > 0 {
+		return c.RecordTTL
+	}
+
+	return 600
+}
@@ -26,7 +26,7 @@ func (c *VultrConfiguration) TestDomainLiveness(domainName string) bool {
 		Name:     vultrRecordName,
 		Type:     "TXT",
 		Data:     vultrRecordValue,
-		TTL:      600,
+		TTL:      c.recordTTL(),
 		Priority: govultr.IntToIntPtr(100),
 	}
 
@@ -7,9 +7,17 @@ See the LICENSE file for more details.
 package main
 
 import (
+	"context"
+	"encoding/base64"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/kubefirst/kubefirst-api/docs"
+	"github.com/kubefirst/kubefirst-api/internal/controller"
 	"github.com/kubefirst/kubefirst-api/internal/env"
 	api "github.com/kubefirst/kubefirst-api/internal/router"
 	"github.com/kubefirst/kubefirst-api/internal/secrets"
@@ -38,34 +46,72 @@ func main() {
 		log.Fatal().Msg(err.Error())
 	}
 
-	log.Info().Msg("checking for cluster import secret for management cluster")
-	// Import if needed
-	importedCluster, err := secrets.ImportClusterIfEmpty(true)
-	if err != nil {
-		log.Fatal().Msg(err.Error())
+	if env.StoreEncryptionKey != "" {
+		key, err := base64.StdEncoding.DecodeString(env.StoreEncryptionKey)
+		if err != nil {
+			log.Fatal().Msgf("STORE_ENCRYPTION_KEY is not base64: %s", err)
+		}
+		err = secrets.SetEncryptionKey(key)
+		if err != nil {
+			log.Fatal().Msg(err.Error())
+		}
 	}
 
-	if importedCluster.ClusterName != "" {
-		log.Info().Msgf("adding default services for cluster %s", importedCluster.ClusterName)
-		services.AddDefaultServices(&importedCluster)
-
-		if importedCluster.PostInstallCatalogApps != nil {
-			go func() {
-				for _, catalogApp := range importedCluster.PostInstallCatalogApps {
-					log.Info().Msgf("installing catalog application %s", catalogApp.Name)
+	switch env.StoreBackend {
+	case "kubernetes":
+		err = utils.VerifyClusterStore()
+		if err != nil {
+			log.Fatal().Msgf("error connecting to the cluster store: %s", err)
+		}
+	case "memory":
+		store, err := secrets.NewMemoryStore(env.StorePath)
+		if err != nil {
+			log.Fatal().Msg(err.Error())
+		}
+		secrets.SetStore(store)
+		log.Info().Msgf("keeping cluster records in memory, persisted to %q", env.StorePath)
+	default:
+		log.Fatal().Msgf("unsupported store backend %s, use kubernetes or memory", env.StoreBackend)
+	}
 
-					request := &types.GitopsCatalogAppCreateRequest{
-						User:       "kbot",
-						SecretKeys: catalogApp.SecretKeys,
-						ConfigKeys: catalogApp.ConfigKeys,
-					}
+	// a read-only instance observes clusters managed by another instance and
+	// must not import or change anything on startup, or when reading records
+	secrets.SetWritesDisabled(controller.ReadOnly)
+	if env.ReadOnly {
+		log.Info().Msg("running in read-only mode, mutating operations will be rejected")
+	} else {
+		log.Info().Msg("checking for cluster import secret for management cluster")
+		// Import if needed
+		importedCluster, err := secrets.ImportClusterIfEmpty(true)
+		if err != nil {
+			log.Fatal().Msg(err.Error())
+		}
 
-					err = services.CreateService(&importedCluster, catalogApp.Name, &catalogApp, request, true)
-					if err != nil {
-						log.Info().Msgf("Error creating default environments %s", err.Error())
+		if importedCluster.ClusterName != "" {
+			log.Info().Msgf("adding default services for cluster %s", importedCluster.ClusterName)
+			_, err = services.EnsureDefaultServices(&importedCluster)
+			if err != nil {
+				log.Error().Msgf("error adding default service entries for cluster %s: %s", importedCluster.ClusterName, err)
+			}
+
+			if importedCluster.PostInstallCatalogApps != nil {
+				go func() {
+					for _, catalogApp := range importedCluster.PostInstallCatalogApps {
+						log.Info().Msgf("installing catalog application %s", catalogApp.Name)
+
+						request := &types.GitopsCatalogAppCreateRequest{
+							User:       "kbot",
+							SecretKeys: catalogApp.SecretKeys,
+							ConfigKeys: catalogApp.ConfigKeys,
+						}
+
+						err = services.CreateService(&importedCluster, catalogApp.Name, &catalogApp, request, true)
+						if err != nil {
+							log.Info().Msgf("Error creating default environments %s", err.Error())
+						}
 					}
-				}
-			}()
+				}()
+			}
 		}
 	}
 
@@ -95,7 +141,7 @@ func main() {
 		ParentClusterId:   env.ParentClusterId,
 		UserId:            env.ClusterId,
 	}
-	if env.IsClusterZero != "true" {
+	if env.IsClusterZero != "true" && !env.ReadOnly {
 		// Subroutine to automatically update gitops catalog
 		go utils.ScheduledGitopsCatalogUpdate()
 	}
@@ -104,8 +150,40 @@ func main() {
 	// API
 	r := api.SetupRouter()
 
-	err = r.Run(fmt.Sprintf(":%v", env.ServerPort))
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%v", env.ServerPort),
+		Handler: r,
+	}
+
+	go func() {
+		err := srv.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatal().Msgf("Error starting API: %s", err)
+		}
+	}()
+
+	// on SIGTERM refuse new provisions, give in-progress ones the grace
+	// period to finish and checkpoint the rest so they can be resumed, then
+	// stop the api server. The server keeps answering status requests while
+	// provisions finish, and long-lived requests like event streams can't
+	// hold up the checkpoints
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-quit
+	log.Info().Msgf("received %s, shutting down", sig)
+
+	deadline := time.Now().Add(time.Duration(env.ShutdownGracePeriod) * time.Second)
+	controller.Shutdown(time.Until(deadline))
+
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	err = srv.Shutdown(ctx)
+	if err != nil {
+		log.Error().Msgf("error shutting down API server: %s", err)
+	}
+
+	err = apitelemetry.Default().Close()
 	if err != nil {
-		log.Fatal().Msgf("Error starting API: %s", err)
+		log.Error().Msgf("error closing telemetry: %s", err)
 	}
 }
@@ -0,0 +1,31 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package akamai
+
+import (
+	"net/http"
+
+	"github.com/linode/linodego"
+	"golang.org/x/oauth2"
+)
+
+// NewAkamai creates a linode api client, apiURL overrides the api url without
+// its version (https://api.linode.com), linodego itself falls back to
+// linodego.APIHostVar (LINODE_URL)
+func NewAkamai(token string, apiURL string) linodego.Client {
+	client := linodego.NewClient(&http.Client{
+		Transport: &oauth2.Transport{
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}),
+		},
+	})
+
+	if apiURL != "" {
+		client.SetBaseURL(apiURL)
+	}
+
+	return client
+}
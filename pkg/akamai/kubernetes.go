@@ -0,0 +1,27 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package akamai
+
+import (
+	"fmt"
+)
+
+// ListKubernetesVersions returns the lke versions new clusters can be created
+// with
+func (c *AkamaiConfiguration) ListKubernetesVersions() ([]string, error) {
+	lkeVersions, err := c.Client.ListLKEVersions(c.Context, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error listing kubernetes versions: %s", err)
+	}
+
+	versions := []string{}
+	for _, version := range lkeVersions {
+		versions = append(versions, version.ID)
+	}
+
+	return versions, nil
+}
@@ -2,6 +2,9 @@ package akamai
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/kubefirst/kubefirst-api/pkg/types"
 	"github.com/linode/linodego"
@@ -46,3 +49,48 @@ func (c *AkamaiConfiguration) CreateObjectStorageBucketAndKeys(clusterName strin
 
 	return AkamaiBucketAndKeysConfiguration{stateStoreData, stateStoreCredentialsData}, nil
 }
+
+// CreateObjectStorageBucketKeys creates read_write access keys scoped to a
+// bucket, the key id is returned as the credentials id
+func (c *AkamaiConfiguration) CreateObjectStorageBucketKeys(bucketName string, cluster string) (types.StateStoreCredentials, error) {
+	creds, err := c.Client.CreateObjectStorageKey(c.Context, linodego.ObjectStorageKeyCreateOptions{
+		Label: fmt.Sprintf("%s-%d", bucketName, time.Now().Unix()),
+		BucketAccess: &[]linodego.ObjectStorageKeyBucketAccess{
+			{
+				BucketName:  bucketName,
+				Cluster:     cluster,
+				Permissions: "read_write",
+			},
+		},
+	})
+	if err != nil {
+		return types.StateStoreCredentials{}, fmt.Errorf("error creating object storage keys for bucket %s: %s", bucketName, err)
+	}
+
+	return types.StateStoreCredentials{
+		AccessKeyID:     creds.AccessKey,
+		SecretAccessKey: creds.SecretKey,
+		Name:            bucketName,
+		ID:              strconv.Itoa(creds.ID),
+	}, nil
+}
+
+// DeleteObjectStorageKey revokes the object storage keys with the access key
+func (c *AkamaiConfiguration) DeleteObjectStorageKey(accessKey string) error {
+	keys, err := c.Client.ListObjectStorageKeys(c.Context, nil)
+	if err != nil {
+		return fmt.Errorf("error listing object storage keys: %s", err)
+	}
+
+	for _, key := range keys {
+		if key.AccessKey == accessKey {
+			err = c.Client.DeleteObjectStorageKey(c.Context, key.ID)
+			if err != nil {
+				return fmt.Errorf("error deleting object storage key %s: %s", key.Label, err)
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("object storage key %s not found", accessKey)
+}
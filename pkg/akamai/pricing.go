@@ -0,0 +1,29 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package akamai
+
+import "fmt"
+
+// GetTypeMonthlyPrice returns the monthly price in usd of a linode type in
+// region, regions with their own pricing override the base price
+func (c *AkamaiConfiguration) GetTypeMonthlyPrice(typeID string, region string) (float64, error) {
+	linodeType, err := c.Client.GetType(c.Context, typeID)
+	if err != nil {
+		return 0, fmt.Errorf("error getting linode type %s: %s", typeID, err)
+	}
+
+	for _, regionPrice := range linodeType.RegionPrices {
+		if regionPrice.ID == region {
+			return float64(regionPrice.Monthly), nil
+		}
+	}
+	if linodeType.Price == nil {
+		return 0, fmt.Errorf("linode type %s has no price", typeID)
+	}
+
+	return float64(linodeType.Price.Monthly), nil
+}
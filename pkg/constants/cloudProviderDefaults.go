@@ -1,17 +1,70 @@
 package constants
 
-import "github.com/kubefirst/kubefirst-api/pkg/types"
-
-var cloudProviderDefaults = types.CloudProviderDefaults{
-	Akamai:       types.CloudDefault{InstanceSize: "g6-standard-4", NodeCount: "4"},
-	Aws:          types.CloudDefault{InstanceSize: "m5.large", NodeCount: "5"},
-	Civo:         types.CloudDefault{InstanceSize: "g4s.kube.large", NodeCount: "4"},
-	DigitalOcean: types.CloudDefault{InstanceSize: "s-4vcpu-8gb", NodeCount: "4"},
-	Google:       types.CloudDefault{InstanceSize: "e2-medium", NodeCount: "2"},
-	Vultr:        types.CloudDefault{InstanceSize: "vc2-4c-8gb", NodeCount: "4"},
-	K3d:          types.CloudDefault{InstanceSize: "", NodeCount: "3"},
+import (
+	"strconv"
+
+	"github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// cloudDefault converts the cloud provider's cluster definition defaults,
+// which are the single source of node sizes, to its /cloud-defaults entry
+func cloudDefault(cloudProvider string) types.CloudDefault {
+	defaults := types.ProviderDefinitionDefaults[cloudProvider]
+
+	return types.CloudDefault{
+		InstanceSize: defaults.NodeType,
+		NodeCount:    strconv.Itoa(defaults.NodeCount),
+	}
 }
 
 func GetCloudDefaults() types.CloudProviderDefaults {
-	return cloudProviderDefaults
+	return types.CloudProviderDefaults{
+		Akamai:       cloudDefault("akamai"),
+		Aws:          cloudDefault("aws"),
+		Civo:         cloudDefault("civo"),
+		DigitalOcean: cloudDefault("digitalocean"),
+		Google:       cloudDefault("google"),
+		Vultr:        cloudDefault("vultr"),
+		K3d:          cloudDefault("k3s"),
+	}
+}
+
+// provisionTimeouts are the cloud providers' default provisioning timeouts,
+// managed kubernetes on aws and google takes longer to come up
+var provisionTimeouts = map[string]types.ProvisionTimeouts{
+	"akamai":       {ClusterCreate: 600, NodeReady: 600, VaultReady: 1500},
+	"aws":          {ClusterCreate: 900, NodeReady: 900, VaultReady: 1800},
+	"civo":         {ClusterCreate: 300, NodeReady: 600, VaultReady: 1200},
+	"digitalocean": {ClusterCreate: 300, NodeReady: 600, VaultReady: 1500},
+	"google":       {ClusterCreate: 900, NodeReady: 900, VaultReady: 1800},
+	"k3s":          {ClusterCreate: 300, NodeReady: 300, VaultReady: 1200},
+	"vultr":        {ClusterCreate: 600, NodeReady: 900, VaultReady: 1500},
+}
+
+// GetProvisionTimeouts returns the default provisioning timeouts of every
+// cloud provider
+func GetProvisionTimeouts() map[string]types.ProvisionTimeouts {
+	timeouts := make(map[string]types.ProvisionTimeouts, len(provisionTimeouts))
+	for cloudProvider, t := range provisionTimeouts {
+		timeouts[cloudProvider] = t
+	}
+
+	return timeouts
+}
+
+// GetProvisionTimeoutsFor returns the cloud provider's default provisioning
+// timeouts with the set fields of overrides applied
+func GetProvisionTimeoutsFor(cloudProvider string, overrides types.ProvisionTimeouts) types.ProvisionTimeouts {
+	timeouts := provisionTimeouts[cloudProvider]
+	if overrides.ClusterCreate > 0 {
+		timeouts.ClusterCreate = overrides.ClusterCreate
+	}
+	if overrides.NodeReady > 0 {
+		timeouts.NodeReady = overrides.NodeReady
+	}
+	if overrides.VaultReady > 0 {
+		timeouts.VaultReady = overrides.VaultReady
+	}
+
+	return timeouts
 }
@@ -0,0 +1,34 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package constants
+
+import (
+	"testing"
+
+	"github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestGetCloudDefaults(t *testing.T) {
+	defaults := GetCloudDefaults()
+
+	for name, test := range map[string]struct {
+		got  types.CloudDefault
+		want types.CloudDefault
+	}{
+		"akamai":       {got: defaults.Akamai, want: types.CloudDefault{InstanceSize: "g6-standard-4", NodeCount: "4"}},
+		"aws":          {got: defaults.Aws, want: types.CloudDefault{InstanceSize: "m5.large", NodeCount: "5"}},
+		"civo":         {got: defaults.Civo, want: types.CloudDefault{InstanceSize: "g4s.kube.large", NodeCount: "4"}},
+		"digitalocean": {got: defaults.DigitalOcean, want: types.CloudDefault{InstanceSize: "s-4vcpu-8gb", NodeCount: "4"}},
+		"google":       {got: defaults.Google, want: types.CloudDefault{InstanceSize: "e2-medium", NodeCount: "2"}},
+		"vultr":        {got: defaults.Vultr, want: types.CloudDefault{InstanceSize: "vc2-4c-8gb", NodeCount: "4"}},
+		"k3d":          {got: defaults.K3d, want: types.CloudDefault{InstanceSize: "", NodeCount: "3"}},
+	} {
+		if test.got != test.want {
+			t.Errorf("%s: GetCloudDefaults() = %+v, want %+v", name, test.got, test.want)
+		}
+	}
+}
@@ -80,7 +80,7 @@ func (conf *GoogleConfiguration) TestHostedZoneLiveness(hostedZoneName string) b
 	stat, err := dnsService.ResourceRecordSets.Create(conf.Project, zone.Name, &googleDNS.ResourceRecordSet{
 		Name:    recordName,
 		Rrdatas: []string{recordValue},
-		Ttl:     10,
+		Ttl:     conf.recordTTL(),
 		Type:    "TXT",
 	}).Do()
 	if err != nil {
@@ -7,7 +7,9 @@ See the LICENSE file for more details.
 package google
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	storage "cloud.google.com/go/storage"
@@ -51,6 +53,107 @@ func (conf *GoogleConfiguration) CreateBucket(bucketName string, keyFile []byte)
 	}
 }
 
+// SetBucketEncryption sets the default customer managed kms key used to
+// encrypt objects written to a GCS bucket
+func (conf *GoogleConfiguration) SetBucketEncryption(bucketName string, kmsKeyName string, keyFile []byte) error {
+	creds, err := google.CredentialsFromJSON(conf.Context, keyFile, secretmanager.DefaultAuthScopes()...)
+	if err != nil {
+		return fmt.Errorf("could not create google storage client credentials: %s", err)
+	}
+	client, err := storage.NewClient(conf.Context, option.WithCredentials(creds))
+	if err != nil {
+		return fmt.Errorf("could not create google storage client: %s", err)
+	}
+	defer client.Close()
+
+	log.Info().Msgf("setting default kms key on gcs bucket %s", bucketName)
+	_, err = client.Bucket(bucketName).Update(conf.Context, storage.BucketAttrsToUpdate{
+		Encryption: &storage.BucketEncryption{DefaultKMSKeyName: kmsKeyName},
+	})
+	if err != nil {
+		return fmt.Errorf("error setting encryption on gcs bucket %s: %s", bucketName, err)
+	}
+
+	return nil
+}
+
+// VerifyBucketAccess confirms an existing GCS bucket can be reached with the
+// provided credentials
+func (conf *GoogleConfiguration) VerifyBucketAccess(bucketName string, keyFile []byte) error {
+	creds, err := google.CredentialsFromJSON(conf.Context, keyFile, secretmanager.DefaultAuthScopes()...)
+	if err != nil {
+		return fmt.Errorf("could not create google storage client credentials: %s", err)
+	}
+	client, err := storage.NewClient(conf.Context, option.WithCredentials(creds))
+	if err != nil {
+		return fmt.Errorf("could not create google storage client: %s", err)
+	}
+	defer client.Close()
+
+	log.Info().Msgf("verifying access to existing gcs bucket %s", bucketName)
+	_, err = client.Bucket(bucketName).Attrs(conf.Context)
+	if err != nil {
+		return fmt.Errorf("gcs bucket %s is missing or inaccessible: %s", bucketName, err)
+	}
+
+	return nil
+}
+
+// ProbeBucket writes, reads back and deletes an object in a GCS bucket to
+// confirm the provided credentials can store state in it
+func (conf *GoogleConfiguration) ProbeBucket(bucketName string, key string, keyFile []byte) error {
+	creds, err := google.CredentialsFromJSON(conf.Context, keyFile, secretmanager.DefaultAuthScopes()...)
+	if err != nil {
+		return fmt.Errorf("could not create google storage client credentials: %s", err)
+	}
+	client, err := storage.NewClient(conf.Context, option.WithCredentials(creds))
+	if err != nil {
+		return fmt.Errorf("could not create google storage client: %s", err)
+	}
+	defer client.Close()
+
+	log.Info().Msgf("probing gcs bucket %s", bucketName)
+	obj := client.Bucket(bucketName).Object(key)
+	body := []byte(key)
+
+	w := obj.NewWriter(conf.Context)
+	_, err = w.Write(body)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("error writing to gcs bucket %s: %s", bucketName, err)
+	}
+
+	readErr := func() error {
+		r, err := obj.NewReader(conf.Context)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		got, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(got, body) {
+			return fmt.Errorf("the object read back differs from the one written")
+		}
+		return nil
+	}()
+
+	// the probe object is removed even when it couldn't be read back
+	err = obj.Delete(conf.Context)
+	if readErr != nil {
+		return fmt.Errorf("error reading from gcs bucket %s: %s", bucketName, readErr)
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting from gcs bucket %s: %s", bucketName, err)
+	}
+
+	return nil
+}
+
 // DeleteBucket deletes a GCS bucket
 func (conf *GoogleConfiguration) DeleteBucket(bucketName string, keyFile []byte) error {
 	creds, err := google.CredentialsFromJSON(conf.Context, keyFile, secretmanager.DefaultAuthScopes()...)
@@ -142,3 +142,27 @@ func (conf *GoogleConfiguration) GetContainerClusterAuth(clusterName string, key
 		RestConfig: config,
 	}, nil
 }
+
+// ListKubernetesVersions returns the gke control plane versions new clusters
+// in the region can be created with
+func (conf *GoogleConfiguration) ListKubernetesVersions() ([]string, error) {
+	creds, err := google.CredentialsFromJSON(conf.Context, []byte(conf.KeyFile), container.DefaultAuthScopes()...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create google container client credentials: %s", err)
+	}
+
+	client, err := container.NewClusterManagerClient(conf.Context, option.WithCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("could not create google container client: %s", err)
+	}
+	defer client.Close()
+
+	serverConfig, err := client.GetServerConfig(conf.Context, &containerpb.GetServerConfigRequest{
+		Name: fmt.Sprintf("projects/%s/locations/%s", conf.Project, conf.Region),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting container server config: %s", err)
+	}
+
+	return serverConfig.GetValidMasterVersions(), nil
+}
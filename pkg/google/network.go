@@ -0,0 +1,91 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package google
+
+import (
+	"fmt"
+	"strings"
+
+	compute "cloud.google.com/go/compute/apiv1"
+	computepb "cloud.google.com/go/compute/apiv1/computepb"
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// GetSubnetworkCIDRs confirms the vpc network exists and returns the primary
+// ip ranges of the named subnetworks in the configured region, or of every
+// subnetwork of the network in the region when none are named
+func (conf *GoogleConfiguration) GetSubnetworkCIDRs(network string, subnetworks []string) ([]string, error) {
+	creds, err := google.CredentialsFromJSON(conf.Context, []byte(conf.KeyFile), secretmanager.DefaultAuthScopes()...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create google storage client credentials: %s", err)
+	}
+
+	networksClient, err := compute.NewNetworksRESTClient(conf.Context, option.WithCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("could not create google compute client: %s", err)
+	}
+	defer networksClient.Close()
+
+	vpc, err := networksClient.Get(conf.Context, &computepb.GetNetworkRequest{
+		Project: conf.Project,
+		Network: network,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting network %s: %s", network, err)
+	}
+
+	subnetworksClient, err := compute.NewSubnetworksRESTClient(conf.Context, option.WithCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("could not create google compute client: %s", err)
+	}
+	defer subnetworksClient.Close()
+
+	var cidrs []string
+	if len(subnetworks) != 0 {
+		for _, name := range subnetworks {
+			subnetwork, err := subnetworksClient.Get(conf.Context, &computepb.GetSubnetworkRequest{
+				Project:    conf.Project,
+				Region:     conf.Region,
+				Subnetwork: name,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("error getting subnetwork %s: %s", name, err)
+			}
+			if subnetwork.GetNetwork() != vpc.GetSelfLink() {
+				return nil, fmt.Errorf("subnetwork %s does not belong to network %s", name, network)
+			}
+			cidrs = append(cidrs, subnetwork.GetIpCidrRange())
+		}
+
+		return cidrs, nil
+	}
+
+	it := subnetworksClient.List(conf.Context, &computepb.ListSubnetworksRequest{
+		Project: conf.Project,
+		Region:  conf.Region,
+	})
+	for {
+		subnetwork, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(subnetwork.GetNetwork(), fmt.Sprintf("/networks/%s", network)) {
+			cidrs = append(cidrs, subnetwork.GetIpCidrRange())
+		}
+	}
+	if len(cidrs) == 0 {
+		return nil, fmt.Errorf("network %s has no subnetworks in region %s", network, conf.Region)
+	}
+
+	return cidrs, nil
+}
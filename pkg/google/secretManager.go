@@ -0,0 +1,62 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package google
+
+import (
+	"fmt"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PutSecret creates the secret manager secret secretID when it doesn't exist
+// and adds data as its latest version
+func (conf *GoogleConfiguration) PutSecret(secretID string, data []byte) error {
+	creds, err := google.CredentialsFromJSON(conf.Context, []byte(conf.KeyFile), secretmanager.DefaultAuthScopes()...)
+	if err != nil {
+		return fmt.Errorf("could not create google secret manager client credentials: %s", err)
+	}
+
+	client, err := secretmanager.NewClient(conf.Context, option.WithCredentials(creds))
+	if err != nil {
+		return fmt.Errorf("could not create google secret manager client: %s", err)
+	}
+	defer client.Close()
+
+	parent := fmt.Sprintf("projects/%s", conf.Project)
+	secret, err := client.CreateSecret(conf.Context, &secretmanagerpb.CreateSecretRequest{
+		Parent:   parent,
+		SecretId: secretID,
+		Secret: &secretmanagerpb.Secret{
+			Replication: &secretmanagerpb.Replication{
+				Replication: &secretmanagerpb.Replication_Automatic_{
+					Automatic: &secretmanagerpb.Replication_Automatic{},
+				},
+			},
+		},
+	})
+	name := fmt.Sprintf("%s/secrets/%s", parent, secretID)
+	if err == nil {
+		name = secret.Name
+	} else if status.Code(err) != codes.AlreadyExists {
+		return fmt.Errorf("error creating secret %s: %s", secretID, err)
+	}
+
+	_, err = client.AddSecretVersion(conf.Context, &secretmanagerpb.AddSecretVersionRequest{
+		Parent:  name,
+		Payload: &secretmanagerpb.SecretPayload{Data: data},
+	})
+	if err != nil {
+		return fmt.Errorf("error adding a version to secret %s: %s", secretID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package google
+
+import (
+	"fmt"
+	"strings"
+
+	pkg "github.com/kubefirst/kubefirst-api/internal"
+)
+
+// MachineTypeOfferedInRegion reports whether machineType is offered in a zone
+// of region
+func (conf *GoogleConfiguration) MachineTypeOfferedInRegion(machineType string, region string) (bool, error) {
+	zones, err := conf.GetZones()
+	if err != nil {
+		return false, fmt.Errorf("error listing zones: %s", err)
+	}
+
+	for _, zone := range zones {
+		if !strings.HasPrefix(zone, fmt.Sprintf("%s-", region)) {
+			continue
+		}
+
+		machineTypes, err := conf.ListInstances(zone)
+		if err != nil {
+			return false, fmt.Errorf("error listing machine types in %s: %s", zone, err)
+		}
+		if pkg.FindStringInSlice(machineTypes, machineType) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
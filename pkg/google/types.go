@@ -14,4 +14,14 @@ type GoogleConfiguration struct {
 	Project string
 	Region  string
 	KeyFile string
+	// RecordTTL is the ttl of the dns records created, 0 keeps 10 seconds
+	RecordTTL int
+}
+
+func (conf *GoogleConfiguration) recordTTL() int64 {
+	if conf.RecordTTL > 0 {
+		return int64(conf.RecordTTL)
+	}
+
+	return 10
 }
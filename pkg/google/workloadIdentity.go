@@ -0,0 +1,82 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package google
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2/google"
+	iam "google.golang.org/api/iam/v1"
+	"google.golang.org/api/option"
+)
+
+const workloadIdentityUserRole = "roles/iam.workloadIdentityUser"
+
+// GetWorkloadPool returns the workload identity pool of a gke cluster, empty
+// when workload identity is not enabled
+func (conf *GoogleConfiguration) GetWorkloadPool(clusterName string) (string, error) {
+	cluster, err := conf.GetContainerCluster(clusterName)
+	if err != nil {
+		return "", err
+	}
+
+	if cluster.WorkloadIdentityConfig == nil {
+		return "", nil
+	}
+
+	return cluster.WorkloadIdentityConfig.WorkloadPool, nil
+}
+
+// BindWorkloadIdentity allows the kubernetes service account namespace/name
+// to impersonate the google service account gcpServiceAccount
+func (conf *GoogleConfiguration) BindWorkloadIdentity(gcpServiceAccount string, workloadPool string, namespace string, serviceAccount string, keyFile []byte) error {
+	creds, err := google.CredentialsFromJSON(conf.Context, keyFile, iam.CloudPlatformScope)
+	if err != nil {
+		return fmt.Errorf("could not create google iam client credentials: %s", err)
+	}
+
+	iamService, err := iam.NewService(conf.Context, option.WithCredentials(creds))
+	if err != nil {
+		return fmt.Errorf("could not create google iam client: %s", err)
+	}
+
+	resource := fmt.Sprintf("projects/-/serviceAccounts/%s", gcpServiceAccount)
+	policy, err := iamService.Projects.ServiceAccounts.GetIamPolicy(resource).Context(conf.Context).Do()
+	if err != nil {
+		return fmt.Errorf("error getting iam policy for service account %s: %s", gcpServiceAccount, err)
+	}
+
+	member := fmt.Sprintf("serviceAccount:%s[%s/%s]", workloadPool, namespace, serviceAccount)
+
+	var binding *iam.Binding
+	for _, b := range policy.Bindings {
+		if b.Role == workloadIdentityUserRole {
+			binding = b
+			break
+		}
+	}
+	if binding == nil {
+		binding = &iam.Binding{Role: workloadIdentityUserRole}
+		policy.Bindings = append(policy.Bindings, binding)
+	}
+	for _, m := range binding.Members {
+		if m == member {
+			log.Info().Msgf("service account %s already bound to %s", gcpServiceAccount, member)
+			return nil
+		}
+	}
+	binding.Members = append(binding.Members, member)
+
+	_, err = iamService.Projects.ServiceAccounts.SetIamPolicy(resource, &iam.SetIamPolicyRequest{Policy: policy}).Context(conf.Context).Do()
+	if err != nil {
+		return fmt.Errorf("error setting iam policy for service account %s: %s", gcpServiceAccount, err)
+	}
+	log.Info().Msgf("bound %s to service account %s", member, gcpServiceAccount)
+
+	return nil
+}
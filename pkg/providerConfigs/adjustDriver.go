@@ -13,9 +13,9 @@ import (
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
-	"github.com/go-git/go-git/v5/plumbing"
 	pkg "github.com/kubefirst/kubefirst-api/internal"
 	"github.com/kubefirst/kubefirst-api/internal/gitClient"
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
 
 	cp "github.com/otiai10/copy"
 	"github.com/rs/zerolog/log"
@@ -31,6 +31,11 @@ func AdjustGitopsRepo(
 	k1Dir string,
 	apexContentExists bool,
 	useCloudflareOriginIssuer bool,
+	installProfile string,
+	ingressController string,
+	networkPolicies bool,
+	externalSecretsProvider string,
+	syncPolicy pkgtypes.ArgoCDSyncPolicy,
 ) error {
 	//* clean up all other platforms
 	for _, platform := range pkg.SupportedPlatforms {
@@ -39,6 +44,39 @@ func AdjustGitopsRepo(
 		}
 	}
 
+	//* drop the registry components the install profile leaves out
+	if err := pruneInstallProfile(fmt.Sprintf("%s/%s-%s", gitopsRepoDir, cloudProvider, gitProvider), clusterType, installProfile); err != nil {
+		return err
+	}
+
+	//* keep only the selected ingress controller
+	if err := pruneIngressControllers(fmt.Sprintf("%s/%s-%s", gitopsRepoDir, cloudProvider, gitProvider), clusterType, ingressController); err != nil {
+		return err
+	}
+
+	//* replace vault with the cloud secret manager's cluster secret store
+	droppedComponents := InstallProfiles[installProfile]
+	if externalSecretsProvider != "" {
+		if err := writeExternalSecretStore(fmt.Sprintf("%s/%s-%s", gitopsRepoDir, cloudProvider, gitProvider), clusterType, externalSecretsProvider); err != nil {
+			return err
+		}
+		droppedComponents = append(append([]string{}, droppedComponents...), ExternalSecretsReplacedComponents...)
+	}
+
+	//* add default deny network policies for the platform namespaces
+	if networkPolicies {
+		if err := writeNetworkPolicies(fmt.Sprintf("%s/%s-%s", gitopsRepoDir, cloudProvider, gitProvider), clusterType, droppedComponents, ingressController); err != nil {
+			return err
+		}
+	}
+
+	//* replace the template's automated sync across the app-of-apps
+	if !syncPolicy.IsDefault() {
+		if err := writeSyncPolicy(fmt.Sprintf("%s/%s-%s", gitopsRepoDir, cloudProvider, gitProvider), syncPolicy); err != nil {
+			return err
+		}
+	}
+
 	//* copy options
 	opt := cp.Options{
 		Skip: func(src string) (bool, error) {
@@ -595,7 +633,13 @@ func AdjustMetaphorRepo(
 	gitopsRepoDir string,
 	gitProvider string,
 	k1Dir string,
+	defaultBranch string,
+	commitMessage string,
 ) error {
+	if commitMessage == "" {
+		commitMessage = "init commit pre ref change"
+	}
+
 	//* create ~/.k1/metaphor
 	metaphorDir := fmt.Sprintf("%s/metaphor", k1Dir)
 	os.Mkdir(metaphorDir, 0700)
@@ -640,22 +684,16 @@ func AdjustMetaphorRepo(
 		// Remove metaphor content from gitops repository directory
 		os.RemoveAll(fmt.Sprintf("%s/metaphor", gitopsRepoDir))
 
-		err = gitClient.Commit(metaphorRepo, "init commit pre ref change")
+		err = gitClient.Commit(metaphorRepo, commitMessage)
 		if err != nil {
 			return err
 		}
 
-		metaphorRepo, err = gitClient.SetRefToMainBranch(metaphorRepo)
+		metaphorRepo, err = gitClient.MoveHeadToBranch(metaphorRepo, defaultBranch)
 		if err != nil {
 			return err
 		}
 
-		// remove old git ref
-		err = metaphorRepo.Storer.RemoveReference(plumbing.NewBranchReferenceName("master"))
-		if err != nil {
-			return fmt.Errorf("error removing previous git ref: %s", err)
-		}
-
 		// create remote
 		_, err = metaphorRepo.CreateRemote(&config.RemoteConfig{
 			Name: "origin",
@@ -690,22 +728,16 @@ func AdjustMetaphorRepo(
 		// Remove metaphor content from gitops repository directory
 		os.RemoveAll(fmt.Sprintf("%s/metaphor", gitopsRepoDir))
 
-		err = gitClient.Commit(metaphorRepo, "init commit pre ref change")
+		err = gitClient.Commit(metaphorRepo, commitMessage)
 		if err != nil {
 			return err
 		}
 
-		metaphorRepo, err = gitClient.SetRefToMainBranch(metaphorRepo)
+		metaphorRepo, err = gitClient.MoveHeadToBranch(metaphorRepo, defaultBranch)
 		if err != nil {
 			return err
 		}
 
-		// remove old git ref
-		err = metaphorRepo.Storer.RemoveReference(plumbing.NewBranchReferenceName("master"))
-		if err != nil {
-			return fmt.Errorf("error removing previous git ref: %s", err)
-		}
-
 		// create remote
 		_, err = metaphorRepo.CreateRemote(&config.RemoteConfig{
 			Name: "origin",
@@ -740,22 +772,16 @@ func AdjustMetaphorRepo(
 		// Remove metaphor content from gitops repository directory
 		os.RemoveAll(fmt.Sprintf("%s/metaphor", gitopsRepoDir))
 
-		err = gitClient.Commit(metaphorRepo, "init commit pre ref change")
+		err = gitClient.Commit(metaphorRepo, commitMessage)
 		if err != nil {
 			return err
 		}
 
-		metaphorRepo, err = gitClient.SetRefToMainBranch(metaphorRepo)
+		metaphorRepo, err = gitClient.MoveHeadToBranch(metaphorRepo, defaultBranch)
 		if err != nil {
 			return err
 		}
 
-		// remove old git ref
-		err = metaphorRepo.Storer.RemoveReference(plumbing.NewBranchReferenceName("master"))
-		if err != nil {
-			return fmt.Errorf("error removing previous git ref: %s", err)
-		}
-
 		// create remote
 		_, err = metaphorRepo.CreateRemote(&config.RemoteConfig{
 			Name: "origin",
@@ -790,22 +816,16 @@ func AdjustMetaphorRepo(
 		// Remove metaphor content from gitops repository directory
 		os.RemoveAll(fmt.Sprintf("%s/metaphor", gitopsRepoDir))
 
-		err = gitClient.Commit(metaphorRepo, "init commit pre ref change")
+		err = gitClient.Commit(metaphorRepo, commitMessage)
 		if err != nil {
 			return err
 		}
 
-		metaphorRepo, err = gitClient.SetRefToMainBranch(metaphorRepo)
+		metaphorRepo, err = gitClient.MoveHeadToBranch(metaphorRepo, defaultBranch)
 		if err != nil {
 			return err
 		}
 
-		// remove old git ref
-		err = metaphorRepo.Storer.RemoveReference(plumbing.NewBranchReferenceName("master"))
-		if err != nil {
-			return fmt.Errorf("error removing previous git ref: %s", err)
-		}
-
 		// create remote
 		_, err = metaphorRepo.CreateRemote(&config.RemoteConfig{
 			Name: "origin",
@@ -840,22 +860,16 @@ func AdjustMetaphorRepo(
 		// Remove metaphor content from gitops repository directory
 		os.RemoveAll(fmt.Sprintf("%s/metaphor", gitopsRepoDir))
 
-		err = gitClient.Commit(metaphorRepo, "init commit pre ref change")
+		err = gitClient.Commit(metaphorRepo, commitMessage)
 		if err != nil {
 			return err
 		}
 
-		metaphorRepo, err = gitClient.SetRefToMainBranch(metaphorRepo)
+		metaphorRepo, err = gitClient.MoveHeadToBranch(metaphorRepo, defaultBranch)
 		if err != nil {
 			return err
 		}
 
-		// remove old git ref
-		err = metaphorRepo.Storer.RemoveReference(plumbing.NewBranchReferenceName("master"))
-		if err != nil {
-			return fmt.Errorf("error removing previous git ref: %s", err)
-		}
-
 		// create remote
 		_, err = metaphorRepo.CreateRemote(&config.RemoteConfig{
 			Name: "origin",
@@ -921,22 +935,16 @@ func AdjustMetaphorRepo(
 	// Remove metaphor content from gitops repository directory
 	os.RemoveAll(fmt.Sprintf("%s/metaphor", gitopsRepoDir))
 
-	err = gitClient.Commit(metaphorRepo, "init commit pre ref change")
+	err = gitClient.Commit(metaphorRepo, commitMessage)
 	if err != nil {
 		return err
 	}
 
-	metaphorRepo, err = gitClient.SetRefToMainBranch(metaphorRepo)
+	metaphorRepo, err = gitClient.MoveHeadToBranch(metaphorRepo, defaultBranch)
 	if err != nil {
 		return err
 	}
 
-	// remove old git ref
-	err = metaphorRepo.Storer.RemoveReference(plumbing.NewBranchReferenceName("master"))
-	if err != nil {
-		return fmt.Errorf("error removing previous git ref: %s", err)
-	}
-
 	// create remote
 	_, err = metaphorRepo.CreateRemote(&config.RemoteConfig{
 		Name: "origin",
@@ -959,6 +967,7 @@ func PrepareGitRepositories(
 	gitopsDir string,
 	gitopsTemplateBranch string,
 	gitopsTemplateURL string,
+	gitopsTemplateCommit string,
 	destinationMetaphorRepoURL string,
 	k1Dir string,
 	gitopsTokens *GitopsDirectoryValues,
@@ -967,73 +976,94 @@ func PrepareGitRepositories(
 	apexContentExists bool,
 	gitProtocol string,
 	useCloudflareOriginIssuer bool,
-) error {
+) (string, error) {
 	//* clone the gitops-template repo
-	gitopsRepo, err := gitClient.CloneRefSetMain(gitopsTemplateBranch, gitopsDir, gitopsTemplateURL)
+	defaultBranch := gitClient.BranchOrDefault(gitopsTokens.DefaultBranch)
+	gitopsRepo, err := gitClient.CloneRefSetMain(gitopsTemplateBranch, gitopsDir, gitopsTemplateURL, defaultBranch)
 	if err != nil {
 		log.Panic().Msgf("error opening repo at: %s, err: %v", gitopsDir, err)
 	}
 	log.Info().Msg("gitops repository clone complete")
 
+	//* pin the gitops-template content to a specific commit
+	if gitopsTemplateCommit != "" {
+		err = gitClient.ResetToCommit(gitopsRepo, gitopsTemplateCommit)
+		if err != nil {
+			return "", err
+		}
+	}
+	templateHead, err := gitopsRepo.Head()
+	if err != nil {
+		return "", fmt.Errorf("error reading gitops template commit: %s", err)
+	}
+	templateCommit := templateHead.Hash().String()
+	log.Info().Msgf("gitops template %s prepared at commit %s", gitopsTemplateURL, templateCommit)
+
 	// ADJUST CONTENT
 	//* adjust the content for the gitops repo
-	err = AdjustGitopsRepo(cloudProvider, clusterName, clusterType, gitopsDir, gitProvider, k1Dir, apexContentExists, useCloudflareOriginIssuer)
+	err = AdjustGitopsRepo(cloudProvider, clusterName, clusterType, gitopsDir, gitProvider, k1Dir, apexContentExists, useCloudflareOriginIssuer, gitopsTokens.InstallProfile, gitopsTokens.IngressController, gitopsTokens.NetworkPolicies, gitopsTokens.ExternalSecrets.Provider, gitopsTokens.ArgoCDSyncPolicy)
 	if err != nil {
 		log.Info().Msgf("err: %v", err)
-		return err
+		return "", err
 	}
 
 	// DETOKENIZE
 	//* detokenize the gitops repo
 	DetokenizeGitGitops(gitopsDir, gitopsTokens, gitProtocol, useCloudflareOriginIssuer)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// ADJUST CONTENT
 	//* adjust the content for the metaphor repo
-	err = AdjustMetaphorRepo(destinationMetaphorRepoURL, gitopsDir, gitProvider, k1Dir)
+	err = AdjustMetaphorRepo(destinationMetaphorRepoURL, gitopsDir, gitProvider, k1Dir, defaultBranch, gitopsTokens.CommitMessage)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// DETOKENIZE
 	//* detokenize the metaphor repo
 	DetokenizeGitMetaphor(metaphorDir, metaphorTokens)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// COMMIT
 	//* commit initial gitops-template content
-	err = gitClient.Commit(gitopsRepo, "committing initial detokenized gitops-template repo content")
+	gitopsCommitMessage := "committing initial detokenized gitops-template repo content"
+	metaphorCommitMessage := "committing initial detokenized metaphor repo content"
+	if gitopsTokens.CommitMessage != "" {
+		gitopsCommitMessage = gitopsTokens.CommitMessage
+		metaphorCommitMessage = gitopsTokens.CommitMessage
+	}
+	err = gitClient.Commit(gitopsRepo, gitopsCommitMessage)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	//* commit initial metaphor content
 	metaphorRepo, err := git.PlainOpen(metaphorDir)
 	if err != nil {
-		return fmt.Errorf("error opening metaphor git repository: %s", err)
+		return "", fmt.Errorf("error opening metaphor git repository: %s", err)
 	}
 
-	err = gitClient.Commit(metaphorRepo, "committing initial detokenized metaphor repo content")
+	err = gitClient.Commit(metaphorRepo, metaphorCommitMessage)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// ADD REMOTE(S)
 	//* add new remote for gitops repo
 	err = gitClient.AddRemote(destinationGitopsRepoURL, gitProvider, gitopsRepo)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	//* add new remote for metaphor repo
 	err = gitClient.AddRemote(destinationMetaphorRepoURL, gitProvider, metaphorRepo)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	return templateCommit, nil
 }
@@ -0,0 +1,32 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package providerConfigs
+
+import pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+
+// AutoscalingProviders are the cloud providers whose node pools can
+// autoscale
+var AutoscalingProviders = []string{"akamai", "aws", "civo", "digitalocean", "google", "vultr"}
+
+// ClusterAutoscalerProviders are the autoscaling providers without a managed
+// autoscaler, the gitops template deploys cluster-autoscaler on them
+var ClusterAutoscalerProviders = []string{"aws", "civo"}
+
+// ResolveNodePoolBounds returns the node counts the pool is kept between,
+// autoscaling pools start at nodeCount and MinNodes defaults to 1
+func ResolveNodePoolBounds(pool string, nodeCount int, autoscaling pkgtypes.NodePoolAutoscaling) pkgtypes.NodePoolBounds {
+	if autoscaling.MaxNodes == 0 {
+		return pkgtypes.NodePoolBounds{Pool: pool, MinNodes: nodeCount, MaxNodes: nodeCount}
+	}
+
+	minNodes := autoscaling.MinNodes
+	if minNodes == 0 {
+		minNodes = 1
+	}
+
+	return pkgtypes.NodePoolBounds{Pool: pool, MinNodes: minNodes, MaxNodes: autoscaling.MaxNodes, Autoscaling: true}
+}
@@ -15,6 +15,7 @@ import (
 	"strconv"
 	"strings"
 
+	pkg "github.com/kubefirst/kubefirst-api/internal"
 	log "github.com/rs/zerolog/log"
 )
 
@@ -25,6 +26,11 @@ func DetokenizeGitGitops(path string, tokens *GitopsDirectoryValues, gitProtocol
 		return err
 	}
 
+	err = WriteTerraformBackendOverrides(path, tokens.TerraformBackend)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -87,6 +93,126 @@ func detokenizeGitops(path string, tokens *GitopsDirectoryValues, gitProtocol st
 				newContents = strings.Replace(newContents, "<NODE_TYPE>", tokens.NodeType, -1)
 				newContents = strings.Replace(newContents, "<NODE_COUNT>", fmt.Sprint(tokens.NodeCount), -1)
 
+				// node labels and taints are rendered as terraform map and list literals
+				nodeLabels := tokens.NodeLabels
+				if nodeLabels == nil {
+					nodeLabels = map[string]string{}
+				}
+				nodeLabelsBytes, err := json.Marshal(nodeLabels)
+				if err != nil {
+					return err
+				}
+				newContents = strings.Replace(newContents, "<NODE_LABELS>", string(nodeLabelsBytes), -1)
+
+				nodeTaintsBytes, err := json.Marshal(ProviderNodeTaints(tokens.CloudProvider, tokens.NodeTaints))
+				if err != nil {
+					return err
+				}
+				newContents = strings.Replace(newContents, "<NODE_TAINTS>", string(nodeTaintsBytes), -1)
+
+				// the spot node pool, rendered disabled with an empty node type
+				// when the definition doesn't add one
+				spotNodeLabelsBytes, err := json.Marshal(SpotNodeLabels(tokens.NodeLabels))
+				if err != nil {
+					return err
+				}
+				spotNodeTaintsBytes, err := json.Marshal(ProviderNodeTaints(tokens.CloudProvider, SpotNodeTaints(tokens.NodeTaints)))
+				if err != nil {
+					return err
+				}
+				newContents = strings.Replace(newContents, "<SPOT_NODE_POOL_ENABLED>", strconv.FormatBool(tokens.SpotNodePool.NodeCount > 0), -1)
+				newContents = strings.Replace(newContents, "<SPOT_NODE_TYPE>", tokens.SpotNodePool.NodeType, -1)
+				newContents = strings.Replace(newContents, "<SPOT_NODE_COUNT>", fmt.Sprint(tokens.SpotNodePool.NodeCount), -1)
+				newContents = strings.Replace(newContents, "<SPOT_NODE_LABELS>", string(spotNodeLabelsBytes), -1)
+				newContents = strings.Replace(newContents, "<SPOT_NODE_TAINTS>", string(spotNodeTaintsBytes), -1)
+
+				// node pool autoscaling, pools that don't autoscale render
+				// their node count as both bounds
+				nodeBounds := ResolveNodePoolBounds("default", tokens.NodeCount, tokens.Autoscaling)
+				spotNodeBounds := ResolveNodePoolBounds("spot", tokens.SpotNodePool.NodeCount, tokens.SpotNodePool.Autoscaling)
+				clusterAutoscaler := (nodeBounds.Autoscaling || spotNodeBounds.Autoscaling) && pkg.FindStringInSlice(ClusterAutoscalerProviders, tokens.CloudProvider)
+				newContents = strings.Replace(newContents, "<AUTOSCALING_ENABLED>", strconv.FormatBool(nodeBounds.Autoscaling), -1)
+				newContents = strings.Replace(newContents, "<NODE_MIN_COUNT>", fmt.Sprint(nodeBounds.MinNodes), -1)
+				newContents = strings.Replace(newContents, "<NODE_MAX_COUNT>", fmt.Sprint(nodeBounds.MaxNodes), -1)
+				newContents = strings.Replace(newContents, "<SPOT_AUTOSCALING_ENABLED>", strconv.FormatBool(spotNodeBounds.Autoscaling), -1)
+				newContents = strings.Replace(newContents, "<SPOT_NODE_MIN_COUNT>", fmt.Sprint(spotNodeBounds.MinNodes), -1)
+				newContents = strings.Replace(newContents, "<SPOT_NODE_MAX_COUNT>", fmt.Sprint(spotNodeBounds.MaxNodes), -1)
+				newContents = strings.Replace(newContents, "<CLUSTER_AUTOSCALER_ENABLED>", strconv.FormatBool(clusterAutoscaler), -1)
+
+				// custom cni, templates leave their default cni out unless it's default
+				cni := tokens.CNI.Name
+				if cni == "" {
+					cni = "default"
+				}
+				newContents = strings.Replace(newContents, "<CNI>", cni, -1)
+
+				// sso, the client secret is read from the platform secret store
+				newContents = strings.Replace(newContents, "<OIDC_ENABLED>", strconv.FormatBool(tokens.OIDC.IssuerURL != ""), -1)
+				newContents = strings.Replace(newContents, "<OIDC_ISSUER_URL>", tokens.OIDC.IssuerURL, -1)
+				newContents = strings.Replace(newContents, "<OIDC_CLIENT_ID>", tokens.OIDC.ClientID, -1)
+
+				// image overrides for mirrored registries, components that are
+				// not overridden render empty so the chart default applies
+				for component, tokenPrefix := range ImageOverrideComponents {
+					var repository, tag string
+					if image, ok := tokens.ImageOverrides[component]; ok {
+						repository, tag, err = SplitImage(image)
+						if err != nil {
+							return err
+						}
+					}
+					newContents = strings.Replace(newContents, fmt.Sprintf("<%s_IMAGE_REPOSITORY>", tokenPrefix), repository, -1)
+					newContents = strings.Replace(newContents, fmt.Sprintf("<%s_IMAGE_TAG>", tokenPrefix), tag, -1)
+				}
+
+				// ingress controller wiring for the registry, cert-manager and external-dns
+				ingressController := ResolveIngressController(tokens.IngressController)
+				newContents = strings.Replace(newContents, "<INGRESS_CONTROLLER>", ingressController.Component, -1)
+				newContents = strings.Replace(newContents, "<INGRESS_CLASS>", ingressController.IngressClass, -1)
+				newContents = strings.Replace(newContents, "<EXTERNAL_DNS_SOURCES>", fmt.Sprintf("[%s]", strings.Join(ingressController.ExternalDNSSources, ", ")), -1)
+
+				// load balancer annotations of the ingress controller service as an
+				// inline yaml map
+				loadBalancerAnnotations := tokens.LoadBalancerAnnotations
+				if loadBalancerAnnotations == nil {
+					loadBalancerAnnotations = map[string]string{}
+				}
+				loadBalancerAnnotationsBytes, err := json.Marshal(loadBalancerAnnotations)
+				if err != nil {
+					return err
+				}
+				newContents = strings.Replace(newContents, "<INGRESS_SERVICE_ANNOTATIONS>", string(loadBalancerAnnotationsBytes), -1)
+
+				// image pull secrets as an inline yaml list, e.g. [{name: registry}]
+				imagePullSecrets := make([]string, 0, len(tokens.ImagePullSecrets))
+				for _, name := range tokens.ImagePullSecrets {
+					imagePullSecrets = append(imagePullSecrets, fmt.Sprintf("{name: %s}", name))
+				}
+				newContents = strings.Replace(newContents, "<IMAGE_PULL_SECRETS>", fmt.Sprintf("[%s]", strings.Join(imagePullSecrets, ", ")), -1)
+
+				// resource requests and limits, unset values render empty so the
+				// chart default applies
+				for component, tokenPrefix := range ResourceOverrideComponents {
+					resources := tokens.ResourceOverrides[component]
+					newContents = strings.Replace(newContents, fmt.Sprintf("<%s_CPU_REQUEST>", tokenPrefix), resources.CPURequest, -1)
+					newContents = strings.Replace(newContents, fmt.Sprintf("<%s_MEMORY_REQUEST>", tokenPrefix), resources.MemoryRequest, -1)
+					newContents = strings.Replace(newContents, fmt.Sprintf("<%s_CPU_LIMIT>", tokenPrefix), resources.CPULimit, -1)
+					newContents = strings.Replace(newContents, fmt.Sprintf("<%s_MEMORY_LIMIT>", tokenPrefix), resources.MemoryLimit, -1)
+				}
+
+				// persistence, unset values render empty so the chart default applies
+				newContents = strings.Replace(newContents, "<STORAGE_CLASS>", tokens.StorageClass, -1)
+				for component, tokenPrefix := range VolumeSizeComponents {
+					newContents = strings.Replace(newContents, fmt.Sprintf("<%s_VOLUME_SIZE>", tokenPrefix), tokens.VolumeSizes[component], -1)
+				}
+
+				// pod security standard namespace labels, namespaces without an
+				// override get the cluster wide level
+				newContents = strings.Replace(newContents, "<POD_SECURITY_LEVEL>", ResolvePodSecurityLevel(tokens.PodSecurity, ""), -1)
+				for namespace, tokenPrefix := range PodSecurityNamespaces {
+					newContents = strings.Replace(newContents, fmt.Sprintf("<%s_POD_SECURITY_LEVEL>", tokenPrefix), ResolvePodSecurityLevel(tokens.PodSecurity, namespace), -1)
+				}
+
 				// AWS
 				newContents = strings.Replace(newContents, "<AWS_ACCOUNT_ID>", tokens.AwsAccountID, -1)
 				newContents = strings.Replace(newContents, "<AWS_IAM_ARN_ACCOUNT_ROOT>", tokens.AwsIamArnAccountRoot, -1)
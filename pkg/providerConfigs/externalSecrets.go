@@ -0,0 +1,128 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package providerConfigs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ExternalSecretStoreName is the ClusterSecretStore platform secrets are
+// read through when external_secrets replaces vault, rendered into
+// <SECRET_STORE_REF>
+const ExternalSecretStoreName = "cloud-secret-manager"
+
+// externalSecretStoreFileName is written to the registry of clusters using a
+// cloud secret manager
+const externalSecretStoreFileName = "10-cloud-secret-manager.yaml"
+
+// ExternalSecretsProviders maps the cloud secret managers external secrets
+// operator can read platform secrets from to the cloud provider they're
+// supported on, external secrets operator authenticates with the cloud's
+// workload identity
+var ExternalSecretsProviders = map[string]string{
+	"aws-secrets-manager":   "aws",
+	"google-secret-manager": "google",
+}
+
+// ExternalSecretsReplacedComponents are left out of the registry when a
+// cloud secret manager replaces vault
+var ExternalSecretsReplacedComponents = []string{"vault"}
+
+// externalSecretStores holds the ClusterSecretStore of each cloud secret
+// manager, the tokens are rendered by the gitops detokenize
+var externalSecretStores = map[string]string{
+	"aws-secrets-manager": `apiVersion: external-secrets.io/v1beta1
+kind: ClusterSecretStore
+metadata:
+  name: cloud-secret-manager
+  annotations:
+    argocd.argoproj.io/sync-wave: "10"
+    argocd.argoproj.io/sync-options: SkipDryRunOnMissingResource=true
+spec:
+  provider:
+    aws:
+      service: SecretsManager
+      region: <CLOUD_REGION>
+      auth:
+        jwt:
+          serviceAccountRef:
+            name: external-secrets
+            namespace: external-secrets-operator
+`,
+	"google-secret-manager": `apiVersion: external-secrets.io/v1beta1
+kind: ClusterSecretStore
+metadata:
+  name: cloud-secret-manager
+  annotations:
+    argocd.argoproj.io/sync-wave: "10"
+    argocd.argoproj.io/sync-options: SkipDryRunOnMissingResource=true
+spec:
+  provider:
+    gcpsm:
+      projectID: <GOOGLE_PROJECT>
+      auth:
+        workloadIdentity:
+          clusterLocation: <CLOUD_REGION>
+          clusterName: <CLUSTER_NAME>
+          serviceAccountRef:
+            name: external-secrets
+            namespace: external-secrets-operator
+`,
+}
+
+// ExternalSecretsProviderNames returns the supported cloud secret managers
+// sorted by name
+func ExternalSecretsProviderNames() []string {
+	names := make([]string, 0, len(ExternalSecretsProviders))
+	for name := range ExternalSecretsProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// writeExternalSecretStore removes vault from the cluster type content of the
+// driver directory and adds the ClusterSecretStore of the cloud secret
+// manager in its place
+func writeExternalSecretStore(driverDir string, clusterType string, provider string) error {
+	store, ok := externalSecretStores[provider]
+	if !ok {
+		return fmt.Errorf("unsupported external secrets provider %s: must be one of %v", provider, ExternalSecretsProviderNames())
+	}
+
+	err := pruneComponents(driverDir, clusterType, fmt.Sprintf("external secrets provider %s", provider), ExternalSecretsReplacedComponents)
+	if err != nil {
+		return err
+	}
+
+	for _, contentDir := range []string{
+		strings.ToLower(fmt.Sprintf("%s/templates/%s", driverDir, clusterType)),
+		strings.ToLower(fmt.Sprintf("%s/cluster-types/%s", driverDir, clusterType)),
+	} {
+		if _, err := os.Stat(contentDir); os.IsNotExist(err) {
+			continue
+		}
+
+		path := filepath.Join(contentDir, externalSecretStoreFileName)
+		err := os.WriteFile(path, []byte(store), 0o644)
+		if err != nil {
+			return fmt.Errorf("error writing cluster secret store to %s: %s", path, err)
+		}
+		log.Info().Msgf("external secrets provider %s: wrote cluster secret store %s", provider, ExternalSecretStoreName)
+
+		return nil
+	}
+
+	return fmt.Errorf("the gitops template has no %s cluster content to add the cluster secret store to", clusterType)
+}
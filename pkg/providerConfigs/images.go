@@ -0,0 +1,78 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package providerConfigs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultConsoleRepository is the repository a console_image given as just
+// a version is pulled from
+const DefaultConsoleRepository = "ghcr.io/kubefirst/console"
+
+var (
+	// imageRepositoryRegex follows the docker reference grammar, an optional
+	// registry host - a domain, localhost or anything with a port - then
+	// lowercase path components
+	imageRepositoryRegex = regexp.MustCompile(`^(?:(?:[a-zA-Z0-9-]+(?:\.[a-zA-Z0-9-]+)+|localhost)(?::[0-9]+)?/|[a-zA-Z0-9-]+:[0-9]+/)?[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	imageTagRegex        = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127}$`)
+)
+
+// ImageOverrideComponents maps the platform components whose images can be
+// overridden to the prefix of their gitops template tokens, e.g. argocd is
+// rendered into <ARGOCD_IMAGE_REPOSITORY> and <ARGOCD_IMAGE_TAG>
+var ImageOverrideComponents = map[string]string{
+	"argocd":       "ARGOCD",
+	"cert-manager": "CERT_MANAGER",
+	"console":      "CONSOLE",
+	"vault":        "VAULT",
+}
+
+// SplitImage splits an image reference of the form repository:tag into its
+// repository and tag
+func SplitImage(image string) (string, string, error) {
+	if image == "" || strings.ContainsAny(image, " \t\n") || strings.Contains(image, "://") {
+		return "", "", fmt.Errorf("invalid image %q, expected repository:tag", image)
+	}
+
+	// the tag separator is the last colon after the final path segment so
+	// registry ports (registry:5000/argocd:v2) are kept in the repository
+	i := strings.LastIndex(image, ":")
+	if i <= strings.LastIndex(image, "/") || i == len(image)-1 {
+		return "", "", fmt.Errorf("invalid image %q, expected repository:tag", image)
+	}
+
+	repository, tag := image[:i], image[i+1:]
+	if !imageRepositoryRegex.MatchString(repository) {
+		return "", "", fmt.Errorf("invalid image %q, %s is not a valid repository", image, repository)
+	}
+	if !imageTagRegex.MatchString(tag) {
+		return "", "", fmt.Errorf("invalid image %q, %s is not a valid tag", image, tag)
+	}
+
+	return repository, tag, nil
+}
+
+// ConsoleImage resolves a console_image, either an image of the form
+// repository:tag or a version of the default console repository
+func ConsoleImage(image string) (string, error) {
+	if !strings.ContainsAny(image, ":/") {
+		if !imageTagRegex.MatchString(image) {
+			return "", fmt.Errorf("invalid console image %q, expected repository:tag or a version", image)
+		}
+		image = fmt.Sprintf("%s:%s", DefaultConsoleRepository, image)
+	}
+
+	_, _, err := SplitImage(image)
+	if err != nil {
+		return "", err
+	}
+
+	return image, nil
+}
@@ -0,0 +1,44 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package providerConfigs
+
+import "testing"
+
+func TestSplitImage(t *testing.T) {
+	for image, want := range map[string][2]string{
+		"argoproj/argocd:v2.8.4":                  {"argoproj/argocd", "v2.8.4"},
+		"registry.example.com:5000/argocd:v2.8.4": {"registry.example.com:5000/argocd", "v2.8.4"},
+		"ghcr.io/kubefirst/console:2.3.0-rc.1":    {"ghcr.io/kubefirst/console", "2.3.0-rc.1"},
+	} {
+		repository, tag, err := SplitImage(image)
+		if err != nil || repository != want[0] || tag != want[1] {
+			t.Errorf("%s: expected %v, got %s %s (%v)", image, want, repository, tag, err)
+		}
+	}
+
+	for _, image := range []string{"", "argocd", "registry:5000/argocd", "Argoproj/argocd:v2", "argocd:v2 latest", "argocd:-v2", "https://ghcr.io/argocd:v2"} {
+		_, _, err := SplitImage(image)
+		if err == nil {
+			t.Errorf("%s: expected an error", image)
+		}
+	}
+}
+
+func TestConsoleImage(t *testing.T) {
+	image, err := ConsoleImage("2.3.0")
+	if err != nil || image != "ghcr.io/kubefirst/console:2.3.0" {
+		t.Errorf("expected the version of the default repository, got %s (%v)", image, err)
+	}
+	image, err = ConsoleImage("registry.example.com/console:2.3.0")
+	if err != nil || image != "registry.example.com/console:2.3.0" {
+		t.Errorf("expected the image to be kept, got %s (%v)", image, err)
+	}
+	_, err = ConsoleImage("2.3.0!")
+	if err == nil {
+		t.Error("expected an error for an invalid version")
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package providerConfigs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultIngressController is installed when the definition doesn't choose one
+const DefaultIngressController = "nginx"
+
+// IngressController describes how a supported ingress controller is wired
+// into the gitops registry
+type IngressController struct {
+	// Component is the registry application and component directory name
+	Component string
+	// IngressClass is used by cert-manager http01 solvers and platform ingresses
+	IngressClass string
+	// ExternalDNSSources are the external-dns sources that pick up its routes
+	ExternalDNSSources []string
+}
+
+// IngressControllers are the ingress controllers selectable with
+// ingress_controller on the cluster definition
+var IngressControllers = map[string]IngressController{
+	"nginx": {
+		Component:          "ingress-nginx",
+		IngressClass:       "nginx",
+		ExternalDNSSources: []string{"ingress"},
+	},
+	"traefik": {
+		Component:          "traefik",
+		IngressClass:       "traefik",
+		ExternalDNSSources: []string{"ingress", "traefik-proxy"},
+	},
+	"contour": {
+		Component:          "contour",
+		IngressClass:       "contour",
+		ExternalDNSSources: []string{"ingress", "contour-httpproxy"},
+	},
+}
+
+// IngressControllerNames returns the supported ingress controllers sorted by
+// name
+func IngressControllerNames() []string {
+	names := make([]string, 0, len(IngressControllers))
+	for name := range IngressControllers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ResolveIngressController returns the named ingress controller, falling
+// back to the default when name is empty or unknown
+func ResolveIngressController(name string) IngressController {
+	if ingressController, ok := IngressControllers[name]; ok {
+		return ingressController
+	}
+
+	return IngressControllers[DefaultIngressController]
+}
+
+// IngressApplications returns the argocd applications of the ingress
+// controller, removed before cluster destroy so its load balancer is released
+func IngressApplications(name string) []string {
+	component := ResolveIngressController(name).Component
+
+	return []string{fmt.Sprintf("%s-components", component), component}
+}
+
+// pruneIngressControllers removes the registry applications and component
+// directories of every ingress controller other than the selected one, and
+// fails when the gitops template doesn't ship the selected controller
+func pruneIngressControllers(driverDir string, clusterType string, ingressController string) error {
+	if ingressController == "" {
+		ingressController = DefaultIngressController
+	}
+	selected, ok := IngressControllers[ingressController]
+	if !ok {
+		return fmt.Errorf("unknown ingress controller %s: must be one of %v", ingressController, IngressControllerNames())
+	}
+
+	found := false
+	for _, contentDir := range []string{
+		strings.ToLower(fmt.Sprintf("%s/templates/%s", driverDir, clusterType)),
+		strings.ToLower(fmt.Sprintf("%s/cluster-types/%s", driverDir, clusterType)),
+	} {
+		entries, err := os.ReadDir(contentDir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error reading %s: %s", contentDir, err)
+		}
+
+		for _, entry := range entries {
+			name := registryEntryName(entry.Name())
+			if name == selected.Component || name == fmt.Sprintf("%s-components", selected.Component) {
+				found = true
+				continue
+			}
+			for _, other := range IngressControllers {
+				if other.Component == selected.Component {
+					continue
+				}
+				if name == other.Component || name == fmt.Sprintf("%s-components", other.Component) {
+					log.Info().Msgf("ingress controller %s: removing %s", ingressController, entry.Name())
+					os.RemoveAll(filepath.Join(contentDir, entry.Name()))
+				}
+			}
+		}
+		for _, other := range IngressControllers {
+			if other.Component != selected.Component {
+				os.RemoveAll(filepath.Join(contentDir, "components", other.Component))
+			}
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("the gitops template does not include the %s ingress controller", ingressController)
+	}
+
+	return nil
+}
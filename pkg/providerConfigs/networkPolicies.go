@@ -0,0 +1,266 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package providerConfigs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+// networkPoliciesFileName is written to the registry of clusters created with
+// network_policies, the late sync wave lets the platform applications create
+// their namespaces first
+const networkPoliciesFileName = "99-network-policies.yaml"
+
+// networkPolicyRules are the allow rules a platform namespace needs on top of
+// traffic within the namespace, dns and the kubernetes api
+type networkPolicyRules struct {
+	// ingressFrom are the namespaces allowed to reach the namespace's pods
+	ingressFrom []string
+	// ingressController allows traffic from the ingress controller
+	ingressController bool
+	// egressTo are the namespaces the namespace's pods reach
+	egressTo []string
+	// externalPorts are reachable anywhere in addition to 443 and 6443
+	externalPorts []int
+	// webhookPorts serve admission webhooks, the kubernetes api server calls
+	// them from the control plane, which has no address a policy can select
+	webhookPorts []int
+}
+
+// NetworkPolicyNamespaces holds the allow rules of every platform namespace
+// that gets a default deny policy, the ingress controller's namespace is
+// left open since it serves traffic from outside the cluster
+//
+//	argo:                      ingress controller, egress to argocd and chartmuseum, ssh to git
+//	argocd:                    ingress controller, argo and kubefirst, egress to chartmuseum and vault, ssh to git
+//	atlantis:                  ingress controller for git webhooks, egress to vault, ssh to git
+//	cert-manager:              http on 80 for acme http01 self checks, webhook on 10250
+//	chartmuseum:               ingress controller, argo and argocd
+//	external-dns:              the dns provider api over 443 only
+//	external-secrets-operator: egress to vault, webhook on 10250
+//	kubefirst:                 ingress controller, egress to argocd and vault
+//	vault:                     ingress controller, argo, argocd, atlantis, external-secrets-operator and kubefirst, agent injector webhook on 8080
+var NetworkPolicyNamespaces = map[string]networkPolicyRules{
+	"argo": {
+		ingressController: true,
+		egressTo:          []string{"argocd", "chartmuseum"},
+		externalPorts:     []int{22},
+	},
+	"argocd": {
+		ingressController: true,
+		ingressFrom:       []string{"argo", "kubefirst"},
+		egressTo:          []string{"chartmuseum", "vault"},
+		externalPorts:     []int{22},
+	},
+	"atlantis": {
+		ingressController: true,
+		egressTo:          []string{"vault"},
+		externalPorts:     []int{22},
+	},
+	"cert-manager": {
+		externalPorts: []int{80},
+		webhookPorts:  []int{10250},
+	},
+	"chartmuseum": {
+		ingressController: true,
+		ingressFrom:       []string{"argo", "argocd"},
+	},
+	"external-dns": {},
+	"external-secrets-operator": {
+		egressTo:     []string{"vault"},
+		webhookPorts: []int{10250},
+	},
+	"kubefirst": {
+		ingressController: true,
+		egressTo:          []string{"argocd", "vault"},
+	},
+	"vault": {
+		ingressController: true,
+		ingressFrom:       []string{"argo", "argocd", "atlantis", "external-secrets-operator", "kubefirst"},
+		webhookPorts:      []int{8080},
+	},
+}
+
+// writeNetworkPolicies adds default deny network policies and the allow rules
+// of NetworkPolicyNamespaces to the cluster type content of the driver
+// directory, namespaces of components left out of the registry are skipped
+func writeNetworkPolicies(driverDir string, clusterType string, droppedComponents []string, ingressController string) error {
+	dropped := map[string]bool{}
+	for _, component := range droppedComponents {
+		dropped[component] = true
+	}
+
+	namespaces := make([]string, 0, len(NetworkPolicyNamespaces))
+	for namespace := range NetworkPolicyNamespaces {
+		if !dropped[namespace] {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	sort.Strings(namespaces)
+
+	ingressNamespace := ResolveIngressController(ingressController).Component
+
+	documents := []string{}
+	for _, namespace := range namespaces {
+		for _, policy := range namespaceNetworkPolicies(namespace, NetworkPolicyNamespaces[namespace], ingressNamespace, dropped) {
+			document, err := yaml.Marshal(policy)
+			if err != nil {
+				return fmt.Errorf("error rendering network policy %s/%s: %s", namespace, policy.Name, err)
+			}
+			documents = append(documents, string(document))
+		}
+	}
+
+	for _, contentDir := range []string{
+		strings.ToLower(fmt.Sprintf("%s/templates/%s", driverDir, clusterType)),
+		strings.ToLower(fmt.Sprintf("%s/cluster-types/%s", driverDir, clusterType)),
+	} {
+		if _, err := os.Stat(contentDir); os.IsNotExist(err) {
+			continue
+		}
+
+		path := filepath.Join(contentDir, networkPoliciesFileName)
+		err := os.WriteFile(path, []byte(strings.Join(documents, "---\n")), 0o644)
+		if err != nil {
+			return fmt.Errorf("error writing network policies to %s: %s", path, err)
+		}
+		log.Info().Msgf("network policies: wrote default deny policies for %d namespaces", len(namespaces))
+
+		return nil
+	}
+
+	return fmt.Errorf("the gitops template has no %s cluster content to add network policies to", clusterType)
+}
+
+// namespaceNetworkPolicies returns the default deny policy of a namespace
+// and the policies allowing the traffic its components need
+func namespaceNetworkPolicies(namespace string, rules networkPolicyRules, ingressNamespace string, dropped map[string]bool) []networkingv1.NetworkPolicy {
+	tcp := corev1.ProtocolTCP
+	udp := corev1.ProtocolUDP
+
+	policies := []networkingv1.NetworkPolicy{
+		newNetworkPolicy(namespace, "default-deny-all", networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		}),
+		newNetworkPolicy(namespace, "allow-same-namespace", networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}}}},
+			Egress:      []networkingv1.NetworkPolicyEgressRule{{To: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}}}},
+		}),
+		newNetworkPolicy(namespace, "allow-dns", networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress: []networkingv1.NetworkPolicyEgressRule{{
+				To:    []networkingv1.NetworkPolicyPeer{namespacePeer("kube-system")},
+				Ports: []networkingv1.NetworkPolicyPort{networkPolicyPort(udp, 53), networkPolicyPort(tcp, 53)},
+			}},
+		}),
+	}
+
+	// the kubernetes api and cloud apis don't have stable addresses across
+	// providers so https is allowed to any destination
+	externalPorts := []networkingv1.NetworkPolicyPort{networkPolicyPort(tcp, 443), networkPolicyPort(tcp, 6443)}
+	for _, port := range rules.externalPorts {
+		externalPorts = append(externalPorts, networkPolicyPort(tcp, port))
+	}
+	policies = append(policies, newNetworkPolicy(namespace, "allow-external-egress", networkingv1.NetworkPolicySpec{
+		PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+		Egress:      []networkingv1.NetworkPolicyEgressRule{{Ports: externalPorts}},
+	}))
+
+	ingressFrom := []networkingv1.NetworkPolicyPeer{}
+	if rules.ingressController {
+		ingressFrom = append(ingressFrom, namespacePeer(ingressNamespace))
+	}
+	for _, source := range rules.ingressFrom {
+		if !dropped[source] {
+			ingressFrom = append(ingressFrom, namespacePeer(source))
+		}
+	}
+	if len(ingressFrom) != 0 {
+		policies = append(policies, newNetworkPolicy(namespace, "allow-platform-ingress", networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: ingressFrom}},
+		}))
+	}
+
+	// the api server's source address differs per provider, so webhook
+	// ports are open to any source
+	if len(rules.webhookPorts) != 0 {
+		webhookPorts := []networkingv1.NetworkPolicyPort{}
+		for _, port := range rules.webhookPorts {
+			webhookPorts = append(webhookPorts, networkPolicyPort(tcp, port))
+		}
+		policies = append(policies, newNetworkPolicy(namespace, "allow-webhook-ingress", networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{{Ports: webhookPorts}},
+		}))
+	}
+
+	egressTo := []networkingv1.NetworkPolicyPeer{}
+	for _, destination := range rules.egressTo {
+		if !dropped[destination] {
+			egressTo = append(egressTo, namespacePeer(destination))
+		}
+	}
+	if len(egressTo) != 0 {
+		policies = append(policies, newNetworkPolicy(namespace, "allow-platform-egress", networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress:      []networkingv1.NetworkPolicyEgressRule{{To: egressTo}},
+		}))
+	}
+
+	return policies
+}
+
+// newNetworkPolicy returns a policy selecting every pod of the namespace,
+// synced after the platform applications
+func newNetworkPolicy(namespace string, name string, spec networkingv1.NetworkPolicySpec) networkingv1.NetworkPolicy {
+	spec.PodSelector = metav1.LabelSelector{}
+
+	return networkingv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "networking.k8s.io/v1",
+			Kind:       "NetworkPolicy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Annotations: map[string]string{
+				"argocd.argoproj.io/sync-wave":    "100",
+				"argocd.argoproj.io/sync-options": "SkipDryRunOnMissingResource=true",
+			},
+		},
+		Spec: spec,
+	}
+}
+
+// namespacePeer selects every pod of a namespace
+func namespacePeer(namespace string) networkingv1.NetworkPolicyPeer {
+	return networkingv1.NetworkPolicyPeer{
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"kubernetes.io/metadata.name": namespace},
+		},
+	}
+}
+
+// networkPolicyPort returns a policy port
+func networkPolicyPort(protocol corev1.Protocol, port int) networkingv1.NetworkPolicyPort {
+	p := intstr.FromInt(port)
+
+	return networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &p}
+}
@@ -0,0 +1,36 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package providerConfigs
+
+import "testing"
+
+func TestNamespaceNetworkPoliciesWebhooks(t *testing.T) {
+	for namespace, wantPorts := range map[string][]int{
+		"cert-manager":              {10250},
+		"external-secrets-operator": {10250},
+		"vault":                     {8080},
+		"argo":                      nil,
+	} {
+		ports := []int{}
+		for _, policy := range namespaceNetworkPolicies(namespace, NetworkPolicyNamespaces[namespace], "ingress-nginx", map[string]bool{}) {
+			if policy.Name != "allow-webhook-ingress" {
+				continue
+			}
+			for _, rule := range policy.Spec.Ingress {
+				if len(rule.From) != 0 {
+					t.Errorf("%s: webhook ingress is limited to %v, want any source", namespace, rule.From)
+				}
+				for _, port := range rule.Ports {
+					ports = append(ports, port.Port.IntValue())
+				}
+			}
+		}
+		if len(ports) != len(wantPorts) || (len(ports) == 1 && ports[0] != wantPorts[0]) {
+			t.Errorf("%s: webhook ingress ports = %v, want %v", namespace, ports, wantPorts)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package providerConfigs
+
+import pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+
+// DefaultPodSecurityLevel is enforced on platform namespaces when the
+// definition doesn't choose a level
+const DefaultPodSecurityLevel = "restricted"
+
+// PodSecurityLevels are the pod security standard levels namespaces can be
+// labelled with
+var PodSecurityLevels = []string{"privileged", "baseline", "restricted"}
+
+// PodSecurityNamespaces maps the platform namespaces whose pod security level
+// can be overridden to the prefix of their gitops template token, e.g. vault
+// is rendered into <VAULT_POD_SECURITY_LEVEL>
+var PodSecurityNamespaces = map[string]string{
+	"argo":                      "ARGO",
+	"argocd":                    "ARGOCD",
+	"atlantis":                  "ATLANTIS",
+	"cert-manager":              "CERT_MANAGER",
+	"chartmuseum":               "CHARTMUSEUM",
+	"external-dns":              "EXTERNAL_DNS",
+	"external-secrets-operator": "EXTERNAL_SECRETS_OPERATOR",
+	"kubefirst":                 "KUBEFIRST",
+	"vault":                     "VAULT",
+}
+
+// ValidPodSecurityLevel reports whether level is a pod security standard level
+func ValidPodSecurityLevel(level string) bool {
+	for _, l := range PodSecurityLevels {
+		if l == level {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ResolvePodSecurityLevel returns the level enforced on namespace, its
+// override when set and otherwise the cluster wide level
+func ResolvePodSecurityLevel(podSecurity pkgtypes.PodSecurity, namespace string) string {
+	if level, ok := podSecurity.NamespaceOverrides[namespace]; ok && level != "" {
+		return level
+	}
+	if podSecurity.Level != "" {
+		return podSecurity.Level
+	}
+
+	return DefaultPodSecurityLevel
+}
@@ -0,0 +1,110 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package providerConfigs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// InstallProfiles maps each install profile to the registry components it
+// leaves out of the gitops repository, set with install_profile on the
+// cluster definition
+//
+//	full:     every component in the gitops template, the default
+//	standard: the platform without the metaphor sample application
+//	minimal:  standard without atlantis and the observability stack
+//	          (kube-prometheus-stack, prometheus, grafana, loki, datadog)
+var InstallProfiles = map[string][]string{
+	"full": {},
+	"standard": {
+		"metaphor",
+	},
+	"minimal": {
+		"metaphor",
+		"atlantis",
+		"kube-prometheus-stack",
+		"prometheus",
+		"grafana",
+		"loki",
+		"datadog",
+	},
+}
+
+// InstallProfileNames returns the supported install profiles sorted by name
+func InstallProfileNames() []string {
+	names := make([]string, 0, len(InstallProfiles))
+	for name := range InstallProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// registryEntryName strips the .yaml extension and the sync wave prefix
+// registry applications may carry, e.g. 40-atlantis.yaml is atlantis
+func registryEntryName(fileName string) string {
+	name := strings.TrimSuffix(fileName, ".yaml")
+	if idx := strings.Index(name, "-"); idx > 0 && strings.Trim(name[:idx], "0123456789") == "" {
+		name = name[idx+1:]
+	}
+
+	return name
+}
+
+// pruneInstallProfile removes the registry application and component
+// directory of every component the install profile leaves out from the
+// cluster type content of the driver directory, before it's copied into the
+// registry
+func pruneInstallProfile(driverDir string, clusterType string, installProfile string) error {
+	if installProfile == "" {
+		return nil
+	}
+
+	dropped, ok := InstallProfiles[installProfile]
+	if !ok {
+		return fmt.Errorf("unknown install profile %s: must be one of %v", installProfile, InstallProfileNames())
+	}
+
+	return pruneComponents(driverDir, clusterType, fmt.Sprintf("install profile %s", installProfile), dropped)
+}
+
+// pruneComponents removes the registry applications and component
+// directories of components from the cluster type content of the driver
+// directory, reason is logged with every removal
+func pruneComponents(driverDir string, clusterType string, reason string, dropped []string) error {
+	for _, contentDir := range []string{
+		strings.ToLower(fmt.Sprintf("%s/templates/%s", driverDir, clusterType)),
+		strings.ToLower(fmt.Sprintf("%s/cluster-types/%s", driverDir, clusterType)),
+	} {
+		entries, err := os.ReadDir(contentDir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error reading %s: %s", contentDir, err)
+		}
+
+		for _, component := range dropped {
+			for _, entry := range entries {
+				if registryEntryName(entry.Name()) == component {
+					log.Info().Msgf("%s: removing %s", reason, entry.Name())
+					os.RemoveAll(filepath.Join(contentDir, entry.Name()))
+				}
+			}
+			os.RemoveAll(filepath.Join(contentDir, "components", component))
+		}
+	}
+
+	return nil
+}
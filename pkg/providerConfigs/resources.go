@@ -0,0 +1,79 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package providerConfigs
+
+import pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+
+// ResourceOverrideComponents maps the platform components whose requests and
+// limits can be overridden to the prefix of their gitops template tokens, e.g.
+// vault is rendered into <VAULT_CPU_REQUEST>, <VAULT_MEMORY_REQUEST>,
+// <VAULT_CPU_LIMIT> and <VAULT_MEMORY_LIMIT>
+var ResourceOverrideComponents = map[string]string{
+	"argocd":  "ARGOCD",
+	"console": "CONSOLE",
+	"vault":   "VAULT",
+}
+
+// ResourceProfiles are named presets selected with resource_profile on the
+// cluster definition
+//
+// minimal trims the platform components down for small dev and local
+// clusters:
+//
+//	argocd:  requests 50m cpu / 128Mi memory, limits 500m cpu / 512Mi memory
+//	console: requests 25m cpu / 64Mi memory,  limits 250m cpu / 256Mi memory
+//	vault:   requests 50m cpu / 128Mi memory, limits 500m cpu / 256Mi memory
+var ResourceProfiles = map[string]map[string]pkgtypes.ComponentResources{
+	"minimal": {
+		"argocd": {
+			CPURequest:    "50m",
+			MemoryRequest: "128Mi",
+			CPULimit:      "500m",
+			MemoryLimit:   "512Mi",
+		},
+		"console": {
+			CPURequest:    "25m",
+			MemoryRequest: "64Mi",
+			CPULimit:      "250m",
+			MemoryLimit:   "256Mi",
+		},
+		"vault": {
+			CPURequest:    "50m",
+			MemoryRequest: "128Mi",
+			CPULimit:      "500m",
+			MemoryLimit:   "256Mi",
+		},
+	},
+}
+
+// ResolveResources merges explicit overrides on top of the named profile,
+// a value set in overrides always wins over the profile value
+func ResolveResources(profile string, overrides map[string]pkgtypes.ComponentResources) map[string]pkgtypes.ComponentResources {
+	resolved := map[string]pkgtypes.ComponentResources{}
+	for component, resources := range ResourceProfiles[profile] {
+		resolved[component] = resources
+	}
+
+	for component, override := range overrides {
+		resources := resolved[component]
+		if override.CPURequest != "" {
+			resources.CPURequest = override.CPURequest
+		}
+		if override.MemoryRequest != "" {
+			resources.MemoryRequest = override.MemoryRequest
+		}
+		if override.CPULimit != "" {
+			resources.CPULimit = override.CPULimit
+		}
+		if override.MemoryLimit != "" {
+			resources.MemoryLimit = override.MemoryLimit
+		}
+		resolved[component] = resources
+	}
+
+	return resolved
+}
@@ -0,0 +1,44 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package providerConfigs
+
+import pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+
+// SpotProviders are the cloud providers a spot node pool can be added in
+var SpotProviders = []string{"aws", "google"}
+
+// SpotNodeLabel marks the nodes of the spot node pool
+const (
+	SpotNodeLabelKey   = "kubefirst.io/capacity-type"
+	SpotNodeLabelValue = "spot"
+)
+
+// SpotNodeTaint keeps workloads that don't tolerate interruption off the
+// spot node pool
+var SpotNodeTaint = pkgtypes.NodeTaint{
+	Key:    "kubefirst.io/spot",
+	Value:  "true",
+	Effect: "NoSchedule",
+}
+
+// SpotNodeLabels returns the labels of the spot node pool, the cluster's
+// node labels plus SpotNodeLabelKey
+func SpotNodeLabels(nodeLabels map[string]string) map[string]string {
+	labels := make(map[string]string, len(nodeLabels)+1)
+	for key, value := range nodeLabels {
+		labels[key] = value
+	}
+	labels[SpotNodeLabelKey] = SpotNodeLabelValue
+
+	return labels
+}
+
+// SpotNodeTaints returns the taints of the spot node pool, the cluster's
+// node taints plus SpotNodeTaint
+func SpotNodeTaints(nodeTaints []pkgtypes.NodeTaint) []pkgtypes.NodeTaint {
+	return append(append([]pkgtypes.NodeTaint{}, nodeTaints...), SpotNodeTaint)
+}
@@ -0,0 +1,16 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package providerConfigs
+
+// VolumeSizeComponents maps the stateful platform components whose volume
+// size can be set to the prefix of their gitops template token, e.g. vault is
+// rendered into <VAULT_VOLUME_SIZE>
+var VolumeSizeComponents = map[string]string{
+	"atlantis":    "ATLANTIS",
+	"chartmuseum": "CHARTMUSEUM",
+	"vault":       "VAULT",
+}
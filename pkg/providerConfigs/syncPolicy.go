@@ -0,0 +1,190 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package providerConfigs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// writeSyncPolicy rewrites the sync policy of every argocd Application and
+// ApplicationSet below the driver directory, so the whole app-of-apps syncs
+// the way the cluster's sync policy says
+func writeSyncPolicy(driverDir string, policy pkgtypes.ArgoCDSyncPolicy) error {
+	updated := 0
+	err := filepath.Walk(driverDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if fi.Name() == ".git" || fi.Name() == "terraform" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+
+		changed, err := rewriteSyncPolicy(path, policy)
+		if err != nil {
+			return fmt.Errorf("error setting the sync policy of %s: %s", path, err)
+		}
+		if changed {
+			updated++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msgf("argocd sync policy: updated the applications of %d files", updated)
+
+	return nil
+}
+
+// rewriteSyncPolicy sets the sync policy of the argocd applications in a
+// yaml file, files that aren't plain yaml (e.g. helm templates) are skipped
+func rewriteSyncPolicy(path string, policy pkgtypes.ArgoCDSyncPolicy) (bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Contains(content, []byte("argoproj.io/")) {
+		return false, nil
+	}
+
+	documents := []*yaml.Node{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		document := &yaml.Node{}
+		err := decoder.Decode(document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return false, nil
+		}
+		documents = append(documents, document)
+	}
+
+	changed := false
+	for _, document := range documents {
+		if len(document.Content) == 0 {
+			continue
+		}
+		root := document.Content[0]
+		// values files and other non-manifest documents have no kind
+		kind := mappingValue(root, "kind")
+		if kind == nil {
+			continue
+		}
+
+		var spec *yaml.Node
+		switch kind.Value {
+		case "Application":
+			spec = mappingValue(root, "spec")
+		case "ApplicationSet":
+			spec = mappingValue(mappingValue(mappingValue(root, "spec"), "template"), "spec")
+		}
+		if spec == nil || spec.Kind != yaml.MappingNode {
+			continue
+		}
+
+		setSyncPolicy(spec, policy)
+		changed = true
+	}
+	if !changed {
+		return false, nil
+	}
+
+	rendered := make([]string, 0, len(documents))
+	for _, document := range documents {
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		err := encoder.Encode(document)
+		if err != nil {
+			return false, err
+		}
+		encoder.Close()
+		rendered = append(rendered, buf.String())
+	}
+
+	return true, os.WriteFile(path, []byte(strings.Join(rendered, "---\n")), 0o644)
+}
+
+// setSyncPolicy replaces the automated block of an application spec's
+// syncPolicy, its sync options and retry are kept
+func setSyncPolicy(spec *yaml.Node, policy pkgtypes.ArgoCDSyncPolicy) {
+	syncPolicy := mappingValue(spec, "syncPolicy")
+	if syncPolicy == nil || syncPolicy.Kind != yaml.MappingNode {
+		syncPolicy = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setMappingValue(spec, "syncPolicy", syncPolicy)
+	}
+
+	if policy.Mode == pkgtypes.SyncModeManual {
+		removeMappingKey(syncPolicy, "automated")
+		return
+	}
+
+	setMappingValue(syncPolicy, "automated", &yaml.Node{
+		Kind: yaml.MappingNode,
+		Tag:  "!!map",
+		Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: "prune"},
+			{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(!policy.DisablePrune)},
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: "selfHeal"},
+			{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(!policy.DisableSelfHeal)},
+		},
+	})
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// setMappingValue replaces or appends the value of key in a mapping node
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// removeMappingKey drops key and its value from a mapping node
+func removeMappingKey(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package providerConfigs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestRewriteSyncPolicy(t *testing.T) {
+	manual := pkgtypes.ArgoCDSyncPolicy{Mode: pkgtypes.SyncModeManual}
+
+	for name, test := range map[string]struct {
+		content     string
+		wantChanged bool
+	}{
+		"application": {
+			content:     "apiVersion: argoproj.io/v1alpha1\nkind: Application\nspec:\n  syncPolicy:\n    automated:\n      prune: true\n",
+			wantChanged: true,
+		},
+		"document without kind": {
+			content:     "image:\n  repository: argoproj.io/argocd\n",
+			wantChanged: false,
+		},
+		"document without kind before an application": {
+			content:     "annotations:\n  source: argoproj.io\n---\napiVersion: argoproj.io/v1alpha1\nkind: Application\nspec:\n  syncPolicy:\n    automated:\n      prune: true\n",
+			wantChanged: true,
+		},
+	} {
+		path := filepath.Join(t.TempDir(), "app.yaml")
+		err := os.WriteFile(path, []byte(test.content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		changed, err := rewriteSyncPolicy(path, manual)
+		if err != nil || changed != test.wantChanged {
+			t.Errorf("%s: rewriteSyncPolicy() = %v, %v, want %v", name, changed, err, test.wantChanged)
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.wantChanged && strings.Contains(string(content), "automated") {
+			t.Errorf("%s: expected the automated sync to be removed, got:\n%s", name, content)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package providerConfigs
+
+import pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+
+// nodeGroupTaintEffects maps the kubernetes taint effects to the names the
+// eks and gke node group apis expect
+var nodeGroupTaintEffects = map[string]string{
+	"NoSchedule":       "NO_SCHEDULE",
+	"PreferNoSchedule": "PREFER_NO_SCHEDULE",
+	"NoExecute":        "NO_EXECUTE",
+}
+
+// ProviderNodeTaints returns the taints as the cloud provider's terraform
+// expects them, aws and google take their node group api's effect names
+func ProviderNodeTaints(cloudProvider string, taints []pkgtypes.NodeTaint) []pkgtypes.NodeTaint {
+	providerTaints := make([]pkgtypes.NodeTaint, 0, len(taints))
+	for _, taint := range taints {
+		if effect, ok := nodeGroupTaintEffects[taint.Effect]; ok && (cloudProvider == "aws" || cloudProvider == "google") {
+			taint.Effect = effect
+		}
+		providerTaints = append(providerTaints, taint)
+	}
+
+	return providerTaints
+}
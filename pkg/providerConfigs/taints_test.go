@@ -0,0 +1,45 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package providerConfigs
+
+import (
+	"testing"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestProviderNodeTaints(t *testing.T) {
+	taints := []pkgtypes.NodeTaint{
+		{Key: "dedicated", Value: "gpu", Effect: "NoSchedule"},
+		{Key: "preferred", Effect: "PreferNoSchedule"},
+		{Key: "evicted", Effect: "NoExecute"},
+	}
+
+	for cloudProvider, want := range map[string][]string{
+		"aws":    {"NO_SCHEDULE", "PREFER_NO_SCHEDULE", "NO_EXECUTE"},
+		"google": {"NO_SCHEDULE", "PREFER_NO_SCHEDULE", "NO_EXECUTE"},
+		"civo":   {"NoSchedule", "PreferNoSchedule", "NoExecute"},
+		"vultr":  {"NoSchedule", "PreferNoSchedule", "NoExecute"},
+	} {
+		got := ProviderNodeTaints(cloudProvider, taints)
+		if len(got) != len(want) {
+			t.Fatalf("%s: ProviderNodeTaints() = %v", cloudProvider, got)
+		}
+		for i, taint := range got {
+			if taint.Effect != want[i] || taint.Key != taints[i].Key || taint.Value != taints[i].Value {
+				t.Errorf("%s: taint %d = %+v, want effect %s", cloudProvider, i, taint, want[i])
+			}
+		}
+	}
+
+	if taints[0].Effect != "NoSchedule" {
+		t.Error("expected the definition's taints to be left unchanged")
+	}
+	if got := ProviderNodeTaints("aws", nil); got == nil || len(got) != 0 {
+		t.Errorf("ProviderNodeTaints(nil) = %#v, want an empty list", got)
+	}
+}
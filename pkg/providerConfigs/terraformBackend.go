@@ -0,0 +1,170 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package providerConfigs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// terraformBackendOverrideFile is loaded by terraform after the entrypoint's
+// own files, its backend block replaces the template's backend
+const terraformBackendOverrideFile = "backend_override.tf"
+
+// TerraformBackendRequiredConfig lists the supported terraform backends and
+// the config arguments each requires
+var TerraformBackendRequiredConfig = map[string][]string{
+	"azurerm": {"storage_account_name", "container_name"},
+	"gcs":     {"bucket"},
+	"s3":      {"bucket", "region"},
+}
+
+// terraformBackendStateKey is the config argument each backend stores the
+// entrypoint's state under, it's derived from the key prefix
+var terraformBackendStateKey = map[string]string{
+	"azurerm": "key",
+	"gcs":     "prefix",
+	"s3":      "key",
+}
+
+var (
+	terraformBackendBlockRegex     = regexp.MustCompile(`backend\s+"[a-z0-9_]+"\s*\{`)
+	terraformBackendCredentialKeys = []string{"access_key", "secret_key", "token", "credentials", "sas_token", "client_secret", "password"}
+)
+
+// ValidateTerraformBackend makes sure a custom backend is supported, has its
+// required arguments and carries no credentials
+func ValidateTerraformBackend(backend pkgtypes.TerraformBackend) error {
+	if backend.Type == "" {
+		if len(backend.Config) != 0 || backend.KeyPrefix != "" {
+			return fmt.Errorf("terraform_backend.type is required when the backend is configured")
+		}
+		return nil
+	}
+
+	required, ok := TerraformBackendRequiredConfig[backend.Type]
+	if !ok {
+		types := make([]string, 0, len(TerraformBackendRequiredConfig))
+		for t := range TerraformBackendRequiredConfig {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		return fmt.Errorf("unsupported terraform backend %s: must be one of %v", backend.Type, types)
+	}
+
+	for _, key := range required {
+		if backend.Config[key] == "" {
+			return fmt.Errorf("terraform backend %s requires config %s", backend.Type, key)
+		}
+	}
+
+	stateKey := terraformBackendStateKey[backend.Type]
+	for key := range backend.Config {
+		if key == stateKey {
+			return fmt.Errorf("terraform backend config %s is set per entrypoint, use key_prefix instead", key)
+		}
+		for _, credentialKey := range terraformBackendCredentialKeys {
+			if strings.Contains(key, credentialKey) {
+				return fmt.Errorf("terraform backend config %s looks like a credential, provide it through the environment instead", key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// WriteTerraformBackendOverrides writes a backend override next to every
+// terraform entrypoint below gitopsDir that declares a backend, so local
+// runs and atlantis use the custom backend
+func WriteTerraformBackendOverrides(gitopsDir string, backend pkgtypes.TerraformBackend) error {
+	if backend.Type == "" {
+		return nil
+	}
+
+	entrypoints := map[string]bool{}
+	err := filepath.Walk(gitopsDir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() && (fi.Name() == ".git" || fi.Name() == ".terraform") {
+			return filepath.SkipDir
+		}
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".tf") || fi.Name() == terraformBackendOverrideFile {
+			return nil
+		}
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if terraformBackendBlockRegex.Match(content) {
+			entrypoints[filepath.Dir(p)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for entrypoint := range entrypoints {
+		relative, err := filepath.Rel(gitopsDir, entrypoint)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(filepath.Join(entrypoint, terraformBackendOverrideFile), []byte(renderTerraformBackend(backend, filepath.ToSlash(relative))), 0644)
+		if err != nil {
+			return fmt.Errorf("error writing terraform backend override for %s: %s", relative, err)
+		}
+		log.Info().Msgf("wrote %s terraform backend override for %s", backend.Type, relative)
+	}
+
+	return nil
+}
+
+// renderTerraformBackend renders the backend block for the entrypoint at
+// relativePath, storing its state below the backend's key prefix
+func renderTerraformBackend(backend pkgtypes.TerraformBackend, relativePath string) string {
+	config := map[string]string{}
+	for key, value := range backend.Config {
+		config[key] = value
+	}
+
+	statePath := path.Join(backend.KeyPrefix, relativePath)
+	if backend.Type == "gcs" {
+		config[terraformBackendStateKey[backend.Type]] = statePath
+	} else {
+		config[terraformBackendStateKey[backend.Type]] = path.Join(statePath, "terraform.tfstate")
+	}
+
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("# managed by kubefirst - the cluster definition's terraform_backend\n")
+	b.WriteString("terraform {\n")
+	b.WriteString(fmt.Sprintf("  backend %q {\n", backend.Type))
+	for _, key := range keys {
+		b.WriteString(fmt.Sprintf("    %s = %s\n", key, strconv.Quote(config[key])))
+	}
+	b.WriteString("  }\n")
+	b.WriteString("}\n")
+
+	return b.String()
+}
@@ -6,6 +6,8 @@ See the LICENSE file for more details.
 */
 package providerConfigs
 
+import pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
+
 type GitopsDirectoryValues struct {
 	AlertsEmail                    string
 	AtlantisAllowList              string
@@ -27,6 +29,27 @@ type GitopsDirectoryValues struct {
 	StateStoreBucketHostname       string
 	NodeType                       string
 	NodeCount                      int
+	NodeLabels                     map[string]string
+	NodeTaints                     []pkgtypes.NodeTaint
+	ImageOverrides                 map[string]string
+	StorageClass                   string
+	ResourceOverrides              map[string]pkgtypes.ComponentResources
+	InstallProfile                 string
+	ImagePullSecrets               []string
+	IngressController              string
+	LoadBalancerAnnotations        map[string]string
+	TerraformBackend               pkgtypes.TerraformBackend
+	VolumeSizes                    map[string]string
+	PodSecurity                    pkgtypes.PodSecurity
+	NetworkPolicies                bool
+	CNI                            pkgtypes.CNI
+	ExternalSecrets                pkgtypes.ExternalSecrets
+	SpotNodePool                   pkgtypes.SpotNodePool
+	Autoscaling                    pkgtypes.NodePoolAutoscaling
+	OIDC                           pkgtypes.OIDC
+	ArgoCDSyncPolicy               pkgtypes.ArgoCDSyncPolicy
+	DefaultBranch                  string
+	CommitMessage                  string
 	ArgoCDIngressURL               string
 	ArgoCDIngressNoHTTPSURL        string
 	ArgoWorkflowsIngressURL        string
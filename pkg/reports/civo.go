@@ -17,7 +17,7 @@ import (
 )
 
 // CivoHandoffScreen prints the handoff screen
-func CivoHandoffScreen(argocdAdminPassword, clusterName, domainName string, gitOwner string, config *providerConfigs.ProviderConfig, silentMode bool) {
+func CivoHandoffScreen(argocdAdminPassword, clusterName, domainName, argoCDHost string, gitOwner string, config *providerConfigs.ProviderConfig, silentMode bool) {
 	// prepare data for the handoff report
 	if silentMode {
 		log.Printf("[#99] Silent mode enabled, LocalHandoffScreen skipped, please check ~/.kubefirst file for your cluster and service credentials.")
@@ -47,7 +47,7 @@ func CivoHandoffScreen(argocdAdminPassword, clusterName, domainName string, gitO
 
 	handOffData.WriteString("\n--- ArgoCD ")
 	handOffData.WriteString(strings.Repeat("-", 59))
-	handOffData.WriteString(fmt.Sprintf("\n URL: https://argocd.%s", domainName))
+	handOffData.WriteString(fmt.Sprintf("\n URL: https://%s", argoCDHost))
 
 	handOffData.WriteString("\n--- Vault ")
 	handOffData.WriteString(strings.Repeat("-", 60))
@@ -0,0 +1,31 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package types
+
+// CatalogAppTemplate describes an installable application defined as a
+// parameterized argocd Application template
+type CatalogAppTemplate struct {
+	Name        string                `bson:"name" json:"name"`
+	DisplayName string                `bson:"display_name" json:"display_name"`
+	Description string                `bson:"description" json:"description"`
+	Category    string                `bson:"category" json:"category"`
+	ImageURL    string                `bson:"image_url,omitempty" json:"image_url,omitempty"`
+	Parameters  []CatalogAppParameter `bson:"parameters" json:"parameters"`
+	Template    string                `bson:"-" json:"-"`
+}
+
+// CatalogAppParameter describes a value a catalog app template accepts, Type
+// is one of string, integer or boolean
+type CatalogAppParameter struct {
+	Name        string      `bson:"name" json:"name"`
+	Description string      `bson:"description" json:"description"`
+	Type        string      `bson:"type" json:"type"`
+	Required    bool        `bson:"required" json:"required"`
+	Default     interface{} `bson:"default,omitempty" json:"default,omitempty"`
+	Pattern     string      `bson:"pattern,omitempty" json:"pattern,omitempty"`
+	Enum        []string    `bson:"enum,omitempty" json:"enum,omitempty"`
+}
@@ -8,7 +8,15 @@ package types
 
 // AkamaiAuth holds necessary auth credentials for interacting with civo
 type AkamaiAuth struct {
-	Token string `bson:"token" json:"token"`
+	Token  string `bson:"token" json:"token"`
+	APIURL string `bson:"api_url,omitempty" json:"api_url,omitempty"`
+}
+
+// ArgoCDCredentials holds the admin credentials and url for a cluster's argocd
+type ArgoCDCredentials struct {
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
 }
 
 // AWSAuth holds necessary auth credentials for interacting with aws
@@ -16,11 +24,13 @@ type AWSAuth struct {
 	AccessKeyID     string `bson:"access_key_id" json:"access_key_id"`
 	SecretAccessKey string `bson:"secret_access_key" json:"secret_access_key"`
 	SessionToken    string `bson:"session_token" json:"session_token"`
+	APIURL          string `bson:"api_url,omitempty" json:"api_url,omitempty"`
 }
 
 // CivoAuth holds necessary auth credentials for interacting with civo
 type CivoAuth struct {
-	Token string `bson:"token" json:"token"`
+	Token  string `bson:"token" json:"token"`
+	APIURL string `bson:"api_url,omitempty" json:"api_url,omitempty"`
 }
 
 // CloudflareAuth holds necessary auth credentials for interacting with vultr
@@ -28,6 +38,8 @@ type CloudflareAuth struct {
 	Token             string `bson:"token" json:"token"` // DEPRECATED: please transition to APIToken
 	APIToken          string `bson:"api_token" json:"api_token"`
 	OriginCaIssuerKey string `bson:"origin_ca_issuer_key" json:"origin_ca_issuer_key"`
+	// AccountID is only required when kubefirst creates the zone
+	AccountID string `bson:"account_id,omitempty" json:"account_id,omitempty"`
 }
 
 // DigitaloceanAuth holds necessary auth credentials for interacting with digitalocean
@@ -35,11 +47,13 @@ type DigitaloceanAuth struct {
 	Token        string `bson:"token" json:"token"`
 	SpacesKey    string `bson:"spaces_key" json:"spaces_key"`
 	SpacesSecret string `bson:"spaces_secret" json:"spaces_secret"`
+	APIURL       string `bson:"api_url,omitempty" json:"api_url,omitempty"`
 }
 
 // VultrAuth holds necessary auth credentials for interacting with vultr
 type VultrAuth struct {
-	Token string `bson:"token" json:"token"`
+	Token  string `bson:"token" json:"token"`
+	APIURL string `bson:"api_url,omitempty" json:"api_url,omitempty"`
 }
 
 // StateStoreCredentials
@@ -59,6 +73,28 @@ type GitAuth struct {
 	PublicKey  string `bson:"public_key,omitempty" json:"public_key,omitempty"`
 	PrivateKey string `bson:"private_key,omitempty" json:"private_key,omitempty"`
 	PublicKeys string `bson:"public_keys,omitempty" json:"public_keys,omitempty"`
+
+	// GitHub App credentials, used instead of a personal access token when
+	// set - Token then holds the current installation token
+	AppID             int64  `bson:"app_id,omitempty" json:"app_id,omitempty"`
+	AppInstallationID int64  `bson:"app_installation_id,omitempty" json:"app_installation_id,omitempty"`
+	AppPrivateKey     string `bson:"app_private_key,omitempty" json:"app_private_key,omitempty"`
+}
+
+// UsesGitHubApp reports whether the credentials are for a GitHub App
+// installation rather than a personal access token
+func (g GitAuth) UsesGitHubApp() bool {
+	return g.AppID != 0
+}
+
+// HasCredentials reports whether a token or the complete GitHub App
+// credentials are set
+func (g GitAuth) HasCredentials() bool {
+	if g.UsesGitHubApp() {
+		return g.AppInstallationID != 0 && g.AppPrivateKey != ""
+	}
+
+	return g.Token != ""
 }
 
 type VaultAuth struct {
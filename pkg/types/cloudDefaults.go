@@ -14,3 +14,12 @@ type CloudProviderDefaults struct {
 	Vultr        CloudDefault `json:"vultr"`
 	K3d          CloudDefault `json:"k3d"`
 }
+
+// ProvisionTimeouts bound, in seconds, how long the create pipeline waits
+// for the created cluster's control plane, for its node pools and for vault
+// to become ready - unset fields use the cloud provider's default
+type ProvisionTimeouts struct {
+	ClusterCreate int `bson:"cluster_create,omitempty" json:"cluster_create,omitempty"`
+	NodeReady     int `bson:"node_ready,omitempty" json:"node_ready,omitempty"`
+	VaultReady    int `bson:"vault_ready,omitempty" json:"vault_ready,omitempty"`
+}
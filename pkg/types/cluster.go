@@ -7,37 +7,119 @@ See the LICENSE file for more details.
 package types
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // ClusterDefinition describes an incoming request to create a cluster
 type ClusterDefinition struct {
+	SchemaVersion int `json:"schema_version,omitempty"`
+
 	//Cluster
-	AdminEmail             string             `json:"admin_email" binding:"required"`
-	CloudProvider          string             `json:"cloud_provider" binding:"required,oneof=akamai aws civo digitalocean google k3s vultr"`
-	CloudRegion            string             `json:"cloud_region" binding:"required"`
-	ClusterName            string             `json:"cluster_name,omitempty"`
-	DomainName             string             `json:"domain_name" binding:"required"`
-	SubdomainName          string             `json:"subdomain_name,omitempty"`
-	DnsProvider            string             `json:"dns_provider,omitempty" binding:"required"`
-	Type                   string             `json:"type" binding:"required,oneof=mgmt workload"`
-	ForceDestroy           bool               `bson:"force_destroy,omitempty" json:"force_destroy,omitempty"`
-	NodeType               string             `json:"node_type" binding:"required"`
-	NodeCount              int                `json:"node_count" binding:"required"`
-	PostInstallCatalogApps []GitopsCatalogApp `bson:"post_install_catalog_apps,omitempty" json:"post_install_catalog_apps,omitempty"`
-	InstallKubefirstPro    bool               `bson:"install_kubefirst_pro,omitempty" json:"install_kubefirst_pro,omitempty"`
+	AdminEmail              string                        `json:"admin_email" binding:"required"`
+	CloudProvider           string                        `json:"cloud_provider" binding:"required,oneof=akamai aws civo digitalocean google k3s vultr"`
+	CloudRegion             string                        `json:"cloud_region" binding:"required"`
+	Regions                 []string                      `json:"regions,omitempty"`
+	RegionGitOwners         map[string]string             `json:"region_git_owners,omitempty"`
+	ClusterName             string                        `json:"cluster_name,omitempty"`
+	ClusterGroup            string                        `json:"cluster_group,omitempty"`
+	Tags                    map[string]string             `json:"tags,omitempty"`
+	FeatureGates            map[string]bool               `json:"feature_gates,omitempty"`
+	DomainName              string                        `json:"domain_name" binding:"required"`
+	SubdomainName           string                        `json:"subdomain_name,omitempty"`
+	DnsProvider             string                        `json:"dns_provider,omitempty" binding:"required"`
+	DNSTTL                  int                           `json:"dns_ttl,omitempty"`
+	ArgoCDHost              string                        `json:"argocd_host,omitempty"`
+	ArgoCDSyncPolicy        ArgoCDSyncPolicy              `json:"argocd_sync_policy,omitempty"`
+	Type                    string                        `json:"type" binding:"required,oneof=mgmt workload"`
+	ForceDestroy            bool                          `bson:"force_destroy,omitempty" json:"force_destroy,omitempty"`
+	NodeType                string                        `json:"node_type" binding:"required"`
+	NodeCount               int                           `json:"node_count" binding:"required"`
+	NodeLabels              map[string]string             `json:"node_labels,omitempty"`
+	NodeTaints              []NodeTaint                   `json:"node_taints,omitempty"`
+	Autoscaling             NodePoolAutoscaling           `json:"autoscaling,omitempty"`
+	ImageOverrides          map[string]string             `json:"image_overrides,omitempty"`
+	ConsoleImage            string                        `json:"console_image,omitempty"`
+	StorageClass            string                        `json:"storage_class,omitempty"`
+	ResourceProfile         string                        `json:"resource_profile,omitempty"`
+	ResourceOverrides       map[string]ComponentResources `json:"resource_overrides,omitempty"`
+	InstallProfile          string                        `json:"install_profile,omitempty"`
+	ExistingNetwork         ExistingNetwork               `json:"existing_network,omitempty"`
+	EgressGateway           EgressGateway                 `json:"egress_gateway,omitempty"`
+	ImagePullSecrets        []ImagePullSecret             `json:"image_pull_secrets,omitempty"`
+	IngressController       string                        `json:"ingress_controller,omitempty"`
+	KubernetesVersion       string                        `json:"kubernetes_version,omitempty"`
+	LoadBalancerAnnotations map[string]string             `json:"load_balancer_annotations,omitempty"`
+	TerraformBackend        TerraformBackend              `json:"terraform_backend,omitempty"`
+	TerraformCloud          TerraformCloud                `json:"terraform_cloud,omitempty"`
+	TerraformHooks          TerraformHooks                `json:"terraform_hooks,omitempty"`
+	WorkloadIdentity        WorkloadIdentity              `json:"workload_identity,omitempty"`
+	VolumeSizes             map[string]string             `json:"volume_sizes,omitempty"`
+	PostInstallCatalogApps  []GitopsCatalogApp            `bson:"post_install_catalog_apps,omitempty" json:"post_install_catalog_apps,omitempty"`
+	PostInstallManifests    []PostInstallManifest         `bson:"post_install_manifests,omitempty" json:"post_install_manifests,omitempty"`
+	InstallKubefirstPro     bool                          `bson:"install_kubefirst_pro,omitempty" json:"install_kubefirst_pro,omitempty"`
+
+	PodSecurity     PodSecurity       `json:"pod_security,omitempty"`
+	NetworkPolicies bool              `json:"network_policies,omitempty"`
+	CNI             CNI               `json:"cni,omitempty"`
+	ExternalSecrets ExternalSecrets   `json:"external_secrets,omitempty"`
+	SpotNodePool    SpotNodePool      `json:"spot_node_pool,omitempty"`
+	Timeouts        ProvisionTimeouts `json:"timeouts,omitempty"`
+	UserDirectory   UserDirectory     `json:"user_directory,omitempty"`
+	OIDC            OIDC              `json:"oidc,omitempty"`
+
+	// StopAfterStep halts provisioning after the named step, leaving the
+	// resources up for inspection, and ResumeFrom skips the steps before the
+	// named one on a later create - both are meant for provider development
+	StopAfterStep string `json:"stop_after_step,omitempty"`
+	ResumeFrom    string `json:"resume_from,omitempty"`
 
 	// Git
 
 	// Git
+	// GitopsTemplateURL and GitopsTemplateBranch select the gitops template
+	// repository (e.g. a fork) and the branch or tag to clone it at,
+	// GitopsTemplateCommit optionally pins the clone to a commit on that ref
 	GitopsTemplateURL    string `json:"gitops_template_url"`
 	GitopsTemplateBranch string `json:"gitops_template_branch"`
-	GitProvider          string `json:"git_provider" binding:"required,oneof=github gitlab"`
-	GitProtocol          string `json:"git_protocol" binding:"required,oneof=ssh https"`
+	GitopsTemplateCommit string `json:"gitops_template_commit,omitempty"`
+	// SkipManifestValidation pushes the gitops repository without validating
+	// its manifests first
+	SkipManifestValidation bool   `json:"skip_manifest_validation,omitempty"`
+	GitProvider            string `json:"git_provider" binding:"required,oneof=github gitlab"`
+	GitProtocol            string `json:"git_protocol" binding:"required,oneof=ssh https"`
+	// AdoptRepositories pushes into gitops and metaphor repositories that
+	// already exist (e.g. from a partial install) when they're empty,
+	// ForceAdoptRepositories also adopts and overwrites non-empty ones
+	AdoptRepositories      bool `json:"adopt_repositories,omitempty"`
+	ForceAdoptRepositories bool `json:"force_adopt_repositories,omitempty"`
+	// GitopsMirrors are secondary remotes the gitops repository is pushed to
+	// alongside the git provider, e.g. for disaster recovery
+	GitopsMirrors []GitMirror `json:"gitops_mirrors,omitempty"`
+	// MetaphorOwner is the github organization or user the metaphor
+	// repository is created under when it differs from git_auth.owner
+	MetaphorOwner string `json:"metaphor_owner,omitempty"`
+	// DefaultBranch is the branch the gitops and metaphor repositories are
+	// pushed to, CommitMessage replaces the message of their initial commits
+	DefaultBranch string `json:"default_branch,omitempty"`
+	CommitMessage string `json:"commit_message,omitempty"`
+	// AtlantisWebhookSecret replaces the generated secret the gitops
+	// repository's atlantis webhook is signed with
+	AtlantisWebhookSecret string `json:"atlantis_webhook_secret,omitempty"`
+
+	// KubeconfigContextName renames the context of the cluster's kubeconfig
+	// file and the cluster and user entries it references, aws and google
+	// build their clients from the cloud credentials and have no such file
+	KubeconfigContextName string `json:"kubeconfig_context_name,omitempty"`
 
 	// AWS
 	ECR bool `json:"ecr,omitempty"`
 
+	// State store
+	ExistingStateStoreBucket string `json:"existing_state_store_bucket,omitempty"`
+	StateStoreKMSKey         string `json:"state_store_kms_key,omitempty"`
+
 	//Auth
 	AkamaiAuth       AkamaiAuth       `json:"akamai_auth,omitempty"`
 	AWSAuth          AWSAuth          `json:"aws_auth,omitempty"`
@@ -55,23 +137,36 @@ type ClusterDefinition struct {
 type Cluster struct {
 	ID                primitive.ObjectID `bson:"_id" json:"_id"`
 	CreationTimestamp string             `bson:"creation_timestamp" json:"creation_timestamp"`
+	SchemaVersion     int                `bson:"schema_version" json:"schema_version"`
 
 	// Status
 	Status        string `bson:"status" json:"status"`
 	LastCondition string `bson:"last_condition" json:"last_condition"`
-	InProgress    bool   `bson:"in_progress" json:"in_progress"`
+	// InterruptedStep is the step in progress when the api shut down
+	InterruptedStep string `bson:"interrupted_step,omitempty" json:"interrupted_step,omitempty"`
+	// PausedAfterStep is the stop_after_step breakpoint provisioning halted at
+	PausedAfterStep string `bson:"paused_after_step,omitempty" json:"paused_after_step,omitempty"`
+	InProgress      bool   `bson:"in_progress" json:"in_progress"`
+	QueuePosition   int    `bson:"queue_position,omitempty" json:"queue_position,omitempty"`
 
 	// Identifiers
-	AlertsEmail            string             `bson:"alerts_email" json:"alerts_email"`
-	CloudProvider          string             `bson:"cloud_provider" json:"cloud_provider"`
-	CloudRegion            string             `bson:"cloud_region" json:"cloud_region"`
-	ClusterName            string             `bson:"cluster_name" json:"cluster_name"`
-	ClusterID              string             `bson:"cluster_id" json:"cluster_id"`
-	ClusterType            string             `bson:"cluster_type" json:"cluster_type"`
-	DomainName             string             `bson:"domain_name" json:"domain_name"`
-	SubdomainName          string             `bson:"subdomain_name" json:"subdomain_name,omitempty"`
-	DnsProvider            string             `bson:"dns_provider" json:"dns_provider"`
-	PostInstallCatalogApps []GitopsCatalogApp `bson:"post_install_catalog_apps,omitempty" json:"post_install_catalog_apps,omitempty"`
+	AlertsEmail                string                      `bson:"alerts_email" json:"alerts_email"`
+	CloudProvider              string                      `bson:"cloud_provider" json:"cloud_provider"`
+	CloudRegion                string                      `bson:"cloud_region" json:"cloud_region"`
+	ClusterName                string                      `bson:"cluster_name" json:"cluster_name"`
+	ClusterID                  string                      `bson:"cluster_id" json:"cluster_id"`
+	ClusterType                string                      `bson:"cluster_type" json:"cluster_type"`
+	ClusterGroup               string                      `bson:"cluster_group,omitempty" json:"cluster_group,omitempty"`
+	Tags                       map[string]string           `bson:"tags,omitempty" json:"tags,omitempty"`
+	FeatureGates               map[string]bool             `bson:"feature_gates,omitempty" json:"feature_gates,omitempty"`
+	DomainName                 string                      `bson:"domain_name" json:"domain_name"`
+	SubdomainName              string                      `bson:"subdomain_name" json:"subdomain_name,omitempty"`
+	DnsProvider                string                      `bson:"dns_provider" json:"dns_provider"`
+	DNSTTL                     int                         `bson:"dns_ttl,omitempty" json:"dns_ttl,omitempty"`
+	PostInstallCatalogApps     []GitopsCatalogApp          `bson:"post_install_catalog_apps,omitempty" json:"post_install_catalog_apps,omitempty"`
+	PostInstallManifests       []PostInstallManifest       `bson:"post_install_manifests,omitempty" json:"post_install_manifests,omitempty"`
+	PostInstallManifestResults []PostInstallManifestResult `bson:"post_install_manifest_results,omitempty" json:"post_install_manifest_results,omitempty"`
+	DefaultServiceResults      []DefaultServiceResult      `bson:"default_service_results,omitempty" json:"default_service_results,omitempty"`
 
 	// Auth
 	AkamaiAuth       AkamaiAuth       `bson:"akamai_auth,omitempty" json:"akamai_auth,omitempty"`
@@ -85,26 +180,89 @@ type Cluster struct {
 	GoogleAuth       GoogleAuth       `bson:"google_auth,omitempty" json:"google_auth,omitempty"`
 	K3sAuth          K3sAuth          `bson:"k3s_auth,omitempty" json:"k3s_auth,omitempty"`
 
-	GitopsTemplateURL    string `bson:"gitops_template_url" json:"gitops_template_url"`
-	GitopsTemplateBranch string `bson:"gitops_template_branch" json:"gitops_template_branch"`
-	GitProvider          string `bson:"git_provider" json:"git_provider"`
-	GitProtocol          string `bson:"git_protocol" json:"git_protocol"`
-	GitHost              string `bson:"git_host" json:"git_host"`
-	GitlabOwnerGroupID   int    `bson:"gitlab_owner_group_id" json:"gitlab_owner_group_id"`
+	// ProvisionedUsers are the users the users terraform created, their
+	// initial passwords stay in vault
+	ProvisionedUsers []ProvisionedUser `bson:"provisioned_users,omitempty" json:"provisioned_users,omitempty"`
+
+	GitopsTemplateURL            string `bson:"gitops_template_url" json:"gitops_template_url"`
+	GitopsTemplateBranch         string `bson:"gitops_template_branch" json:"gitops_template_branch"`
+	GitopsTemplateCommit         string `bson:"gitops_template_commit,omitempty" json:"gitops_template_commit,omitempty"`
+	GitopsTemplateResolvedCommit string `bson:"gitops_template_resolved_commit,omitempty" json:"gitops_template_resolved_commit,omitempty"`
+	SkipManifestValidation       bool   `bson:"skip_manifest_validation,omitempty" json:"skip_manifest_validation,omitempty"`
+	GitProvider                  string `bson:"git_provider" json:"git_provider"`
+	GitProtocol                  string `bson:"git_protocol" json:"git_protocol"`
+	GitHost                      string `bson:"git_host" json:"git_host"`
+	GitlabOwnerGroupID           int    `bson:"gitlab_owner_group_id" json:"gitlab_owner_group_id"`
+
+	AdoptRepositories      bool     `bson:"adopt_repositories,omitempty" json:"adopt_repositories,omitempty"`
+	ForceAdoptRepositories bool     `bson:"force_adopt_repositories,omitempty" json:"force_adopt_repositories,omitempty"`
+	AdoptedRepositories    []string `bson:"adopted_repositories,omitempty" json:"adopted_repositories,omitempty"`
+	AdoptedTeams           []string `bson:"adopted_teams,omitempty" json:"adopted_teams,omitempty"`
+	// DeletedRepositories records the repositories DeleteRepositories removed
+	DeletedRepositories []DeletedRepository `bson:"deleted_repositories,omitempty" json:"deleted_repositories,omitempty"`
+
+	GitopsMirrors        []GitMirror       `bson:"gitops_mirrors,omitempty" json:"gitops_mirrors,omitempty"`
+	GitopsMirrorStatuses []GitMirrorStatus `bson:"gitops_mirror_statuses,omitempty" json:"gitops_mirror_statuses,omitempty"`
+	MetaphorOwner        string            `bson:"metaphor_owner,omitempty" json:"metaphor_owner,omitempty"`
+	DefaultBranch        string            `bson:"default_branch,omitempty" json:"default_branch,omitempty"`
+	CommitMessage        string            `bson:"commit_message,omitempty" json:"commit_message,omitempty"`
+
+	// AtlantisWebhookSecretRotatedAt records when RotateGitWebhookSecret
+	// last replaced the webhook secret
+	AtlantisWebhookSecretRotatedAt time.Time `bson:"atlantis_webhook_secret_rotated_at,omitempty" json:"atlantis_webhook_secret_rotated_at,omitempty"`
+
+	AtlantisWebhookSecret   string                        `bson:"atlantis_webhook_secret" json:"atlantis_webhook_secret"`
+	AtlantisWebhookURL      string                        `bson:"atlantis_webhook_url" json:"atlantis_webhook_url"`
+	KubefirstTeam           string                        `bson:"kubefirst_team" json:"kubefirst_team"`
+	NodeType                string                        `bson:"node_type" json:"node_type" binding:"required"`
+	NodeCount               int                           `bson:"node_count" json:"node_count" binding:"required"`
+	NodeLabels              map[string]string             `bson:"node_labels,omitempty" json:"node_labels,omitempty"`
+	NodeTaints              []NodeTaint                   `bson:"node_taints,omitempty" json:"node_taints,omitempty"`
+	Autoscaling             NodePoolAutoscaling           `bson:"autoscaling,omitempty" json:"autoscaling,omitempty"`
+	NodePoolBounds          []NodePoolBounds              `bson:"node_pool_bounds,omitempty" json:"node_pool_bounds,omitempty"`
+	ImageOverrides          map[string]string             `bson:"image_overrides,omitempty" json:"image_overrides,omitempty"`
+	ConsoleImage            string                        `bson:"console_image,omitempty" json:"console_image,omitempty"`
+	StorageClass            string                        `bson:"storage_class,omitempty" json:"storage_class,omitempty"`
+	ResourceProfile         string                        `bson:"resource_profile,omitempty" json:"resource_profile,omitempty"`
+	ResourceOverrides       map[string]ComponentResources `bson:"resource_overrides,omitempty" json:"resource_overrides,omitempty"`
+	InstallProfile          string                        `bson:"install_profile,omitempty" json:"install_profile,omitempty"`
+	ExistingNetwork         ExistingNetwork               `bson:"existing_network,omitempty" json:"existing_network,omitempty"`
+	EgressGateway           EgressGateway                 `bson:"egress_gateway,omitempty" json:"egress_gateway,omitempty"`
+	ImagePullSecrets        []ImagePullSecret             `bson:"image_pull_secrets,omitempty" json:"image_pull_secrets,omitempty"`
+	IngressController       string                        `bson:"ingress_controller,omitempty" json:"ingress_controller,omitempty"`
+	KubernetesVersion       string                        `bson:"kubernetes_version,omitempty" json:"kubernetes_version,omitempty"`
+	LoadBalancerAnnotations map[string]string             `bson:"load_balancer_annotations,omitempty" json:"load_balancer_annotations,omitempty"`
+	TerraformBackend        TerraformBackend              `bson:"terraform_backend,omitempty" json:"terraform_backend,omitempty"`
+	TerraformCloud          TerraformCloud                `bson:"terraform_cloud,omitempty" json:"terraform_cloud,omitempty"`
+	TerraformCloudRuns      map[string]TerraformCloudRun  `bson:"terraform_cloud_runs,omitempty" json:"terraform_cloud_runs,omitempty"`
+	TerraformHooks          TerraformHooks                `bson:"terraform_hooks,omitempty" json:"terraform_hooks,omitempty"`
+	TerraformHookResults    []TerraformHookResult         `bson:"terraform_hook_results,omitempty" json:"terraform_hook_results,omitempty"`
+	WorkloadIdentity        WorkloadIdentity              `bson:"workload_identity,omitempty" json:"workload_identity,omitempty"`
+	VolumeSizes             map[string]string             `bson:"volume_sizes,omitempty" json:"volume_sizes,omitempty"`
+	LogFileName             string                        `bson:"log_file,omitempty" json:"log_file,omitempty"`
+
+	PodSecurity     PodSecurity       `bson:"pod_security,omitempty" json:"pod_security,omitempty"`
+	NetworkPolicies bool              `bson:"network_policies,omitempty" json:"network_policies,omitempty"`
+	CNI             CNI               `bson:"cni,omitempty" json:"cni,omitempty"`
+	ExternalSecrets ExternalSecrets   `bson:"external_secrets,omitempty" json:"external_secrets,omitempty"`
+	SpotNodePool    SpotNodePool      `bson:"spot_node_pool,omitempty" json:"spot_node_pool,omitempty"`
+	Timeouts        ProvisionTimeouts `bson:"timeouts,omitempty" json:"timeouts,omitempty"`
+	UserDirectory   UserDirectory     `bson:"user_directory,omitempty" json:"user_directory,omitempty"`
+	OIDC            OIDC              `bson:"oidc,omitempty" json:"oidc,omitempty"`
 
-	AtlantisWebhookSecret string `bson:"atlantis_webhook_secret" json:"atlantis_webhook_secret"`
-	AtlantisWebhookURL    string `bson:"atlantis_webhook_url" json:"atlantis_webhook_url"`
-	KubefirstTeam         string `bson:"kubefirst_team" json:"kubefirst_team"`
-	NodeType              string `bson:"node_type" json:"node_type" binding:"required"`
-	NodeCount             int    `bson:"node_count" json:"node_count" binding:"required"`
-	LogFileName           string `bson:"log_file,omitempty" json:"log_file,omitempty"`
+	KubeconfigContextName string `bson:"kubeconfig_context_name,omitempty" json:"kubeconfig_context_name,omitempty"`
 
-	StateStoreCredentials StateStoreCredentials `bson:"state_store_credentials,omitempty" json:"state_store_credentials,omitempty"`
-	StateStoreDetails     StateStoreDetails     `bson:"state_store_details,omitempty" json:"state_store_details,omitempty"`
+	StateStoreCredentials          StateStoreCredentials `bson:"state_store_credentials,omitempty" json:"state_store_credentials,omitempty"`
+	StateStoreCredentialsRotatedAt time.Time             `bson:"state_store_credentials_rotated_at,omitempty" json:"state_store_credentials_rotated_at,omitempty"`
+	StateStoreDetails              StateStoreDetails     `bson:"state_store_details,omitempty" json:"state_store_details,omitempty"`
+	ExistingStateStoreBucket       string                `bson:"existing_state_store_bucket,omitempty" json:"existing_state_store_bucket,omitempty"`
+	StateStoreKMSKey               string                `bson:"state_store_kms_key,omitempty" json:"state_store_kms_key,omitempty"`
 
-	ArgoCDUsername  string `bson:"argocd_username" json:"argocd_username"`
-	ArgoCDPassword  string `bson:"argocd_password" json:"argocd_password"`
-	ArgoCDAuthToken string `bson:"argocd_auth_token" json:"argocd_auth_token"`
+	ArgoCDUsername   string           `bson:"argocd_username" json:"argocd_username"`
+	ArgoCDPassword   string           `bson:"argocd_password" json:"argocd_password"`
+	ArgoCDAuthToken  string           `bson:"argocd_auth_token" json:"argocd_auth_token"`
+	ArgoCDHost       string           `bson:"argocd_host,omitempty" json:"argocd_host,omitempty"`
+	ArgoCDSyncPolicy ArgoCDSyncPolicy `bson:"argocd_sync_policy,omitempty" json:"argocd_sync_policy,omitempty"`
 
 	// Container Registry and Secrets
 	ECR bool `bson:"ecr" json:"ecr"`
@@ -137,9 +295,40 @@ type Cluster struct {
 	VaultInitializedCheck          bool              `bson:"vault_initialized_check" json:"vault_initialized_check"`
 	VaultTerraformApplyCheck       bool              `bson:"vault_terraform_apply_check" json:"vault_terraform_apply_check"`
 	UsersTerraformApplyCheck       bool              `bson:"users_terraform_apply_check" json:"users_terraform_apply_check"`
+	PostInstallManifestsCheck      bool              `bson:"post_install_manifests_check" json:"post_install_manifests_check"`
+	WorkloadIdentityCheck          bool              `bson:"workload_identity_check" json:"workload_identity_check"`
+	ImagePullSecretsCheck          bool              `bson:"image_pull_secrets_check" json:"image_pull_secrets_check"`
+	EgressIPVerifiedCheck          bool              `bson:"egress_ip_verified_check" json:"egress_ip_verified_check"`
+	CNIInstallCheck                bool              `bson:"cni_install_check" json:"cni_install_check"`
 	WorkloadClusters               []WorkloadCluster `bson:"workload_clusters,omitempty" json:"workload_clusters,omitempty"`
 }
 
+// ClusterGroup describes a set of clusters provisioned from a single
+// multi-region cluster definition
+type ClusterGroup struct {
+	Name     string    `bson:"name" json:"name"`
+	Status   string    `bson:"status" json:"status"`
+	Clusters []Cluster `bson:"clusters" json:"clusters"`
+}
+
+// FleetMetrics are statistics across the cluster records, installs are
+// counted for the clusters created between From and To
+type FleetMetrics struct {
+	From  time.Time `bson:"from" json:"from"`
+	To    time.Time `bson:"to" json:"to"`
+	Total int       `bson:"total" json:"total"`
+	// ByRegion is keyed by <cloud provider>/<region>
+	ByStatus               map[string]int `bson:"by_status" json:"by_status"`
+	ByProvider             map[string]int `bson:"by_provider" json:"by_provider"`
+	ByRegion               map[string]int `bson:"by_region" json:"by_region"`
+	TotalNodes             int            `bson:"total_nodes" json:"total_nodes"`
+	OldestCluster          string         `bson:"oldest_cluster,omitempty" json:"oldest_cluster,omitempty"`
+	OldestClusterCreatedAt time.Time      `bson:"oldest_cluster_created_at,omitempty" json:"oldest_cluster_created_at,omitempty"`
+	InstallsSucceeded      int            `bson:"installs_succeeded" json:"installs_succeeded"`
+	InstallsFailed         int            `bson:"installs_failed" json:"installs_failed"`
+	InstallSuccessRate     float64        `bson:"install_success_rate" json:"install_success_rate"`
+}
+
 // StateStoreDetails
 type StateStoreDetails struct {
 	Name                string `bson:"name,omitempty" json:"name,omitempty"`
@@ -194,3 +383,321 @@ type WorkloadCluster struct {
 type WorkloadClusterSet struct {
 	Clusters []WorkloadCluster `json:"clusters"`
 }
+
+// NodeTaint describes a taint applied to the nodes of a cluster's node pool
+type NodeTaint struct {
+	Key    string `bson:"key" json:"key"`
+	Value  string `bson:"value,omitempty" json:"value,omitempty"`
+	Effect string `bson:"effect" json:"effect"`
+}
+
+// SpotNodePool adds a node pool of spot instances next to the cluster's
+// on demand nodes, the pool is enabled when NodeCount is set and NodeType
+// defaults to the cluster's node type
+type SpotNodePool struct {
+	NodeType    string              `bson:"node_type,omitempty" json:"node_type,omitempty"`
+	NodeCount   int                 `bson:"node_count,omitempty" json:"node_count,omitempty"`
+	Autoscaling NodePoolAutoscaling `bson:"autoscaling,omitempty" json:"autoscaling,omitempty"`
+}
+
+// NodePoolAutoscaling lets the cloud provider's cluster autoscaler scale a
+// node pool between MinNodes and MaxNodes, the pool keeps its node count when
+// MaxNodes isn't set
+type NodePoolAutoscaling struct {
+	MinNodes int `bson:"min_nodes,omitempty" json:"min_nodes,omitempty"`
+	MaxNodes int `bson:"max_nodes,omitempty" json:"max_nodes,omitempty"`
+}
+
+// NodePoolBounds are the node counts a node pool is kept between, equal to
+// its node count when it doesn't autoscale
+type NodePoolBounds struct {
+	Pool        string `bson:"pool" json:"pool"`
+	MinNodes    int    `bson:"min_nodes" json:"min_nodes"`
+	MaxNodes    int    `bson:"max_nodes" json:"max_nodes"`
+	Autoscaling bool   `bson:"autoscaling" json:"autoscaling"`
+}
+
+// ComponentResources overrides the resource requests and limits of a
+// platform component, empty values keep the chart default
+type ComponentResources struct {
+	CPURequest    string `bson:"cpu_request,omitempty" json:"cpu_request,omitempty"`
+	MemoryRequest string `bson:"memory_request,omitempty" json:"memory_request,omitempty"`
+	CPULimit      string `bson:"cpu_limit,omitempty" json:"cpu_limit,omitempty"`
+	MemoryLimit   string `bson:"memory_limit,omitempty" json:"memory_limit,omitempty"`
+}
+
+// PodSecurity sets the pod security standard enforced on platform
+// namespaces, NamespaceOverrides relaxes or tightens it per namespace for
+// components that need an exception
+type PodSecurity struct {
+	Level              string            `bson:"level,omitempty" json:"level,omitempty"`
+	NamespaceOverrides map[string]string `bson:"namespace_overrides,omitempty" json:"namespace_overrides,omitempty"`
+}
+
+// ExternalSecrets replaces vault with a cloud secret manager that external
+// secrets operator reads platform secrets from, secrets are stored below
+// Prefix which defaults to kubefirst-<cluster name>
+type ExternalSecrets struct {
+	Provider string `bson:"provider,omitempty" json:"provider,omitempty"`
+	Prefix   string `bson:"prefix,omitempty" json:"prefix,omitempty"`
+}
+
+// TerraformBackend replaces the backend of every terraform entrypoint in the
+// gitops repository, each entrypoint's state is stored below KeyPrefix
+// (e.g. <key_prefix>/terraform/vault/terraform.tfstate) - backend
+// credentials are read from the environment and must not be set in Config
+type TerraformBackend struct {
+	Type      string            `bson:"type,omitempty" json:"type,omitempty"`
+	Config    map[string]string `bson:"config,omitempty" json:"config,omitempty"`
+	KeyPrefix string            `bson:"key_prefix,omitempty" json:"key_prefix,omitempty"`
+}
+
+// TerraformCloud executes the cloud and git terraform entrypoints as runs in
+// a terraform cloud/enterprise organization instead of applying them locally,
+// the api token is read from TFE_TOKEN
+type TerraformCloud struct {
+	Organization    string `bson:"organization,omitempty" json:"organization,omitempty"`
+	Hostname        string `bson:"hostname,omitempty" json:"hostname,omitempty"`
+	WorkspacePrefix string `bson:"workspace_prefix,omitempty" json:"workspace_prefix,omitempty"`
+}
+
+// TerraformHooks are commands run on the api host before and after each
+// terraform entrypoint is applied, a failing hook fails the step
+type TerraformHooks struct {
+	Pre  []TerraformHook `bson:"pre,omitempty" json:"pre,omitempty"`
+	Post []TerraformHook `bson:"post,omitempty" json:"post,omitempty"`
+}
+
+// TerraformHook is a shell command and the directory it runs in, relative
+// directories are resolved against the gitops repository and the entrypoint
+// is used when empty. Entrypoints limits the hook to the named terraform
+// entrypoints, e.g. aws, github, vault or users
+type TerraformHook struct {
+	Command        string   `bson:"command" json:"command"`
+	WorkingDir     string   `bson:"working_dir,omitempty" json:"working_dir,omitempty"`
+	Entrypoints    []string `bson:"entrypoints,omitempty" json:"entrypoints,omitempty"`
+	TimeoutSeconds int      `bson:"timeout_seconds,omitempty" json:"timeout_seconds,omitempty"`
+}
+
+// TerraformHookResult records a terraform hook run and the tail of its output
+type TerraformHookResult struct {
+	Entrypoint string    `bson:"entrypoint" json:"entrypoint"`
+	Stage      string    `bson:"stage" json:"stage"`
+	Command    string    `bson:"command" json:"command"`
+	Passed     bool      `bson:"passed" json:"passed"`
+	Output     string    `bson:"output,omitempty" json:"output,omitempty"`
+	Error      string    `bson:"error,omitempty" json:"error,omitempty"`
+	RanAt      time.Time `bson:"ran_at" json:"ran_at"`
+}
+
+// TerraformCloudRun is the latest terraform cloud run of an entrypoint
+type TerraformCloudRun struct {
+	Workspace string `bson:"workspace" json:"workspace"`
+	RunID     string `bson:"run_id" json:"run_id"`
+	URL       string `bson:"url" json:"url"`
+	Status    string `bson:"status" json:"status"`
+}
+
+// ExistingNetwork points the cloud terraform at a pre-existing vpc/network
+// and its subnets instead of creating new ones
+type ExistingNetwork struct {
+	NetworkID string   `bson:"network_id,omitempty" json:"network_id,omitempty"`
+	SubnetIDs []string `bson:"subnet_ids,omitempty" json:"subnet_ids,omitempty"`
+}
+
+// EgressGateway routes the nodes' outbound traffic through a NAT gateway with
+// a static public ip, for allowlisting at external services - the terraform
+// creates the gateway with EgressIP unless NatGatewayID names an existing one
+type EgressGateway struct {
+	EgressIP     string `bson:"egress_ip,omitempty" json:"egress_ip,omitempty"`
+	NatGatewayID string `bson:"nat_gateway_id,omitempty" json:"nat_gateway_id,omitempty"`
+}
+
+// CNI replaces the cloud provider's default container network interface, the
+// manifests at ManifestURL are applied on providers where kubefirst installs
+// the cni itself
+type CNI struct {
+	Name        string `bson:"name,omitempty" json:"name,omitempty"`
+	ManifestURL string `bson:"manifest_url,omitempty" json:"manifest_url,omitempty"`
+}
+
+// Argo CD sync modes of an ArgoCDSyncPolicy
+const (
+	SyncModeAutomated = "automated"
+	SyncModeManual    = "manual"
+)
+
+// ArgoCDSyncPolicy selects how argocd syncs the registry application and the
+// applications of the app-of-apps, Mode defaults to automated with prune and
+// self heal - manual applications only sync when promoted
+type ArgoCDSyncPolicy struct {
+	Mode            string `bson:"mode,omitempty" json:"mode,omitempty"`
+	DisablePrune    bool   `bson:"disable_prune,omitempty" json:"disable_prune,omitempty"`
+	DisableSelfHeal bool   `bson:"disable_self_heal,omitempty" json:"disable_self_heal,omitempty"`
+}
+
+// IsDefault reports whether the policy keeps the automated sync with prune
+// and self heal the gitops template ships with
+func (policy ArgoCDSyncPolicy) IsDefault() bool {
+	return policy.Mode != SyncModeManual && !policy.DisablePrune && !policy.DisableSelfHeal
+}
+
+// OIDC is the identity provider argocd and the console sign users in with,
+// the client secret is stored in the platform secret store and never
+// rendered into the gitops repository
+type OIDC struct {
+	IssuerURL    string `bson:"issuer_url,omitempty" json:"issuer_url,omitempty"`
+	ClientID     string `bson:"client_id,omitempty" json:"client_id,omitempty"`
+	ClientSecret string `bson:"client_secret,omitempty" json:"client_secret,omitempty"`
+}
+
+// ImagePullSecret holds the credentials of a private container registry,
+// created as a docker config secret in each of its namespaces
+type ImagePullSecret struct {
+	Name       string   `bson:"name" json:"name"`
+	Server     string   `bson:"server" json:"server"`
+	Username   string   `bson:"username" json:"username"`
+	Password   string   `bson:"password" json:"password"`
+	Email      string   `bson:"email,omitempty" json:"email,omitempty"`
+	Namespaces []string `bson:"namespaces" json:"namespaces"`
+}
+
+// WorkloadIdentity lets in-cluster service accounts assume cloud identities
+// through the cluster's oidc issuer instead of static credentials - irsa on
+// aws and gke workload identity on google
+type WorkloadIdentity struct {
+	Enabled  bool                      `bson:"enabled" json:"enabled"`
+	Bindings []WorkloadIdentityBinding `bson:"bindings,omitempty" json:"bindings,omitempty"`
+}
+
+// WorkloadIdentityBinding binds a kubernetes service account to a cloud
+// identity, an iam role arn on aws or a service account email on google
+type WorkloadIdentityBinding struct {
+	Namespace      string `bson:"namespace" json:"namespace"`
+	ServiceAccount string `bson:"service_account" json:"service_account"`
+	CloudIdentity  string `bson:"cloud_identity" json:"cloud_identity"`
+}
+
+// ArgoCDRepairResult reports what RepairArgoCD found and did
+type ArgoCDRepairResult struct {
+	SyncStatus   string   `json:"sync_status,omitempty"`
+	HealthStatus string   `json:"health_status,omitempty"`
+	Repaired     bool     `json:"repaired"`
+	Actions      []string `json:"actions,omitempty"`
+}
+
+// VaultSyncResult reports the vault terraform changes SyncVault found and
+// whether it applied them
+type VaultSyncResult struct {
+	Changes []string `json:"changes"`
+	Applied bool     `json:"applied"`
+}
+
+// ConsoleStatus describes whether the kubefirst console is reachable
+type ConsoleStatus struct {
+	URL           string `json:"url"`
+	Created       bool   `json:"created"`
+	Ready         bool   `json:"ready"`
+	Replicas      int32  `json:"replicas"`
+	ReadyReplicas int32  `json:"ready_replicas"`
+	Image         string `json:"image,omitempty"`
+}
+
+// ClusterStatusRefresh reports the status RefreshStatus found on a cluster
+// record, the status it derived from the live cluster and why
+type ClusterStatusRefresh struct {
+	ClusterName string   `json:"cluster_name"`
+	Before      string   `json:"before"`
+	After       string   `json:"after"`
+	Changed     bool     `json:"changed"`
+	Reasons     []string `json:"reasons,omitempty"`
+}
+
+// KubernetesUpgradeTargets are the kubernetes versions ListUpgradeTargets
+// found a cluster can be upgraded to from its current version
+type KubernetesUpgradeTargets struct {
+	ClusterName    string   `json:"cluster_name"`
+	CurrentVersion string   `json:"current_version"`
+	Targets        []string `json:"targets"`
+}
+
+// ComponentLogOptions controls which in-cluster logs StreamComponentLogs
+// returns, zero values stream everything
+type ComponentLogOptions struct {
+	TailLines int64     `json:"tail_lines,omitempty"`
+	SinceTime time.Time `json:"since_time,omitempty"`
+	Follow    bool      `json:"follow,omitempty"`
+}
+
+// SupportBundleOptions controls what GenerateSupportBundle collects and
+// redacts
+type SupportBundleOptions struct {
+	// Redact lists field names, matched case insensitively as substrings,
+	// whose values are redacted - empty uses the default list
+	Redact []string `json:"redact,omitempty"`
+	// LogTailLines is the number of recent log lines collected per component
+	LogTailLines int64 `json:"log_tail_lines,omitempty"`
+}
+
+// PostInstallManifest is a set of kubernetes manifests applied once after the
+// gitops registry has synced, either fetched from URL or supplied inline
+type PostInstallManifest struct {
+	URL     string `bson:"url,omitempty" json:"url,omitempty"`
+	Content string `bson:"content,omitempty" json:"content,omitempty"`
+	// Namespace is set on namespaced objects without one, default if unset
+	Namespace string `bson:"namespace,omitempty" json:"namespace,omitempty"`
+}
+
+// GitMirror is a secondary https remote the gitops repository is mirrored to
+type GitMirror struct {
+	Name     string `bson:"name" json:"name"`
+	URL      string `bson:"url" json:"url"`
+	Username string `bson:"username,omitempty" json:"username,omitempty"`
+	Password string `bson:"password,omitempty" json:"password,omitempty"`
+}
+
+// GitMirrorStatus records the outcome of the last push to a gitops mirror
+type GitMirrorStatus struct {
+	Name     string `bson:"name" json:"name"`
+	URL      string `bson:"url" json:"url"`
+	Pushed   bool   `bson:"pushed" json:"pushed"`
+	Error    string `bson:"error,omitempty" json:"error,omitempty"`
+	PushedAt string `bson:"pushed_at,omitempty" json:"pushed_at,omitempty"`
+}
+
+// PostInstallManifestResult records the outcome of applying a post install
+// manifest
+type PostInstallManifestResult struct {
+	Source  string `bson:"source" json:"source"`
+	Applied bool   `bson:"applied" json:"applied"`
+	Error   string `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// GitopsTemplateDiff summarizes how a cluster's gitops repository differs from
+// a detokenized copy of the gitops template
+type GitopsTemplateDiff struct {
+	TemplateURL    string           `json:"template_url"`
+	TemplateRef    string           `json:"template_ref"`
+	TemplateCommit string           `json:"template_commit"`
+	Files          []GitopsFileDiff `json:"files"`
+}
+
+// GitopsFileDiff describes a single differing file - added files exist only
+// in the template, removed files exist only in the cluster's repository
+type GitopsFileDiff struct {
+	Path         string `json:"path"`
+	Status       string `json:"status"`
+	LinesAdded   int    `json:"lines_added,omitempty"`
+	LinesRemoved int    `json:"lines_removed,omitempty"`
+}
+
+// GitopsUpgradeResult describes the template changes merged into a cluster's
+// gitops repository by an upgrade, or that would be merged for a dry run
+type GitopsUpgradeResult struct {
+	TemplateRef    string           `json:"template_ref"`
+	TemplateCommit string           `json:"template_commit"`
+	DryRun         bool             `json:"dry_run"`
+	Files          []GitopsFileDiff `json:"files"`
+	Conflicts      []string         `json:"conflicts"`
+	Commit         string           `json:"commit,omitempty"`
+}
@@ -0,0 +1,40 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package types
+
+// Cost estimate categories
+const (
+	CostCategoryControlPlane  = "control_plane"
+	CostCategoryNodes         = "nodes"
+	CostCategoryLoadBalancer  = "load_balancer"
+	CostCategoryStorage       = "storage"
+	CostCategoryObjectStorage = "object_storage"
+)
+
+// CostEstimate is the estimated monthly cost of the resources a cluster
+// definition provisions, it's an estimate from list prices and excludes
+// bandwidth, taxes, discounts and usage based charges
+type CostEstimate struct {
+	CloudProvider string             `json:"cloud_provider"`
+	CloudRegion   string             `json:"cloud_region"`
+	Currency      string             `json:"currency"`
+	MonthlyTotal  float64            `json:"monthly_total"`
+	Items         []CostEstimateItem `json:"items"`
+	Note          string             `json:"note"`
+}
+
+// CostEstimateItem is a single line of a cost estimate, Source is
+// provider_api when the unit price came from the provider's pricing api and
+// list_price when it's a published price kubefirst keeps
+type CostEstimateItem struct {
+	Category    string  `json:"category"`
+	Description string  `json:"description"`
+	Quantity    float64 `json:"quantity"`
+	UnitMonthly float64 `json:"unit_monthly"`
+	Monthly     float64 `json:"monthly"`
+	Source      string  `json:"source"`
+}
@@ -0,0 +1,381 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package types
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// ClusterDefinitionDefaults are the values NewClusterDefinitionBuilder starts
+// a provider's definition with, the /cloud-defaults endpoint serves the same
+// node sizes
+type ClusterDefinitionDefaults struct {
+	NodeType    string
+	NodeCount   int
+	DnsProvider string
+}
+
+// ProviderDefinitionDefaults holds the builder defaults of each supported
+// cloud provider
+var ProviderDefinitionDefaults = map[string]ClusterDefinitionDefaults{
+	"akamai":       {NodeType: "g6-standard-4", NodeCount: 4, DnsProvider: "cloudflare"},
+	"aws":          {NodeType: "m5.large", NodeCount: 5, DnsProvider: "aws"},
+	"civo":         {NodeType: "g4s.kube.large", NodeCount: 4, DnsProvider: "civo"},
+	"digitalocean": {NodeType: "s-4vcpu-8gb", NodeCount: 4, DnsProvider: "digitalocean"},
+	"google":       {NodeType: "e2-medium", NodeCount: 2, DnsProvider: "google"},
+	"k3s":          {NodeType: "", NodeCount: 3, DnsProvider: "cloudflare"},
+	"vultr":        {NodeType: "vc2-4c-8gb", NodeCount: 4, DnsProvider: "vultr"},
+}
+
+// ClusterDefinitionBuilder assembles a ClusterDefinition with fluent setters,
+// each setter validates its input and the first error is returned by Build
+type ClusterDefinitionBuilder struct {
+	def ClusterDefinition
+	err error
+}
+
+// NewClusterDefinitionBuilder starts a management cluster definition for
+// cloudProvider using the provider's defaults, a github repository over https
+// and the current schema version
+func NewClusterDefinitionBuilder(cloudProvider string) *ClusterDefinitionBuilder {
+	b := &ClusterDefinitionBuilder{
+		def: ClusterDefinition{
+			SchemaVersion: ClusterSchemaVersion,
+			CloudProvider: cloudProvider,
+			Type:          "mgmt",
+			GitProvider:   "github",
+			GitProtocol:   "https",
+		},
+	}
+
+	defaults, ok := ProviderDefinitionDefaults[cloudProvider]
+	if !ok {
+		b.err = fmt.Errorf("unsupported cloud provider %s", cloudProvider)
+		return b
+	}
+	b.def.NodeType = defaults.NodeType
+	b.def.NodeCount = defaults.NodeCount
+	b.def.DnsProvider = defaults.DnsProvider
+
+	return b
+}
+
+// set applies fn unless an earlier setter already failed
+func (b *ClusterDefinitionBuilder) set(fn func(def *ClusterDefinition) error) *ClusterDefinitionBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.err = fn(&b.def)
+
+	return b
+}
+
+// requireProvider fails setters of provider specific values used with a
+// different provider
+func requireProvider(def *ClusterDefinition, setting string, providers ...string) error {
+	for _, p := range providers {
+		if def.CloudProvider == p {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s is only supported for %s, not %s", setting, strings.Join(providers, ", "), def.CloudProvider)
+}
+
+// AdminEmail sets the email used for alerts and certificate registration
+func (b *ClusterDefinitionBuilder) AdminEmail(email string) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		if _, err := mail.ParseAddress(email); err != nil {
+			return fmt.Errorf("invalid admin email %q: %s", email, err)
+		}
+		def.AdminEmail = email
+		return nil
+	})
+}
+
+// Region sets the cloud region the cluster is created in
+func (b *ClusterDefinitionBuilder) Region(region string) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		if region == "" {
+			return fmt.Errorf("cloud region cannot be empty")
+		}
+		def.CloudRegion = region
+		return nil
+	})
+}
+
+// ClusterName sets the name of the cluster
+func (b *ClusterDefinitionBuilder) ClusterName(name string) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		if name == "" {
+			return fmt.Errorf("cluster name cannot be empty")
+		}
+		def.ClusterName = name
+		return nil
+	})
+}
+
+// Workload makes the definition a workload cluster
+func (b *ClusterDefinitionBuilder) Workload() *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		def.Type = "workload"
+		return nil
+	})
+}
+
+// Domain sets the domain and optional subdomain the platform is served from
+func (b *ClusterDefinitionBuilder) Domain(domainName string, subdomainName string) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		if domainName == "" || !strings.Contains(domainName, ".") {
+			return fmt.Errorf("invalid domain name %q", domainName)
+		}
+		def.DomainName = domainName
+		def.SubdomainName = subdomainName
+		return nil
+	})
+}
+
+// DnsProvider overrides the provider's default dns provider
+func (b *ClusterDefinitionBuilder) DnsProvider(dnsProvider string) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		if dnsProvider == "" {
+			return fmt.Errorf("dns provider cannot be empty")
+		}
+		def.DnsProvider = dnsProvider
+		return nil
+	})
+}
+
+// Nodes overrides the provider's default node type and count
+func (b *ClusterDefinitionBuilder) Nodes(nodeType string, nodeCount int) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		if nodeCount < 1 {
+			return fmt.Errorf("node count must be at least 1, got %d", nodeCount)
+		}
+		if nodeType == "" && def.CloudProvider != "k3s" {
+			return fmt.Errorf("node type cannot be empty")
+		}
+		def.NodeType = nodeType
+		def.NodeCount = nodeCount
+		return nil
+	})
+}
+
+// NodeLabels sets the kubernetes labels applied to the cluster's nodes
+func (b *ClusterDefinitionBuilder) NodeLabels(labels map[string]string) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		def.NodeLabels = labels
+		return nil
+	})
+}
+
+// NodeTaints sets the taints applied to the cluster's nodes
+func (b *ClusterDefinitionBuilder) NodeTaints(taints ...NodeTaint) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		def.NodeTaints = append(def.NodeTaints, taints...)
+		return nil
+	})
+}
+
+// InstallProfile sets which registry components are deployed
+func (b *ClusterDefinitionBuilder) InstallProfile(profile string) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		def.InstallProfile = profile
+		return nil
+	})
+}
+
+// IngressController sets the ingress controller installed by the registry
+func (b *ClusterDefinitionBuilder) IngressController(controller string) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		def.IngressController = controller
+		return nil
+	})
+}
+
+// GitopsTemplate clones the gitops template from url at branch instead of
+// the default template
+func (b *ClusterDefinitionBuilder) GitopsTemplate(url string, branch string) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		if url == "" || branch == "" {
+			return fmt.Errorf("gitops template url and branch are both required")
+		}
+		def.GitopsTemplateURL = url
+		def.GitopsTemplateBranch = branch
+		return nil
+	})
+}
+
+// Git sets the git provider and protocol along with the credentials and
+// owner the repositories are created for
+func (b *ClusterDefinitionBuilder) Git(gitProvider string, gitProtocol string, auth GitAuth) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		if gitProvider != "github" && gitProvider != "gitlab" {
+			return fmt.Errorf("unsupported git provider %s, must be one of [github gitlab]", gitProvider)
+		}
+		if gitProtocol != "https" && gitProtocol != "ssh" {
+			return fmt.Errorf("unsupported git protocol %s, must be one of [https ssh]", gitProtocol)
+		}
+		if !auth.HasCredentials() || auth.Owner == "" {
+			return fmt.Errorf("git token or github app credentials and owner are required")
+		}
+		if auth.UsesGitHubApp() && gitProvider != "github" {
+			return fmt.Errorf("github app credentials are only supported with github")
+		}
+		def.GitProvider = gitProvider
+		def.GitProtocol = gitProtocol
+		def.GitAuth = auth
+		return nil
+	})
+}
+
+// CloudflareAuth sets the cloudflare credentials used when cloudflare
+// manages dns
+func (b *ClusterDefinitionBuilder) CloudflareAuth(auth CloudflareAuth) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		if auth.APIToken == "" && auth.Token == "" {
+			return fmt.Errorf("cloudflare api token is required")
+		}
+		def.CloudflareAuth = auth
+		return nil
+	})
+}
+
+// AkamaiAuth sets the akamai credentials
+func (b *ClusterDefinitionBuilder) AkamaiAuth(auth AkamaiAuth) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		if err := requireProvider(def, "akamai auth", "akamai"); err != nil {
+			return err
+		}
+		def.AkamaiAuth = auth
+		return nil
+	})
+}
+
+// AWSAuth sets the aws credentials
+func (b *ClusterDefinitionBuilder) AWSAuth(auth AWSAuth) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		if err := requireProvider(def, "aws auth", "aws"); err != nil {
+			return err
+		}
+		def.AWSAuth = auth
+		return nil
+	})
+}
+
+// CivoAuth sets the civo credentials
+func (b *ClusterDefinitionBuilder) CivoAuth(auth CivoAuth) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		if err := requireProvider(def, "civo auth", "civo"); err != nil {
+			return err
+		}
+		def.CivoAuth = auth
+		return nil
+	})
+}
+
+// DigitaloceanAuth sets the digitalocean credentials
+func (b *ClusterDefinitionBuilder) DigitaloceanAuth(auth DigitaloceanAuth) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		if err := requireProvider(def, "digitalocean auth", "digitalocean"); err != nil {
+			return err
+		}
+		def.DigitaloceanAuth = auth
+		return nil
+	})
+}
+
+// GoogleAuth sets the google credentials
+func (b *ClusterDefinitionBuilder) GoogleAuth(auth GoogleAuth) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		if err := requireProvider(def, "google auth", "google"); err != nil {
+			return err
+		}
+		def.GoogleAuth = auth
+		return nil
+	})
+}
+
+// K3sAuth sets the servers and ssh credentials of a k3s cluster
+func (b *ClusterDefinitionBuilder) K3sAuth(auth K3sAuth) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		if err := requireProvider(def, "k3s auth", "k3s"); err != nil {
+			return err
+		}
+		def.K3sAuth = auth
+		return nil
+	})
+}
+
+// VultrAuth sets the vultr credentials
+func (b *ClusterDefinitionBuilder) VultrAuth(auth VultrAuth) *ClusterDefinitionBuilder {
+	return b.set(func(def *ClusterDefinition) error {
+		if err := requireProvider(def, "vultr auth", "vultr"); err != nil {
+			return err
+		}
+		def.VultrAuth = auth
+		return nil
+	})
+}
+
+// Build returns the definition or the first error found while building it,
+// required values that were never set are reported here
+func (b *ClusterDefinitionBuilder) Build() (ClusterDefinition, error) {
+	if b.err != nil {
+		return ClusterDefinition{}, b.err
+	}
+
+	def := b.def
+	switch {
+	case def.AdminEmail == "":
+		return ClusterDefinition{}, fmt.Errorf("admin email is required")
+	case def.CloudRegion == "" && def.CloudProvider != "k3s":
+		return ClusterDefinition{}, fmt.Errorf("cloud region is required")
+	case def.DomainName == "":
+		return ClusterDefinition{}, fmt.Errorf("domain name is required")
+	case !def.GitAuth.HasCredentials():
+		return ClusterDefinition{}, fmt.Errorf("git credentials are required")
+	case def.DnsProvider == "cloudflare" && def.CloudflareAuth.APIToken == "" && def.CloudflareAuth.Token == "":
+		return ClusterDefinition{}, fmt.Errorf("cloudflare auth is required when cloudflare manages dns")
+	}
+
+	if err := validateProviderAuth(def); err != nil {
+		return ClusterDefinition{}, err
+	}
+
+	return def, nil
+}
+
+// validateProviderAuth checks the credentials of the definition's cloud
+// provider were supplied
+func validateProviderAuth(def ClusterDefinition) error {
+	var missing bool
+	switch def.CloudProvider {
+	case "akamai":
+		missing = def.AkamaiAuth.Token == ""
+	case "aws":
+		missing = def.AWSAuth.AccessKeyID == "" || def.AWSAuth.SecretAccessKey == ""
+	case "civo":
+		missing = def.CivoAuth.Token == ""
+	case "digitalocean":
+		missing = def.DigitaloceanAuth.Token == "" || def.DigitaloceanAuth.SpacesKey == "" || def.DigitaloceanAuth.SpacesSecret == ""
+	case "google":
+		missing = def.GoogleAuth.KeyFile == "" || def.GoogleAuth.ProjectId == ""
+	case "k3s":
+		missing = len(def.K3sAuth.K3sServersPrivateIps) == 0 || def.K3sAuth.K3sSshUser == ""
+	case "vultr":
+		missing = def.VultrAuth.Token == ""
+	}
+
+	if missing {
+		return fmt.Errorf("%s auth is required", def.CloudProvider)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package types
+
+import "testing"
+
+func TestDefinitionBuilderGitCredentials(t *testing.T) {
+	for name, test := range map[string]struct {
+		gitProvider string
+		auth        GitAuth
+		valid       bool
+	}{
+		"token": {
+			gitProvider: "github",
+			auth:        GitAuth{Token: "token", Owner: "org"},
+			valid:       true,
+		},
+		"github app": {
+			gitProvider: "github",
+			auth:        GitAuth{AppID: 1, AppInstallationID: 2, AppPrivateKey: "app-private-key", Owner: "org"},
+			valid:       true,
+		},
+		"incomplete github app": {
+			gitProvider: "github",
+			auth:        GitAuth{AppID: 1, Owner: "org"},
+		},
+		"github app on gitlab": {
+			gitProvider: "gitlab",
+			auth:        GitAuth{AppID: 1, AppInstallationID: 2, AppPrivateKey: "app-private-key", Owner: "org"},
+		},
+		"no credentials": {
+			gitProvider: "github",
+			auth:        GitAuth{Owner: "org"},
+		},
+	} {
+		_, err := NewClusterDefinitionBuilder("civo").
+			AdminEmail("admin@example.com").
+			Region("nyc1").
+			ClusterName("kf-test").
+			Domain("example.com", "").
+			Git(test.gitProvider, "https", test.auth).
+			CivoAuth(CivoAuth{Token: "civo-token"}).
+			Build()
+		if (err == nil) != test.valid {
+			t.Errorf("%s: Build() = %v, want valid %v", name, err, test.valid)
+		}
+	}
+}
+
+func TestNewClusterDefinitionBuilderDefaults(t *testing.T) {
+	for name, test := range map[string]struct {
+		cloudProvider string
+		nodeType      string
+		nodeCount     int
+		dnsProvider   string
+		valid         bool
+	}{
+		"aws": {
+			cloudProvider: "aws",
+			nodeType:      "m5.large",
+			nodeCount:     5,
+			dnsProvider:   "aws",
+			valid:         true,
+		},
+		"akamai uses cloudflare": {
+			cloudProvider: "akamai",
+			nodeType:      "g6-standard-4",
+			nodeCount:     4,
+			dnsProvider:   "cloudflare",
+			valid:         true,
+		},
+		"k3s has no node type": {
+			cloudProvider: "k3s",
+			nodeCount:     3,
+			dnsProvider:   "cloudflare",
+			valid:         true,
+		},
+		"unsupported provider": {
+			cloudProvider: "openstack",
+		},
+	} {
+		b := NewClusterDefinitionBuilder(test.cloudProvider)
+		if (b.err == nil) != test.valid {
+			t.Errorf("%s: err = %v, want valid %v", name, b.err, test.valid)
+			continue
+		}
+		if !test.valid {
+			continue
+		}
+		if b.def.NodeType != test.nodeType || b.def.NodeCount != test.nodeCount || b.def.DnsProvider != test.dnsProvider {
+			t.Errorf("%s: defaults = %s/%d/%s, want %s/%d/%s", name,
+				b.def.NodeType, b.def.NodeCount, b.def.DnsProvider,
+				test.nodeType, test.nodeCount, test.dnsProvider)
+		}
+	}
+}
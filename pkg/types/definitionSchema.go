@@ -0,0 +1,233 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package types
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ClusterDefinitionProviderRequired lists, per cloud provider, the
+// ClusterDefinition fields that must be supplied and the keys each of them
+// requires
+var ClusterDefinitionProviderRequired = map[string]map[string][]string{
+	"akamai":       {"akamai_auth": {"token"}},
+	"aws":          {"aws_auth": {"access_key_id", "secret_access_key"}},
+	"civo":         {"civo_auth": {"token"}},
+	"digitalocean": {"do_auth": {"token", "spaces_key", "spaces_secret"}},
+	"google":       {"google_auth": {"key_file", "project_id"}},
+	"k3s":          {"k3s_auth": {"servers_private_ips", "ssh_user"}},
+	"vultr":        {"vultr_auth": {"token"}},
+}
+
+// clusterDefinitionDescriptions documents every top level ClusterDefinition
+// field in the schema, a field missing here fails the schema test
+var clusterDefinitionDescriptions = map[string]string{
+	"schema_version":              "Version of the definition schema, defaults to the latest",
+	"admin_email":                 "Email used for alerts and certificate registration",
+	"cloud_provider":              "Cloud provider the cluster is created in",
+	"cloud_region":                "Cloud region the cluster is created in",
+	"regions":                     "Additional regions for multi-region installs",
+	"region_git_owners":           "Git owner each region's gitops and metaphor repositories are created in, one per region",
+	"cluster_name":                "Name of the cluster",
+	"cluster_group":               "Group the cluster belongs to",
+	"tags":                        "Key/value tags applied to every cloud resource created for the cluster",
+	"domain_name":                 "Domain the platform is served from",
+	"subdomain_name":              "Optional subdomain of domain_name the platform is served from",
+	"dns_provider":                "Provider managing the domain's dns zone",
+	"dns_ttl":                     "Ttl in seconds of the dns records the api creates, defaults to the dns provider's default",
+	"argocd_host":                 "Overrides the argocd hostname",
+	"feature_gates":               "Optional controller behaviors enabled or disabled for the cluster, by gate name",
+	"argocd_sync_policy":          "Automated or manual sync of the registry and app-of-apps applications, with optional prune and self heal",
+	"type":                        "Management or workload cluster",
+	"force_destroy":               "Destroy state store buckets even when they contain objects",
+	"node_type":                   "Instance type of the cluster's nodes",
+	"node_count":                  "Number of nodes in the cluster",
+	"node_labels":                 "Kubernetes labels applied to the cluster's nodes",
+	"node_taints":                 "Taints applied to the cluster's nodes",
+	"autoscaling":                 "Node counts the cloud provider's cluster autoscaler scales the default node pool between",
+	"image_overrides":             "Container images replacing the defaults of platform components",
+	"console_image":               "Console image as repository:tag, or a version of the default console repository, e.g. to match the api version",
+	"storage_class":               "Storage class used by platform volumes",
+	"resource_profile":            "Preset of resource requests and limits for platform components",
+	"resource_overrides":          "Resource requests and limits per platform component",
+	"install_profile":             "Which registry components are deployed",
+	"existing_network":            "Pre-existing network and subnets to create the cluster in",
+	"stop_after_step":             "Provisioning step to pause after, leaving resources up for inspection",
+	"resume_from":                 "Provisioning step to resume a paused cluster from, earlier steps are skipped",
+	"egress_gateway":              "Static public ip, and optionally an existing NAT gateway, all outbound node traffic is routed through",
+	"image_pull_secrets":          "Private container registry credentials",
+	"ingress_controller":          "Ingress controller installed by the registry",
+	"load_balancer_annotations":   "Annotations of the ingress controller's load balancer service",
+	"kubernetes_version":          "Kubernetes version the cluster is created with, the provider default when empty",
+	"terraform_backend":           "Custom backend for the gitops terraform entrypoints",
+	"terraform_cloud":             "Terraform cloud organization cloud and git terraform runs execute in",
+	"terraform_hooks":             "Commands run before and after each terraform entrypoint is applied",
+	"workload_identity":           "Bindings of service accounts to cloud identities",
+	"volume_sizes":                "Persistent volume sizes per platform component",
+	"external_secrets":            "Cloud secret manager external secrets operator reads platform secrets from instead of vault",
+	"user_directory":              "Identity provider SCIM api, and optionally a group, the users terraform syncs its users from",
+	"oidc":                        "OIDC provider argocd and the console are configured to sign users in with",
+	"spot_node_pool":              "Additional node pool of spot instances, labelled and tainted so only workloads tolerating interruption are scheduled on it",
+	"timeouts":                    "Seconds to wait for the cluster's control plane, node pools and vault to become ready, defaults to the cloud provider's timeouts",
+	"network_policies":            "Deploy default deny network policies with the allow rules platform components need",
+	"cni":                         "Container network interface run instead of the cloud provider's default, e.g. cilium",
+	"pod_security":                "Pod security standard level enforced on platform namespaces, restricted by default, with per-namespace overrides",
+	"post_install_catalog_apps":   "Gitops catalog applications installed after provisioning",
+	"post_install_manifests":      "Kubernetes manifests applied after provisioning",
+	"install_kubefirst_pro":       "Install kubefirst pro",
+	"gitops_template_url":         "Gitops template repository to clone",
+	"gitops_template_branch":      "Branch or tag of the gitops template repository",
+	"gitops_template_commit":      "Commit the gitops template clone is pinned to",
+	"skip_manifest_validation":    "Push the gitops repository without validating its manifests first",
+	"git_provider":                "Git provider hosting the gitops and metaphor repositories",
+	"git_protocol":                "Protocol used to push to the git provider",
+	"adopt_repositories":          "Push into gitops and metaphor repositories that already exist and are empty",
+	"force_adopt_repositories":    "Also adopt and overwrite existing repositories that aren't empty",
+	"gitops_mirrors":              "Secondary git remotes the gitops repository is mirrored to",
+	"default_branch":              "Branch the gitops and metaphor repositories are pushed to, defaults to main",
+	"commit_message":              "Message of the initial commit of the gitops and metaphor repositories",
+	"atlantis_webhook_secret":     "Secret the gitops repository's atlantis webhook is signed with, generated when empty",
+	"metaphor_owner":              "Github organization or user the metaphor repository is created under, defaults to the git_auth owner",
+	"kubeconfig_context_name":     "Name of the cluster's context in the generated kubeconfig on civo, digitalocean, k3s and vultr",
+	"ecr":                         "Use ecr as the container registry on aws",
+	"existing_state_store_bucket": "Pre-existing bucket to use as the state store",
+	"state_store_kms_key":         "Kms key encrypting the state store bucket kubefirst creates, not used with existing_state_store_bucket",
+	"akamai_auth":                 "Akamai credentials",
+	"aws_auth":                    "Aws credentials",
+	"civo_auth":                   "Civo credentials",
+	"do_auth":                     "Digitalocean credentials",
+	"vultr_auth":                  "Vultr credentials",
+	"cloudflare_auth":             "Cloudflare credentials, required when cloudflare manages dns",
+	"google_auth":                 "Google cloud credentials",
+	"k3s_auth":                    "K3s servers and ssh credentials",
+	"git_auth":                    "Git provider credentials and the owner repositories are created for",
+	"log_file":                    "Name of the log file the install writes to",
+}
+
+// ClusterDefinitionJSONSchema returns a json schema (draft 2020-12) of
+// ClusterDefinition - properties, required fields and enums are derived from
+// the struct tags and each cloud provider's required credentials are added
+// as conditional requirements
+func ClusterDefinitionJSONSchema() map[string]interface{} {
+	schema := jsonSchemaForType(reflect.TypeOf(ClusterDefinition{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "ClusterDefinition"
+
+	properties := schema["properties"].(map[string]interface{})
+	for name, description := range clusterDefinitionDescriptions {
+		if property, ok := properties[name].(map[string]interface{}); ok {
+			property["description"] = description
+		}
+	}
+
+	providers := make([]string, 0, len(ClusterDefinitionProviderRequired))
+	for provider := range ClusterDefinitionProviderRequired {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	conditions := []interface{}{}
+	for _, provider := range providers {
+		fields := ClusterDefinitionProviderRequired[provider]
+
+		required := []string{}
+		thenProperties := map[string]interface{}{}
+		for field, keys := range fields {
+			required = append(required, field)
+			thenProperties[field] = map[string]interface{}{"required": keys}
+		}
+		sort.Strings(required)
+
+		conditions = append(conditions, map[string]interface{}{
+			"if": map[string]interface{}{
+				"properties": map[string]interface{}{
+					"cloud_provider": map[string]interface{}{"const": provider},
+				},
+			},
+			"then": map[string]interface{}{
+				"required":   required,
+				"properties": thenProperties,
+			},
+		})
+	}
+	schema["allOf"] = conditions
+
+	return schema
+}
+
+// jsonSchemaForType maps a go type to its json schema
+func jsonSchemaForType(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return jsonSchemaForType(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": jsonSchemaForType(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchemaForType(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		required := []string{}
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name := jsonFieldName(field)
+			if name == "" {
+				continue
+			}
+
+			property := jsonSchemaForType(field.Type)
+			for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+				switch {
+				case rule == "required":
+					required = append(required, name)
+				case strings.HasPrefix(rule, "oneof="):
+					property["enum"] = strings.Fields(strings.TrimPrefix(rule, "oneof="))
+				}
+			}
+			properties[name] = property
+		}
+
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) != 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+
+	return map[string]interface{}{}
+}
+
+// jsonFieldName returns the name a struct field is encoded as, or an empty
+// string when it isn't encoded
+func jsonFieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		return field.Name
+	}
+
+	return name
+}
@@ -0,0 +1,123 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestClusterDefinitionSchemaInSync fails when a ClusterDefinition field is
+// added or removed without updating the schema descriptions
+func TestClusterDefinitionSchemaInSync(t *testing.T) {
+	definitionType := reflect.TypeOf(ClusterDefinition{})
+
+	fields := map[string]bool{}
+	for i := 0; i < definitionType.NumField(); i++ {
+		name := jsonFieldName(definitionType.Field(i))
+		if name == "" {
+			continue
+		}
+		fields[name] = true
+
+		if _, ok := clusterDefinitionDescriptions[name]; !ok {
+			t.Errorf("ClusterDefinition field %s has no schema description, add it to clusterDefinitionDescriptions", name)
+		}
+	}
+
+	for name := range clusterDefinitionDescriptions {
+		if !fields[name] {
+			t.Errorf("schema description %s does not match any ClusterDefinition field", name)
+		}
+	}
+}
+
+func TestClusterDefinitionSchemaProviderRequired(t *testing.T) {
+	schema := ClusterDefinitionJSONSchema()
+	properties := schema["properties"].(map[string]interface{})
+
+	cloudProvider := properties["cloud_provider"].(map[string]interface{})
+	providers := cloudProvider["enum"].([]string)
+	sort.Strings(providers)
+
+	for _, provider := range providers {
+		fields, ok := ClusterDefinitionProviderRequired[provider]
+		if !ok {
+			t.Errorf("cloud provider %s has no required fields in ClusterDefinitionProviderRequired", provider)
+			continue
+		}
+
+		for field, keys := range fields {
+			property, ok := properties[field].(map[string]interface{})
+			if !ok {
+				t.Errorf("%s requires unknown field %s", provider, field)
+				continue
+			}
+
+			keyProperties := property["properties"].(map[string]interface{})
+			for _, key := range keys {
+				if _, ok := keyProperties[key]; !ok {
+					t.Errorf("%s requires unknown key %s.%s", provider, field, key)
+				}
+			}
+		}
+	}
+
+	if len(schema["allOf"].([]interface{})) != len(ClusterDefinitionProviderRequired) {
+		t.Errorf("expected a condition per provider, got %d", len(schema["allOf"].([]interface{})))
+	}
+}
+
+func TestClusterDefinitionSchema(t *testing.T) {
+	schema := ClusterDefinitionJSONSchema()
+
+	_, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("schema does not encode as json: %s", err)
+	}
+
+	properties := schema["properties"].(map[string]interface{})
+
+	tests := []struct {
+		field    string
+		wantType string
+	}{
+		{field: "node_count", wantType: "integer"},
+		{field: "force_destroy", wantType: "boolean"},
+		{field: "node_labels", wantType: "object"},
+		{field: "node_taints", wantType: "array"},
+		{field: "aws_auth", wantType: "object"},
+		{field: "cluster_name", wantType: "string"},
+	}
+	for _, tt := range tests {
+		property := properties[tt.field].(map[string]interface{})
+		if property["type"] != tt.wantType {
+			t.Errorf("%s: got type %v, want %s", tt.field, property["type"], tt.wantType)
+		}
+		if property["description"] == nil {
+			t.Errorf("%s: missing description", tt.field)
+		}
+	}
+
+	required := schema["required"].([]string)
+	for _, want := range []string{"admin_email", "cloud_provider", "domain_name", "git_provider"} {
+		found := false
+		for _, r := range required {
+			found = found || r == want
+		}
+		if !found {
+			t.Errorf("expected %s to be required, got %v", want, required)
+		}
+	}
+
+	gitProtocol := properties["git_protocol"].(map[string]interface{})
+	if !reflect.DeepEqual(gitProtocol["enum"], []string{"ssh", "https"}) {
+		t.Errorf("git_protocol: got enum %v", gitProtocol["enum"])
+	}
+}
@@ -0,0 +1,28 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package types
+
+import "time"
+
+// ProvisionEvent types
+const (
+	ProvisionEventDone = "done"
+	ProvisionEventLog  = "log"
+	ProvisionEventStep = "step"
+)
+
+// ProvisionEvent is one entry of a cluster's live provisioning stream: a log
+// line with its level, a step with its status (started, skipped, succeeded
+// or failed), or the final done event with the cluster's status
+type ProvisionEvent struct {
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level,omitempty"`
+	Step    string    `json:"step,omitempty"`
+	Status  string    `json:"status,omitempty"`
+	Message string    `json:"message,omitempty"`
+}
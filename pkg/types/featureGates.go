@@ -0,0 +1,38 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package types
+
+// Feature gates toggle optional controller behaviors per cluster, so a new
+// behavior can be tried on select clusters before it becomes the default
+const (
+	// FeatureGateGitopsUpgradeMerge merges the template changes into gitops
+	// files the cluster also changed during UpgradeGitops, disabled those
+	// files are reported as conflicts for a manual merge
+	FeatureGateGitopsUpgradeMerge = "GitopsUpgradeMerge"
+	// FeatureGateHelmTemplateValidation renders the helm charts of the
+	// gitops repository with helm template during the manifest validation,
+	// which needs helm on the PATH and network access to the chart
+	// repositories
+	FeatureGateHelmTemplateValidation = "HelmTemplateValidation"
+)
+
+// FeatureGateDefaults holds every known feature gate and whether it is
+// enabled when a cluster doesn't set it
+var FeatureGateDefaults = map[string]bool{
+	FeatureGateGitopsUpgradeMerge:     true,
+	FeatureGateHelmTemplateValidation: false,
+}
+
+// FeatureEnabled reports whether the cluster enables gate, falling back to
+// the gate's default - unknown gates are disabled
+func (cl Cluster) FeatureEnabled(gate string) bool {
+	if enabled, ok := cl.FeatureGates[gate]; ok {
+		return enabled
+	}
+
+	return FeatureGateDefaults[gate]
+}
@@ -0,0 +1,24 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package types
+
+import "testing"
+
+func TestFeatureEnabled(t *testing.T) {
+	cl := Cluster{}
+	if !cl.FeatureEnabled(FeatureGateGitopsUpgradeMerge) {
+		t.Errorf("expected %s to default to enabled", FeatureGateGitopsUpgradeMerge)
+	}
+	if cl.FeatureEnabled("Unknown") {
+		t.Error("expected an unknown gate to be disabled")
+	}
+
+	cl.FeatureGates = map[string]bool{FeatureGateGitopsUpgradeMerge: false}
+	if cl.FeatureEnabled(FeatureGateGitopsUpgradeMerge) {
+		t.Errorf("expected the cluster to disable %s", FeatureGateGitopsUpgradeMerge)
+	}
+}
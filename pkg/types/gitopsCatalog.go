@@ -46,6 +46,13 @@ type GitopsCatalogAppCreateRequest struct {
 	Environment         string                 `bson:"environment" json:"environment"`
 }
 
+// ServiceDefinition describes a gitops catalog app to add to a cluster as a
+// service after install, Name is the catalog app name
+type ServiceDefinition struct {
+	Name string `bson:"name" json:"name"`
+	GitopsCatalogAppCreateRequest
+}
+
 // GitopsCatalogAppValidateRequest
 type GitopsCatalogAppValidateRequest struct {
 	CanDeleteService bool `bson:"can_delete_service" json:"can_delete_service"`
@@ -0,0 +1,33 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package types
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Operation is a day-2 operation submitted for a cluster, e.g. a gitops
+// upgrade, tracked from pending to succeeded or failed
+type Operation struct {
+	ID          string          `bson:"_id" json:"id"`
+	ClusterName string          `bson:"cluster_name" json:"cluster_name"`
+	Type        string          `bson:"type" json:"type"`
+	Status      string          `bson:"status" json:"status"`
+	Result      json.RawMessage `bson:"result,omitempty" json:"result,omitempty"`
+	Error       string          `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt   time.Time       `bson:"created_at" json:"created_at"`
+	StartedAt   *time.Time      `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	FinishedAt  *time.Time      `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+// OperationEvent is posted to the webhook url when an operation finishes,
+// the event is operation.succeeded or operation.failed
+type OperationEvent struct {
+	Event     string    `json:"event"`
+	Operation Operation `json:"operation"`
+}
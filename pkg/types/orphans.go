@@ -0,0 +1,32 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package types
+
+import "time"
+
+// OrphanedResource is a cloud resource kubefirst created for a cluster that
+// no live cluster record owns anymore, e.g. after a failed install. Deleted
+// and Error are only set when the report deleted its resources
+type OrphanedResource struct {
+	Kind        string `json:"kind"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	ClusterName string `json:"cluster_name"`
+	Deleted     bool   `json:"deleted,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// OrphanReport lists the orphaned resources of a cloud provider account,
+// a dry run report's id is needed to delete them
+type OrphanReport struct {
+	ID            string             `json:"id"`
+	CloudProvider string             `json:"cloud_provider"`
+	CloudRegion   string             `json:"cloud_region"`
+	DryRun        bool               `json:"dry_run"`
+	CreatedAt     time.Time          `json:"created_at"`
+	Resources     []OrphanedResource `json:"resources"`
+}
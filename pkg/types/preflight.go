@@ -0,0 +1,37 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package types
+
+// Preflight check names, used to skip individual checks
+const (
+	PreflightCheckDefinition  = "definition"
+	PreflightCheckCredentials = "credentials"
+	PreflightCheckQuota       = "quota"
+	PreflightCheckDNS         = "dns"
+	PreflightCheckNetwork     = "network"
+	PreflightCheckBackend     = "terraform_backend"
+	PreflightCheckSpot        = "spot"
+	PreflightCheckOIDC        = "oidc"
+	PreflightCheckStateStore  = "state_store"
+	PreflightCheckKubernetes  = "kubernetes_version"
+	PreflightCheckAPIURL      = "api_url"
+)
+
+// PreflightReport is the combined result of validating a cluster definition
+// against the target cloud, git and dns providers without provisioning
+type PreflightReport struct {
+	Passed bool                   `json:"passed"`
+	Checks []PreflightCheckResult `json:"checks"`
+}
+
+// PreflightCheckResult is the outcome of a single preflight check
+type PreflightCheckResult struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,37 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package types
+
+import "time"
+
+// DeletedRepository is a git repository of the cluster that was deleted
+// without tearing the cluster down
+type DeletedRepository struct {
+	Owner     string    `bson:"owner" json:"owner"`
+	Name      string    `bson:"name" json:"name"`
+	DeletedAt time.Time `bson:"deleted_at" json:"deleted_at"`
+}
+
+// RepositoryDeletion lists the repositories a repository deletion removes,
+// without Confirmed nothing was deleted. Repositories are owner/name, the
+// skipped ones map to the reason they are kept
+type RepositoryDeletion struct {
+	Confirmed    bool              `json:"confirmed"`
+	Repositories []string          `json:"repositories"`
+	Skipped      map[string]string `json:"skipped,omitempty"`
+}
+
+// GitWebhookVerification reports the state VerifyGitWebhook found the gitops
+// repository's atlantis webhook in, every problem found was repaired when
+// Repaired is set
+type GitWebhookVerification struct {
+	ClusterName string   `json:"cluster_name"`
+	Repository  string   `json:"repository"`
+	URL         string   `json:"url"`
+	Problems    []string `json:"problems,omitempty"`
+	Repaired    bool     `json:"repaired"`
+}
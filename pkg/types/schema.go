@@ -0,0 +1,24 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package types
+
+import "fmt"
+
+// ClusterSchemaVersion is the version of the ClusterDefinition and Cluster
+// schema written by this release - bump it and add a migration in
+// internal/secrets whenever a stored field changes incompatibly
+const ClusterSchemaVersion = 1
+
+// ValidateSchemaVersion returns an error for schema versions newer than this
+// release understands
+func ValidateSchemaVersion(version int) error {
+	if version < 0 || version > ClusterSchemaVersion {
+		return fmt.Errorf("unsupported schema_version %d, this release supports up to version %d", version, ClusterSchemaVersion)
+	}
+
+	return nil
+}
@@ -11,3 +11,11 @@ type SecretListReference struct {
 	Name string   `bson:"name" json:"name"`
 	List []string `bson:"list" json:"list"`
 }
+
+// RecordEncryptionResult counts the cluster records a record encryption
+// migration changed
+type RecordEncryptionResult struct {
+	Clusters  int `json:"clusters"`
+	Encrypted int `json:"encrypted"`
+	Redacted  int `json:"redacted"`
+}
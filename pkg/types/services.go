@@ -6,6 +6,8 @@ See the LICENSE file for more details.
 */
 package types
 
+import "time"
+
 // Service defines an individual cluster service
 type Service struct {
 	Name        string   `bson:"name" json:"name"`
@@ -17,6 +19,26 @@ type Service struct {
 	CreatedBy   string   `bson:"created_by" json:"created_by"`
 }
 
+// DefaultServiceResult records the outcome of adding a default service entry
+// for a cluster
+type DefaultServiceResult struct {
+	Name  string `bson:"name" json:"name"`
+	Added bool   `bson:"added" json:"added"`
+	Error string `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// ServiceStatus reports the argocd sync and health status of a cluster service
+type ServiceStatus struct {
+	Name          string     `json:"name"`
+	Found         bool       `json:"found"`
+	SyncStatus    string     `json:"sync_status,omitempty"`
+	HealthStatus  string     `json:"health_status,omitempty"`
+	HealthMessage string     `json:"health_message,omitempty"`
+	Revision      string     `json:"revision,omitempty"`
+	LastSyncedAt  *time.Time `json:"last_synced_at,omitempty"`
+	SyncErrors    []string   `json:"sync_errors,omitempty"`
+}
+
 // ClusterServiceList tracks services per cluster
 type ClusterServiceList struct {
 	ClusterName string    `bson:"cluster_name" json:"cluster_name"`
@@ -0,0 +1,43 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package types
+
+// ProvisionedUser is a user account created by the users terraform and where
+// its initial credentials are stored in vault, Directory is set for the users
+// synced from the cluster's user directory
+type ProvisionedUser struct {
+	Username   string `bson:"username" json:"username"`
+	VaultMount string `bson:"vault_mount" json:"vault_mount"`
+	VaultPath  string `bson:"vault_path" json:"vault_path"`
+	VaultKey   string `bson:"vault_key" json:"vault_key"`
+	Directory  bool   `bson:"directory,omitempty" json:"directory,omitempty"`
+}
+
+// UserDirectory syncs the users terraform's users from an identity provider's
+// SCIM 2.0 api instead of defining them in the gitops repository, Group
+// limits them to the members of one directory group
+type UserDirectory struct {
+	SCIMURL string `bson:"scim_url,omitempty" json:"scim_url,omitempty"`
+	Token   string `bson:"token,omitempty" json:"token,omitempty"`
+	Group   string `bson:"group,omitempty" json:"group,omitempty"`
+}
+
+// DirectoryUser is an active user read from the user directory
+type DirectoryUser struct {
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// UserSyncResult lists the users a directory sync adds to and removes from
+// the cluster, with DryRun nothing was applied
+type UserSyncResult struct {
+	DryRun  bool     `json:"dry_run"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/kubefirst/kubefirst-api/internal/constants"
 	"github.com/kubefirst/kubefirst-api/internal/env"
+	"github.com/kubefirst/kubefirst-api/internal/gitClient"
 	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
 	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
 	"github.com/thanhpk/randstr"
@@ -29,6 +30,11 @@ func CreateTokensFromDatabaseRecord(cl *pkgtypes.Cluster, registryPath string, s
 		fullDomainName = cl.DomainName
 	}
 
+	argoCDHost := cl.ArgoCDHost
+	if argoCDHost == "" {
+		argoCDHost = fmt.Sprintf("argocd.%s", fullDomainName)
+	}
+
 	destinationGitopsRepoURL := fmt.Sprintf("https://%s/%s/gitops.git", cl.GitHost, cl.GitAuth.Owner)
 
 	if cl.GitProtocol == "ssh" {
@@ -86,8 +92,8 @@ func CreateTokensFromDatabaseRecord(cl *pkgtypes.Cluster, registryPath string, s
 		NodeType:                       cl.NodeType,
 		NodeCount:                      cl.NodeCount,
 		KubefirstVersion:               env.KubefirstVersion,
-		ArgoCDIngressURL:               fmt.Sprintf("https://argocd.%s", fullDomainName),
-		ArgoCDIngressNoHTTPSURL:        fmt.Sprintf("argocd.%s", fullDomainName),
+		ArgoCDIngressURL:               fmt.Sprintf("https://%s", argoCDHost),
+		ArgoCDIngressNoHTTPSURL:        argoCDHost,
 		ArgoWorkflowsIngressURL:        fmt.Sprintf("https://argo.%s", fullDomainName),
 		ArgoWorkflowsIngressNoHTTPSURL: fmt.Sprintf("argo.%s", fullDomainName),
 		AtlantisIngressURL:             fmt.Sprintf("https://atlantis.%s", fullDomainName),
@@ -122,8 +128,8 @@ func CreateTokensFromDatabaseRecord(cl *pkgtypes.Cluster, registryPath string, s
 
 		GitopsRepoAtlantisWebhookURL:               cl.AtlantisWebhookURL,
 		GitopsRepoNoHTTPSURL:                       fmt.Sprintf("%s/%s/gitops.git", cl.GitHost, cl.GitAuth.Owner),
-		WorkloadClusterTerraformModuleURL:          fmt.Sprintf("git::https://%s/%s/gitops.git//terraform/%s/modules/workload-cluster?ref=main", cl.GitHost, cl.GitAuth.Owner, cl.CloudProvider),
-		WorkloadClusterBootstrapTerraformModuleURL: fmt.Sprintf("git::https://%s/%s/gitops.git//terraform/%s/modules/bootstrap?ref=main", cl.GitHost, cl.GitAuth.Owner, cl.CloudProvider),
+		WorkloadClusterTerraformModuleURL:          fmt.Sprintf("git::https://%s/%s/gitops.git//terraform/%s/modules/workload-cluster?ref=%s", cl.GitHost, cl.GitAuth.Owner, cl.CloudProvider, gitClient.BranchOrDefault(cl.DefaultBranch)),
+		WorkloadClusterBootstrapTerraformModuleURL: fmt.Sprintf("git::https://%s/%s/gitops.git//terraform/%s/modules/bootstrap?ref=%s", cl.GitHost, cl.GitAuth.Owner, cl.CloudProvider, gitClient.BranchOrDefault(cl.DefaultBranch)),
 		ClusterId: cl.ClusterID,
 
 		// external-dns optionality to provide cloudflare support regardless of cloud provider
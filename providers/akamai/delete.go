@@ -19,10 +19,12 @@ import (
 	pkg "github.com/kubefirst/kubefirst-api/internal"
 	"github.com/kubefirst/kubefirst-api/internal/argocd"
 	"github.com/kubefirst/kubefirst-api/internal/constants"
+	"github.com/kubefirst/kubefirst-api/internal/controller"
 	"github.com/kubefirst/kubefirst-api/internal/errors"
 	gitlab "github.com/kubefirst/kubefirst-api/internal/gitlab"
 	"github.com/kubefirst/kubefirst-api/internal/k8s"
 	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	apitelemetry "github.com/kubefirst/kubefirst-api/internal/telemetry"
 	"github.com/kubefirst/kubefirst-api/internal/utils"
 	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
 	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
@@ -32,15 +34,18 @@ import (
 
 // DeleteAkamaiCluster
 func DeleteAkamaiCluster(cl *pkgtypes.Cluster, telemetryEvent telemetry.TelemetryEvent) error {
-	telemetry.SendEvent(telemetryEvent, telemetry.ClusterDeleteStarted, "")
+	apitelemetry.Transmit(telemetryEvent, telemetry.ClusterDeleteStarted, "")
 
 	// Instantiate civo config
 	config := providerConfigs.GetConfig(cl.ClusterName, cl.DomainName, cl.GitProvider, cl.GitAuth.Owner, cl.GitProtocol, cl.CloudflareAuth.APIToken, cl.CloudflareAuth.OriginCaIssuerKey)
 
-	kcfg := utils.GetKubernetesClient(cl.ClusterName)
+	kcfg, err := utils.GetClusterStoreClient(cl.ClusterName)
+	if err != nil {
+		return err
+	}
 
 	cl.Status = constants.ClusterStatusDeleting
-	err := secrets.UpdateCluster(kcfg.Clientset, *cl)
+	err = secrets.UpdateCluster(kcfg.Clientset, *cl)
 	if err != nil {
 		return err
 	}
@@ -95,7 +100,7 @@ func DeleteAkamaiCluster(cl *pkgtypes.Cluster, telemetryEvent telemetry.Telemetr
 			tfEnvs = civoext.GetGitlabTerraformEnvs(tfEnvs, gitlabClient.ParentGroupID, cl)
 		}
 
-		err = terraformext.InitDestroyAutoApprove(config.TerraformClient, tfEntrypoint, tfEnvs)
+		err = terraformext.DestroyAutoApprove(config.TerraformClient, cl, tfEntrypoint, tfEnvs)
 		if err != nil {
 			log.Info().Msgf("error executing terraform destroy %s", tfEntrypoint)
 			errors.HandleClusterError(cl, err.Error())
@@ -104,6 +109,12 @@ func DeleteAkamaiCluster(cl *pkgtypes.Cluster, telemetryEvent telemetry.Telemetr
 
 		log.Info().Msgf("%s resources terraform destroyed", cl.GitProvider)
 
+		err = controller.DeleteMetaphorRepository(cl)
+		if err != nil {
+			errors.HandleClusterError(cl, err.Error())
+			return err
+		}
+
 		cl.GitTerraformApplyCheck = false
 		err = secrets.UpdateCluster(kcfg.Clientset, *cl)
 		if err != nil {
@@ -213,7 +224,7 @@ func DeleteAkamaiCluster(cl *pkgtypes.Cluster, telemetryEvent telemetry.Telemetr
 			}
 			tfEnvs = civoext.GetGitlabTerraformEnvs(tfEnvs, gid, cl)
 		}
-		err = terraformext.InitDestroyAutoApprove(config.TerraformClient, tfEntrypoint, tfEnvs)
+		err = terraformext.DestroyAutoApprove(config.TerraformClient, cl, tfEntrypoint, tfEnvs)
 		if err != nil {
 			log.Printf("error executing terraform destroy %s", tfEntrypoint)
 			errors.HandleClusterError(cl, err.Error())
@@ -242,7 +253,7 @@ func DeleteAkamaiCluster(cl *pkgtypes.Cluster, telemetryEvent telemetry.Telemetr
 		}
 	}
 
-	telemetry.SendEvent(telemetryEvent, telemetry.ClusterDeleteCompleted, "")
+	apitelemetry.Transmit(telemetryEvent, telemetry.ClusterDeleteCompleted, "")
 
 	cl.Status = constants.ClusterStatusDeleted
 	err = secrets.UpdateCluster(kcfg.Clientset, *cl)
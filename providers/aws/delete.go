@@ -19,10 +19,12 @@ import (
 	"github.com/kubefirst/kubefirst-api/internal/argocd"
 	awsinternal "github.com/kubefirst/kubefirst-api/internal/aws"
 	"github.com/kubefirst/kubefirst-api/internal/constants"
+	"github.com/kubefirst/kubefirst-api/internal/controller"
 	"github.com/kubefirst/kubefirst-api/internal/errors"
 	gitlab "github.com/kubefirst/kubefirst-api/internal/gitlab"
 	"github.com/kubefirst/kubefirst-api/internal/k8s"
 	"github.com/kubefirst/kubefirst-api/internal/secrets"
+	apitelemetry "github.com/kubefirst/kubefirst-api/internal/telemetry"
 	"github.com/kubefirst/kubefirst-api/internal/utils"
 	"github.com/kubefirst/kubefirst-api/pkg/providerConfigs"
 	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
@@ -32,15 +34,18 @@ import (
 
 // DeleteAWSCluster
 func DeleteAWSCluster(cl *pkgtypes.Cluster, telemetryEvent telemetry.TelemetryEvent) error {
-	telemetry.SendEvent(telemetryEvent, telemetry.ClusterDeleteStarted, "")
+	apitelemetry.Transmit(telemetryEvent, telemetry.ClusterDeleteStarted, "")
 
 	// Instantiate aws config
 	config := providerConfigs.GetConfig(cl.ClusterName, cl.DomainName, cl.GitProvider, cl.GitAuth.Owner, cl.GitProtocol, cl.CloudflareAuth.APIToken, cl.CloudflareAuth.OriginCaIssuerKey)
 
-	kcfg := utils.GetKubernetesClient(cl.ClusterName)
+	kcfg, err := utils.GetClusterStoreClient(cl.ClusterName)
+	if err != nil {
+		return err
+	}
 
 	cl.Status = constants.ClusterStatusDeleting
-	err := secrets.UpdateCluster(kcfg.Clientset, *cl)
+	err = secrets.UpdateCluster(kcfg.Clientset, *cl)
 	if err != nil {
 		return err
 	}
@@ -54,7 +59,7 @@ func DeleteAWSCluster(cl *pkgtypes.Cluster, telemetryEvent telemetry.TelemetryEv
 			tfEnvs := map[string]string{}
 			tfEnvs = awsext.GetAwsTerraformEnvs(tfEnvs, cl)
 			tfEnvs = awsext.GetGithubTerraformEnvs(tfEnvs, cl)
-			err := terraformext.InitDestroyAutoApprove(config.TerraformClient, tfEntrypoint, tfEnvs)
+			err := terraformext.DestroyAutoApprove(config.TerraformClient, cl, tfEntrypoint, tfEnvs)
 			if err != nil {
 				log.Error().Msgf("error executing terraform destroy %s", tfEntrypoint)
 				errors.HandleClusterError(cl, err.Error())
@@ -62,7 +67,11 @@ func DeleteAWSCluster(cl *pkgtypes.Cluster, telemetryEvent telemetry.TelemetryEv
 			}
 			log.Info().Msg("github resources terraform destroyed")
 
-			kcfg := utils.GetKubernetesClient(cl.ClusterName)
+			err = controller.DeleteMetaphorRepository(cl)
+			if err != nil {
+				errors.HandleClusterError(cl, err.Error())
+				return err
+			}
 
 			cl.GitTerraformApplyCheck = false
 			err = secrets.UpdateCluster(kcfg.Clientset, *cl)
@@ -111,7 +120,7 @@ func DeleteAWSCluster(cl *pkgtypes.Cluster, telemetryEvent telemetry.TelemetryEv
 			tfEnvs := map[string]string{}
 			tfEnvs = awsext.GetAwsTerraformEnvs(tfEnvs, cl)
 			tfEnvs = awsext.GetGitlabTerraformEnvs(tfEnvs, gitlabClient.ParentGroupID, cl)
-			err = terraformext.InitDestroyAutoApprove(config.TerraformClient, tfEntrypoint, tfEnvs)
+			err = terraformext.DestroyAutoApprove(config.TerraformClient, cl, tfEntrypoint, tfEnvs)
 			if err != nil {
 				log.Error().Msgf("error executing terraform destroy %s", tfEntrypoint)
 				errors.HandleClusterError(cl, err.Error())
@@ -137,6 +146,7 @@ func DeleteAWSCluster(cl *pkgtypes.Cluster, telemetryEvent telemetry.TelemetryEv
 					cl.AWSAuth.AccessKeyID,
 					cl.AWSAuth.SecretAccessKey,
 					cl.AWSAuth.SessionToken,
+					cl.AWSAuth.APIURL,
 				),
 			}
 			kcfg := awsext.CreateEKSKubeconfig(&awsClient.Config, cl.ClusterName)
@@ -145,7 +155,7 @@ func DeleteAWSCluster(cl *pkgtypes.Cluster, telemetryEvent telemetry.TelemetryEv
 
 			// Only port-forward to ArgoCD and delete registry if ArgoCD was installed
 			if cl.ArgoCDInstallCheck {
-				removeArgoCDApps := []string{"ingress-nginx-components", "ingress-nginx"}
+				removeArgoCDApps := providerConfigs.IngressApplications(cl.IngressController)
 				err = argocd.ArgoCDApplicationCleanup(kcfg.Clientset, removeArgoCDApps)
 				if err != nil {
 					log.Error().Msgf("encountered error during argocd application cleanup: %s", err)
@@ -222,7 +232,7 @@ func DeleteAWSCluster(cl *pkgtypes.Cluster, telemetryEvent telemetry.TelemetryEv
 			}
 			tfEnvs = awsext.GetGitlabTerraformEnvs(tfEnvs, gid, cl)
 		}
-		err = terraformext.InitDestroyAutoApprove(config.TerraformClient, tfEntrypoint, tfEnvs)
+		err = terraformext.DestroyAutoApprove(config.TerraformClient, cl, tfEntrypoint, tfEnvs)
 		if err != nil {
 			log.Error().Msgf("error executing terraform destroy %s", tfEntrypoint)
 			errors.HandleClusterError(cl, err.Error())
@@ -257,7 +267,7 @@ func DeleteAWSCluster(cl *pkgtypes.Cluster, telemetryEvent telemetry.TelemetryEv
 		}
 	}
 
-	telemetry.SendEvent(telemetryEvent, telemetry.ClusterDeleteCompleted, "")
+	apitelemetry.Transmit(telemetryEvent, telemetry.ClusterDeleteCompleted, "")
 
 	cl.Status = constants.ClusterStatusDeleted
 	err = secrets.UpdateCluster(kcfg.Clientset, *cl)
@@ -16,16 +16,24 @@ import (
 	"github.com/kubefirst/kubefirst-api/internal/services"
 	"github.com/kubefirst/kubefirst-api/internal/ssl"
 	pkgtypes "github.com/kubefirst/kubefirst-api/pkg/types"
-	log "github.com/rs/zerolog/log"
 )
 
 // CreateDigitaloceanCluster
-func CreateDigitaloceanCluster(definition *pkgtypes.ClusterDefinition) error {
-	ctrl := controller.ClusterController{}
+// CreateDigitaloceanCluster provisions the cluster of definition, its steps log to logger or
+// the global logger when it's nil
+func CreateDigitaloceanCluster(definition *pkgtypes.ClusterDefinition, logger controller.Logger) error {
+	ctrl := controller.ClusterController{Logger: logger}
 	err := ctrl.InitController(definition)
 	if err != nil {
 		return err
 	}
+	defer ctrl.Close()
+
+	err = ctrl.AcquireProvisionSlot()
+	if err != nil {
+		return err
+	}
+	defer ctrl.ReleaseProvisionSlot()
 
 	ctrl.Cluster.InProgress = true
 	err = secrets.UpdateCluster(ctrl.KubernetesClient, ctrl.Cluster)
@@ -40,71 +48,95 @@ func CreateDigitaloceanCluster(definition *pkgtypes.ClusterDefinition) error {
 		return err
 	}
 
-	err = ctrl.DomainLivenessTest()
+	err = ctrl.RunStep("DomainLivenessTest", ctrl.DomainLivenessTest)
+	if err != nil {
+		ctrl.HandleError(err.Error())
+		return err
+	}
+
+	err = ctrl.RunStep("StateStoreCredentials", ctrl.StateStoreCredentials)
 	if err != nil {
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
-	err = ctrl.StateStoreCredentials()
+	err = ctrl.RunStep("ProbeStateStore", ctrl.ProbeStateStore)
 	if err != nil {
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
-	err = ctrl.GitInit()
+	err = ctrl.RunStep("GitInit", ctrl.GitInit)
 	if err != nil {
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
-	err = ctrl.InitializeBot()
+	err = ctrl.RunStep("InitializeBot", ctrl.InitializeBot)
 	if err != nil {
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
-	err = ctrl.RepositoryPrep()
+	err = ctrl.RunStep("RepositoryPrep", ctrl.RepositoryPrep)
 	if err != nil {
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
-	err = ctrl.RunGitTerraform()
+	err = ctrl.RunStep("RunGitTerraform", ctrl.RunGitTerraform)
 	if err != nil {
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
-	err = ctrl.RepositoryPush()
+	err = ctrl.RunStep("RepositoryPush", ctrl.RepositoryPush)
 	if err != nil {
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
-	err = ctrl.CreateCluster()
+	err = ctrl.RunStep("CreateCluster", ctrl.CreateCluster)
 	if err != nil {
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
-	err = ctrl.WaitForClusterReady()
+	err = ctrl.RunStep("WaitForClusterReady", ctrl.WaitForClusterReady)
 	if err != nil {
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
-	err = ctrl.ClusterSecretsBootstrap()
+	err = ctrl.RunStep("ConfigureWorkloadIdentity", ctrl.ConfigureWorkloadIdentity)
+	if err != nil {
+		ctrl.HandleError(err.Error())
+		return err
+	}
+
+	err = ctrl.RunStep("ValidateStorageClass", ctrl.ValidateStorageClass)
+	if err != nil {
+		ctrl.HandleError(err.Error())
+		return err
+	}
+
+	err = ctrl.RunStep("CreateImagePullSecrets", ctrl.CreateImagePullSecrets)
+	if err != nil {
+		ctrl.HandleError(err.Error())
+		return err
+	}
+
+	err = ctrl.RunStep("ClusterSecretsBootstrap", ctrl.ClusterSecretsBootstrap)
 	if err != nil {
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
 	//* check for ssl restore
-	log.Info().Msg("checking for tls secrets to restore")
+	ctrl.Log().Info("checking for tls secrets to restore")
 	secretsFilesToRestore, err := os.ReadDir(ctrl.ProviderConfig.SSLBackupDir + "/secrets")
 	if err != nil {
-		log.Info().Msg(err.Error())
+		ctrl.Log().Info(err.Error())
 	}
 	if len(secretsFilesToRestore) != 0 {
 		// todo would like these but requires CRD's and is not currently supported
@@ -112,37 +144,37 @@ func CreateDigitaloceanCluster(definition *pkgtypes.ClusterDefinition) error {
 		// https://raw.githubusercontent.com/cert-manager/cert-manager/v1.11.0/deploy/crds/crd-clusterissuers.yaml
 		// https://raw.githubusercontent.com/cert-manager/cert-manager/v1.11.0/deploy/crds/crd-certificates.yaml
 		// add certificates, and clusterissuers
-		log.Info().Msgf("found %d tls secrets to restore", len(secretsFilesToRestore))
+		ctrl.Log().Infof("found %d tls secrets to restore", len(secretsFilesToRestore))
 		ssl.Restore(ctrl.ProviderConfig.SSLBackupDir, ctrl.DomainName, ctrl.ProviderConfig.Kubeconfig)
 	} else {
-		log.Info().Msg("no files found in secrets directory, continuing")
+		ctrl.Log().Info("no files found in secrets directory, continuing")
 	}
 
-	err = ctrl.InstallArgoCD()
+	err = ctrl.RunStep("InstallArgoCD", ctrl.InstallArgoCD)
 	if err != nil {
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
-	err = ctrl.InitializeArgoCD()
+	err = ctrl.RunStep("InitializeArgoCD", ctrl.InitializeArgoCD)
 	if err != nil {
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
-	err = ctrl.DeployRegistryApplication()
+	err = ctrl.RunStep("DeployRegistryApplication", ctrl.DeployRegistryApplication)
 	if err != nil {
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
-	err = ctrl.WaitForVault()
+	err = ctrl.RunStep("WaitForVault", ctrl.WaitForVault)
 	if err != nil {
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
-	err = ctrl.InitializeVault()
+	err = ctrl.RunStep("InitializeVault", ctrl.InitializeVault)
 	if err != nil {
 		ctrl.HandleError(err.Error())
 		return err
@@ -155,40 +187,28 @@ func CreateDigitaloceanCluster(definition *pkgtypes.ClusterDefinition) error {
 
 	//* configure vault with terraform
 	//* vault port-forward
-	vaultStopChannel := make(chan struct{}, 1)
-	defer func() {
-		close(vaultStopChannel)
-	}()
-	k8s.OpenPortForwardPodWrapper(
-		kcfg.Clientset,
-		kcfg.RestConfig,
-		"vault-0",
-		"vault",
-		8200,
-		8200,
-		vaultStopChannel,
-	)
+	ctrl.OpenPortForward(kcfg, "vault-0", "vault", 8200, 8200)
 
-	err = ctrl.RunVaultTerraform()
+	err = ctrl.RunStep("RunVaultTerraform", ctrl.RunVaultTerraform)
 	if err != nil {
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
-	err = ctrl.WriteVaultSecrets()
+	err = ctrl.RunStep("WriteVaultSecrets", ctrl.WriteVaultSecrets)
 	if err != nil {
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
-	err = ctrl.RunUsersTerraform()
+	err = ctrl.RunStep("RunUsersTerraform", ctrl.RunUsersTerraform)
 	if err != nil {
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
 	// Wait for last sync wave app transition to Running
-	log.Info().Msg("waiting for final sync wave Deployment to transition to Running")
+	ctrl.Log().Info("waiting for final sync wave Deployment to transition to Running")
 	crossplaneDeployment, err := k8s.ReturnDeploymentObject(
 		kcfg.Clientset,
 		"app.kubernetes.io/instance",
@@ -197,34 +217,45 @@ func CreateDigitaloceanCluster(definition *pkgtypes.ClusterDefinition) error {
 		3600,
 	)
 	if err != nil {
-		log.Error().Msgf("Error finding crossplane Deployment: %s", err)
+		ctrl.Log().Errorf("Error finding crossplane Deployment: %s", err)
 		ctrl.HandleError(err.Error())
 		return err
 	}
-	log.Info().Msg("waiting on dns, tls certificates from letsencrypt and remaining sync waves.\n this may take up to 60 minutes but regularly completes in under 20 minutes")
+	ctrl.Log().Info("waiting on dns, tls certificates from letsencrypt and remaining sync waves.\n this may take up to 60 minutes but regularly completes in under 20 minutes")
 	_, err = k8s.WaitForDeploymentReady(kcfg.Clientset, crossplaneDeployment, 3600)
 	if err != nil {
-		log.Error().Msgf("Error waiting for all Apps to sync ready state: %s", err)
+		ctrl.Log().Errorf("Error waiting for all Apps to sync ready state: %s", err)
 
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
+	err = ctrl.RunStep("VerifyArgoCDCertificate", ctrl.VerifyArgoCDCertificate)
+	if err != nil {
+		ctrl.HandleError(err.Error())
+		return err
+	}
+
+	err = ctrl.RunStep("ApplyPostInstallManifests", ctrl.ApplyPostInstallManifests)
+	if err != nil {
+		ctrl.HandleError(err.Error())
+		return err
+	}
 	//* export and import cluster
-	err = ctrl.ExportClusterRecord()
+	err = ctrl.RunStep("ExportClusterRecord", ctrl.ExportClusterRecord)
 	if err != nil {
-		log.Error().Msgf("Error exporting cluster record: %s", err)
+		ctrl.Log().Errorf("Error exporting cluster record: %s", err)
 		ctrl.HandleError(err.Error())
 	} else {
 		// Create default service entries
 		cl, _ := secrets.GetCluster(ctrl.KubernetesClient, ctrl.ClusterName)
-		err = services.AddDefaultServices(&cl)
+		_, err = services.EnsureDefaultServices(&cl)
 		if err != nil {
-			log.Error().Msgf("error adding default service entries for cluster %s: %s", cl.ClusterName, err)
+			ctrl.Log().Errorf("error adding default service entries for cluster %s: %s", cl.ClusterName, err)
 		}
 	}
 
-	log.Info().Msg("waiting for kubefirst-api Deployment to transition to Running")
+	ctrl.Log().Info("waiting for kubefirst-api Deployment to transition to Running")
 	kubefirstAPI, err := k8s.ReturnDeploymentObject(
 		kcfg.Clientset,
 		"app.kubernetes.io/name",
@@ -233,20 +264,20 @@ func CreateDigitaloceanCluster(definition *pkgtypes.ClusterDefinition) error {
 		1200,
 	)
 	if err != nil {
-		log.Error().Msgf("Error finding kubefirst api Deployment: %s", err)
+		ctrl.Log().Errorf("Error finding kubefirst api Deployment: %s", err)
 		ctrl.HandleError(err.Error())
 		return err
 	}
 	_, err = k8s.WaitForDeploymentReady(kcfg.Clientset, kubefirstAPI, 300)
 	if err != nil {
-		log.Error().Msgf("Error waiting for kubefirst-api to transition to Running: %s", err)
+		ctrl.Log().Errorf("Error waiting for kubefirst-api to transition to Running: %s", err)
 
 		ctrl.HandleError(err.Error())
 		return err
 	}
 
 	// Wait for last sync wave app transition to Running
-	log.Info().Msg("waiting for final sync wave Deployment to transition to Running")
+	ctrl.Log().Info("waiting for final sync wave Deployment to transition to Running")
 	argocdDeployment, err := k8s.ReturnDeploymentObject(
 		kcfg.Clientset,
 		"app.kubernetes.io/name",
@@ -255,13 +286,13 @@ func CreateDigitaloceanCluster(definition *pkgtypes.ClusterDefinition) error {
 		3600,
 	)
 	if err != nil {
-		log.Error().Msgf("Error finding argocd Deployment: %s", err)
+		ctrl.Log().Errorf("Error finding argocd Deployment: %s", err)
 		ctrl.HandleError(err.Error())
 		return err
 	}
 	_, err = k8s.WaitForDeploymentReady(kcfg.Clientset, argocdDeployment, 3600)
 	if err != nil {
-		log.Error().Msgf("Error waiting for argocd deployment to enter Ready state: %s", err)
+		ctrl.Log().Errorf("Error waiting for argocd deployment to enter Ready state: %s", err)
 
 		ctrl.HandleError(err.Error())
 		return err
@@ -274,7 +305,7 @@ func CreateDigitaloceanCluster(definition *pkgtypes.ClusterDefinition) error {
 		return err
 	}
 
-	log.Info().Msg("cluster creation complete")
+	ctrl.Log().Info("cluster creation complete")
 
 	return nil
 }